@@ -0,0 +1,6397 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/diff"
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/gvc"
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// catFile prints the contents of a gvc object (like Git's cat-file -p)
+func catFile(repo *gvc.Repository, name string) error {
+	sha, err := repo.ResolveObject(name)
+	if err != nil {
+		return err
+	}
+	objectType, content, err := repo.ReadObject(sha)
+	if err != nil {
+		return err
+	}
+
+	switch objectType {
+	case object.BlobObject:
+		fmt.Print(string(content))
+	case object.TreeObject, object.CommitObject, object.TagObject:
+		fmt.Print(string(content))
+	default:
+		return fmt.Errorf("unknown object type: %s", objectType)
+	}
+
+	return nil
+}
+
+// catFileBatch answers one object name per line of in with writeBatchObject
+func catFileBatch(repo *gvc.Repository, in io.Reader, out io.Writer, checkOnly bool) error {
+	w := bufio.NewWriter(out)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" {
+			continue
+		}
+		if err := writeBatchObject(repo, w, name, checkOnly); err != nil {
+			return err
+		}
+		// Flush every answer so a caller can interleave requests and replies
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read object names: %w", err)
+	}
+	return nil
+}
+
+// writeBatchObject answers one object name: "<sha> <type> <size>", followed
+// by the content and a newline unless checkOnly is set. A name that does not
+// resolve to a stored object gets "<name> missing".
+func writeBatchObject(repo *gvc.Repository, w io.Writer, name string, checkOnly bool) error {
+	sha, err := repo.ResolveObject(name)
+	var objectType object.Type
+	var content []byte
+	if err == nil {
+		objectType, content, err = repo.ReadObject(sha)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	if err != nil {
+		_, err = fmt.Fprintf(w, "%s missing\n", name)
+		return err
+	}
+	fmt.Fprintf(w, "%s %s %d\n", sha, objectType, len(content))
+	if !checkOnly {
+		w.Write(content)
+		io.WriteString(w, "\n")
+	}
+	return nil
+}
+
+// catFileBatchCommand runs one command per line of in, the way
+// cat-file --batch-command does: "contents <object>" and "info <object>"
+// answer as --batch and --batch-check do, and "rev-parse <rev>" prints the
+// SHA a revision resolves to, or "<rev> missing". Answers are flushed after
+// every command or, with buffer, only on "flush" and at the end of input.
+func catFileBatchCommand(repo *gvc.Repository, in io.Reader, out io.Writer, buffer bool) error {
+	w := bufio.NewWriter(out)
+	defer w.Flush()
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		command, arg, _ := strings.Cut(line, " ")
+		arg = strings.TrimSpace(arg)
+		switch {
+		case command == "flush" && arg != "":
+			return fmt.Errorf("flush takes no argument: %s", line)
+		case command != "flush" && arg == "":
+			return fmt.Errorf("missing argument: %s", line)
+		}
+		switch command {
+		case "contents", "info":
+			if err := writeBatchObject(repo, w, arg, command == "info"); err != nil {
+				return err
+			}
+		case "rev-parse":
+			if sha, err := repo.ResolveObject(arg); err != nil {
+				fmt.Fprintf(w, "%s missing\n", arg)
+			} else {
+				fmt.Fprintln(w, sha)
+			}
+		case "flush":
+			if !buffer {
+				return errors.New("flush is only valid with --buffer")
+			}
+		default:
+			return fmt.Errorf("unknown command: %s", command)
+		}
+		if !buffer || command == "flush" {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read commands: %w", err)
+	}
+	return w.Flush()
+}
+
+// hashObject hashes a file or, when path is "", standard input into a blob
+// object, storing it when write is set
+func hashObject(repo *gvc.Repository, path string, write bool) error {
+	var sha string
+	var err error
+	if path == "" {
+		var content []byte
+		if content, err = io.ReadAll(os.Stdin); err != nil {
+			return fmt.Errorf("failed to read standard input: %w", err)
+		}
+		if write {
+			sha, err = repo.WriteObject(object.BlobObject, content)
+		} else {
+			sha = repo.Format.Hash(object.BlobObject, content)
+		}
+	} else {
+		sha, err = repo.HashFile(path, write)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(sha)
+	return nil
+}
+
+// lsTreeOptions controls how ls-tree prints entries
+type lsTreeOptions struct {
+	nameOnly bool
+	long     bool   // show the size of each blob
+	abbrev   int    // SHA digits to print; 0 prints them in full
+	format   string // a --format string; empty for the default layout
+}
+
+// lsTree lists the contents of a tree object
+func lsTree(repo *gvc.Repository, treeish string, opts lsTreeOptions) error {
+	sha, err := repo.ResolveObject(treeish)
+	if err != nil {
+		return err
+	}
+	objectType, content, err := repo.ReadObject(sha)
+	if err != nil {
+		return err
+	}
+
+	if objectType != object.TreeObject {
+		// A commit, or a tag of one, stands for its tree
+		commitSHA, err := repo.ResolveCommit(treeish)
+		if err != nil {
+			return fmt.Errorf("expected tree object, got %s", objectType)
+		}
+		commit, err := repo.ReadCommit(commitSHA)
+		if err != nil {
+			return err
+		}
+		if _, content, err = repo.ReadObject(commit.TreeSHA); err != nil {
+			return err
+		}
+	}
+
+	entries, err := repo.Format.ParseTree(content)
+	if err != nil {
+		return err
+	}
+	if jsonOutput || opts.nameOnly {
+		for _, entry := range entries {
+			if !jsonOutput {
+				fmt.Println(entry.Name)
+			} else if err := printJSON(jsonTreeEntry{record("tree-entry"), entry.Mode, string(entry.Type), entry.SHA, entry.Name}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	// Sizes are only looked up when shown, as that means reading each blob's header
+	sizes := make([]string, len(entries))
+	if opts.long || strings.Contains(opts.format, "%(objectsize") {
+		for i, entry := range entries {
+			sizes[i] = "-"
+			if entry.Type != object.BlobObject {
+				continue
+			}
+			size, err := repo.ObjectSize(entry.SHA)
+			if err != nil {
+				return err
+			}
+			sizes[i] = strconv.FormatInt(size, 10)
+		}
+	}
+	abbrev := func(sha string) string {
+		if opts.abbrev == 0 {
+			return sha
+		}
+		return sha[:min(max(opts.abbrev, 4), len(sha))]
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+	if opts.format != "" {
+		for i, entry := range entries {
+			line, err := formatTreeEntry(opts.format, map[string]string{
+				"objectmode":        treeMode(entry.Mode),
+				"objecttype":        string(entry.Type),
+				"objectname":        abbrev(entry.SHA),
+				"objectsize":        sizes[i],
+				"objectsize:padded": fmt.Sprintf("%7s", sizes[i]),
+				"path":              entry.Name,
+			})
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(w, line)
+		}
+		return nil
+	}
+
+	// Columns are as wide as their widest value, so a submodule's "commit"
+	// or a large blob does not push its line out of line
+	typeWidth, sizeWidth := 0, 7
+	for i, entry := range entries {
+		typeWidth = max(typeWidth, len(entry.Type))
+		sizeWidth = max(sizeWidth, len(sizes[i]))
+	}
+	for i, entry := range entries {
+		fmt.Fprintf(w, "%s %-*s %s", treeMode(entry.Mode), typeWidth, entry.Type, abbrev(entry.SHA))
+		if opts.long {
+			fmt.Fprintf(w, " %*s", sizeWidth, sizes[i])
+		}
+		fmt.Fprintf(w, "\t%s\n", entry.Name)
+	}
+	return nil
+}
+
+// treeMode pads a tree entry's mode to six digits, as Git prints it
+func treeMode(mode string) string {
+	return strings.Repeat("0", max(6-len(mode), 0)) + mode
+}
+
+// formatTreeEntry expands the placeholders of ls-tree --format:
+// %(objectmode), %(objecttype), %(objectname), %(objectsize),
+// %(objectsize:padded) and %(path), plus %n (newline), %xNN (the byte with
+// hex value NN) and %%
+func formatTreeEntry(format string, fields map[string]string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			out.WriteByte(format[i])
+			continue
+		}
+		switch next := format[i+1]; {
+		case next == '%':
+			out.WriteByte('%')
+			i++
+		case next == 'n':
+			out.WriteByte('\n')
+			i++
+		case next == 'x' && i+4 <= len(format) && isHexByte(format[i+2:i+4]):
+			b, _ := strconv.ParseUint(format[i+2:i+4], 16, 8)
+			out.WriteByte(byte(b))
+			i += 3
+		case next == '(':
+			end := strings.IndexByte(format[i:], ')')
+			if end < 0 {
+				return "", fmt.Errorf("invalid --format: unterminated placeholder in %q", format)
+			}
+			name := format[i+2 : i+end]
+			value, ok := fields[name]
+			if !ok {
+				return "", fmt.Errorf("invalid --format: unknown placeholder %%(%s)", name)
+			}
+			out.WriteString(value)
+			i += end
+		default:
+			out.WriteByte('%')
+		}
+	}
+	return out.String(), nil
+}
+
+// isHexByte reports whether s is two hex digits
+func isHexByte(s string) bool {
+	_, err := strconv.ParseUint(s, 16, 8)
+	return len(s) == 2 && err == nil
+}
+
+// Command handlers
+func handleInit(args []string) error {
+	format := object.SHA1
+	shared := ""
+	for _, arg := range args {
+		if arg == "--shared" {
+			shared = "group"
+			continue
+		}
+		if value, ok := strings.CutPrefix(arg, "--shared="); ok {
+			shared = value
+			continue
+		}
+		name, ok := strings.CutPrefix(arg, "--object-format=")
+		if !ok {
+			return commandUsage("init")
+		}
+		var err error
+		if format, err = object.ParseFormat(name); err != nil {
+			return err
+		}
+	}
+
+	var repo *gvc.Repository
+	var err error
+	if gitDir := os.Getenv(gvc.EnvGvcDir); gitDir != "" {
+		repo, err = gvc.InitAt(".", gitDir, format)
+	} else {
+		repo, err = gvc.Init(".", format)
+	}
+	if err != nil {
+		return err
+	}
+	if shared != "" {
+		if err := repo.SetSharedRepository(shared); err != nil {
+			return err
+		}
+	}
+	if verbosity != quiet {
+		fmt.Println("Initialized empty gvc repository")
+	}
+	return nil
+}
+
+func handleCatFile(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("cat-file")
+	if len(args) == 1 && (args[0] == "--batch" || args[0] == "--batch-check") {
+		return catFileBatch(repo, os.Stdin, os.Stdout, args[0] == "--batch-check")
+	}
+	if len(args) > 0 && args[0] == "--batch-command" {
+		switch {
+		case len(args) == 1:
+			return catFileBatchCommand(repo, os.Stdin, os.Stdout, false)
+		case len(args) == 2 && args[1] == "--buffer":
+			return catFileBatchCommand(repo, os.Stdin, os.Stdout, true)
+		}
+		return usage
+	}
+	if len(args) < 2 || args[0] != "-p" {
+		return usage
+	}
+	return catFile(repo, args[1])
+}
+
+func handleHashObject(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("hash-object")
+	write, stdin := false, false
+	var paths []string
+	for _, arg := range args {
+		switch {
+		case arg == "-w":
+			write = true
+		case arg == "--stdin":
+			stdin = true
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			paths = append(paths, arg)
+		}
+	}
+	if stdin == (len(paths) > 0) {
+		return usage
+	}
+	if stdin {
+		return hashObject(repo, "", write)
+	}
+	for _, path := range paths {
+		if err := hashObject(repo, path, write); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func handleLsTree(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("ls-tree")
+	var opts lsTreeOptions
+	var treeish string
+	for _, arg := range args {
+		switch {
+		case arg == "--name-only":
+			opts.nameOnly = true
+		case arg == "-l" || arg == "--long":
+			opts.long = true
+		case arg == "--abbrev":
+			opts.abbrev = 7
+		case strings.HasPrefix(arg, "--abbrev="):
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--abbrev="))
+			if err != nil || n < 0 {
+				return usage
+			}
+			opts.abbrev = n
+		case strings.HasPrefix(arg, "--format="):
+			opts.format = strings.TrimPrefix(arg, "--format=")
+		case strings.HasPrefix(arg, "-") || treeish != "":
+			return usage
+		default:
+			treeish = arg
+		}
+	}
+	if treeish == "" {
+		return usage
+	}
+	if opts.format != "" && (opts.nameOnly || opts.long) {
+		return usageError("--format cannot be combined with --name-only or -l")
+	}
+	if opts.nameOnly && opts.long {
+		return usageError("--name-only cannot be combined with -l")
+	}
+	if jsonOutput && (opts.format != "" || opts.long || opts.abbrev > 0) {
+		return usageError("--json cannot be combined with --format, -l or --abbrev")
+	}
+
+	return lsTree(repo, treeish, opts)
+}
+
+func handleWriteTree(repo *gvc.Repository, args []string) error {
+	if len(args) > 0 {
+		return commandUsage("write-tree")
+	}
+
+	treeSHA, err := repo.WriteTree()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(treeSHA)
+	return nil
+}
+
+func handleCommitTree(repo *gvc.Repository, args []string) error {
+	if len(args) < 5 || args[1] != "-p" || args[3] != "-m" {
+		return commandUsage("commit-tree")
+	}
+
+	treeSHA := args[0]
+	parentSHA := args[2]
+	message := args[4]
+
+	commitSHA, err := repo.CommitTree(treeSHA, parentSHA, message)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(commitSHA)
+	return nil
+}
+
+// NEW: Add command
+func handleAdd(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("add")
+	var opts gvc.AddOptions
+	var paths []string
+	patchMode := false
+	for _, arg := range args {
+		switch arg {
+		case "-p", "--patch":
+			patchMode = true
+		case "-A", "--all":
+			opts.All = true
+		case "-u", "--update":
+			opts.Update = true
+		case "-f", "--force":
+			opts.Force = true
+		case "-n", "--dry-run":
+			opts.DryRun = true
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return usage
+			}
+			path, err := repo.RelPath(arg)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, path)
+		}
+	}
+	if patchMode {
+		if opts.All || opts.Update || opts.Force || opts.DryRun {
+			return usage
+		}
+		return addPatch(repo, os.Stdin, paths)
+	}
+	if (opts.All && opts.Update) || (len(paths) == 0 && !opts.All && !opts.Update) {
+		return usage
+	}
+
+	if err := repo.AddWithOptions(opts, paths...); err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+	if len(paths) > 0 {
+		fmt.Printf("Added %d path(s) to staging area\n", len(paths))
+	} else {
+		fmt.Println("Staged all changes")
+	}
+	return nil
+}
+
+// addPatchHelp explains the answers add -p takes
+const addPatchHelp = `y - stage this hunk
+n - do not stage this hunk
+s - split this hunk into smaller hunks
+a - stage this hunk and the rest of the file's hunks
+d - do not stage this hunk or the rest of the file's hunks
+q - quit; do not stage this hunk or any remaining ones
+? - print help
+`
+
+// addPatch shows the unstaged hunks of the tracked files under paths one at
+// a time, reading from in whether to stage each, and stages the accepted
+// ones file by file. Hunks already accepted are staged when quitting.
+func addPatch(repo *gvc.Repository, in io.Reader, paths []string) error {
+	patches, err := repo.UnstagedPatches(paths...)
+	if err != nil {
+		return err
+	}
+	if len(patches) == 0 {
+		fmt.Println("No changes.")
+		return nil
+	}
+
+	_, err = choosePatchHunks(repo, bufio.NewReader(in), patches)
+	return err
+}
+
+// choosePatchHunks asks hunk by hunk which of patches to stage, reading the
+// answers add -p takes, and stages the accepted ones file by file. It
+// reports whether it was told to quit or ran out of answers.
+func choosePatchHunks(repo *gvc.Repository, answers *bufio.Reader, patches []gvc.FilePatch) (bool, error) {
+	quit := false
+	for _, patch := range patches {
+		fmt.Printf("diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", patch.Path, patch.Path, patch.Path, patch.Path)
+		hunks := patch.Hunks
+		var accepted []diff.Hunk
+		for i := 0; i < len(hunks) && !quit; i++ {
+			printHunk(hunks[i])
+			options := "y,n,a,d,q,?"
+			parts := hunks[i].Split()
+			if len(parts) > 1 {
+				options = "y,n,s,a,d,q,?"
+			}
+			fmt.Printf("(%d/%d) Stage this hunk [%s]? ", i+1, len(hunks), options)
+			line, err := answers.ReadString('\n')
+			if err != nil && line == "" {
+				// Out of answers: stop as if told to quit
+				fmt.Println()
+				quit = true
+				break
+			}
+
+			switch strings.TrimSpace(line) {
+			case "y":
+				accepted = append(accepted, hunks[i])
+			case "n":
+			case "s":
+				if len(parts) == 1 {
+					fmt.Println("Sorry, cannot split this hunk")
+					i--
+					continue
+				}
+				fmt.Printf("Split into %d hunks.\n", len(parts))
+				hunks = slices.Concat(hunks[:i], parts, hunks[i+1:])
+				i--
+			case "a":
+				accepted = append(accepted, hunks[i:]...)
+				i = len(hunks)
+			case "d":
+				i = len(hunks)
+			case "q":
+				quit = true
+			default:
+				fmt.Print(addPatchHelp)
+				i--
+			}
+		}
+		if err := repo.StageHunks(patch, accepted); err != nil {
+			return false, err
+		}
+		if quit {
+			break
+		}
+	}
+	return quit, nil
+}
+
+// printHunk prints a hunk as diff shows it
+func printHunk(hunk diff.Hunk) {
+	fmt.Println(hunk.Header())
+	for _, op := range hunk.Ops {
+		fmt.Print(string(op.Kind) + op.Line)
+		if !strings.HasSuffix(op.Line, "\n") {
+			fmt.Print("\n\\ No newline at end of file\n")
+		}
+	}
+}
+
+// NEW: Commit command
+func handleCommit(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("commit")
+	var opts gvc.CommitOptions
+	message, hasMessage := "", false
+	fixupKind, fixupTarget := "", ""
+	dryRun, edit, template := false, false, ""
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "--fixup", "--squash":
+			if i+1 >= len(args) || fixupKind != "" {
+				return usage
+			}
+			fixupKind, fixupTarget = strings.TrimPrefix(args[i], "--"), args[i+1]
+			i++
+		case "-a", "--all":
+			opts.All = true
+		case "--amend":
+			opts.Amend = true
+		case "-S", "--gpg-sign":
+			opts.Sign = true
+		case "--no-gpg-sign":
+			opts.NoSign = true
+		case "-n", "--no-verify":
+			opts.NoVerify = true
+		case "--allow-secrets":
+			opts.AllowSecrets = true
+		case "--dry-run":
+			dryRun = true
+		case "-e", "--edit":
+			edit = true
+		case "-m", "-t", "--template":
+			if i+1 >= len(args) {
+				return usage
+			}
+			i++
+			if args[i-1] == "-m" {
+				message, hasMessage = args[i], true
+			} else {
+				template = args[i]
+			}
+		default:
+			if v, ok := strings.CutPrefix(args[i], "--template="); ok {
+				template = v
+				continue
+			}
+			return usage
+		}
+	}
+	if fixupKind != "" {
+		if opts.Amend {
+			return usage
+		}
+		var err error
+		if message, err = repo.FixupMessage(fixupKind, fixupTarget, message); err != nil {
+			return err
+		}
+	} else if !hasMessage && !opts.Amend {
+		// Without a message the editor asks for one
+		edit = true
+	}
+	if dryRun {
+		return commitDryRun(repo, opts)
+	}
+	if edit {
+		if template != "" {
+			// The template is named relative to where gvc runs, not the working tree
+			abs, err := filepath.Abs(template)
+			if err != nil {
+				return err
+			}
+			template = abs
+		}
+		var err error
+		if message, err = editCommitMessage(repo, message, template, opts); err != nil {
+			return err
+		}
+	}
+
+	commitSHA, err := repo.CommitWithOptions(message, opts)
+	if err != nil {
+		return err
+	}
+	// The commit-msg hook may have rewritten the message
+	commit, err := repo.ReadCommit(commitSHA)
+	if err != nil {
+		return err
+	}
+
+	summary, err := repo.CommitSummary(commitSHA, commit.Message)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(repo.Out, summary)
+	return nil
+}
+
+// commitDryRun prints the changes a commit would record, the way status
+// lists staged changes, and reports a negative result when there are none
+func commitDryRun(repo *gvc.Repository, opts gvc.CommitOptions) error {
+	lines, err := commitChangeLines(repo, opts)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 && !opts.Amend {
+		fmt.Println("nothing to commit (no changes staged since HEAD)")
+		return negativeResult()
+	}
+	fmt.Println("Changes to be committed:")
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+	return nil
+}
+
+// commitChangeLines lists the changes a commit would record, one status
+// line each
+func commitChangeLines(repo *gvc.Repository, opts gvc.CommitOptions) ([]string, error) {
+	changes, err := repo.CommitPreview(opts)
+	if err != nil {
+		return nil, err
+	}
+	if changes, err = detectRenames(repo, changes, -1); err != nil {
+		return nil, err
+	}
+	lines := make([]string, 0, len(changes))
+	for _, change := range changes {
+		record := newJSONChange(change, "staged")
+		if record.OldPath != "" {
+			lines = append(lines, fmt.Sprintf("\t%-12s%s -> %s", record.Change+":", record.OldPath, record.Path))
+		} else {
+			lines = append(lines, fmt.Sprintf("\t%-12s%s", record.Change+":", record.Path))
+		}
+	}
+	return lines, nil
+}
+
+// editCommitMessage opens the editor on message, or on the amended commit's
+// message or the template when it is empty, under a summary of what the
+// commit records
+func editCommitMessage(repo *gvc.Repository, message, template string, opts gvc.CommitOptions) (string, error) {
+	if message == "" && opts.Amend {
+		head, err := repo.HeadCommit()
+		if err != nil {
+			return "", err
+		}
+		if head != "" {
+			commit, err := repo.ReadCommit(head)
+			if err != nil {
+				return "", err
+			}
+			message = commit.Message
+		}
+	}
+
+	var status strings.Builder
+	ref, err := repo.HeadRef()
+	if err != nil {
+		return "", err
+	}
+	if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+		fmt.Fprintf(&status, "On branch %s\n", branch)
+	} else {
+		status.WriteString("HEAD detached\n")
+	}
+	lines, err := commitChangeLines(repo, opts)
+	if err != nil {
+		return "", err
+	}
+	if len(lines) > 0 {
+		status.WriteString("Changes to be committed:\n" + strings.Join(lines, "\n") + "\n")
+	}
+	return repo.EditCommitMessage(message, template, status.String())
+}
+
+// NEW: Log command
+func handleLog(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("log")
+	leftRight, showSignature, showGraph, showBoundary := false, false, false, false
+	notesOpts := notesDisplay{defaults: true}
+	format := "medium"
+	var opts gvc.LogOptions
+	var revs []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		// Options that take a value accept it inline or as the next argument
+		value := func(name string) (string, bool) {
+			if v, ok := strings.CutPrefix(arg, name+"="); ok {
+				return v, true
+			}
+			if arg == name && i+1 < len(args) {
+				i++
+				return args[i], true
+			}
+			return "", false
+		}
+
+		if arg == "--" {
+			for _, path := range args[i+1:] {
+				rel, err := repo.RelPath(path)
+				if err != nil {
+					return err
+				}
+				opts.Paths = append(opts.Paths, rel)
+			}
+			break
+		}
+		if v, ok := value("--format"); ok {
+			format = v
+		} else if v, ok := value("--pretty"); ok {
+			format = v
+		} else if v, ok := value("--author"); ok {
+			pattern, err := regexp.Compile(v)
+			if err != nil {
+				return fmt.Errorf("invalid --author pattern: %w", err)
+			}
+			opts.Author = pattern
+		} else if v, ok := value("--since"); ok {
+			since, err := gvc.ParseDate(v, time.Now())
+			if err != nil {
+				return err
+			}
+			opts.Since = since
+		} else if v, ok := value("--until"); ok {
+			until, err := gvc.ParseDate(v, time.Now())
+			if err != nil {
+				return err
+			}
+			opts.Until = until
+		} else if v, ok := value("--max-count"); ok {
+			if opts.MaxCount, ok = parseCount(v); !ok {
+				return usage
+			}
+		} else if v, ok := value("-n"); ok {
+			if opts.MaxCount, ok = parseCount(v); !ok {
+				return usage
+			}
+		} else if count, ok := parseCount(strings.TrimPrefix(arg, "-n")); ok && strings.HasPrefix(arg, "-n") {
+			opts.MaxCount = count
+		} else if count, ok := parseCount(strings.TrimPrefix(arg, "-")); ok && strings.HasPrefix(arg, "-") {
+			opts.MaxCount = count
+		} else {
+			switch {
+			case arg == "--oneline":
+				format = "oneline"
+			case arg == "--left-right":
+				leftRight = true
+			case arg == "--graph":
+				showGraph = true
+			case arg == "--show-signature":
+				showSignature = true
+			case arg == "--follow":
+				opts.Follow = true
+			case arg == "--full-history":
+				opts.FullHistory = true
+			case arg == "--reverse":
+				opts.Reverse = true
+			case arg == "--boundary":
+				showBoundary = true
+			case notesOpts.parse(arg):
+				if notesOpts.invalid {
+					return usage
+				}
+			case isRefSelector(arg):
+				revs = append(revs, arg)
+			case revOrders[arg] != gvc.OrderDefault:
+				opts.Order = revOrders[arg]
+			case strings.HasPrefix(arg, "-"):
+				return usage
+			default:
+				revs = append(revs, arg)
+			}
+		}
+	}
+	if format != "oneline" && format != "medium" && !strings.Contains(format, "%") {
+		return fmt.Errorf("invalid --format: %s (use oneline, medium or a format string with %%-placeholders)", format)
+	}
+
+	if len(revs) == 0 {
+		head, err := repo.HeadCommit()
+		if err != nil {
+			return err
+		}
+		if head == "" {
+			if !jsonOutput {
+				fmt.Println("No commits yet")
+			}
+			return nil
+		}
+	}
+	if jsonOutput && showGraph {
+		return usageError("log --json cannot be combined with --graph")
+	}
+	if opts.Reverse && showGraph {
+		return usageError("log --reverse cannot be combined with --graph")
+	}
+	if showGraph && opts.Order == gvc.OrderDefault {
+		opts.Order = gvc.OrderTopo
+	}
+	opts.RewriteParents = showGraph
+	commits, err := repo.LogWithOptions(opts, revs...)
+	if err != nil {
+		return err
+	}
+
+	// Boundary commits follow the listed ones, or lead them oldest first
+	boundary := make(map[string]bool)
+	if showBoundary {
+		boundaryCommits, err := repo.BoundaryCommits(commits)
+		if err != nil {
+			return err
+		}
+		for _, commit := range boundaryCommits {
+			boundary[commit.SHA] = true
+		}
+		if opts.Reverse {
+			slices.Reverse(boundaryCommits)
+			commits = append(boundaryCommits, commits...)
+		} else {
+			commits = append(commits, boundaryCommits...)
+		}
+	}
+
+	// Notes are shown in the default layout, or in any once asked for, as
+	// in Git; JSON records always carry them
+	var notes []*gvc.Notes
+	if format == "medium" || jsonOutput || notesOpts.explicit {
+		if notes, err = notesOpts.load(repo); err != nil {
+			return err
+		}
+	}
+
+	var revRange *gvc.RevRange
+	if leftRight && len(revs) > 0 {
+		if revRange, err = repo.ParseRevRange(revs); err != nil {
+			return err
+		}
+	}
+
+	var graph *gvc.Graph
+	shown := make(map[string]bool)
+	if showGraph {
+		graph = &gvc.Graph{}
+		for _, commit := range commits {
+			shown[commit.SHA] = true
+		}
+	}
+
+	// Display the commit history, newest first unless reversed
+	for _, commit := range commits {
+		if jsonOutput {
+			record := newJSONCommit(commit)
+			record.Boundary = boundary[commit.SHA]
+			for _, n := range notes {
+				note, ok, err := n.Get(commit.SHA)
+				if err != nil {
+					return err
+				}
+				if ok {
+					record.Notes = append(record.Notes, jsonNote{Ref: n.Ref, Text: note})
+				}
+			}
+			if err := printJSON(record); err != nil {
+				return err
+			}
+			continue
+		}
+		marker := ""
+		// The graph marks a boundary commit with o in place of *
+		if boundary[commit.SHA] && graph == nil {
+			marker = "- "
+		} else if revRange != nil {
+			marker = sideMarker(revRange, commit.SHA)
+		}
+		var text string
+		switch format {
+		case "oneline":
+			text = marker + commit.Format("%h %s", marker)
+		case "medium":
+			if text, err = mediumCommit(repo, commit, marker, showSignature, notes); err != nil {
+				return err
+			}
+		default:
+			text = commit.Format(format, marker)
+		}
+		// Other layouts get the notes right below the commit's line
+		if format != "medium" && len(notes) > 0 {
+			var b strings.Builder
+			if err := writeNotes(&b, commit.SHA, notes, ""); err != nil {
+				return err
+			}
+			if b.Len() > 0 {
+				text += "\n" + strings.TrimSuffix(b.String(), "\n")
+			}
+		}
+
+		if graph == nil {
+			fmt.Println(text)
+			continue
+		}
+		// Only parents that are shown get a rail
+		var parents []string
+		for _, parent := range commit.Parents {
+			if shown[parent] {
+				parents = append(parents, parent)
+			}
+		}
+		rows := graph.NextCommit(commit.SHA, parents)
+		for i, line := range strings.Split(text, "\n") {
+			rails := rows.Padding
+			if i == 0 {
+				rails = rows.Commit
+				if boundary[commit.SHA] {
+					rails = strings.Replace(rails, "*", "o", 1)
+				}
+			}
+			fmt.Println(strings.TrimRight(rails+" "+line, " "))
+		}
+		for _, connector := range rows.Connectors {
+			fmt.Println(connector)
+		}
+	}
+
+	return nil
+}
+
+// mediumCommit renders a commit the way log and show print it by default,
+// followed by the notes attached to it in each of notes
+func mediumCommit(repo *gvc.Repository, commit *object.Commit, marker string, showSignature bool, notes []*gvc.Notes) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "commit %s%s\n", marker, commit.SHA)
+	if showSignature {
+		check, err := repo.VerifyCommit(commit.SHA)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(check.Output)
+	}
+	if len(commit.Parents) > 1 {
+		short := make([]string, len(commit.Parents))
+		for i, parent := range commit.Parents {
+			short[i] = parent[:7]
+		}
+		fmt.Fprintf(&b, "Merge: %s\n", strings.Join(short, " "))
+	}
+	fmt.Fprintf(&b, "Author: %s\n", commit.Author)
+	fmt.Fprintf(&b, "Date: %s\n", commit.Timestamp.Format("Mon Jan 2 15:04:05 2006 -0700"))
+	fmt.Fprintf(&b, "\n    %s\n", strings.ReplaceAll(commit.Message, "\n", "\n    "))
+	if err := writeNotes(&b, commit.SHA, notes, "\n"); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// writeNotes writes a section for each of notes that has a note on a
+// commit, each preceded by sep: "Notes:" for the default notes ref and
+// "Notes (<name>):" for others, then the note indented
+func writeNotes(b *strings.Builder, commitSHA string, notes []*gvc.Notes, sep string) error {
+	for _, n := range notes {
+		note, ok, err := n.Get(commitSHA)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		heading := "Notes:"
+		if n.Ref != gvc.NotesRef {
+			heading = fmt.Sprintf("Notes (%s):", strings.TrimPrefix(n.Ref, "refs/notes/"))
+		}
+		note = strings.TrimRight(note, "\n")
+		fmt.Fprintf(b, "%s%s\n    %s\n", sep, heading, strings.ReplaceAll(note, "\n", "\n    "))
+	}
+	return nil
+}
+
+// notesDisplay tracks which notes refs log and show print. As in Git,
+// --notes=<ref> adds a ref and, when it is the first notes option, drops
+// the default one; --notes brings the default back and --no-notes clears
+// everything, so "--no-notes --notes=review" shows review's notes alone.
+type notesDisplay struct {
+	defaults bool     // show the default notes ref
+	refs     []string // other refs asked for, expanded
+	explicit bool     // a notes option was given
+	invalid  bool     // --notes= was given without a ref
+}
+
+// parse consumes a notes option, reporting whether arg was one
+func (d *notesDisplay) parse(arg string) bool {
+	switch {
+	case arg == "--notes":
+		d.defaults = true
+	case arg == "--no-notes":
+		d.defaults, d.refs = false, nil
+	case strings.HasPrefix(arg, "--notes="):
+		ref := strings.TrimPrefix(arg, "--notes=")
+		if ref == "" {
+			d.invalid = true
+		}
+		if !d.explicit {
+			d.defaults = false
+		}
+		if ref = gvc.ExpandNotesRef(ref); !slices.Contains(d.refs, ref) {
+			d.refs = append(d.refs, ref)
+		}
+	default:
+		return false
+	}
+	d.explicit = true
+	return true
+}
+
+// load reads the notes refs to display, the default one first
+func (d *notesDisplay) load(repo *gvc.Repository) ([]*gvc.Notes, error) {
+	refs := d.refs
+	if d.defaults && !slices.Contains(refs, gvc.NotesRef) {
+		refs = append([]string{gvc.NotesRef}, refs...)
+	}
+	var loaded []*gvc.Notes
+	for _, ref := range refs {
+		notes, err := repo.LoadNotesRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		loaded = append(loaded, notes)
+	}
+	return loaded, nil
+}
+
+// parseCount reads the positive number given to -n
+func parseCount(value string) (int, bool) {
+	count, err := strconv.Atoi(value)
+	return count, err == nil && count >= 0 && value != ""
+}
+
+// NEW: Stash command
+func handleStash(repo *gvc.Repository, args []string) error {
+	subcommand := "push"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		subcommand = args[0]
+		args = args[1:]
+	}
+
+	switch subcommand {
+	case "push":
+		usage := subcommandUsage("stash", "push")
+		var message string
+		mode := gvc.StashAll
+		for i := 0; i < len(args); i++ {
+			switch args[i] {
+			case "-m", "--message":
+				if i+1 >= len(args) {
+					return usage
+				}
+				message = args[i+1]
+				i++
+			case "-k", "--keep-index":
+				if mode == gvc.StashStaged {
+					return errors.New("--keep-index and --staged cannot be used together")
+				}
+				mode = gvc.StashKeepIndex
+			case "-S", "--staged":
+				if mode == gvc.StashKeepIndex {
+					return errors.New("--keep-index and --staged cannot be used together")
+				}
+				mode = gvc.StashStaged
+			default:
+				return usage
+			}
+		}
+		return repo.StashPush(message, mode)
+
+	case "list":
+		usage := subcommandUsage("stash", "list")
+		stat, dateFormat := false, "relative"
+		for _, arg := range args {
+			switch {
+			case arg == "--stat":
+				stat = true
+			case arg == "--date=relative" || arg == "--date=iso":
+				dateFormat = strings.TrimPrefix(arg, "--date=")
+			default:
+				return usage
+			}
+		}
+		entries, err := repo.ReadStashEntries()
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for i, entry := range entries {
+			// The message names the branch the stash was made on
+			date := gvc.RelativeDate(entry.Timestamp, now)
+			if dateFormat == "iso" {
+				date = entry.Timestamp.Format("2006-01-02 15:04:05 -0700")
+			}
+			fmt.Printf("stash@{%d}: %s (%s)\n", i, entry.Message, date)
+			if !stat {
+				continue
+			}
+			changes, err := repo.StashChanges(entry.NewSHA)
+			if err != nil {
+				return err
+			}
+			if err := repo.WriteDiffStat(os.Stdout, changes); err != nil {
+				return err
+			}
+			if i < len(entries)-1 {
+				fmt.Println()
+			}
+		}
+		return nil
+
+	case "pop", "drop":
+		if len(args) > 1 {
+			return subcommandUsage("stash", subcommand)
+		}
+		entries, err := repo.ReadStashEntries()
+		if err != nil {
+			return err
+		}
+		var ref string
+		if len(args) == 1 {
+			ref = args[0]
+		}
+		n, err := gvc.ParseStashIndex(ref, entries)
+		if err != nil {
+			return err
+		}
+
+		if subcommand == "pop" {
+			if err := repo.StashApply(entries[n].NewSHA); err != nil {
+				return err
+			}
+		}
+
+		dropped := entries[n]
+		entries = append(entries[:n], entries[n+1:]...)
+		if err := repo.WriteStashEntries(entries); err != nil {
+			return err
+		}
+		fmt.Printf("Dropped stash@{%d} (%s)\n", n, dropped.NewSHA)
+		return nil
+
+	default:
+		return usageError(fmt.Sprintf("unknown stash subcommand: %s", subcommand))
+	}
+}
+
+// NEW: Reflog command
+func handleReflog(repo *gvc.Repository, args []string) error {
+	if len(args) > 0 && args[0] == "show" {
+		args = args[1:]
+	}
+	if len(args) > 1 {
+		return commandUsage("reflog")
+	}
+
+	name := "HEAD"
+	if len(args) == 1 {
+		name = args[0]
+	}
+	ref, err := repo.ExpandRefName(name)
+	if err != nil {
+		return err
+	}
+
+	entries, err := repo.ReadReflog(ref)
+	if err != nil {
+		return err
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		fmt.Printf("%s %s@{%d}: %s\n", entry.NewSHA[:7], name, len(entries)-1-i, entry.Message)
+	}
+	return nil
+}
+
+// NEW: Cherry-pick command
+func handleCherryPick(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("cherry-pick")
+	if len(args) == 1 && args[0] == "--continue" {
+		return repo.ContinueCherryPick()
+	}
+	if len(args) == 1 && args[0] == "--abort" {
+		return repo.AbortCherryPick()
+	}
+
+	var opts gvc.CherryPickOptions
+	var commits []string
+	for _, arg := range args {
+		switch {
+		case arg == "-n" || arg == "--no-commit":
+			opts.NoCommit = true
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			commits = append(commits, arg)
+		}
+	}
+	// Several commits are only picked together into the index
+	if len(commits) == 0 || len(commits) > 1 && !opts.NoCommit {
+		return usage
+	}
+	for _, commit := range commits {
+		if err := repo.CherryPickWithOptions(commit, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NEW: Merge command
+func handleMerge(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("merge")
+	if len(args) == 1 && args[0] == "--continue" {
+		return repo.ContinueMerge()
+	}
+	if len(args) == 1 && args[0] == "--abort" {
+		return repo.AbortMerge()
+	}
+
+	var opts gvc.MergeOptions
+	var commits []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--no-commit":
+			opts.NoCommit = true
+		case arg == "--commit":
+			opts.NoCommit = false
+		case arg == "-m" || arg == "--message":
+			if i+1 >= len(args) {
+				return usage
+			}
+			opts.Message = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			commits = append(commits, arg)
+		}
+	}
+	if len(commits) != 1 {
+		return usage
+	}
+	return repo.Merge(commits[0], opts)
+}
+
+// NEW: Revert command
+func handleRevert(repo *gvc.Repository, args []string) error {
+	if len(args) != 1 {
+		return commandUsage("revert")
+	}
+
+	switch args[0] {
+	case "--continue":
+		return repo.ContinueRevert()
+	case "--abort":
+		return repo.AbortRevert()
+	default:
+		return repo.Revert(args[0])
+	}
+}
+
+// NEW: Rebase command
+func handleRebase(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("rebase")
+	if len(args) == 1 && args[0] == "--continue" {
+		return repo.ContinueRebase()
+	}
+	if len(args) == 1 && args[0] == "--abort" {
+		return repo.AbortRebase()
+	}
+
+	var opts gvc.RebaseOptions
+	var upstream []string
+	for _, arg := range args {
+		switch {
+		case arg == "--autosquash":
+			opts.Autosquash = true
+		case arg == "--no-autosquash":
+			opts.NoAutosquash = true
+		case arg == "-i" || arg == "--interactive":
+			opts.Interactive = true
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			upstream = append(upstream, arg)
+		}
+	}
+	if len(upstream) != 1 {
+		return usage
+	}
+	return repo.RebaseWithOptions(upstream[0], opts)
+}
+
+// NEW: Config command
+func handleConfig(repo *gvc.Repository, args []string) error {
+	switch {
+	case len(args) == 1 && args[0] == "--list":
+		entries, err := repo.ReadConfigEntries()
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s=%s\n", entry[0], entry[1])
+		}
+		return nil
+	case len(args) == 1:
+		value, found, err := repo.GetConfig(args[0])
+		if err != nil {
+			return err
+		}
+		if !found {
+			return negativeResult()
+		}
+		fmt.Println(value)
+		return nil
+	case len(args) == 2:
+		return repo.SetConfig(args[0], args[1])
+	default:
+		return commandUsage("config")
+	}
+}
+
+// NEW: GC command
+func handleGC(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("gc")
+	var opts gvc.GCOptions
+	for _, arg := range args {
+		switch {
+		case arg == "--aggressive":
+			opts.Aggressive = true
+		case arg == "--dry-run":
+			opts.DryRun = true
+		case arg == "--prune":
+			opts.Prune, opts.PruneExpire = true, ""
+		case strings.HasPrefix(arg, "--prune="):
+			opts.Prune, opts.PruneExpire = true, strings.TrimPrefix(arg, "--prune=")
+			if opts.PruneExpire == "" {
+				return usage
+			}
+		case arg == "--no-prune":
+			opts.Prune = false
+		default:
+			return usage
+		}
+	}
+	if jsonOutput {
+		if !opts.DryRun {
+			return usageError("gc --json needs --dry-run")
+		}
+		report, err := repo.PlanGC(opts)
+		if err != nil {
+			return err
+		}
+		return printGCReport(report)
+	}
+	if err := repo.GCWithOptions(opts); err != nil {
+		return err
+	}
+	if err := printSpaceByType(repo); err != nil {
+		return err
+	}
+	// A shallow repository cannot have a commit-graph
+	if shallow, err := repo.IsShallow(); err != nil || shallow {
+		return err
+	}
+	if opts.DryRun {
+		fmt.Println("Would write the commit-graph")
+		return nil
+	}
+
+	count, err := repo.WriteCommitGraph()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote commit-graph with %d commits\n", count)
+	return nil
+}
+
+// jsonGC is the summary of a gc dry run as --json prints it, after a
+// record for each reflog entry, reflog, object and file it would delete
+type jsonGC struct {
+	jsonRecord
+	Pack          int      `json:"pack"`
+	LooseRemoved  int      `json:"loose_removed"`
+	OldPacks      []string `json:"old_packs"`
+	ExpiredReflog int      `json:"expired_reflog_entries"`
+	Pruned        int      `json:"pruned_objects"`
+	Reclaimed     int64    `json:"reclaimed"`
+}
+
+// jsonReflogEntry is a reflog entry a gc dry run would expire
+type jsonReflogEntry struct {
+	jsonRecord
+	Ref     string `json:"ref"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Ident   string `json:"ident"`
+	Date    string `json:"date"`
+	Message string `json:"message"`
+}
+
+// jsonPrunedObject is an unreachable object a gc dry run would prune
+type jsonPrunedObject struct {
+	jsonRecord
+	SHA        string `json:"sha"`
+	ObjectType string `json:"object_type"`
+	Size       int64  `json:"size"`
+}
+
+// jsonRemovedPath is a reflog or temporary file a gc dry run would remove
+type jsonRemovedPath struct {
+	jsonRecord
+	Path string `json:"path"`
+}
+
+// printGCReport prints what a gc dry run found to delete as JSON records,
+// for audit logs
+func printGCReport(report *gvc.GCReport) error {
+	var records []any
+	for _, entry := range report.ExpiredReflog {
+		records = append(records, jsonReflogEntry{
+			jsonRecord: record("reflog-entry"),
+			Ref:        entry.Ref,
+			Old:        entry.OldSHA,
+			New:        entry.NewSHA,
+			Ident:      entry.Ident,
+			Date:       entry.Timestamp.Format(time.RFC3339),
+			Message:    entry.Message,
+		})
+	}
+	for _, ref := range report.RemovedReflogs {
+		records = append(records, jsonRemovedPath{jsonRecord: record("reflog"), Path: ref})
+	}
+	pruned := 0
+	if report.Pruned != nil {
+		pruned = len(report.Pruned.Objects)
+		for _, obj := range report.Pruned.Objects {
+			records = append(records, jsonPrunedObject{jsonRecord: record("pruned-object"), SHA: obj.SHA, ObjectType: string(obj.Type), Size: obj.Size})
+		}
+		for _, name := range report.Pruned.TempFiles {
+			records = append(records, jsonRemovedPath{jsonRecord: record("temp-file"), Path: filepath.ToSlash(name)})
+		}
+		for _, name := range report.Pruned.Locks {
+			records = append(records, jsonRemovedPath{jsonRecord: record("stale-lock"), Path: filepath.ToSlash(name)})
+		}
+	}
+	oldPacks := []string{}
+	for _, path := range report.OldPacks {
+		oldPacks = append(oldPacks, filepath.Base(path))
+	}
+	records = append(records, jsonGC{
+		jsonRecord:    record("gc"),
+		Pack:          report.Pack,
+		LooseRemoved:  report.LooseRemoved,
+		OldPacks:      oldPacks,
+		ExpiredReflog: len(report.ExpiredReflog),
+		Pruned:        pruned,
+		Reclaimed:     report.Reclaimed,
+	})
+	for _, rec := range records {
+		if err := printJSON(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printSpaceByType shows how many objects of each type the repository
+// stores and the disk space they take, to point at what bloats it
+func printSpaceByType(repo *gvc.Repository) error {
+	spaces, err := repo.SpaceByType()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, space := range spaces {
+		total += space.Size
+	}
+	fmt.Println("Space by object type:")
+	for _, space := range spaces {
+		share := 0.0
+		if total > 0 {
+			share = float64(space.Size) * 100 / float64(total)
+		}
+		fmt.Printf("  %-7s %8d objects %10s %5.1f%%\n", space.Type+"s", space.Count, gvc.FormatSize(space.Size), share)
+	}
+	return nil
+}
+
+// NEW: Commit-graph command
+func handleCommitGraph(repo *gvc.Repository, args []string) error {
+	if len(args) != 1 || args[0] != "write" {
+		return commandUsage("commit-graph")
+	}
+	count, err := repo.WriteCommitGraph()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote commit-graph with %d commits\n", count)
+	return nil
+}
+
+// NEW: Merge-base command
+func handleMergeBase(repo *gvc.Repository, args []string) error {
+	all, isAncestor := false, false
+	if len(args) > 0 && args[0] == "--all" {
+		all = true
+		args = args[1:]
+	} else if len(args) > 0 && args[0] == "--is-ancestor" {
+		isAncestor = true
+		args = args[1:]
+	}
+	if len(args) != 2 {
+		return commandUsage("merge-base")
+	}
+
+	a, err := repo.ResolveCommit(args[0])
+	if err != nil {
+		return err
+	}
+	b, err := repo.ResolveCommit(args[1])
+	if err != nil {
+		return err
+	}
+	if isAncestor {
+		// Only the exit code answers, for scripts
+		ancestor, err := repo.IsAncestor(a, b)
+		if err != nil {
+			return err
+		}
+		if !ancestor {
+			return negativeResult()
+		}
+		return nil
+	}
+	bases, err := repo.MergeBases(a, b)
+	if err != nil {
+		return err
+	}
+	if len(bases) == 0 {
+		return negativeResult()
+	}
+	if !all {
+		bases = bases[:1]
+	}
+	for _, base := range bases {
+		fmt.Println(base)
+	}
+	return nil
+}
+
+// sideMarker returns the --left-right marker for a commit
+func sideMarker(revRange *gvc.RevRange, sha string) string {
+	if revRange.Left == nil {
+		return ""
+	}
+	if revRange.Left[sha] {
+		return "< "
+	}
+	return "> "
+}
+
+// NEW: Rev-list command
+func handleRevList(repo *gvc.Repository, args []string) error {
+	revListUsage := commandUsage("rev-list")
+	leftRight, count := false, false
+	objects, printOmitted := false, false
+	var filter *gvc.ObjectFilter
+	order := gvc.OrderDefault
+	var revs []string
+	for _, arg := range args {
+		switch {
+		case arg == "--left-right":
+			leftRight = true
+		case arg == "--count":
+			count = true
+		case arg == "--objects":
+			objects = true
+		case arg == "--filter-print-omitted":
+			printOmitted = true
+		case strings.HasPrefix(arg, "--filter="):
+			var err error
+			if filter, err = gvc.ParseObjectFilter(strings.TrimPrefix(arg, "--filter=")); err != nil {
+				return usageError(err.Error())
+			}
+		case revOrders[arg] != gvc.OrderDefault:
+			order = revOrders[arg]
+		default:
+			if strings.HasPrefix(arg, "--") && !isRefSelector(arg) {
+				return revListUsage
+			}
+			revs = append(revs, arg)
+		}
+	}
+	if len(revs) == 0 || (filter != nil || printOmitted) && !objects || objects && leftRight {
+		return revListUsage
+	}
+
+	revRange, err := repo.ParseRevRange(revs)
+	if err != nil {
+		return err
+	}
+	commits, err := repo.WalkRevisions(revRange)
+	if err != nil {
+		return err
+	}
+	if commits, err = repo.OrderRevisions(commits, order); err != nil {
+		return err
+	}
+	if objects {
+		return revListObjects(repo, commits, revRange.Exclude, filter, count, printOmitted)
+	}
+
+	if count {
+		if leftRight && revRange.Left != nil {
+			left := 0
+			for _, node := range commits {
+				if revRange.Left[node.SHA] {
+					left++
+				}
+			}
+			fmt.Printf("%d\t%d\n", left, len(commits)-left)
+		} else {
+			fmt.Println(len(commits))
+		}
+		return nil
+	}
+
+	for _, node := range commits {
+		marker := ""
+		if leftRight {
+			marker = strings.TrimSpace(sideMarker(revRange, node.SHA))
+		}
+		fmt.Println(marker + node.SHA)
+	}
+	return nil
+}
+
+// revListObjects prints the objects of rev-list --objects: each commit's
+// SHA, then each tree's and blob's followed by its path, and with
+// printOmitted those the filter left out as ~<sha>
+func revListObjects(repo *gvc.Repository, commits []*gvc.CommitNode, exclude []string, filter *gvc.ObjectFilter, count, printOmitted bool) error {
+	listed, err := repo.ListObjects(commits, exclude, filter)
+	if err != nil {
+		return err
+	}
+	if count {
+		n := 0
+		for _, obj := range listed {
+			if !obj.Omitted {
+				n++
+			}
+		}
+		fmt.Println(n)
+		return nil
+	}
+	for _, obj := range listed {
+		switch {
+		case !obj.Omitted && obj.Type != object.CommitObject && obj.Path != "":
+			fmt.Println(obj.SHA + " " + obj.Path)
+		case !obj.Omitted:
+			fmt.Println(obj.SHA)
+		case printOmitted:
+			fmt.Println("~" + obj.SHA)
+		}
+	}
+	return nil
+}
+
+// revOrders maps the options that choose the order of log and rev-list output to the order each selects
+var revOrders = map[string]gvc.RevOrder{
+	"--topo-order":        gvc.OrderTopo,
+	"--date-order":        gvc.OrderDate,
+	"--author-date-order": gvc.OrderAuthorDate,
+}
+
+// isRefSelector reports whether arg is --all or one of the --branches,
+// --tags and --remotes options, which log and rev-list pass on as revisions
+func isRefSelector(arg string) bool {
+	option, _, _ := strings.Cut(arg, "=")
+	switch option {
+	case "--branches", "--tags", "--remotes":
+		return true
+	}
+	return arg == "--all"
+}
+
+// authorName strips the e-mail address from an author identity
+func authorName(author string) string {
+	if i := strings.LastIndex(author, " <"); i >= 0 && strings.HasSuffix(author, ">") {
+		return author[:i]
+	}
+	return author
+}
+
+// NEW: Blame command
+func handleBlame(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("blame")
+	var rest []string
+	format := ""
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
+		case arg != "--":
+			rest = append(rest, arg)
+		}
+	}
+	rev, path := "HEAD", ""
+	switch len(rest) {
+	case 1:
+		path = rest[0]
+	case 2:
+		rev, path = rest[0], rest[1]
+	default:
+		return usage
+	}
+
+	path, err := repo.RelPath(path)
+	if err != nil {
+		return err
+	}
+	startSHA, err := repo.ResolveCommit(rev)
+	if err != nil {
+		return err
+	}
+	lines, err := repo.Blame(startSHA, path)
+	if err != nil {
+		return err
+	}
+	if format != "" {
+		return repo.WriteBlameReport(os.Stdout, path, lines, format, time.Now())
+	}
+
+	// Root commits are boundaries: their lines may predate recorded history
+	type lineInfo struct {
+		prefix string
+		author string
+		date   string
+	}
+	infos := make(map[string]lineInfo)
+	authorWidth := 0
+	for _, line := range lines {
+		if _, ok := infos[line.Commit]; ok {
+			continue
+		}
+		commit, err := repo.ReadCommit(line.Commit)
+		if err != nil {
+			return err
+		}
+		node, err := repo.LookupCommitNode(line.Commit)
+		if err != nil {
+			return err
+		}
+		prefix := line.Commit[:8]
+		if len(node.Parents) == 0 {
+			prefix = "^" + line.Commit[:7]
+		}
+		info := lineInfo{prefix: prefix, author: authorName(commit.Author),
+			date: commit.Timestamp.Format("2006-01-02 15:04:05 -0700")}
+		infos[line.Commit] = info
+		if len(info.author) > authorWidth {
+			authorWidth = len(info.author)
+		}
+	}
+
+	numberWidth := len(strconv.Itoa(len(lines)))
+	for i, line := range lines {
+		info := infos[line.Commit]
+		fmt.Printf("%s (%-*s %s %*d) %s\n", info.prefix, authorWidth, info.author, info.date,
+			numberWidth, i+1, strings.TrimSuffix(line.Content, "\n"))
+	}
+	return nil
+}
+
+var fullSHAPattern = regexp.MustCompile(`\b(?:[0-9a-f]{64}|[0-9a-f]{40})\b`)
+
+// NEW: Name-rev command
+func handleNameRev(repo *gvc.Repository, args []string) error {
+	nameOnly, annotate := false, false
+	var revs []string
+	for _, arg := range args {
+		switch arg {
+		case "--name-only":
+			nameOnly = true
+		case "--annotate-stdin", "--stdin":
+			annotate = true
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return commandUsage("name-rev")
+			}
+			revs = append(revs, arg)
+		}
+	}
+	if annotate == (len(revs) > 0) {
+		return commandUsage("name-rev")
+	}
+
+	names, err := repo.NameRevs()
+	if err != nil {
+		return err
+	}
+
+	if annotate {
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			line := fullSHAPattern.ReplaceAllStringFunc(scanner.Text(), func(sha string) string {
+				name, ok := names[sha]
+				if !ok {
+					return sha
+				}
+				if nameOnly {
+					return name.String()
+				}
+				return fmt.Sprintf("%s (%s)", sha, name)
+			})
+			fmt.Println(line)
+		}
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+		return nil
+	}
+
+	for _, rev := range revs {
+		sha, err := repo.ResolveCommit(rev)
+		if err != nil {
+			return err
+		}
+		name := "undefined"
+		if n, ok := names[sha]; ok {
+			name = n.String()
+		}
+		if nameOnly {
+			fmt.Println(name)
+		} else {
+			fmt.Printf("%s %s\n", rev, name)
+		}
+	}
+	return nil
+}
+
+// NEW: Backup command
+func handleBackup(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("backup")
+	if len(args) == 0 {
+		return usage
+	}
+	if args[0] == "restore" {
+		if len(args) < 2 {
+			return usage
+		}
+		for _, path := range args[1:] {
+			if err := repo.RestoreBackup(path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(args) != 1 || strings.HasPrefix(args[0], "-") {
+		return usage
+	}
+	return repo.Backup(args[0])
+}
+
+// NEW: Branch command
+func handleBranch(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("branch")
+
+	var filter, filterRev, sortKey, into string
+	var names []string
+	deleteMode, force := false, false
+	staleBefore, yes := "", false
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "-d", "--delete":
+			deleteMode = true
+		case "-D":
+			deleteMode, force = true, true
+		case "--merged", "--no-merged", "--contains":
+			filter = arg
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				filterRev = args[i+1]
+				i++
+			} else if arg == "--contains" {
+				filterRev = "HEAD"
+			}
+		case "--stale":
+			staleBefore = "3.months.ago"
+		case "--into":
+			if i+1 >= len(args) {
+				return usage
+			}
+			into = args[i+1]
+			i++
+		case "-y", "--yes":
+			yes = true
+		case "--sort":
+			if i+1 >= len(args) {
+				return usage
+			}
+			sortKey = args[i+1]
+			i++
+		default:
+			if value, ok := strings.CutPrefix(arg, "--sort="); ok {
+				sortKey = value
+				continue
+			}
+			if value, ok := strings.CutPrefix(arg, "--stale="); ok && value != "" {
+				staleBefore = value
+				continue
+			}
+			if strings.HasPrefix(arg, "-") {
+				return usage
+			}
+			names = append(names, arg)
+		}
+	}
+
+	if staleBefore != "" {
+		if len(names) > 0 || force || filter != "" || sortKey != "" {
+			return usage
+		}
+		return staleBranches(repo, staleBefore, into, deleteMode, yes)
+	}
+	if into != "" || yes {
+		return usage
+	}
+
+	if deleteMode {
+		if len(names) == 0 || filter != "" || sortKey != "" {
+			return usage
+		}
+		for _, name := range names {
+			if err := repo.DeleteBranch(name, force); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(names) > 0 {
+		if len(names) > 2 || filter != "" || sortKey != "" {
+			return usage
+		}
+		start := "HEAD"
+		if len(names) == 2 {
+			start = names[1]
+		}
+		return repo.CreateBranch(names[0], start)
+	}
+
+	var filterSHA string
+	var contains *gvc.ContainsCache
+	if filter != "" {
+		if filterRev == "" {
+			filterRev = "HEAD"
+		}
+		sha, err := repo.ResolveCommit(filterRev)
+		if err != nil {
+			return err
+		}
+		filterSHA = sha
+		// The walks of all branches share what they learn
+		if filter == "--contains" {
+			if contains, err = repo.NewContainsCache(sha); err != nil {
+				return err
+			}
+		}
+	}
+
+	branches, err := repo.ListBranchInfo(sortKey)
+	if err != nil {
+		return err
+	}
+	current, err := repo.HeadRef()
+	if err != nil {
+		return err
+	}
+	for _, branch := range branches {
+		name, tip := branch.Name, branch.SHA
+		if filter != "" {
+			var keep bool
+			switch filter {
+			case "--merged":
+				keep, err = repo.IsAncestor(tip, filterSHA)
+			case "--no-merged":
+				keep, err = repo.IsAncestor(tip, filterSHA)
+				keep = !keep
+			case "--contains":
+				keep, err = contains.ReachedFrom(tip)
+			}
+			if err != nil {
+				return err
+			}
+			if !keep {
+				continue
+			}
+		}
+
+		if jsonOutput {
+			if err := printJSON(jsonBranch{record("branch"), name, tip, current == "refs/heads/"+name,
+				branch.Committed.Format(time.RFC3339), branch.Created.Format(time.RFC3339), branch.Updated.Format(time.RFC3339)}); err != nil {
+				return err
+			}
+			continue
+		}
+		marker := "  "
+		if current == "refs/heads/"+name {
+			marker = "* "
+		}
+		fmt.Println(marker + name)
+	}
+	return nil
+}
+
+// staleBranches lists the branches merged into into, the default branch
+// when empty, whose tips were committed before the date before names, and
+// with remove deletes them once confirmed on standard input, or at once with
+// yes
+func staleBranches(repo *gvc.Repository, before, into string, remove, yes bool) error {
+	now := time.Now()
+	cutoff, err := gvc.ParseDate(before, now)
+	if err != nil {
+		return usageError(err.Error())
+	}
+	branches, err := repo.StaleBranches(cutoff, into)
+	if err != nil {
+		return err
+	}
+	if len(branches) == 0 {
+		if remove {
+			fmt.Println("No stale branches.")
+		}
+		return nil
+	}
+	width := 0
+	for _, branch := range branches {
+		width = max(width, len(branch.Name))
+	}
+	for _, branch := range branches {
+		fmt.Printf("  %-*s %s  %s\n", width, branch.Name, branch.SHA[:min(7, len(branch.SHA))], gvc.RelativeDate(branch.Committed, now))
+	}
+	if !remove {
+		return nil
+	}
+
+	if !yes {
+		noun := "branches"
+		if len(branches) == 1 {
+			noun = "branch"
+		}
+		fmt.Printf("Delete these %d %s? [y/N] ", len(branches), noun)
+		answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer != "y" && answer != "yes" {
+			fmt.Println("Nothing deleted.")
+			return nil
+		}
+	}
+	for _, branch := range branches {
+		// Each was checked to be merged into the default branch, whatever HEAD is
+		if err := repo.DeleteBranch(branch.Name, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NEW: Switch command
+func handleSwitch(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("switch")
+	if len(args) > 0 && args[0] == "--orphan" {
+		keep := len(args) == 3 && args[2] == "--keep"
+		if (len(args) != 2 && !keep) || strings.HasPrefix(args[1], "-") {
+			return usage
+		}
+		return repo.SwitchOrphan(args[1], keep)
+	}
+	create := false
+	if len(args) > 0 && (args[0] == "-c" || args[0] == "--create") {
+		create = true
+		args = args[1:]
+	}
+	if len(args) == 0 || len(args) > 2 || (!create && len(args) != 1) || strings.HasPrefix(args[0], "-") {
+		return usage
+	}
+
+	start := "HEAD"
+	if len(args) == 2 {
+		start = args[1]
+	}
+	return repo.SwitchBranch(args[0], create, start)
+}
+
+// NEW: Repair command
+func handleRepair(repo *gvc.Repository, args []string) error {
+	if len(args) != 0 {
+		return commandUsage("repair")
+	}
+	report, err := repo.Repair()
+	if err != nil {
+		return err
+	}
+	if len(report.Broken) == 0 {
+		return nil
+	}
+
+	fmt.Printf("\n%d objects could not be restored:\n", len(report.Broken))
+	for _, sha := range report.Broken {
+		fmt.Println("  " + sha)
+	}
+	fmt.Println("\nTo finish recovering:")
+	fmt.Println("  - restore the objects from a backup with 'gvc backup restore <file>' or copy them from")
+	fmt.Println("    another copy of the repository, then run 'gvc repair' again")
+	if len(report.Rescued) > 0 {
+		fmt.Printf("  - the damaged refs were moved under %s; history up to the damage is still\n", gvc.RescueRefsDir)
+		fmt.Println("    readable there, and a branch can be recreated from an intact commit with 'gvc branch'")
+	}
+	return negativeResult()
+}
+
+// NEW: Remote command
+func handleRemote(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("remote")
+	if len(args) > 0 && args[0] == "set-head" {
+		if len(args) != 3 {
+			return usage
+		}
+		switch name, arg := args[1], args[2]; {
+		case arg == "-a" || arg == "--auto":
+			branch, err := repo.SetRemoteHeadAuto(name)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("%s/HEAD set to %s\n", name, strings.TrimPrefix(branch, "refs/heads/"))
+			return nil
+		case arg == "-d" || arg == "--delete":
+			return repo.SetRemoteHead(name, "")
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			return repo.SetRemoteHead(name, arg)
+		}
+	}
+	if len(args) > 0 && args[0] == "get-url" {
+		push := len(args) == 3 && args[1] == "--push"
+		if len(args) != 2 && !push {
+			return usage
+		}
+		remote, err := repo.LookupRemote(args[len(args)-1])
+		if err != nil {
+			return err
+		}
+		if push {
+			fmt.Println(remote.PushURL)
+		} else {
+			fmt.Println(remote.URL)
+		}
+		return nil
+	}
+
+	verbose := len(args) == 1 && args[0] == "-v"
+	if len(args) > 0 && !verbose {
+		return usage
+	}
+	remotes, err := repo.Remotes()
+	if err != nil {
+		return err
+	}
+	for _, remote := range remotes {
+		if verbose {
+			fmt.Printf("%s\t%s (fetch)\n%s\t%s (push)\n", remote.Name, remote.URL, remote.Name, remote.PushURL)
+		} else {
+			fmt.Println(remote.Name)
+		}
+	}
+	return nil
+}
+
+// NEW: Fsck command
+func handleFsck(repo *gvc.Repository, args []string) error {
+	strict, unreachable := false, false
+	for _, arg := range args {
+		switch arg {
+		case "--strict":
+			strict = true
+		case "--unreachable":
+			unreachable = true
+		default:
+			return commandUsage("fsck")
+		}
+	}
+
+	report, err := repo.Fsck()
+	if err != nil {
+		return err
+	}
+	for _, problem := range report.Problems {
+		fmt.Println(problem.Message)
+	}
+	listed := report.Dangling
+	label := "dangling"
+	if unreachable {
+		listed, label = report.Unreachable, "unreachable"
+	}
+	for _, obj := range listed {
+		fmt.Printf("%s %s %s\n", label, obj.Type, obj.SHA)
+	}
+
+	if report.Failed(strict) {
+		return negativeResult()
+	}
+	return nil
+}
+
+// NEW: Clone command
+func handleClone(args []string) error {
+	usage := commandUsage("clone")
+	var opts gvc.CloneOptions
+	opts.Out, opts.Progress = outputWriters()
+	opts.Verbose = verbosity == verbose
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--no-hardlinks":
+			opts.NoHardlinks = true
+		case arg == "--recurse-submodules":
+			opts.RecurseSubmodules = true
+		case arg == "--depth" || strings.HasPrefix(arg, "--depth="):
+			value, ok := strings.CutPrefix(arg, "--depth=")
+			if !ok {
+				if i+1 == len(args) {
+					return usage
+				}
+				i++
+				value = args[i]
+			}
+			depth, err := parseDepth(value)
+			if err != nil {
+				return err
+			}
+			opts.Depth = depth
+		case arg == "--limit-rate" || strings.HasPrefix(arg, "--limit-rate="):
+			value, ok := strings.CutPrefix(arg, "--limit-rate=")
+			if !ok {
+				if i+1 == len(args) {
+					return usage
+				}
+				i++
+				value = args[i]
+			}
+			rate, err := parseLimitRate(value)
+			if err != nil {
+				return err
+			}
+			opts.LimitRate = rate
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) < 1 || len(positional) > 2 {
+		return usage
+	}
+	dir := ""
+	if len(positional) == 2 {
+		dir = positional[1]
+	}
+
+	opts.Context = interruptContext()
+	repo, err := gvc.Clone(positional[0], dir, opts)
+	if err != nil {
+		return err
+	}
+	if verbosity != quiet {
+		fmt.Printf("Cloned into '%s'\n", repo.Root)
+	}
+	return nil
+}
+
+// NEW: Fetch command
+func handleFetch(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("fetch")
+	var opts gvc.FetchOptions
+	remote := "origin"
+	all, jobsGiven := false, false
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--all":
+			all = true
+		case arg == "-j" || arg == "--jobs" || strings.HasPrefix(arg, "--jobs="):
+			value, ok := strings.CutPrefix(arg, "--jobs=")
+			if !ok {
+				if i+1 == len(args) {
+					return usage
+				}
+				i++
+				value = args[i]
+			}
+			jobs, err := strconv.Atoi(value)
+			if err != nil || jobs < 0 {
+				return usageError(fmt.Sprintf("jobs %s is not a number of jobs", value))
+			}
+			opts.Jobs, jobsGiven = jobs, true
+		case arg == "--no-hardlinks":
+			opts.NoHardlinks = true
+		case arg == "--dry-run":
+			opts.DryRun = true
+		case arg == "--unshallow":
+			opts.Unshallow = true
+		case arg == "--depth" || strings.HasPrefix(arg, "--depth="):
+			value, ok := strings.CutPrefix(arg, "--depth=")
+			if !ok {
+				if i+1 == len(args) {
+					return usage
+				}
+				i++
+				value = args[i]
+			}
+			depth, err := parseDepth(value)
+			if err != nil {
+				return err
+			}
+			opts.Depth = depth
+		case arg == "--limit-rate" || strings.HasPrefix(arg, "--limit-rate="):
+			value, ok := strings.CutPrefix(arg, "--limit-rate=")
+			if !ok {
+				if i+1 == len(args) {
+					return usage
+				}
+				i++
+				value = args[i]
+			}
+			rate, err := parseLimitRate(value)
+			if err != nil {
+				return err
+			}
+			opts.LimitRate = rate
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) > 1 || opts.Unshallow && opts.Depth > 0 || all && len(positional) > 0 {
+		return usage
+	}
+	if all {
+		return repo.FetchAll(opts)
+	}
+	if jobsGiven {
+		return usage
+	}
+	if len(positional) == 1 {
+		remote = positional[0]
+	}
+	return repo.Fetch(remote, opts)
+}
+
+// NEW: Push command
+func handlePush(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("push")
+	var opts gvc.PushOptions
+	remote := ""
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-f" || arg == "--force":
+			opts.Force = true
+		case arg == "-n" || arg == "--dry-run":
+			opts.DryRun = true
+		case arg == "-u" || arg == "--set-upstream":
+			opts.SetUpstream = true
+		case arg == "--no-hardlinks":
+			opts.NoHardlinks = true
+		case arg == "--no-verify":
+			opts.NoVerify = true
+		case arg == "--limit-rate" || strings.HasPrefix(arg, "--limit-rate="):
+			value, ok := strings.CutPrefix(arg, "--limit-rate=")
+			if !ok {
+				if i+1 == len(args) {
+					return usage
+				}
+				i++
+				value = args[i]
+			}
+			rate, err := parseLimitRate(value)
+			if err != nil {
+				return err
+			}
+			opts.LimitRate = rate
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) > 0 {
+		remote, positional = positional[0], positional[1:]
+	}
+	return repo.Push(remote, positional, opts)
+}
+
+// NEW: Verify-commit command
+func handleVerifyCommit(repo *gvc.Repository, args []string) error {
+	verbose := false
+	var revs []string
+	for _, arg := range args {
+		switch {
+		case arg == "-v" || arg == "--verbose":
+			verbose = true
+		case strings.HasPrefix(arg, "-"):
+			return commandUsage("verify-commit")
+		default:
+			revs = append(revs, arg)
+		}
+	}
+	if len(revs) == 0 {
+		return commandUsage("verify-commit")
+	}
+
+	failed := false
+	for _, rev := range revs {
+		sha, err := repo.ResolveCommit(rev)
+		if err != nil {
+			return err
+		}
+		check, err := repo.VerifyCommit(sha)
+		if err != nil {
+			return err
+		}
+		if verbose {
+			if err := catFile(repo, sha); err != nil {
+				return err
+			}
+		}
+		if check.Status == gvc.SignatureNone {
+			fmt.Fprintf(os.Stderr, "commit %s has no signature\n", sha)
+		}
+		fmt.Fprint(os.Stderr, check.Output)
+		if !check.Verified() {
+			failed = true
+		}
+	}
+	if failed {
+		return negativeResult()
+	}
+	return nil
+}
+
+// NEW: Show command
+func handleShow(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("show")
+	showSignature, stat := false, false
+	notesOpts := notesDisplay{defaults: true}
+	renames := -1
+	var revs []string
+	for _, arg := range args {
+		if threshold, ok, err := renameOption(arg); ok {
+			if err != nil {
+				return usage
+			}
+			renames = threshold
+			continue
+		}
+		switch {
+		case arg == "--show-signature":
+			showSignature = true
+		case arg == "--stat":
+			stat = true
+		case notesOpts.parse(arg):
+			if notesOpts.invalid {
+				return usage
+			}
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			revs = append(revs, arg)
+		}
+	}
+	if len(revs) == 0 {
+		revs = []string{"HEAD"}
+	}
+	notes, err := notesOpts.load(repo)
+	if err != nil {
+		return err
+	}
+
+	for i, rev := range revs {
+		sha, err := repo.ResolveObject(rev)
+		if err != nil {
+			return err
+		}
+		objectType, content, err := repo.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		if i > 0 && objectType != object.BlobObject {
+			fmt.Println()
+		}
+
+		switch objectType {
+		case object.CommitObject:
+			commit, err := repo.ReadCommit(sha)
+			if err != nil {
+				return err
+			}
+			text, err := mediumCommit(repo, commit, "", showSignature, notes)
+			if err != nil {
+				return err
+			}
+			fmt.Print(text)
+
+			// The patch is against the first parent; a root commit adds everything
+			parentTree := ""
+			if commit.ParentSHA != "" {
+				parent, err := repo.ReadCommit(commit.ParentSHA)
+				if err != nil {
+					return err
+				}
+				parentTree = parent.TreeSHA
+			}
+			changes, err := repo.DiffTrees(parentTree, commit.TreeSHA)
+			if err != nil {
+				return err
+			}
+			if changes, err = detectRenames(repo, changes, renames); err != nil {
+				return err
+			}
+			if len(changes) > 0 {
+				fmt.Println()
+				if stat {
+					err = repo.WriteDiffStat(os.Stdout, changes)
+				} else {
+					err = repo.WritePatch(os.Stdout, changes)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		case object.TreeObject:
+			entries, err := repo.Format.ParseTree(content)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("tree %s\n\n", rev)
+			for _, entry := range entries {
+				if entry.Type == object.TreeObject {
+					fmt.Println(entry.Name + "/")
+				} else {
+					fmt.Println(entry.Name)
+				}
+			}
+		default:
+			os.Stdout.Write(content)
+		}
+	}
+	return nil
+}
+
+// NEW: Ls-files command
+func handleLsFiles(repo *gvc.Repository, args []string) error {
+	cached, stage, modified, others, ignored := false, false, false, false, false
+	for _, arg := range args {
+		switch arg {
+		case "-i", "--ignored":
+			ignored = true
+		case "-c", "--cached":
+			cached = true
+		case "-s", "--stage":
+			stage = true
+		case "-m", "--modified":
+			modified = true
+		case "-o", "--others":
+			others = true
+		default:
+			return commandUsage("ls-files")
+		}
+	}
+	if ignored && !others {
+		return usageError("ls-files --ignored needs --others")
+	}
+	// Like Git, the staged files are listed unless only other kinds are asked for
+	if stage || (!modified && !others) {
+		cached = true
+	}
+
+	if cached {
+		entries, err := repo.StagedFiles()
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if jsonOutput {
+				if err := printJSON(jsonFile{record("file"), entry.Path, "cached", entry.Mode, entry.SHA}); err != nil {
+					return err
+				}
+			} else if stage {
+				fmt.Printf("%s %s 0\t%s\n", entry.Mode, entry.SHA, entry.Path)
+			} else {
+				fmt.Println(entry.Path)
+			}
+		}
+	}
+	if modified {
+		paths, err := repo.ModifiedFiles()
+		if err != nil {
+			return err
+		}
+		if err := printPaths(paths, "modified"); err != nil {
+			return err
+		}
+	}
+	if others {
+		paths, err := repo.UntrackedFiles(ignored)
+		if err != nil {
+			return err
+		}
+		status := "other"
+		if ignored {
+			status = "ignored"
+		}
+		if err := printPaths(paths, status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// printPaths prints the paths ls-files found, as records of the given
+// status with --json
+func printPaths(paths []string, status string) error {
+	for _, path := range paths {
+		if !jsonOutput {
+			fmt.Println(path)
+		} else if err := printJSON(jsonFile{jsonRecord: record("file"), Path: path, Status: status}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NEW: Check-ignore command
+func handleCheckIgnore(repo *gvc.Repository, args []string) error {
+	verbose := false
+	var paths []string
+	for _, arg := range args {
+		switch {
+		case arg == "-v" || arg == "--verbose":
+			verbose = true
+		case strings.HasPrefix(arg, "-"):
+			return commandUsage("check-ignore")
+		default:
+			paths = append(paths, arg)
+		}
+	}
+	if len(paths) == 0 {
+		return commandUsage("check-ignore")
+	}
+
+	ig, err := repo.LoadIgnore()
+	if err != nil {
+		return err
+	}
+	matched := false
+	for _, path := range paths {
+		rel, err := repo.RelPath(path)
+		if err != nil {
+			return err
+		}
+		info, err := os.Stat(path)
+		rule, err := ig.Match(rel, err == nil && info.IsDir())
+		if err != nil {
+			return err
+		}
+		if rule == nil || rule.Negate() {
+			continue
+		}
+		matched = true
+		if verbose {
+			fmt.Printf("%s:%d:%s\t%s\n", rule.Source, rule.Line, rule.Pattern, path)
+		} else {
+			fmt.Println(path)
+		}
+	}
+	if !matched {
+		return negativeResult()
+	}
+	return nil
+}
+
+// NEW: Check-ref-format command
+func handleCheckRefFormat(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("check-ref-format")
+	if len(args) == 2 && args[0] == "--branch" {
+		name, err := repo.ExpandBranchName(args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Println(name)
+		return nil
+	}
+
+	var opts gvc.RefFormatOptions
+	var names []string
+	for _, arg := range args {
+		switch {
+		case arg == "--normalize" || arg == "--print":
+			opts.Normalize = true
+		case arg == "--allow-onelevel":
+			opts.AllowOneLevel = true
+		case arg == "--no-allow-onelevel":
+			opts.AllowOneLevel = false
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			names = append(names, arg)
+		}
+	}
+	if len(names) != 1 {
+		return usage
+	}
+
+	name, err := gvc.CheckRefFormat(names[0], opts)
+	if err != nil {
+		return negativeResult()
+	}
+	if opts.Normalize {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+// NEW: Diff command
+func handleDiff(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("diff")
+	cached, stat, exitCode := false, false, false
+	renames := -1
+	ignore := gvc.SubmoduleIgnore(-1)
+	submoduleFormat, _, err := repo.GetConfig("diff.submodule")
+	if err != nil {
+		return err
+	}
+	opts, err := repo.DiffPatchOptions()
+	if err != nil {
+		return err
+	}
+	relative := false
+	var revs []string
+	for _, arg := range args {
+		if threshold, ok, err := renameOption(arg); ok {
+			if err != nil {
+				return usage
+			}
+			renames = threshold
+			continue
+		}
+		if level, ok, err := ignoreSubmodulesOption(arg); ok {
+			if err != nil {
+				return usageError(err.Error())
+			}
+			ignore = level
+			continue
+		}
+		switch {
+		case arg == "--submodule":
+			submoduleFormat = "log"
+		case strings.HasPrefix(arg, "--submodule="):
+			submoduleFormat = strings.TrimPrefix(arg, "--submodule=")
+		case arg == "--cached" || arg == "--staged":
+			cached = true
+		case arg == "--stat":
+			stat = true
+		case arg == "--exit-code":
+			exitCode = true
+		case arg == "--relative":
+			relative = true
+		case strings.HasPrefix(arg, "--relative="):
+			relative, opts.Relative = false, strings.TrimPrefix(arg, "--relative=")
+		case arg == "--no-relative":
+			relative, opts.Relative = false, ""
+		case strings.HasPrefix(arg, "--src-prefix="):
+			opts.SrcPrefix, opts.NoPrefix = strings.TrimPrefix(arg, "--src-prefix="), false
+		case strings.HasPrefix(arg, "--dst-prefix="):
+			opts.DstPrefix, opts.NoPrefix = strings.TrimPrefix(arg, "--dst-prefix="), false
+		case arg == "--no-prefix":
+			opts.NoPrefix = true
+		case arg == "--default-prefix":
+			opts.SrcPrefix, opts.DstPrefix, opts.NoPrefix = "a/", "b/", false
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			revs = append(revs, arg)
+		}
+	}
+	if len(revs) > 2 || (cached && len(revs) > 1) {
+		return usage
+	}
+	if relative {
+		// Plain --relative means the current directory
+		if opts.Relative, err = repo.RelPath("."); err != nil {
+			return err
+		}
+	}
+	switch submoduleFormat {
+	case "", "short":
+	case "log":
+		opts.SubmoduleLog = true
+	default:
+		return usageError(fmt.Sprintf("unknown submodule format: %s", submoduleFormat))
+	}
+
+	merged, unmerged, err := diffChanges(repo, cached, revs, renames, ignore)
+	if err != nil {
+		return err
+	}
+
+	merged = slices.DeleteFunc(merged, func(change gvc.FileChange) bool { return !opts.Includes(change.Path) })
+	unmerged = slices.DeleteFunc(unmerged, func(path string) bool { return !opts.Includes(path) })
+	if stat {
+		if len(merged) > 0 {
+			if err := repo.WriteDiffStatWithOptions(os.Stdout, merged, opts); err != nil {
+				return err
+			}
+		}
+	} else {
+		if len(unmerged) > 0 {
+			if err := repo.WriteCombinedPatchWithOptions(os.Stdout, unmerged, opts); err != nil {
+				return err
+			}
+		}
+		if err := repo.WritePatchWithOptions(os.Stdout, merged, opts); err != nil {
+			return err
+		}
+	}
+
+	if exitCode && len(merged)+len(unmerged) > 0 {
+		return negativeResult()
+	}
+	return nil
+}
+
+// diffChanges lists the changes diff shows: from the index or a commit to
+// the working tree, or to the index when cached, or between two commits.
+// Conflicted paths of the working tree are returned apart, as unmerged.
+// Submodules are shown as ignore says, or as diff.ignoreSubmodules does
+// when it is negative.
+func diffChanges(repo *gvc.Repository, cached bool, revs []string, renames int, ignore gvc.SubmoduleIgnore) ([]gvc.FileChange, []string, error) {
+	var changes []gvc.FileChange
+	var unmerged []string
+	var err error
+	switch {
+	case len(revs) == 2:
+		var trees [2]string
+		for i, rev := range revs {
+			sha, err := repo.ResolveCommit(rev)
+			if err != nil {
+				return nil, nil, err
+			}
+			commit, err := repo.ReadCommit(sha)
+			if err != nil {
+				return nil, nil, err
+			}
+			trees[i] = commit.TreeSHA
+		}
+		changes, err = repo.DiffTrees(trees[0], trees[1])
+	case cached:
+		changes, err = repo.DiffIndex(strings.Join(revs, ""))
+	default:
+		if changes, err = repo.DiffWorkingTree(strings.Join(revs, "")); err == nil && len(revs) == 0 {
+			unmerged, err = repo.UnmergedPaths()
+		}
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if changes, err = detectRenames(repo, changes, renames); err != nil {
+		return nil, nil, err
+	}
+	if changes, err = ignoreSubmodules(repo, changes, ignore, !cached && len(revs) < 2); err != nil {
+		return nil, nil, err
+	}
+
+	// Conflicted paths get a combined diff against both sides instead
+	isUnmerged := make(map[string]bool, len(unmerged))
+	for _, path := range unmerged {
+		isUnmerged[path] = true
+	}
+	var merged []gvc.FileChange
+	for _, change := range changes {
+		if !isUnmerged[change.Path] {
+			merged = append(merged, change)
+		}
+	}
+	return merged, unmerged, nil
+}
+
+// NEW: Submodule command
+func handleSubmodule(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("submodule")
+	subcommand := "status"
+	if len(args) > 0 {
+		subcommand, args = args[0], args[1:]
+	}
+
+	switch subcommand {
+	case "status":
+		if len(args) > 0 {
+			return usage
+		}
+		submodules, err := repo.Submodules()
+		if err != nil {
+			return err
+		}
+		staged, err := repo.StagedFiles()
+		if err != nil {
+			return err
+		}
+		recorded := make(map[string]string)
+		for _, entry := range staged {
+			if entry.Mode == gvc.GitlinkMode {
+				recorded[entry.Path] = entry.SHA
+			}
+		}
+		for _, sub := range submodules {
+			head, err := repo.SubmoduleHead(sub)
+			if err != nil {
+				return err
+			}
+			// "-" marks a submodule not cloned yet, "+" one checked out at another commit
+			switch {
+			case head == "":
+				fmt.Printf("-%s %s\n", recorded[sub.Path], sub.Path)
+			case head != recorded[sub.Path]:
+				fmt.Printf("+%s %s\n", head, sub.Path)
+			default:
+				fmt.Printf(" %s %s\n", head, sub.Path)
+			}
+		}
+		return nil
+	case "init":
+		if len(args) > 0 {
+			return usage
+		}
+		return repo.InitSubmodules()
+	case "update":
+		var opts gvc.SubmoduleUpdateOptions
+		for _, arg := range args {
+			switch arg {
+			case "--init":
+				opts.Init = true
+			case "--recursive":
+				opts.Recursive = true
+			case "--no-hardlinks":
+				opts.NoHardlinks = true
+			default:
+				return usage
+			}
+		}
+		return repo.UpdateSubmodules(opts)
+	}
+	return usage
+}
+
+// NEW: Archive command
+func handleArchive(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("archive")
+	var opts gvc.ArchiveOptions
+	var output, rev string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "--format="):
+			opts.Format = strings.TrimPrefix(arg, "--format=")
+		case strings.HasPrefix(arg, "--prefix="):
+			opts.Prefix = strings.TrimPrefix(arg, "--prefix=")
+		case strings.HasPrefix(arg, "--output="):
+			output = strings.TrimPrefix(arg, "--output=")
+		case arg == "-o" || arg == "--output":
+			if i+1 == len(args) {
+				return usage
+			}
+			i++
+			output = args[i]
+		case strings.HasPrefix(arg, "-") || rev != "":
+			return usage
+		default:
+			rev = arg
+		}
+	}
+	if rev == "" {
+		return usage
+	}
+	if opts.Format == "" {
+		opts.Format = gvc.ArchiveFormatFor(output)
+	}
+
+	if output == "" {
+		// The archive goes to stdout, so warnings must not
+		repo.Out = os.Stderr
+		return repo.Archive(os.Stdout, rev, opts)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	if err := repo.Archive(f, rev, opts); err != nil {
+		f.Close()
+		os.Remove(output)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	return nil
+}
+
+// NEW: Bundle command
+func handleBundle(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("bundle")
+	if len(args) < 2 {
+		return usage
+	}
+	subcommand, path := args[0], args[1]
+	if subcommand == "create" {
+		if len(args) < 3 {
+			return usage
+		}
+		return repo.CreateBundle(path, args[2:])
+	}
+	if len(args) != 2 {
+		return usage
+	}
+
+	switch subcommand {
+	case "verify":
+		bundle, err := repo.VerifyBundle(path)
+		if err != nil {
+			return err
+		}
+		printBundleList("The bundle contains %s:\n", bundle.Refs)
+		if len(bundle.Prerequisites) == 0 {
+			fmt.Println("The bundle records a complete history.")
+		} else {
+			var prerequisites []gvc.Ref
+			for _, sha := range bundle.Prerequisites {
+				prerequisites = append(prerequisites, gvc.Ref{SHA: sha})
+			}
+			printBundleList("The bundle requires %s:\n", prerequisites)
+		}
+		fmt.Printf("%s is okay\n", path)
+		return nil
+	case "list-heads":
+		bundle, err := gvc.ReadBundleHeader(path)
+		if err != nil {
+			return err
+		}
+		for _, ref := range bundle.Refs {
+			fmt.Printf("%s %s\n", ref.SHA, ref.Name)
+		}
+		return nil
+	case "unbundle":
+		refs, err := repo.Unbundle(path)
+		if err != nil {
+			return err
+		}
+		for _, ref := range refs {
+			fmt.Printf("%s %s\n", ref.SHA, ref.Name)
+		}
+		return nil
+	}
+	return usage
+}
+
+// printBundleList prints refs under a heading that counts them the way Git
+// does: "this ref" for one, "these N refs" for more
+func printBundleList(heading string, refs []gvc.Ref) {
+	count := "this ref"
+	if len(refs) != 1 {
+		count = fmt.Sprintf("these %d refs", len(refs))
+	}
+	fmt.Printf(heading, count)
+	for _, ref := range refs {
+		fmt.Println(strings.TrimSpace(ref.SHA + " " + ref.Name))
+	}
+}
+
+// NEW: Grep command
+func handleGrep(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("grep")
+	lineNumbers, ignoreCase := false, false
+	var opts gvc.GrepOptions
+	var rest, paths []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--":
+			paths = append(paths, args[i+1:]...)
+			i = len(args)
+		case arg == "-n" || arg == "--line-number":
+			lineNumbers = true
+		case arg == "-i" || arg == "--ignore-case":
+			ignoreCase = true
+		case arg == "--untracked":
+			opts.Untracked = true
+		case arg == "-e" && i+1 < len(args) && len(rest) == 0:
+			i++
+			rest = append(rest, args[i])
+		case strings.HasPrefix(arg, "-") && len(rest) == 0:
+			return usage
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	if len(rest) == 0 {
+		return usage
+	}
+
+	expr := rest[0]
+	if ignoreCase {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	opts.Pattern = pattern
+
+	// The word after the pattern is a revision when it names a commit
+	rest = rest[1:]
+	if len(rest) > 0 {
+		if _, err := repo.ResolveCommit(rest[0]); err == nil {
+			opts.Rev, rest = rest[0], rest[1:]
+		}
+	}
+	if opts.Rev != "" && opts.Untracked {
+		return usageError("--untracked cannot be used with a revision")
+	}
+	for _, path := range append(rest, paths...) {
+		rel, err := repo.RelPath(path)
+		if err != nil {
+			return err
+		}
+		opts.Paths = append(opts.Paths, rel)
+	}
+
+	matches, err := repo.Grep(opts)
+	if err != nil {
+		return err
+	}
+	prefix := ""
+	if opts.Rev != "" {
+		prefix = opts.Rev + ":"
+	}
+	for _, match := range matches {
+		switch {
+		case match.Binary:
+			fmt.Printf("Binary file %s%s matches\n", prefix, match.Path)
+		case lineNumbers:
+			fmt.Printf("%s%s:%d:%s\n", prefix, match.Path, match.Line, match.Content)
+		default:
+			fmt.Printf("%s%s:%s\n", prefix, match.Path, match.Content)
+		}
+	}
+	if len(matches) == 0 {
+		return negativeResult()
+	}
+	return nil
+}
+
+// NEW: Stats command
+func handleStats(repo *gvc.Repository, args []string) error {
+	top := 10
+	for _, arg := range args {
+		value, ok := strings.CutPrefix(arg, "--top=")
+		if !ok {
+			return commandUsage("stats")
+		}
+		if top, ok = parseCount(value); !ok {
+			return commandUsage("stats")
+		}
+	}
+
+	stats, err := repo.Stats(top)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Commits: %d\n", stats.Commits)
+	if len(stats.Authors) > 0 {
+		fmt.Println("\nCommits per author:")
+		for _, author := range stats.Authors {
+			fmt.Printf("  %6d  %s\n", author.Commits, author.Name)
+		}
+	}
+	if len(stats.Months) > 0 {
+		fmt.Println("\nCommits and new blobs per month:")
+		for _, month := range stats.Months {
+			fmt.Printf("  %s  %6d commits  %6d blobs  %10s\n", month.Month, month.Commits, month.NewBlobs, gvc.FormatSize(month.BlobBytes))
+		}
+	}
+	if len(stats.FileTypes) > 0 {
+		fmt.Println("\nFile types at HEAD:")
+		for _, fileType := range stats.FileTypes {
+			ext := fileType.Extension
+			if ext == "" {
+				ext = "(none)"
+			}
+			fmt.Printf("  %-12s %6d files  %10s\n", ext, fileType.Files, gvc.FormatSize(fileType.Bytes))
+		}
+	}
+	if len(stats.LargestBlobs) > 0 {
+		fmt.Println("\nLargest blobs:")
+		for _, blob := range stats.LargestBlobs {
+			fmt.Printf("  %10s  %s  %s\n", gvc.FormatSize(blob.Size), blob.SHA[:7], blob.Path)
+		}
+	}
+	store := stats.Store
+	fmt.Println("\nObject store:")
+	fmt.Printf("  loose objects:  %d (%s)\n", store.LooseObjects, gvc.FormatSize(store.LooseBytes))
+	fmt.Printf("  packs:          %d (%d objects, %s)\n", store.Packs, store.PackedObjects, gvc.FormatSize(store.PackBytes))
+	fmt.Printf("  total on disk:  %s\n", gvc.FormatSize(store.LooseBytes+store.PackBytes))
+	return nil
+}
+
+// renameOption parses the rename detection options of diff and show: -M and
+// --find-renames with an optional threshold, and --no-renames, which gives
+// 0. As in Git, a threshold without "%" is a fraction, so -M5 is 50%.
+func renameOption(arg string) (int, bool, error) {
+	switch arg {
+	case "--no-renames":
+		return 0, true, nil
+	case "-M", "--find-renames":
+		return gvc.DefaultRenameThreshold, true, nil
+	}
+	value, ok := strings.CutPrefix(arg, "-M")
+	if !ok {
+		if value, ok = strings.CutPrefix(arg, "--find-renames="); !ok {
+			return 0, false, nil
+		}
+	}
+	digits, percent := strings.CutSuffix(value, "%")
+	n, valid := parseCount(digits)
+	if valid && !percent {
+		// Scale the fraction's digits to a percentage
+		scale := 1
+		for range len(digits) {
+			scale *= 10
+		}
+		n = n * 100 / scale
+	}
+	if !valid || n > 100 {
+		return 0, true, fmt.Errorf("invalid rename threshold %q", value)
+	}
+	return n, true, nil
+}
+
+// detectRenames pairs renames in changes at threshold, or at the repository's
+// default when threshold is negative
+func detectRenames(repo *gvc.Repository, changes []gvc.FileChange, threshold int) ([]gvc.FileChange, error) {
+	if threshold < 0 {
+		var err error
+		if threshold, err = repo.RenameThreshold(); err != nil {
+			return nil, err
+		}
+	}
+	return repo.DetectRenames(changes, threshold)
+}
+
+// ignoreSubmodulesOption parses --ignore-submodules, which ignores them all,
+// and --ignore-submodules=<when>
+func ignoreSubmodulesOption(arg string) (gvc.SubmoduleIgnore, bool, error) {
+	if arg == "--ignore-submodules" {
+		return gvc.IgnoreSubmodulesAll, true, nil
+	}
+	value, ok := strings.CutPrefix(arg, "--ignore-submodules=")
+	if !ok {
+		return 0, false, nil
+	}
+	ignore, err := gvc.ParseSubmoduleIgnore(value)
+	return ignore, true, err
+}
+
+// ignoreSubmodules applies ignore to changes, or diff.ignoreSubmodules when
+// it is negative; workingTree marks a diff against the working tree
+func ignoreSubmodules(repo *gvc.Repository, changes []gvc.FileChange, ignore gvc.SubmoduleIgnore, workingTree bool) ([]gvc.FileChange, error) {
+	if ignore < 0 {
+		var err error
+		if ignore, err = repo.DiffIgnoreSubmodules(); err != nil {
+			return nil, err
+		}
+	}
+	return repo.IgnoreSubmodules(changes, ignore, workingTree)
+}
+
+// NEW: Find-large-blobs command
+func handleFindLargeBlobs(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("find-large-blobs")
+	minSize, err := repo.LargeFileThreshold()
+	if err != nil {
+		return err
+	}
+	if minSize <= 0 {
+		minSize = gvc.DefaultLargeFileThreshold
+	}
+	for _, arg := range args {
+		value, ok := strings.CutPrefix(arg, "--min-size=")
+		if !ok {
+			return usage
+		}
+		if minSize, err = gvc.ParseSize(value); err != nil {
+			return usage
+		}
+	}
+
+	blobs, err := repo.FindLargeBlobs(minSize)
+	if err != nil {
+		return err
+	}
+	for _, blob := range blobs {
+		fmt.Printf("%10s  %s  %s  %s\n", gvc.FormatSize(blob.Size), blob.SHA[:7], blob.Commit[:7], blob.Path)
+	}
+	return nil
+}
+
+// NEW: Bisect command
+func handleBisect(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("bisect")
+	if len(args) == 0 {
+		return usage
+	}
+	subcommand, args := args[0], args[1:]
+
+	var result *gvc.BisectResult
+	var err error
+	switch subcommand {
+	case "start":
+		var bad string
+		if len(args) > 0 {
+			bad = args[0]
+			args = args[1:]
+		}
+		result, err = repo.BisectStart(bad, args)
+	case "good", "bad", "skip":
+		result, err = repo.BisectMark(subcommand, args)
+	case "reset":
+		if len(args) > 1 {
+			return usage
+		}
+		rev := ""
+		if len(args) == 1 {
+			rev = args[0]
+		}
+		return repo.BisectReset(rev)
+	case "log":
+		if len(args) > 0 {
+			return usage
+		}
+		text, err := repo.BisectLog()
+		if err != nil {
+			return err
+		}
+		fmt.Print(text)
+		return nil
+	case "run":
+		if len(args) == 0 {
+			return usage
+		}
+		result, err = repo.BisectRun(args, func(step *gvc.BisectResult) error {
+			return printBisectResult(repo, step)
+		})
+		if err != nil {
+			return err
+		}
+		if result.FirstBad != "" {
+			fmt.Println("bisect found first bad commit")
+		}
+		return nil
+	default:
+		return usage
+	}
+	if err != nil {
+		return err
+	}
+	return printBisectResult(repo, result)
+}
+
+// printBisectResult reports a bisect step: the commit to test next, what the
+// search still waits for, or the first bad commit with its diffstat
+func printBisectResult(repo *gvc.Repository, result *gvc.BisectResult) error {
+	switch {
+	case result.Waiting != "":
+		fmt.Printf("status: %s\n", result.Waiting)
+	case len(result.Skipped) > 0:
+		fmt.Println("There are only 'skip'ped commits left to test.")
+		fmt.Println("The first bad commit could be any of:")
+		for _, sha := range result.Skipped {
+			fmt.Println(sha)
+		}
+		fmt.Println("We cannot bisect more!")
+	case result.FirstBad != "":
+		commit, err := repo.ReadCommit(result.FirstBad)
+		if err != nil {
+			return err
+		}
+		text, err := mediumCommit(repo, commit, "", false, nil)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s is the first bad commit\n%s", commit.SHA, text)
+		parentTree := ""
+		if commit.ParentSHA != "" {
+			parent, err := repo.ReadCommit(commit.ParentSHA)
+			if err != nil {
+				return err
+			}
+			parentTree = parent.TreeSHA
+		}
+		changes, err := repo.DiffTrees(parentTree, commit.TreeSHA)
+		if err != nil {
+			return err
+		}
+		if changes, err = detectRenames(repo, changes, -1); err != nil {
+			return err
+		}
+		if len(changes) > 0 {
+			fmt.Println()
+			return repo.WriteDiffStat(os.Stdout, changes)
+		}
+	default:
+		commit, err := repo.ReadCommit(result.Next)
+		if err != nil {
+			return err
+		}
+		revisions := "revisions"
+		if result.Remaining == 1 {
+			revisions = "revision"
+		}
+		steps := "steps"
+		if result.Steps == 1 {
+			steps = "step"
+		}
+		fmt.Printf("Bisecting: %d %s left to test after this (roughly %d %s)\n", result.Remaining, revisions, result.Steps, steps)
+		fmt.Printf("[%s] %s\n", commit.SHA, strings.SplitN(commit.Message, "\n", 2)[0])
+	}
+	return nil
+}
+
+// NEW: Verify-index command
+func handleVerifyIndex(repo *gvc.Repository, args []string) error {
+	if len(args) > 0 {
+		return commandUsage("verify-index")
+	}
+	report, err := repo.VerifyIndex()
+	if err != nil {
+		return err
+	}
+	for _, problem := range report.Problems {
+		fmt.Println(problem)
+	}
+	if len(report.Problems) > 0 {
+		return negativeResult()
+	}
+	fmt.Printf("index OK: %d entries\n", report.Entries)
+	return nil
+}
+
+// NEW: Worktree command
+func handleWorktree(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("worktree")
+	if len(args) == 0 {
+		return usage
+	}
+	subcommand, args := args[0], args[1:]
+
+	switch subcommand {
+	case "add":
+		var opts gvc.WorktreeAddOptions
+		var positional []string
+		for i := 0; i < len(args); i++ {
+			switch {
+			case args[i] == "-b":
+				if i+1 >= len(args) {
+					return usage
+				}
+				opts.NewBranch = args[i+1]
+				i++
+			case args[i] == "--detach":
+				opts.Detach = true
+			case strings.HasPrefix(args[i], "-"):
+				return usage
+			default:
+				positional = append(positional, args[i])
+			}
+		}
+		if len(positional) < 1 || len(positional) > 2 || (opts.Detach && opts.NewBranch != "") {
+			return usage
+		}
+		commit := ""
+		if len(positional) == 2 {
+			commit = positional[1]
+		}
+		return repo.AddWorktree(positional[0], commit, opts)
+
+	case "list":
+		if len(args) > 0 {
+			return usage
+		}
+		worktrees, err := repo.Worktrees()
+		if err != nil {
+			return err
+		}
+		width := 0
+		for _, wt := range worktrees {
+			width = max(width, len(wt.Path))
+		}
+		for _, wt := range worktrees {
+			head := "0000000"
+			if wt.Head != "" {
+				head = wt.Head[:7]
+			}
+			line := fmt.Sprintf("%-*s  %s", width, wt.Path, head)
+			if wt.Branch != "" {
+				line += " [" + strings.TrimPrefix(wt.Branch, "refs/heads/") + "]"
+			} else {
+				line += " (detached HEAD)"
+			}
+			if wt.Locked {
+				line += " locked"
+			}
+			if wt.Prunable {
+				line += " prunable"
+			}
+			fmt.Println(line)
+		}
+		return nil
+
+	case "remove":
+		force := 0
+		var positional []string
+		for _, arg := range args {
+			switch arg {
+			case "-f", "--force":
+				force++
+			default:
+				positional = append(positional, arg)
+			}
+		}
+		if len(positional) != 1 {
+			return usage
+		}
+		return repo.RemoveWorktree(positional[0], force)
+
+	case "lock":
+		reason := ""
+		var positional []string
+		for i := 0; i < len(args); i++ {
+			switch {
+			case args[i] == "--reason":
+				if i+1 >= len(args) {
+					return usage
+				}
+				reason = args[i+1]
+				i++
+			case strings.HasPrefix(args[i], "--reason="):
+				reason = strings.TrimPrefix(args[i], "--reason=")
+			default:
+				positional = append(positional, args[i])
+			}
+		}
+		if len(positional) != 1 {
+			return usage
+		}
+		return repo.LockWorktree(positional[0], reason)
+
+	case "unlock":
+		if len(args) != 1 {
+			return usage
+		}
+		return repo.UnlockWorktree(args[0])
+
+	case "prune":
+		if len(args) > 0 {
+			return usage
+		}
+		return repo.PruneWorktrees()
+	}
+	return usage
+}
+
+// NEW: JSON output
+
+// jsonSchema is the version of the records --json prints. It changes only
+// when a field is renamed, removed or changes meaning; new fields may appear
+// in any version.
+const jsonSchema = 1
+
+// jsonOutput is set by the global --json flag
+var jsonOutput bool
+
+// jsonRecord starts every record: the schema version and what the record is
+type jsonRecord struct {
+	Schema int    `json:"schema"`
+	Type   string `json:"type"`
+}
+
+// record starts a record of the given type
+func record(recordType string) jsonRecord {
+	return jsonRecord{Schema: jsonSchema, Type: recordType}
+}
+
+// printJSON writes one record as a line of JSON
+func printJSON(value any) error {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Errorf("failed to encode JSON record: %w", err)
+	}
+	return nil
+}
+
+// jsonCommit is a commit as log --json prints it
+type jsonCommit struct {
+	jsonRecord
+	SHA           string     `json:"sha"`
+	Tree          string     `json:"tree"`
+	Parents       []string   `json:"parents"`
+	AuthorName    string     `json:"author_name"`
+	AuthorEmail   string     `json:"author_email"`
+	AuthorDate    string     `json:"author_date"`
+	CommitterDate string     `json:"committer_date"`
+	Subject       string     `json:"subject"`
+	Message       string     `json:"message"`
+	Boundary      bool       `json:"boundary,omitempty"` // log --boundary only
+	Notes         []jsonNote `json:"notes,omitempty"`    // log only
+}
+
+// jsonNote is a note attached to a commit, with the notes ref holding it
+type jsonNote struct {
+	Ref  string `json:"ref"`
+	Text string `json:"text"`
+}
+
+// newJSONCommit converts a commit, with its dates in RFC 3339
+func newJSONCommit(commit *object.Commit) jsonCommit {
+	name, email := commit.Author, ""
+	if i := strings.LastIndex(commit.Author, " <"); i >= 0 {
+		name, email = commit.Author[:i], strings.TrimSuffix(commit.Author[i+2:], ">")
+	}
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	parents := commit.Parents
+	if parents == nil {
+		parents = []string{}
+	}
+	committed := commit.Committed
+	if committed.IsZero() {
+		committed = commit.Timestamp
+	}
+	return jsonCommit{
+		jsonRecord:    record("commit"),
+		SHA:           commit.SHA,
+		Tree:          commit.TreeSHA,
+		Parents:       parents,
+		AuthorName:    name,
+		AuthorEmail:   email,
+		AuthorDate:    commit.Timestamp.Format(time.RFC3339),
+		CommitterDate: committed.Format(time.RFC3339),
+		Subject:       subject,
+		Message:       commit.Message,
+	}
+}
+
+// jsonTreeEntry is an entry of ls-tree --json
+type jsonTreeEntry struct {
+	jsonRecord
+	Mode       string `json:"mode"`
+	ObjectType string `json:"object_type"`
+	SHA        string `json:"sha"`
+	Name       string `json:"name"`
+}
+
+// jsonBranch is a branch of branch --json
+type jsonBranch struct {
+	jsonRecord
+	Name          string `json:"name"`
+	SHA           string `json:"sha"`
+	Current       bool   `json:"current"`
+	CommitterDate string `json:"committer_date"`
+	Created       string `json:"created"` // from the reflog, or the committer date without one
+	Updated       string `json:"updated"`
+}
+
+// jsonFile is a path of ls-files --json. Staged files carry their mode and
+// blob; status is cached, modified, other or ignored.
+type jsonFile struct {
+	jsonRecord
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	Mode   string `json:"mode,omitempty"`
+	SHA    string `json:"sha,omitempty"`
+}
+
+// jsonHead is the first record of status --json. Branch is empty when HEAD
+// is detached and Commit when the branch has no commits yet.
+type jsonHead struct {
+	jsonRecord
+	Branch string `json:"branch"`
+	Commit string `json:"commit"`
+}
+
+// jsonChange is a changed path of status --json: area is staged, unstaged,
+// untracked or ignored, and change is added, modified, deleted or renamed
+type jsonChange struct {
+	jsonRecord
+	Path      string `json:"path"`
+	OldPath   string `json:"old_path,omitempty"`
+	Area      string `json:"area"`
+	Change    string `json:"change"`
+	Submodule string `json:"submodule,omitempty"` // e.g. "new commits, modified content"
+}
+
+// statusRecords collects what status reports: the head, then the staged
+// changes (with renames), the unstaged ones and the untracked files,
+// followed by the ignored files when ignored is set. Submodules are shown as
+// ignore says, or as diff.ignoreSubmodules does when it is negative.
+func statusRecords(repo *gvc.Repository, ignore gvc.SubmoduleIgnore, ignored bool) (jsonHead, []jsonChange, error) {
+	ref, err := repo.HeadRef()
+	if err != nil {
+		return jsonHead{}, nil, err
+	}
+	head, err := repo.HeadCommit()
+	if err != nil {
+		return jsonHead{}, nil, err
+	}
+	staged, err := repo.DiffIndex("")
+	if err != nil {
+		return jsonHead{}, nil, err
+	}
+	if staged, err = detectRenames(repo, staged, -1); err != nil {
+		return jsonHead{}, nil, err
+	}
+	if ignore < 0 {
+		if ignore, err = repo.DiffIgnoreSubmodules(); err != nil {
+			return jsonHead{}, nil, err
+		}
+	}
+	if staged, err = repo.IgnoreSubmodules(staged, ignore, false); err != nil {
+		return jsonHead{}, nil, err
+	}
+	modified, err := repo.ModifiedFiles()
+	if err != nil {
+		return jsonHead{}, nil, err
+	}
+	// Submodules count as modified for new commits or local changes, as far
+	// as ignore lets them, and say which
+	states, err := repo.SubmoduleStates(ignore)
+	if err != nil {
+		return jsonHead{}, nil, err
+	}
+	submodules := make(map[string]string)
+	for _, state := range states {
+		submodules[state.Path] = state.Describe()
+	}
+	stagedFiles, err := repo.StagedFiles()
+	if err != nil {
+		return jsonHead{}, nil, err
+	}
+	for _, entry := range stagedFiles {
+		if entry.Mode != gvc.GitlinkMode {
+			continue
+		}
+		modified = slices.DeleteFunc(modified, func(path string) bool { return path == entry.Path })
+		if submodules[entry.Path] != "" {
+			modified = append(modified, entry.Path)
+		}
+	}
+	slices.Sort(modified)
+	untracked, err := repo.UntrackedFiles(false)
+	if err != nil {
+		return jsonHead{}, nil, err
+	}
+
+	var changes []jsonChange
+	for _, change := range staged {
+		changes = append(changes, newJSONChange(change, "staged"))
+	}
+	for _, path := range modified {
+		// A file is deleted when nothing is there any more, or a directory
+		// took its place or that of one of its parents
+		kind := "modified"
+		info, err := os.Lstat(filepath.Join(repo.Root, filepath.FromSlash(path)))
+		if _, isSubmodule := submodules[path]; err != nil || info.IsDir() && !isSubmodule {
+			kind = "deleted"
+		}
+		changes = append(changes, jsonChange{jsonRecord: record("change"), Path: path, Area: "unstaged", Change: kind, Submodule: submodules[path]})
+	}
+	for _, path := range untracked {
+		changes = append(changes, jsonChange{jsonRecord: record("change"), Path: path, Area: "untracked", Change: "added"})
+	}
+	if ignored {
+		paths, err := repo.UntrackedFiles(true)
+		if err != nil {
+			return jsonHead{}, nil, err
+		}
+		for _, path := range paths {
+			changes = append(changes, jsonChange{jsonRecord: record("change"), Path: path, Area: "ignored", Change: "added"})
+		}
+	}
+	branch, _ := strings.CutPrefix(ref, "refs/heads/")
+	return jsonHead{jsonRecord: record("head"), Branch: branch, Commit: head}, changes, nil
+}
+
+// newJSONChange converts a change between two snapshots in area
+func newJSONChange(change gvc.FileChange, area string) jsonChange {
+	kind := "modified"
+	switch {
+	case change.OldPath != "":
+		kind = "renamed"
+	case change.Old == nil:
+		kind = "added"
+	case change.New == nil:
+		kind = "deleted"
+	}
+	return jsonChange{jsonRecord: record("change"), Path: change.Path, OldPath: change.OldPath, Area: area, Change: kind}
+}
+
+// statusCodes are the letters status -s shows for each kind of change
+var statusCodes = map[string]byte{"added": 'A', "modified": 'M', "deleted": 'D', "renamed": 'R'}
+
+// printShortStatus prints changes one path per line, as status -s does: two
+// letters for the staged and the unstaged change, "??" for an untracked file
+// and "!!" for an ignored one
+func printShortStatus(changes []jsonChange) {
+	type shortLine struct {
+		codes   [2]byte
+		oldPath string
+	}
+	lines := make(map[string]*shortLine)
+	var paths, others []string
+	for _, change := range changes {
+		switch change.Area {
+		case "untracked":
+			others = append(others, "?? "+change.Path)
+			continue
+		case "ignored":
+			others = append(others, "!! "+change.Path)
+			continue
+		}
+		line, ok := lines[change.Path]
+		if !ok {
+			line = &shortLine{codes: [2]byte{' ', ' '}}
+			lines[change.Path] = line
+			paths = append(paths, change.Path)
+		}
+		side := 0
+		if change.Area == "unstaged" {
+			side = 1
+		}
+		line.codes[side] = statusCodes[change.Change]
+		if change.OldPath != "" {
+			line.oldPath = change.OldPath
+		}
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		line := lines[path]
+		if line.oldPath != "" {
+			fmt.Printf("%s %s -> %s\n", line.codes[:], line.oldPath, path)
+		} else {
+			fmt.Printf("%s %s\n", line.codes[:], path)
+		}
+	}
+	for _, other := range others {
+		fmt.Println(other)
+	}
+}
+
+// statusSummary counts changes for the line closing status, such as
+// "3 staged, 2 modified, 5 untracked", leaving out the areas with none
+func statusSummary(changes []jsonChange) string {
+	counts := make(map[string]int)
+	for _, change := range changes {
+		counts[change.Area]++
+	}
+	var parts []string
+	for _, area := range []struct{ name, label string }{
+		{"staged", "staged"},
+		{"unstaged", "modified"},
+		{"untracked", "untracked"},
+		{"ignored", "ignored"},
+	} {
+		if counts[area.name] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[area.name], area.label))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// NEW: Status command
+func handleStatus(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("status")
+	ignore := gvc.SubmoduleIgnore(-1)
+	short, ignored := false, false
+	for _, arg := range args {
+		switch arg {
+		case "-s", "--short":
+			short = true
+			continue
+		case "--ignored":
+			ignored = true
+			continue
+		}
+		level, ok, err := ignoreSubmodulesOption(arg)
+		if !ok {
+			return usage
+		}
+		if err != nil {
+			return usageError(err.Error())
+		}
+		ignore = level
+	}
+	headRecord, changes, err := statusRecords(repo, ignore, ignored)
+	if err != nil {
+		return err
+	}
+	branch, head := headRecord.Branch, headRecord.Commit
+
+	if jsonOutput {
+		if err := printJSON(headRecord); err != nil {
+			return err
+		}
+		for _, change := range changes {
+			if err := printJSON(change); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if short {
+		printShortStatus(changes)
+		return nil
+	}
+
+	switch {
+	case branch == "":
+		fmt.Printf("HEAD detached at %s\n", head[:7])
+	default:
+		fmt.Printf("On branch %s\n", branch)
+	}
+	if head == "" {
+		fmt.Println("\nNo commits yet")
+	}
+	sections := []struct{ area, title string }{
+		{"staged", "Changes to be committed:"},
+		{"unstaged", "Changes not staged for commit:"},
+		{"untracked", "Untracked files:"},
+		{"ignored", "Ignored files:"},
+	}
+	for _, section := range sections {
+		printed := false
+		for _, change := range changes {
+			if change.Area != section.area {
+				continue
+			}
+			if !printed {
+				fmt.Printf("\n%s\n", section.title)
+				printed = true
+			}
+			switch {
+			case change.Area == "untracked" || change.Area == "ignored":
+				fmt.Printf("\t%s\n", change.Path)
+			case change.OldPath != "":
+				fmt.Printf("\t%-12s%s -> %s\n", change.Change+":", change.OldPath, change.Path)
+			case change.Submodule != "":
+				fmt.Printf("\t%-12s%s (%s)\n", change.Change+":", change.Path, change.Submodule)
+			default:
+				fmt.Printf("\t%-12s%s\n", change.Change+":", change.Path)
+			}
+		}
+	}
+	summary := statusSummary(changes)
+	switch {
+	case len(changes) == 0:
+		fmt.Println("nothing to commit, working tree clean")
+	case !slices.ContainsFunc(changes, func(change jsonChange) bool { return change.Area != "ignored" }):
+		fmt.Printf("\nnothing to commit, working tree clean (%s)\n", summary)
+	default:
+		fmt.Printf("\n%s\n", summary)
+	}
+	return nil
+}
+
+// NEW: Fast-export and fast-import commands
+func handleFastExport(repo *gvc.Repository, args []string) error {
+	// The stream goes to stdout, so warnings must not
+	repo.Out = os.Stderr
+	return repo.FastExport(os.Stdout, args)
+}
+
+func handleFastImport(repo *gvc.Repository, args []string) error {
+	var opts gvc.FastImportOptions
+	for _, arg := range args {
+		if arg != "--force" {
+			return commandUsage("fast-import")
+		}
+		opts.Force = true
+	}
+	refs, err := repo.FastImport(os.Stdin, opts)
+	for _, ref := range refs {
+		fmt.Printf("%s %s\n", ref.SHA, ref.Name)
+	}
+	return err
+}
+
+// NEW: API server command
+
+// apiSocket is where api-server listens unless --socket says otherwise,
+// inside the repository's metadata directory
+const apiSocket = "api.sock"
+
+// JSON-RPC 2.0 error codes api-server answers with. A failed method call is
+// an apiFailed error whose data holds the exit code the command would have
+// exited with.
+const (
+	apiParseError     = -32700
+	apiInvalidRequest = -32600
+	apiNoMethod       = -32601
+	apiInvalidParams  = -32602
+	apiFailed         = -32000
+)
+
+// apiRequest is a JSON-RPC 2.0 request; a request without an id is a
+// notification, which gets no response
+type apiRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// apiResponse answers one request with its result or an error
+type apiResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *apiError       `json:"error,omitempty"`
+}
+
+// apiError is the error of a failed request
+type apiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    any    `json:"data,omitempty"`
+}
+
+// apiErrorData is the data of an apiFailed error
+type apiErrorData struct {
+	ExitCode int `json:"exit_code"`
+}
+
+// apiStatus is the result of the status method
+type apiStatus struct {
+	Head    jsonHead     `json:"head"`
+	Changes []jsonChange `json:"changes"`
+}
+
+// apiLog is the result of the log method, newest commit first
+type apiLog struct {
+	Commits []jsonCommit `json:"commits"`
+}
+
+// apiDiff is the result of the diff method: the changed paths, the
+// conflicted ones and the patch diff would print for them
+type apiDiff struct {
+	Changes  []jsonChange `json:"changes"`
+	Unmerged []string     `json:"unmerged"`
+	Patch    string       `json:"patch"`
+}
+
+// apiMethods are the methods api-server serves. Each decodes its params with
+// decodeAPIParams and returns the value to send as the result.
+var apiMethods = map[string]func(repo *gvc.Repository, params json.RawMessage) (any, error){
+	"status": apiStatusMethod,
+	"log":    apiLogMethod,
+	"diff":   apiDiffMethod,
+	"commit": apiCommitMethod,
+}
+
+// apiWriteMethods are the methods that modify the repository, and so
+// register with BeginWrite like a writing command does
+var apiWriteMethods = map[string]bool{
+	"commit": true,
+}
+
+// decodeAPIParams decodes a method's params into v. Missing params leave v
+// as it is; unknown fields are refused so a typo is not silently ignored.
+func decodeAPIParams(params json.RawMessage, v any) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return usageError(fmt.Sprintf("invalid params: %v", err))
+	}
+	return nil
+}
+
+// apiStatusMethod takes no params and returns what status --json prints
+func apiStatusMethod(repo *gvc.Repository, params json.RawMessage) (any, error) {
+	if err := decodeAPIParams(params, &struct{}{}); err != nil {
+		return nil, err
+	}
+	head, changes, err := statusRecords(repo, -1, false)
+	if err != nil {
+		return nil, err
+	}
+	if changes == nil {
+		changes = []jsonChange{}
+	}
+	return apiStatus{Head: head, Changes: changes}, nil
+}
+
+// apiLogMethod lists the commits reachable from revs, HEAD when there are
+// none, that touch paths, at most max_count of them when it is positive
+func apiLogMethod(repo *gvc.Repository, params json.RawMessage) (any, error) {
+	var p struct {
+		Revs     []string `json:"revs"`
+		Paths    []string `json:"paths"`
+		MaxCount int      `json:"max_count"`
+	}
+	if err := decodeAPIParams(params, &p); err != nil {
+		return nil, err
+	}
+	result := apiLog{Commits: []jsonCommit{}}
+	if len(p.Revs) == 0 {
+		head, err := repo.HeadCommit()
+		if err != nil || head == "" {
+			return result, err
+		}
+	}
+	commits, err := repo.LogWithOptions(gvc.LogOptions{Paths: p.Paths, MaxCount: p.MaxCount}, p.Revs...)
+	if err != nil {
+		return nil, err
+	}
+	for _, commit := range commits {
+		result.Commits = append(result.Commits, newJSONCommit(commit))
+	}
+	return result, nil
+}
+
+// apiDiffMethod compares what diff does for the same arguments: the working
+// tree, or the index when cached, against the index or the one commit in
+// revs, or two commits
+func apiDiffMethod(repo *gvc.Repository, params json.RawMessage) (any, error) {
+	var p struct {
+		Cached bool     `json:"cached"`
+		Revs   []string `json:"revs"`
+	}
+	if err := decodeAPIParams(params, &p); err != nil {
+		return nil, err
+	}
+	if len(p.Revs) > 2 || (p.Cached && len(p.Revs) > 1) {
+		return nil, usageError("invalid params: diff takes at most two revs, or one when cached")
+	}
+	merged, unmerged, err := diffChanges(repo, p.Cached, p.Revs, -1, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	area := "unstaged"
+	switch {
+	case len(p.Revs) == 2:
+		area = "commits"
+	case p.Cached:
+		area = "staged"
+	}
+	result := apiDiff{Changes: []jsonChange{}, Unmerged: []string{}}
+	for _, change := range merged {
+		result.Changes = append(result.Changes, newJSONChange(change, area))
+	}
+	result.Unmerged = append(result.Unmerged, unmerged...)
+
+	var patch bytes.Buffer
+	if len(unmerged) > 0 {
+		if err := repo.WriteCombinedPatch(&patch, unmerged); err != nil {
+			return nil, err
+		}
+	}
+	if err := repo.WritePatch(&patch, merged); err != nil {
+		return nil, err
+	}
+	result.Patch = patch.String()
+	return result, nil
+}
+
+// apiCommitMethod commits the staged changes, or every tracked change with
+// all, and returns the new commit as log --json prints it
+func apiCommitMethod(repo *gvc.Repository, params json.RawMessage) (any, error) {
+	var p struct {
+		Message  string `json:"message"`
+		All      bool   `json:"all"`
+		Amend    bool   `json:"amend"`
+		NoVerify bool   `json:"no_verify"`
+	}
+	if err := decodeAPIParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Message == "" && !p.Amend {
+		return nil, usageError("invalid params: commit needs a message")
+	}
+	commitSHA, err := repo.CommitWithOptions(p.Message, gvc.CommitOptions{All: p.All, Amend: p.Amend, NoVerify: p.NoVerify})
+	if err != nil {
+		return nil, err
+	}
+	commit, err := repo.ReadCommit(commitSHA)
+	if err != nil {
+		return nil, err
+	}
+	return newJSONCommit(commit), nil
+}
+
+// apiServer answers JSON-RPC requests on one repository. Requests are
+// handled one at a time, each on the repository as it is on disk then.
+type apiServer struct {
+	repo *gvc.Repository
+	mu   sync.Mutex
+}
+
+// call runs one request, returning nil for a notification
+func (s *apiServer) call(line []byte) *apiResponse {
+	response := &apiResponse{JSONRPC: "2.0", ID: json.RawMessage("null")}
+	var request apiRequest
+	if err := json.Unmarshal(line, &request); err != nil {
+		response.Error = &apiError{Code: apiParseError, Message: fmt.Sprintf("parse error: %v", err)}
+		return response
+	}
+	if len(request.ID) > 0 {
+		response.ID = request.ID
+	}
+	if request.JSONRPC != "2.0" || request.Method == "" {
+		response.Error = &apiError{Code: apiInvalidRequest, Message: `invalid request: expected "jsonrpc": "2.0" and a method`}
+		return response
+	}
+	method, ok := apiMethods[request.Method]
+	if !ok {
+		response.Error = &apiError{Code: apiNoMethod, Message: "method not found: " + request.Method}
+	} else {
+		result, err := s.run(method, apiWriteMethods[request.Method], request.Params)
+		switch {
+		case err == nil:
+			response.Result = result
+		case exitCode(err) == ExitUsage:
+			response.Error = &apiError{Code: apiInvalidParams, Message: err.Error()}
+		default:
+			response.Error = &apiError{Code: apiFailed, Message: err.Error(), Data: apiErrorData{ExitCode: exitCode(err)}}
+		}
+	}
+	if len(request.ID) == 0 {
+		return nil
+	}
+	return response
+}
+
+// run calls a method on a freshly opened copy of the repository, so nothing
+// cached by an earlier request outlives changes made by other processes. A
+// method that writes waits for gc and prune, and they for it, as a command
+// would.
+func (s *apiServer) run(method func(*gvc.Repository, json.RawMessage) (any, error), writes bool, params json.RawMessage) (any, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	repo, err := gvc.OpenAt(s.repo.Root, s.repo.GitDir)
+	if err != nil {
+		return nil, err
+	}
+	repo.Out, repo.Err = os.Stderr, os.Stderr
+	repo.ReadOnly = repo.ReadOnly || s.repo.ReadOnly
+	if writes {
+		release, err := repo.BeginWrite()
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	return method(repo, params)
+}
+
+// serve answers the requests on one connection, one JSON value per line
+func (s *apiServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	encoder := json.NewEncoder(conn)
+	encoder.SetEscapeHTML(false)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if response := s.call(line); response != nil {
+				if encoder.Encode(response) != nil {
+					return
+				}
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func handleAPIServer(repo *gvc.Repository, args []string) error {
+	socket := filepath.Join(repo.GitDir, apiSocket)
+	for _, arg := range args {
+		value, ok := strings.CutPrefix(arg, "--socket=")
+		if !ok || value == "" {
+			return commandUsage("api-server")
+		}
+		socket = value
+	}
+
+	// A socket left behind by a server that died can be replaced, but not
+	// one another server still answers on
+	if _, err := os.Lstat(socket); err == nil {
+		if conn, err := net.Dial("unix", socket); err == nil {
+			conn.Close()
+			return fmt.Errorf("an API server is already listening on %s", socket)
+		}
+		if err := os.Remove(socket); err != nil {
+			return fmt.Errorf("failed to remove stale socket %s: %w", socket, err)
+		}
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socket, err)
+	}
+	// Closing the listener removes the socket
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		listener.Close()
+	}()
+	fmt.Printf("Listening on %s\n", socket)
+
+	server := &apiServer{repo: repo}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("failed to accept a connection: %w", err)
+		}
+		go server.serve(conn)
+	}
+}
+
+// NEW: UI command
+func handleUI(repo *gvc.Repository, args []string) error {
+	if len(args) > 0 {
+		return commandUsage("ui")
+	}
+	return runUI(repo)
+}
+
+// NEW: Autosnapshot command
+func handleAutosnapshot(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("autosnapshot")
+	subcommand := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		subcommand = args[0]
+		args = args[1:]
+	}
+
+	switch subcommand {
+	case "run":
+		interval := 5 * time.Minute
+		for _, arg := range args {
+			value, ok := strings.CutPrefix(arg, "--interval=")
+			if !ok {
+				return usage
+			}
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return usageError(fmt.Sprintf("invalid interval: %s", value))
+			}
+			interval = parsed
+		}
+		return runAutosnapshot(repo, interval)
+
+	case "save":
+		if len(args) > 0 {
+			return usage
+		}
+		sha, err := repo.SaveSnapshot()
+		if err != nil {
+			return err
+		}
+		if sha == "" {
+			fmt.Println("No changes since the last snapshot")
+		} else {
+			fmt.Printf("Saved snapshot %s\n", sha[:7])
+		}
+		return nil
+
+	case "list":
+		var branches []string
+		all, dateFormat := false, "relative"
+		for _, arg := range args {
+			switch {
+			case arg == "--all":
+				all = true
+			case arg == "--date=relative" || arg == "--date=iso":
+				dateFormat = strings.TrimPrefix(arg, "--date=")
+			case !strings.HasPrefix(arg, "-") && len(branches) == 0:
+				branches = append(branches, arg)
+			default:
+				return usage
+			}
+		}
+		if all {
+			if len(branches) > 0 {
+				return usage
+			}
+			var err error
+			if branches, err = repo.SnapshotBranches(); err != nil {
+				return err
+			}
+		} else if len(branches) == 0 {
+			branches = []string{""}
+		}
+		now := time.Now()
+		for _, branch := range branches {
+			snapshots, err := repo.Snapshots(branch)
+			if err != nil {
+				return err
+			}
+			for _, snapshot := range snapshots {
+				date := gvc.RelativeDate(snapshot.Time, now)
+				if dateFormat == "iso" {
+					date = snapshot.Time.Format("2006-01-02 15:04:05 -0700")
+				}
+				subject := strings.SplitN(snapshot.Message, "\n", 2)[0]
+				fmt.Printf("%s %s (%s) %s\n", snapshot.SHA[:7], snapshot.Name, date, subject)
+			}
+		}
+		return nil
+
+	case "restore":
+		if len(args) > 0 && args[0] == "--" || len(args) == 0 {
+			return usage
+		}
+		rev, paths := args[0], args[1:]
+		if len(paths) > 0 && paths[0] == "--" {
+			paths = paths[1:]
+		}
+		restored, saved, err := repo.RestoreSnapshot(rev, paths...)
+		if saved != "" {
+			fmt.Printf("Saved the working tree as snapshot %s\n", saved[:7])
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restored %d file(s) from %s\n", restored, rev)
+		return nil
+
+	default:
+		return usage
+	}
+}
+
+// runAutosnapshot snapshots the working tree every interval until
+// interrupted. Each round reopens the repository so it sees what other
+// commands changed; a round that fails is reported and the next one tried.
+func runAutosnapshot(repo *gvc.Repository, interval time.Duration) error {
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	fmt.Printf("Snapshotting %s every %s\n", repo.Root, interval)
+	for {
+		current, err := gvc.OpenAt(repo.Root, repo.GitDir)
+		if err == nil {
+			current.Out, current.Err = os.Stderr, os.Stderr
+			current.ReadOnly = current.ReadOnly || repo.ReadOnly
+			var sha string
+			if sha, err = saveSnapshotRound(current); err == nil && sha != "" {
+				fmt.Printf("%s Saved snapshot %s\n", time.Now().Format("15:04:05"), sha[:7])
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warning: snapshot failed: %v\n", err)
+		}
+
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// saveSnapshotRound saves one snapshot for runAutosnapshot, registered with
+// BeginWrite only while it is written
+func saveSnapshotRound(repo *gvc.Repository) (string, error) {
+	release, err := repo.BeginWrite()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return repo.SaveSnapshot()
+}
+
+// NEW: Clean command
+func handleClean(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("clean")
+	var opts gvc.CleanOptions
+	dryRun, force, quiet := false, false, false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			opts.Paths = append(opts.Paths, args[i+1:]...)
+			break
+		}
+		switch arg {
+		case "--dry-run":
+			dryRun = true
+			continue
+		case "--force":
+			force = true
+			continue
+		case "--quiet":
+			quiet = true
+			continue
+		}
+		if !strings.HasPrefix(arg, "-") {
+			opts.Paths = append(opts.Paths, arg)
+			continue
+		}
+		// Short options combine, as in clean -fdx
+		if len(arg) < 2 || strings.HasPrefix(arg, "--") {
+			return usage
+		}
+		for _, flag := range arg[1:] {
+			switch flag {
+			case 'n':
+				dryRun = true
+			case 'f':
+				force = true
+			case 'd':
+				opts.Directories = true
+			case 'x':
+				opts.Ignored = true
+			case 'q':
+				quiet = true
+			default:
+				return usage
+			}
+		}
+	}
+	if !dryRun && !force {
+		return errors.New("refusing to clean without -f, or -n to only list what would be removed")
+	}
+
+	paths, err := repo.CleanPaths(opts)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		for _, path := range paths {
+			fmt.Printf("Would remove %s\n", path)
+		}
+		return nil
+	}
+	if !quiet {
+		for _, path := range paths {
+			fmt.Printf("Removing %s\n", path)
+		}
+	}
+	return repo.Clean(paths)
+}
+
+// NEW: Encryption command
+func handleEncryption(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("encryption")
+	subcommand := "status"
+	if len(args) > 0 {
+		subcommand, args = args[0], args[1:]
+	}
+
+	switch subcommand {
+	case "status":
+		if len(args) > 0 {
+			return usage
+		}
+		status, err := repo.Encryption()
+		if err != nil {
+			return err
+		}
+		if status.Cipher == "" {
+			fmt.Println("Objects are not encrypted")
+		} else {
+			fmt.Printf("Objects are encrypted with %s\n", status.Cipher)
+			if status.KeyFile != "" {
+				fmt.Printf("Key file: %s\n", status.KeyFile)
+			}
+		}
+		fmt.Printf("%d object files encrypted, %d unencrypted\n", status.Sealed, status.Plain)
+		return nil
+
+	case "enable":
+		var keyFile string
+		for _, arg := range args {
+			value, ok := strings.CutPrefix(arg, "--keyfile=")
+			if !ok || value == "" {
+				return usage
+			}
+			keyFile = value
+		}
+		rewritten, err := repo.EnableEncryption(keyFile)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Encryption enabled; encrypted %d object files\n", rewritten)
+		return nil
+
+	case "disable":
+		if len(args) > 0 {
+			return usage
+		}
+		rewritten, err := repo.DisableEncryption()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Encryption disabled; decrypted %d object files\n", rewritten)
+		return nil
+
+	default:
+		return usage
+	}
+}
+
+// NEW: Describe command
+func handleDescribe(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("describe")
+	opts := gvc.DescribeOptions{Abbrev: 7}
+	var revs []string
+	for _, arg := range args {
+		switch {
+		case arg == "--tags":
+			opts.Tags = true
+		case arg == "--long":
+			opts.Long = true
+		case arg == "--always":
+			opts.Always = true
+		case arg == "--exact-match":
+			opts.ExactMatch = true
+		case arg == "--dirty":
+			opts.Dirty = "-dirty"
+		case strings.HasPrefix(arg, "--dirty="):
+			opts.Dirty = strings.TrimPrefix(arg, "--dirty=")
+		case strings.HasPrefix(arg, "--match="):
+			opts.Match = strings.TrimPrefix(arg, "--match=")
+		case strings.HasPrefix(arg, "--abbrev="):
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--abbrev="))
+			if err != nil || n < 0 {
+				return usage
+			}
+			opts.Abbrev = n
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			revs = append(revs, arg)
+		}
+	}
+	if opts.Dirty != "" && len(revs) > 0 {
+		return usageError("--dirty is incompatible with commit-ishes")
+	}
+	if opts.Long && opts.Abbrev == 0 {
+		return usageError("--long is incompatible with --abbrev=0")
+	}
+	if len(revs) == 0 {
+		revs = []string{""}
+	}
+
+	for _, rev := range revs {
+		name, err := repo.Describe(rev, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Println(name)
+	}
+	return nil
+}
+
+// NEW: Shortlog command
+func handleShortlog(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("shortlog")
+	numbered, summary, email := false, false, false
+	var revs []string
+	for _, arg := range args {
+		switch {
+		case arg == "--numbered":
+			numbered = true
+		case arg == "--summary":
+			summary = true
+		case arg == "--email":
+			email = true
+		case isRefSelector(arg):
+			revs = append(revs, arg)
+		case strings.HasPrefix(arg, "-") && len(arg) > 1 && !strings.HasPrefix(arg, "--"):
+			// Short flags may be combined, as in -sn
+			for _, flag := range arg[1:] {
+				switch flag {
+				case 'n':
+					numbered = true
+				case 's':
+					summary = true
+				case 'e':
+					email = true
+				default:
+					return usage
+				}
+			}
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			revs = append(revs, arg)
+		}
+	}
+
+	authors, err := repo.Shortlog(revs, email)
+	if err != nil {
+		return err
+	}
+	if numbered {
+		slices.SortStableFunc(authors, func(a, b gvc.ShortlogAuthor) int {
+			return len(b.Subjects) - len(a.Subjects)
+		})
+	}
+	for _, author := range authors {
+		name := author.Name
+		if email {
+			name = fmt.Sprintf("%s <%s>", author.Name, author.Email)
+		}
+		if summary {
+			fmt.Printf("%6d\t%s\n", len(author.Subjects), name)
+			continue
+		}
+		fmt.Printf("%s (%d):\n", name, len(author.Subjects))
+		for _, subject := range author.Subjects {
+			fmt.Printf("      %s\n", subject)
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+// NEW: Scan-history command
+func handleScanHistory(repo *gvc.Repository, args []string) error {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") && !isRefSelector(arg) {
+			return commandUsage("scan-history")
+		}
+	}
+	findings, err := repo.ScanHistory(args)
+	if err != nil {
+		return err
+	}
+	commits := make(map[string]bool)
+	for _, finding := range findings {
+		commits[finding.Commit] = true
+		fmt.Printf("%s %s\n", finding.Commit[:7], finding)
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "%d possible secret(s) in %d commit(s)\n", len(findings), len(commits))
+	return negativeResult()
+}
+
+// NEW: Update-ref command
+func handleUpdateRef(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("update-ref")
+	message := ""
+	noDeref, deleteRef, stdin := false, false, false
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "-m":
+			if i+1 >= len(args) {
+				return usage
+			}
+			i++
+			message = args[i]
+		case "--no-deref":
+			noDeref = true
+		case "-d":
+			deleteRef = true
+		case "--stdin":
+			stdin = true
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return usage
+			}
+			rest = append(rest, arg)
+		}
+	}
+
+	if stdin {
+		if deleteRef || len(rest) > 0 {
+			return usage
+		}
+		edits, err := readRefEdits(repo, os.Stdin, noDeref)
+		if err != nil {
+			return err
+		}
+		return repo.UpdateRefs(edits, message)
+	}
+
+	var line string
+	switch {
+	case deleteRef && (len(rest) == 1 || len(rest) == 2):
+		line = "delete " + strings.Join(rest, " ")
+	case !deleteRef && (len(rest) == 2 || len(rest) == 3):
+		line = "update " + strings.Join(rest, " ")
+	default:
+		return usage
+	}
+	edit, err := parseRefEdit(repo, line, noDeref)
+	if err != nil {
+		return err
+	}
+	return repo.UpdateRefs([]gvc.RefEdit{edit}, message)
+}
+
+// readRefEdits reads the update-ref --stdin commands, one per line, that
+// make up a transaction
+func readRefEdits(repo *gvc.Repository, in io.Reader, noDeref bool) ([]gvc.RefEdit, error) {
+	var edits []gvc.RefEdit
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		edit, err := parseRefEdit(repo, line, noDeref)
+		if err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read input: %w", err)
+	}
+	return edits, nil
+}
+
+// parseRefEdit parses one update-ref command: update <ref> <new> [<old>],
+// create <ref> <new>, delete <ref> [<old>] or verify <ref> [<old>]. Values
+// are revisions; the all-zero SHA stands for a ref that does not exist.
+func parseRefEdit(repo *gvc.Repository, line string, noDeref bool) (gvc.RefEdit, error) {
+	fields := strings.Fields(line)
+	edit := gvc.RefEdit{NoDeref: noDeref}
+	value := func(rev string) (string, error) {
+		if rev == repo.Format.ZeroSHA() {
+			return "", nil
+		}
+		return repo.ResolveObject(rev)
+	}
+	values := func(least, most int) ([]string, error) {
+		if len(fields) < least+2 || len(fields) > most+2 {
+			return nil, fmt.Errorf("%s: wrong number of arguments: %s", fields[0], line)
+		}
+		edit.Ref = fields[1]
+		resolved := make([]string, len(fields)-2)
+		for i, rev := range fields[2:] {
+			sha, err := value(rev)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = sha
+		}
+		return resolved, nil
+	}
+
+	var resolved []string
+	var err error
+	switch fields[0] {
+	case "update":
+		if resolved, err = values(1, 2); err == nil {
+			edit.New = resolved[0]
+			if len(resolved) == 2 {
+				edit.Old, edit.CheckOld = resolved[1], true
+			}
+			if edit.New == "" {
+				err = fmt.Errorf("update %s: missing new value", edit.Ref)
+			}
+		}
+	case "create":
+		if resolved, err = values(1, 1); err == nil {
+			edit.New, edit.CheckOld = resolved[0], true
+			if edit.New == "" {
+				err = fmt.Errorf("create %s: zero new value", edit.Ref)
+			}
+		}
+	case "delete":
+		if resolved, err = values(0, 1); err == nil && len(resolved) == 1 {
+			edit.Old, edit.CheckOld = resolved[0], true
+			if edit.Old == "" {
+				err = fmt.Errorf("delete %s: zero old value", edit.Ref)
+			}
+		}
+	case "verify":
+		if resolved, err = values(0, 1); err == nil {
+			edit.Verify, edit.CheckOld = true, true
+			if len(resolved) == 1 {
+				edit.Old = resolved[0]
+			}
+		}
+	default:
+		err = fmt.Errorf("unknown command: %s", line)
+	}
+	return edit, err
+}
+
+// NEW: Symbolic-ref command
+func handleSymbolicRef(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("symbolic-ref")
+	quiet, short := false, false
+	message := ""
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; arg {
+		case "-q", "--quiet":
+			quiet = true
+		case "--short":
+			short = true
+		case "-m":
+			if i+1 >= len(args) {
+				return usage
+			}
+			i++
+			message = args[i]
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return usage
+			}
+			rest = append(rest, arg)
+		}
+	}
+
+	switch len(rest) {
+	case 1:
+		target, err := repo.SymbolicRef(rest[0])
+		if err != nil {
+			if quiet {
+				return negativeResult()
+			}
+			return err
+		}
+		if short {
+			for _, prefix := range []string{"refs/heads/", "refs/remotes/", "refs/"} {
+				if name, ok := strings.CutPrefix(target, prefix); ok {
+					target = name
+					break
+				}
+			}
+		}
+		fmt.Println(target)
+		return nil
+	case 2:
+		return repo.SetSymbolicRef(rest[0], rest[1], message)
+	default:
+		return usage
+	}
+}
+
+// NEW: Show-ref command
+func handleShowRef(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("show-ref")
+	var opts gvc.ShowRefOptions
+	dereference, quiet := false, false
+	hashLen := -1
+	var patterns []string
+	for _, arg := range args {
+		switch {
+		case arg == "--head":
+			opts.Head = true
+		case arg == "--heads":
+			opts.Heads = true
+		case arg == "--tags":
+			opts.Tags = true
+		case arg == "--verify":
+			opts.Verify = true
+		case arg == "-d" || arg == "--dereference":
+			dereference = true
+		case arg == "-q" || arg == "--quiet":
+			quiet = true
+		case arg == "-s" || arg == "--hash":
+			hashLen = 0
+		case strings.HasPrefix(arg, "--hash="):
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--hash="))
+			if err != nil || n < 4 {
+				return usage
+			}
+			hashLen = n
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			patterns = append(patterns, arg)
+		}
+	}
+	if opts.Verify && len(patterns) == 0 {
+		return usageError("--verify requires a reference")
+	}
+
+	refs, err := repo.ShowRefs(patterns, opts)
+	if err != nil {
+		if quiet {
+			return negativeResult()
+		}
+		return err
+	}
+	if len(refs) == 0 {
+		return negativeResult()
+	}
+	if quiet {
+		return nil
+	}
+	show := func(sha, name string) {
+		switch {
+		case hashLen > 0:
+			fmt.Println(sha[:min(hashLen, len(sha))])
+		case hashLen == 0:
+			fmt.Println(sha)
+		default:
+			fmt.Printf("%s %s\n", sha, name)
+		}
+	}
+	for _, ref := range refs {
+		if ref.Tag == "" {
+			show(ref.SHA, ref.Name)
+			continue
+		}
+		show(ref.Tag, ref.Name)
+		if dereference {
+			show(ref.SHA, ref.Name+"^{}")
+		}
+	}
+	return nil
+}
+
+// NEW: Pack-refs command
+func handlePackRefs(repo *gvc.Repository, args []string) error {
+	all, prune := false, true
+	for _, arg := range args {
+		switch arg {
+		case "--all":
+			all = true
+		case "--no-prune":
+			prune = false
+		case "--prune":
+			prune = true
+		default:
+			return commandUsage("pack-refs")
+		}
+	}
+	_, err := repo.PackRefs(all, prune)
+	return err
+}
+
+// NEW: Rm command
+func handleRm(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("rm")
+	var opts gvc.RemoveOptions
+	quiet := false
+	var paths []string
+	for i, arg := range args {
+		if arg == "--" {
+			paths = append(paths, args[i+1:]...)
+			break
+		}
+		switch arg {
+		case "--cached":
+			opts.Cached = true
+		case "-r":
+			opts.Recursive = true
+		case "-f", "--force":
+			opts.Force = true
+		case "-n", "--dry-run":
+			opts.DryRun = true
+		case "-q", "--quiet":
+			quiet = true
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return usage
+			}
+			paths = append(paths, arg)
+		}
+	}
+	if len(paths) == 0 {
+		return usage
+	}
+	for i, path := range paths {
+		rel, err := repo.RelPath(path)
+		if err != nil {
+			return err
+		}
+		paths[i] = rel
+	}
+
+	removed, err := repo.Remove(opts, paths...)
+	if err != nil {
+		return err
+	}
+	if !quiet {
+		for _, path := range removed {
+			fmt.Printf("rm '%s'\n", path)
+		}
+	}
+	return nil
+}
+
+// NEW: Count-objects command
+func handleCountObjects(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("count-objects")
+	verbose, human := false, false
+	var opts gvc.CountObjectsOptions
+	for _, arg := range args {
+		switch {
+		case arg == "-v" || arg == "--verbose":
+			verbose = true
+		case arg == "-H" || arg == "--human-readable":
+			human = true
+		case arg == "--largest":
+			opts.Largest = 10
+		case strings.HasPrefix(arg, "--largest="):
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--largest="))
+			if err != nil || n <= 0 {
+				return usage
+			}
+			opts.Largest = n
+		default:
+			return usage
+		}
+	}
+	opts.Unreachable = verbose
+
+	stats, err := repo.CountObjects(opts)
+	if err != nil {
+		return err
+	}
+	// Sizes are in KiB as in Git, unless asked to be readable
+	size := func(bytes int64) string {
+		if human {
+			return gvc.FormatSize(bytes)
+		}
+		return strconv.FormatInt(bytes/1024, 10)
+	}
+	if !verbose {
+		if human {
+			fmt.Printf("%d objects, %s\n", stats.Loose, size(stats.LooseSize))
+		} else {
+			fmt.Printf("%d objects, %s kilobytes\n", stats.Loose, size(stats.LooseSize))
+		}
+	} else {
+		fmt.Printf("count: %d\n", stats.Loose)
+		fmt.Printf("size: %s\n", size(stats.LooseSize))
+		fmt.Printf("in-pack: %d\n", stats.InPack)
+		fmt.Printf("packs: %d\n", stats.Packs)
+		fmt.Printf("size-pack: %s\n", size(stats.PackSize))
+		fmt.Printf("prune-packable: %d\n", stats.PrunePackable)
+		fmt.Printf("garbage: %d\n", stats.Garbage)
+		fmt.Printf("size-garbage: %s\n", size(stats.GarbageSize))
+		fmt.Printf("unreachable: %d\n", stats.Unreachable)
+	}
+	if len(stats.Largest) > 0 {
+		fmt.Println("largest blobs:")
+		for _, blob := range stats.Largest {
+			fmt.Printf("%s %10s %s\n", blob.SHA, gvc.FormatSize(blob.Size), blob.Path)
+		}
+	}
+	if verbose && stats.GCAdvised {
+		fmt.Fprintln(os.Stderr, "hint: there are many loose objects or packs; run 'gvc gc' to pack them")
+	}
+	return nil
+}
+
+// NEW: Prune command
+func handlePrune(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("prune")
+	var opts gvc.PruneOptions
+	verbose := false
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-n" || arg == "--dry-run":
+			opts.DryRun = true
+		case arg == "-v" || arg == "--verbose":
+			verbose = true
+		case arg == "--expire":
+			if i+1 >= len(args) {
+				return usage
+			}
+			i++
+			opts.Expire = args[i]
+		case strings.HasPrefix(arg, "--expire="):
+			opts.Expire = strings.TrimPrefix(arg, "--expire=")
+		default:
+			return usage
+		}
+	}
+
+	result, err := repo.Prune(opts)
+	if err != nil {
+		return err
+	}
+	if opts.DryRun || verbose {
+		for _, obj := range result.Objects {
+			fmt.Printf("%s %s\n", obj.SHA, obj.Type)
+		}
+	}
+	for _, name := range result.TempFiles {
+		if opts.DryRun {
+			fmt.Printf("Would remove stale temporary file %s\n", name)
+		} else if verbose {
+			fmt.Printf("Removed stale temporary file %s\n", name)
+		}
+	}
+	for _, name := range result.Locks {
+		if opts.DryRun {
+			fmt.Printf("Would remove stale lock %s\n", name)
+		} else if verbose {
+			fmt.Printf("Removed stale lock %s\n", name)
+		}
+	}
+	return nil
+}
+
+// NEW: Format-patch command
+func handleFormatPatch(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("format-patch")
+	var opts gvc.FormatPatchOptions
+	var revs []string
+	outDir, stdout := "", false
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-o" || arg == "--output-directory":
+			if i+1 >= len(args) {
+				return usage
+			}
+			i++
+			outDir = args[i]
+		case strings.HasPrefix(arg, "--output-directory="):
+			outDir = strings.TrimPrefix(arg, "--output-directory=")
+		case arg == "--stdout":
+			stdout = true
+		case arg == "-n" || arg == "--numbered":
+			opts.Numbered = true
+		case arg == "-N" || arg == "--no-numbered":
+			opts.NoNumbered = true
+		case strings.HasPrefix(arg, "--subject-prefix="):
+			opts.SubjectPrefix = strings.TrimPrefix(arg, "--subject-prefix=")
+		case len(arg) > 1 && arg[0] == '-':
+			n, err := strconv.Atoi(arg[1:])
+			if err != nil || n <= 0 {
+				return usage
+			}
+			opts.MaxCount = n
+		default:
+			revs = append(revs, arg)
+		}
+	}
+	if stdout && outDir != "" {
+		return usageError("--stdout and -o cannot be used together")
+	}
+
+	patches, err := repo.FormatPatches(revs, opts)
+	if err != nil {
+		return err
+	}
+	if stdout {
+		for _, patch := range patches {
+			if _, err := os.Stdout.Write(patch.Content); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if outDir != "" {
+		if err := os.MkdirAll(outDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	for _, patch := range patches {
+		path := filepath.Join(outDir, patch.Name)
+		if err := os.WriteFile(path, patch.Content, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Println(path)
+	}
+	return nil
+}
+
+// NEW: Apply command
+func handleApply(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("apply")
+	opts := gvc.ApplyOptions{Strip: 1}
+	verbose := false
+	var files []string
+	for _, arg := range args {
+		switch {
+		case arg == "--check":
+			opts.Check = true
+		case arg == "--cached":
+			opts.Cached = true
+		case arg == "--index":
+			opts.Index = true
+		case arg == "-3" || arg == "--3way":
+			opts.ThreeWay = true
+		case arg == "-v" || arg == "--verbose":
+			verbose = true
+		case strings.HasPrefix(arg, "-p") && len(arg) > 2:
+			n, err := strconv.Atoi(arg[2:])
+			if err != nil || n < 0 {
+				return usage
+			}
+			opts.Strip = n
+		case strings.HasPrefix(arg, "--fuzz="):
+			n, err := strconv.Atoi(strings.TrimPrefix(arg, "--fuzz="))
+			if err != nil || n < 0 {
+				return usage
+			}
+			opts.Fuzz = n
+		case arg == "-":
+			files = append(files, arg)
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			files = append(files, arg)
+		}
+	}
+	if opts.Cached && opts.Index {
+		return usageError("--cached and --index cannot be used together")
+	}
+
+	// Patches are read from standard input when no files are named
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	var patch []byte
+	for _, file := range files {
+		var content []byte
+		var err error
+		if file == "-" {
+			content, err = io.ReadAll(os.Stdin)
+		} else {
+			content, err = os.ReadFile(file)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read patch %s: %w", file, err)
+		}
+		patch = append(patch, content...)
+	}
+
+	applied, err := repo.Apply(patch, opts)
+	var applyErr *gvc.ApplyError
+	if errors.As(err, &applyErr) {
+		return &ExitError{Code: ExitNegative, Err: err}
+	}
+	var conflictErr *gvc.ConflictError
+	if err != nil && !errors.As(err, &conflictErr) {
+		return err
+	}
+	for _, file := range applied {
+		for _, note := range file.Notes {
+			fmt.Printf("%s: %s\n", file.Path(), note)
+		}
+		if verbose && !opts.Check {
+			switch {
+			case file.Conflicted:
+				fmt.Printf("Applied patch %s with conflicts.\n", file.Path())
+			case len(file.Notes) == 0:
+				fmt.Printf("Applied patch %s cleanly.\n", file.Path())
+			default:
+				fmt.Printf("Applied patch %s with changes.\n", file.Path())
+			}
+		}
+	}
+	// Conflicts leave the patch applied, so the notes above are printed first
+	return err
+}
+
+// NEW: Checkout command
+func handleCheckout(repo *gvc.Repository, args []string) error {
+	usage := usageError(usageText("checkout") + "\n(use 'gvc switch' to change branches)")
+	recreate := false
+	var paths []string
+	for i, arg := range args {
+		if arg == "--" {
+			paths = append(paths, args[i+1:]...)
+			break
+		}
+		switch {
+		case arg == "-m" || arg == "--merge":
+			recreate = true
+		case strings.HasPrefix(arg, "--conflict="):
+			if style := strings.TrimPrefix(arg, "--conflict="); style != "merge" {
+				return usageError(fmt.Sprintf("unsupported conflict style '%s' (use merge)", style))
+			}
+			recreate = true
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			paths = append(paths, arg)
+		}
+	}
+	if !recreate || len(paths) == 0 {
+		return usage
+	}
+
+	specs := make([]string, len(paths))
+	for i, path := range paths {
+		rel, err := repo.RelPath(path)
+		if err != nil {
+			return err
+		}
+		specs[i] = rel
+	}
+	recreated, err := repo.RecreateConflicts(specs)
+	if err != nil {
+		return err
+	}
+	if len(recreated) == 1 {
+		fmt.Println("Recreated 1 merge conflict")
+	} else {
+		fmt.Printf("Recreated %d merge conflicts\n", len(recreated))
+	}
+	return nil
+}
+
+// parseDepth reads the value of a --depth option, a positive number of commits
+func parseDepth(value string) (int, error) {
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth <= 0 {
+		return 0, usageError(fmt.Sprintf("depth %s is not a positive number", value))
+	}
+	return depth, nil
+}
+
+// parseLimitRate reads the value of a --limit-rate option, bytes per second
+// with an optional k, m or g suffix
+func parseLimitRate(value string) (int64, error) {
+	rate, err := gvc.ParseSize(value)
+	if err != nil || rate <= 0 {
+		return 0, usageError(fmt.Sprintf("limit rate %s is not a positive size", value))
+	}
+	return rate, nil
+}
+
+// NEW: Sparse-checkout command
+func handleSparseCheckout(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("sparse-checkout")
+	if len(args) == 0 {
+		return usage
+	}
+
+	switch args[0] {
+	case "set":
+		if len(args) == 1 {
+			return usage
+		}
+		return repo.SetSparseCheckout(args[1:])
+	case "list":
+		if len(args) > 1 {
+			return usage
+		}
+		paths, err := repo.SparseCheckoutPaths()
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			fmt.Println(path)
+		}
+		return nil
+	case "disable":
+		if len(args) > 1 {
+			return usage
+		}
+		return repo.DisableSparseCheckout()
+	case "reapply":
+		if len(args) > 1 {
+			return usage
+		}
+		return repo.ReapplySparseCheckout()
+	default:
+		return usage
+	}
+}
+
+// NEW: Rewrite-authors command
+func handleRewriteAuthors(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("rewrite-authors")
+	var opts gvc.RewriteAuthorsOptions
+	var files []string
+	for _, arg := range args {
+		switch {
+		case arg == "-n" || arg == "--dry-run":
+			opts.DryRun = true
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			files = append(files, arg)
+		}
+	}
+	if len(files) != 1 {
+		return usage
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		return fmt.Errorf("failed to read mapping file: %w", err)
+	}
+	mapping, err := gvc.ParseIdentMap(data)
+	if err != nil {
+		return fmt.Errorf("invalid mapping file %s: %w", files[0], err)
+	}
+
+	// The SHA map goes to stdout, so warnings must not
+	repo.Out = os.Stderr
+	rewritten, err := repo.RewriteAuthors(mapping, opts)
+	if err != nil {
+		return err
+	}
+	oldSHAs := make([]string, 0, len(rewritten))
+	for oldSHA := range rewritten {
+		oldSHAs = append(oldSHAs, oldSHA)
+	}
+	slices.Sort(oldSHAs)
+	for _, oldSHA := range oldSHAs {
+		fmt.Printf("%s %s\n", oldSHA, rewritten[oldSHA])
+	}
+	if verbosity != quiet {
+		action := "Rewrote"
+		if opts.DryRun {
+			action = "Would rewrite"
+		}
+		fmt.Fprintf(os.Stderr, "%s %d objects\n", action, len(rewritten))
+	}
+	return nil
+}
+
+// NEW: Prompt command
+func handlePrompt(repo *gvc.Repository, args []string) error {
+	var opts gvc.PromptOptions
+	for _, arg := range args {
+		switch arg {
+		case "-u", "--untracked":
+			opts.Untracked = true
+		default:
+			return commandUsage("prompt")
+		}
+	}
+	status, err := repo.PromptStatus(opts)
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		return printJSON(jsonPrompt{
+			jsonRecord: record("prompt"),
+			Branch:     status.Branch,
+			Commit:     status.Commit,
+			Upstream:   status.Upstream,
+			Ahead:      status.Ahead,
+			Behind:     status.Behind,
+			Staged:     status.Staged,
+			Unstaged:   status.Unstaged,
+			Untracked:  status.Untracked,
+			Operation:  status.Operation,
+		})
+	}
+
+	// The layout follows Git's bash prompt: "main *+% u+1-2|REBASE"
+	var b strings.Builder
+	switch {
+	case status.Branch != "":
+		b.WriteString(status.Branch)
+	case status.Commit != "":
+		fmt.Fprintf(&b, "(%s)", status.Commit[:7])
+	}
+	markers := ""
+	if status.Unstaged {
+		markers += "*"
+	}
+	if status.Staged {
+		markers += "+"
+	}
+	if status.Untracked {
+		markers += "%"
+	}
+	if markers != "" {
+		b.WriteString(" " + markers)
+	}
+	if status.Upstream != "" {
+		switch {
+		case status.Ahead == 0 && status.Behind == 0:
+			b.WriteString(" u=")
+		default:
+			b.WriteString(" u")
+			if status.Ahead > 0 {
+				fmt.Fprintf(&b, "+%d", status.Ahead)
+			}
+			if status.Behind > 0 {
+				fmt.Fprintf(&b, "-%d", status.Behind)
+			}
+		}
+	}
+	if status.Operation != "" {
+		b.WriteString("|" + strings.ToUpper(status.Operation))
+	}
+	fmt.Println(b.String())
+	return nil
+}
+
+// jsonPrompt is the record prompt --json prints
+type jsonPrompt struct {
+	jsonRecord
+	Branch    string `json:"branch"`
+	Commit    string `json:"commit"`
+	Upstream  string `json:"upstream,omitempty"`
+	Ahead     int    `json:"ahead"`
+	Behind    int    `json:"behind"`
+	Staged    bool   `json:"staged"`
+	Unstaged  bool   `json:"unstaged"`
+	Untracked bool   `json:"untracked"`
+	Operation string `json:"operation,omitempty"`
+}
+
+// NEW: Notes command
+func handleNotes(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("notes")
+	if len(args) == 0 {
+		return usage
+	}
+	subcommand, args := args[0], args[1:]
+	force, ref := false, gvc.NotesRef
+	var messages, revs []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case strings.HasPrefix(arg, "--ref=") && len(arg) > len("--ref="):
+			ref = strings.TrimPrefix(arg, "--ref=")
+		case arg == "--ref" && i+1 < len(args):
+			i++
+			ref = args[i]
+		case subcommand == "add" && (arg == "-f" || arg == "--force"):
+			force = true
+		case subcommand == "add" && arg == "-m" && i+1 < len(args):
+			i++
+			messages = append(messages, args[i])
+		case subcommand == "add" && strings.HasPrefix(arg, "-m") && len(arg) > 2:
+			messages = append(messages, arg[2:])
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			revs = append(revs, arg)
+		}
+	}
+	if len(revs) > 1 {
+		return usage
+	}
+	rev := "HEAD"
+	if len(revs) == 1 {
+		rev = revs[0]
+	}
+
+	switch subcommand {
+	case "add":
+		if len(messages) == 0 {
+			return usage
+		}
+		// Each -m is a paragraph, as for commit
+		return repo.AddNote(ref, rev, strings.Join(messages, "\n\n"), force)
+	case "show":
+		note, ok, err := repo.Note(ref, rev)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return &ExitError{Code: ExitNegative, Err: fmt.Errorf("no note found for %s", rev)}
+		}
+		fmt.Print(note)
+		return nil
+	case "remove":
+		return repo.RemoveNote(ref, rev)
+	}
+	return usage
+}
+
+// NEW: Global config, read and written without opening a repository, so a
+// repository gvc refuses to open can be trusted from inside it
+func handleGlobalConfig(args []string) error {
+	switch {
+	case len(args) == 1 && args[0] == "--list":
+		entries, err := gvc.GlobalConfigEntries()
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			fmt.Printf("%s=%s\n", entry[0], entry[1])
+		}
+		return nil
+	case len(args) == 1:
+		entries, err := gvc.GlobalConfigEntries()
+		if err != nil {
+			return err
+		}
+		value, found := "", false
+		for _, entry := range entries {
+			if strings.EqualFold(entry[0], args[0]) {
+				value, found = entry[1], true
+			}
+		}
+		if !found {
+			return negativeResult()
+		}
+		fmt.Println(value)
+		return nil
+	case len(args) == 3 && args[0] == "--add":
+		return gvc.AddGlobalConfig(args[1], args[2])
+	default:
+		return subcommandUsage("config", "--global")
+	}
+}
+
+// NEW: Tag command
+func handleTag(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("tag")
+
+	var opts gvc.TagListOptions
+	var names []string
+	var message string
+	list, deleteMode, force, annotate := false, false, false, false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		option, value, hasValue := strings.Cut(arg, "=")
+		switch {
+		case arg == "-l" || arg == "--list":
+			list = true
+		case arg == "-a" || arg == "--annotate":
+			annotate = true
+		case arg == "-m" || arg == "--message":
+			if i+1 >= len(args) {
+				return usage
+			}
+			message, annotate = args[i+1], true
+			i++
+		case arg == "-d" || arg == "--delete":
+			deleteMode = true
+		case arg == "-f" || arg == "--force":
+			force = true
+		case option == "--sort" && hasValue:
+			opts.Sort = value
+		case option == "--contains" || option == "--no-contains" || option == "--merged" || option == "--no-merged":
+			// The commit is optional and defaults to HEAD
+			if !hasValue {
+				value = "HEAD"
+				if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+					value = args[i+1]
+					i++
+				}
+			}
+			switch option {
+			case "--contains":
+				opts.Contains = value
+			case "--no-contains":
+				opts.NoContains = value
+			case "--merged":
+				opts.Merged = value
+			case "--no-merged":
+				opts.NoMerged = value
+			}
+			list = true
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			names = append(names, arg)
+		}
+	}
+
+	switch {
+	case annotate && (list || deleteMode || len(names) == 0):
+		return usage
+	case deleteMode:
+		if list || len(names) == 0 {
+			return usage
+		}
+		for _, name := range names {
+			sha, err := repo.DeleteTag(name)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Deleted tag '%s' (was %s)\n", name, sha[:min(7, len(sha))])
+		}
+		return nil
+	case !list && opts.Sort == "" && len(names) > 0:
+		if len(names) > 2 {
+			return usage
+		}
+		start := "HEAD"
+		if len(names) == 2 {
+			start = names[1]
+		}
+		if annotate {
+			return repo.CreateAnnotatedTag(names[0], start, message, force)
+		}
+		return repo.CreateTag(names[0], start, force)
+	}
+
+	opts.Patterns = names
+	tags, err := repo.ListTags(opts)
+	if err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		fmt.Println(strings.TrimPrefix(tag.Name, gvc.TagsDir+"/"))
+	}
+	return nil
+}
+
+// NEW: Changelog command
+func handleChangelog(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("changelog")
+	var format string
+	var revs []string
+	for _, arg := range args {
+		switch {
+		case strings.HasPrefix(arg, "--format="):
+			format = strings.TrimPrefix(arg, "--format=")
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			revs = append(revs, arg)
+		}
+	}
+	if len(revs) == 1 && strings.Contains(revs[0], "..") {
+		from, to, _ := strings.Cut(revs[0], "..")
+		revs = []string{from, to}
+	}
+	if len(revs) > 2 {
+		return usage
+	}
+	revs = append(revs, "", "")
+
+	changelog, err := repo.Changelog(revs[0], revs[1])
+	if err != nil {
+		return err
+	}
+	var entry func(gvc.ChangelogEntry) string
+	if format != "" {
+		// Entries take the placeholders of log --format
+		entry = func(e gvc.ChangelogEntry) string { return e.Commit.Format(format, "") }
+	}
+	fmt.Print(changelog.Markdown(entry))
+	return nil
+}
+
+// NEW: Serve command, a git daemon for clones and fetches of the
+// repositories below a directory
+func handleServe(args []string) error {
+	usage := commandUsage("serve")
+	host, port, base := "", strconv.Itoa(gvc.GitPort), "."
+	var positional []string
+	for _, arg := range args {
+		if v, ok := strings.CutPrefix(arg, "--listen="); ok {
+			host = v
+		} else if v, ok := strings.CutPrefix(arg, "--port="); ok {
+			if n, err := strconv.Atoi(v); err != nil || n < 0 || n > 65535 {
+				return fmt.Errorf("invalid port: %s", v)
+			}
+			port = v
+		} else if strings.HasPrefix(arg, "-") {
+			return usage
+		} else {
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) > 1 {
+		return usage
+	}
+	if len(positional) == 1 {
+		base = positional[0]
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	out, _ := outputWriters()
+	abs, err := filepath.Abs(base)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", base, err)
+	}
+	fmt.Fprintf(out, "Serving %s on git://%s/\n", abs, listener.Addr())
+	var log io.Writer = os.Stderr
+	if verbosity == quiet {
+		log = nil
+	}
+	return gvc.Serve(interruptContext(), listener, gvc.ServeOptions{BasePath: base, Log: log})
+}
+
+// splitWholeHelp explains the answers split takes for a change that cannot
+// be staged by hunk
+const splitWholeHelp = `y - stage this change
+n - do not stage this change
+q - quit; do not stage this change or any remaining ones
+? - print help
+`
+
+// NEW: Split command
+func handleSplit(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("split")
+	switch {
+	case len(args) == 1 && args[0] == "--continue":
+		return repo.FinishSplit()
+	case len(args) == 1 && args[0] == "--abort":
+		if commit, err := repo.SplittingCommit(); err != nil {
+			return err
+		} else if commit == nil {
+			return errors.New("no split in progress")
+		}
+		return repo.AbortRebase()
+	case len(args) > 1 || len(args) == 1 && strings.HasPrefix(args[0], "-"):
+		return usage
+	}
+
+	splitting, err := repo.SplittingCommit()
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		if splitting != nil {
+			return errors.New("a split is already in progress; use --continue or --abort")
+		}
+		if splitting, err = repo.StartSplit(args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Splitting %s %s\n", splitting.SHA[:7], strings.SplitN(splitting.Message, "\n", 2)[0])
+	} else if op := repo.OperationInProgress(); splitting == nil && op != "" {
+		return fmt.Errorf("a %s is in progress; finish it with 'gvc %s --continue' or '--abort'", op, op)
+	}
+
+	answers := bufio.NewReader(os.Stdin)
+	for n := 1; ; n++ {
+		patches, whole, err := repo.SplitChanges()
+		if err != nil {
+			return err
+		}
+		if len(patches) == 0 && len(whole) == 0 {
+			if n == 1 && splitting == nil {
+				fmt.Println("No changes.")
+			}
+			break
+		}
+		fmt.Printf("Choose the changes for commit %d.\n", n)
+		quit, err := choosePatchHunks(repo, answers, patches)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < len(whole) && !quit; i++ {
+			fmt.Printf("Stage the whole change to %s (%s) [y,n,q,?]? ", whole[i].Path, whole[i].Kind)
+			line, err := answers.ReadString('\n')
+			if err != nil && line == "" {
+				fmt.Println()
+				quit = true
+				break
+			}
+			switch strings.TrimSpace(line) {
+			case "y":
+				if err := repo.Add(whole[i].Path); err != nil {
+					return err
+				}
+			case "n":
+			case "q":
+				quit = true
+			default:
+				fmt.Print(splitWholeHelp)
+				i--
+			}
+		}
+
+		staged, err := repo.HasStagedChanges()
+		if err != nil {
+			return err
+		}
+		if !staged {
+			break
+		}
+		prompt := fmt.Sprintf("Message for commit %d: ", n)
+		if splitting != nil {
+			prompt = fmt.Sprintf("Message for commit %d (empty for the original): ", n)
+		}
+		fmt.Print(prompt)
+		message, err := answers.ReadString('\n')
+		if err != nil && message == "" {
+			fmt.Println()
+		}
+		message = strings.TrimSpace(message)
+		if message == "" && splitting == nil {
+			return errors.New("aborting commit due to empty message; the chosen changes are left staged")
+		}
+		if _, err := repo.CommitSplit(message); err != nil {
+			return err
+		}
+		if quit {
+			break
+		}
+	}
+
+	if splitting != nil {
+		return repo.FinishSplit()
+	}
+	patches, whole, err := repo.SplitChanges()
+	if err != nil {
+		return err
+	}
+	if left := len(patches) + len(whole); left > 0 {
+		fmt.Printf("Left %d changed file(s) uncommitted.\n", left)
+	}
+	return nil
+}
+
+// NEW: Subtree command
+func handleSubtree(repo *gvc.Repository, args []string) error {
+	usage := commandUsage("subtree")
+	if len(args) == 0 {
+		return usage
+	}
+	subcommand, args := args[0], args[1:]
+	if subcommand == "merge" && len(args) == 1 {
+		switch args[0] {
+		case "--continue":
+			_, err := repo.ContinueSubtreeMerge()
+			return err
+		case "--abort":
+			return repo.AbortSubtreeMerge()
+		}
+	}
+
+	var prefix, message string
+	var opts gvc.SubtreeSplitOptions
+	var positional []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "--prefix="):
+			prefix = strings.TrimPrefix(arg, "--prefix=")
+		case arg == "--prefix" || arg == "-P":
+			if i+1 >= len(args) {
+				return usage
+			}
+			prefix = args[i+1]
+			i++
+		case subcommand == "split" && arg == "-b":
+			if i+1 >= len(args) {
+				return usage
+			}
+			opts.Branch = args[i+1]
+			i++
+		case subcommand == "split" && strings.HasPrefix(arg, "--branch="):
+			opts.Branch = strings.TrimPrefix(arg, "--branch=")
+		case subcommand == "split" && arg == "--rejoin":
+			opts.Rejoin = true
+		case subcommand != "split" && arg == "-m":
+			if i+1 >= len(args) {
+				return usage
+			}
+			message = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "-"):
+			return usage
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if prefix == "" {
+		return usage
+	}
+
+	switch subcommand {
+	case "split":
+		if len(positional) > 1 {
+			return usage
+		}
+		rev := ""
+		if len(positional) == 1 {
+			rev = positional[0]
+		}
+		sha, err := repo.SubtreeSplit(prefix, rev, opts)
+		if err != nil {
+			return err
+		}
+		fmt.Println(sha)
+		return nil
+	case "add", "merge":
+		if len(positional) != 1 {
+			return usage
+		}
+		run := repo.SubtreeAdd
+		if subcommand == "merge" {
+			run = repo.SubtreeMerge
+		}
+		_, err := run(prefix, positional[0], message)
+		return err
+	}
+	return usage
+}
+
+// NEW: Version command
+
+// jsonVersion is the build and what it supports, as version --json prints it
+type jsonVersion struct {
+	jsonRecord
+	Version                  string          `json:"version"`
+	GoVersion                string          `json:"go_version"`
+	Platform                 string          `json:"platform"`
+	Revision                 string          `json:"revision,omitempty"`
+	RevisionTime             string          `json:"revision_time,omitempty"`
+	Modified                 bool            `json:"modified"`
+	RepositoryFormatVersions []int           `json:"repository_format_versions"`
+	Extensions               []string        `json:"extensions"`
+	ObjectFormats            []string        `json:"object_formats"`
+	IndexVersions            []int           `json:"index_versions"`
+	PackVersions             []int           `json:"pack_versions"`
+	BundleVersions           []int           `json:"bundle_versions"`
+	Transports               []jsonTransport `json:"transports"`
+	Features                 []string        `json:"features"`
+}
+
+// jsonTransport is a kind of repository address and what it can be used for
+type jsonTransport struct {
+	Scheme string `json:"scheme"`
+	Fetch  bool   `json:"fetch"`
+	Push   bool   `json:"push"`
+}
+
+func handleVersion(args []string) error {
+	buildInfo := false
+	for _, arg := range args {
+		if arg != "--build-info" {
+			return commandUsage("version")
+		}
+		buildInfo = true
+	}
+	info := gvc.ReadBuildInfo()
+	if terminalUI {
+		info.Features = append(info.Features, "ui")
+	}
+	slices.Sort(info.Features)
+
+	if jsonOutput {
+		transports := make([]jsonTransport, len(info.Transports))
+		for i, transport := range info.Transports {
+			transports[i] = jsonTransport{Scheme: transport.Scheme, Fetch: transport.Fetch, Push: transport.Push}
+		}
+		return printJSON(jsonVersion{
+			jsonRecord:               record("version"),
+			Version:                  info.Version,
+			GoVersion:                info.GoVersion,
+			Platform:                 info.Platform,
+			Revision:                 info.Revision,
+			RevisionTime:             info.RevisionTime,
+			Modified:                 info.Modified,
+			RepositoryFormatVersions: info.RepositoryFormatVersions,
+			Extensions:               info.Extensions,
+			ObjectFormats:            info.ObjectFormats,
+			IndexVersions:            info.IndexVersions,
+			PackVersions:             info.PackVersions,
+			BundleVersions:           info.BundleVersions,
+			Transports:               transports,
+			Features:                 info.Features,
+		})
+	}
+
+	fmt.Printf("gvc version %s\n", info.Version)
+	if !buildInfo {
+		return nil
+	}
+	revision := info.Revision
+	if revision == "" {
+		revision = "unknown"
+	} else if info.Modified {
+		revision += " (modified)"
+	}
+	transports := make([]string, len(info.Transports))
+	for i, transport := range info.Transports {
+		var uses []string
+		if transport.Fetch {
+			uses = append(uses, "fetch")
+		}
+		if transport.Push {
+			uses = append(uses, "push")
+		}
+		transports[i] = fmt.Sprintf("%s (%s)", transport.Scheme, strings.Join(uses, ", "))
+	}
+	fields := [][2]string{
+		{"go version", info.GoVersion},
+		{"platform", info.Platform},
+		{"revision", revision},
+		{"revision time", info.RevisionTime},
+		{"repository format versions", joinInts(info.RepositoryFormatVersions)},
+		{"extensions", strings.Join(info.Extensions, ", ")},
+		{"object formats", strings.Join(info.ObjectFormats, ", ")},
+		{"index versions", joinInts(info.IndexVersions)},
+		{"pack versions", joinInts(info.PackVersions)},
+		{"bundle versions", joinInts(info.BundleVersions)},
+		{"transports", strings.Join(transports, ", ")},
+		{"features", strings.Join(info.Features, ", ")},
+	}
+	for _, field := range fields {
+		if field[1] != "" {
+			fmt.Printf("%s: %s\n", field[0], field[1])
+		}
+	}
+	return nil
+}
+
+// joinInts lists numbers separated by commas
+func joinInts(numbers []int) string {
+	parts := make([]string, len(numbers))
+	for i, n := range numbers {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ", ")
+}
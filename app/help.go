@@ -0,0 +1,878 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// globalUsage is how gvc itself is invoked
+const globalUsage = "gvc [--read-only] [--json] [-q | -v] [--namespace=<name>] <command> [<args>...]"
+
+// commandHelp is the reference page of a command. The synopsis is also what
+// the command prints when its command line is invalid, so the two cannot
+// disagree.
+type commandHelp struct {
+	// synopsis lists the forms of the command, one per line; a line starting
+	// with spaces continues the form above it
+	synopsis string
+	// summary is a line for the command list
+	summary string
+	// description is paragraphs separated by blank lines; lines indented
+	// further are printed as they are
+	description string
+	// seeAlso names related commands and topics
+	seeAlso []string
+}
+
+// helpTopic is a guide to a subject several commands share
+type helpTopic struct {
+	summary string
+	text    string
+}
+
+// standaloneCommands are the commands main runs without a repository
+var standaloneCommands = []string{"init", "clone", "serve", "help", "version"}
+
+// helpPages holds the reference page of every command
+var helpPages = map[string]commandHelp{
+	"help": {
+		synopsis: "gvc help [<command> | <topic>]",
+		summary:  "Show the reference page of a command or a guide",
+		description: `Without arguments, lists the commands and the guides. With a command, prints its reference page: the forms it takes, the same ones it prints when given an invalid command line, and what it does. With a topic, prints that guide.
+
+"gvc <command> --help" and "gvc <command> -h" print the command's page too.`,
+	},
+	"version": {
+		synopsis: "gvc version [--build-info]",
+		summary:  "Show the version and what this build supports",
+		description: `Prints the version of gvc; "gvc --version" does the same. --build-info adds the Go release and platform it was built for, the commit it was built from, the repository format versions, extensions, object formats, index, pack and bundle versions it reads, the transports it fetches and pushes over, and the features available on this platform.
+
+With the global --json, prints all of it as one record, so tools can check a build supports a repository before they work on it.`,
+	},
+	"init": {
+		synopsis: "gvc init [--object-format=sha1|sha256] [--shared[=<permissions>]]",
+		summary:  "Create an empty repository",
+		description: `Creates a .gvc directory in the current directory. Every other command finds the repository by walking up from the current directory, or uses GVC_DIR when it is set.
+
+--object-format=sha256 names objects with SHA-256 instead of SHA-1, recorded as extensions.objectFormat. --shared sets up a repository several users push to: it sets core.sharedRepository (group when no value is given) and gives the new .gvc directory those permissions.`,
+		seeAlso: []string{"clone", "config"},
+	},
+	"clone": {
+		synopsis: `gvc clone [--no-hardlinks] [--recurse-submodules] [--depth <depth>] [--limit-rate <rate>]
+          <repository> [<directory>]`,
+		summary: "Copy a repository into a new directory",
+		description: `Copies a repository from a local path, a file:// URL or a git:// URL, sets it up as the remote origin and checks out its default branch. On the same filesystem, packs and loose objects are hardlinked rather than copied unless --no-hardlinks is given.
+
+--depth makes a shallow clone of the newest commits only, --limit-rate caps the transfer rate, and --recurse-submodules also initializes and checks out the submodules.`,
+		seeAlso: []string{"fetch", "push", "remote", "submodule"},
+	},
+	"serve": {
+		synopsis:    "gvc serve [--listen=<address>] [--port=<port>] [<base-path>]",
+		summary:     "Serve repositories over the git:// protocol",
+		description: `Answers git:// requests the way git daemon does, on port 9418 unless --port says otherwise. git://host/project names the repository <base-path>/project, and no path reaches outside the base path. Clones and fetches are served; pushes are refused.`,
+		seeAlso:     []string{"clone", "fetch"},
+	},
+	"cat-file": {
+		synopsis: `gvc cat-file -p <object>
+gvc cat-file (--batch | --batch-check) < <list-of-objects>
+gvc cat-file --batch-command [--buffer] < <list-of-commands>`,
+		summary: "Print the content of an object",
+		description: `-p prints an object, named by SHA or by any revision such as HEAD:README.md. --batch and --batch-check read object names from standard input and answer each with "<sha> <type> <size>", followed by the content for --batch.
+
+--batch-command reads "contents <object>", "info <object>" and "rev-parse <rev>" lines instead. Answers are flushed as they are written, or with --buffer only on a "flush" line and at the end of the input.`,
+		seeAlso: []string{"hash-object", "ls-tree", "revisions"},
+	},
+	"hash-object": {
+		synopsis: `gvc hash-object [-w] <file>...
+gvc hash-object [-w] --stdin`,
+		summary:     "Compute the object ID of a file",
+		description: `Prints the blob SHA of each file, or of standard input with --stdin. -w also stores the blob in the repository. Files are streamed, so they may be larger than memory.`,
+		seeAlso:     []string{"cat-file"},
+	},
+	"ls-tree": {
+		synopsis: "gvc ls-tree [--name-only | -l | --format=<format>] [--abbrev[=<n>]] <tree-ish>",
+		summary:  "List the contents of a tree",
+		description: `Lists the entries of a tree, or of the tree a commit records, as "<mode> <type> <sha>" and the name after a tab. -l adds the size of each blob, --abbrev shortens SHAs to n digits (7 by default) and --name-only prints the names alone.
+
+--format prints each entry through a format string with %(objectmode), %(objecttype), %(objectname), %(objectsize), %(objectsize:padded), %(path), %n, %xNN and %%.`,
+		seeAlso: []string{"cat-file", "ls-files", "revisions"},
+	},
+	"ls-files": {
+		synopsis:    "gvc ls-files [-c | --cached] [-s | --stage] [-m | --modified] [-o | --others [-i | --ignored]]",
+		summary:     "List the files in the index and the working tree",
+		description: `Lists the files in the index, the ones the next commit records. --stage adds each file's mode and blob SHA, --modified lists tracked files whose working copy differs from the staged one, and --others lists untracked files, or with --ignored the ignored ones.`,
+		seeAlso:     []string{"status", "check-ignore", "ignore"},
+	},
+	"check-ignore": {
+		synopsis:    "gvc check-ignore [-v] <path>...",
+		summary:     "Tell which paths are ignored",
+		description: `Prints the paths given that ignore rules leave out, and exits with 1 when none is. -v also prints the file, line and pattern that decided each path.`,
+		seeAlso:     []string{"ls-files", "ignore"},
+	},
+	"check-ref-format": {
+		synopsis: `gvc check-ref-format [--normalize] [--[no-]allow-onelevel] <refname>
+gvc check-ref-format --branch <branchname-shorthand>`,
+		summary:     "Check that a ref name is valid",
+		description: `Checks a ref name against Git's rules, exiting with 1 when it is invalid. --normalize prints the name with a leading slash dropped and repeated slashes collapsed. --branch expands @{-n}, the nth branch checked out before the current one, and prints the branch name.`,
+		seeAlso:     []string{"update-ref", "revisions"},
+	},
+	"write-tree": {
+		synopsis:    "gvc write-tree",
+		summary:     "Create a tree object from the working tree",
+		description: `Stores the files of the working tree that are not ignored as blobs, builds trees from them and prints the SHA of the top tree. Files are hashed on core.threads workers.`,
+		seeAlso:     []string{"commit-tree", "ls-tree"},
+	},
+	"commit-tree": {
+		synopsis:    "gvc commit-tree <tree_sha> -p <parent_sha> -m <commit_message>",
+		summary:     "Create a commit object from a tree",
+		description: `Writes a commit recording the tree, with the parent and message given, and prints its SHA. No ref is moved.`,
+		seeAlso:     []string{"write-tree", "update-ref"},
+	},
+	"add": {
+		synopsis: `gvc add [-n | --dry-run] [-f | --force] [-A | --all | -u | --update] [<path>...]
+gvc add (-p | --patch) [<path>...]`,
+		summary: "Stage changes for the next commit",
+		description: `Copies files into the index. A directory stages every file below it that is not ignored, and tracked files deleted from the working tree are staged as removals. -u restages every tracked file, including deletions, and -A also picks up new files. Ignored files are refused unless -f is given, and -n only prints what would be staged.
+
+-p goes through the unstaged changes hunk by hunk and asks whether to stage each one: y stages it, n skips it, s splits it, a and d stage or skip the rest of the file, and q stops.`,
+		seeAlso: []string{"rm", "commit", "status", "ignore"},
+	},
+	"rm": {
+		synopsis:    "gvc rm [--cached] [-r] [-f | --force] [-n | --dry-run] [-q | --quiet] [--] <path>...",
+		summary:     "Remove files from the index and the working tree",
+		description: `Stops tracking files and deletes them, so the next commit records their removal; --cached keeps the working tree copies. Directories need -r. A file with staged or unstaged changes is refused unless -f is given, and -n only prints what would be removed.`,
+		seeAlso:     []string{"add", "clean"},
+	},
+	"commit": {
+		synopsis: `gvc commit [-a | --all] [-S | --gpg-sign | --no-gpg-sign] [-n | --no-verify] [--allow-secrets] [--dry-run]
+           [-e | --edit] [-t <file> | --template=<file>] [-m <message>]
+gvc commit [<options>] --amend [-m <message>]
+gvc commit [<options>] (--fixup | --squash) <commit> [-m <message>]`,
+		summary: "Record the staged changes as a new commit",
+		description: `Records the index as a new commit on the current branch. -a first stages every change to tracked files. Without -m, or with -e, the message is written in core.editor, starting from commit.template or -t. --amend replaces the last commit, and --fixup and --squash make a commit rebase --autosquash folds into the one named. --dry-run prints what would be committed.
+
+The pre-commit and commit-msg hooks run first and can stop the commit; --no-verify skips them unless hooks.allowNoVerify is false. The lines the commit adds are scanned for credentials, and --allow-secrets commits despite a match. -S signs the commit with user.signingKey.`,
+		seeAlso: []string{"add", "log", "verify-commit", "hooks"},
+	},
+	"verify-commit": {
+		synopsis:    "gvc verify-commit [-v] <commit>...",
+		summary:     "Check the signatures of commits",
+		description: `Checks each commit's signature with gpg or ssh-keygen and shows who signed it; SSH signers are looked up in gpg.ssh.allowedSignersFile. -v also prints the commit.`,
+		seeAlso:     []string{"commit", "log"},
+	},
+	"log": {
+		synopsis: `gvc log [--oneline | --format=<format>] [-n <count>] [--author=<pattern>]
+        [--since=<date>] [--until=<date>] [--graph] [--left-right] [--show-signature]
+        [--topo-order | --date-order | --author-date-order] [--full-history] [--follow]
+        [--reverse] [--boundary] [--notes[=<ref>] | --no-notes]
+        [--all | --branches[=<pattern>] | --tags[=<pattern>] | --remotes[=<pattern>]]
+        [<revision range>...] [-- <path>...]`,
+		summary: "Show the commit history",
+		description: `Lists the commits reachable from HEAD, or from the revisions and ranges given. --oneline and --format change the layout, -n limits the count, and --author, --since and --until filter the commits. Paths after -- keep the commits that changed them, simplifying merges as Git does unless --full-history is given; --follow follows a single file across renames.
+
+--graph draws the history as rails, --left-right marks the side of a symmetric difference each commit is on, and --notes adds the notes attached to each commit.`,
+		seeAlso: []string{"show", "rev-list", "shortlog", "revisions"},
+	},
+	"show": {
+		synopsis:    "gvc show [--show-signature] [--stat] [--notes[=<ref>] | --no-notes] [-M[<n>] | --no-renames] [<object>...]",
+		summary:     "Show a commit, tree or file",
+		description: `Prints a commit with its patch against the first parent, HEAD by default. Trees are listed and blobs printed as they are, so <rev>:<path> shows a file as of a commit. --stat prints a diffstat instead of the patch.`,
+		seeAlso:     []string{"log", "diff", "revisions"},
+	},
+	"diff": {
+		synopsis: `gvc diff [--stat] [--exit-code] [-M[<n>] | --no-renames] [--submodule[=<format>]]
+         [--ignore-submodules[=<when>]] [--relative[=<path>] | --no-relative]
+         [--src-prefix=<prefix>] [--dst-prefix=<prefix>] [--no-prefix | --default-prefix] [<commit>]
+gvc diff [<options>] (--cached | --staged) [<commit>]
+gvc diff [<options>] <commit> <commit>`,
+		summary: "Show changes between the working tree, the index and commits",
+		description: `Shows the unstaged changes as a patch, or with --cached the staged changes against HEAD or the commit given. With one commit, compares it to the working tree; with two, compares them.
+
+--stat prints a diffstat and --exit-code exits with 1 when there are differences. Renames are detected at -M similarity (50% by default). --relative limits the diff to the current directory and prints paths relative to it.`,
+		seeAlso: []string{"status", "show", "apply"},
+	},
+	"stash": {
+		synopsis: `gvc stash push [--keep-index | --staged] [-m <message>]
+gvc stash list [--stat] [--date=relative|iso]
+gvc stash pop [stash@{n}]
+gvc stash drop [stash@{n}]`,
+		summary:     "Shelve local changes and bring them back",
+		description: `push, the default, saves the staged and unstaged changes as commits under refs/stash and resets the working tree to HEAD. --keep-index leaves the staged changes in place, and --staged stashes only them. list shows the entries, pop applies one and drops it, and drop discards it.`,
+		seeAlso:     []string{"autosnapshot"},
+	},
+	"autosnapshot": {
+		synopsis: `gvc autosnapshot [--interval=<duration>]
+gvc autosnapshot save
+gvc autosnapshot list [<branch> | --all] [--date=relative|iso]
+gvc autosnapshot restore <snapshot> [--] [<path>...]`,
+		summary:     "Take background snapshots of the working tree",
+		description: `Checks the working tree every five minutes, or at --interval, until interrupted, and commits it whenever it changed to refs/snapshots/<branch>. Branches, HEAD and the index are never touched. save takes one snapshot now, list shows them as snapshots/<branch>~<n>, and restore writes a snapshot's files back after snapshotting the current state.`,
+		seeAlso:     []string{"stash", "backup"},
+	},
+	"reflog": {
+		synopsis:    "gvc reflog [show] [<ref>]",
+		summary:     "Show where a ref has pointed",
+		description: `Lists every recorded update of HEAD, or of the ref given, newest first, so commits a ref no longer points to can be found again as <ref>@{n}.`,
+		seeAlso:     []string{"log", "revisions"},
+	},
+	"cherry-pick": {
+		synopsis: `gvc cherry-pick <commit> | --continue | --abort
+gvc cherry-pick (-n | --no-commit) <commit>...`,
+		summary:     "Apply the changes of a commit",
+		description: `Replays the change a commit introduced onto the current branch with a three-way merge, stopping with conflict markers when it does not apply cleanly; resolve them and run --continue, or --abort. -n applies the changes of the commits to the index and working tree without committing.`,
+		seeAlso:     []string{"revert", "rebase", "checkout"},
+	},
+	"merge": {
+		synopsis: `gvc merge [--no-commit] [-m <message>] <commit>
+gvc merge --continue | --abort`,
+		summary:     "Join another history into the current branch",
+		description: `Fast-forwards the current branch to the commit when it is behind, and otherwise merges the changes since their merge base into it as a merge commit, stopping on conflicts until --continue or --abort. --no-commit stops before the merge commit with the result staged, so it can be checked or changed first; --continue then records it.`,
+		seeAlso:     []string{"cherry-pick", "merge-base", "rebase"},
+	},
+	"revert": {
+		synopsis:    "gvc revert <commit> | --continue | --abort",
+		summary:     "Undo a commit with a new commit",
+		description: `Records a new commit undoing the changes an earlier commit introduced, stopping on conflicts until --continue or --abort.`,
+		seeAlso:     []string{"cherry-pick"},
+	},
+	"rebase": {
+		synopsis: `gvc rebase [-i | --interactive] [--autosquash | --no-autosquash] <upstream>
+gvc rebase --continue | --abort`,
+		summary:     "Replay the current branch on top of another",
+		description: `Replays the commits of the current branch that upstream lacks on top of it, stopping on conflicts until --continue or --abort. --autosquash, or rebase.autoSquash, folds commits made with commit --fixup or --squash into the commits they name. -i opens the list of steps in the editor first, already reordered by --autosquash, to be reordered, marked pick, squash, fixup or drop, or emptied to abort.`,
+		seeAlso:     []string{"cherry-pick", "commit", "split"},
+	},
+	"split": {
+		synopsis: `gvc split [<commit>]
+gvc split --continue | --abort`,
+		summary:     "Split changes into several commits",
+		description: `Without arguments, goes through the uncommitted changes hunk by hunk, as add -p does, asking for the changes and message of each new commit. With a commit, rewrites the branch so that commit is replaced by several, then replays the commits after it.`,
+		seeAlso:     []string{"add", "rebase"},
+	},
+	"checkout": {
+		synopsis:    "gvc checkout (-m | --merge | --conflict=merge) [--] <pathspec>...",
+		summary:     "Recreate the conflict markers of resolved files",
+		description: `Writes the files given with conflict markers again, from the versions kept when a cherry-pick, revert or rebase stopped on them, so a botched resolution can be redone. Branches are changed with switch.`,
+		seeAlso:     []string{"switch", "cherry-pick"},
+	},
+	"sparse-checkout": {
+		synopsis: `gvc sparse-checkout set <path>...
+gvc sparse-checkout list | disable | reapply`,
+		summary:     "Check out only part of the tree",
+		description: `set keeps only the files at or below the paths given in the working tree; the others stay in the index with the skip-worktree flag, so commits keep them unchanged. list prints the paths, reapply applies them again, and disable checks out everything.`,
+		seeAlso:     []string{"switch"},
+	},
+	"config": {
+		synopsis: `gvc config <key> [<value>] | --list
+gvc config --global <key> | --add <key> <value> | --list`,
+		summary:     "Read and write settings",
+		description: `With a key, prints its value from .gvc/config; with a value too, sets it. --list prints every setting. --global reads and writes the per-user config, ~/.config/gvc/config, and --add adds a value to a key that takes several, such as safe.directory.`,
+		seeAlso:     []string{"init"},
+	},
+	"gc": {
+		synopsis: "gvc gc [--aggressive] [--dry-run] [--prune[=<date>] | --no-prune]",
+		summary:  "Pack objects and clean up the repository",
+		description: `Packs loose objects into packfiles with delta compression and refreshes the commit-graph. --aggressive repacks everything into one pack with a wider delta window. --prune first expires old reflog entries and deletes unreachable objects older than the date, as prune does. --dry-run only reports what would be done.
+
+Temporary files and locks left by interrupted commands are removed too.`,
+		seeAlso: []string{"prune", "count-objects", "fsck"},
+	},
+	"prune": {
+		synopsis:    "gvc prune [-n | --dry-run] [-v | --verbose] [--expire <time>]",
+		summary:     "Delete unreachable loose objects",
+		description: `Deletes the loose objects that no ref, reflog, index or operation in progress refers to and that are older than --expire, or gc.pruneExpire (two weeks by default). -n only lists them, and -v lists what is removed.`,
+		seeAlso:     []string{"gc", "fsck"},
+	},
+	"commit-graph": {
+		synopsis:    "gvc commit-graph write",
+		summary:     "Write the commit-graph",
+		description: `Writes a Git-compatible commit-graph with generation numbers, letting ancestry queries skip history that cannot contain the commit they look for.`,
+		seeAlso:     []string{"gc"},
+	},
+	"merge-base": {
+		synopsis: `gvc merge-base [--all] <commit> <commit>
+gvc merge-base --is-ancestor <commit> <commit>`,
+		summary:     "Find the common ancestor of two commits",
+		description: `Prints the best common ancestor of two commits, or with --all every one. --is-ancestor prints nothing and exits with 0 when the first commit is an ancestor of the second, and with 1 when it is not.`,
+		seeAlso:     []string{"rev-list", "revisions"},
+	},
+	"rev-list": {
+		synopsis: `gvc rev-list [--left-right] [--count] [--topo-order | --date-order | --author-date-order]
+             [--objects [--filter=<spec>] [--filter-print-omitted]]
+             [--all | --branches[=<pattern>] | --tags[=<pattern>] | --remotes[=<pattern>]] <revision range>...`,
+		summary:     "List the commits in a range",
+		description: `Prints the SHAs of the commits the revisions and ranges select. --left-right marks the side of a symmetric difference each is on, and --count only counts them. --objects also lists the trees and blobs they reach, each with its path, and --filter leaves objects out as Git's partial clones do.`,
+		seeAlso:     []string{"log", "revisions"},
+	},
+	"blame": {
+		synopsis:    "gvc blame [--format=(html|markdown)] [<rev>] [--] <file>",
+		summary:     "Show which commit last changed each line",
+		description: `Shows the commit, author and date that last changed every line of a file, as of HEAD or the revision given. --format=html or --format=markdown exports the annotation as a table with each line's age.`,
+		seeAlso:     []string{"log"},
+	},
+	"grep": {
+		synopsis:    "gvc grep [-n | --line-number] [-i | --ignore-case] [--untracked] [-e] <pattern> [<rev>] [--] [<path>...]",
+		summary:     "Search tracked files",
+		description: `Prints the lines of tracked files that match a regular expression in Go syntax: in the working tree, or in a commit's tree when a revision follows the pattern. -n adds line numbers, -i ignores case, paths limit the search, and --untracked also searches untracked files that are not ignored. Exits with 1 when nothing matches.`,
+		seeAlso:     []string{"ignore"},
+	},
+	"stats": {
+		synopsis:    "gvc stats [--top=<n>]",
+		summary:     "Summarize the history and the object store",
+		description: `Prints the commits per author, commits and new blobs per month, file types at HEAD, the largest blobs (--top, 10 by default) and the size of the object store.`,
+		seeAlso:     []string{"count-objects", "find-large-blobs"},
+	},
+	"find-large-blobs": {
+		synopsis:    "gvc find-large-blobs [--min-size=<size>]",
+		summary:     "List large files in the history",
+		description: `Lists every blob in the history of HEAD and the refs that is at least add.largeFileThreshold, or --min-size, largest first, with the commit that introduced it and its path there.`,
+		seeAlso:     []string{"stats"},
+	},
+	"bisect": {
+		synopsis:    "gvc bisect (start [<bad> [<good>...]] | good [<rev>...] | bad [<rev>] | skip [<rev>...] | reset [<commit>] | log | run <cmd>...)",
+		summary:     "Find the commit that introduced a bug",
+		description: `Binary-searches history: after start, mark commits good or bad (HEAD by default), and each time the commit that best halves the remaining candidates is checked out, until the first bad commit is found. run drives the search with a command, whose exit status 0 means good, 125 skip and anything else up to 127 bad. reset ends the search.`,
+		seeAlso:     []string{"log"},
+	},
+	"verify-index": {
+		synopsis:    "gvc verify-index",
+		summary:     "Check the index",
+		description: `Checks the index's header and checksum, that its entries are sorted, unique and well formed, and that their objects exist, without changing it. Exits with 1 when anything is wrong.`,
+		seeAlso:     []string{"fsck"},
+	},
+	"worktree": {
+		synopsis: `gvc worktree add [-b <new-branch>] [--detach] <path> [<commit-ish>]
+gvc worktree list
+gvc worktree remove [-f] <worktree>
+gvc worktree lock [--reason <string>] <worktree>
+gvc worktree unlock <worktree>
+gvc worktree prune`,
+		summary:     "Check out several branches at once",
+		description: `add creates a linked worktree with its own HEAD and index but the objects, refs and config of this one. A branch can only be checked out in one worktree. remove deletes a worktree, lock keeps prune from removing one whose directory is missing, and prune forgets those that are gone.`,
+		seeAlso:     []string{"switch"},
+	},
+	"status": {
+		synopsis:    "gvc status [-s | --short] [--ignored] [--ignore-submodules[=<when>]]",
+		summary:     "Show the state of the working tree",
+		description: `Shows the current branch, the staged changes, the unstaged ones and the untracked files, with a count of each. -s prints one line per path instead, and --ignored also lists ignored files. With the global --json, prints one record per change.`,
+		seeAlso:     []string{"diff", "add", "ignore"},
+	},
+	"fast-export": {
+		synopsis:    "gvc fast-export [<revision range>...]",
+		summary:     "Write history as a fast-import stream",
+		description: `Writes every branch and tag, or the history the arguments select, to standard output in Git's fast-import format, so "git fast-import" can read it.`,
+		seeAlso:     []string{"fast-import", "bundle"},
+	},
+	"fast-import": {
+		synopsis:    "gvc fast-import [--force] < <stream>",
+		summary:     "Read history from a fast-import stream",
+		description: `Reads a stream in Git's fast-import format, such as "git fast-export --all" writes, from standard input, and points the branches and tags at the result. A ref is only moved to a commit that contains its current one unless --force is given.`,
+		seeAlso:     []string{"fast-export"},
+	},
+	"api-server": {
+		synopsis:    "gvc api-server [--socket=<path>]",
+		summary:     "Serve status, log, diff and commit over a socket",
+		description: `Answers JSON-RPC 2.0 requests for status, log, diff and commit, one per line, on a Unix socket, .gvc/api.sock unless --socket says otherwise, so editors can drive gvc without starting a process per command.`,
+		seeAlso:     []string{"status"},
+	},
+	"ui": {
+		synopsis:    "gvc ui",
+		summary:     "Browse the status and log in the terminal",
+		description: `A full-screen terminal browser of the status and the log. s stages the selected file and u unstages it, enter shows a diff or commit, l lists the log, r refreshes, q goes back and Ctrl-C quits.`,
+		seeAlso:     []string{"status", "log"},
+	},
+	"clean": {
+		synopsis:    "gvc clean [-n] [-f] [-d] [-x] [-q] [--] [<path>...]",
+		summary:     "Delete untracked files",
+		description: `Deletes the untracked files status lists. It refuses to run without -f, and -n only lists what would go. -d also removes untracked directories, -x ignored files too, and paths limit it to part of the tree. Nested repositories are never removed.`,
+		seeAlso:     []string{"status", "ignore"},
+	},
+	"encryption": {
+		synopsis: `gvc encryption [status]
+gvc encryption enable [--keyfile=<path>]
+gvc encryption disable`,
+		summary:     "Encrypt the object store at rest",
+		description: `enable seals every object and pack with AES-256-GCM, under a key derived from GVC_PASSPHRASE or from the key file given, which is kept in encryption.keyFile. disable decrypts them again, and status reports whether the store is encrypted.`,
+	},
+	"describe": {
+		synopsis: `gvc describe [--tags] [--match=<pattern>] [--long] [--abbrev=<n>] [--always]
+             [--exact-match] [--dirty[=<mark>] | <commit>...]`,
+		summary:     "Name a commit after the nearest tag",
+		description: `Names a commit, HEAD by default, as <tag>-<commits since it>-g<abbreviated SHA>, or just the tag when it is tagged. Annotated tags are used unless --tags allows lightweight ones, and --match limits them by name. --always falls back to the SHA, and --dirty marks a working tree with changes.`,
+		seeAlso:     []string{"tag", "name-rev"},
+	},
+	"shortlog": {
+		synopsis: `gvc shortlog [-n] [-s] [-e]
+             [--all | --branches[=<pattern>] | --tags[=<pattern>] | --remotes[=<pattern>]] [<revision range>...]`,
+		summary:     "Summarize history by author",
+		description: `Lists each author's commit count and subjects, oldest first, for the revisions given or HEAD. -n sorts by count, -s prints only the counts and -e adds emails.`,
+		seeAlso:     []string{"log", "changelog"},
+	},
+	"changelog": {
+		synopsis:    "gvc changelog [--format=<format>] [<from> [<to>] | <from>..<to>]",
+		summary:     "Write release notes from conventional commits",
+		description: `Writes Markdown release notes for the commits between two revisions, grouped by their conventional-commit type. <to> defaults to HEAD and <from> to the nearest tag before it.`,
+		seeAlso:     []string{"shortlog", "describe"},
+	},
+	"scan-history": {
+		synopsis:    "gvc scan-history [--all | --branches[=<pattern>] | --tags[=<pattern>] | --remotes[=<pattern>]] [<revision range>...]",
+		summary:     "Look for secrets committed in the past",
+		description: `Scans the changes of the commits selected, HEAD's history by default, with the rules commit uses to block credentials, reporting each secret in the commit that added it. Exits with 1 when it finds any.`,
+		seeAlso:     []string{"commit"},
+	},
+	"update-ref": {
+		synopsis: `gvc update-ref [-m <reason>] [--no-deref] <ref> <new-value> [<old-value>]
+gvc update-ref [-m <reason>] [--no-deref] -d <ref> [<old-value>]
+gvc update-ref [-m <reason>] [--no-deref] --stdin`,
+		summary:     "Move or delete a ref safely",
+		description: `Points a ref at a new value, only if it still has the old value when one is given; the all-zero SHA means it must not exist yet. -d deletes the ref, -m records a reason in the reflog and --no-deref updates HEAD itself rather than its branch. --stdin reads update, create, delete and verify lines and applies them all or none.`,
+		seeAlso:     []string{"symbolic-ref", "show-ref"},
+	},
+	"symbolic-ref": {
+		synopsis: `gvc symbolic-ref [-q] [--short] <name>
+gvc symbolic-ref [-m <reason>] HEAD <ref>`,
+		summary:     "Read or set a symbolic ref",
+		description: `Prints the ref a symbolic ref such as HEAD points to, --short for its short name, or with -q just exits with 1 when it is detached. With a ref, points HEAD at it.`,
+		seeAlso:     []string{"update-ref"},
+	},
+	"show-ref": {
+		synopsis: `gvc show-ref [--head] [--heads] [--tags] [-d | --dereference] [-s | --hash[=<n>]]
+             [-q | --quiet] [--verify] [<pattern>...]`,
+		summary:     "List refs",
+		description: `Lists the refs and the objects they point to, limited to branches or tags, or to the patterns given. -d adds the objects annotated tags point to, and --verify checks that each full ref name given exists.`,
+		seeAlso:     []string{"update-ref", "pack-refs"},
+	},
+	"pack-refs": {
+		synopsis:    "gvc pack-refs [--all] [--no-prune]",
+		summary:     "Pack refs into packed-refs",
+		description: `Moves tags and refs already packed, or with --all every ref, into the packed-refs file, and removes their loose files unless --no-prune is given.`,
+		seeAlso:     []string{"show-ref", "gc"},
+	},
+	"count-objects": {
+		synopsis:    "gvc count-objects [-v | --verbose] [-H | --human-readable] [--largest[=<n>]]",
+		summary:     "Count the objects in the repository",
+		description: `Prints the number of loose objects and the space they take. -v adds the packed objects, packs, loose objects already packed, garbage and unreachable objects, -H prints readable sizes and --largest lists the largest objects.`,
+		seeAlso:     []string{"gc", "stats"},
+	},
+	"format-patch": {
+		synopsis: `gvc format-patch [-o <dir> | --stdout] [-n | --numbered | -N | --no-numbered]
+                 [--subject-prefix=<prefix>] [-<n>] [<since> | <revision range>]`,
+		summary:     "Write commits as patch emails",
+		description: `Writes each commit not in <since>, or in the range given, as a mailbox-style patch email, one 0001-<subject>.patch file per commit in the current directory or -o, or all of them to standard output with --stdout. -<n> takes the newest n commits.`,
+		seeAlso:     []string{"apply", "diff"},
+	},
+	"apply": {
+		synopsis:    "gvc apply [--check] [--cached | --index] [-3 | --3way] [-p<n>] [--fuzz=<n>] [-v | --verbose] [<patch>...]",
+		summary:     "Apply a patch to the working tree or the index",
+		description: `Applies unified diffs, from the files given or standard input, to the working tree; --cached applies them to the index alone and --index to both. --check only reports whether they apply. -3 falls back to a three-way merge, leaving conflict markers, when a hunk does not apply.`,
+		seeAlso:     []string{"format-patch", "diff"},
+	},
+	"rewrite-authors": {
+		synopsis:    "gvc rewrite-authors [-n | --dry-run] <mapping-file>",
+		summary:     "Fix author names and emails across history",
+		description: `Rewrites every commit and annotated tag reachable from a branch, tag or HEAD so their authors and committers follow the mapping file, which is in Git's mailmap format. -n only reports what would change.`,
+	},
+	"prompt": {
+		synopsis:    "gvc prompt [-u | --untracked]",
+		summary:     "Print a summary for a shell prompt",
+		description: `Prints the branch, markers for staged and unstaged changes, with -u for untracked files too, the distance to the upstream and any operation in progress, laid out like Git's bash prompt.`,
+		seeAlso:     []string{"status"},
+	},
+	"notes": {
+		synopsis: `gvc notes add [--ref=<ref>] [-f] -m <message>... [<commit>]
+gvc notes show [--ref=<ref>] [<commit>]
+gvc notes remove [--ref=<ref>] [<commit>]`,
+		summary:     "Attach notes to commits",
+		description: `Attaches a message to a commit, HEAD by default, without rewriting it, under refs/notes/commits or --ref. -f replaces an existing note. show prints a commit's note and remove deletes it. log and show print the notes with --notes.`,
+		seeAlso:     []string{"log", "show"},
+	},
+	"name-rev": {
+		synopsis: `gvc name-rev [--name-only] <commit>...
+gvc name-rev [--name-only] --annotate-stdin`,
+		summary:     "Name commits relative to refs",
+		description: `Names each commit after the nearest ref it can be reached from, such as main~4. --annotate-stdin adds the names to every full SHA in standard input.`,
+		seeAlso:     []string{"describe", "revisions"},
+	},
+	"backup": {
+		synopsis: `gvc backup <file>
+gvc backup restore <file>...`,
+		summary:     "Write incremental backups as bundles",
+		description: `Writes a bundle holding only the objects added since the previous backup, which marker refs under refs/backup/ keep track of. restore replays a chain of bundles into the repository.`,
+		seeAlso:     []string{"bundle"},
+	},
+	"branch": {
+		synopsis: `gvc branch [--merged [<commit>] | --no-merged [<commit>] | --contains <commit>] [--sort=<key>]
+gvc branch <name> [<start>]
+gvc branch (-d | -D) <name>
+gvc branch --stale[=<date>] [--into <branch>] [--delete [--yes]]`,
+		summary: "List, create and delete branches",
+		description: `Lists the branches, filtered by ancestry with --merged, --no-merged and --contains, and ordered by --sort (refname, committerdate, creatordate or updatedate, newest first with a leading -). With a name, creates a branch at HEAD or <start>; -d deletes a merged branch and -D any branch.
+
+--stale lists the branches merged into the default branch whose tips are older than the date, and --delete removes them after asking, or at once with --yes.`,
+		seeAlso: []string{"switch", "tag"},
+	},
+	"switch": {
+		synopsis: `gvc switch <branch>
+gvc switch -c <new-branch> [<start>]
+gvc switch --orphan <new-branch> [--keep]`,
+		summary:     "Switch branches",
+		description: `Checks out a branch, keeping local changes to files the switch does not touch. -c creates the branch first. A branch only a remote has is created from it, tracking it. --orphan starts a branch with no history, removing the tracked files unless --keep is given.`,
+		seeAlso:     []string{"branch", "worktree", "revisions"},
+	},
+	"subtree": {
+		synopsis: `gvc subtree split --prefix=<dir> [-b <branch>] [--rejoin] [<commit>]
+gvc subtree add --prefix=<dir> [-m <message>] <commit>
+gvc subtree merge --prefix=<dir> [-m <message>] <commit>
+gvc subtree merge --continue | --abort`,
+		summary:     "Move a directory's history in and out of a repository",
+		description: `split rewrites the history of a commit into one where the directory is the root and prints its tip, or stores it as -b. add brings another history in as the directory, and merge merges later changes of it, stopping on conflicts until --continue or --abort.`,
+	},
+	"fsck": {
+		synopsis:    "gvc fsck [--strict] [--unreachable]",
+		summary:     "Check the object store",
+		description: `Re-hashes every object, checks tree and commit syntax, and reports objects missing from the history of HEAD, the refs, the reflogs and the index, and dangling objects; --unreachable lists every unreachable one. Errors make it exit with 1, and --strict fails on warnings too.`,
+		seeAlso:     []string{"repair", "verify-index", "gc"},
+	},
+	"repair": {
+		synopsis:    "gvc repair",
+		summary:     "Rebuild missing or corrupt objects",
+		description: `Rebuilds missing or corrupt objects from packed copies, working tree files and the index. Refs whose history is still damaged are moved under refs/rescue/, and the remaining steps to recover are printed.`,
+		seeAlso:     []string{"fsck"},
+	},
+	"remote": {
+		synopsis: `gvc remote [-v]
+gvc remote get-url [--push] <name>
+gvc remote set-head <name> (-a | --auto | -d | --delete | <branch>)`,
+		summary:     "Show remotes and their URLs",
+		description: `Lists the remotes, with -v their URLs after url.<base>.insteadOf rewriting. get-url prints one remote's URL, or its push URL. set-head sets refs/remotes/<name>/HEAD, the branch the remote's name alone stands for, from the remote with -a, or deletes it with -d.`,
+		seeAlso:     []string{"fetch", "push", "clone"},
+	},
+	"fetch": {
+		synopsis: `gvc fetch [--no-hardlinks] [--dry-run] [--depth <depth> | --unshallow] [--limit-rate <rate>]
+          [<remote> | --all [-j <n> | --jobs <n>]]`,
+		summary:     "Download branches and tags from a remote",
+		description: `Copies the objects origin, or the remote given, has and this repository lacks, and updates the remote-tracking branches. --all fetches from every remote, -j of them at a time. --depth deepens or limits a shallow history and --unshallow completes it. --dry-run only reports what would be updated.`,
+		seeAlso:     []string{"push", "remote", "clone"},
+	},
+	"push": {
+		synopsis: `gvc push [-f | --force] [-n | --dry-run] [-u | --set-upstream] [--no-hardlinks]
+         [--no-verify] [--limit-rate <rate>] [<remote> [<refspec>...]]`,
+		summary:     "Upload branches to a remote",
+		description: `Updates the remote's branches from local ones, the current branch by default, refusing updates that would lose its commits unless -f is given. -u records the remote branch as the upstream. The pre-push hook runs first and can stop the push; --no-verify skips it.`,
+		seeAlso:     []string{"fetch", "remote", "hooks"},
+	},
+	"submodule": {
+		synopsis: `gvc submodule [status]
+gvc submodule init
+gvc submodule update [--init] [--recursive] [--no-hardlinks]`,
+		summary:     "Check out the repositories a project depends on",
+		description: `status lists the submodules .gvcmodules describes with the commits recorded for them. init stores each URL as submodule.<name>.url, and update clones missing submodules and checks out the recorded commits, --recursive also in their submodules.`,
+		seeAlso:     []string{"clone"},
+	},
+	"archive": {
+		synopsis:    "gvc archive [--format=<format>] [--prefix=<prefix>/] [-o <file>] <tree-ish>",
+		summary:     "Export a tree as a tar or zip archive",
+		description: `Writes the files of a commit or tree as a tar, tar.gz or zip archive, to standard output or -o, without checking it out. The format comes from --format or the file's extension. --prefix puts everything under one directory. Archives are reproducible, and the export-ignore and export-subst attributes are honored.`,
+	},
+	"bundle": {
+		synopsis: `gvc bundle create <file> <revision range>...
+gvc bundle verify <file>
+gvc bundle list-heads <file>
+gvc bundle unbundle <file>`,
+		summary:     "Move history between repositories as a file",
+		description: `create writes the commits the arguments select, such as main, --all or v1..main, to a file in Git's bundle format. verify checks it and that this repository has the commits it builds on, list-heads prints its refs, and unbundle adds its objects.`,
+		seeAlso:     []string{"backup", "fetch"},
+	},
+	"tag": {
+		synopsis: `gvc tag [-l | --list] [--contains [<commit>]] [--no-contains [<commit>]]
+        [--merged [<commit>]] [--no-merged [<commit>]] [--sort=[-]<key>] [<pattern>...]
+gvc tag [-f | --force] [-a | --annotate] [-m <message>] <name> [<commit>]
+gvc tag (-d | --delete) <name>...`,
+		summary:     "List, create and delete tags",
+		description: `Lists the tags, limited by glob patterns and by ancestry. With a name, creates a lightweight tag at HEAD or the commit given, replacing an existing one only with -f; -m, with or without -a, creates an annotated tag with that message instead, which describe uses without --tags. -d deletes tags.`,
+		seeAlso:     []string{"describe", "branch"},
+	},
+}
+
+// helpTopics holds the guides to subjects several commands share
+var helpTopics = map[string]helpTopic{
+	"revisions": {
+		summary: "Naming commits and ranges of commits",
+		text: `Wherever a command takes a commit, it accepts any of these:
+
+    <sha>
+        A full object name, or a unique prefix of it of at least 4 digits.
+    <refname>
+        A ref, looked up as refs/<refname>, refs/tags/<refname>,
+        refs/heads/<refname>, refs/remotes/<refname> and
+        refs/remotes/<refname>/HEAD in that order, so origin alone names
+        the default branch of origin. HEAD, or @, is the current commit.
+    <rev>~<n>
+        The nth first-parent ancestor: main~3 is main's great-grandparent.
+    <rev>^<n>
+        The nth parent, the first when n is left out; <rev>^0 is the commit
+        itself. They combine, as in HEAD~2^2.
+    <rev>^{<type>}
+        The object rev leads to of type commit, tree, blob or tag, following
+        annotated tags and going from a commit to its tree: HEAD^{tree} is
+        the tree of the current commit. <rev>^{} follows tags to whatever
+        they point to.
+    <ref>@{<n>}
+        The value the ref had n updates ago, from its reflog, such as
+        HEAD@{1} or stash@{0}.
+    @{-<n>}
+        The nth branch checked out before the current one, for
+        check-ref-format --branch.
+    <rev>:<path>
+        The file or directory at path in the commit's tree, for show,
+        cat-file and the like; :<path> is the file as staged in the index.
+
+An annotated tag stands for the commit it points to.
+
+Commands that walk history, such as log, rev-list, shortlog and format-patch, take ranges:
+
+    <rev>
+        The commit and every commit reachable from it.
+    ^<rev>
+        Leaves out the commits reachable from rev.
+    <a>..<b>
+        The commits reachable from b but not from a, like ^a b; either side
+        defaults to HEAD.
+    <a>...<b>
+        The commits reachable from either but not both, the symmetric
+        difference; --left-right marks which side each is on.
+    --all, --branches[=<pattern>], --tags[=<pattern>], --remotes[=<pattern>]
+        Every ref, or the branches, tags or remote-tracking branches whose
+        names match the glob pattern.`,
+	},
+	"ignore": {
+		summary: "Leaving untracked files out with ignore patterns",
+		text: `Ignored files are left out of add, write-tree, status, clean and ls-files --others unless they are already tracked; add -f stages them anyway. check-ignore -v shows which pattern ignores a path.
+
+Patterns come from these files, later ones overriding earlier ones:
+
+    core.excludesFile
+        Patterns for every repository of the user, ~/.config/gvc/ignore
+        by default.
+    .gvc/info/exclude
+        Private patterns of one repository, not committed.
+    .gvcignore
+        Patterns committed in any directory of the working tree, applying
+        to the paths below it; deeper files override shallower ones. In a
+        Git repository, .gitignore is read instead.
+
+Each line is a pattern, in Git's syntax:
+
+    blank lines and lines starting with #
+        Are skipped; \# starts a pattern with #.
+    !<pattern>
+        Re-includes what an earlier pattern ignored; \! starts a pattern
+        with !. A path inside an ignored directory cannot be re-included.
+    <pattern>/
+        Matches directories only.
+    /<pattern>, <dir>/<pattern>
+        A slash at the start or in the middle anchors the pattern to the
+        directory of the file it is in; without one, it matches a name at
+        any depth.
+    *, ?, [a-z]
+        Match anything but a slash, a single character, or one of a set.
+    **
+        Matches across directories: **/logs, logs/** and a/**/b.
+
+Trailing spaces are dropped unless escaped with a backslash. When several patterns match a path, the last one decides.`,
+	},
+	"hooks": {
+		summary: "Running programs at points of commit and push",
+		text: `Hooks are executable programs in .gvc/hooks, or in the directory core.hooksPath names, relative to the top of the working tree. One that is missing or not executable is skipped.
+
+    pre-commit
+        Runs before a commit is recorded; failing stops the commit.
+    commit-msg
+        Runs with the path of .gvc/COMMIT_EDITMSG, holding the message. It
+        may rewrite the file, and failing stops the commit.
+    post-commit
+        Runs once a commit is recorded. Its exit status is ignored.
+    pre-push
+        Runs with the remote's name and URL before a push updates
+        anything, reading a "<local ref> <local sha> <remote ref>
+        <remote sha>" line per ref on standard input; failing stops the
+        push.
+
+Hooks run from the top of the working tree, and their output goes to standard error. GVC_DIR names the repository directory, GVC_COMMON_DIR the one holding the objects, refs and hooks every worktree shares, GVC_WORK_TREE the top of the working tree, GVC_INDEX_FILE the index and GVC_OPERATION the command running.
+
+commit --no-verify and push --no-verify skip the hooks, unless hooks.allowNoVerify is set to false to make them mandatory.`,
+	},
+}
+
+// usageText returns the usage message of a command: its synopsis
+func usageText(command string) string {
+	page, ok := helpPages[command]
+	if !ok {
+		panic("no help page for " + command)
+	}
+	lines := strings.Split(page.synopsis, "\n")
+	for i := range lines {
+		if i == 0 {
+			lines[i] = "usage: " + lines[i]
+		} else {
+			lines[i] = "       " + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// commandUsage reports an invalid command line with the command's synopsis
+func commandUsage(command string) error {
+	return usageError(usageText(command))
+}
+
+// subcommandUsage reports an invalid command line with the forms of the
+// command's synopsis for one subcommand
+func subcommandUsage(command, subcommand string) error {
+	prefix := "gvc " + command + " " + subcommand + " "
+	var forms []string
+	for _, line := range strings.Split(helpPages[command].synopsis, "\n") {
+		continues := strings.HasPrefix(line, " ")
+		if strings.HasPrefix(line+" ", prefix) || continues && len(forms) > 0 {
+			forms = append(forms, line)
+		} else if len(forms) > 0 {
+			break
+		}
+	}
+	if len(forms) == 0 {
+		return commandUsage(command)
+	}
+	return usageError("usage: " + strings.Join(forms, "\n       "))
+}
+
+// wantsHelp reports whether a command's arguments only ask for its page
+func wantsHelp(args []string) bool {
+	return len(args) == 1 && (args[0] == "--help" || args[0] == "-h")
+}
+
+// NEW: Help command
+func handleHelp(args []string) error {
+	if len(args) > 1 {
+		return commandUsage("help")
+	}
+	width := helpWidth()
+	if len(args) == 0 {
+		printHelpIndex(os.Stdout, width)
+		return nil
+	}
+	if page, ok := helpPages[args[0]]; ok {
+		printCommandHelp(os.Stdout, args[0], page, width)
+		return nil
+	}
+	if topic, ok := helpTopics[args[0]]; ok {
+		printTopicHelp(os.Stdout, args[0], topic, width)
+		return nil
+	}
+	return usageError(fmt.Sprintf("no command or guide named '%s'; 'gvc help' lists them", args[0]))
+}
+
+// helpWidth is the width help is wrapped to: the terminal's, up to 100
+// columns, or 80 when standard output is not a terminal
+func helpWidth() int {
+	if isTerminal(os.Stdout.Fd()) {
+		if width, _, err := terminalSize(os.Stdout.Fd()); err == nil && width >= 40 {
+			return min(width, 100)
+		}
+	}
+	return 80
+}
+
+// commandNames returns every command, sorted
+func commandNames() []string {
+	names := slices.Clone(standaloneCommands)
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// printHelpIndex lists the commands and guides with their summaries
+func printHelpIndex(w io.Writer, width int) {
+	fmt.Fprintf(w, "usage: %s\n\nCommands:\n", globalUsage)
+	names := commandNames()
+	column := 0
+	for _, name := range names {
+		column = max(column, len(name))
+	}
+	for _, name := range names {
+		fmt.Fprintf(w, "   %-*s  %s\n", column, name, helpPages[name].summary)
+	}
+
+	topics := make([]string, 0, len(helpTopics))
+	for name := range helpTopics {
+		topics = append(topics, name)
+	}
+	slices.Sort(topics)
+	fmt.Fprintln(w, "\nGuides:")
+	for _, name := range topics {
+		fmt.Fprintf(w, "   %-*s  %s\n", column, name, helpTopics[name].summary)
+	}
+	fmt.Fprintln(w)
+	writeParagraphs(w, "'gvc help <command>' shows a command's reference page and 'gvc help <guide>' a guide.", 0, width)
+}
+
+// printCommandHelp prints a command's reference page
+func printCommandHelp(w io.Writer, name string, page commandHelp, width int) {
+	fmt.Fprintln(w, "NAME")
+	writeParagraphs(w, fmt.Sprintf("gvc-%s - %s", name, page.summary), 4, width)
+	fmt.Fprintln(w, "\nSYNOPSIS")
+	for _, line := range strings.Split(page.synopsis, "\n") {
+		fmt.Fprintf(w, "    %s\n", line)
+	}
+	fmt.Fprintln(w, "\nDESCRIPTION")
+	writeParagraphs(w, page.description, 4, width)
+	if len(page.seeAlso) > 0 {
+		refs := make([]string, len(page.seeAlso))
+		for i, ref := range page.seeAlso {
+			refs[i] = "gvc help " + ref
+		}
+		fmt.Fprintln(w, "\nSEE ALSO")
+		writeParagraphs(w, strings.Join(refs, ", "), 4, width)
+	}
+}
+
+// printTopicHelp prints a guide
+func printTopicHelp(w io.Writer, name string, topic helpTopic, width int) {
+	fmt.Fprintln(w, "NAME")
+	writeParagraphs(w, fmt.Sprintf("gvc%s - %s", name, topic.summary), 4, width)
+	fmt.Fprintln(w, "\nDESCRIPTION")
+	writeParagraphs(w, topic.text, 4, width)
+}
+
+// writeParagraphs prints text indented, wrapping its paragraphs to width.
+// Blocks whose lines are indented are printed as they are.
+func writeParagraphs(w io.Writer, text string, indent, width int) {
+	margin := strings.Repeat(" ", indent)
+	for i, block := range strings.Split(text, "\n\n") {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if strings.HasPrefix(block, " ") {
+			for _, line := range strings.Split(block, "\n") {
+				fmt.Fprintf(w, "%s%s\n", margin, line)
+			}
+			continue
+		}
+		line := margin
+		for _, word := range strings.Fields(block) {
+			if len(line) > indent && len(line)+1+len(word) > width {
+				fmt.Fprintln(w, line)
+				line = margin
+			}
+			if len(line) > indent {
+				line += " "
+			}
+			line += word
+		}
+		fmt.Fprintln(w, line)
+	}
+}
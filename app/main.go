@@ -1,846 +1,428 @@
 package main
 
 import (
-	"bytes"
-	"compress/zlib"
-	"crypto/sha1"
-	"encoding/hex"
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
-	"sort"
-	"strconv"
+	"os/signal"
+	"slices"
 	"strings"
-	"time"
-)
 
-const (
-	GvcDir     = ".gvc"
-	ObjectsDir = ".gvc/objects"
-	RefsDir    = ".gvc/refs"
-	HeadFile   = ".gvc/HEAD"
-	IndexFile  = ".gvc/index"
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/gvc"
 )
 
-// ObjectType represents the type of Git object
-type ObjectType string
-
+// Exit codes form the contract scripts can rely on instead of parsing output
 const (
-	BlobObject   ObjectType = "blob"
-	TreeObject   ObjectType = "tree"
-	CommitObject ObjectType = "commit"
+	// ExitOK means the command succeeded
+	ExitOK = 0
+	// ExitNegative means the command ran but reports a negative outcome:
+	// differences found by diff --exit-code, no match for grep, not an ancestor
+	ExitNegative = 1
+	// ExitConflict means the operation stopped on conflicts that need resolving
+	// (merge, cherry-pick, revert, rebase, stash pop)
+	ExitConflict = 2
+	// ExitFatal means the command failed: missing repository, corrupt objects, I/O errors
+	ExitFatal = 128
+	// ExitUsage means the command line was invalid
+	ExitUsage = 129
+	// ExitInterrupted means the command was stopped with Ctrl-C
+	ExitInterrupted = 130
 )
 
-// TreeEntry represents an entry in a tree object
-type TreeEntry struct {
-	Mode string
-	Name string
-	SHA  string
-	Type ObjectType
-}
-
-// IndexEntry represents a file in the staging area
-type IndexEntry struct {
-	Path    string    `json:"path"`
-	SHA     string    `json:"sha"`
-	Mode    string    `json:"mode"`
-	Size    int64     `json:"size"`
-	ModTime time.Time `json:"mod_time"`
-}
-
-// Index represents the staging area
-type Index struct {
-	Entries []IndexEntry `json:"entries"`
-}
-
-// CommitInfo represents parsed commit information
-type CommitInfo struct {
-	SHA       string
-	TreeSHA   string
-	ParentSHA string
-	Author    string
-	Message   string
-	Timestamp time.Time
-}
-
-// initializeRepo sets up a new .gvc directory structure if it doesn't already exist.
-func initializeRepo() error {
-	if _, err := os.Stat(GvcDir); err == nil {
-		return errors.New("gvc repository already initialized")
-	}
-
-	// Create required subdirectories
-	dirs := []string{GvcDir, ObjectsDir, RefsDir, RefsDir + "/heads"}
-	for _, dir := range dirs {
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directory %s: %w", dir, err)
-		}
-	}
-
-	// Write the HEAD reference to point to main branch
-	headContent := []byte("ref: refs/heads/main\n")
-	if err := os.WriteFile(HeadFile, headContent, 0644); err != nil {
-		return fmt.Errorf("failed to write HEAD file: %w", err)
-	}
-
-	// Initialize empty index
-	emptyIndex := Index{Entries: []IndexEntry{}}
-	if err := writeIndex(&emptyIndex); err != nil {
-		return fmt.Errorf("failed to initialize index: %w", err)
-	}
-
-	fmt.Println("Initialized empty gvc repository")
-	return nil
-}
-
-// validateSHA checks if the provided SHA is valid
-func validateSHA(sha string) error {
-	if len(sha) != 40 {
-		return fmt.Errorf("invalid SHA length: expected 40, got %d", len(sha))
-	}
-	if _, err := hex.DecodeString(sha); err != nil {
-		return fmt.Errorf("invalid SHA format: %w", err)
-	}
-	return nil
-}
-
-// getObjectPath returns the file path for a given object SHA
-func getObjectPath(sha string) string {
-	return filepath.Join(ObjectsDir, sha[:2], sha[2:])
-}
-
-// readObject reads and decompresses a Git object
-func readObject(sha string) (ObjectType, []byte, error) {
-	if err := validateSHA(sha); err != nil {
-		return "", nil, err
-	}
-
-	objPath := getObjectPath(sha)
-	data, err := os.ReadFile(objPath)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to read object %s: %w", sha, err)
-	}
-
-	// Decompress the object
-	zr, err := zlib.NewReader(bytes.NewReader(data))
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to decompress object: %w", err)
-	}
-	defer zr.Close()
-
-	// Read the object header (e.g., "blob 14")
-	var objectType string
-	var contentLength int
-	if _, err := fmt.Fscanf(zr, "%s %d\x00", &objectType, &contentLength); err != nil {
-		return "", nil, fmt.Errorf("failed to parse object header: %w", err)
-	}
-
-	// Read and verify the content
-	content, err := io.ReadAll(zr)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to read decompressed content: %w", err)
-	}
-
-	if len(content) != contentLength {
-		return "", nil, fmt.Errorf("content length mismatch: expected %d, got %d", contentLength, len(content))
-	}
-
-	return ObjectType(objectType), content, nil
-}
-
-// writeObject compresses and stores an object, returning its SHA
-func writeObject(objectType ObjectType, content []byte) (string, error) {
-	// Prepare the object header
-	header := fmt.Sprintf("%s %d\x00", objectType, len(content))
-	fullContent := append([]byte(header), content...)
-
-	// Generate SHA-1 hash
-	hashBytes := sha1.Sum(fullContent)
-	sha := hex.EncodeToString(hashBytes[:])
-
-	// Compress the object
-	var compressed bytes.Buffer
-	w := zlib.NewWriter(&compressed)
-	if _, err := w.Write(fullContent); err != nil {
-		return "", fmt.Errorf("failed to compress object: %w", err)
-	}
-	if err := w.Close(); err != nil {
-		return "", fmt.Errorf("failed to close compressor: %w", err)
-	}
-
-	// Store the compressed object
-	objDir := filepath.Join(ObjectsDir, sha[:2])
-	objPath := filepath.Join(objDir, sha[2:])
-
-	if err := os.MkdirAll(objDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create object directory: %w", err)
-	}
-
-	if err := os.WriteFile(objPath, compressed.Bytes(), 0644); err != nil {
-		return "", fmt.Errorf("failed to write object: %w", err)
-	}
-
-	return sha, nil
-}
-
-// Index management functions
-func readIndex() (*Index, error) {
-	data, err := os.ReadFile(IndexFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return &Index{Entries: []IndexEntry{}}, nil
-		}
-		return nil, fmt.Errorf("failed to read index: %w", err)
-	}
-
-	var index Index
-	if err := json.Unmarshal(data, &index); err != nil {
-		return nil, fmt.Errorf("failed to parse index: %w", err)
-	}
-
-	return &index, nil
-}
-
-func writeIndex(index *Index) error {
-	data, err := json.MarshalIndent(index, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal index: %w", err)
-	}
-
-	if err := os.WriteFile(IndexFile, data, 0644); err != nil {
-		return fmt.Errorf("failed to write index: %w", err)
-	}
-
-	return nil
-}
-
-// getCurrentBranchRef returns the current branch reference
-func getCurrentBranchRef() (string, error) {
-	headData, err := os.ReadFile(HeadFile)
-	if err != nil {
-		return "", fmt.Errorf("failed to read HEAD: %w", err)
-	}
-
-	headContent := strings.TrimSpace(string(headData))
-	if strings.HasPrefix(headContent, "ref: ") {
-		return strings.TrimPrefix(headContent, "ref: "), nil
-	}
-	// HEAD points directly to a commit (detached HEAD)
-	return "", nil
-}
-
-// getCurrentCommit returns the SHA of the current commit
-func getCurrentCommit() (string, error) {
-	branchRef, err := getCurrentBranchRef()
-	if err != nil {
-		return "", err
-	}
-
-	if branchRef == "" {
-		// Detached HEAD
-		headData, err := os.ReadFile(HeadFile)
-		if err != nil {
-			return "", err
+// ExitError carries the exit code a command should terminate with
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("exit status %d", e.Code)
+	}
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// usageError reports an invalid command line
+func usageError(usage string) error {
+	return &ExitError{Code: ExitUsage, Err: errors.New(usage)}
+}
+
+// negativeResult makes the command exit with ExitNegative without printing an error
+func negativeResult() error {
+	return &ExitError{Code: ExitNegative}
+}
+
+// exitCode maps a command error onto the exit code contract
+func exitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	var conflictErr *gvc.ConflictError
+	if errors.As(err, &conflictErr) {
+		return ExitConflict
+	}
+	if errors.Is(err, context.Canceled) {
+		return ExitInterrupted
+	}
+	return ExitFatal
+}
+
+// commands maps every subcommand that works on an existing repository to its handler
+var commands = map[string]func(repo *gvc.Repository, args []string) error{
+	"cat-file":         handleCatFile,
+	"hash-object":      handleHashObject,
+	"ls-tree":          handleLsTree,
+	"ls-files":         handleLsFiles,
+	"check-ignore":     handleCheckIgnore,
+	"check-ref-format": handleCheckRefFormat,
+	"write-tree":       handleWriteTree,
+	"commit-tree":      handleCommitTree,
+	"add":              handleAdd,
+	"commit":           handleCommit,
+	"log":              handleLog,
+	"show":             handleShow,
+	"diff":             handleDiff,
+	"stash":            handleStash,
+	"reflog":           handleReflog,
+	"cherry-pick":      handleCherryPick,
+	"merge":            handleMerge,
+	"revert":           handleRevert,
+	"config":           handleConfig,
+	"gc":               handleGC,
+	"commit-graph":     handleCommitGraph,
+	"merge-base":       handleMergeBase,
+	"rev-list":         handleRevList,
+	"blame":            handleBlame,
+	"grep":             handleGrep,
+	"stats":            handleStats,
+	"find-large-blobs": handleFindLargeBlobs,
+	"bisect":           handleBisect,
+	"verify-index":     handleVerifyIndex,
+	"worktree":         handleWorktree,
+	"status":           handleStatus,
+	"fast-export":      handleFastExport,
+	"fast-import":      handleFastImport,
+	"api-server":       handleAPIServer,
+	"ui":               handleUI,
+	"autosnapshot":     handleAutosnapshot,
+	"clean":            handleClean,
+	"encryption":       handleEncryption,
+	"describe":         handleDescribe,
+	"shortlog":         handleShortlog,
+	"scan-history":     handleScanHistory,
+	"update-ref":       handleUpdateRef,
+	"symbolic-ref":     handleSymbolicRef,
+	"show-ref":         handleShowRef,
+	"pack-refs":        handlePackRefs,
+	"rm":               handleRm,
+	"count-objects":    handleCountObjects,
+	"prune":            handlePrune,
+	"format-patch":     handleFormatPatch,
+	"apply":            handleApply,
+	"checkout":         handleCheckout,
+	"sparse-checkout":  handleSparseCheckout,
+	"rewrite-authors":  handleRewriteAuthors,
+	"prompt":           handlePrompt,
+	"notes":            handleNotes,
+	"name-rev":         handleNameRev,
+	"backup":           handleBackup,
+	"branch":           handleBranch,
+	"switch":           handleSwitch,
+	"rebase":           handleRebase,
+	"split":            handleSplit,
+	"subtree":          handleSubtree,
+	"fsck":             handleFsck,
+	"repair":           handleRepair,
+	"remote":           handleRemote,
+	"fetch":            handleFetch,
+	"submodule":        handleSubmodule,
+	"archive":          handleArchive,
+	"bundle":           handleBundle,
+	"push":             handlePush,
+	"verify-commit":    handleVerifyCommit,
+	"tag":              handleTag,
+	"changelog":        handleChangelog,
+}
+
+// readOnlyCommands are the commands that never modify the repository, and
+// for those that sometimes do, which invocations do not. The long-running
+// ui and autosnapshot run are listed too: they register with BeginWrite for
+// each change they make, so gc and prune need not wait for them to exit.
+var readOnlyCommands = map[string]func(args []string) bool{
+	"cat-file":         always,
+	"ls-tree":          always,
+	"ls-files":         always,
+	"status":           always,
+	"check-ignore":     always,
+	"check-ref-format": always,
+	"log":              always,
+	"show":             always,
+	"diff":             always,
+	"reflog":           always,
+	"merge-base":       always,
+	"rev-list":         always,
+	"blame":            always,
+	"grep":             always,
+	"stats":            always,
+	"find-large-blobs": always,
+	"verify-index":     always,
+	"name-rev":         always,
+	"fsck":             always,
+	"remote":           func(args []string) bool { return len(args) == 0 || args[0] != "set-head" },
+	"archive":          always,
+	"verify-commit":    always,
+	"fast-export":      always,
+	"api-server":       always,
+	"hash-object":      func(args []string) bool { return !slices.Contains(args, "-w") },
+	"config":           func(args []string) bool { return len(args) == 1 },
+	"autosnapshot":     autosnapshotReads,
+	"ui":               always,
+	"encryption":       func(args []string) bool { return len(args) == 0 || args[0] == "status" },
+	"describe":         always,
+	"shortlog":         always,
+	"scan-history":     always,
+	"show-ref":         always,
+	"symbolic-ref":     symbolicRefReads,
+	"count-objects":    always,
+	"add":              dryRun("-n"),
+	"rm":               dryRun("-n"),
+	"commit":           dryRun(),
+	"fetch":            dryRun(),
+	"gc":               dryRun(),
+	"prune":            dryRun("-n"),
+	"format-patch":     always,
+	"apply":            func(args []string) bool { return slices.Contains(args, "--check") },
+	"branch": func(args []string) bool {
+		if slices.ContainsFunc(args, func(arg string) bool { return arg == "--stale" || strings.HasPrefix(arg, "--stale=") }) {
+			return !slices.Contains(args, "--delete")
 		}
-		return strings.TrimSpace(string(headData)), nil
-	}
-
-	// Read branch reference
-	branchFile := filepath.Join(GvcDir, branchRef)
-	data, err := os.ReadFile(branchFile)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "", nil // No commits yet
+		return len(args) == 0 || slices.Contains([]string{"--merged", "--no-merged", "--contains", "--sort"}, args[0]) ||
+			strings.HasPrefix(args[0], "--sort=")
+	},
+	"clean": func(args []string) bool {
+		return slices.ContainsFunc(args, func(arg string) bool {
+			return arg == "--dry-run" || len(arg) > 1 && arg[0] == '-' && arg[1] != '-' && strings.ContainsRune(arg, 'n')
+		})
+	},
+	"stash":           firstArgIn("list", "show"),
+	"bisect":          firstArgIn("log"),
+	"worktree":        firstArgIn("list"),
+	"sparse-checkout": firstArgIn("list"),
+	"rewrite-authors": dryRun("-n"),
+	"prompt":          always,
+	"notes":           firstArgIn("show"),
+	"submodule":       func(args []string) bool { return len(args) == 0 || args[0] == "status" },
+	"bundle":          firstArgIn("create", "verify", "list-heads"),
+	"tag":             tagLists,
+	"changelog":       always,
+}
+
+// jsonCommands are the commands that print records under --json
+var jsonCommands = map[string]bool{"log": true, "status": true, "ls-tree": true, "branch": true, "ls-files": true, "prompt": true, "gc": true,
+	"version": true}
+
+// always accepts any arguments
+func always([]string) bool { return true }
+
+// dryRun accepts --dry-run, or any of the given short forms of it
+func dryRun(short ...string) func(args []string) bool {
+	return func(args []string) bool {
+		return slices.ContainsFunc(args, func(arg string) bool { return arg == "--dry-run" || slices.Contains(short, arg) })
+	}
+}
+
+// symbolicRefReads accepts symbolic-ref reading a ref: a single name, with
+// no new target after it
+func symbolicRefReads(args []string) bool {
+	return len(args) > 0 && !slices.Contains(args, "-m") && (len(args) == 1 || strings.HasPrefix(args[len(args)-2], "-"))
+}
+
+// autosnapshotReads accepts autosnapshot listing snapshots, or running,
+// which registers each snapshot as a write of its own
+func autosnapshotReads(args []string) bool {
+	return len(args) == 0 || strings.HasPrefix(args[0], "-") || args[0] == "run" || args[0] == "list"
+}
+
+// tagLists accepts tag listing tags: with no arguments, -l, a filter or a
+// sort order, and without -d
+func tagLists(args []string) bool {
+	lists := len(args) == 0
+	for _, arg := range args {
+		option, _, _ := strings.Cut(arg, "=")
+		switch option {
+		case "-d", "--delete":
+			return false
+		case "-l", "--list", "--sort", "--contains", "--no-contains", "--merged", "--no-merged":
+			lists = true
 		}
-		return "", fmt.Errorf("failed to read branch ref: %w", err)
 	}
-
-	return strings.TrimSpace(string(data)), nil
+	return lists
 }
 
-// updateBranchRef updates the current branch to point to a commit
-func updateBranchRef(commitSHA string) error {
-	branchRef, err := getCurrentBranchRef()
-	if err != nil {
-		return err
-	}
-
-	if branchRef == "" {
-		return errors.New("cannot update detached HEAD")
-	}
-
-	branchFile := filepath.Join(GvcDir, branchRef)
-	if err := os.MkdirAll(filepath.Dir(branchFile), 0755); err != nil {
-		return fmt.Errorf("failed to create branch directory: %w", err)
-	}
-
-	if err := os.WriteFile(branchFile, []byte(commitSHA+"\n"), 0644); err != nil {
-		return fmt.Errorf("failed to write branch ref: %w", err)
-	}
-
-	return nil
+// firstArgIn accepts the subcommands given
+func firstArgIn(subcommands ...string) func(args []string) bool {
+	return func(args []string) bool { return len(args) > 0 && slices.Contains(subcommands, args[0]) }
 }
 
-// parseCommit parses a commit object and returns CommitInfo
-func parseCommit(commitSHA string, content []byte) (*CommitInfo, error) {
-	lines := strings.Split(string(content), "\n")
-	
-	commit := &CommitInfo{SHA: commitSHA}
-	messageStart := -1
-
-	for i, line := range lines {
-		if line == "" {
-			messageStart = i + 1
-			break
-		}
-
-		parts := strings.SplitN(line, " ", 2)
-		if len(parts) < 2 {
-			continue
-		}
-
-		switch parts[0] {
-		case "tree":
-			commit.TreeSHA = parts[1]
-		case "parent":
-			commit.ParentSHA = parts[1]
-		case "author":
-			// Parse author and timestamp
-			authorParts := strings.Split(parts[1], " ")
-			if len(authorParts) >= 2 {
-				timestamp := authorParts[len(authorParts)-2]
-				ts := time.Unix(parseInt64(timestamp), 0);					commit.Timestamp = ts
-
-				commit.Author = strings.Join(authorParts[:len(authorParts)-2], " ")
-			}
-		}
-	}
-
-	if messageStart > 0 && messageStart < len(lines) {
-		commit.Message = strings.Join(lines[messageStart:], "\n")
-		commit.Message = strings.TrimSpace(commit.Message)
-	}
-
-	return commit, nil
-}
+// verbosityLevel is how much gvc reports besides a command's own output
+type verbosityLevel int
 
-func parseInt64(s string) int64 {
-    if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
-        return ts
-    }
-    fmt.Printf("warning: could not parse timestamp %q\n", s)
-    return 0
-}
-// catFile prints the contents of a gvc object (like Git's cat-file -p)
-func catFile(sha string) error {
-	objectType, content, err := readObject(sha)
-	if err != nil {
-		return err
-	}
-
-	switch objectType {
-	case BlobObject:
-		fmt.Print(string(content))
-	case TreeObject, CommitObject:
-		fmt.Print(string(content))
-	default:
-		return fmt.Errorf("unknown object type: %s", objectType)
-	}
-
-	return nil
-}
+const (
+	quiet   verbosityLevel = iota - 1 // no messages or progress meters
+	normal                            // messages, and progress meters on a terminal
+	verbose                           // progress meters from the start, even when not on a terminal
+)
 
-// hashObject reads a file, creates a blob object, and stores it
-func hashObject(filepath string) error {
-	data, err := os.ReadFile(filepath)
-	if err != nil {
-		return fmt.Errorf("failed to read file %s: %w", filepath, err)
-	}
+// verbosity is set by the global -q and -v flags
+var verbosity = normal
 
-	sha, err := writeObject(BlobObject, data)
-	if err != nil {
-		return err
+// outputWriters returns where messages and progress meters go under the
+// global -q and -v flags. Progress goes to standard error, and only when it
+// is a terminal unless -v is given.
+func outputWriters() (out, progress io.Writer) {
+	switch {
+	case verbosity == quiet:
+		return io.Discard, nil
+	case verbosity == verbose || isTerminal(os.Stderr.Fd()):
+		return os.Stdout, os.Stderr
 	}
-
-	fmt.Println(sha)
-	return nil
+	return os.Stdout, nil
 }
 
-// parseTreeEntries parses tree object content into structured entries
-
-func parseTreeEntries(content []byte) ([]TreeEntry, error) {
-	var entries []TreeEntry
-	index := 0
-
-	for index < len(content) {
-		// Read mode
-		modeStart := index
-		for index < len(content) && content[index] != ' ' {
-			index++
-		}
-		if index >= len(content) {
-			return nil, errors.New("malformed tree: missing space after mode")
-		}
-		mode := string(content[modeStart:index])
-		index++ // skip space
-
-		// Read name
-		nameStart := index
-		for index < len(content) && content[index] != 0 {
-			index++
-		}
-		if index >= len(content) {
-			return nil, errors.New("malformed tree: missing null byte after name")
-		}
-		name := string(content[nameStart:index])
-		index++ // skip null byte
-
-		// Read SHA (20 bytes)
-		if index+20 > len(content) {
-			return nil, errors.New("malformed tree: incomplete SHA")
-		}
-		shaBytes := content[index : index+20]
-		sha := hex.EncodeToString(shaBytes)
-		index += 20
-
-		// Determine object type based on mode
-		var objType ObjectType
-		switch mode {
-		case "40000":
-			objType = TreeObject
-		default:
-			objType = BlobObject
-		}
-
-		entries = append(entries, TreeEntry{
-			Mode: mode,
-			Name: name,
-			SHA:  sha,
-			Type: objType,
-		})
-	}
-
-	return entries, nil
+// setVerbosity applies the global -q and -v flags to a repository
+func setVerbosity(repo *gvc.Repository) {
+	repo.Out, repo.Progress = outputWriters()
+	repo.Err = os.Stderr
+	repo.Verbose = verbosity == verbose
 }
 
-// lsTree lists the contents of a tree object
-func lsTree(treeSHA string, nameOnly bool) error {
-	objectType, content, err := readObject(treeSHA)
+// runCommand finds the repository containing the current directory and runs
+// handler on it, refusing up front commands that would modify a read-only one
+func runCommand(command string, handler func(repo *gvc.Repository, args []string) error, args []string, readOnly bool) error {
+	repo, err := gvc.Discover(".")
 	if err != nil {
 		return err
 	}
-
-	if objectType != TreeObject {
-		return fmt.Errorf("expected tree object, got %s", objectType)
+	setVerbosity(repo)
+	repo.Context = interruptContext()
+	repo.Operation = command
+	if readOnly {
+		repo.ReadOnly = true
 	}
-
-	entries, err := parseTreeEntries(content)
-	if err != nil {
-		return err
+	allowed, ok := readOnlyCommands[command]
+	writes := !ok || !allowed(args)
+	if repo.ReadOnly && writes {
+		return fmt.Errorf("%w: 'gvc %s' would modify it", gvc.ErrReadOnly, command)
 	}
-
-	for _, entry := range entries {
-		if nameOnly {
-			fmt.Println(entry.Name)
-		} else {
-			fmt.Printf("%s %s %s\t%s\n", entry.Mode, entry.Type, entry.SHA, entry.Name)
+	if writes {
+		// gc and prune wait for the command, or it for them
+		release, err := repo.BeginWrite()
+		if err != nil {
+			return err
 		}
+		defer release()
 	}
-
-	return nil
+	return handler(repo, args)
 }
 
-// createTreeFromIndex creates a tree object from the current index
-func createTreeFromIndex() (string, error) {
-	index, err := readIndex()
-	if err != nil {
-		return "", err
-	}
-
-	if len(index.Entries) == 0 {
-		return "", errors.New("nothing to commit (staging area is empty)")
-	}
-
-	// Sort entries by name (Git requirement)
-	sort.Slice(index.Entries, func(i, j int) bool {
-		return index.Entries[i].Path < index.Entries[j].Path
-	})
-
-	// Build tree content
-	var treeContent bytes.Buffer
-	for _, entry := range index.Entries {
-		// Format: <mode> <name>\0<20-byte SHA>
-		treeContent.WriteString(fmt.Sprintf("%s %s", entry.Mode, filepath.Base(entry.Path)))
-		treeContent.WriteByte(0)
-		shaBytes, _ := hex.DecodeString(entry.SHA)
-		treeContent.Write(shaBytes)
-	}
-
-	return writeObject(TreeObject, treeContent.Bytes())
+// interruptContext returns a context the first Ctrl-C cancels, so long
+// operations stop at a safe point and release their locks. A second Ctrl-C
+// kills gvc at once.
+func interruptContext() context.Context {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx
 }
 
-// writeTree recursively creates tree objects for a directory
-func writeTree(basePath string) (string, error) {
-	dirEntries, err := os.ReadDir(basePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to read directory %s: %w", basePath, err)
-	}
-
-	var treeEntries []TreeEntry
-
-	for _, entry := range dirEntries {
-		name := entry.Name()
-
-		// Skip .gvc directory
-		if name == GvcDir {
-			continue
-		}
-
-		fullPath := filepath.Join(basePath, name)
-		var entrySHA string
-		var entryMode string
-		var entryType ObjectType
-
-		if entry.IsDir() {
-			entrySHA, err = writeTree(fullPath)
-			if err != nil {
-				return "", err
-			}
-			entryMode = "40000"
-			entryType = TreeObject
-		} else {
-			// Read file and create blob
-			data, err := os.ReadFile(fullPath)
-			if err != nil {
-				return "", fmt.Errorf("failed to read file %s: %w", fullPath, err)
+func main() {
+	args := os.Args[1:]
+	readOnly := false
+globalFlags:
+	for len(args) > 0 {
+		switch args[0] {
+		case "--json":
+			jsonOutput = true
+		case "--read-only":
+			readOnly = true
+		case "-q", "--quiet":
+			verbosity = quiet
+		case "-v", "--verbose":
+			verbosity = verbose
+		case "-h", "--help":
+			args = []string{"help"}
+			break globalFlags
+		case "--version":
+			args = []string{"version"}
+			break globalFlags
+		case "--namespace":
+			// The remotes fetched from, pushed to and cloned are confined to it
+			if len(args) < 2 {
+				fmt.Fprintln(os.Stderr, "--namespace requires a name")
+				os.Exit(ExitUsage)
 			}
-
-			entrySHA, err = writeObject(BlobObject, data)
-			if err != nil {
-				return "", err
+			os.Setenv(gvc.EnvNamespace, args[1])
+			args = args[1:]
+		default:
+			if ns, ok := strings.CutPrefix(args[0], "--namespace="); ok {
+				os.Setenv(gvc.EnvNamespace, ns)
+				break
 			}
-
-			entryMode = "100644"
-			entryType = BlobObject
-		}
-
-		treeEntries = append(treeEntries, TreeEntry{
-			Mode: entryMode,
-			Name: name,
-			SHA:  entrySHA,
-			Type: entryType,
-		})
-	}
-
-	// Sort entries by name (Git requirement)
-	sort.Slice(treeEntries, func(i, j int) bool {
-		return treeEntries[i].Name < treeEntries[j].Name
-	})
-
-	// Build tree content
-	var treeContent bytes.Buffer
-	for _, entry := range treeEntries {
-		// Format: <mode> <name>\0<20-byte SHA>
-		treeContent.WriteString(fmt.Sprintf("%s %s", entry.Mode, entry.Name))
-		treeContent.WriteByte(0)
-		shaBytes, _ := hex.DecodeString(entry.SHA)
-		treeContent.Write(shaBytes)
-	}
-
-	return writeObject(TreeObject, treeContent.Bytes())
-}
-
-// commitTree creates a commit object
-func commitTree(treeSHA, parentSHA, message string) (string, error) {
-	if err := validateSHA(treeSHA); err != nil {
-		return "", fmt.Errorf("invalid tree SHA: %w", err)
-	}
-
-	if parentSHA != "" {
-		if err := validateSHA(parentSHA); err != nil {
-			return "", fmt.Errorf("invalid parent SHA: %w", err)
+			break globalFlags
 		}
+		args = args[1:]
 	}
-
-	author := "gvc <Ritik Chauhan> <critik1704@gmail.com>"
-	timestamp := fmt.Sprintf("%d +0000", time.Now().Unix())
-
-	var commitContent bytes.Buffer
-	commitContent.WriteString(fmt.Sprintf("tree %s\n", treeSHA))
-	if parentSHA != "" {
-		commitContent.WriteString(fmt.Sprintf("parent %s\n", parentSHA))
-	}
-	commitContent.WriteString(fmt.Sprintf("author %s %s\ncommitter %s %s\n\n%s\n",
-		author, timestamp, author, timestamp, message))
-
-	return writeObject(CommitObject, commitContent.Bytes())
-}
-
-// Command handlers
-func handleInit() error {
-	return initializeRepo()
-}
-
-func handleCatFile(args []string) error {
-	if len(args) < 2 || args[0] != "-p" {
-		return errors.New("usage: gvc cat-file -p <hash>")
-	}
-	return catFile(args[1])
-}
-
-func handleHashObject(args []string) error {
-	if len(args) < 2 || args[0] != "-w" {
-		return errors.New("usage: gvc hash-object -w <file>")
-	}
-	return hashObject(args[1])
-}
-
-func handleLsTree(args []string) error {
 	if len(args) < 1 {
-		return errors.New("usage: gvc ls-tree [--name-only] <tree-sha>")
+		fmt.Fprintf(os.Stderr, "usage: %s\nSee 'gvc help' for the commands.\n", globalUsage)
+		os.Exit(ExitUsage)
 	}
+	command, args := args[0], args[1:]
 
-	var nameOnly bool
-	var treeSHA string
-
-	if len(args) == 1 {
-		treeSHA = args[0]
-	} else if len(args) == 2 && args[0] == "--name-only" {
-		nameOnly = true
-		treeSHA = args[1]
+	var err error
+	if jsonOutput && !jsonCommands[command] {
+		err = usageError(fmt.Sprintf("'gvc %s' has no JSON output", command))
+	} else if command == "help" {
+		err = handleHelp(args)
+	} else if _, ok := helpPages[command]; ok && wantsHelp(args) {
+		err = handleHelp([]string{command})
+	} else if readOnly && (command == "init" || command == "clone") {
+		err = fmt.Errorf("%w: 'gvc %s' creates a repository", gvc.ErrReadOnly, command)
+	} else if command == "init" {
+		err = handleInit(args)
+	} else if command == "clone" {
+		err = handleClone(args)
+	} else if command == "serve" {
+		err = handleServe(args)
+	} else if command == "version" {
+		err = handleVersion(args)
+	} else if command == "config" && len(args) > 0 && args[0] == "--global" {
+		err = handleGlobalConfig(args[1:])
+	} else if handler, ok := commands[command]; ok {
+		err = runCommand(command, handler, args, readOnly)
 	} else {
-		return errors.New("usage: gvc ls-tree [--name-only] <tree-sha>")
-	}
-
-	return lsTree(treeSHA, nameOnly)
-}
-
-func handleWriteTree(args []string) error {
-	if len(args) > 0 {
-		return errors.New("usage: gvc write-tree")
-	}
-
-	treeSHA, err := writeTree(".")
-	if err != nil {
-		return err
-	}
-
-	fmt.Println(treeSHA)
-	return nil
-}
-
-func handleCommitTree(args []string) error {
-	if len(args) < 5 || args[1] != "-p" || args[3] != "-m" {
-		return errors.New("usage: gvc commit-tree <tree_sha> -p <parent_sha> -m <commit_message>")
-	}
-
-	treeSHA := args[0]
-	parentSHA := args[2]
-	message := args[4]
-
-	commitSHA, err := commitTree(treeSHA, parentSHA, message)
-	if err != nil {
-		return err
-	}
-
-	fmt.Print(commitSHA)
-	return nil
-}
-
-// NEW: Add command
-func handleAdd(args []string) error {
-	if len(args) == 0 {
-		return errors.New("usage: gvc add <file>")
+		fmt.Fprintf(os.Stderr, "Unknown command: %s\nSee 'gvc help' for the commands.\n", command)
+		os.Exit(ExitUsage)
 	}
 
-	index, err := readIndex()
 	if err != nil {
-		return err
-	}
-
-	for _, filePath := range args {
-		// Check if file exists
-		fileInfo, err := os.Stat(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to stat file %s: %w", filePath, err)
-		}
-
-		// Read file content
-		data, err := os.ReadFile(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to read file %s: %w", filePath, err)
-		}
-
-		// Create blob object
-		sha, err := writeObject(BlobObject, data)
-		if err != nil {
-			return fmt.Errorf("failed to create blob for %s: %w", filePath, err)
+		var exitErr *ExitError
+		if errors.Is(err, context.Canceled) {
+			fmt.Fprintln(os.Stderr, "Interrupted")
+		} else if !errors.As(err, &exitErr) || exitErr.Err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
 		}
-
-		// Determine file mode
-		mode := "100644" // Regular file
-		if fileInfo.Mode()&0111 != 0 {
-			mode = "100755" // Executable file
-		}
-
-		// Update index entry
-		entry := IndexEntry{
-			Path:    filePath,
-			SHA:     sha,
-			Mode:    mode,
-			Size:    fileInfo.Size(),
-			ModTime: fileInfo.ModTime(),
-		}
-
-		// Remove existing entry for this path
-		for i, existing := range index.Entries {
-			if existing.Path == filePath {
-				index.Entries = append(index.Entries[:i], index.Entries[i+1:]...)
-				break
-			}
-		}
-
-		// Add new entry
-		index.Entries = append(index.Entries, entry)
+		os.Exit(exitCode(err))
 	}
-
-	// Write updated index
-	if err := writeIndex(index); err != nil {
-		return err
-	}
-
-	fmt.Printf("Added %d file(s) to staging area\n", len(args))
-	return nil
 }
-
-// NEW: Commit command
-func handleCommit(args []string) error {
-	if len(args) < 2 || args[0] != "-m" {
-		return errors.New("usage: gvc commit -m <message>")
-	}
-
-	message := args[1]
-
-	// Create tree from current index
-	treeSHA, err := createTreeFromIndex()
-	if err != nil {
-		return err
-	}
-
-	// Get current commit as parent
-	parentSHA, err := getCurrentCommit()
-	if err != nil {
-		return fmt.Errorf("failed to get current commit: %w", err)
-	}
-
-	// Create commit object
-	commitSHA, err := commitTree(treeSHA, parentSHA, message)
-	if err != nil {
-		return err
-	}
-
-	// Update branch reference
-	if err := updateBranchRef(commitSHA); err != nil {
-		return fmt.Errorf("failed to update branch: %w", err)
-	}
-
-	// Clear index after successful commit
-	emptyIndex := Index{Entries: []IndexEntry{}}
-	if err := writeIndex(&emptyIndex); err != nil {
-		return fmt.Errorf("failed to clear index: %w", err)
-	}
-
-	fmt.Printf("[main %s] %s\n", commitSHA[:7], message)
-	return nil
-}
-
-// NEW: Log command
-func handleLog(args []string) error {
-	if len(args) > 0 {
-		return errors.New("usage: gvc log")
-	}
-	currentCommit, err := getCurrentCommit()
-	if err != nil {
-		return fmt.Errorf("failed to get current commit: %w", err)
-	}
-
-	if currentCommit == "" {
-		fmt.Println("No commits yet")
-		return nil
-	}
-
-	// Walk the commit history
-	commitSHA := currentCommit
-	for commitSHA != "" {
-		// Read commit object
-		objectType, content, err := readObject(commitSHA)
-		if err != nil {
-			return fmt.Errorf("failed to read commit %s: %w", commitSHA, err)
-		}
-
-		if objectType != CommitObject {
-			return fmt.Errorf("expected commit object, got %s", objectType)
-		}
-
-		// Parse commit
-		commit, err := parseCommit(commitSHA, content)
-		if err != nil {
-			return fmt.Errorf("failed to parse commit %s: %w", commitSHA, err)
-		}
-
-		// Display commit info
-		fmt.Printf("commit %s\n", commitSHA)
-		fmt.Printf("Author: %s\n", commit.Author)
-		fmt.Printf("Date: %s\n", commit.Timestamp.Format("Mon Jan 2 15:04:05 2006 -0700"))
-		fmt.Printf("\n    %s\n\n", commit.Message)
-
-		// Move to parent commit
-		commitSHA = commit.ParentSHA
-	}
-
-	return nil
-}
-
-func main() {
-	if len(os.Args) < 2 {
-		fmt.Fprintln(os.Stderr, "usage: gvc <command> [<args>...]")
-		os.Exit(1)
-	}
-
-	command := os.Args[1]
-	args := os.Args[2:]
-
-	var err error
-
-	switch command {
-	case "init":
-		err = handleInit()
-	case "cat-file":
-		err = handleCatFile(args)
-	case "hash-object":
-		err = handleHashObject(args)
-	case "ls-tree":
-		err = handleLsTree(args)
-	case "write-tree":
-		err = handleWriteTree(args)
-	case "commit-tree":
-		err = handleCommitTree(args)
-	case "add":
-		err = handleAdd(args)
-	case "commit":
-		err = handleCommit(args)
-	case "log":
-		err = handleLog(args)
-	default:
-		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
-		os.Exit(1)
-	}
-
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error:", err)
-		os.Exit(1)
-	}
-}
\ No newline at end of file
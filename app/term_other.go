@@ -0,0 +1,26 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package main
+
+import "errors"
+
+// terminalUI is set where gvc can drive the terminal for ui
+const terminalUI = false
+
+// errNoTerminal is returned where gvc cannot drive the terminal
+var errNoTerminal = errors.New("the terminal UI is not supported on this platform")
+
+// isTerminal reports whether fd is a terminal; it cannot tell here
+func isTerminal(fd uintptr) bool {
+	return false
+}
+
+// makeRaw cannot put the terminal into raw mode here
+func makeRaw(fd uintptr) (restore func(), err error) {
+	return nil, errNoTerminal
+}
+
+// terminalSize cannot read the terminal size here
+func terminalSize(fd uintptr) (width, height int, err error) {
+	return 0, 0, errNoTerminal
+}
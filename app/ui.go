@@ -0,0 +1,598 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/gvc"
+)
+
+// ANSI escape sequences the terminal UI draws with
+const (
+	ansiAltScreen  = "\x1b[?1049h\x1b[?25l" // switch to the alternate screen, hide the cursor
+	ansiMainScreen = "\x1b[?25h\x1b[?1049l" // the reverse
+	ansiHome       = "\x1b[H"
+	ansiClearLine  = "\x1b[K"
+	ansiReset      = "\x1b[m"
+)
+
+// SGR colors of the terminal UI
+const (
+	uiBold    = "1"
+	uiReverse = "7"
+	uiRed     = "31"
+	uiGreen   = "32"
+	uiYellow  = "33"
+	uiCyan    = "36"
+)
+
+// uiItem is what a selectable line stands for: a path in a status area, or
+// a commit
+type uiItem struct {
+	area string // staged, unstaged or untracked
+	path string
+	sha  string
+}
+
+// uiLine is one line of a pane
+type uiLine struct {
+	text  string
+	color string
+	item  *uiItem // nil for lines that cannot be selected
+}
+
+// uiPane is one screen of the UI. A pane with selectable lines moves a
+// cursor over them; one without scrolls like a pager.
+type uiPane struct {
+	title  string
+	help   string
+	lines  []uiLine
+	cursor int // the selected line, -1 in a pager
+	top    int // the first line shown
+	// load fills title and lines, and runs again on refresh; nil for a pane
+	// whose lines never change
+	load func(p *uiPane) error
+	// key handles the keys a pane adds to the common ones, reporting
+	// whether it knew the key
+	key func(u *ui, key string) (bool, error)
+}
+
+// ui is a running terminal UI: a stack of panes, the top one shown
+type ui struct {
+	repo    *gvc.Repository
+	out     *bufio.Writer
+	panes   []*uiPane
+	message string       // shown in the status bar until the next key
+	output  bytes.Buffer // warnings the repository prints
+	quit    bool
+}
+
+// runUI runs the terminal UI on repo until it is quit
+func runUI(repo *gvc.Repository) error {
+	if !isTerminal(os.Stdout.Fd()) {
+		return fmt.Errorf("gvc ui needs a terminal")
+	}
+	restore, err := makeRaw(os.Stdin.Fd())
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	u := &ui{repo: repo, out: bufio.NewWriter(os.Stdout)}
+	repo.Out, repo.Err = &u.output, &u.output
+	u.out.WriteString(ansiAltScreen)
+	defer func() {
+		u.out.WriteString(ansiMainScreen)
+		u.out.Flush()
+	}()
+
+	if err := u.push(u.statusPane()); err != nil {
+		return err
+	}
+	buf := make([]byte, 64)
+	for !u.quit && len(u.panes) > 0 {
+		u.draw()
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return fmt.Errorf("failed to read keys: %w", err)
+		}
+		u.message = ""
+		for _, key := range decodeKeys(buf[:n]) {
+			if u.quit || len(u.panes) == 0 {
+				break
+			}
+			if err := u.handleKey(key); err != nil {
+				u.message = "error: " + err.Error()
+			}
+		}
+		if line, _, _ := strings.Cut(strings.TrimSpace(u.output.String()), "\n"); line != "" {
+			u.message = line
+		}
+		u.output.Reset()
+	}
+	return nil
+}
+
+// decodeKeys splits what one read from the terminal returned into keys, as
+// several can arrive at once when typed or pasted quickly
+func decodeKeys(input []byte) []string {
+	var keys []string
+	for len(input) > 0 {
+		n := 1
+		if input[0] == 0x1b && len(input) > 2 && (input[1] == '[' || input[1] == 'O') {
+			// An escape sequence runs to its final letter or ~
+			for n = 2; n < len(input); n++ {
+				if c := input[n]; c == '~' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') {
+					n++
+					break
+				}
+			}
+		} else if _, size := utf8.DecodeRune(input); size > 1 {
+			n = size
+		}
+		keys = append(keys, decodeKey(input[:n]))
+		input = input[n:]
+	}
+	return keys
+}
+
+// decodeKey names a key: the escape sequences of arrows and paging keys get
+// names, anything else is itself
+func decodeKey(input []byte) string {
+	switch key := string(input); key {
+	case "\x1b[A", "\x1bOA":
+		return "up"
+	case "\x1b[B", "\x1bOB":
+		return "down"
+	case "\x1b[5~":
+		return "pgup"
+	case "\x1b[6~":
+		return "pgdn"
+	case "\x1b[H", "\x1bOH", "\x1b[1~":
+		return "home"
+	case "\x1b[F", "\x1bOF", "\x1b[4~":
+		return "end"
+	case "\r", "\n":
+		return "enter"
+	case "\x1b":
+		return "esc"
+	case "\x03":
+		return "ctrl-c"
+	default:
+		return key
+	}
+}
+
+// push loads a pane and shows it on top
+func (u *ui) push(p *uiPane) error {
+	if p.load != nil {
+		if err := p.load(p); err != nil {
+			return err
+		}
+	}
+	p.cursor = -1
+	for i, line := range p.lines {
+		if line.item != nil {
+			p.cursor = i
+			break
+		}
+	}
+	u.panes = append(u.panes, p)
+	return nil
+}
+
+// reload refills the top pane, keeping the cursor near where it was
+func (u *ui) reload() error {
+	p := u.panes[len(u.panes)-1]
+	if p.load == nil {
+		return nil
+	}
+	if err := p.load(p); err != nil {
+		return err
+	}
+	if p.cursor >= 0 {
+		p.cursor = min(p.cursor, len(p.lines)-1)
+		if !p.selectable(p.cursor) && !p.moveCursor(1) && !p.moveCursor(-1) {
+			p.cursor = -1
+		}
+	}
+	return nil
+}
+
+// handleKey runs a key on the top pane: its own keys first, then the ones
+// every pane shares
+func (u *ui) handleKey(key string) error {
+	p := u.panes[len(u.panes)-1]
+	if p.key != nil {
+		if handled, err := p.key(u, key); handled || err != nil {
+			return err
+		}
+	}
+	_, height := u.size()
+	page := max(height-3, 1)
+	switch key {
+	case "q", "esc":
+		u.panes = u.panes[:len(u.panes)-1]
+	case "ctrl-c":
+		u.quit = true
+	case "j", "down":
+		p.scroll(1)
+	case "k", "up":
+		p.scroll(-1)
+	case " ", "pgdn", "f":
+		p.scroll(page)
+	case "b", "pgup":
+		p.scroll(-page)
+	case "g", "home":
+		p.scroll(-len(p.lines))
+	case "G", "end":
+		p.scroll(len(p.lines))
+	case "r":
+		return u.reload()
+	}
+	return nil
+}
+
+// selectable reports whether line i can take the cursor
+func (p *uiPane) selectable(i int) bool {
+	return i >= 0 && i < len(p.lines) && p.lines[i].item != nil
+}
+
+// moveCursor moves the cursor to the next selectable line in direction
+// step, reporting whether there was one
+func (p *uiPane) moveCursor(step int) bool {
+	for i := p.cursor + step; i >= 0 && i < len(p.lines); i += step {
+		if p.selectable(i) {
+			p.cursor = i
+			return true
+		}
+	}
+	return false
+}
+
+// scroll moves the cursor by n selectable lines, or a pager by n lines
+func (p *uiPane) scroll(n int) {
+	if p.cursor < 0 {
+		p.top = max(min(p.top+n, len(p.lines)-1), 0)
+		return
+	}
+	step := 1
+	if n < 0 {
+		n, step = -n, -1
+	}
+	for ; n > 0 && p.moveCursor(step); n-- {
+	}
+}
+
+// selected returns the item under the cursor, or nil
+func (p *uiPane) selected() *uiItem {
+	if !p.selectable(p.cursor) {
+		return nil
+	}
+	return p.lines[p.cursor].item
+}
+
+// size returns the terminal's size, 80x24 when it cannot be read
+func (u *ui) size() (width, height int) {
+	width, height, err := terminalSize(os.Stdout.Fd())
+	if err != nil || width <= 0 || height <= 0 {
+		return 80, 24
+	}
+	return width, height
+}
+
+// draw redraws the screen: the top pane's title, as many of its lines as
+// fit and a status bar with the message or the pane's keys
+func (u *ui) draw() {
+	p := u.panes[len(u.panes)-1]
+	width, height := u.size()
+	body := max(height-2, 1)
+	if p.cursor >= 0 {
+		// Keep the cursor on screen
+		if p.cursor < p.top {
+			p.top = p.cursor
+		} else if p.cursor >= p.top+body {
+			p.top = p.cursor - body + 1
+		}
+	}
+
+	u.out.WriteString(ansiHome)
+	u.drawLine(p.title, uiReverse, width)
+	for row := 0; row < body; row++ {
+		i := p.top + row
+		if i >= len(p.lines) {
+			u.drawLine("", "", width)
+			continue
+		}
+		color := p.lines[i].color
+		if i == p.cursor {
+			color = uiReverse
+		}
+		u.drawLine(p.lines[i].text, color, width)
+	}
+	status, color := p.help, ""
+	if u.message != "" {
+		status, color = u.message, uiYellow
+	}
+	fmt.Fprintf(u.out, "\x1b[%dH", height)
+	u.out.WriteString(ansiClearLine)
+	u.out.WriteString(colorize(fitLine(status, width), color))
+	u.out.Flush()
+}
+
+// drawLine writes one row of the screen, ending the line
+func (u *ui) drawLine(text, color string, width int) {
+	u.out.WriteString(colorize(fitLine(text, width), color))
+	u.out.WriteString(ansiClearLine + "\r\n")
+}
+
+// colorize wraps text in an SGR color
+func colorize(text, color string) string {
+	if color == "" {
+		return text
+	}
+	return "\x1b[" + color + "m" + text + ansiReset
+}
+
+// fitLine makes text safe to draw in one row: tabs become spaces, other
+// control characters are replaced and the text is cut at width
+func fitLine(text string, width int) string {
+	text = strings.ReplaceAll(strings.TrimRight(text, "\r\n"), "\t", "    ")
+	runes := []rune(text)
+	if len(runes) > width {
+		runes = runes[:width]
+	}
+	for i, r := range runes {
+		if r < ' ' || r == 0x7f {
+			runes[i] = '?'
+		}
+	}
+	return string(runes)
+}
+
+// statusPane lists the staged, unstaged and untracked files the way status does
+func (u *ui) statusPane() *uiPane {
+	return &uiPane{
+		help: "j/k move  enter diff  s stage  u unstage  l log  r refresh  q quit",
+		load: func(p *uiPane) error {
+			head, changes, err := statusRecords(u.repo, -1, false)
+			if err != nil {
+				return err
+			}
+			p.title = "On branch " + head.Branch
+			if head.Branch == "" {
+				p.title = "HEAD detached at " + head.Commit[:7]
+			}
+			p.lines = nil
+			sections := []struct{ area, title, color string }{
+				{"staged", "Changes to be committed:", uiGreen},
+				{"unstaged", "Changes not staged for commit:", uiRed},
+				{"untracked", "Untracked files:", uiRed},
+			}
+			for _, section := range sections {
+				var lines []uiLine
+				for _, change := range changes {
+					if change.Area != section.area {
+						continue
+					}
+					text := fmt.Sprintf("    %-12s %s", change.Change+":", change.Path)
+					if change.OldPath != "" {
+						text = fmt.Sprintf("    %-12s %s -> %s", change.Change+":", change.OldPath, change.Path)
+					}
+					if section.area == "untracked" {
+						text = "    " + change.Path
+					}
+					lines = append(lines, uiLine{text: text, color: section.color, item: &uiItem{area: section.area, path: change.Path}})
+				}
+				if len(lines) > 0 {
+					p.lines = append(p.lines, uiLine{text: section.title, color: uiBold})
+					p.lines = append(p.lines, lines...)
+					p.lines = append(p.lines, uiLine{})
+				}
+			}
+			if len(p.lines) == 0 {
+				p.lines = []uiLine{{text: "nothing to commit, working tree clean"}}
+			}
+			return nil
+		},
+		key: func(u *ui, key string) (bool, error) {
+			p := u.panes[len(u.panes)-1]
+			item := p.selected()
+			switch key {
+			case "l":
+				return true, u.push(u.logPane())
+			case "enter":
+				if item == nil {
+					return true, nil
+				}
+				pager, err := u.fileDiffPane(item)
+				if err != nil {
+					return true, err
+				}
+				return true, u.push(pager)
+			case "s":
+				if item == nil || item.area == "staged" {
+					return true, nil
+				}
+				if err := u.write(func() error { return u.repo.Add(item.path) }); err != nil {
+					return true, err
+				}
+				u.message = "staged " + item.path
+				return true, u.reload()
+			case "u":
+				if item == nil || item.area != "staged" {
+					return true, nil
+				}
+				if err := u.write(func() error { return u.repo.Unstage(item.path) }); err != nil {
+					return true, err
+				}
+				u.message = "unstaged " + item.path
+				return true, u.reload()
+			}
+			return false, nil
+		},
+	}
+}
+
+// write makes a change to the repository registered with BeginWrite, as a
+// writing command is, so gc and prune wait for the change but not for the
+// whole session
+func (u *ui) write(change func() error) error {
+	release, err := u.repo.BeginWrite()
+	if err != nil {
+		return err
+	}
+	defer release()
+	return change()
+}
+
+// fileDiffPane shows the change to one file of the status pane: the staged
+// or unstaged patch, or an untracked file's content
+func (u *ui) fileDiffPane(item *uiItem) (*uiPane, error) {
+	var patch bytes.Buffer
+	if item.area == "untracked" {
+		content, err := os.ReadFile(filepath.Join(u.repo.Root, filepath.FromSlash(item.path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", item.path, err)
+		}
+		fmt.Fprintf(&patch, "new file %s\n", item.path)
+		if bytes.IndexByte(content, 0) >= 0 {
+			patch.WriteString("Binary file\n")
+		} else {
+			for _, line := range strings.SplitAfter(string(content), "\n") {
+				if line != "" {
+					patch.WriteString("+" + line)
+				}
+			}
+		}
+	} else {
+		merged, unmerged, err := diffChanges(u.repo, item.area == "staged", nil, -1, -1)
+		if err != nil {
+			return nil, err
+		}
+		var changes []gvc.FileChange
+		for _, change := range merged {
+			if change.Path == item.path {
+				changes = append(changes, change)
+			}
+		}
+		for _, path := range unmerged {
+			if path == item.path {
+				if err := u.repo.WriteCombinedPatch(&patch, []string{path}); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if err := u.repo.WritePatch(&patch, changes); err != nil {
+			return nil, err
+		}
+	}
+	return pagerPane(item.area+": "+item.path, patch.String()), nil
+}
+
+// logPane lists the commits reachable from HEAD, newest first
+func (u *ui) logPane() *uiPane {
+	return &uiPane{
+		title: "Log",
+		help:  "j/k move  enter show  r refresh  q back",
+		load: func(p *uiPane) error {
+			p.lines = nil
+			head, err := u.repo.HeadCommit()
+			if err != nil {
+				return err
+			}
+			if head == "" {
+				p.lines = []uiLine{{text: "No commits yet"}}
+				return nil
+			}
+			commits, err := u.repo.Log()
+			if err != nil {
+				return err
+			}
+			for _, commit := range commits {
+				subject, _, _ := strings.Cut(commit.Message, "\n")
+				name := commit.Author
+				if i := strings.LastIndex(name, " <"); i >= 0 {
+					name = name[:i]
+				}
+				text := fmt.Sprintf("%s %s %s (%s)", commit.SHA[:7], commit.Timestamp.Format("2006-01-02"), subject, name)
+				p.lines = append(p.lines, uiLine{text: text, item: &uiItem{sha: commit.SHA}})
+			}
+			return nil
+		},
+		key: func(u *ui, key string) (bool, error) {
+			item := u.panes[len(u.panes)-1].selected()
+			if key != "enter" || item == nil {
+				return false, nil
+			}
+			pager, err := u.commitPane(item.sha)
+			if err != nil {
+				return true, err
+			}
+			return true, u.push(pager)
+		},
+	}
+}
+
+// commitPane shows a commit the way show does: its details and the patch
+// against its first parent
+func (u *ui) commitPane(sha string) (*uiPane, error) {
+	commit, err := u.repo.ReadCommit(sha)
+	if err != nil {
+		return nil, err
+	}
+	text, err := mediumCommit(u.repo, commit, "", false, nil)
+	if err != nil {
+		return nil, err
+	}
+	parentTree := ""
+	if commit.ParentSHA != "" {
+		parent, err := u.repo.ReadCommit(commit.ParentSHA)
+		if err != nil {
+			return nil, err
+		}
+		parentTree = parent.TreeSHA
+	}
+	changes, err := u.repo.DiffTrees(parentTree, commit.TreeSHA)
+	if err != nil {
+		return nil, err
+	}
+	if changes, err = detectRenames(u.repo, changes, -1); err != nil {
+		return nil, err
+	}
+	var patch bytes.Buffer
+	if err := u.repo.WritePatch(&patch, changes); err != nil {
+		return nil, err
+	}
+	if patch.Len() > 0 {
+		text += "\n" + patch.String()
+	}
+	return pagerPane("commit "+sha[:7], text), nil
+}
+
+// pagerPane shows text to scroll through, coloring it like a patch
+func pagerPane(title, text string) *uiPane {
+	p := &uiPane{title: title, help: "j/k scroll  space/b page  g/G top/bottom  q back"}
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		color := ""
+		switch {
+		case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "diff --git "):
+			color = uiBold
+		case strings.HasPrefix(line, "@@"):
+			color = uiCyan
+		case strings.HasPrefix(line, "+"):
+			color = uiGreen
+		case strings.HasPrefix(line, "-"):
+			color = uiRed
+		case strings.HasPrefix(line, "commit "):
+			color = uiYellow
+		}
+		p.lines = append(p.lines, uiLine{text: line, color: color})
+	}
+	return p
+}
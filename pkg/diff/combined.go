@@ -0,0 +1,155 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CombinedLine is one line of a combined diff of a result against several
+// parents. Marks has a column per parent: ' ' when the parent has the line,
+// '+' when the result added it, and '-' when the result lost it.
+type CombinedLine struct {
+	Marks []byte
+	Line  string
+}
+
+// inParent reports whether the line exists in parent p
+func (l CombinedLine) inParent(p int) bool {
+	if l.Marks[p] == '-' {
+		return true
+	}
+	return l.Marks[p] == ' ' && !l.lost()
+}
+
+// lost reports whether the line was dropped from one of the parents
+func (l CombinedLine) lost() bool {
+	return strings.IndexByte(string(l.Marks), '-') >= 0
+}
+
+// changed reports whether the line differs from any parent
+func (l CombinedLine) changed() bool {
+	return strings.Trim(string(l.Marks), " ") != ""
+}
+
+// Combined diffs result against every parent at once, as Git shows merges:
+// each result line is marked per parent, and lines a parent had but the
+// result dropped appear before the result line that follows them
+func Combined(parents [][]string, result []string) []CombinedLine {
+	added := make([][]bool, len(parents))
+	lost := make([][][]string, len(parents))
+	for p, parent := range parents {
+		added[p] = make([]bool, len(result))
+		lost[p] = make([][]string, len(result)+1)
+		j := 0
+		for _, op := range Lines(parent, result) {
+			switch op.Kind {
+			case '-':
+				lost[p][j] = append(lost[p][j], op.Line)
+			case '+':
+				added[p][j] = true
+				j++
+			default:
+				j++
+			}
+		}
+	}
+
+	var lines []CombinedLine
+	for j := 0; j <= len(result); j++ {
+		for p := range parents {
+			for _, line := range lost[p][j] {
+				marks := []byte(strings.Repeat(" ", len(parents)))
+				marks[p] = '-'
+				lines = append(lines, CombinedLine{Marks: marks, Line: line})
+			}
+		}
+		if j == len(result) {
+			break
+		}
+		marks := make([]byte, len(parents))
+		for p := range parents {
+			marks[p] = ' '
+			if added[p][j] {
+				marks[p] = '+'
+			}
+		}
+		lines = append(lines, CombinedLine{Marks: marks, Line: result[j]})
+	}
+	return lines
+}
+
+// CombinedHunk is a run of combined diff lines with the unchanged lines around them
+type CombinedHunk struct {
+	OldStarts, OldLines []int // 1-based first line and line count in each parent
+	NewStart, NewLines  int   // 1-based first line and line count in the result
+	Lines               []CombinedLine
+}
+
+// Header returns the hunk's "@@@ -a,b -c,d +e,f @@@" line, with one more "@"
+// than there are parents
+func (h CombinedHunk) Header() string {
+	marker := strings.Repeat("@", len(h.OldStarts)+1)
+	var header strings.Builder
+	header.WriteString(marker)
+	for p := range h.OldStarts {
+		fmt.Fprintf(&header, " -%s", hunkRange(h.OldStarts[p], h.OldLines[p]))
+	}
+	fmt.Fprintf(&header, " +%s %s", hunkRange(h.NewStart, h.NewLines), marker)
+	return header.String()
+}
+
+// CombinedHunks groups combined diff lines into hunks the way Hunks groups an
+// edit script
+func CombinedHunks(lines []CombinedLine, context int) []CombinedHunk {
+	if len(lines) == 0 {
+		return nil
+	}
+	parents := len(lines[0].Marks)
+
+	// Line numbers in every parent and the result at the start of every line
+	oldLine := make([][]int, len(lines)+1)
+	newLine := make([]int, len(lines)+1)
+	oldLine[0] = make([]int, parents)
+	for p := range parents {
+		oldLine[0][p] = 1
+	}
+	newLine[0] = 1
+	var changes []int
+	for i, line := range lines {
+		oldLine[i+1] = append([]int(nil), oldLine[i]...)
+		for p := range parents {
+			if line.inParent(p) {
+				oldLine[i+1][p]++
+			}
+		}
+		newLine[i+1] = newLine[i]
+		if !line.lost() {
+			newLine[i+1]++
+		}
+		if line.changed() {
+			changes = append(changes, i)
+		}
+	}
+
+	var hunks []CombinedHunk
+	for c := 0; c < len(changes); {
+		start := max(changes[c]-context, 0)
+		last := changes[c]
+		for c++; c < len(changes) && changes[c]-last <= 2*context+1; c++ {
+			last = changes[c]
+		}
+		end := min(last+context+1, len(lines))
+		hunk := CombinedHunk{
+			OldStarts: oldLine[start],
+			OldLines:  make([]int, parents),
+			NewStart:  newLine[start],
+			NewLines:  newLine[end] - newLine[start],
+			Lines:     lines[start:end],
+		}
+		for p := range parents {
+			hunk.OldLines[p] = oldLine[end][p] - oldLine[start][p]
+		}
+		hunks = append(hunks, hunk)
+	}
+	return hunks
+}
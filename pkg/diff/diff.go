@@ -0,0 +1,150 @@
+// Package diff computes line diffs, combined diffs and three-way merges of text.
+package diff
+
+import (
+	"strings"
+)
+
+// Op is one line of an edit script
+type Op struct {
+	Kind byte // ' ' unchanged, '-' only in a, '+' only in b
+	Line string
+}
+
+// maxEditDistance bounds the Myers search; beyond it the remaining
+// region is reported as a wholesale replacement
+const maxEditDistance = 4096
+
+// SplitLines splits content into lines, keeping each line's terminator
+func SplitLines(content []byte) []string {
+	var lines []string
+	text := string(content)
+	for text != "" {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			lines = append(lines, text)
+			break
+		}
+		lines = append(lines, text[:i+1])
+		text = text[i+1:]
+	}
+	return lines
+}
+
+// Lines computes a shortest edit script turning a into b (Myers' algorithm)
+func Lines(a, b []string) []Op {
+	// Trim the common prefix and suffix before searching
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	for _, line := range a[:prefix] {
+		ops = append(ops, Op{Kind: ' ', Line: line})
+	}
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, Op{Kind: ' ', Line: line})
+	}
+	return ops
+}
+
+func myersDiff(a, b []string) []Op {
+	n, m := len(a), len(b)
+	replaceAll := func() []Op {
+		ops := make([]Op, 0, n+m)
+		for _, line := range a {
+			ops = append(ops, Op{Kind: '-', Line: line})
+		}
+		for _, line := range b {
+			ops = append(ops, Op{Kind: '+', Line: line})
+		}
+		return ops
+	}
+	if n == 0 || m == 0 {
+		return replaceAll()
+	}
+
+	// trace[d] holds the furthest x reached on each diagonal k in [-d, d] after d edits
+	var trace [][]int
+	prev := []int{0}
+	found := false
+	for d := 0; d <= n+m && d <= maxEditDistance; d++ {
+		v := make([]int, 2*d+1)
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && prev[k-1+(d-1)] < prev[k+1+(d-1)]) {
+				if d == 0 {
+					x = 0
+				} else {
+					x = prev[k+1+(d-1)]
+				}
+			} else {
+				x = prev[k-1+(d-1)] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+d] = x
+			if x >= n && y >= m {
+				found = true
+			}
+		}
+		trace = append(trace, v)
+		prev = v
+		if found {
+			break
+		}
+	}
+	if !found {
+		return replaceAll()
+	}
+
+	// Walk the trace backwards from (n, m) to recover the script
+	var reversed []Op
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		k := x - y
+		var prevK int
+		if d == 0 {
+			prevK = 0
+		} else if k == -d || (k != d && trace[d-1][k-1+(d-1)] < trace[d-1][k+1+(d-1)]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := 0
+		if d > 0 {
+			prevX = trace[d-1][prevK+(d-1)]
+		}
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, Op{Kind: ' ', Line: a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Op{Kind: '+', Line: b[prevY]})
+			} else {
+				reversed = append(reversed, Op{Kind: '-', Line: a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	ops := make([]Op, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
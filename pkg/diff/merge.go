@@ -0,0 +1,117 @@
+package diff
+
+import (
+	"sort"
+	"strings"
+)
+
+// mergeHunk is a changed region: base[BaseStart:BaseEnd] became side[SideStart:SideEnd]
+type mergeHunk struct {
+	BaseStart, BaseEnd int
+	SideStart, SideEnd int
+	Ours               bool
+}
+
+// diffHunks groups an edit script into changed regions
+func diffHunks(base, side []string, ours bool) []mergeHunk {
+	var hunks []mergeHunk
+	baseIdx, sideIdx := 0, 0
+	var current *mergeHunk
+	for _, op := range Lines(base, side) {
+		if op.Kind == ' ' {
+			if current != nil {
+				hunks = append(hunks, *current)
+				current = nil
+			}
+			baseIdx++
+			sideIdx++
+			continue
+		}
+		if current == nil {
+			current = &mergeHunk{BaseStart: baseIdx, BaseEnd: baseIdx, SideStart: sideIdx, SideEnd: sideIdx, Ours: ours}
+		}
+		if op.Kind == '-' {
+			baseIdx++
+			current.BaseEnd = baseIdx
+		} else {
+			sideIdx++
+			current.SideEnd = sideIdx
+		}
+	}
+	if current != nil {
+		hunks = append(hunks, *current)
+	}
+	return hunks
+}
+
+// Merge performs a diff3-style merge of two descendants of base. Regions
+// changed differently on both sides are wrapped in conflict markers.
+func Merge(base, ours, theirs []string, oursLabel, theirsLabel string) ([]string, bool) {
+	hunks := append(diffHunks(base, ours, true), diffHunks(base, theirs, false)...)
+	sort.SliceStable(hunks, func(i, j int) bool {
+		return hunks[i].BaseStart < hunks[j].BaseStart
+	})
+
+	var result []string
+	conflict := false
+	pos := 0
+	// Offsets of each side relative to base, from hunks already consumed
+	oursDelta, theirsDelta := 0, 0
+
+	for i := 0; i < len(hunks); {
+		start, end := hunks[i].BaseStart, hunks[i].BaseEnd
+		j := i + 1
+		for j < len(hunks) && hunks[j].BaseStart <= end {
+			if hunks[j].BaseEnd > end {
+				end = hunks[j].BaseEnd
+			}
+			j++
+		}
+		group := hunks[i:j]
+		i = j
+
+		result = append(result, base[pos:start]...)
+		pos = end
+
+		oursChanged, theirsChanged := false, false
+		oursStart, theirsStart := start+oursDelta, start+theirsDelta
+		for _, h := range group {
+			if h.Ours {
+				oursChanged = true
+				oursDelta += (h.SideEnd - h.SideStart) - (h.BaseEnd - h.BaseStart)
+			} else {
+				theirsChanged = true
+				theirsDelta += (h.SideEnd - h.SideStart) - (h.BaseEnd - h.BaseStart)
+			}
+		}
+		oursLines := ours[oursStart : end+oursDelta]
+		theirsLines := theirs[theirsStart : end+theirsDelta]
+
+		switch {
+		case !theirsChanged:
+			result = append(result, oursLines...)
+		case !oursChanged:
+			result = append(result, theirsLines...)
+		case strings.Join(oursLines, "") == strings.Join(theirsLines, ""):
+			result = append(result, oursLines...)
+		default:
+			conflict = true
+			result = append(result, "<<<<<<< "+oursLabel+"\n")
+			result = appendTerminated(result, oursLines)
+			result = append(result, "=======\n")
+			result = appendTerminated(result, theirsLines)
+			result = append(result, ">>>>>>> "+theirsLabel+"\n")
+		}
+	}
+	result = append(result, base[pos:]...)
+	return result, conflict
+}
+
+// appendTerminated appends lines, making sure the last one ends with a newline
+func appendTerminated(dst, lines []string) []string {
+	dst = append(dst, lines...)
+	if n := len(dst); n > 0 && len(lines) > 0 && !strings.HasSuffix(dst[n-1], "\n") {
+		dst[n-1] += "\n"
+	}
+	return dst
+}
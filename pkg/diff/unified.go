@@ -0,0 +1,148 @@
+package diff
+
+import "fmt"
+
+// Hunk is a run of changes with the unchanged lines around them, as shown in a unified diff
+type Hunk struct {
+	OldStart, OldLines int // 1-based first line and line count in a
+	NewStart, NewLines int // 1-based first line and line count in b
+	Ops                []Op
+}
+
+// Header returns the hunk's "@@ -a,b +c,d @@" line
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+// hunkRange formats one side of a hunk header; an empty side names the line before it
+func hunkRange(start, lines int) string {
+	switch lines {
+	case 0:
+		return fmt.Sprintf("%d,0", start-1)
+	case 1:
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// Hunks groups an edit script into hunks with up to context unchanged lines
+// before and after each change. Changes closer than twice the context share a hunk.
+func Hunks(ops []Op, context int) []Hunk {
+	// Line numbers in a and b at the start of every op
+	oldLine := make([]int, len(ops)+1)
+	newLine := make([]int, len(ops)+1)
+	oldLine[0], newLine[0] = 1, 1
+	var changes []int
+	for i, op := range ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.Kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.Kind != '-' {
+			newLine[i+1]++
+		}
+		if op.Kind != ' ' {
+			changes = append(changes, i)
+		}
+	}
+
+	var hunks []Hunk
+	for c := 0; c < len(changes); {
+		start := max(changes[c]-context, 0)
+		last := changes[c]
+		for c++; c < len(changes) && changes[c]-last <= 2*context+1; c++ {
+			last = changes[c]
+		}
+		end := min(last+context+1, len(ops))
+		hunks = append(hunks, Hunk{
+			OldStart: oldLine[start],
+			OldLines: oldLine[end] - oldLine[start],
+			NewStart: newLine[start],
+			NewLines: newLine[end] - newLine[start],
+			Ops:      ops[start:end],
+		})
+	}
+	return hunks
+}
+
+// Split divides a hunk at the unchanged lines between its runs of changes,
+// so each part holds one run. The unchanged lines between two runs are
+// context for both parts. A hunk with a single run is returned as it is.
+func (h Hunk) Split() []Hunk {
+	// Line numbers at the start of every op, and the first and last op of
+	// every run of changes
+	oldLine := make([]int, len(h.Ops)+1)
+	newLine := make([]int, len(h.Ops)+1)
+	oldLine[0], newLine[0] = h.OldStart, h.NewStart
+	var runs [][2]int
+	for i, op := range h.Ops {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if op.Kind != '+' {
+			oldLine[i+1]++
+		}
+		if op.Kind != '-' {
+			newLine[i+1]++
+		}
+		if op.Kind == ' ' {
+			continue
+		}
+		if n := len(runs); n > 0 && runs[n-1][1] == i-1 {
+			runs[n-1][1] = i
+		} else {
+			runs = append(runs, [2]int{i, i})
+		}
+	}
+	if len(runs) < 2 {
+		return []Hunk{h}
+	}
+
+	parts := make([]Hunk, len(runs))
+	for i := range runs {
+		start, end := 0, len(h.Ops)
+		if i > 0 {
+			start = runs[i-1][1] + 1
+		}
+		if i < len(runs)-1 {
+			end = runs[i+1][0]
+		}
+		parts[i] = Hunk{
+			OldStart: oldLine[start],
+			OldLines: oldLine[end] - oldLine[start],
+			NewStart: newLine[start],
+			NewLines: newLine[end] - newLine[start],
+			Ops:      h.Ops[start:end],
+		}
+	}
+	return parts
+}
+
+// Apply makes the changes of hunks, which must come from one edit script of
+// a, to a and returns the result. Hunks may be left out, to apply only some
+// of the changes, and may share context, as the parts of a split hunk do.
+func Apply(a []string, hunks []Hunk) []string {
+	removed := make(map[int]bool)      // lines of a to drop, 1-based
+	inserted := make(map[int][]string) // lines to add before a line of a
+	for _, h := range hunks {
+		line := h.OldStart
+		for _, op := range h.Ops {
+			switch op.Kind {
+			case '-':
+				removed[line] = true
+				line++
+			case '+':
+				inserted[line] = append(inserted[line], op.Line)
+			default:
+				line++
+			}
+		}
+	}
+
+	var b []string
+	for line := 1; line <= len(a)+1; line++ {
+		b = append(b, inserted[line]...)
+		if line <= len(a) && !removed[line] {
+			b = append(b, a[line-1])
+		}
+	}
+	return b
+}
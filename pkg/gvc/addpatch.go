@@ -0,0 +1,139 @@
+package gvc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/diff"
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// FilePatch is the unstaged change to one tracked file, split into the hunks
+// add -p offers one at a time
+type FilePatch struct {
+	Path  string
+	Old   IndexEntry // the staged file
+	New   IndexEntry // the working tree copy
+	Hunks []diff.Hunk
+}
+
+// UnstagedPatches returns the unstaged changes to the tracked files at or
+// below paths, every tracked file when there are none, sorted by path.
+// Only changed text in regular files can be staged by hunk, so deleted,
+// binary and conflicted files, symlinks, submodules and large-file
+// pointers are left out, as are changes that only touch the mode.
+func (r *Repository) UnstagedPatches(paths ...string) ([]FilePatch, error) {
+	changes, err := r.DiffWorkingTree("")
+	if err != nil {
+		return nil, err
+	}
+	unmerged, err := r.UnmergedPaths()
+	if err != nil {
+		return nil, err
+	}
+	conflicted := make(map[string]bool, len(unmerged))
+	for _, path := range unmerged {
+		conflicted[path] = true
+	}
+
+	var patches []FilePatch
+	for _, change := range changes {
+		if change.Old == nil || change.New == nil || conflicted[change.Path] || !underPaths(change.Path, paths) {
+			continue
+		}
+		if modeType(change.Old.Mode) != "100644" || modeType(change.New.Mode) != "100644" {
+			continue
+		}
+		oldContent, err := r.patchContent(change.Old)
+		if err != nil {
+			return nil, err
+		}
+		newContent, err := r.patchContent(change.New)
+		if err != nil {
+			return nil, err
+		}
+		if isBinary(oldContent) || isBinary(newContent) {
+			continue
+		}
+		if _, ok := ParseLFSPointer(oldContent); ok {
+			continue
+		}
+		if _, ok := ParseLFSPointer(newContent); ok {
+			continue
+		}
+		hunks := diff.Hunks(diff.Lines(diff.SplitLines(oldContent), diff.SplitLines(newContent)), PatchContext)
+		if len(hunks) > 0 {
+			patches = append(patches, FilePatch{Path: change.Path, Old: *change.Old, New: *change.New, Hunks: hunks})
+		}
+	}
+	return patches, nil
+}
+
+// underPaths reports whether a slash-separated path is at or below one of
+// paths, "" and "." standing for the whole tree; no paths match everything
+func underPaths(path string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, spec := range paths {
+		spec = normalizePath(spec)
+		if spec == "" || spec == "." || path == spec || strings.HasPrefix(path, spec+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// StageHunks stages some of the hunks of an unstaged change: the staged file
+// gets a new blob holding its content with just those hunks applied, which
+// may be the parts of split hunks. The working tree file is left alone, so
+// the rest of the change stays unstaged.
+func (r *Repository) StageHunks(patch FilePatch, hunks []diff.Hunk) error {
+	if len(hunks) == 0 {
+		return nil
+	}
+	l, err := r.lockGitFile(r.indexPath())
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+
+	index, err := r.ReadIndex()
+	if err != nil {
+		return err
+	}
+	i := -1
+	for j, entry := range index.Entries {
+		if normalizePath(entry.Path) == patch.Path {
+			i = j
+		}
+	}
+	if i < 0 || index.Entries[i].SHA != patch.Old.SHA {
+		return fmt.Errorf("%s changed in the index since its hunks were computed", patch.Path)
+	}
+
+	_, content, err := r.ReadObject(patch.Old.SHA)
+	if err != nil {
+		return err
+	}
+	staged := strings.Join(diff.Apply(diff.SplitLines(content), hunks), "")
+	sha, err := r.WriteObject(object.BlobObject, []byte(staged))
+	if err != nil {
+		return err
+	}
+	// With no modification time the entry never matches the working tree
+	// file by its stat data, which it no longer describes
+	index.Entries[i].SHA = sha
+	index.Entries[i].Size = int64(len(staged))
+	index.Entries[i].ModTime = time.Time{}
+
+	data, err := r.encodeIndex(index)
+	if err != nil {
+		return err
+	}
+	if err := l.commit(data); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,779 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/diff"
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// PatchedFile is the change a unified diff makes to one file
+type PatchedFile struct {
+	OldPath string // "" for a file the patch creates
+	NewPath string // "" for a file the patch deletes
+	OldMode string // "" when the diff does not say
+	NewMode string
+	OldSHA  string // abbreviated blob names from the "index" line, if any
+	NewSHA  string
+	Hunks   []diff.Hunk
+	Binary  bool // a binary change, which cannot be applied from the diff
+}
+
+// Path names the file a change is reported under: its new path, or the old
+// one for a deletion
+func (f PatchedFile) Path() string {
+	if f.NewPath != "" {
+		return f.NewPath
+	}
+	return f.OldPath
+}
+
+// hunkHeader matches the "@@ -a,b +c,d @@" line that starts a hunk
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// ParsePatch reads the file changes in a unified diff, with Git's extended
+// headers for new, deleted and renamed files and mode changes. Anything
+// before the first diff, such as the headers and message of a patch email,
+// and after the last hunk is skipped. strip leading components are removed
+// from the paths on the "diff --git", "---" and "+++" lines, as patch -p
+// does; 1 drops Git's a/ and b/.
+func ParsePatch(data []byte, strip int) ([]PatchedFile, error) {
+	lines := diff.SplitLines(data)
+	var files []PatchedFile
+	var file *PatchedFile
+	inHeader := false // between "diff --git" and the file's first hunk
+	name := func(raw string) (string, error) {
+		raw = strings.TrimRight(raw, "\r\n")
+		// Some diff tools put a timestamp after the name, separated by a tab
+		if i := strings.IndexByte(raw, '\t'); i >= 0 {
+			raw = raw[:i]
+		}
+		if strings.HasPrefix(raw, `"`) {
+			unquoted, err := strconv.Unquote(raw)
+			if err != nil {
+				return "", fmt.Errorf("invalid quoted path %s", raw)
+			}
+			raw = unquoted
+		}
+		if raw == "/dev/null" {
+			return "", nil
+		}
+		parts := strings.Split(raw, "/")
+		if len(parts) <= strip {
+			return "", fmt.Errorf("cannot strip %d components from %s", strip, raw)
+		}
+		return strings.Join(parts[strip:], "/"), nil
+	}
+	start := func() {
+		files = append(files, PatchedFile{})
+		file = &files[len(files)-1]
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], "\n")
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			start()
+			inHeader = true
+			// Both names are the same unless the file is renamed, when the
+			// rename lines give them
+			names := line[len("diff --git "):]
+			if half := len(names) / 2; len(names)%2 == 1 && names[half] == ' ' {
+				oldName, err := name(names[:half])
+				if err != nil {
+					return nil, err
+				}
+				newName, err := name(names[half+1:])
+				if err != nil {
+					return nil, err
+				}
+				if oldName == newName {
+					file.OldPath, file.NewPath = oldName, newName
+				}
+			}
+
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if !inHeader {
+				start()
+			}
+			inHeader = false
+			var err error
+			if file.OldPath, err = name(line[4:]); err != nil {
+				return nil, err
+			}
+			if file.NewPath, err = name(lines[i+1][4:]); err != nil {
+				return nil, err
+			}
+			i++
+
+		case strings.HasPrefix(line, "@@ ") && file != nil:
+			inHeader = false
+			hunk, end, err := parseHunk(lines, i)
+			if err != nil {
+				return nil, err
+			}
+			file.Hunks = append(file.Hunks, hunk)
+			i = end - 1
+
+		case inHeader:
+			if err := parseExtendedHeader(file, line); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, file := range files {
+		if file.OldPath == "" && file.NewPath == "" {
+			return nil, errors.New("patch has a file without a name")
+		}
+	}
+	return files, nil
+}
+
+// parseExtendedHeader reads one of the lines Git writes between "diff --git"
+// and a file's hunks
+func parseExtendedHeader(file *PatchedFile, line string) error {
+	key, value, _ := strings.Cut(line, " ")
+	switch {
+	case strings.HasPrefix(line, "new file mode "):
+		file.OldPath, file.NewMode = "", strings.TrimPrefix(line, "new file mode ")
+	case strings.HasPrefix(line, "deleted file mode "):
+		file.NewPath, file.OldMode = "", strings.TrimPrefix(line, "deleted file mode ")
+	case strings.HasPrefix(line, "old mode "):
+		file.OldMode = strings.TrimPrefix(line, "old mode ")
+	case strings.HasPrefix(line, "new mode "):
+		file.NewMode = strings.TrimPrefix(line, "new mode ")
+	case strings.HasPrefix(line, "rename from "):
+		file.OldPath = strings.TrimPrefix(line, "rename from ")
+	case strings.HasPrefix(line, "rename to "):
+		file.NewPath = strings.TrimPrefix(line, "rename to ")
+	case strings.HasPrefix(line, "copy from "), strings.HasPrefix(line, "copy to "):
+		return errors.New("copy patches are not supported")
+	case key == "index":
+		// "index <old>..<new> <mode>" gives the mode of an unchanged-mode file
+		fields := strings.Fields(value)
+		if len(fields) > 0 {
+			file.OldSHA, file.NewSHA, _ = strings.Cut(fields[0], "..")
+		}
+		if len(fields) == 2 {
+			file.OldMode, file.NewMode = fields[1], fields[1]
+		}
+	case strings.HasPrefix(line, "Binary files "), line == "GIT binary patch":
+		file.Binary = true
+	}
+	return nil
+}
+
+// parseHunk reads the hunk whose header is lines[start], returning it and
+// the index of the line after it. A line missing its leading space, as some
+// mailers leave an empty context line, counts as context.
+func parseHunk(lines []string, start int) (diff.Hunk, int, error) {
+	m := hunkHeader.FindStringSubmatch(lines[start])
+	if m == nil {
+		return diff.Hunk{}, 0, fmt.Errorf("corrupt patch at line %d: invalid hunk header", start+1)
+	}
+	count := func(s string) int {
+		if s == "" {
+			return 1
+		}
+		n, _ := strconv.Atoi(s)
+		return n
+	}
+	var h diff.Hunk
+	h.OldStart, _ = strconv.Atoi(m[1])
+	h.OldLines = count(m[2])
+	h.NewStart, _ = strconv.Atoi(m[3])
+	h.NewLines = count(m[4])
+	// An empty side names the line before it
+	if h.OldLines == 0 {
+		h.OldStart++
+	}
+	if h.NewLines == 0 {
+		h.NewStart++
+	}
+
+	oldLeft, newLeft := h.OldLines, h.NewLines
+	i := start + 1
+	for ; i < len(lines) && (oldLeft > 0 || newLeft > 0 || strings.HasPrefix(lines[i], `\`)); i++ {
+		line := lines[i]
+		kind := line[0]
+		if line == "\n" {
+			kind, line = ' ', " \n"
+		}
+		switch kind {
+		case ' ':
+			oldLeft--
+			newLeft--
+		case '-':
+			oldLeft--
+		case '+':
+			newLeft--
+		case '\\':
+			// "\ No newline at end of file" belongs to the line before it
+			if n := len(h.Ops); n > 0 {
+				h.Ops[n-1].Line = strings.TrimSuffix(h.Ops[n-1].Line, "\n")
+			}
+			continue
+		default:
+			return diff.Hunk{}, 0, fmt.Errorf("corrupt patch at line %d", i+1)
+		}
+		if oldLeft < 0 || newLeft < 0 {
+			return diff.Hunk{}, 0, fmt.Errorf("corrupt patch at line %d: hunk is longer than its header says", i+1)
+		}
+		h.Ops = append(h.Ops, diff.Op{Kind: kind, Line: line[1:]})
+	}
+	if oldLeft > 0 || newLeft > 0 {
+		return diff.Hunk{}, 0, fmt.Errorf("corrupt patch at line %d: hunk is shorter than its header says", i+1)
+	}
+	return h, i, nil
+}
+
+// ApplyOptions controls Apply
+type ApplyOptions struct {
+	Cached bool // apply to the index alone, leaving the working tree as it is
+	Index  bool // apply to the index as well as the working tree
+	Check  bool // only check that the patch applies
+	// Strip is how many leading path components to remove, as patch -p
+	// does; the command line passes 1 unless told otherwise
+	Strip int
+	// Fuzz is how many context lines at either end of a hunk may be
+	// ignored when it does not apply with all of them
+	Fuzz int
+	// ThreeWay merges a file whose hunks do not apply, when the blob the
+	// patch was made against is in the object store, instead of rejecting
+	// the patch. It implies Index unless Cached is set.
+	ThreeWay bool
+}
+
+// AppliedFile is a file Apply changed, or would change
+type AppliedFile struct {
+	PatchedFile
+	Notes      []string // hunks that applied away from their line or with fuzz
+	Conflicted bool     // a three-way merge left conflict markers in it
+}
+
+// threeWayMerge is a file Apply merged, kept to record its sides as a
+// resolve-undo record when it conflicts
+type threeWayMerge struct {
+	base, ours, theirs patchTarget
+	conflict           bool
+}
+
+// ApplyError lists why a patch does not apply
+type ApplyError struct {
+	Problems []string
+}
+
+func (e *ApplyError) Error() string {
+	return strings.Join(e.Problems, "\n")
+}
+
+// patchTarget is the content and mode of a path as the patch sees it
+type patchTarget struct {
+	content []byte
+	mode    string
+	exists  bool
+}
+
+// Apply makes the changes of a unified diff to the working tree, the index
+// (Cached) or both (Index). Every file is patched in memory first, so a
+// patch that does not apply cleanly changes nothing and an *ApplyError says
+// why. A hunk whose lines have moved is applied where its lines are now
+// found, nearest its stated line first; with Fuzz it may also be applied
+// with up to that many context lines at either end not matching. With
+// ThreeWay, a file whose hunks still do not apply is merged instead: the
+// patch is applied to the blob its "index" line names and the result merged
+// with the file as it is, as a cherry-pick would. Conflicts are left marked
+// in the working tree and the index, with resolve-undo records, and the
+// returned *ConflictError lists them.
+func (r *Repository) Apply(data []byte, opts ApplyOptions) ([]AppliedFile, error) {
+	if opts.ThreeWay && !opts.Cached {
+		opts.Index = true
+	}
+	files, err := ParsePatch(data, opts.Strip)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, errors.New("no valid patches in input")
+	}
+	useIndex := opts.Cached || opts.Index
+	if !opts.Check {
+		if err := r.checkWritable(); err != nil {
+			return nil, err
+		}
+	}
+	var l *lockFile
+	if useIndex && !opts.Check {
+		if l, err = r.lockGitFile(r.indexPath()); err != nil {
+			return nil, err
+		}
+		defer l.unlock()
+	}
+	index, err := r.ReadIndex()
+	if err != nil {
+		return nil, err
+	}
+	staged := make(map[string]int, len(index.Entries))
+	for i, entry := range index.Entries {
+		staged[entry.Path] = i
+	}
+
+	// Results so far, so later files in the patch see earlier changes
+	results := make(map[string]patchTarget)
+	var order []string
+	read := func(path string) (patchTarget, error) {
+		if target, ok := results[path]; ok {
+			return target, nil
+		}
+		var target patchTarget
+		i, inIndex := staged[path]
+		if useIndex && inIndex {
+			entry := index.Entries[i]
+			_, content, err := r.ReadObject(entry.SHA)
+			if err != nil {
+				return target, err
+			}
+			target = patchTarget{content: content, mode: entry.Mode, exists: true}
+		}
+		if opts.Cached {
+			return target, nil
+		}
+		info, err := os.Lstat(r.worktreePath(path))
+		if os.IsNotExist(err) {
+			if target.exists {
+				return target, errors.New("does not match index")
+			}
+			return patchTarget{}, nil
+		}
+		if err != nil {
+			return target, err
+		}
+		previousMode := ""
+		if inIndex {
+			previousMode = index.Entries[i].Mode
+		}
+		mode, err := r.workingMode(info, previousMode)
+		if err != nil {
+			return target, err
+		}
+		work := patchTarget{mode: mode, exists: true}
+		if info.Mode()&os.ModeSymlink != 0 {
+			link, err := os.Readlink(r.worktreePath(path))
+			if err != nil {
+				return target, err
+			}
+			work.content = []byte(filepath.ToSlash(link))
+		} else if info.Mode().IsRegular() {
+			if work.content, err = os.ReadFile(r.worktreePath(path)); err != nil {
+				return target, err
+			}
+		} else {
+			return target, errors.New("not a regular file")
+		}
+		if opts.Index && (!target.exists || r.Format.Hash(object.BlobObject, work.content) != r.Format.Hash(object.BlobObject, target.content)) {
+			return target, errors.New("does not match index")
+		}
+		return work, nil
+	}
+	set := func(path string, target patchTarget) {
+		if _, ok := results[path]; !ok {
+			order = append(order, path)
+		}
+		results[path] = target
+	}
+
+	var applied []AppliedFile
+	var problems []string
+	merges := make(map[string]threeWayMerge)
+	var conflicts []string
+	for _, file := range files {
+		path := file.Path()
+		fail := func(format string, args ...any) {
+			problems = append(problems, fmt.Sprintf("%s: ", path)+fmt.Sprintf(format, args...))
+		}
+		// A patch may come from anyone; it must not reach outside the
+		// working tree, into .gvc or through a symlinked directory
+		invalid := false
+		for _, p := range []string{file.OldPath, file.NewPath} {
+			if p == "" {
+				continue
+			}
+			if err := checkWorkingPath(p); err != nil {
+				fail("%v", err)
+				invalid = true
+			} else if !opts.Cached && r.beyondSymlink(p) {
+				fail("%v", errBeyondSymlink(p))
+				invalid = true
+			}
+		}
+		if invalid {
+			continue
+		}
+		if file.Binary {
+			fail("cannot apply binary patch")
+			continue
+		}
+
+		var source patchTarget
+		if file.OldPath != "" {
+			if source, err = read(file.OldPath); err != nil {
+				fail("%v", err)
+				continue
+			}
+			if !source.exists {
+				if useIndex {
+					fail("does not exist in index")
+				} else {
+					fail("No such file or directory")
+				}
+				continue
+			}
+			if source.mode == GitlinkMode {
+				fail("cannot patch a submodule")
+				continue
+			}
+		}
+		if file.NewPath != "" && file.NewPath != file.OldPath {
+			existing, err := read(file.NewPath)
+			if err != nil {
+				fail("%v", err)
+				continue
+			}
+			if existing.exists {
+				if opts.Cached {
+					fail("already exists in index")
+				} else {
+					fail("already exists in working directory")
+				}
+				continue
+			}
+		}
+
+		lines, notes, err := applyHunks(diff.SplitLines(source.content), file.Hunks, opts.Fuzz)
+		var merge *threeWayMerge
+		if err != nil && opts.ThreeWay && source.exists && file.NewPath != "" {
+			var mergeErr error
+			lines, merge, mergeErr = r.mergePatchedFile(file, source)
+			if mergeErr != nil {
+				fail("%v", mergeErr)
+				continue
+			}
+			if merge != nil {
+				err = nil
+				notes = []string{fmt.Sprintf("Applied with a three-way merge from %s.", file.OldSHA)}
+				if merge.conflict {
+					notes[0] = fmt.Sprintf("Applied with conflicts by a three-way merge from %s.", file.OldSHA)
+				}
+			}
+		}
+		if err != nil {
+			fail("%v", err)
+			problems = append(problems, fmt.Sprintf("%s: patch does not apply", path))
+			continue
+		}
+		content := []byte(strings.Join(lines, ""))
+		if file.NewPath == "" {
+			if len(content) > 0 {
+				fail("removal patch leaves file contents")
+				continue
+			}
+			set(file.OldPath, patchTarget{})
+		} else {
+			mode := file.NewMode
+			if mode == "" || file.OldMode == file.NewMode && source.exists {
+				mode = source.mode
+			}
+			if mode == "" {
+				mode = "100644"
+			}
+			if file.OldPath != "" && file.OldPath != file.NewPath {
+				set(file.OldPath, patchTarget{})
+			}
+			set(file.NewPath, patchTarget{content: content, mode: mode, exists: true})
+		}
+		conflicted := false
+		if merge != nil && merge.conflict {
+			merge.theirs.mode = results[file.NewPath].mode
+			merges[file.NewPath] = *merge
+			conflicts = append(conflicts, file.NewPath)
+			conflicted = true
+		}
+		applied = append(applied, AppliedFile{PatchedFile: file, Notes: notes, Conflicted: conflicted})
+	}
+	if len(problems) > 0 {
+		return applied, &ApplyError{Problems: problems}
+	}
+	var conflictErr error
+	if len(conflicts) > 0 {
+		sort.Strings(conflicts)
+		conflictErr = conflictError(fmt.Errorf("patch applied with conflicts in:\n\t%s\nfix them and 'gvc add' the result",
+			strings.Join(conflicts, "\n\t")))
+	}
+	if opts.Check {
+		return applied, conflictErr
+	}
+
+	for _, path := range order {
+		target := results[path]
+		if !opts.Cached {
+			if err := r.writePatchedFile(path, target); err != nil {
+				return nil, err
+			}
+		}
+		if !useIndex {
+			continue
+		}
+		i, inIndex := staged[path]
+		if !target.exists {
+			if inIndex {
+				index.Entries[i].Path = ""
+			}
+			continue
+		}
+		sha, err := r.WriteObject(object.BlobObject, target.content)
+		if err != nil {
+			return nil, err
+		}
+		// With no modification time the entry is compared with the working
+		// tree file by content, as a patched index entry may not match it
+		entry := IndexEntry{Path: path, SHA: sha, Mode: target.mode, Size: int64(len(target.content)), ModTime: time.Time{}}
+		if inIndex {
+			index.Entries[i] = entry
+		} else {
+			index.Entries = append(index.Entries, entry)
+		}
+	}
+	if !useIndex {
+		return applied, conflictErr
+	}
+	entries := index.Entries[:0]
+	for _, entry := range index.Entries {
+		if entry.Path != "" {
+			entries = append(entries, entry)
+		}
+	}
+	index.Entries = entries
+	encoded, err := r.encodeIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.commit(encoded); err != nil {
+		return nil, fmt.Errorf("failed to write index: %w", err)
+	}
+	if len(conflicts) > 0 {
+		if err := r.recordApplyConflicts(conflicts, merges); err != nil {
+			return nil, err
+		}
+	}
+	return applied, conflictErr
+}
+
+// mergePatchedFile applies a file's hunks to the blob its "index" line names
+// and merges the result with source, the file as it is now. The merge is nil
+// when the patch names no blob or the object store does not have it.
+func (r *Repository) mergePatchedFile(file PatchedFile, source patchTarget) ([]string, *threeWayMerge, error) {
+	if file.OldSHA == "" || strings.Trim(file.OldSHA, "0") == "" {
+		return nil, nil, nil
+	}
+	sha, err := r.resolveObjectPrefix(file.OldSHA)
+	if err != nil || sha == "" {
+		return nil, nil, err
+	}
+	objectType, base, err := r.ReadObject(sha)
+	if err != nil {
+		return nil, nil, err
+	}
+	if objectType != object.BlobObject {
+		return nil, nil, nil
+	}
+	baseLines := diff.SplitLines(base)
+	theirs, _, err := applyHunks(baseLines, file.Hunks, 0)
+	if err != nil {
+		return nil, nil, fmt.Errorf("patch does not apply to its preimage %s: %w", file.OldSHA, err)
+	}
+	merged, conflict := diff.Merge(baseLines, diff.SplitLines(source.content), theirs, "ours", "theirs")
+	merge := &threeWayMerge{
+		base:     patchTarget{content: base, mode: source.mode, exists: true},
+		ours:     source,
+		theirs:   patchTarget{content: []byte(strings.Join(theirs, "")), exists: true},
+		conflict: conflict,
+	}
+	if file.OldMode != "" {
+		merge.base.mode = file.OldMode
+	}
+	return merged, merge, nil
+}
+
+// recordApplyConflicts keeps the sides of the files a three-way apply left
+// in conflict as resolve-undo records, and adds them to the conflicts of the
+// operation in progress, if any
+func (r *Repository) recordApplyConflicts(paths []string, merges map[string]threeWayMerge) error {
+	sides := []map[string]IndexEntry{{}, {}, {}}
+	for _, path := range paths {
+		merge := merges[path]
+		for i, side := range []patchTarget{merge.base, merge.ours, merge.theirs} {
+			sha, err := r.WriteObject(object.BlobObject, side.content)
+			if err != nil {
+				return err
+			}
+			sides[i][path] = IndexEntry{Path: path, SHA: sha, Mode: side.mode}
+		}
+	}
+	if err := r.recordResolveUndo(paths, sides[0], sides[1], sides[2]); err != nil {
+		return err
+	}
+	if r.OperationInProgress() != "" {
+		return r.addConflictPaths(paths)
+	}
+	return nil
+}
+
+// writePatchedFile puts a patched file in the working tree, or removes it
+func (r *Repository) writePatchedFile(path string, target patchTarget) error {
+	if !target.exists {
+		return r.removeWorkingFile(path)
+	}
+	full := r.worktreePath(path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if info, err := os.Lstat(full); err == nil && (info.Mode()&os.ModeSymlink != 0 || target.mode == SymlinkMode) {
+		if err := os.Remove(full); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	if target.mode == SymlinkMode {
+		if err := os.Symlink(filepath.FromSlash(string(target.content)), full); err != nil {
+			return fmt.Errorf("failed to create symlink %s: %w", path, err)
+		}
+		return nil
+	}
+	perm := os.FileMode(0644)
+	if target.mode == "100755" {
+		perm = 0755
+	}
+	if err := os.WriteFile(full, target.content, perm); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	return os.Chmod(full, perm)
+}
+
+// applyHunks applies hunks, in order, to lines. Each is looked for at its
+// stated line, moved by how far the hunks before it were, then ever further
+// above and below; failing that, with one more context line dropped from
+// each end, up to fuzz lines. Notes describe hunks that did not apply
+// exactly where stated.
+func applyHunks(lines []string, hunks []diff.Hunk, fuzz int) ([]string, []string, error) {
+	var result, notes []string
+	cursor, offset := 0, 0
+	for n, h := range hunks {
+		pos, used, lead, trail := -1, 0, 0, 0
+		for f := 0; f <= fuzz && pos < 0; f++ {
+			lead = min(f, leadingContext(h.Ops))
+			trail = min(f, trailingContext(h.Ops[lead:]))
+			if f > 0 && lead < f && trail < f {
+				break // no more context to drop
+			}
+			expected := h.OldStart - 1 + lead + offset
+			pos, used = findPreimage(lines, h.Ops[lead:len(h.Ops)-trail], expected, cursor), f
+		}
+		if pos < 0 {
+			return nil, nil, fmt.Errorf("patch failed at hunk #%d (line %d)", n+1, h.OldStart)
+		}
+		ops := h.Ops[lead : len(h.Ops)-trail]
+		stated := h.OldStart - 1 + lead
+		if moved := pos - stated; moved != 0 || used > 0 {
+			note := fmt.Sprintf("Hunk #%d succeeded at %d", n+1, pos+1-lead)
+			if moved != 0 {
+				note += fmt.Sprintf(" (offset %d line%s)", moved, plural(moved))
+			}
+			if used > 0 {
+				note += fmt.Sprintf(" with fuzz %d", used)
+			}
+			notes = append(notes, note+".")
+		}
+		offset = pos - stated
+
+		result = append(result, lines[cursor:pos]...)
+		for _, op := range ops {
+			if op.Kind == ' ' {
+				result = append(result, lines[pos])
+			}
+			if op.Kind != '+' {
+				pos++
+			} else {
+				result = append(result, op.Line)
+			}
+		}
+		cursor = pos
+	}
+	return append(result, lines[cursor:]...), notes, nil
+}
+
+// findPreimage returns where the lines ops keep or remove appear in lines,
+// at or after cursor, searching outward from expected; -1 if nowhere
+func findPreimage(lines []string, ops []diff.Op, expected, cursor int) int {
+	var pre []string
+	for _, op := range ops {
+		if op.Kind != '+' {
+			pre = append(pre, op.Line)
+		}
+	}
+	last := len(lines) - len(pre)
+	matches := func(at int) bool {
+		if at < cursor || at > last {
+			return false
+		}
+		for i, line := range pre {
+			if lines[at+i] != line {
+				return false
+			}
+		}
+		return true
+	}
+	expected = max(min(expected, last), cursor)
+	for d := 0; expected-d >= cursor || expected+d <= last; d++ {
+		if matches(expected - d) {
+			return expected - d
+		}
+		if matches(expected + d) {
+			return expected + d
+		}
+	}
+	return -1
+}
+
+// leadingContext counts the unchanged lines at the start of ops
+func leadingContext(ops []diff.Op) int {
+	n := 0
+	for n < len(ops) && ops[n].Kind == ' ' {
+		n++
+	}
+	return n
+}
+
+// trailingContext counts the unchanged lines at the end of ops
+func trailingContext(ops []diff.Op) int {
+	n := 0
+	for n < len(ops) && ops[len(ops)-1-n].Kind == ' ' {
+		n++
+	}
+	return n
+}
+
+// plural returns "s" unless n is 1 or -1
+func plural(n int) string {
+	if n == 1 || n == -1 {
+		return ""
+	}
+	return "s"
+}
@@ -0,0 +1,360 @@
+package gvc
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// ArchiveFormats are the formats Archive writes
+var ArchiveFormats = []string{"tar", "tar.gz", "tgz", "zip"}
+
+// ArchiveOptions controls Archive
+type ArchiveOptions struct {
+	Format string // one of ArchiveFormats; tar when empty
+	Prefix string // prepended to every path, e.g. "project-1.0/"
+}
+
+// ArchiveFormatFor guesses the archive format from an output file name,
+// returning "" when the extension is not one Archive knows
+func ArchiveFormatFor(name string) string {
+	for _, format := range []string{"tar.gz", "tgz", "tar", "zip"} {
+		if strings.HasSuffix(name, "."+format) {
+			return format
+		}
+	}
+	return ""
+}
+
+// archiveEntry is one file or directory of an archive
+type archiveEntry struct {
+	path  string
+	mode  string
+	sha   string // blob of a file or symlink
+	subst bool   // expand $Format:...$ placeholders; the export-subst attribute
+}
+
+// Archive writes the tree of a commit, or a tree itself, to w as a tar or
+// zip archive. Files keep their executable bit and symlinks stay symlinks;
+// submodules become empty directories. The output only depends on the tree,
+// so release tarballs can be checksummed: entries come in tree order, dated
+// at the commit's committer date in UTC (a tree has no date, so
+// SOURCE_DATE_EPOCH, or else the current time, is used), with no owner, and
+// gzip records no name or time. The commit ID is stored the way Git stores
+// it: in a pax header for tar, and as the archive comment for zip.
+//
+// The archived tree's .gvcattributes files and .gvc/info/attributes are
+// honored: paths with export-ignore are left out, and in files with
+// export-subst each $Format:<format>$ is replaced by the commit formatted as
+// log --format=<format> would.
+func (r *Repository) Archive(w io.Writer, rev string, opts ArchiveOptions) error {
+	format := opts.Format
+	if format == "" {
+		format = "tar"
+	}
+	if !slices.Contains(ArchiveFormats, format) {
+		return fmt.Errorf("unknown archive format %q (use %s)", format, strings.Join(ArchiveFormats, ", "))
+	}
+	if err := archivePrefix(opts.Prefix); err != nil {
+		return err
+	}
+
+	sha, err := r.ResolveObject(rev)
+	if err != nil {
+		return err
+	}
+	objectType, _, err := r.ReadObject(sha)
+	if err != nil {
+		return err
+	}
+	treeSHA, modTime := sha, time.Now()
+	var commit *object.Commit
+	switch objectType {
+	case object.CommitObject:
+		if commit, err = r.ReadCommit(sha); err != nil {
+			return err
+		}
+		treeSHA, modTime = commit.TreeSHA, commit.Committed
+	case object.TreeObject:
+		if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+			seconds, err := strconv.ParseInt(epoch, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", epoch)
+			}
+			modTime = time.Unix(seconds, 0)
+		}
+	default:
+		return fmt.Errorf("%s is a %s, not a commit or tree", rev, objectType)
+	}
+	// Dates are kept in UTC so a zip's local-time fields are the same everywhere
+	modTime = modTime.UTC().Truncate(time.Second)
+
+	var entries []archiveEntry
+	if opts.Prefix != "" && strings.HasSuffix(opts.Prefix, "/") {
+		entries = append(entries, archiveEntry{path: opts.Prefix, mode: "40000"})
+	}
+	info, err := r.infoAttributes()
+	if err != nil {
+		return err
+	}
+	walk := archiveWalk{prefix: opts.Prefix, info: info, entries: &entries}
+	if err := r.archiveTree(treeSHA, walk); err != nil {
+		return err
+	}
+
+	switch format {
+	case "tar":
+		return r.writeTarArchive(w, entries, commit, modTime)
+	case "tar.gz", "tgz":
+		gz := gzip.NewWriter(w)
+		if err := r.writeTarArchive(gz, entries, commit, modTime); err != nil {
+			return err
+		}
+		return gz.Close()
+	}
+	return r.writeZipArchive(w, entries, commit, modTime)
+}
+
+// archiveWalk is what archiveTree carries down the tree
+type archiveWalk struct {
+	prefix  string          // prepended to every path in the archive
+	info    []attributeRule // from .gvc/info/attributes, which override the tree's
+	entries *[]archiveEntry
+}
+
+// archiveTree lists the entries of the tree depth first, each directory
+// before its contents, leaving out those with export-ignore. Each
+// directory's attributes are those of the directories above with its own
+// attributes file added.
+func (r *Repository) archiveTree(treeSHA string, walk archiveWalk) error {
+	rootRules, err := r.treeAttributes(treeSHA, "", nil)
+	if err != nil {
+		return err
+	}
+	rules := map[string][]attributeRule{"": rootRules}
+	walker := r.WalkTree(treeSHA, TreeWalkOptions{Trees: true})
+	for entry, ok := walker.Next(); ok; entry, ok = walker.Next() {
+		dir := path.Dir(entry.Path)
+		if dir == "." {
+			dir = ""
+		}
+		isDir := entry.Type == object.TreeObject || entry.Mode == GitlinkMode
+		attrs := attributesFor(slices.Concat(rules[dir], walk.info), entry.Path, isDir)
+		if attrs["export-ignore"] == "true" {
+			walker.SkipDir()
+			continue
+		}
+		archived := walk.prefix + entry.Path
+		switch {
+		case entry.Type == object.TreeObject:
+			*walk.entries = append(*walk.entries, archiveEntry{path: archived + "/", mode: entry.Mode})
+			if rules[entry.Path], err = r.treeAttributes(entry.SHA, entry.Path, rules[dir]); err != nil {
+				return err
+			}
+		case entry.Mode == GitlinkMode:
+			*walk.entries = append(*walk.entries, archiveEntry{path: archived + "/", mode: "40000"})
+		default:
+			*walk.entries = append(*walk.entries, archiveEntry{path: archived, mode: entry.Mode, sha: entry.SHA, subst: attrs["export-subst"] == "true"})
+		}
+	}
+	return walker.Err()
+}
+
+// treeAttributes returns rules with those of the attributes file of the
+// tree at dir added
+func (r *Repository) treeAttributes(treeSHA, dir string, rules []attributeRule) ([]attributeRule, error) {
+	entry, found, err := r.treeEntryAt(treeSHA, r.attributesFileName())
+	if err != nil || !found || entry.Type != object.BlobObject {
+		return rules, err
+	}
+	_, data, err := r.ReadObject(entry.SHA)
+	if err != nil {
+		return nil, err
+	}
+	return append(slices.Clip(rules), parseAttributes(data, dir)...), nil
+}
+
+// exportSubst replaces each $Format:<format>$ in content with the commit
+// formatted as log --format=<format> would
+func exportSubst(content []byte, commit *object.Commit) []byte {
+	var out bytes.Buffer
+	for {
+		start := bytes.Index(content, []byte("$Format:"))
+		if start < 0 {
+			break
+		}
+		end := bytes.IndexByte(content[start+len("$Format:"):], '$')
+		if end < 0 {
+			break
+		}
+		end += start + len("$Format:")
+		format := string(content[start+len("$Format:") : end])
+		if strings.Contains(format, "\n") {
+			// A placeholder cannot span lines
+			out.Write(content[:start+1])
+			content = content[start+1:]
+			continue
+		}
+		out.Write(content[:start])
+		out.WriteString(commit.Format(format, ""))
+		content = content[end+1:]
+	}
+	out.Write(content)
+	return out.Bytes()
+}
+
+// archiveContent reads the blob of an archive entry, or nothing for a
+// directory. A large-file pointer is replaced by the content it names, when
+// the store has it, and export-subst placeholders are expanded for commit.
+func (r *Repository) archiveContent(entry archiveEntry, commit *object.Commit) ([]byte, error) {
+	if err := r.canceled(); err != nil {
+		return nil, err
+	}
+	if entry.sha == "" {
+		return nil, nil
+	}
+	objectType, content, err := r.ReadObject(entry.sha)
+	if err != nil {
+		return nil, err
+	}
+	if objectType != object.BlobObject {
+		return nil, fmt.Errorf("expected blob object for %s, got %s", entry.path, objectType)
+	}
+	if pointer, ok := ParseLFSPointer(content); ok && entry.mode != SymlinkMode {
+		f, err := r.openLargeFile(pointer)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				fmt.Fprintf(r.Out, "warning: %s: %v; archiving its pointer\n", entry.path, err)
+				return content, nil
+			}
+			return nil, err
+		}
+		defer f.Close()
+		if content, err = io.ReadAll(f); err != nil {
+			return nil, fmt.Errorf("failed to read large file %s: %w", entry.path, err)
+		}
+	}
+	if entry.subst && commit != nil && entry.mode != SymlinkMode {
+		content = exportSubst(content, commit)
+	}
+	return content, nil
+}
+
+// archivePerm returns the permission bits an archive records for a tree mode
+func archivePerm(mode string) fs.FileMode {
+	switch mode {
+	case "40000", "100755":
+		return 0755
+	case SymlinkMode:
+		return 0777
+	}
+	return 0644
+}
+
+// writeTarArchive writes entries as a tar archive in the pax format
+func (r *Repository) writeTarArchive(w io.Writer, entries []archiveEntry, commit *object.Commit, modTime time.Time) error {
+	tw := tar.NewWriter(w)
+	if commit != nil {
+		header := &tar.Header{Typeflag: tar.TypeXGlobalHeader, Name: "pax_global_header", PAXRecords: map[string]string{"comment": commit.SHA}}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	for _, entry := range entries {
+		content, err := r.archiveContent(entry, commit)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{
+			Name:    entry.path,
+			Mode:    int64(archivePerm(entry.mode)),
+			ModTime: modTime,
+			Format:  tar.FormatPAX,
+		}
+		switch {
+		case strings.HasSuffix(entry.path, "/"):
+			header.Typeflag = tar.TypeDir
+		case entry.mode == SymlinkMode:
+			header.Typeflag, header.Linkname = tar.TypeSymlink, string(content)
+		default:
+			header.Typeflag, header.Size = tar.TypeReg, int64(len(content))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write(content); err != nil {
+				return fmt.Errorf("failed to write archive: %w", err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// writeZipArchive writes entries as a zip archive, storing symlinks as
+// entries whose content is the target, as Info-ZIP does
+func (r *Repository) writeZipArchive(w io.Writer, entries []archiveEntry, commit *object.Commit, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	if commit != nil {
+		if err := zw.SetComment(commit.SHA); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		content, err := r.archiveContent(entry, commit)
+		if err != nil {
+			return err
+		}
+		header := &zip.FileHeader{Name: entry.path, Modified: modTime, Method: zip.Deflate}
+		mode := archivePerm(entry.mode)
+		switch {
+		case strings.HasSuffix(entry.path, "/"):
+			header.Method = zip.Store
+			mode |= fs.ModeDir
+		case entry.mode == SymlinkMode:
+			header.Method = zip.Store
+			mode |= fs.ModeSymlink
+		}
+		header.SetMode(mode)
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+		if _, err := fw.Write(content); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write archive: %w", err)
+	}
+	return nil
+}
+
+// archivePrefix checks a --prefix value, which must not escape the archive
+func archivePrefix(prefix string) error {
+	for _, part := range strings.Split(prefix, "/") {
+		if part == ".." {
+			return fmt.Errorf("invalid archive prefix %q", prefix)
+		}
+	}
+	if path.IsAbs(prefix) {
+		return fmt.Errorf("invalid archive prefix %q", prefix)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package gvc
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+func TestArchiveRejectsInvalidEntryNames(t *testing.T) {
+	repo, err := Init(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blob, err := repo.WriteObject(object.BlobObject, []byte("payload\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", ".", "..", "../escape", "dir/file"} {
+		// Nested so the bad name is met below the root too
+		inner, err := repo.writeTreeEntries([]object.TreeEntry{{Mode: "100644", Name: name, SHA: blob, Type: object.BlobObject}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		outer, err := repo.writeTreeEntries([]object.TreeEntry{{Mode: "40000", Name: "sub", SHA: inner, Type: object.TreeObject}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, format := range ArchiveFormats {
+			err := repo.Archive(io.Discard, outer, ArchiveOptions{Format: format})
+			if err == nil || !strings.Contains(err.Error(), "invalid entry name") {
+				t.Errorf("archiving a tree with entry %q as %s: got %v, want an invalid entry name error", name, format, err)
+			}
+		}
+	}
+}
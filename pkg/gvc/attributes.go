@@ -0,0 +1,109 @@
+package gvc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AttributesFile is the name of the per-directory files giving paths
+// attributes, in Git's .gitattributes format
+const AttributesFile = ".gvcattributes"
+
+// InfoAttributesFile holds a repository's private attributes, which are not
+// committed and override those that are
+const InfoAttributesFile = "info/attributes"
+
+// attributeRule is one line of an attributes file: a pattern and the
+// attributes it sets on the paths it matches
+type attributeRule struct {
+	pattern  IgnoreRule
+	settings []attributeSetting
+}
+
+// attributeSetting is one attribute of a rule: "true" for a set attribute
+// (name), "false" for an unset one (-name), "" to make it unspecified
+// again (!name) and anything else for name=value
+type attributeSetting struct {
+	name, value string
+}
+
+// parseAttributes reads the rules of an attributes file whose patterns
+// apply under base. Patterns follow ignore files, except that they cannot
+// be negated; macros and quoted patterns are not supported and skipped.
+func parseAttributes(data []byte, base string) []attributeRule {
+	var rules []attributeRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], `"`) || strings.HasPrefix(fields[0], "[attr]") {
+			continue
+		}
+		pattern, ok := parseIgnoreRule(fields[0])
+		if !ok || pattern.negate {
+			continue
+		}
+		pattern.base = base
+		rule := attributeRule{pattern: pattern}
+		for _, field := range fields[1:] {
+			switch {
+			case strings.HasPrefix(field, "-"):
+				rule.settings = append(rule.settings, attributeSetting{field[1:], "false"})
+			case strings.HasPrefix(field, "!"):
+				rule.settings = append(rule.settings, attributeSetting{field[1:], ""})
+			default:
+				name, value, ok := strings.Cut(field, "=")
+				if !ok {
+					value = "true"
+				}
+				rule.settings = append(rule.settings, attributeSetting{name, value})
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// attributesFor returns the attributes rules give a slash-separated path,
+// the later of two rules setting one winning; unspecified attributes are
+// left out
+func attributesFor(rules []attributeRule, p string, isDir bool) map[string]string {
+	attrs := make(map[string]string)
+	for _, rule := range rules {
+		if !rule.pattern.matches(p, isDir) {
+			continue
+		}
+		for _, setting := range rule.settings {
+			if setting.value == "" {
+				delete(attrs, setting.name)
+			} else {
+				attrs[setting.name] = setting.value
+			}
+		}
+	}
+	return attrs
+}
+
+// attributesFileName returns the name of the per-directory attributes
+// files: .gvcattributes, or .gitattributes in a Git repository
+func (r *Repository) attributesFileName() string {
+	if r.Git {
+		return GitAttributesFile
+	}
+	return AttributesFile
+}
+
+// infoAttributes reads the rules of .gvc/info/attributes
+func (r *Repository) infoAttributes() ([]attributeRule, error) {
+	data, err := r.readGitFile(r.gitPath(filepath.FromSlash(InfoAttributesFile)))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", InfoAttributesFile, err)
+	}
+	return parseAttributes(data, ""), nil
+}
@@ -0,0 +1,219 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Rebase todo actions. A fixup folds a commit's change into the one before it
+// and keeps that commit's message; a squash also appends its own message. A
+// drop, only written in an interactive rebase's todo, leaves the commit out.
+const (
+	RebasePick   = "pick"
+	RebaseFixup  = "fixup"
+	RebaseSquash = "squash"
+	RebaseDrop   = "drop"
+)
+
+// FixupMessage builds the message of a commit made with commit --fixup or
+// --squash (kind is RebaseFixup or RebaseSquash): the target's subject with a
+// "fixup! " or "squash! " prefix that rebase --autosquash recognizes, then the
+// optional message as the body
+func (r *Repository) FixupMessage(kind, targetRev, message string) (string, error) {
+	targetSHA, err := r.ResolveCommit(targetRev)
+	if err != nil {
+		return "", err
+	}
+	target, err := r.ReadCommit(targetSHA)
+	if err != nil {
+		return "", err
+	}
+	subject := strings.SplitN(target.Message, "\n", 2)[0]
+	result := kind + "! " + subject
+	if message = strings.TrimSpace(message); message != "" {
+		result += "\n\n" + message
+	}
+	return result, nil
+}
+
+// autosquashTodo turns a list of commits to replay into todo lines, moving
+// each "fixup! " and "squash! " commit right after the commit it names and
+// marking it to be folded in. The target is named by its subject or a SHA
+// prefix; commits whose target is not being replayed stay plain picks.
+func (r *Repository) autosquashTodo(commits []string) ([]string, error) {
+	type step struct {
+		action, sha string
+		folded      []*step
+	}
+	steps := make([]*step, 0, len(commits))
+	bySubject := make(map[string]*step)
+	var order []*step
+	for _, sha := range commits {
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return nil, err
+		}
+		subject := strings.SplitN(commit.Message, "\n", 2)[0]
+		s := &step{action: RebasePick, sha: sha}
+		steps = append(steps, s)
+
+		// "fixup! fixup! x" folds into x like "fixup! x"
+		action, rest := "", subject
+		for {
+			if trimmed, ok := strings.CutPrefix(rest, RebaseFixup+"! "); ok {
+				action, rest = firstNonEmpty(action, RebaseFixup), trimmed
+			} else if trimmed, ok := strings.CutPrefix(rest, RebaseSquash+"! "); ok {
+				action, rest = firstNonEmpty(action, RebaseSquash), trimmed
+			} else {
+				break
+			}
+		}
+
+		var target *step
+		if action != "" {
+			if target = bySubject[rest]; target == nil && len(rest) >= 4 {
+				for _, candidate := range steps[:len(steps)-1] {
+					if candidate.action == RebasePick && strings.HasPrefix(candidate.sha, rest) {
+						target = candidate
+						break
+					}
+				}
+			}
+		}
+		if target == nil {
+			if _, seen := bySubject[subject]; !seen {
+				bySubject[subject] = s
+			}
+			order = append(order, s)
+			continue
+		}
+		s.action = action
+		target.folded = append(target.folded, s)
+	}
+
+	var todo []string
+	for _, s := range order {
+		todo = append(todo, s.action+" "+s.sha)
+		for _, folded := range s.folded {
+			todo = append(todo, folded.action+" "+folded.sha)
+		}
+	}
+	return todo, nil
+}
+
+// firstNonEmpty returns a unless it is empty, and b otherwise
+func firstNonEmpty(a, b string) string {
+	if a != "" {
+		return a
+	}
+	return b
+}
+
+// parseTodoLine splits a todo line into its action and commit. Lines written
+// before todo actions existed hold only the commit, which is a pick.
+func parseTodoLine(line string) (action, sha string, err error) {
+	fields := strings.Fields(line)
+	switch {
+	case len(fields) == 1:
+		return RebasePick, fields[0], nil
+	case len(fields) == 2 && (fields[0] == RebasePick || fields[0] == RebaseFixup || fields[0] == RebaseSquash):
+		return fields[0], fields[1], nil
+	}
+	return "", "", fmt.Errorf("invalid rebase todo line: %q", line)
+}
+
+// rebaseTodoHelp follows the steps in the todo list an interactive rebase
+// opens in the editor
+const rebaseTodoHelp = `
+Commands:
+p, pick <commit> = use commit
+s, squash <commit> = use commit, but meld into previous commit
+f, fixup <commit> = like "squash", but discard this commit's log message
+d, drop <commit> = remove commit
+
+These lines can be re-ordered; they are executed from top to bottom.
+If you remove a line here THAT COMMIT WILL BE LOST.
+However, if you remove everything, the rebase will be aborted.
+`
+
+// editRebaseTodo has the user edit the todo of an interactive rebase of head
+// onto upstream in the editor, on RebaseTodoFile, and returns the steps it
+// is left with, dropped ones left out
+func (r *Repository) editRebaseTodo(todo []string, upstream, head string) ([]string, error) {
+	var b strings.Builder
+	for _, line := range todo {
+		action, sha, err := parseTodoLine(line)
+		if err != nil {
+			return nil, err
+		}
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&b, "%s %s %s\n", action, sha[:7], strings.SplitN(commit.Message, "\n", 2)[0])
+	}
+	if len(todo) == 0 {
+		b.WriteString("noop\n")
+	}
+	fmt.Fprintf(&b, "\n# Rebase %s..%s onto %s (%d commands)\n#\n", upstream[:7], head[:7], upstream[:7], len(todo))
+	for _, line := range strings.Split(strings.TrimPrefix(rebaseTodoHelp, "\n"), "\n") {
+		if line == "" {
+			b.WriteString("#\n")
+		} else {
+			b.WriteString("# " + line + "\n")
+		}
+	}
+
+	todoFile := r.gitPath(RebaseTodoFile)
+	if err := os.WriteFile(todoFile, []byte(strings.TrimSuffix(b.String(), "#\n")), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", RebaseTodoFile, err)
+	}
+	defer os.Remove(todoFile)
+	if err := r.EditFile(todoFile); err != nil {
+		return nil, err
+	}
+	edited, err := os.ReadFile(todoFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", RebaseTodoFile, err)
+	}
+
+	var steps []string
+	empty := true
+	for n, line := range strings.Split(string(edited), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		empty = false
+		if fields[0] == "noop" {
+			continue
+		}
+		action, ok := map[string]string{
+			"p": RebasePick, RebasePick: RebasePick,
+			"f": RebaseFixup, RebaseFixup: RebaseFixup,
+			"s": RebaseSquash, RebaseSquash: RebaseSquash,
+			"d": RebaseDrop, RebaseDrop: RebaseDrop,
+		}[fields[0]]
+		if !ok || len(fields) < 2 {
+			return nil, fmt.Errorf("invalid line %d of the rebase todo: %s", n+1, line)
+		}
+		sha, err := r.ResolveCommit(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid line %d of the rebase todo: %w", n+1, err)
+		}
+		if action == RebaseDrop {
+			continue
+		}
+		// The commit before the first step is upstream's, which is not rewritten
+		if action != RebasePick && len(steps) == 0 {
+			return nil, fmt.Errorf("cannot '%s' without a previous commit", action)
+		}
+		steps = append(steps, action+" "+sha)
+	}
+	if empty {
+		return nil, errors.New("nothing to do")
+	}
+	return steps, nil
+}
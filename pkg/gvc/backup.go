@@ -0,0 +1,189 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// Backup writes a bundle of everything added since the previous backup and
+// moves the backup marker refs to the tips it contains
+func (r *Repository) Backup(path string) error {
+	refs, err := r.listRefs()
+	if err != nil {
+		return err
+	}
+
+	var tips, markers []string
+	var bundleRefs []Ref
+	for _, ref := range refs {
+		if strings.HasPrefix(ref.Name, BackupRefPrefix) {
+			markers = append(markers, ref.SHA)
+			continue
+		}
+		objectType, _, err := r.ReadObject(ref.SHA)
+		if err != nil {
+			return err
+		}
+		if objectType != object.CommitObject {
+			continue
+		}
+		tips = append(tips, ref.SHA)
+		bundleRefs = append(bundleRefs, ref)
+	}
+	if len(tips) == 0 {
+		return errors.New("nothing to back up: the repository has no commits")
+	}
+	// HEAD comes first, as git bundle create --all writes it, so a clone of
+	// the bundle checks out the branch that was current
+	head, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	if head != "" {
+		tips = append(tips, head)
+		bundleRefs = append([]Ref{{Name: "HEAD", SHA: head}}, bundleRefs...)
+	}
+
+	objects, err := r.reachableObjects(tips, markers)
+	if err != nil {
+		return err
+	}
+	if len(objects) == 0 {
+		fmt.Fprintln(r.Out, "Nothing new since the last backup")
+		return nil
+	}
+
+	// Prerequisites are the marker commits the new history builds on
+	needed := make(map[string]bool)
+	for _, sha := range objects {
+		needed[sha] = true
+	}
+	var prerequisites []string
+	for _, marker := range markers {
+		if !needed[marker] && !containsString(prerequisites, marker) {
+			prerequisites = append(prerequisites, marker)
+		}
+	}
+
+	if err := r.writeBundle(path, bundleRefs, prerequisites, objects); err != nil {
+		return err
+	}
+
+	// The bundle is safely written; only now move the markers
+	for _, ref := range refs {
+		if strings.HasPrefix(ref.Name, BackupRefPrefix) {
+			if err := r.deleteRef(ref.Name); err != nil {
+				return fmt.Errorf("failed to move backup marker: %w", err)
+			}
+		}
+	}
+	for _, ref := range bundleRefs {
+		marker := BackupRefPrefix + strings.TrimPrefix(ref.Name, "refs/")
+		if err := r.writeRef(marker, ref.SHA, "backup: "+filepath.Base(path)); err != nil {
+			return err
+		}
+	}
+
+	kind := "Full"
+	if len(markers) > 0 {
+		kind = "Incremental"
+	}
+	fmt.Fprintf(r.Out, "%s backup written to %s (%d objects, %d refs)\n", kind, path, len(objects), len(bundleRefs))
+	return nil
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// RestoreBackup unpacks a backup bundle into the repository and updates its refs
+func (r *Repository) RestoreBackup(path string) error {
+	bundle, err := readBundle(path)
+	if err != nil {
+		return err
+	}
+	if bundle.Format != r.Format {
+		return fmt.Errorf("%s holds %s objects but this repository uses %s", path, bundle.Format.Name, r.Format.Name)
+	}
+	for _, sha := range bundle.Prerequisites {
+		if !r.objectExists(sha) {
+			return fmt.Errorf("%s needs commit %s, which is missing; restore the earlier backups first", path, sha[:7])
+		}
+	}
+
+	headBefore, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	name, objects, err := r.installPack(bundle.Pack)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, ref := range bundle.Refs {
+		if ref.Name == "HEAD" || strings.HasPrefix(ref.Name, BackupRefPrefix) {
+			continue
+		}
+		if err := r.writeRef(ref.Name, ref.SHA, "backup: restored from "+filepath.Base(path)); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(r.Out, "Restored %d objects into %s and %d refs from %s\n", len(objects), name, len(bundle.Refs), path)
+
+	headAfter, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	if headAfter == headBefore {
+		return nil
+	}
+	return r.updateWorktreeAfterRestore(headBefore, headAfter)
+}
+
+// updateWorktreeAfterRestore brings the working tree along when a restore moved
+// the checked-out branch, unless that would overwrite local files
+func (r *Repository) updateWorktreeAfterRestore(oldSHA, newSHA string) error {
+	old := map[string]IndexEntry{}
+	if oldSHA != "" {
+		commit, err := r.ReadCommit(oldSHA)
+		if err != nil {
+			return err
+		}
+		if old, err = r.flattenTree(commit.TreeSHA); err != nil {
+			return err
+		}
+	}
+	commit, err := r.ReadCommit(newSHA)
+	if err != nil {
+		return err
+	}
+	entries, err := r.flattenTree(commit.TreeSHA)
+	if err != nil {
+		return err
+	}
+
+	if err := r.checkCleanState(old); err != nil {
+		fmt.Fprintln(r.Out, "Not updating the working tree: it has local changes")
+		return nil
+	}
+	for path := range entries {
+		if _, tracked := old[path]; tracked {
+			continue
+		}
+		if _, err := os.Lstat(r.worktreePath(path)); err == nil {
+			fmt.Fprintf(r.Out, "Not updating the working tree: %s already exists\n", path)
+			return nil
+		}
+	}
+	return r.checkoutEntries(old, entries)
+}
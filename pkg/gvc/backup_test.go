@@ -0,0 +1,40 @@
+package gvc
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupRecordsHead(t *testing.T) {
+	repo, commitSHA := newTestRepoWithCommit(t)
+	path := filepath.Join(t.TempDir(), "full.bundle")
+	if err := repo.Backup(path); err != nil {
+		t.Fatal(err)
+	}
+	bundle, err := ReadBundleHeader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Refs) == 0 || bundle.Refs[0].Name != "HEAD" || bundle.Refs[0].SHA != commitSHA {
+		t.Fatalf("the bundle starts with %v, want HEAD at %s", bundle.Refs, commitSHA)
+	}
+	if branch := bundle.headBranch(); branch != HeadsDir+"/main" {
+		t.Errorf("a clone of the bundle would check out %q, want main", branch)
+	}
+
+	// HEAD's marker keeps the next backup incremental
+	second := commitTestChange(t, repo, "next.txt", "next\n", "next")
+	path = filepath.Join(t.TempDir(), "incremental.bundle")
+	if err := repo.Backup(path); err != nil {
+		t.Fatal(err)
+	}
+	if bundle, err = ReadBundleHeader(path); err != nil {
+		t.Fatal(err)
+	}
+	if len(bundle.Prerequisites) != 1 || bundle.Prerequisites[0] != commitSHA {
+		t.Errorf("the incremental bundle needs %v, want only %s", bundle.Prerequisites, commitSHA)
+	}
+	if bundle.Refs[0].Name != "HEAD" || bundle.Refs[0].SHA != second {
+		t.Errorf("the incremental bundle starts with %v, want HEAD at %s", bundle.Refs[0], second)
+	}
+}
@@ -0,0 +1,471 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Bisect state: where to return to, the commands run so far, and the marked
+// commits as refs/bisect/bad, refs/bisect/good-<sha> and refs/bisect/skip-<sha>,
+// which also keep them from being pruned
+const (
+	BisectStartFile = "BISECT_START"
+	BisectLogFile   = "BISECT_LOG"
+	BisectRefsDir   = "refs/bisect"
+)
+
+// bisectSkipCode is the exit code with which a bisect run command reports
+// that the commit cannot be tested
+const bisectSkipCode = 125
+
+// BisectResult is where a bisection stands after a step
+type BisectResult struct {
+	Next      string   // commit checked out to test next
+	Remaining int      // revisions left to test after Next
+	Steps     int      // rough number of steps the search takes from here
+	FirstBad  string   // the first bad commit, once found
+	Skipped   []string // the commits the first bad one could be, when only skipped ones are left
+	Waiting   string   // what is still needed before the search can start
+}
+
+// Done reports whether the search has ended
+func (b *BisectResult) Done() bool {
+	return b.FirstBad != "" || len(b.Skipped) > 0
+}
+
+// Bisecting reports whether a bisection is in progress
+func (r *Repository) Bisecting() bool {
+	_, err := os.Stat(r.gitPath(BisectStartFile))
+	return err == nil
+}
+
+// BisectStart begins a bisection from the current HEAD, optionally marking a
+// bad commit and good ones straight away. The working tree must be clean.
+func (r *Repository) BisectStart(bad string, good []string) (*BisectResult, error) {
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	if r.Bisecting() {
+		return nil, errors.New("a bisect is already in progress; use 'gvc bisect reset' first")
+	}
+	if op := r.OperationInProgress(); op != "" {
+		return nil, fmt.Errorf("a %s is in progress; use --continue or --abort first", op)
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkCleanState(head); err != nil {
+		return nil, err
+	}
+
+	// Resolve everything before any state is written
+	var badSHA string
+	if bad != "" {
+		if badSHA, err = r.ResolveCommit(bad); err != nil {
+			return nil, err
+		}
+	}
+	goodSHAs := make([]string, len(good))
+	for i, rev := range good {
+		if goodSHAs[i], err = r.ResolveCommit(rev); err != nil {
+			return nil, err
+		}
+	}
+
+	// Remember the branch to go back to, or the commit when HEAD is detached
+	start, err := r.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	if start == "" {
+		return nil, errors.New("cannot bisect without any commits")
+	}
+	branchRef, err := r.HeadRef()
+	if err != nil {
+		return nil, err
+	}
+	if branchRef != "" {
+		start = strings.TrimPrefix(branchRef, HeadsDir+"/")
+	}
+	if err := writeFileLocked(r.gitPath(BisectStartFile), []byte(start+"\n")); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", BisectStartFile, err)
+	}
+
+	// The log replays the marks given up front through the start command
+	var log strings.Builder
+	command := "gvc bisect start"
+	if badSHA != "" {
+		comment, err := r.bisectMark("bad", badSHA)
+		if err != nil {
+			return nil, err
+		}
+		log.WriteString(comment)
+		command += " " + shellQuote(bad)
+	}
+	for i, sha := range goodSHAs {
+		comment, err := r.bisectMark("good", sha)
+		if err != nil {
+			return nil, err
+		}
+		log.WriteString(comment)
+		command += " " + shellQuote(good[i])
+	}
+	log.WriteString(command + "\n")
+	if err := writeFileLocked(r.gitPath(BisectLogFile), []byte(log.String())); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", BisectLogFile, err)
+	}
+	return r.bisectNext()
+}
+
+// BisectMark marks revisions as good, bad or skip (HEAD when none are given)
+// and checks out the next commit to test
+func (r *Repository) BisectMark(term string, revs []string) (*BisectResult, error) {
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	if !r.Bisecting() {
+		return nil, errors.New("no bisect in progress; use 'gvc bisect start' first")
+	}
+	if term != "good" && term != "bad" && term != "skip" {
+		return nil, fmt.Errorf("unknown bisect term %q", term)
+	}
+	if len(revs) == 0 {
+		revs = []string{"HEAD"}
+	}
+	if term == "bad" && len(revs) > 1 {
+		return nil, errors.New("'bisect bad' takes only one revision")
+	}
+	shas := make([]string, len(revs))
+	for i, rev := range revs {
+		var err error
+		if shas[i], err = r.ResolveCommit(rev); err != nil {
+			return nil, err
+		}
+	}
+	f, err := os.OpenFile(r.gitPath(BisectLogFile), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", BisectLogFile, err)
+	}
+	defer f.Close()
+	for _, sha := range shas {
+		comment, err := r.bisectMark(term, sha)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := fmt.Fprintf(f, "%sgvc bisect %s %s\n", comment, term, sha); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", BisectLogFile, err)
+		}
+	}
+	return r.bisectNext()
+}
+
+// bisectMark records a marked commit as a ref, returning the comment that
+// names it in the bisect log
+func (r *Repository) bisectMark(term, sha string) (string, error) {
+	ref := BisectRefsDir + "/bad"
+	if term != "bad" {
+		ref = BisectRefsDir + "/" + term + "-" + sha
+	}
+	if err := writeFileLocked(r.gitPath(filepath.FromSlash(ref)), []byte(sha+"\n")); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", ref, err)
+	}
+	commit, err := r.ReadCommit(sha)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("# %s: [%s] %s\n", term, sha, strings.SplitN(commit.Message, "\n", 2)[0]), nil
+}
+
+// bisectTerms reads the marked commits
+func (r *Repository) bisectTerms() (bad string, good, skip []string, err error) {
+	refs, err := r.listRefs()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	for _, ref := range refs {
+		name, ok := strings.CutPrefix(ref.Name, BisectRefsDir+"/")
+		switch {
+		case !ok:
+		case name == "bad":
+			bad = ref.SHA
+		case strings.HasPrefix(name, "good-"):
+			good = append(good, ref.SHA)
+		case strings.HasPrefix(name, "skip-"):
+			skip = append(skip, ref.SHA)
+		}
+	}
+	return bad, good, skip, nil
+}
+
+// bisectNext picks the commit that best halves the commits that may still be
+// the first bad one — those reachable from the bad commit but from no good
+// one — and checks it out. The best commit has as close to half of them
+// among its ancestors as possible; skipped commits are never picked.
+func (r *Repository) bisectNext() (*BisectResult, error) {
+	bad, good, skip, err := r.bisectTerms()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case bad == "" && len(good) == 0:
+		return &BisectResult{Waiting: "waiting for both good and bad commits"}, nil
+	case bad == "":
+		return &BisectResult{Waiting: fmt.Sprintf("waiting for bad commit, %d good commit(s) known", len(good))}, nil
+	case len(good) == 0:
+		return &BisectResult{Waiting: "waiting for good commit(s), bad commit known"}, nil
+	}
+
+	nodes, err := r.WalkRevisions(&RevRange{Include: []string{bad}, Exclude: good})
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 {
+		return nil, errors.New("some good revisions are not ancestors of the bad revision; mark them again")
+	}
+	candidates := make(map[string]*CommitNode, len(nodes))
+	for _, node := range nodes {
+		candidates[node.SHA] = node
+	}
+	skipped := make(map[string]bool, len(skip))
+	for _, sha := range skip {
+		skipped[sha] = true
+	}
+
+	// Like Git, take the first commit, oldest first, that splits the
+	// candidates within one of half; failing that, the newest of those that
+	// come closest. Commits with no candidate parents are only in the running
+	// for the latter.
+	all := len(nodes)
+	weights := make(map[string]int, all)
+	for _, node := range nodes {
+		weights[node.SHA] = bisectWeight(node.SHA, candidates)
+	}
+	best := ""
+	for i := len(nodes) - 1; i >= 0 && best == ""; i-- {
+		node := nodes[i]
+		if node.SHA == bad || skipped[node.SHA] || weights[node.SHA] == 1 {
+			continue
+		}
+		if diff := 2*weights[node.SHA] - all; diff >= -1 && diff <= 1 {
+			best = node.SHA
+		}
+	}
+	if best == "" {
+		bestDistance := -1
+		for _, node := range nodes {
+			if node.SHA == bad || skipped[node.SHA] {
+				continue
+			}
+			weight := weights[node.SHA]
+			if distance := min(weight, all-weight); distance > bestDistance {
+				best, bestDistance = node.SHA, distance
+			}
+		}
+	}
+	if best == "" {
+		if all == 1 {
+			return &BisectResult{FirstBad: bad}, nil
+		}
+		// Only skipped commits lie between the good ones and the bad one
+		result := &BisectResult{}
+		for _, node := range nodes {
+			result.Skipped = append(result.Skipped, node.SHA)
+		}
+		return result, nil
+	}
+
+	head, err := r.headEntries()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkCleanState(head); err != nil {
+		return nil, err
+	}
+	if err := r.detachHead(best, "checkout: moving to "+best); err != nil {
+		return nil, err
+	}
+	return &BisectResult{Next: best, Remaining: all - weights[best] - 1, Steps: bisectSteps(all)}, nil
+}
+
+// bisectWeight counts the candidates among a commit's ancestors, itself included
+func bisectWeight(sha string, candidates map[string]*CommitNode) int {
+	seen := map[string]bool{sha: true}
+	stack := []string{sha}
+	for len(stack) > 0 {
+		node := candidates[stack[len(stack)-1]]
+		stack = stack[:len(stack)-1]
+		for _, parent := range node.Parents {
+			if _, ok := candidates[parent]; ok && !seen[parent] {
+				seen[parent] = true
+				stack = append(stack, parent)
+			}
+		}
+	}
+	return len(seen)
+}
+
+// bisectSteps estimates the steps needed to search n commits, as Git does
+func bisectSteps(n int) int {
+	if n < 3 {
+		return 0
+	}
+	log := bits.Len(uint(n)) - 1
+	e := 1 << log
+	if e < 3*(n-e) {
+		return log
+	}
+	return log - 1
+}
+
+// BisectRun drives the search with a command run at the top of the working
+// tree for each commit: exit code 0 marks it good, 125 skips it, and 1 to 127
+// mark it bad. Any other exit code, or failing to run the command, stops the
+// search. report is called after every step.
+func (r *Repository) BisectRun(argv []string, report func(*BisectResult) error) (*BisectResult, error) {
+	if !r.Bisecting() {
+		return nil, errors.New("no bisect in progress; use 'gvc bisect start' first")
+	}
+	if len(argv) == 0 {
+		return nil, errors.New("bisect run needs a command")
+	}
+	result, err := r.bisectNext()
+	if err != nil {
+		return nil, err
+	}
+	for !result.Done() {
+		if result.Waiting != "" {
+			return nil, fmt.Errorf("cannot bisect run: %s", result.Waiting)
+		}
+
+		var cmd *exec.Cmd
+		if len(argv) == 1 {
+			cmd = exec.Command("sh", "-c", argv[0])
+		} else {
+			cmd = exec.Command(argv[0], argv[1:]...)
+		}
+		cmd.Dir = r.Root
+		cmd.Env = r.Environ()
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, r.Out, os.Stderr
+		fmt.Fprintf(r.Out, "running %s\n", strings.Join(argv, " "))
+		code := 0
+		if err := cmd.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return nil, fmt.Errorf("failed to run bisect command: %w", err)
+			}
+			code = exitErr.ExitCode()
+		}
+
+		var term string
+		switch {
+		case code == 0:
+			term = "good"
+		case code == bisectSkipCode:
+			term = "skip"
+		case code > 0 && code < 128:
+			term = "bad"
+		default:
+			return nil, fmt.Errorf("bisect run failed: exit code %d from '%s' is < 0 or >= 128", code, strings.Join(argv, " "))
+		}
+		if result, err = r.BisectMark(term, nil); err != nil {
+			return nil, err
+		}
+		if err := report(result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// BisectReset ends a bisection, checking out the branch or commit it started
+// from, or rev when one is given
+func (r *Repository) BisectReset(rev string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if !r.Bisecting() {
+		return errors.New("no bisect in progress")
+	}
+	data, err := os.ReadFile(r.gitPath(BisectStartFile))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", BisectStartFile, err)
+	}
+	if rev == "" {
+		rev = strings.TrimSpace(string(data))
+	}
+
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	if err := r.checkCleanState(head); err != nil {
+		return err
+	}
+	branchSHA, err := r.ReadRef(HeadsDir + "/" + rev)
+	if err != nil {
+		return err
+	}
+	if branchSHA != "" {
+		if err := r.detachHead(branchSHA, "checkout: moving to "+rev); err != nil {
+			return err
+		}
+		if err := r.attachHead(HeadsDir+"/"+rev, "checkout: moving to "+rev); err != nil {
+			return err
+		}
+	} else {
+		sha, err := r.ResolveCommit(rev)
+		if err != nil {
+			return err
+		}
+		if err := r.detachHead(sha, "checkout: moving to "+sha); err != nil {
+			return err
+		}
+	}
+	return r.clearBisectState()
+}
+
+// clearBisectState removes the bisect files and refs
+func (r *Repository) clearBisectState() error {
+	if !r.usesFileRefs() {
+		err := r.walkGitFiles(r.gitPath(filepath.FromSlash(BisectRefsDir)), func(path string) error {
+			l, err := r.lockGitFile(path)
+			if err != nil {
+				return err
+			}
+			return l.remove()
+		})
+		if err != nil {
+			return fmt.Errorf("failed to clean up bisect state: %w", err)
+		}
+	}
+	for _, path := range []string{r.gitPath(BisectStartFile), r.gitPath(BisectLogFile), r.gitPath(filepath.FromSlash(BisectRefsDir))} {
+		if err := os.RemoveAll(path); err != nil {
+			return fmt.Errorf("failed to clean up bisect state: %w", err)
+		}
+	}
+	return nil
+}
+
+// BisectLog returns the bisect commands run so far, with the subject of each
+// marked commit in a comment
+func (r *Repository) BisectLog() (string, error) {
+	if !r.Bisecting() {
+		return "", errors.New("no bisect in progress")
+	}
+	data, err := os.ReadFile(r.gitPath(BisectLogFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", BisectLogFile, err)
+	}
+	return string(data), nil
+}
+
+// shellQuote quotes an argument for the bisect log, which reads as a script
+func shellQuote(arg string) string {
+	return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+}
@@ -0,0 +1,167 @@
+package gvc
+
+import (
+	"container/heap"
+	"fmt"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/diff"
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// BlameLine attributes one line of the final file to a commit
+type BlameLine struct {
+	Commit  string
+	Line    int // line number in the blamed commit's version
+	Content string
+}
+
+// pendingLine tracks a final-file line while it is passed down to older versions
+type pendingLine struct {
+	final int // index in the blamed file
+	index int // index in the version currently being examined
+}
+
+// fileAtCommit returns the blob SHA of path in a commit, or "" if the path is absent
+func (r *Repository) fileAtCommit(commitSHA, path string) (string, error) {
+	commit, err := r.ReadCommit(commitSHA)
+	if err != nil {
+		return "", err
+	}
+	entry, ok, err := r.treeEntryAt(commit.TreeSHA, path)
+	if err != nil || !ok || entry.Type != object.BlobObject {
+		return "", err
+	}
+	return entry.SHA, nil
+}
+
+// blobLines reads a blob and splits it into lines
+func (r *Repository) blobLines(sha string) ([]string, error) {
+	objectType, content, err := r.ReadObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	if objectType != object.BlobObject {
+		return nil, fmt.Errorf("expected blob object, got %s", objectType)
+	}
+	return diff.SplitLines(content), nil
+}
+
+// Blame attributes every line of path at a commit to the commit that introduced it.
+// Lines unchanged against a parent are passed down to that parent; whatever no
+// parent explains belongs to the commit itself.
+func (r *Repository) Blame(startSHA, path string) ([]BlameLine, error) {
+	startBlob, err := r.fileAtCommit(startSHA, path)
+	if err != nil {
+		return nil, err
+	}
+	if startBlob == "" {
+		return nil, fmt.Errorf("no such path '%s' in %s", path, startSHA[:7])
+	}
+	finalLines, err := r.blobLines(startBlob)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]BlameLine, len(finalLines))
+	pending := map[string][]pendingLine{}
+	for i := range finalLines {
+		pending[startSHA] = append(pending[startSHA], pendingLine{final: i, index: i})
+	}
+	blobs := map[string]string{startSHA: startBlob}
+
+	queue := &dateQueue{}
+	startNode, err := r.LookupCommitNode(startSHA)
+	if err != nil {
+		return nil, err
+	}
+	heap.Push(queue, &dateQueueItem{node: startNode})
+	inQueue := map[string]bool{startSHA: true}
+	seq := 1
+
+	for queue.Len() > 0 {
+		node := heap.Pop(queue).(*dateQueueItem).node
+		inQueue[node.SHA] = false
+		lines := pending[node.SHA]
+		delete(pending, node.SHA)
+		if len(lines) == 0 {
+			continue
+		}
+
+		blob := blobs[node.SHA]
+		var current []string
+		for _, parent := range node.Parents {
+			if len(lines) == 0 {
+				break
+			}
+			parentBlob, err := r.fileAtCommit(parent, path)
+			if err != nil {
+				return nil, err
+			}
+			if parentBlob == "" {
+				continue
+			}
+
+			// Map each line of this version to its line in the parent, if unchanged
+			var mapping []int
+			if parentBlob != blob {
+				if current == nil {
+					if current, err = r.blobLines(blob); err != nil {
+						return nil, err
+					}
+				}
+				parentLines, err := r.blobLines(parentBlob)
+				if err != nil {
+					return nil, err
+				}
+				mapping = make([]int, len(current))
+				a, b := 0, 0
+				for _, op := range diff.Lines(parentLines, current) {
+					switch op.Kind {
+					case ' ':
+						mapping[b] = a
+						a++
+						b++
+					case '-':
+						a++
+					case '+':
+						mapping[b] = -1
+						b++
+					}
+				}
+			}
+
+			var remaining []pendingLine
+			passed := false
+			for _, line := range lines {
+				parentIndex := line.index
+				if mapping != nil {
+					parentIndex = mapping[line.index]
+				}
+				if parentIndex < 0 {
+					remaining = append(remaining, line)
+					continue
+				}
+				pending[parent] = append(pending[parent], pendingLine{final: line.final, index: parentIndex})
+				passed = true
+			}
+			if passed {
+				if !inQueue[parent] {
+					parentNode, err := r.LookupCommitNode(parent)
+					if err != nil {
+						return nil, err
+					}
+					heap.Push(queue, &dateQueueItem{node: parentNode, seq: seq})
+					inQueue[parent] = true
+					seq++
+				}
+				blobs[parent] = parentBlob
+			}
+			lines = remaining
+		}
+
+		for _, line := range lines {
+			result[line.final] = BlameLine{Commit: node.SHA, Line: line.index + 1, Content: finalLines[line.final]}
+		}
+	}
+	return result, nil
+}
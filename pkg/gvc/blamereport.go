@@ -0,0 +1,144 @@
+package gvc
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BlameReportFormats are the formats WriteBlameReport writes
+var BlameReportFormats = []string{"html", "markdown"}
+
+// blameAgeClasses is how many heat-map classes a report spreads commit ages
+// over, from age-0 for the newest commit to age-4 for the oldest
+const blameAgeClasses = 5
+
+// blameReportCommit is what a report shows about one blamed commit
+type blameReportCommit struct {
+	label    string // abbreviated ID, "^"-prefixed for a root commit
+	author   string
+	date     time.Time
+	ageClass int
+}
+
+// blameReportStyle shades lines from hot (recent) to cold (old)
+const blameReportStyle = `table.blame { border-collapse: collapse; font-family: monospace; font-size: 13px; }
+table.blame th { text-align: left; border-bottom: 1px solid #888; padding: 2px 8px; }
+table.blame td { padding: 0 8px; white-space: pre; vertical-align: top; }
+table.blame td.line { text-align: right; color: #666; }
+tr.age-0 { background: #fdd0c8; }
+tr.age-1 { background: #fde4c8; }
+tr.age-2 { background: #f6f2d0; }
+tr.age-3 { background: #dcebf5; }
+tr.age-4 { background: #c8dcf0; }`
+
+// WriteBlameReport writes blame output for path as a standalone HTML page or
+// a Markdown table, for audit reports. Each line shows its commit, author,
+// date and age relative to now; HTML rows also carry an age-N class so the
+// file reads as a heat map from its newest to its oldest changes.
+func (r *Repository) WriteBlameReport(w io.Writer, path string, lines []BlameLine, format string, now time.Time) error {
+	if !slices.Contains(BlameReportFormats, format) {
+		return fmt.Errorf("unknown blame format %q (use %s)", format, strings.Join(BlameReportFormats, ", "))
+	}
+	commits, err := r.blameReportCommits(lines)
+	if err != nil {
+		return err
+	}
+
+	out := bufio.NewWriter(w)
+	if format == "html" {
+		fmt.Fprintf(out, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>blame: %s</title>\n<style>\n%s\n</style>\n</head>\n<body>\n",
+			html.EscapeString(path), blameReportStyle)
+		fmt.Fprintf(out, "<h1>%s</h1>\n<table class=\"blame\">\n", html.EscapeString(path))
+		fmt.Fprintln(out, "<thead><tr><th>Commit</th><th>Author</th><th>Date</th><th>Age</th><th>Line</th><th>Code</th></tr></thead>")
+		fmt.Fprintln(out, "<tbody>")
+		for i, line := range lines {
+			commit := commits[line.Commit]
+			fmt.Fprintf(out, "<tr class=\"age-%d\"><td class=\"commit\" title=\"%s\">%s</td><td class=\"author\">%s</td><td class=\"date\">%s</td><td class=\"age\">%s</td><td class=\"line\">%d</td><td class=\"code\">%s</td></tr>\n",
+				commit.ageClass, line.Commit, commit.label, html.EscapeString(commit.author),
+				commit.date.Format("2006-01-02 15:04:05 -0700"), RelativeDate(commit.date, now), i+1,
+				html.EscapeString(strings.TrimSuffix(line.Content, "\n")))
+		}
+		fmt.Fprintln(out, "</tbody>\n</table>\n</body>\n</html>")
+	} else {
+		fmt.Fprintf(out, "# %s\n\n", markdownCell(path))
+		fmt.Fprintln(out, "| Commit | Author | Date | Age | Line | Code |")
+		fmt.Fprintln(out, "| --- | --- | --- | --- | ---: | --- |")
+		for i, line := range lines {
+			commit := commits[line.Commit]
+			fmt.Fprintf(out, "| `%s` | %s | %s | %s | %d | %s |\n", commit.label, markdownCell(commit.author),
+				commit.date.Format("2006-01-02"), RelativeDate(commit.date, now), i+1,
+				markdownCode(strings.TrimSuffix(line.Content, "\n")))
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return fmt.Errorf("failed to write blame report: %w", err)
+	}
+	return nil
+}
+
+// blameReportCommits looks up every blamed commit and assigns it an age
+// class by where its date falls between the file's newest and oldest commits
+func (r *Repository) blameReportCommits(lines []BlameLine) (map[string]*blameReportCommit, error) {
+	commits := make(map[string]*blameReportCommit)
+	var newest, oldest time.Time
+	for _, line := range lines {
+		if _, ok := commits[line.Commit]; ok {
+			continue
+		}
+		commit, err := r.ReadCommit(line.Commit)
+		if err != nil {
+			return nil, err
+		}
+		node, err := r.LookupCommitNode(line.Commit)
+		if err != nil {
+			return nil, err
+		}
+		label := line.Commit[:8]
+		if len(node.Parents) == 0 {
+			label = "^" + line.Commit[:7]
+		}
+		author, _, _ := strings.Cut(commit.Author, " <")
+		commits[line.Commit] = &blameReportCommit{label: label, author: author, date: commit.Timestamp}
+		if newest.IsZero() || commit.Timestamp.After(newest) {
+			newest = commit.Timestamp
+		}
+		if oldest.IsZero() || commit.Timestamp.Before(oldest) {
+			oldest = commit.Timestamp
+		}
+	}
+
+	span := newest.Sub(oldest)
+	for _, commit := range commits {
+		if span > 0 {
+			commit.ageClass = min(int(float64(newest.Sub(commit.date))/float64(span)*blameAgeClasses), blameAgeClasses-1)
+		}
+	}
+	return commits, nil
+}
+
+// markdownCell escapes text for a Markdown table cell
+func markdownCell(text string) string {
+	return strings.NewReplacer("|", `\|`, "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// markdownCode renders a line of code as an inline code span in a table
+// cell, using a backtick fence longer than any run inside the line
+func markdownCode(text string) string {
+	if strings.TrimSpace(text) == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	text = strings.ReplaceAll(text, "|", `\|`)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
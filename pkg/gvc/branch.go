@@ -0,0 +1,328 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+const HeadsDir = "refs/heads"
+
+// validateBranchName rejects names that cannot be stored as a ref
+func validateBranchName(name string) error {
+	if name == "HEAD" || strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid branch name: %s", name)
+	}
+	if _, err := CheckRefFormat(HeadsDir+"/"+name, RefFormatOptions{}); err != nil {
+		return fmt.Errorf("invalid branch name: %s", name)
+	}
+	return nil
+}
+
+// ListBranches returns the names of all local branches, sorted
+func (r *Repository) ListBranches() ([]string, error) {
+	var branches []string
+	err := r.walkGitFiles(r.gitPath(HeadsDir), func(path string) error {
+		rel, err := filepath.Rel(r.gitPath(HeadsDir), path)
+		if err != nil {
+			return err
+		}
+		branches = append(branches, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	packed, err := r.packedRefs()
+	if err != nil {
+		return nil, err
+	}
+	for ref := range packed {
+		name, ok := strings.CutPrefix(ref, HeadsDir+"/")
+		if ok && !slices.Contains(branches, name) {
+			branches = append(branches, name)
+		}
+	}
+	sort.Strings(branches)
+	return branches, nil
+}
+
+// BranchInfo is a local branch with the times ListBranchInfo can order by
+type BranchInfo struct {
+	Name      string
+	SHA       string
+	Committed time.Time // the committer date of its tip
+	// Created and Updated are when the branch was created and last moved,
+	// from its reflog; without one both are the tip's committer date
+	Created time.Time
+	Updated time.Time
+}
+
+// RefTimes returns when ref was created and last updated, from the first
+// and last entries of its reflog. ok is false when it has no reflog.
+func (r *Repository) RefTimes(ref string) (created, updated time.Time, ok bool, err error) {
+	entries, err := r.ReadReflog(ref)
+	if err != nil || len(entries) == 0 {
+		return time.Time{}, time.Time{}, false, err
+	}
+	return entries[0].Timestamp, entries[len(entries)-1].Timestamp, true, nil
+}
+
+// ListBranchInfo returns the local branches ordered by sortKey: refname (the
+// default), committerdate, creatordate or updatedate, oldest first for the
+// dates, with a leading "-" reversing the order. An empty sortKey takes
+// branch.sort from the config. Branches the key ties are ordered by name.
+func (r *Repository) ListBranchInfo(sortKey string) ([]BranchInfo, error) {
+	if sortKey == "" {
+		var err error
+		if sortKey, err = r.getConfigString("branch.sort", "refname"); err != nil {
+			return nil, err
+		}
+	}
+	key, reverse := strings.CutPrefix(sortKey, "-")
+	var when func(BranchInfo) time.Time
+	switch key {
+	case "refname":
+	case "committerdate":
+		when = func(b BranchInfo) time.Time { return b.Committed }
+	case "creatordate":
+		when = func(b BranchInfo) time.Time { return b.Created }
+	case "updatedate":
+		when = func(b BranchInfo) time.Time { return b.Updated }
+	default:
+		return nil, fmt.Errorf("unsupported sort key '%s'", sortKey)
+	}
+
+	names, err := r.ListBranches()
+	if err != nil {
+		return nil, err
+	}
+	branches := make([]BranchInfo, 0, len(names))
+	for _, name := range names {
+		ref := HeadsDir + "/" + name
+		sha, err := r.ReadRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return nil, err
+		}
+		branch := BranchInfo{Name: name, SHA: sha, Committed: commit.Committed, Created: commit.Committed, Updated: commit.Committed}
+		created, updated, ok, err := r.RefTimes(ref)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			branch.Created, branch.Updated = created, updated
+		}
+		branches = append(branches, branch)
+	}
+	// ListBranches sorts by name already, which a stable sort keeps for ties
+	slices.SortStableFunc(branches, func(a, b BranchInfo) int {
+		var order int
+		if when != nil {
+			order = when(a).Compare(when(b))
+		} else {
+			order = strings.Compare(a.Name, b.Name)
+		}
+		if reverse {
+			return -order
+		}
+		return order
+	})
+	return branches, nil
+}
+
+// writeRef points ref at sha and appends the move to its reflog
+func (r *Repository) writeRef(ref, sha, message string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	l, err := r.lockGitFile(r.gitPath(filepath.FromSlash(ref)))
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+
+	oldSHA, err := r.ReadRef(ref)
+	if err != nil {
+		return err
+	}
+	if err := r.prepareReflog(ref); err != nil {
+		return err
+	}
+	if err := l.commit([]byte(sha + "\n")); err != nil {
+		return fmt.Errorf("failed to write ref %s: %w", ref, err)
+	}
+	return r.appendReflog(ref, oldSHA, sha, message)
+}
+
+// deleteRef removes ref, loose and packed, and its reflog, waiting for any
+// process updating it
+func (r *Repository) deleteRef(ref string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	l, err := r.lockGitFile(r.gitPath(filepath.FromSlash(ref)))
+	if err != nil {
+		return err
+	}
+	oldSHA, err := r.ReadRef(ref)
+	if err != nil {
+		l.unlock()
+		return err
+	}
+	if err := r.removePackedRefs([]string{ref}); err != nil {
+		l.unlock()
+		return err
+	}
+	if err := l.remove(); err != nil {
+		return err
+	}
+	r.pruneRefDirs(ref)
+	r.notifyRefUpdate(RefUpdate{Name: ref, OldSHA: oldSHA})
+	return r.writeReflog(ref, nil)
+}
+
+// CreateBranch creates a branch pointing at a start revision
+func (r *Repository) CreateBranch(name, startRev string) error {
+	if err := validateBranchName(name); err != nil {
+		return err
+	}
+	ref := "refs/heads/" + name
+	existing, err := r.ReadRef(ref)
+	if err != nil {
+		return err
+	}
+	if existing != "" {
+		return fmt.Errorf("a branch named '%s' already exists", name)
+	}
+
+	startSHA, err := r.ResolveCommit(startRev)
+	if err != nil {
+		return err
+	}
+	return r.writeRef(ref, startSHA, "branch: Created from "+startRev)
+}
+
+// DeleteBranch removes a branch, refusing unmerged work unless forced
+func (r *Repository) DeleteBranch(name string, force bool) error {
+	ref := "refs/heads/" + name
+	sha, err := r.ReadRef(ref)
+	if err != nil {
+		return err
+	}
+	if sha == "" {
+		return fmt.Errorf("branch '%s' not found", name)
+	}
+	current, err := r.HeadRef()
+	if err != nil {
+		return err
+	}
+	if current == ref {
+		return fmt.Errorf("cannot delete the branch '%s' which you are currently on", name)
+	}
+	if other, err := r.branchCheckedOut(ref, true); err != nil {
+		return err
+	} else if other != "" {
+		return fmt.Errorf("cannot delete the branch '%s' which is checked out at '%s'", name, other)
+	}
+
+	if !force {
+		head, err := r.HeadCommit()
+		if err != nil {
+			return err
+		}
+		merged := false
+		if head != "" {
+			if merged, err = r.IsAncestor(sha, head); err != nil {
+				return err
+			}
+		}
+		if !merged {
+			return fmt.Errorf("the branch '%s' is not fully merged; use -D to delete it anyway", name)
+		}
+	}
+
+	if err := r.deleteRef(ref); err != nil {
+		return fmt.Errorf("failed to delete branch: %w", err)
+	}
+	fmt.Fprintf(r.Out, "Deleted branch %s (was %s)\n", name, sha[:7])
+	return nil
+}
+
+// DefaultBranch returns the ref of the branch others are merged into: the
+// local branch named by origin's HEAD, or its remote-tracking branch when
+// there is no such local branch, or else main or master, whichever exists
+func (r *Repository) DefaultBranch() (string, error) {
+	if tracking, err := r.RemoteHead("origin"); err != nil {
+		return "", err
+	} else if tracking != "" {
+		local := HeadsDir + "/" + strings.TrimPrefix(tracking, RemotesDir+"/origin/")
+		if sha, err := r.ReadRef(local); err != nil {
+			return "", err
+		} else if sha != "" {
+			return local, nil
+		}
+		return tracking, nil
+	}
+	for _, name := range []string{"main", "master"} {
+		if sha, err := r.ReadRef(HeadsDir + "/" + name); err != nil {
+			return "", err
+		} else if sha != "" {
+			return HeadsDir + "/" + name, nil
+		}
+	}
+	return "", errors.New("cannot tell the default branch; name the branch to check against")
+}
+
+// StaleBranches returns the local branches whose tips were committed before
+// before and are merged into into, the default branch when empty, oldest
+// first. Into itself, the current branch and branches checked out in other
+// worktrees are never stale.
+func (r *Repository) StaleBranches(before time.Time, into string) ([]BranchInfo, error) {
+	intoRef := into
+	if into == "" {
+		var err error
+		if intoRef, err = r.DefaultBranch(); err != nil {
+			return nil, err
+		}
+	}
+	intoSHA, err := r.ResolveCommit(intoRef)
+	if err != nil {
+		return nil, err
+	}
+	current, err := r.HeadRef()
+	if err != nil {
+		return nil, err
+	}
+	branches, err := r.ListBranchInfo("committerdate")
+	if err != nil {
+		return nil, err
+	}
+	var stale []BranchInfo
+	for _, branch := range branches {
+		ref := HeadsDir + "/" + branch.Name
+		if !branch.Committed.Before(before) || ref == intoRef || branch.Name == into || ref == current {
+			continue
+		}
+		if other, err := r.branchCheckedOut(ref, true); err != nil {
+			return nil, err
+		} else if other != "" {
+			continue
+		}
+		merged, err := r.IsAncestor(branch.SHA, intoSHA)
+		if err != nil {
+			return nil, err
+		}
+		if merged {
+			stale = append(stale, branch)
+		}
+	}
+	return stale, nil
+}
@@ -0,0 +1,71 @@
+package gvc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// assertReflogLength checks that ref has a log of n entries
+func assertReflogLength(t *testing.T, repo *Repository, ref string, n int) {
+	t.Helper()
+	entries, err := repo.ReadReflog(ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != n {
+		t.Errorf("reflog of %s has %d entries, want %d", ref, len(entries), n)
+	}
+}
+
+func TestDeletedRefMakesRoomForNestedRef(t *testing.T) {
+	repo, _ := newTestRepoWithCommit(t)
+	if err := repo.CreateBranch("tmp", "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	// As update-ref -d does
+	if err := repo.UpdateRefs([]RefEdit{{Ref: "refs/heads/tmp"}}, "delete"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(repo.reflogPath("refs/heads/tmp")); !os.IsNotExist(err) {
+		t.Fatalf("the reflog of the deleted ref was kept: %v", err)
+	}
+	if err := repo.CreateBranch("tmp/x", "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	assertReflogLength(t, repo, "refs/heads/tmp/x", 1)
+
+	// And back again, through DeleteBranch
+	if err := repo.DeleteBranch("tmp/x", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateBranch("tmp", "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	assertReflogLength(t, repo, "refs/heads/tmp", 1)
+}
+
+func TestRefIsNotMovedWithoutItsLog(t *testing.T) {
+	repo, _ := newTestRepoWithCommit(t)
+	// A log left behind by a deleted ref is in the way of a nested one
+	stale := repo.reflogPath("refs/heads/tmp")
+	if err := os.WriteFile(stale, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateBranch("tmp/x", "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	assertReflogLength(t, repo, "refs/heads/tmp/x", 1)
+
+	// A log that cannot be created leaves the ref alone
+	blocker := filepath.Join(repo.reflogPath("refs/heads/y"), "z")
+	if err := os.MkdirAll(blocker, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.CreateBranch("y", "HEAD"); err == nil {
+		t.Fatal("creating a branch whose log cannot be written succeeded")
+	}
+	if sha, err := repo.ReadRef("refs/heads/y"); err != nil || sha != "" {
+		t.Errorf("refs/heads/y was written as %q (%v) without its log", sha, err)
+	}
+}
@@ -0,0 +1,343 @@
+package gvc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+const (
+	bundleSignature   = "# v2 git bundle\n"
+	bundleV3Signature = "# v3 git bundle\n"
+	BackupRefPrefix   = "refs/backup/"
+)
+
+// Bundle is a single-file snapshot of refs plus the objects they need
+type Bundle struct {
+	Format        *object.Format // hash the bundle's objects are named with
+	Prerequisites []string       // commits the reader must already have
+	Refs          []Ref
+	Pack          []byte
+}
+
+// writeBundle packs objects into a bundle file advertising refs
+func (r *Repository) writeBundle(path string, refs []Ref, prerequisites []string, objectSHAs []string) error {
+	objects := make([]*packObject, 0, len(objectSHAs))
+	for _, sha := range objectSHAs {
+		if err := r.canceled(); err != nil {
+			return err
+		}
+		objectType, content, err := r.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		objects = append(objects, &packObject{sha: sha, objType: objectType, data: content})
+	}
+	if err := r.setNameHashes(objects); err != nil {
+		return err
+	}
+	findDeltas(objects, PackOptions{Window: 10, Depth: 50, Threads: runtime.NumCPU()})
+	if err := r.canceled(); err != nil {
+		return err
+	}
+
+	// Version 2 bundles are always SHA-1; other formats need a version 3 capability line
+	var out bytes.Buffer
+	if r.Format == object.SHA1 {
+		out.WriteString(bundleSignature)
+	} else {
+		out.WriteString(bundleV3Signature)
+		fmt.Fprintf(&out, "@object-format=%s\n", r.Format.Name)
+	}
+	for _, sha := range prerequisites {
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&out, "-%s %s\n", sha, strings.SplitN(commit.Message, "\n", 2)[0])
+	}
+	for _, ref := range refs {
+		fmt.Fprintf(&out, "%s %s\n", ref.SHA, ref.Name)
+	}
+	out.WriteString("\n")
+	if _, err := encodePack(&out, objects, r.Format, r.startProgress("Writing objects", len(objects))); err != nil {
+		return err
+	}
+
+	if err := writeFileAtomic(path, out.Bytes()); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// readBundle parses a bundle file
+func readBundle(path string) (*Bundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bundle: %w", err)
+	}
+	v3 := bytes.HasPrefix(data, []byte(bundleV3Signature))
+	if !v3 && !bytes.HasPrefix(data, []byte(bundleSignature)) {
+		return nil, fmt.Errorf("%s is not a bundle", path)
+	}
+	data = data[len(bundleSignature):]
+
+	bundle := &Bundle{Format: object.SHA1}
+	for {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			return nil, fmt.Errorf("%s: truncated bundle header", path)
+		}
+		line := string(data[:end])
+		data = data[end+1:]
+		if line == "" {
+			break
+		}
+
+		if v3 && strings.HasPrefix(line, "@") {
+			if name, ok := strings.CutPrefix(line, "@object-format="); ok {
+				if bundle.Format, err = object.ParseFormat(name); err != nil {
+					return nil, fmt.Errorf("%s: %w", path, err)
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "-") {
+			sha := strings.SplitN(line[1:], " ", 2)[0]
+			if bundle.Format.ValidateSHA(sha) != nil {
+				return nil, fmt.Errorf("%s: bad prerequisite line: %s", path, line)
+			}
+			bundle.Prerequisites = append(bundle.Prerequisites, sha)
+			continue
+		}
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) != 2 || bundle.Format.ValidateSHA(parts[0]) != nil {
+			return nil, fmt.Errorf("%s: bad ref line: %s", path, line)
+		}
+		bundle.Refs = append(bundle.Refs, Ref{Name: parts[1], SHA: parts[0]})
+	}
+	bundle.Pack = data
+	return bundle, nil
+}
+
+// objectExists reports whether an object is stored loose or in a pack
+func (r *Repository) objectExists(sha string) bool {
+	if !r.usesFileStore() {
+		found, err := r.Objects.Has(sha)
+		return err == nil && found
+	}
+	if _, err := os.Stat(r.objectPath(sha)); err == nil {
+		return true
+	}
+	packed, err := r.hasPackedObject(sha)
+	return err == nil && packed
+}
+
+// CreateBundle writes the history selected by revision arguments such as
+// main, --all or v1.0..main to a bundle file, for moving it to a machine the
+// repository cannot reach. The refs among the arguments are recorded in the
+// bundle; commits left out with ^A or A.. that the history builds on become
+// prerequisites, which whoever reads the bundle must already have.
+func (r *Repository) CreateBundle(path string, args []string) error {
+	revRange, err := r.ParseRevRange(args)
+	if err != nil {
+		return err
+	}
+	refs, err := r.bundleRefs(args)
+	if err != nil {
+		return err
+	}
+	objects, err := r.reachableObjects(revRange.Include, revRange.Exclude)
+	if err != nil {
+		return err
+	}
+	if len(refs) == 0 || len(objects) == 0 {
+		return errors.New("refusing to create an empty bundle")
+	}
+
+	// The prerequisites are the excluded parents of the bundled commits
+	nodes, err := r.WalkRevisions(revRange)
+	if err != nil {
+		return err
+	}
+	bundled := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		bundled[node.SHA] = true
+	}
+	var prerequisites []string
+	for _, node := range nodes {
+		for _, parent := range node.Parents {
+			if !bundled[parent] && !containsString(prerequisites, parent) {
+				prerequisites = append(prerequisites, parent)
+			}
+		}
+	}
+	return r.writeBundle(path, refs, prerequisites, objects)
+}
+
+// bundleRefs returns the refs named by the positive revision arguments of
+// CreateBundle: ref names, the ref selectors and the right side of ranges.
+// Other revisions, such as HEAD~2, add history but no ref.
+func (r *Repository) bundleRefs(args []string) ([]Ref, error) {
+	var refs []Ref
+	add := func(ref Ref) {
+		for _, existing := range refs {
+			if existing.Name == ref.Name {
+				return
+			}
+		}
+		refs = append(refs, ref)
+	}
+	for _, arg := range args {
+		if selected, ok, err := r.selectRefs(arg); err != nil {
+			return nil, err
+		} else if ok {
+			for _, ref := range selected {
+				add(ref)
+			}
+			continue
+		}
+		if strings.HasPrefix(arg, "^") {
+			continue
+		}
+		if i := strings.LastIndex(arg, ".."); i >= 0 {
+			if arg = arg[i+2:]; arg == "" {
+				arg = "HEAD"
+			}
+		}
+		name, err := r.ExpandRefName(arg)
+		if err != nil {
+			continue // not a ref
+		}
+		sha, err := r.ResolveCommit(name)
+		if err != nil {
+			return nil, err
+		}
+		add(Ref{Name: name, SHA: sha})
+	}
+	return refs, nil
+}
+
+// VerifyBundle checks that a bundle is well formed, that its pack is intact
+// and that this repository has the commits it requires, returning it
+func (r *Repository) VerifyBundle(path string) (*Bundle, error) {
+	bundle, err := readBundle(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkBundlePrerequisites(path, bundle); err != nil {
+		return nil, err
+	}
+	objects, err := r.indexPack(bundle.Pack)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	packed := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		packed[obj.sha] = true
+	}
+	for _, ref := range bundle.Refs {
+		if !packed[ref.SHA] && !r.objectExists(ref.SHA) {
+			return nil, fmt.Errorf("%s: %s points to %s, which the bundle does not contain", path, ref.Name, ref.SHA[:7])
+		}
+	}
+	return bundle, nil
+}
+
+// checkBundlePrerequisites checks that a bundle matches the repository's
+// object format and that the commits it builds on are present
+func (r *Repository) checkBundlePrerequisites(path string, bundle *Bundle) error {
+	if bundle.Format != r.Format {
+		return fmt.Errorf("%s holds %s objects but this repository uses %s", path, bundle.Format.Name, r.Format.Name)
+	}
+	var missing []string
+	for _, sha := range bundle.Prerequisites {
+		if !r.objectExists(sha) {
+			missing = append(missing, sha)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%s requires commits this repository lacks:\n%s", path, strings.Join(missing, "\n"))
+	}
+	return nil
+}
+
+// Unbundle adds the objects of a bundle to the repository and returns the
+// refs it records, leaving the repository's own refs alone
+func (r *Repository) Unbundle(path string) ([]Ref, error) {
+	bundle, err := readBundle(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkBundlePrerequisites(path, bundle); err != nil {
+		return nil, err
+	}
+	_, objects, err := r.installPack(bundle.Pack)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if r.transfer != nil {
+		shas := make([]string, len(objects))
+		for i, o := range objects {
+			shas[i] = o.sha
+		}
+		r.transfer.addObjects(r, shas)
+		r.transfer.add(0, 0, int64(len(bundle.Pack)), 0)
+	}
+	return bundle.Refs, nil
+}
+
+// ReadBundleHeader reads the refs and prerequisites of a bundle without its pack
+func ReadBundleHeader(path string) (*Bundle, error) {
+	bundle, err := readBundle(path)
+	if err != nil {
+		return nil, err
+	}
+	bundle.Pack = nil
+	return bundle, nil
+}
+
+// isBundleFile reports whether path is a bundle file rather than a repository
+func isBundleFile(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	header := make([]byte, len(bundleSignature))
+	if _, err := io.ReadFull(f, header); err != nil {
+		return false
+	}
+	return string(header) == bundleSignature || string(header) == bundleV3Signature
+}
+
+// headBranch guesses the branch a bundle's HEAD was on: the branch pointing
+// at the same commit, preferring main. It returns "" when the bundle has no
+// HEAD or no branch matches it.
+func (b *Bundle) headBranch() string {
+	head := ""
+	for _, ref := range b.Refs {
+		if ref.Name == "HEAD" {
+			head = ref.SHA
+		}
+	}
+	branch := ""
+	for _, ref := range b.Refs {
+		if head == "" || ref.SHA != head || !strings.HasPrefix(ref.Name, HeadsDir+"/") {
+			continue
+		}
+		if ref.Name == HeadsDir+"/main" {
+			return ref.Name
+		}
+		if branch == "" {
+			branch = ref.Name
+		}
+	}
+	return branch
+}
@@ -0,0 +1,258 @@
+package gvc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// Changelog is the release notes for the commits between two revisions
+type Changelog struct {
+	From     string    // the revision the notes start after; "" for the whole history
+	To       string    // the revision they end at
+	Date     time.Time // when To was committed
+	Sections []ChangelogSection
+}
+
+// ChangelogSection is one group of changes, such as Features
+type ChangelogSection struct {
+	Title   string
+	Entries []ChangelogEntry // newest first
+}
+
+// ChangelogEntry is one commit in a changelog
+type ChangelogEntry struct {
+	Commit      *object.Commit
+	Type        string // the conventional-commit type, such as feat
+	Scope       string
+	Description string // the subject without type and scope
+	Breaking    string // what a breaking change breaks; "" for other changes
+}
+
+// conventionalTypes are the conventional-commit types a changelog groups
+// commits by, in the order their sections appear
+var conventionalTypes = []struct{ name, title string }{
+	{"feat", "Features"},
+	{"fix", "Bug Fixes"},
+	{"perf", "Performance Improvements"},
+	{"revert", "Reverts"},
+	{"refactor", "Code Refactoring"},
+	{"docs", "Documentation"},
+	{"test", "Tests"},
+	{"build", "Build System"},
+	{"ci", "Continuous Integration"},
+	{"style", "Styles"},
+	{"chore", "Chores"},
+}
+
+// conventionalSubject matches "type(scope)!: description"
+var conventionalSubject = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?: +(.+)$`)
+
+// changelogGroup is a section changelog.<title>.pattern configures
+type changelogGroup struct {
+	title   string
+	pattern *regexp.Regexp
+}
+
+// changelogGroups reads the sections configured as changelog.<title>.pattern,
+// in the order of the config, each taking the commits whose subject matches
+func (r *Repository) changelogGroups() ([]changelogGroup, error) {
+	entries, err := r.ReadConfigEntries()
+	if err != nil {
+		return nil, err
+	}
+	var groups []changelogGroup
+	for _, entry := range entries {
+		rest, ok := strings.CutPrefix(entry[0], "changelog.")
+		if !ok {
+			continue
+		}
+		title, ok := strings.CutSuffix(rest, ".pattern")
+		if !ok {
+			continue
+		}
+		pattern, err := regexp.Compile(entry[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", entry[0], err)
+		}
+		groups = append(groups, changelogGroup{title, pattern})
+	}
+	return groups, nil
+}
+
+// Changelog groups the commits after from up to to, merges left out, by
+// conventional-commit type (feat, fix, chore and so on), or by the sections
+// configured as changelog.<title>.pattern when there are any. Commits no
+// section takes are listed under Other Changes, and breaking changes, marked
+// with "type!:" or a BREAKING CHANGE trailer, are listed first as well. to
+// defaults to HEAD and from to the nearest tag before to.
+func (r *Repository) Changelog(from, to string) (*Changelog, error) {
+	if to == "" {
+		to = "HEAD"
+	}
+	toSHA, err := r.ResolveCommit(to)
+	if err != nil {
+		return nil, err
+	}
+	if from == "" {
+		if from, err = r.previousTag(toSHA); err != nil {
+			return nil, err
+		}
+	}
+	revRange := &RevRange{Include: []string{toSHA}}
+	if from != "" {
+		fromSHA, err := r.ResolveCommit(from)
+		if err != nil {
+			return nil, err
+		}
+		revRange.Exclude = []string{fromSHA}
+	}
+	nodes, err := r.WalkRevisions(revRange)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := r.changelogGroups()
+	if err != nil {
+		return nil, err
+	}
+	last, err := r.ReadCommit(toSHA)
+	if err != nil {
+		return nil, err
+	}
+
+	var titles []string
+	if len(groups) > 0 {
+		for _, group := range groups {
+			titles = append(titles, group.title)
+		}
+	} else {
+		for _, t := range conventionalTypes {
+			titles = append(titles, t.title)
+		}
+	}
+	titles = append(titles, "Other Changes")
+	sections := make(map[string][]ChangelogEntry)
+	var breaking []ChangelogEntry
+	for _, node := range nodes {
+		if len(node.Parents) > 1 {
+			continue
+		}
+		commit, err := r.ReadCommit(node.SHA)
+		if err != nil {
+			return nil, err
+		}
+		entry, title := parseChangelogEntry(commit, groups)
+		sections[title] = append(sections[title], entry)
+		if entry.Breaking != "" {
+			breaking = append(breaking, entry)
+		}
+	}
+
+	changelog := &Changelog{From: from, To: to, Date: last.Committed}
+	if len(breaking) > 0 {
+		changelog.Sections = append(changelog.Sections, ChangelogSection{"Breaking Changes", breaking})
+	}
+	for _, title := range titles {
+		if entries := sections[title]; len(entries) > 0 {
+			changelog.Sections = append(changelog.Sections, ChangelogSection{title, entries})
+		}
+	}
+	return changelog, nil
+}
+
+// parseChangelogEntry reads a commit's type, scope and description from its
+// subject and returns the title of the section it belongs in
+func parseChangelogEntry(commit *object.Commit, groups []changelogGroup) (ChangelogEntry, string) {
+	subject, _, _ := strings.Cut(commit.Message, "\n")
+	entry := ChangelogEntry{Commit: commit, Description: subject}
+	match := conventionalSubject.FindStringSubmatch(subject)
+	if match != nil {
+		entry.Type, entry.Scope, entry.Description = strings.ToLower(match[1]), match[2], match[4]
+		if match[3] != "" {
+			entry.Breaking = match[4]
+		}
+	}
+	for _, trailer := range object.ParseTrailers(commit.Message) {
+		if trailer.Key == "BREAKING CHANGE" || trailer.Key == "BREAKING-CHANGE" {
+			entry.Breaking = trailer.Value
+		}
+	}
+
+	if len(groups) > 0 {
+		for _, group := range groups {
+			if group.pattern.MatchString(subject) {
+				return entry, group.title
+			}
+		}
+		return entry, "Other Changes"
+	}
+	for _, t := range conventionalTypes {
+		if entry.Type == t.name {
+			return entry, t.title
+		}
+	}
+	return entry, "Other Changes"
+}
+
+// previousTag returns the tag on the nearest commit before sha, the highest
+// version when several tag it, or "" when no earlier commit is tagged
+func (r *Repository) previousTag(sha string) (string, error) {
+	tags, err := r.ListTags(TagListOptions{Sort: "-version:refname"})
+	if err != nil {
+		return "", err
+	}
+	tagged := make(map[string]string)
+	for _, tag := range tags {
+		if _, ok := tagged[tag.SHA]; !ok {
+			tagged[tag.SHA] = strings.TrimPrefix(tag.Name, TagsDir+"/")
+		}
+	}
+	if len(tagged) == 0 {
+		return "", nil
+	}
+	nodes, err := r.WalkRevisions(&RevRange{Include: []string{sha}})
+	if err != nil {
+		return "", err
+	}
+	for _, node := range nodes {
+		if name, ok := tagged[node.SHA]; ok && node.SHA != sha {
+			return name, nil
+		}
+	}
+	return "", nil
+}
+
+// Markdown renders the changelog as release notes: a "## <To> (<date>)"
+// heading, with Unreleased standing for HEAD, and a "### <title>" list per
+// section. entry renders one item; nil gives "**scope:** description (sha)".
+func (c *Changelog) Markdown(entry func(ChangelogEntry) string) string {
+	if entry == nil {
+		entry = func(e ChangelogEntry) string {
+			line := e.Description
+			if e.Scope != "" {
+				line = "**" + e.Scope + ":** " + line
+			}
+			return line + " (" + e.Commit.SHA[:min(7, len(e.Commit.SHA))] + ")"
+		}
+	}
+	title := c.To
+	if title == "HEAD" {
+		title = "Unreleased"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s (%s)\n", title, c.Date.Format("2006-01-02"))
+	for _, section := range c.Sections {
+		fmt.Fprintf(&b, "\n### %s\n\n", section.Title)
+		for _, e := range section.Entries {
+			line := entry(e)
+			if section.Title == "Breaking Changes" && e.Breaking != e.Description {
+				line += "\n  " + strings.ReplaceAll(e.Breaking, "\n", "\n  ")
+			}
+			fmt.Fprintf(&b, "- %s\n", line)
+		}
+	}
+	return b.String()
+}
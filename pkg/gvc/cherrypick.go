@@ -0,0 +1,185 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// CherryPickOptions controls CherryPickWithOptions
+type CherryPickOptions struct {
+	// NoCommit applies the change to the index and working tree without
+	// committing it. The change is made on top of whatever is staged, so
+	// several picks can be combined into one commit.
+	NoCommit bool
+}
+
+// CherryPick replays a commit onto HEAD, stopping with conflict markers when it does not apply cleanly
+func (r *Repository) CherryPick(rev string) error {
+	return r.CherryPickWithOptions(rev, CherryPickOptions{})
+}
+
+// CherryPickWithOptions replays a commit onto HEAD, or with NoCommit onto
+// the index. Conflicts a NoCommit pick leaves are staged with markers to be
+// resolved before committing; there is nothing to continue or abort.
+func (r *Repository) CherryPickWithOptions(rev string, opts CherryPickOptions) error {
+	if op := r.OperationInProgress(); op != "" {
+		return fmt.Errorf("a %s is already in progress; use --continue or --abort", op)
+	}
+
+	commitSHA, err := r.ResolveCommit(rev)
+	if err != nil {
+		return err
+	}
+	commit, err := r.ReadCommit(commitSHA)
+	if err != nil {
+		return err
+	}
+	parents, err := r.commitParents(commitSHA)
+	if err != nil {
+		return err
+	}
+	if len(parents) > 1 {
+		return fmt.Errorf("commit %s is a merge; cherry-picking merges is not supported", commitSHA[:7])
+	}
+
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	if headSHA == "" {
+		return errors.New("cannot cherry-pick onto an empty branch")
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	if opts.NoCommit {
+		// Only the working tree has to be clean: the pick goes on top of
+		// the staged changes, which may come from earlier picks
+		if head, err = r.stagedEntries(); err != nil {
+			return err
+		}
+		if err := r.checkUnstagedChanges(head); err != nil {
+			return err
+		}
+	} else if err := r.checkCleanState(head); err != nil {
+		return err
+	}
+
+	parentTree := ""
+	if len(parents) == 1 {
+		parentCommit, err := r.ReadCommit(parents[0])
+		if err != nil {
+			return err
+		}
+		parentTree = parentCommit.TreeSHA
+	}
+
+	subject := strings.SplitN(commit.Message, "\n", 2)[0]
+	label := fmt.Sprintf("%s (%s)", commitSHA[:7], subject)
+	conflicts, err := r.applyPick(parentTree, commit.TreeSHA, label, head)
+	if err != nil {
+		return err
+	}
+
+	if len(conflicts) > 0 && opts.NoCommit {
+		return conflictError(fmt.Errorf("could not apply %s... %s\nconflicts in:\n\t%s\n"+
+			"resolve them and 'gvc add' the files before committing",
+			commitSHA[:7], subject, strings.Join(conflicts, "\n\t")))
+	}
+	if opts.NoCommit {
+		return nil
+	}
+	if len(conflicts) > 0 {
+		if err := r.writeSequencerState(r.gitPath(CherryPickHeadFile), commitSHA, commit.Message, conflicts); err != nil {
+			return err
+		}
+		return conflictError(fmt.Errorf("could not apply %s... %s\nconflicts in:\n\t%s\n"+
+			"resolve them, 'gvc add' the files and run 'gvc cherry-pick --continue' (or --abort)",
+			commitSHA[:7], subject, strings.Join(conflicts, "\n\t")))
+	}
+
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+	_, err = r.commitStaged(staged, []string{headSHA}, commit.Author, commit.Timestamp, commit.Message, "cherry-pick: "+subject)
+	return err
+}
+
+// continuePick commits an interrupted cherry-pick or revert once its conflicts are
+// resolved. Cherry-picks keep the original author; reverts are authored by the user.
+func (r *Repository) continuePick(headFile, operation string, keepAuthor bool) error {
+	data, err := os.ReadFile(headFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("no %s in progress", operation)
+		}
+		return fmt.Errorf("failed to read %s: %w", filepath.Base(headFile), err)
+	}
+	commit, err := r.ReadCommit(strings.TrimSpace(string(data)))
+	if err != nil {
+		return err
+	}
+	message, err := os.ReadFile(r.gitPath(MergeMsgFile))
+	if err != nil {
+		return fmt.Errorf("failed to read merge message: %w", err)
+	}
+
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+	unresolved, err := r.unresolvedConflicts(staged)
+	if err != nil {
+		return err
+	}
+	if len(unresolved) > 0 {
+		return conflictError(fmt.Errorf("unresolved conflicts remain in:\n\t%s\nfix them and 'gvc add' the result",
+			strings.Join(unresolved, "\n\t")))
+	}
+
+	author, authorTime := r.authorIdent(), time.Now()
+	if keepAuthor {
+		author, authorTime = commit.Author, commit.Timestamp
+	}
+	text := strings.TrimSpace(string(message))
+	subject := strings.SplitN(text, "\n", 2)[0]
+	if _, err := r.commitStaged(staged, []string{headSHA}, author, authorTime, text, operation+": "+subject); err != nil {
+		return err
+	}
+	return r.clearSequencerState(headFile)
+}
+
+// abortPick restores the working tree and index to HEAD and forgets the interrupted operation
+func (r *Repository) abortPick(headFile, operation string) error {
+	if _, err := os.Stat(headFile); err != nil {
+		return fmt.Errorf("no %s in progress", operation)
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	if err := r.resetHard(head); err != nil {
+		return err
+	}
+	return r.clearSequencerState(headFile)
+}
+
+// ContinueCherryPick commits an interrupted cherry-pick once its conflicts are resolved
+func (r *Repository) ContinueCherryPick() error {
+	return r.continuePick(r.gitPath(CherryPickHeadFile), "cherry-pick", true)
+}
+
+// AbortCherryPick gives up an interrupted cherry-pick
+func (r *Repository) AbortCherryPick() error {
+	return r.abortPick(r.gitPath(CherryPickHeadFile), "cherry-pick")
+}
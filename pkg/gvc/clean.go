@@ -0,0 +1,139 @@
+package gvc
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CleanOptions selects what Clean removes
+type CleanOptions struct {
+	Directories bool     // remove untracked directories as a whole
+	Ignored     bool     // remove ignored files too
+	Paths       []string // limit to these paths, the whole tree when empty
+}
+
+// CleanPaths returns the untracked paths Clean would remove, sorted, with a
+// trailing "/" on directories. Files are untracked and ignored as status and
+// ls-files --others see them. Nested repositories are always kept. Without
+// Directories only files are listed, and untracked directories are not
+// looked into unless Paths are given; with it, an untracked directory
+// holding nothing else to keep is listed instead of its files.
+func (r *Repository) CleanPaths(opts CleanOptions) ([]string, error) {
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return nil, err
+	}
+	trackedDirs := make(map[string]bool)
+	for p := range staged {
+		for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+			trackedDirs[dir] = true
+		}
+	}
+	ig, err := r.LoadIgnore()
+	if err != nil {
+		return nil, err
+	}
+	c := &cleaner{repo: r, opts: opts, staged: staged, trackedDirs: trackedDirs, ignore: ig}
+	paths, _, err := c.visit("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan working tree: %w", err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// cleaner walks the working tree for CleanPaths
+type cleaner struct {
+	repo        *Repository
+	opts        CleanOptions
+	staged      map[string]IndexEntry
+	trackedDirs map[string]bool
+	ignore      *Ignore
+}
+
+// visit returns what can be removed below the slash-separated directory dir,
+// "" for the top of the working tree, and whether that is all dir holds
+func (c *cleaner) visit(dir string) ([]string, bool, error) {
+	entries, err := os.ReadDir(c.repo.worktreePath(dir))
+	if err != nil {
+		return nil, false, err
+	}
+	var paths []string
+	whole := true
+	for _, entry := range entries {
+		name, err := c.repo.cleanIndexPath(entry.Name())
+		if err != nil {
+			return nil, false, err
+		}
+		rel := path.Join(dir, name)
+		full := c.repo.worktreePath(rel)
+		isDir := entry.IsDir()
+		if isMetadataDir(entry.Name()) || full == c.repo.GitDir || isDir && isRepoRoot(full) ||
+			!isDir && !entry.Type().IsRegular() && entry.Type()&fs.ModeSymlink == 0 {
+			whole = false
+			continue
+		}
+		if _, ok := c.staged[rel]; ok {
+			whole = false
+			continue
+		}
+		ignored, err := c.ignore.Ignored(rel, isDir)
+		if err != nil {
+			return nil, false, err
+		}
+		if ignored && !c.opts.Ignored {
+			whole = false
+			continue
+		}
+
+		if !isDir {
+			if underPaths(rel, c.opts.Paths) {
+				paths = append(paths, rel)
+			} else {
+				whole = false
+			}
+			continue
+		}
+		tracked := c.trackedDirs[rel]
+		if !tracked && !c.opts.Directories && len(c.opts.Paths) == 0 {
+			whole = false
+			continue
+		}
+		sub, subWhole, err := c.visit(rel)
+		if err != nil {
+			return nil, false, err
+		}
+		if !tracked && c.opts.Directories && subWhole && underPaths(rel, c.opts.Paths) {
+			paths = append(paths, rel+"/")
+			continue
+		}
+		paths = append(paths, sub...)
+		whole = false
+	}
+	return paths, whole, nil
+}
+
+// Clean removes paths listed by CleanPaths from the working tree
+func (r *Repository) Clean(paths []string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	for _, p := range paths {
+		full := r.worktreePath(strings.TrimSuffix(p, "/"))
+		var err error
+		if strings.HasSuffix(p, "/") {
+			err = os.RemoveAll(full)
+		} else {
+			err = os.Remove(full)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", filepath.FromSlash(p), err)
+		}
+	}
+	return nil
+}
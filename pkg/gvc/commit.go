@@ -0,0 +1,290 @@
+package gvc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// CommitTree creates a commit object
+func (r *Repository) CommitTree(treeSHA, parentSHA, message string) (string, error) {
+	var parents []string
+	if parentSHA != "" {
+		parents = append(parents, parentSHA)
+	}
+	return r.commitTreeWithParents(treeSHA, parents, message)
+}
+
+// commitTreeWithParents creates a commit object with any number of parents
+func (r *Repository) commitTreeWithParents(treeSHA string, parents []string, message string) (string, error) {
+	return r.writeCommit(treeSHA, parents, r.authorIdent(), time.Now(), message)
+}
+
+// writeCommit creates a commit object, keeping the given authorship (e.g. when replaying a commit)
+func (r *Repository) writeCommit(treeSHA string, parents []string, author string, authorTime time.Time, message string) (string, error) {
+	content, err := r.buildCommit(treeSHA, parents, author, authorTime, message)
+	if err != nil {
+		return "", err
+	}
+	return r.WriteObject(object.CommitObject, content)
+}
+
+// buildCommit encodes a commit object without storing it
+func (r *Repository) buildCommit(treeSHA string, parents []string, author string, authorTime time.Time, message string) ([]byte, error) {
+	if err := r.Format.ValidateSHA(treeSHA); err != nil {
+		return nil, fmt.Errorf("invalid tree SHA: %w", err)
+	}
+
+	for _, parentSHA := range parents {
+		if err := r.Format.ValidateSHA(parentSHA); err != nil {
+			return nil, fmt.Errorf("invalid parent SHA: %w", err)
+		}
+	}
+
+	committer := r.authorIdent()
+	authorTimestamp := fmt.Sprintf("%d +0000", authorTime.Unix())
+	timestamp := fmt.Sprintf("%d +0000", time.Now().Unix())
+
+	var commitContent bytes.Buffer
+	commitContent.WriteString(fmt.Sprintf("tree %s\n", treeSHA))
+	for _, parentSHA := range parents {
+		commitContent.WriteString(fmt.Sprintf("parent %s\n", parentSHA))
+	}
+	commitContent.WriteString(fmt.Sprintf("author %s %s\ncommitter %s %s\n\n%s\n",
+		author, authorTimestamp, committer, timestamp, message))
+
+	return commitContent.Bytes(), nil
+}
+
+// authorIdent returns the identity recorded in commits and ref logs
+func (r *Repository) authorIdent() string {
+	return "gvc <Ritik Chauhan> <critik1704@gmail.com>"
+}
+
+// ReadCommit reads and parses a commit object. A shallow commit is read
+// without its parents, which the repository does not have.
+func (r *Repository) ReadCommit(commitSHA string) (*object.Commit, error) {
+	objectType, content, err := r.ReadObject(commitSHA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", commitSHA, err)
+	}
+	if objectType != object.CommitObject {
+		return nil, fmt.Errorf("expected commit object, got %s", objectType)
+	}
+	commit, err := object.ParseCommit(commitSHA, content)
+	if err != nil {
+		return nil, err
+	}
+	if shallow, err := r.isShallowCommit(commitSHA); err != nil {
+		return nil, err
+	} else if shallow {
+		commit.ParentSHA, commit.Parents = "", nil
+	}
+	return commit, nil
+}
+
+// commitParents returns every parent recorded in a commit object, none for
+// a shallow commit
+func (r *Repository) commitParents(commitSHA string) ([]string, error) {
+	if shallow, err := r.isShallowCommit(commitSHA); err != nil || shallow {
+		return nil, err
+	}
+	_, content, err := r.ReadObject(commitSHA)
+	if err != nil {
+		return nil, err
+	}
+	var parents []string
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "parent ") {
+			parents = append(parents, strings.TrimPrefix(line, "parent "))
+		}
+	}
+	return parents, nil
+}
+
+// CommitOptions controls how Commit records a commit
+type CommitOptions struct {
+	// Sign embeds a signature made with user.signingKey; commit.gpgSign turns it on by default
+	Sign bool
+	// NoSign overrides commit.gpgSign
+	NoSign bool
+	// NoVerify skips the pre-commit and commit-msg hooks and the commit.conventional
+	// message policy unless hooks.allowNoVerify forbids it
+	NoVerify bool
+	// All restages every tracked file, including deletions, before committing
+	All bool
+	// Amend replaces the current commit instead of adding one on top of it,
+	// keeping its parents and author; an empty message keeps its message too
+	Amend bool
+	// AllowSecrets commits even when the secret scanner, which secrets.scan
+	// turns off, finds credentials in the staged changes
+	AllowSecrets bool
+}
+
+// CommitPreview lists the changes a commit made with opts would record,
+// against HEAD or, when amending, against the parent of HEAD, without writing
+// anything. With All, the working tree copies of tracked files count as
+// staged, as they would be.
+func (r *Repository) CommitPreview(opts CommitOptions) ([]FileChange, error) {
+	if op := r.OperationInProgress(); op != "" {
+		return nil, fmt.Errorf("a %s is in progress; finish it with 'gvc %s --continue' or '--abort'", op, op)
+	}
+	base, err := r.headEntries()
+	if err != nil {
+		return nil, err
+	}
+	if opts.Amend {
+		head, err := r.HeadCommit()
+		if err != nil {
+			return nil, err
+		}
+		if head == "" {
+			return nil, errors.New("nothing to amend: no commits yet")
+		}
+		commit, err := r.ReadCommit(head)
+		if err != nil {
+			return nil, err
+		}
+		base = make(map[string]IndexEntry)
+		if len(commit.Parents) > 0 {
+			if base, err = r.revisionEntries(commit.Parents[0]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return nil, err
+	}
+	if opts.All {
+		indexTime := r.indexModTime()
+		for path, entry := range staged {
+			working, onDisk, err := r.readWorkingEntryCached(path, entry, indexTime)
+			if err != nil {
+				return nil, err
+			}
+			if onDisk {
+				staged[path] = working
+			} else {
+				delete(staged, path)
+			}
+		}
+	}
+	return diffEntries(base, staged), nil
+}
+
+// Commit records the staged changes as a new commit on the current branch and returns its SHA
+func (r *Repository) Commit(message string) (string, error) {
+	return r.CommitWithOptions(message, CommitOptions{})
+}
+
+// CommitWithOptions records the staged changes as a new commit on the current branch and returns its SHA
+func (r *Repository) CommitWithOptions(message string, opts CommitOptions) (string, error) {
+	if op := r.OperationInProgress(); op != "" {
+		return "", fmt.Errorf("a %s is in progress; finish it with 'gvc %s --continue' or '--abort'", op, op)
+	}
+
+	if opts.All {
+		if err := r.AddWithOptions(AddOptions{Update: true}); err != nil {
+			return "", err
+		}
+	}
+
+	// Get current commit as parent
+	parentSHA, err := r.HeadCommit()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current commit: %w", err)
+	}
+	var parents []string
+	if parentSHA != "" {
+		parents = append(parents, parentSHA)
+	}
+	author, authorTime := r.authorIdent(), time.Now()
+	var amended *object.Commit
+	if opts.Amend {
+		if parentSHA == "" {
+			return "", errors.New("nothing to amend: no commits yet")
+		}
+		if amended, err = r.ReadCommit(parentSHA); err != nil {
+			return "", err
+		}
+		parents, author, authorTime = amended.Parents, amended.Author, amended.Timestamp
+		if strings.TrimSpace(message) == "" {
+			message = amended.Message
+		}
+	}
+
+	message, err = r.runCommitHooks(message, opts.NoVerify)
+	if err != nil {
+		return "", err
+	}
+	// The message policy stands in for a commit-msg hook, so it is skipped alike
+	if !opts.NoVerify {
+		if err := r.CheckCommitMessage(message); err != nil {
+			return "", err
+		}
+	}
+	if err := r.checkSecrets(opts.AllowSecrets); err != nil {
+		return "", err
+	}
+
+	// Create tree from current index, which must differ from HEAD unless amending
+	treeSHA, err := r.createTreeFromIndex()
+	if err != nil {
+		return "", err
+	}
+	if amended == nil && parentSHA != "" {
+		parent, err := r.ReadCommit(parentSHA)
+		if err != nil {
+			return "", err
+		}
+		if parent.TreeSHA == treeSHA {
+			return "", errors.New("nothing to commit (no changes staged since HEAD)")
+		}
+	}
+
+	sign := opts.Sign
+	if !sign && !opts.NoSign {
+		if sign, err = r.getConfigBool("commit.gpgSign", false); err != nil {
+			return "", err
+		}
+	}
+
+	// Create commit object
+	content, err := r.buildCommit(treeSHA, parents, author, authorTime, message)
+	if err != nil {
+		return "", err
+	}
+	if sign {
+		if content, err = r.signCommit(content); err != nil {
+			return "", err
+		}
+	}
+	commitSHA, err := r.WriteObject(object.CommitObject, content)
+	if err != nil {
+		return "", err
+	}
+
+	// Update branch reference
+	reflogMessage := "commit: " + strings.SplitN(message, "\n", 2)[0]
+	if amended != nil {
+		reflogMessage = "commit (amend): " + strings.SplitN(message, "\n", 2)[0]
+	} else if parentSHA == "" {
+		reflogMessage = "commit (initial): " + strings.SplitN(message, "\n", 2)[0]
+	}
+	if err := r.UpdateHead(commitSHA, reflogMessage); err != nil {
+		return "", fmt.Errorf("failed to update branch: %w", err)
+	}
+	r.runPostCommitHook()
+	r.notifyCommit(commitSHA)
+
+	return commitSHA, nil
+}
@@ -0,0 +1,461 @@
+package gvc
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+const CommitGraphFile = "objects/info/commit-graph"
+
+// GenerationInfinity marks commits that are not in the commit-graph
+const GenerationInfinity = uint32(0xffffffff)
+
+const (
+	graphParentNone    = 0x70000000
+	graphParentOctopus = 0x80000000
+	graphLastEdge      = 0x80000000
+)
+
+// CommitNode is the ancestry information needed to walk history
+type CommitNode struct {
+	SHA        string
+	Parents    []string
+	Generation uint32
+	Time       int64 // commit date, in Unix seconds
+	graphIndex int   // position in the commit-graph plus one; 0 for commits not in it
+}
+
+// loadCommitGraph reads the commit-graph file once per repository handle; a
+// missing file is not an error. A shallow repository ignores the file, whose
+// parents may be ones it lacks.
+func (r *Repository) loadCommitGraph() (map[string]*CommitNode, error) {
+	if r.commitGraphLoaded {
+		return r.commitGraph, nil
+	}
+	r.commitGraphLoaded = true
+	if shallow, err := r.IsShallow(); err != nil || shallow {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(r.gitPath(CommitGraphFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read commit-graph: %w", err)
+	}
+	graph, err := parseCommitGraph(data, r.Format)
+	if err != nil {
+		return nil, err
+	}
+	r.commitGraph = graph
+	return graph, nil
+}
+
+// parseCommitGraph decodes git's commit-graph format (version 1) for object IDs of format
+func parseCommitGraph(data []byte, format *object.Format) (map[string]*CommitNode, error) {
+	corrupt := errors.New("corrupt commit-graph")
+	hashSize := format.Size
+	if len(data) < 8+12+hashSize || string(data[:4]) != "CGPH" || data[4] != 1 {
+		return nil, corrupt
+	}
+	if data[5] != format.Version {
+		return nil, fmt.Errorf("commit-graph uses hash version %d, expected %d", data[5], format.Version)
+	}
+	sum := format.New()
+	sum.Write(data[:len(data)-hashSize])
+	if !bytes.Equal(sum.Sum(nil), data[len(data)-hashSize:]) {
+		return nil, errors.New("commit-graph checksum mismatch")
+	}
+
+	chunks := make(map[string][]byte)
+	numChunks := int(data[6])
+	for i := 0; i < numChunks; i++ {
+		entry := 8 + i*12
+		if entry+24 > len(data) {
+			return nil, corrupt
+		}
+		id := string(data[entry : entry+4])
+		start := binary.BigEndian.Uint64(data[entry+4:])
+		end := binary.BigEndian.Uint64(data[entry+16:])
+		if start > end || end > uint64(len(data)) {
+			return nil, corrupt
+		}
+		chunks[id] = data[start:end]
+	}
+
+	oids, cdat := chunks["OIDL"], chunks["CDAT"]
+	// Each CDAT row is the tree ID followed by two parent positions and the generation and date
+	rowSize := hashSize + 16
+	count := len(oids) / hashSize
+	if len(cdat) != count*rowSize {
+		return nil, corrupt
+	}
+	shas := make([]string, count)
+	for i := range shas {
+		shas[i] = hex.EncodeToString(oids[i*hashSize : (i+1)*hashSize])
+	}
+
+	edges := chunks["EDGE"]
+	graph := make(map[string]*CommitNode, count)
+	for i, sha := range shas {
+		row := cdat[i*rowSize+hashSize:]
+		node := &CommitNode{SHA: sha, graphIndex: i + 1}
+		parent1 := binary.BigEndian.Uint32(row)
+		parent2 := binary.BigEndian.Uint32(row[4:])
+		if parent1 != graphParentNone {
+			if int(parent1) >= count {
+				return nil, corrupt
+			}
+			node.Parents = append(node.Parents, shas[parent1])
+		}
+		switch {
+		case parent2 == graphParentNone:
+		case parent2&graphParentOctopus != 0:
+			for e := int(parent2 &^ graphParentOctopus); ; e++ {
+				if (e+1)*4 > len(edges) {
+					return nil, corrupt
+				}
+				edge := binary.BigEndian.Uint32(edges[e*4:])
+				if int(edge&^graphLastEdge) >= count {
+					return nil, corrupt
+				}
+				node.Parents = append(node.Parents, shas[edge&^graphLastEdge])
+				if edge&graphLastEdge != 0 {
+					break
+				}
+			}
+		default:
+			if int(parent2) >= count {
+				return nil, corrupt
+			}
+			node.Parents = append(node.Parents, shas[parent2])
+		}
+		genTime := binary.BigEndian.Uint64(row[8:])
+		node.Generation = uint32(genTime >> 34)
+		node.Time = int64(genTime & (1<<34 - 1))
+		graph[sha] = node
+	}
+	return graph, nil
+}
+
+// LookupCommitNode returns ancestry data for a commit, from the commit-graph when possible
+func (r *Repository) LookupCommitNode(sha string) (*CommitNode, error) {
+	graph, err := r.loadCommitGraph()
+	if err != nil {
+		return nil, err
+	}
+	if node, ok := graph[sha]; ok {
+		return node, nil
+	}
+	if node, ok := r.commitNodeCache[sha]; ok {
+		return node, nil
+	}
+
+	commit, err := r.ReadCommit(sha)
+	if err != nil {
+		return nil, err
+	}
+	parents, err := r.commitParents(sha)
+	if err != nil {
+		return nil, err
+	}
+	node := &CommitNode{SHA: sha, Parents: parents, Generation: GenerationInfinity, Time: commit.Committed.Unix()}
+	r.commitNodeCache[sha] = node
+	return node, nil
+}
+
+// WriteCommitGraph records every commit reachable from the refs with its generation number
+func (r *Repository) WriteCommitGraph() (int, error) {
+	if err := r.checkWritable(); err != nil {
+		return 0, err
+	}
+	if shallow, err := r.IsShallow(); err != nil {
+		return 0, err
+	} else if shallow {
+		return 0, errors.New("cannot write a commit-graph in a shallow repository")
+	}
+	tips, err := r.allRefTips()
+	if err != nil {
+		return 0, err
+	}
+
+	// Collect reachable commits straight from the objects, not an older graph
+	nodes := make(map[string]*CommitNode)
+	stack := append([]string(nil), tips...)
+	for len(stack) > 0 {
+		sha := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := nodes[sha]; ok {
+			continue
+		}
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return 0, err
+		}
+		parents, err := r.commitParents(sha)
+		if err != nil {
+			return 0, err
+		}
+		nodes[sha] = &CommitNode{SHA: sha, Parents: parents, Time: commit.Committed.Unix()}
+		stack = append(stack, parents...)
+	}
+
+	// Generation: 1 for roots, otherwise one more than the highest parent
+	var generation func(sha string) uint32
+	generation = func(sha string) uint32 {
+		node := nodes[sha]
+		if node.Generation != 0 {
+			return node.Generation
+		}
+		gen := uint32(1)
+		for _, parent := range node.Parents {
+			if g := generation(parent) + 1; g > gen {
+				gen = g
+			}
+		}
+		node.Generation = gen
+		return gen
+	}
+
+	shas := make([]string, 0, len(nodes))
+	for sha := range nodes {
+		shas = append(shas, sha)
+	}
+	sort.Strings(shas)
+	position := make(map[string]uint32, len(shas))
+	for i, sha := range shas {
+		position[sha] = uint32(i)
+		generation(sha)
+	}
+
+	var fanout [256]uint32
+	var oidl, cdat, edge bytes.Buffer
+	for _, sha := range shas {
+		node := nodes[sha]
+		shaBytes, _ := hex.DecodeString(sha)
+		oidl.Write(shaBytes)
+		first, _ := strconv.ParseUint(sha[:2], 16, 8)
+		for b := int(first); b < 256; b++ {
+			fanout[b]++
+		}
+
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return 0, err
+		}
+		treeBytes, _ := hex.DecodeString(commit.TreeSHA)
+		cdat.Write(treeBytes)
+
+		parent1, parent2 := uint32(graphParentNone), uint32(graphParentNone)
+		if len(node.Parents) > 0 {
+			parent1 = position[node.Parents[0]]
+		}
+		if len(node.Parents) == 2 {
+			parent2 = position[node.Parents[1]]
+		} else if len(node.Parents) > 2 {
+			parent2 = graphParentOctopus | uint32(edge.Len()/4)
+			for i, parent := range node.Parents[1:] {
+				value := position[parent]
+				if i == len(node.Parents)-2 {
+					value |= graphLastEdge
+				}
+				binary.Write(&edge, binary.BigEndian, value)
+			}
+		}
+		binary.Write(&cdat, binary.BigEndian, parent1)
+		binary.Write(&cdat, binary.BigEndian, parent2)
+		binary.Write(&cdat, binary.BigEndian, uint64(node.Generation)<<34|uint64(node.Time)&(1<<34-1))
+	}
+
+	var fanoutBytes bytes.Buffer
+	binary.Write(&fanoutBytes, binary.BigEndian, fanout)
+	chunks := []struct {
+		id   string
+		data []byte
+	}{
+		{"OIDF", fanoutBytes.Bytes()},
+		{"OIDL", oidl.Bytes()},
+		{"CDAT", cdat.Bytes()},
+	}
+	if edge.Len() > 0 {
+		chunks = append(chunks, struct {
+			id   string
+			data []byte
+		}{"EDGE", edge.Bytes()})
+	}
+
+	var out bytes.Buffer
+	out.WriteString("CGPH")
+	out.Write([]byte{1, r.Format.Version, byte(len(chunks)), 0})
+	offset := uint64(8 + (len(chunks)+1)*12)
+	for _, chunk := range chunks {
+		out.WriteString(chunk.id)
+		binary.Write(&out, binary.BigEndian, offset)
+		offset += uint64(len(chunk.data))
+	}
+	out.Write([]byte{0, 0, 0, 0})
+	binary.Write(&out, binary.BigEndian, offset)
+	for _, chunk := range chunks {
+		out.Write(chunk.data)
+	}
+	sum := r.Format.New()
+	sum.Write(out.Bytes())
+	out.Write(sum.Sum(nil))
+
+	if err := r.mkdirShared(filepath.Dir(r.gitPath(CommitGraphFile))); err != nil {
+		return 0, fmt.Errorf("failed to create info directory: %w", err)
+	}
+	if err := r.writeGitFile(r.gitPath(CommitGraphFile), out.Bytes()); err != nil {
+		return 0, fmt.Errorf("failed to write commit-graph: %w", err)
+	}
+	r.commitGraphLoaded = false
+	return len(shas), nil
+}
+
+// commitQueue orders commits by generation, then commit time, highest first
+type commitQueue []*CommitNode
+
+func (q commitQueue) Len() int { return len(q) }
+func (q commitQueue) Less(i, j int) bool {
+	if q[i].Generation != q[j].Generation {
+		return q[i].Generation > q[j].Generation
+	}
+	return q[i].Time > q[j].Time
+}
+func (q commitQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *commitQueue) Push(x any)   { *q = append(*q, x.(*CommitNode)) }
+func (q *commitQueue) Pop() any {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
+
+// IsAncestor reports whether ancestor is reachable from descendant. Commits whose
+// generation is not above the ancestor's cannot reach it, so they are not expanded.
+func (r *Repository) IsAncestor(ancestor, descendant string) (bool, error) {
+	if ancestor == descendant {
+		return true, nil
+	}
+	target, err := r.LookupCommitNode(ancestor)
+	if err != nil {
+		return false, err
+	}
+
+	seen := map[string]bool{descendant: true}
+	stack := []string{descendant}
+	for len(stack) > 0 {
+		sha := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if sha == ancestor {
+			return true, nil
+		}
+		node, err := r.LookupCommitNode(sha)
+		if err != nil {
+			return false, err
+		}
+		if target.Generation != GenerationInfinity && node.Generation <= target.Generation {
+			continue
+		}
+		for _, parent := range node.Parents {
+			if !seen[parent] {
+				seen[parent] = true
+				stack = append(stack, parent)
+			}
+		}
+	}
+	return false, nil
+}
+
+// MergeBases returns the best common ancestors of two commits
+func (r *Repository) MergeBases(a, b string) ([]string, error) {
+	const (
+		fromA = 1 << iota
+		fromB
+		stale
+	)
+
+	marks := make(map[string]int)
+	queue := &commitQueue{}
+	for _, start := range []struct {
+		sha  string
+		flag int
+	}{{a, fromA}, {b, fromB}} {
+		node, err := r.LookupCommitNode(start.sha)
+		if err != nil {
+			return nil, err
+		}
+		if marks[start.sha] == 0 {
+			heap.Push(queue, node)
+		}
+		marks[start.sha] |= start.flag
+	}
+
+	// Paint commits by which side reaches them until only stale commits remain
+	var candidates []string
+	for queue.Len() > 0 {
+		active := false
+		for _, node := range *queue {
+			if marks[node.SHA]&stale == 0 {
+				active = true
+				break
+			}
+		}
+		if !active {
+			break
+		}
+
+		node := heap.Pop(queue).(*CommitNode)
+		flags := marks[node.SHA] & (fromA | fromB | stale)
+		if flags == fromA|fromB {
+			candidates = append(candidates, node.SHA)
+			flags |= stale
+			marks[node.SHA] |= stale
+		}
+		for _, parent := range node.Parents {
+			if marks[parent]&flags == flags {
+				continue
+			}
+			parentNode, err := r.LookupCommitNode(parent)
+			if err != nil {
+				return nil, err
+			}
+			marks[parent] |= flags
+			heap.Push(queue, parentNode)
+		}
+	}
+
+	// Drop candidates that are ancestors of other candidates
+	var bases []string
+	for i, candidate := range candidates {
+		redundant := false
+		for j, other := range candidates {
+			if i == j {
+				continue
+			}
+			reachable, err := r.IsAncestor(candidate, other)
+			if err != nil {
+				return nil, err
+			}
+			if reachable {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			bases = append(bases, candidate)
+		}
+	}
+	return bases, nil
+}
@@ -0,0 +1,157 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// commitPolicy is the conventional-commit message policy commit.conventional
+// turns on
+type commitPolicy struct {
+	types         []string // allowed types; commit.types
+	scopes        []string // allowed scopes, any when empty; commit.scopes
+	subjectMax    int      // longest first line, 0 for any; commit.subjectMaxLength
+	bodyLineMax   int      // longest body line, 0 for any; commit.bodyMaxLineLength
+	requireScopes bool     // commit.scopeRequired
+}
+
+// loadCommitPolicy reads the commit message policy, or nil when
+// commit.conventional is off
+func (r *Repository) loadCommitPolicy() (*commitPolicy, error) {
+	enabled, err := r.getConfigBool("commit.conventional", false)
+	if err != nil || !enabled {
+		return nil, err
+	}
+	policy := &commitPolicy{}
+	for _, t := range conventionalTypes {
+		policy.types = append(policy.types, t.name)
+	}
+	if types, err := r.getConfigString("commit.types", ""); err != nil {
+		return nil, err
+	} else if types != "" {
+		policy.types = splitConfigList(types)
+	}
+	scopes, err := r.getConfigString("commit.scopes", "")
+	if err != nil {
+		return nil, err
+	}
+	policy.scopes = splitConfigList(scopes)
+	if policy.requireScopes, err = r.getConfigBool("commit.scopeRequired", false); err != nil {
+		return nil, err
+	}
+	subjectMax, err := r.getConfigInt("commit.subjectMaxLength", 72)
+	if err != nil {
+		return nil, err
+	}
+	bodyLineMax, err := r.getConfigInt("commit.bodyMaxLineLength", 100)
+	if err != nil {
+		return nil, err
+	}
+	policy.subjectMax, policy.bodyLineMax = int(subjectMax), int(bodyLineMax)
+	return policy, nil
+}
+
+// splitConfigList splits a setting listing names separated by commas or spaces
+func splitConfigList(value string) []string {
+	return strings.FieldsFunc(value, func(c rune) bool { return c == ',' || c == ' ' || c == '\t' })
+}
+
+// CheckCommitMessage checks a message against the conventional-commit policy
+// commit.conventional turns on, returning nil when it is off or the message
+// follows it. The subject must read "type(scope): description", with a type
+// from commit.types (feat, fix, docs, chore and the other conventional types
+// by default), a scope from commit.scopes when that is set and required with
+// commit.scopeRequired, and an optional "!" for a breaking change. It may be
+// at most commit.subjectMaxLength characters (72) and must not end with a
+// period; a blank line separates it from the body, whose lines are wrapped
+// at commit.bodyMaxLineLength (100), except those without a space, such as
+// URLs. Messages gvc writes itself for merges, reverts, fixups and squashes
+// are accepted as they are. The error lists every problem found.
+func (r *Repository) CheckCommitMessage(message string) error {
+	policy, err := r.loadCommitPolicy()
+	if err != nil || policy == nil {
+		return err
+	}
+	problems := policy.check(message)
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("commit message does not follow the conventional commit policy (commit.conventional):\n  - " +
+		strings.Join(problems, "\n  - "))
+}
+
+// generatedSubjects start the subjects of messages gvc writes itself
+var generatedSubjects = []string{"Merge ", "Revert \"", "fixup! ", "squash! ", "amend! "}
+
+// check returns what is wrong with message under the policy
+func (p *commitPolicy) check(message string) []string {
+	lines := strings.Split(strings.TrimRight(message, "\n"), "\n")
+	subject := lines[0]
+	for _, prefix := range generatedSubjects {
+		if strings.HasPrefix(subject, prefix) {
+			return nil
+		}
+	}
+	problems := p.checkSubject(subject)
+	if p.subjectMax > 0 {
+		if n := utf8.RuneCountInString(subject); n > p.subjectMax {
+			problems = append(problems, fmt.Sprintf("the subject is %d characters long; the limit is %d (commit.subjectMaxLength)", n, p.subjectMax))
+		}
+	}
+	if len(lines) > 1 && strings.TrimSpace(lines[1]) != "" {
+		problems = append(problems, "line 2 must be blank, separating the subject from the body")
+	}
+	if p.bodyLineMax > 0 {
+		for i, line := range lines[1:] {
+			n := utf8.RuneCountInString(line)
+			if n > p.bodyLineMax && strings.ContainsAny(strings.TrimSpace(line), " \t") {
+				problems = append(problems, fmt.Sprintf("line %d is %d characters long; wrap the body at %d (commit.bodyMaxLineLength)", i+2, n, p.bodyLineMax))
+			}
+		}
+	}
+	return problems
+}
+
+// checkSubject returns what is wrong with the form of a subject
+func (p *commitPolicy) checkSubject(subject string) []string {
+	example := "e.g. 'fix(parser): handle empty input'"
+	head, description, ok := strings.Cut(subject, ":")
+	if !ok {
+		return []string{fmt.Sprintf("the subject must start with a type and a colon, %s", example)}
+	}
+	var problems []string
+	head = strings.TrimSuffix(head, "!")
+	commitType, scope, hasScope := strings.Cut(head, "(")
+	if hasScope {
+		var closed bool
+		if scope, closed = strings.CutSuffix(scope, ")"); !closed || strings.ContainsAny(scope, "()") {
+			return []string{fmt.Sprintf("malformed scope in '%s'; write it as 'type(scope):', %s", head, example)}
+		}
+	}
+	switch {
+	case commitType == "":
+		problems = append(problems, fmt.Sprintf("the subject must start with a type, %s", example))
+	case !slices.Contains(p.types, commitType):
+		problems = append(problems, fmt.Sprintf("unknown type '%s'; use one of %s (commit.types)", commitType, strings.Join(p.types, ", ")))
+	}
+	switch {
+	case hasScope && strings.TrimSpace(scope) == "":
+		problems = append(problems, "the scope in '()' is empty")
+	case !hasScope && p.requireScopes:
+		problems = append(problems, fmt.Sprintf("a scope is required (commit.scopeRequired), %s", example))
+	case hasScope && len(p.scopes) > 0 && !slices.Contains(p.scopes, scope):
+		problems = append(problems, fmt.Sprintf("unknown scope '%s'; use one of %s (commit.scopes)", scope, strings.Join(p.scopes, ", ")))
+	}
+	switch {
+	case strings.TrimSpace(description) == "":
+		problems = append(problems, "the description after ':' is empty")
+	case !strings.HasPrefix(description, " ") || strings.HasPrefix(description, "  "):
+		problems = append(problems, "put exactly one space after ':'")
+	case strings.HasSuffix(description, "."):
+		problems = append(problems, "the subject must not end with a period")
+	}
+	return problems
+}
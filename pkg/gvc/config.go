@@ -0,0 +1,303 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const ConfigFile = "config"
+
+// splitConfigKey splits "section.key" or "section.sub.key" into its parts
+func splitConfigKey(key string) (section, subsection, name string, err error) {
+	first := strings.Index(key, ".")
+	last := strings.LastIndex(key, ".")
+	if first <= 0 || last == len(key)-1 {
+		return "", "", "", fmt.Errorf("invalid config key: %s", key)
+	}
+	section = strings.ToLower(key[:first])
+	name = strings.ToLower(key[last+1:])
+	if first != last {
+		subsection = key[first+1 : last]
+	}
+	return section, subsection, name, nil
+}
+
+// parseConfigSection parses a "[section]" or `[section "sub"]` header line
+func parseConfigSection(line string) (section, subsection string) {
+	inner := strings.TrimSpace(line[1 : len(line)-1])
+	if i := strings.Index(inner, " "); i >= 0 {
+		return strings.ToLower(inner[:i]), strings.Trim(strings.TrimSpace(inner[i+1:]), `"`)
+	}
+	return strings.ToLower(inner), ""
+}
+
+// configKey builds the canonical lookup key for a setting
+func configKey(section, subsection, name string) string {
+	if subsection == "" {
+		return section + "." + name
+	}
+	return section + "." + subsection + "." + name
+}
+
+// ReadConfigEntries returns every setting in the repository config, in file order
+func (r *Repository) ReadConfigEntries() ([][2]string, error) {
+	data, err := r.readGitFile(r.gitPath(ConfigFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return parseConfigEntries(string(data)), nil
+}
+
+// parseConfigEntries reads the settings of a file in config format, in file order
+func parseConfigEntries(data string) [][2]string {
+	var entries [][2]string
+	section, subsection := "", ""
+	for _, raw := range strings.Split(data, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' && strings.HasSuffix(line, "]") {
+			section, subsection = parseConfigSection(line)
+			continue
+		}
+		name, value, found := strings.Cut(line, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if !found {
+			value = "true" // a bare key is a boolean flag
+		}
+		value = strings.Trim(value, `"`)
+		entries = append(entries, [2]string{configKey(section, subsection, name), value})
+	}
+	return entries
+}
+
+// GetConfig returns the last value set for key
+func (r *Repository) GetConfig(key string) (string, bool, error) {
+	section, subsection, name, err := splitConfigKey(key)
+	if err != nil {
+		return "", false, err
+	}
+	entries, err := r.ReadConfigEntries()
+	if err != nil {
+		return "", false, err
+	}
+
+	want := configKey(section, subsection, name)
+	value, found := "", false
+	for _, entry := range entries {
+		if entry[0] == want {
+			value, found = entry[1], true
+		}
+	}
+	return value, found, nil
+}
+
+// getConfigInt returns an integer setting, accepting k/m/g suffixes
+func (r *Repository) getConfigInt(key string, fallback int64) (int64, error) {
+	value, found, err := r.GetConfig(key)
+	if err != nil || !found {
+		return fallback, err
+	}
+	n, err := ParseSize(value)
+	if err != nil {
+		return 0, fmt.Errorf("bad value for %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// getConfigString returns a setting, or fallback when it is not set
+func (r *Repository) getConfigString(key, fallback string) (string, error) {
+	value, found, err := r.GetConfig(key)
+	if err != nil || !found {
+		return fallback, err
+	}
+	return value, nil
+}
+
+// getConfigBool returns a boolean setting, accepting true/false, yes/no, on/off and 1/0
+func (r *Repository) getConfigBool(key string, fallback bool) (bool, error) {
+	value, found, err := r.GetConfig(key)
+	if err != nil || !found {
+		return fallback, err
+	}
+	return parseConfigBool(key, value)
+}
+
+// parseConfigBool reads a boolean setting the way Git spells them
+func parseConfigBool(key, value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on", "1":
+		return true, nil
+	case "false", "no", "off", "0", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("bad boolean value for %s: %s", key, value)
+}
+
+// ParseSize parses a number with an optional k, m or g suffix
+func ParseSize(value string) (int64, error) {
+	if value == "" {
+		return 0, errors.New("empty size")
+	}
+	multiplier := int64(1)
+	switch strings.ToLower(value[len(value)-1:]) {
+	case "k":
+		multiplier = 1 << 10
+	case "m":
+		multiplier = 1 << 20
+	case "g":
+		multiplier = 1 << 30
+	}
+	if multiplier != 1 {
+		value = value[:len(value)-1]
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return n * multiplier, nil
+}
+
+// FormatSize formats a byte count with binary units, e.g. "1.5 KiB"
+func FormatSize(bytes int64) string {
+	if bytes < 1024 {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	size := float64(bytes)
+	unit := ""
+	for _, next := range []string{"KiB", "MiB", "GiB", "TiB"} {
+		size /= 1024
+		unit = next
+		if size < 1024 {
+			break
+		}
+	}
+	return fmt.Sprintf("%.1f %s", size, unit)
+}
+
+// SetConfig writes key = value into the repository config
+func (r *Repository) SetConfig(key, value string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	section, subsection, name, err := splitConfigKey(key)
+	if err != nil {
+		return err
+	}
+
+	l, err := r.lockGitFile(r.gitPath(ConfigFile))
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+
+	data, err := r.readGitFile(r.gitPath(ConfigFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+
+	newLine := fmt.Sprintf("\t%s = %s", name, value)
+	sectionEnd := -1
+	inSection := false
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			s, sub := parseConfigSection(line)
+			inSection = s == section && sub == subsection
+			if inSection {
+				sectionEnd = i
+			}
+			continue
+		}
+		if !inSection {
+			continue
+		}
+		sectionEnd = i
+		if lineName, _, _ := strings.Cut(line, "="); strings.ToLower(strings.TrimSpace(lineName)) == name {
+			lines[i] = newLine
+			return writeConfigLines(l, lines)
+		}
+	}
+
+	if sectionEnd >= 0 {
+		lines = append(lines[:sectionEnd+1], append([]string{newLine}, lines[sectionEnd+1:]...)...)
+	} else {
+		header := "[" + section + "]"
+		if subsection != "" {
+			header = fmt.Sprintf("[%s %q]", section, subsection)
+		}
+		lines = append(lines, header, newLine)
+	}
+	return writeConfigLines(l, lines)
+}
+
+// writeConfigLines replaces the locked config file with lines
+func writeConfigLines(l *lockFile, lines []string) error {
+	if err := l.commit([]byte(strings.Join(lines, "\n") + "\n")); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// unsetConfig removes key from the repository config, if it is set
+func (r *Repository) unsetConfig(key string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	section, subsection, name, err := splitConfigKey(key)
+	if err != nil {
+		return err
+	}
+
+	l, err := r.lockGitFile(r.gitPath(ConfigFile))
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+
+	data, err := r.readGitFile(r.gitPath(ConfigFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	var lines []string
+	inSection := false
+	for _, raw := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			s, sub := parseConfigSection(line)
+			inSection = s == section && sub == subsection
+		} else if lineName, _, _ := strings.Cut(line, "="); inSection && strings.ToLower(strings.TrimSpace(lineName)) == name {
+			continue
+		}
+		lines = append(lines, raw)
+	}
+
+	// Drop the section when nothing is left in it
+	kept := lines[:0]
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			if s, sub := parseConfigSection(line); s == section && sub == subsection &&
+				(i+1 == len(lines) || strings.HasPrefix(strings.TrimSpace(lines[i+1]), "[")) {
+				continue
+			}
+		}
+		kept = append(kept, raw)
+	}
+	return writeConfigLines(l, kept)
+}
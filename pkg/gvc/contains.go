@@ -0,0 +1,134 @@
+package gvc
+
+// containsMemoLimit bounds how many commits outside the commit-graph a
+// ContainsCache remembers; past it, answers for further commits are still
+// worked out, only not kept
+const containsMemoLimit = 1 << 20
+
+// ContainsCache answers whether commits reach one target commit, as branch
+// and tag --contains ask for every ref. What each walk learns is kept, so
+// history shared by hundreds of refs is walked once in all rather than once
+// per ref. Commits in the commit-graph are remembered in two bits each,
+// indexed by their position there, and are not walked below the target's
+// generation; other commits are remembered in a map of bounded size. A cache
+// is meant for one listing: rewriting the commit-graph invalidates it.
+type ContainsCache struct {
+	r       *Repository
+	target  *CommitNode
+	known   []uint64 // commit-graph positions whose answer is known
+	reaches []uint64 // and those among them that reach the target
+	memo    map[string]bool
+}
+
+// NewContainsCache prepares to answer whether commits reach target, given
+// as a full SHA
+func (r *Repository) NewContainsCache(target string) (*ContainsCache, error) {
+	node, err := r.LookupCommitNode(target)
+	if err != nil {
+		return nil, err
+	}
+	graph, err := r.loadCommitGraph()
+	if err != nil {
+		return nil, err
+	}
+	words := (len(graph) + 63) / 64
+	return &ContainsCache{
+		r:       r,
+		target:  node,
+		known:   make([]uint64, words),
+		reaches: make([]uint64, words),
+		memo:    make(map[string]bool),
+	}, nil
+}
+
+// lookup returns what is known of whether a commit reaches the target
+func (c *ContainsCache) lookup(node *CommitNode) (reaches, ok bool) {
+	if node.SHA == c.target.SHA {
+		return true, true
+	}
+	// Generations drop along every parent, so a commit no later than the
+	// target cannot reach it
+	if c.target.Generation != GenerationInfinity && node.Generation <= c.target.Generation {
+		return false, true
+	}
+	if i := node.graphIndex - 1; i >= 0 && i/64 < len(c.known) {
+		bit := uint64(1) << (i % 64)
+		if c.known[i/64]&bit == 0 {
+			return false, false
+		}
+		return c.reaches[i/64]&bit != 0, true
+	}
+	reaches, ok = c.memo[node.SHA]
+	return reaches, ok
+}
+
+// remember records whether a commit reaches the target
+func (c *ContainsCache) remember(node *CommitNode, reaches bool) {
+	if i := node.graphIndex - 1; i >= 0 && i/64 < len(c.known) {
+		bit := uint64(1) << (i % 64)
+		c.known[i/64] |= bit
+		if reaches {
+			c.reaches[i/64] |= bit
+		}
+		return
+	}
+	if len(c.memo) < containsMemoLimit {
+		c.memo[node.SHA] = reaches
+	}
+}
+
+// ReachedFrom reports whether the target is reachable from tip, given as a
+// full SHA, the way IsAncestor(target, tip) does. The walk is depth first
+// and stops at commits whose answer is already known.
+func (c *ContainsCache) ReachedFrom(tip string) (bool, error) {
+	node, err := c.r.LookupCommitNode(tip)
+	if err != nil {
+		return false, err
+	}
+	if reaches, ok := c.lookup(node); ok {
+		return reaches, nil
+	}
+
+	type frame struct {
+		node *CommitNode
+		next int // index of the next parent to visit
+	}
+	stack := []frame{{node: node}}
+	// Commits seen in this walk that are not on the stack are known not to
+	// reach the target, even once the memo is full
+	seen := map[string]bool{tip: true}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next == len(top.node.Parents) {
+			c.remember(top.node, false)
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		parentSHA := top.node.Parents[top.next]
+		top.next++
+		if seen[parentSHA] {
+			continue
+		}
+		seen[parentSHA] = true
+		if err := c.r.canceled(); err != nil {
+			return false, err
+		}
+		parent, err := c.r.LookupCommitNode(parentSHA)
+		if err != nil {
+			return false, err
+		}
+		reaches, ok := c.lookup(parent)
+		if !ok {
+			stack = append(stack, frame{node: parent})
+			continue
+		}
+		if reaches {
+			// Every commit on the stack reaches the target through parent
+			for _, f := range stack {
+				c.remember(f.node, true)
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,111 @@
+package gvc
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// addRecoveryHints fills in which refs reach a corrupt object and whether a pack
+// holds an intact copy. Lookups that fail only leave the hint out.
+func (r *Repository) addRecoveryHints(corrupt *CorruptObjectError) {
+	if rel, err := filepath.Rel(r.Root, corrupt.Path); err == nil && !strings.HasPrefix(rel, "..") {
+		corrupt.Path = filepath.ToSlash(rel)
+	}
+	corrupt.PackCopy = r.intactPackCopy(corrupt.SHA)
+	corrupt.Refs = r.refsReaching(corrupt.SHA)
+}
+
+// intactPackCopy returns the name of a pack holding a copy of sha that hashes correctly
+func (r *Repository) intactPackCopy(sha string) string {
+	packs, err := r.loadPacks()
+	if err != nil {
+		return ""
+	}
+	for _, pack := range packs {
+		offset, ok := pack.find(sha)
+		if !ok {
+			continue
+		}
+		objectType, content, err := pack.readAt(offset)
+		if err == nil && r.Format.Hash(objectType, content) == sha {
+			return filepath.Base(pack.path)
+		}
+	}
+	return ""
+}
+
+// refsReaching lists HEAD and the refs whose commits, trees or blobs include sha.
+// Objects that cannot be read are skipped so the walk gets past the corruption.
+func (r *Repository) refsReaching(sha string) []string {
+	var tips []Ref
+	if head, err := r.HeadCommit(); err == nil && head != "" {
+		tips = append(tips, Ref{Name: "HEAD", SHA: head})
+	}
+	refs, err := r.listRefs()
+	if err == nil {
+		tips = append(tips, refs...)
+	}
+
+	// reaches memoizes per object whether sha is reachable from it
+	reaches := make(map[string]bool)
+	var walk func(current string) bool
+	walk = func(current string) bool {
+		if current == sha {
+			return true
+		}
+		if found, seen := reaches[current]; seen {
+			return found
+		}
+		reaches[current] = false
+		found := false
+		for _, next := range r.objectLinks(current) {
+			if walk(next) {
+				found = true
+				break
+			}
+		}
+		reaches[current] = found
+		return found
+	}
+
+	var names []string
+	for _, tip := range tips {
+		if walk(tip.SHA) {
+			names = append(names, tip.Name)
+		}
+	}
+	return names
+}
+
+// objectLinks returns the objects a commit or tree points to, or nothing if it cannot be read
+func (r *Repository) objectLinks(sha string) []string {
+	objectType, content, err := r.loadRawObject(sha)
+	if err != nil {
+		return nil
+	}
+	var links []string
+	switch objectType {
+	case object.CommitObject:
+		for _, line := range strings.Split(string(content), "\n") {
+			if line == "" {
+				break
+			}
+			if tree, ok := strings.CutPrefix(line, "tree "); ok {
+				links = append(links, tree)
+			} else if parent, ok := strings.CutPrefix(line, "parent "); ok {
+				links = append(links, parent)
+			}
+		}
+	case object.TreeObject:
+		entries, err := r.Format.ParseTree(content)
+		if err != nil {
+			return nil
+		}
+		for _, entry := range entries {
+			links = append(links, entry.SHA)
+		}
+	}
+	return links
+}
@@ -0,0 +1,333 @@
+package gvc
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// Defaults for the thresholds past which CountObjects advises running gc, as in Git
+const (
+	defaultGCAuto          = 6700
+	defaultGCAutoPackLimit = 50
+)
+
+// CountObjectsOptions selects the statistics that need a walk of the history
+type CountObjectsOptions struct {
+	Unreachable bool // count the objects nothing refers to
+	Largest     int  // list this many of the biggest blobs the history holds
+}
+
+// ObjectStats describes how a repository stores its objects
+type ObjectStats struct {
+	Loose         int   // loose objects
+	LooseSize     int64 // disk space the loose objects take
+	InPack        int   // objects in packs
+	Packs         int
+	PackSize      int64 // disk space the packs and their indexes take
+	PrunePackable int   // loose objects that are also packed, which gc removes
+	Garbage       int   // stray files in the object directories
+	GarbageSize   int64
+	// Unreachable counts the objects not reached from HEAD, the refs, their
+	// reflogs, the index or other worktrees; -1 unless asked for
+	Unreachable int
+	// Largest are the biggest blobs reachable the same way, largest first
+	Largest []BlobSize
+	// GCAdvised is set once there are more loose objects than gc.auto or more
+	// packs than gc.autoPackLimit
+	GCAdvised bool
+}
+
+// BlobSize is a blob's size and the first path it was found at
+type BlobSize struct {
+	SHA  string
+	Size int64
+	Path string
+}
+
+// CountObjects reports the number and disk usage of loose and packed objects,
+// and on request the unreachable ones and the biggest blobs, to show when gc
+// is due
+func (r *Repository) CountObjects(opts CountObjectsOptions) (*ObjectStats, error) {
+	if !r.usesFileStore() {
+		return nil, fmt.Errorf("counting objects on disk is %w", errNeedsFileStore)
+	}
+	stats := &ObjectStats{Unreachable: -1}
+	loose, err := r.listLooseObjects()
+	if err != nil {
+		return nil, err
+	}
+	stats.Loose = len(loose)
+	for _, sha := range loose {
+		if info, err := os.Stat(r.objectPath(sha)); err == nil {
+			stats.LooseSize += info.Size()
+		}
+		packed, err := r.hasPackedObject(sha)
+		if err != nil {
+			return nil, err
+		}
+		if packed {
+			stats.PrunePackable++
+		}
+	}
+
+	packs, err := r.loadPacks()
+	if err != nil {
+		return nil, err
+	}
+	stats.Packs = len(packs)
+	for _, pack := range packs {
+		stats.InPack += len(pack.shas)
+	}
+	if err := r.countObjectFiles(stats); err != nil {
+		return nil, err
+	}
+
+	gcAuto, err := r.getConfigInt("gc.auto", defaultGCAuto)
+	if err != nil {
+		return nil, err
+	}
+	packLimit, err := r.getConfigInt("gc.autoPackLimit", defaultGCAutoPackLimit)
+	if err != nil {
+		return nil, err
+	}
+	stats.GCAdvised = gcAuto > 0 && int64(stats.Loose) > gcAuto || packLimit > 0 && int64(stats.Packs) > packLimit
+
+	if opts.Unreachable || opts.Largest > 0 {
+		if err := r.countReachable(stats, opts); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// countObjectFiles adds up the disk space of the packs and of the files in
+// the object directories that are neither objects nor parts of a pack
+func (r *Repository) countObjectFiles(stats *ObjectStats) error {
+	garbage := func(path string) {
+		stats.Garbage++
+		if info, err := os.Stat(path); err == nil {
+			stats.GarbageSize += info.Size()
+		}
+	}
+
+	dirs, err := os.ReadDir(r.gitPath(ObjectsDir))
+	if err != nil {
+		return fmt.Errorf("failed to read objects directory: %w", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 {
+			continue
+		}
+		files, err := os.ReadDir(r.gitPath(ObjectsDir, dir.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read object directory: %w", err)
+		}
+		for _, file := range files {
+			if r.Format.ValidateSHA(dir.Name()+file.Name()) != nil {
+				garbage(r.gitPath(ObjectsDir, dir.Name(), file.Name()))
+			}
+		}
+	}
+
+	files, err := os.ReadDir(r.gitPath(PackDir))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read pack directory: %w", err)
+	}
+	names := make(map[string]bool, len(files))
+	for _, file := range files {
+		names[file.Name()] = true
+	}
+	for _, file := range files {
+		name := file.Name()
+		base, ext := strings.TrimSuffix(name, filepath.Ext(name)), filepath.Ext(name)
+		switch {
+		case strings.HasPrefix(name, "pack-") && ext == ".pack" && names[base+".idx"],
+			strings.HasPrefix(name, "pack-") && ext == ".idx" && names[base+".pack"]:
+			if info, err := file.Info(); err == nil {
+				stats.PackSize += info.Size()
+			}
+		default:
+			garbage(r.gitPath(PackDir, name))
+		}
+	}
+	return nil
+}
+
+// countReachable walks everything the repository refers to, the way fsck
+// does, to count the objects left out and find the biggest blobs
+func (r *Repository) countReachable(stats *ObjectStats, opts CountObjectsOptions) error {
+	roots, err := r.fsckRoots(nil)
+	if err != nil {
+		return err
+	}
+	reachable := make(map[string]bool)
+	var blobs []BlobSize
+	type pending struct {
+		sha, path  string
+		objectType object.Type
+	}
+	// HEAD comes first, so blobs are named after their paths there rather
+	// than met in the index or a reflog without one
+	stack := make([]pending, 0, len(roots))
+	for i := len(roots) - 1; i >= 0; i-- {
+		stack = append(stack, pending{sha: roots[i].sha, objectType: roots[i].objectType})
+	}
+	for len(stack) > 0 {
+		next := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[next.sha] || !r.objectExists(next.sha) {
+			continue
+		}
+		reachable[next.sha] = true
+		if next.objectType == object.BlobObject {
+			size, err := r.objectSize(next.sha)
+			if err != nil {
+				return err
+			}
+			blobs = append(blobs, BlobSize{SHA: next.sha, Size: size, Path: next.path})
+			continue
+		}
+
+		objectType, content, err := r.ReadObject(next.sha)
+		if err != nil {
+			return err
+		}
+		switch objectType {
+		case object.CommitObject:
+			commit, err := object.ParseCommit(next.sha, content)
+			if err != nil {
+				return err
+			}
+			for _, parent := range commit.Parents {
+				stack = append(stack, pending{sha: parent, objectType: object.CommitObject})
+			}
+			// On top, so the commit's tree is walked before its parents'
+			stack = append(stack, pending{sha: commit.TreeSHA, objectType: object.TreeObject})
+		case object.TreeObject:
+			entries, err := r.Format.ParseTree(content)
+			if err != nil {
+				return err
+			}
+			for _, entry := range entries {
+				if entry.Mode != GitlinkMode {
+					stack = append(stack, pending{sha: entry.SHA, path: path.Join(next.path, entry.Name), objectType: entry.Type})
+				}
+			}
+		case object.TagObject:
+			tag, err := object.ParseTag(next.sha, content)
+			if err != nil {
+				return err
+			}
+			stack = append(stack, pending{sha: tag.Object, objectType: tag.Type})
+		case object.BlobObject:
+			// A root typed loosely, such as a reflog entry of a tag
+			blobs = append(blobs, BlobSize{SHA: next.sha, Size: int64(len(content))})
+		}
+	}
+
+	if opts.Unreachable {
+		shas, err := r.allObjects()
+		if err != nil {
+			return err
+		}
+		stats.Unreachable = 0
+		for _, sha := range shas {
+			if !reachable[sha] {
+				stats.Unreachable++
+			}
+		}
+	}
+	if opts.Largest > 0 {
+		sort.Slice(blobs, func(i, j int) bool {
+			if blobs[i].Size != blobs[j].Size {
+				return blobs[i].Size > blobs[j].Size
+			}
+			return blobs[i].SHA < blobs[j].SHA
+		})
+		stats.Largest = blobs[:min(opts.Largest, len(blobs))]
+	}
+	return nil
+}
+
+// TypeSpace is how many objects of one type a repository stores and the
+// disk space they take
+type TypeSpace struct {
+	Type  object.Type
+	Count int
+	Size  int64 // compressed bytes on disk; a delta counts at its stored size
+}
+
+// SpaceByType adds up the loose and packed objects of each type and the disk
+// space they take, to show what a repository's size is made of: commits,
+// trees, blobs and tags, in that order. An object stored both loose and
+// packed is counted twice, as it takes space twice.
+func (r *Repository) SpaceByType() ([]TypeSpace, error) {
+	if !r.usesFileStore() {
+		return nil, fmt.Errorf("counting objects on disk is %w", errNeedsFileStore)
+	}
+	spaces := []TypeSpace{{Type: object.CommitObject}, {Type: object.TreeObject}, {Type: object.BlobObject}, {Type: object.TagObject}}
+	add := func(objectType object.Type, size int64) {
+		for i := range spaces {
+			if spaces[i].Type == objectType {
+				spaces[i].Count++
+				spaces[i].Size += size
+			}
+		}
+	}
+
+	loose, err := r.listLooseObjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, sha := range loose {
+		if err := r.canceled(); err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(r.objectPath(sha))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object %s: %w", sha, err)
+		}
+		f, err := r.openObjectFile(r.objectPath(sha))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object %s: %w", sha, err)
+		}
+		objectType, _, err := readLooseHeader(f, sha)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		add(objectType, info.Size())
+	}
+
+	packs, err := r.loadPacks()
+	if err != nil {
+		return nil, err
+	}
+	for _, pack := range packs {
+		if len(pack.offsets) == 0 {
+			continue
+		}
+		// An entry runs to the next one, the last to the pack's checksum
+		offsets := slices.Clone(pack.offsets)
+		slices.Sort(offsets)
+		types := make([]object.Type, len(offsets))
+		for i, offset := range offsets {
+			if types[i], err = pack.typeAt(offset); err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(pack.path), err)
+			}
+		}
+		end := uint64(pack.file.Size()) - uint64(r.Format.Size)
+		for i := len(offsets) - 1; i >= 0; i-- {
+			add(types[i], int64(end-offsets[i]))
+			end = offsets[i]
+		}
+	}
+	return spaces, nil
+}
@@ -0,0 +1,103 @@
+package gvc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the absolute date formats ParseDate accepts
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"Mon Jan 2 15:04:05 2006 -0700",
+	time.RFC1123Z,
+}
+
+// dateUnits are the units of relative dates such as "2 weeks ago"
+var dateUnits = map[string]time.Duration{
+	"second": time.Second,
+	"minute": time.Minute,
+	"hour":   time.Hour,
+	"day":    24 * time.Hour,
+	"week":   7 * 24 * time.Hour,
+	"month":  30 * 24 * time.Hour,
+	"year":   365 * 24 * time.Hour,
+}
+
+// ParseDate reads the dates log filters take: "2024-03-01", "2024-03-01 12:00",
+// RFC 3339, "@<unix seconds>", "now", "yesterday" and relative dates such as
+// "3 days ago" or "2.weeks.ago". Dates without a zone are local time.
+func ParseDate(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(value) {
+	case "now":
+		return now, nil
+	case "today":
+		year, month, day := now.Date()
+		return time.Date(year, month, day, 0, 0, 0, 0, now.Location()), nil
+	case "yesterday":
+		return now.Add(-dateUnits["day"]), nil
+	}
+	if unix, ok := strings.CutPrefix(value, "@"); ok {
+		seconds, err := strconv.ParseInt(unix, 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+		return time.Unix(seconds, 0), nil
+	}
+
+	fields := strings.Fields(strings.ReplaceAll(strings.ToLower(value), ".", " "))
+	if len(fields) == 3 && fields[2] == "ago" {
+		count, err := strconv.Atoi(fields[0])
+		unit, known := dateUnits[strings.TrimSuffix(fields[1], "s")]
+		if err != nil || !known {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+		return now.Add(-time.Duration(count) * unit), nil
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// RelativeDate describes t relative to now the way Git's --date=relative
+// does, e.g. "3 hours ago" or "2 weeks ago"
+func RelativeDate(t, now time.Time) string {
+	elapsed := now.Sub(t)
+	if elapsed < 0 {
+		return "in the future"
+	}
+	seconds := int64(elapsed / time.Second)
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s ago", unit)
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch days := seconds / 86400; {
+	case seconds < 90:
+		return plural(seconds, "second")
+	case seconds < 90*60:
+		return plural((seconds+30)/60, "minute")
+	case seconds < 36*3600:
+		return plural((seconds+1800)/3600, "hour")
+	case days < 14:
+		return plural((seconds+43200)/86400, "day")
+	case days < 70:
+		return plural((days+3)/7, "week")
+	case days < 365:
+		return plural((days+15)/30, "month")
+	default:
+		return plural((days+183)/365, "year")
+	}
+}
@@ -0,0 +1,179 @@
+package gvc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+func appendDeltaSize(out []byte, size int) []byte {
+	for size >= 0x80 {
+		out = append(out, byte(size)|0x80)
+		size >>= 7
+	}
+	return append(out, byte(size))
+}
+
+func readDeltaSize(delta []byte) (int, int, error) {
+	size, shift := 0, 0
+	for i, b := range delta {
+		size |= int(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			return size, i + 1, nil
+		}
+	}
+	return 0, 0, errors.New("truncated delta header")
+}
+
+// applyDelta rebuilds a target object from its base and a delta
+func applyDelta(base, delta []byte) ([]byte, error) {
+	sourceSize, n, err := readDeltaSize(delta)
+	if err != nil {
+		return nil, err
+	}
+	delta = delta[n:]
+	if sourceSize != len(base) {
+		return nil, fmt.Errorf("delta base size mismatch: expected %d, got %d", sourceSize, len(base))
+	}
+	targetSize, n, err := readDeltaSize(delta)
+	if err != nil {
+		return nil, err
+	}
+	delta = delta[n:]
+
+	out := make([]byte, 0, targetSize)
+	for len(delta) > 0 {
+		op := delta[0]
+		delta = delta[1:]
+		switch {
+		case op&0x80 != 0:
+			var offset, size int
+			for i := 0; i < 7; i++ {
+				if op&(1<<i) == 0 {
+					continue
+				}
+				if len(delta) == 0 {
+					return nil, errors.New("truncated delta copy instruction")
+				}
+				if i < 4 {
+					offset |= int(delta[0]) << (8 * i)
+				} else {
+					size |= int(delta[0]) << (8 * (i - 4))
+				}
+				delta = delta[1:]
+			}
+			if size == 0 {
+				size = 0x10000
+			}
+			if offset+size > len(base) {
+				return nil, errors.New("delta copy out of range")
+			}
+			out = append(out, base[offset:offset+size]...)
+		case op != 0:
+			if int(op) > len(delta) {
+				return nil, errors.New("truncated delta insert instruction")
+			}
+			out = append(out, delta[:op]...)
+			delta = delta[op:]
+		default:
+			return nil, errors.New("invalid delta opcode 0")
+		}
+	}
+	if len(out) != targetSize {
+		return nil, fmt.Errorf("delta result size mismatch: expected %d, got %d", targetSize, len(out))
+	}
+	return out, nil
+}
+
+const deltaBlockSize = 16
+
+func hashDeltaBlock(block []byte) uint64 {
+	h := uint64(14695981039346656037)
+	for _, b := range block {
+		h ^= uint64(b)
+		h *= 1099511628211
+	}
+	return h
+}
+
+// createDelta encodes target as copies from source plus inserted literals
+func createDelta(source, target []byte) []byte {
+	out := appendDeltaSize(nil, len(source))
+	out = appendDeltaSize(out, len(target))
+
+	blocks := make(map[uint64]int)
+	for i := 0; i+deltaBlockSize <= len(source); i += deltaBlockSize {
+		h := hashDeltaBlock(source[i : i+deltaBlockSize])
+		if _, ok := blocks[h]; !ok {
+			blocks[h] = i
+		}
+	}
+
+	var insert []byte
+	flush := func() {
+		for len(insert) > 0 {
+			n := len(insert)
+			if n > 0x7f {
+				n = 0x7f
+			}
+			out = append(out, byte(n))
+			out = append(out, insert[:n]...)
+			insert = insert[n:]
+		}
+	}
+
+	for t := 0; t < len(target); {
+		if t+deltaBlockSize <= len(target) {
+			s, ok := blocks[hashDeltaBlock(target[t:t+deltaBlockSize])]
+			if ok && bytes.Equal(source[s:s+deltaBlockSize], target[t:t+deltaBlockSize]) {
+				n := deltaBlockSize
+				for s+n < len(source) && t+n < len(target) && source[s+n] == target[t+n] {
+					n++
+				}
+				// Pull literal bytes that also precede the match in source into the copy
+				for len(insert) > 0 && s > 0 && source[s-1] == insert[len(insert)-1] {
+					s--
+					t--
+					n++
+					insert = insert[:len(insert)-1]
+				}
+				flush()
+				t += n
+				for n > 0 {
+					size := n
+					if size > 0x10000 {
+						size = 0x10000
+					}
+					op := byte(0x80)
+					var args []byte
+					for i := 0; i < 4; i++ {
+						if b := byte(s >> (8 * i)); b != 0 {
+							op |= 1 << i
+							args = append(args, b)
+						}
+					}
+					encoded := size
+					if encoded == 0x10000 {
+						encoded = 0
+					}
+					for i := 0; i < 3; i++ {
+						if b := byte(encoded >> (8 * i)); b != 0 {
+							op |= 0x10 << i
+							args = append(args, b)
+						}
+					}
+					out = append(out, op)
+					out = append(out, args...)
+					s += size
+					n -= size
+				}
+				continue
+			}
+		}
+		insert = append(insert, target[t])
+		t++
+	}
+	flush()
+	return out
+}
@@ -0,0 +1,220 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// describeCandidates is how many tags, nearest first in date order, Describe
+// weighs against each other, as in Git
+const describeCandidates = 10
+
+// DescribeOptions selects the tags Describe may use and how it prints a name
+type DescribeOptions struct {
+	Tags       bool   // consider lightweight tags, not only annotated ones
+	Match      string // only tags whose name matches this glob
+	Long       bool   // print the distance and SHA even on a tagged commit
+	Abbrev     int    // SHA digits to print; 0 prints the tag alone
+	Always     bool   // fall back to the abbreviated SHA when no tag fits
+	ExactMatch bool   // only name commits that are tagged themselves
+	Dirty      string // appended when describing HEAD over local changes
+}
+
+// Describe names a commit after the nearest tag it can reach, as
+// <tag>-<commits since it>-g<abbreviated SHA>, or just <tag> when the commit
+// is tagged. Of the tags met first walking back in date order, the one fewest
+// commits away wins. An empty rev describes HEAD.
+func (r *Repository) Describe(rev string, opts DescribeOptions) (string, error) {
+	if rev == "" {
+		rev = "HEAD"
+	}
+	target, err := r.ResolveCommit(rev)
+	if err != nil {
+		return "", err
+	}
+	tags, err := r.describeTags(opts)
+	if err != nil {
+		return "", err
+	}
+	abbrev := target[:min(max(opts.Abbrev, 4), len(target))]
+
+	suffix := ""
+	if opts.Dirty != "" {
+		if suffix, err = r.dirtySuffix(opts.Dirty); err != nil {
+			return "", err
+		}
+	}
+
+	if name, ok := tags[target]; ok && !opts.Long {
+		return name + suffix, nil
+	}
+	if opts.ExactMatch {
+		if name, ok := tags[target]; ok {
+			return name + suffix, nil
+		}
+		if opts.Always {
+			return abbrev + suffix, nil
+		}
+		return "", fmt.Errorf("no tag exactly matches '%s'", target)
+	}
+
+	var candidates []string
+	_, err = r.walkRevisions(&RevRange{Include: []string{target}}, func(node *CommitNode) ([]string, error) {
+		if _, ok := tags[node.SHA]; ok {
+			candidates = append(candidates, node.SHA)
+		}
+		if len(candidates) >= describeCandidates {
+			return nil, nil
+		}
+		return node.Parents, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(candidates) == 0 {
+		switch {
+		case opts.Always:
+			return abbrev + suffix, nil
+		case len(tags) == 0 && !opts.Tags:
+			// Tags made without -a or -m are lightweight, so say they exist
+			lightweight, err := r.describeTags(DescribeOptions{Tags: true, Match: opts.Match})
+			if err != nil {
+				return "", err
+			}
+			if len(lightweight) > 0 {
+				return "", fmt.Errorf("no annotated tags can describe '%s'; however, there were unannotated tags: try --tags", target)
+			}
+			return "", errors.New("no names found, cannot describe anything")
+		case len(tags) == 0:
+			return "", errors.New("no names found, cannot describe anything")
+		default:
+			return "", fmt.Errorf("no tags can describe '%s'; try --always, or create some tags", target)
+		}
+	}
+
+	best, bestDepth := "", -1
+	for _, candidate := range candidates {
+		since, err := r.WalkRevisions(&RevRange{Include: []string{target}, Exclude: []string{candidate}})
+		if err != nil {
+			return "", err
+		}
+		if bestDepth < 0 || len(since) < bestDepth {
+			best, bestDepth = candidate, len(since)
+		}
+	}
+	if opts.Abbrev == 0 {
+		return tags[best] + suffix, nil
+	}
+	return fmt.Sprintf("%s-%d-g%s%s", tags[best], bestDepth, abbrev, suffix), nil
+}
+
+// describeTags maps each tagged commit to the tag Describe would call it.
+// An annotated tag beats a lightweight one on the same commit; otherwise the
+// first name in sorted order is kept.
+func (r *Repository) describeTags(opts DescribeOptions) (map[string]string, error) {
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string)
+	annotated := make(map[string]bool)
+	for _, ref := range refs {
+		name, ok := strings.CutPrefix(ref.Name, "refs/tags/")
+		if !ok || ref.Tag == "" && !opts.Tags {
+			continue
+		}
+		if opts.Match != "" {
+			if matched, err := path.Match(opts.Match, name); err != nil {
+				return nil, fmt.Errorf("invalid --match pattern: %w", err)
+			} else if !matched {
+				continue
+			}
+		}
+		if _, seen := tags[ref.SHA]; seen && (annotated[ref.SHA] || ref.Tag == "") {
+			continue
+		}
+		tags[ref.SHA] = name
+		annotated[ref.SHA] = ref.Tag != ""
+	}
+	return tags, nil
+}
+
+// dirtySuffix returns mark when the index or tracked files differ from HEAD
+func (r *Repository) dirtySuffix(mark string) (string, error) {
+	head, err := r.headEntries()
+	if err != nil {
+		return "", err
+	}
+	if r.checkCleanState(head) != nil {
+		return mark, nil
+	}
+	return "", nil
+}
+
+// ShortlogAuthor is one author's commits in a shortlog
+type ShortlogAuthor struct {
+	Name     string
+	Email    string   // set only when grouping by email
+	Subjects []string // oldest first
+}
+
+// Shortlog groups the commits of revision ranges by author, sorted by name,
+// or by name and email when byEmail is set. Without revisions it summarizes
+// the history of HEAD.
+func (r *Repository) Shortlog(revs []string, byEmail bool) ([]ShortlogAuthor, error) {
+	if len(revs) == 0 {
+		head, err := r.HeadCommit()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current commit: %w", err)
+		}
+		if head == "" {
+			return nil, nil
+		}
+		revs = []string{head}
+	}
+	revRange, err := r.ParseRevRange(revs)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := r.WalkRevisions(revRange)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*ShortlogAuthor)
+	for i := len(nodes) - 1; i >= 0; i-- {
+		commit, err := r.ReadCommit(nodes[i].SHA)
+		if err != nil {
+			return nil, err
+		}
+		name, email, _ := strings.Cut(commit.Author, " <")
+		email = strings.TrimSuffix(email, ">")
+		key := name
+		if byEmail {
+			key += "\x00" + email
+		} else {
+			email = ""
+		}
+		group := groups[key]
+		if group == nil {
+			group = &ShortlogAuthor{Name: name, Email: email}
+			groups[key] = group
+		}
+		group.Subjects = append(group.Subjects, strings.SplitN(commit.Message, "\n", 2)[0])
+	}
+
+	authors := make([]ShortlogAuthor, 0, len(groups))
+	for _, group := range groups {
+		authors = append(authors, *group)
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		if authors[i].Name != authors[j].Name {
+			return authors[i].Name < authors[j].Name
+		}
+		return authors[i].Email < authors[j].Email
+	})
+	return authors, nil
+}
@@ -0,0 +1,35 @@
+package gvc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDescribeUsesTagsGvcCreated(t *testing.T) {
+	repo, firstSHA := newTestRepoWithCommit(t)
+	if err := repo.CreateTag("v0.9", firstSHA, false); err != nil {
+		t.Fatal(err)
+	}
+	// Lightweight tags alone only describe with --tags
+	if _, err := repo.Describe("", DescribeOptions{Abbrev: 7}); err == nil || !strings.Contains(err.Error(), "try --tags") {
+		t.Errorf("describing over lightweight tags alone gave %v, want a hint to use --tags", err)
+	}
+	if name, err := repo.Describe("", DescribeOptions{Tags: true, Abbrev: 7}); err != nil || name != "v0.9" {
+		t.Errorf("describe --tags gave %q (%v), want v0.9", name, err)
+	}
+
+	if err := repo.CreateAnnotatedTag("v1.0", "HEAD", "release 1.0", false); err != nil {
+		t.Fatal(err)
+	}
+	headSHA := commitTestChange(t, repo, "next.txt", "next\n", "next")
+	name, err := repo.Describe("", DescribeOptions{Abbrev: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "v1.0-1-g" + headSHA[:7]; name != want {
+		t.Errorf("describe gave %q, want %q", name, want)
+	}
+	if sha, err := repo.ResolveCommit("v1.0"); err != nil || sha != firstSHA {
+		t.Errorf("v1.0 resolves to %q (%v), want the tagged commit %s", sha, err, firstSHA)
+	}
+}
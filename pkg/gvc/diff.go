@@ -0,0 +1,260 @@
+package gvc
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/diff"
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// revisionEntries returns the flattened tree of a commit
+func (r *Repository) revisionEntries(rev string) (map[string]IndexEntry, error) {
+	commitSHA, err := r.ResolveCommit(rev)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := r.ReadCommit(commitSHA)
+	if err != nil {
+		return nil, err
+	}
+	return r.flattenTree(commit.TreeSHA)
+}
+
+// DiffIndex lists the staged changes relative to a commit, HEAD when rev is empty
+func (r *Repository) DiffIndex(rev string) ([]FileChange, error) {
+	head, err := r.headEntries()
+	if err != nil {
+		return nil, err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return nil, err
+	}
+	base := head
+	if rev != "" {
+		if base, err = r.revisionEntries(rev); err != nil {
+			return nil, err
+		}
+	}
+	return diffEntries(base, staged), nil
+}
+
+// keepWorkingBlob stores the working tree copy of path as a blob. A
+// read-only repository keeps it in memory, where reads still find it.
+func (r *Repository) keepWorkingBlob(path string) error {
+	if !r.ReadOnly {
+		_, err := r.hashWorkingFile(r.worktreePath(path), true)
+		return err
+	}
+	fullPath := r.worktreePath(path)
+	var content []byte
+	info, err := os.Lstat(fullPath)
+	if err == nil && info.Mode()&os.ModeSymlink != 0 {
+		var target string
+		target, err = os.Readlink(fullPath)
+		content = []byte(filepath.ToSlash(target))
+	} else if err == nil {
+		var ok bool
+		if content, ok, err = r.largeFilePointer(fullPath, info, false); err == nil && !ok {
+			content, err = r.readWorkingContent(fullPath)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	if r.unsaved == nil {
+		r.unsaved = NewMemoryStore()
+	}
+	return r.unsaved.Put(r.Format.Hash(object.BlobObject, content), object.BlobObject, content)
+}
+
+// DiffWorkingTree lists how the tracked files in the working tree differ
+// from the staged ones, or from a commit's when rev is not empty. Changed
+// working tree files are stored as blobs so the patch can show them, in
+// memory when the repository is read-only.
+func (r *Repository) DiffWorkingTree(rev string) ([]FileChange, error) {
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return nil, err
+	}
+	base := staged
+	if rev != "" {
+		if base, err = r.revisionEntries(rev); err != nil {
+			return nil, err
+		}
+	}
+
+	indexTime := r.indexModTime()
+	working := make(map[string]IndexEntry, len(staged))
+	for path, entry := range staged {
+		workingEntry, ok, err := r.readWorkingEntryCached(path, entry, indexTime)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			working[path] = workingEntry
+		}
+	}
+
+	changes := diffEntries(base, working)
+	for _, change := range changes {
+		if change.New == nil || change.New.Mode == GitlinkMode {
+			continue
+		}
+		if err := r.keepWorkingBlob(change.Path); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// UnmergedPaths returns the paths an interrupted cherry-pick, revert or
+// rebase left with conflicts that are not yet resolved and staged
+func (r *Repository) UnmergedPaths() ([]string, error) {
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return nil, err
+	}
+	return r.unresolvedConflicts(staged)
+}
+
+// conflictSides returns the snapshots an interrupted operation was merging:
+// HEAD and the side being applied
+func (r *Repository) conflictSides() (ours, theirs map[string]IndexEntry, err error) {
+	if ours, err = r.headEntries(); err != nil {
+		return nil, nil, err
+	}
+	// A subtree merge applies the commit's files below its directory
+	if commitSHA, prefix, err := r.subtreeMergeState(); err != nil {
+		return nil, nil, err
+	} else if commitSHA != "" {
+		commit, err := r.ReadCommit(commitSHA)
+		if err != nil {
+			return nil, nil, err
+		}
+		if theirs, err = r.subtreeEntries(ours, prefix, commit.TreeSHA); err != nil {
+			return nil, nil, err
+		}
+		return ours, theirs, nil
+	}
+
+	var commitSHA string
+	useParent := false
+	for _, file := range []string{r.gitPath(CherryPickHeadFile), r.gitPath(RevertHeadFile), r.rebaseFile("stopped-sha"), r.gitPath(MergeHeadFile)} {
+		if data, err := os.ReadFile(file); err == nil {
+			commitSHA = strings.TrimSpace(string(data))
+			// A revert applies the change from the commit back to its parent
+			useParent = file == r.gitPath(RevertHeadFile)
+			break
+		}
+	}
+	if commitSHA == "" {
+		return nil, nil, fmt.Errorf("no cherry-pick, revert, rebase, merge or subtree merge is in progress")
+	}
+	commit, err := r.ReadCommit(commitSHA)
+	if err != nil {
+		return nil, nil, err
+	}
+	treeSHA := commit.TreeSHA
+	if useParent {
+		treeSHA = ""
+		if len(commit.Parents) > 0 {
+			parent, err := r.ReadCommit(commit.Parents[0])
+			if err != nil {
+				return nil, nil, err
+			}
+			treeSHA = parent.TreeSHA
+		}
+	}
+	if theirs, err = r.flattenTree(treeSHA); err != nil {
+		return nil, nil, err
+	}
+	return ours, theirs, nil
+}
+
+// WriteCombinedPatch writes a combined diff of conflicted paths, as Git's
+// "diff --cc" shows them: the working tree file against both HEAD's version
+// and the version being applied, with a column of markers for each
+func (r *Repository) WriteCombinedPatch(w io.Writer, paths []string) error {
+	return r.WriteCombinedPatchWithOptions(w, paths, PatchOptions{})
+}
+
+// WriteCombinedPatchWithOptions writes a combined diff of the conflicted
+// paths opts.Relative includes, with their names shown as opts says
+func (r *Repository) WriteCombinedPatchWithOptions(w io.Writer, paths []string, opts PatchOptions) error {
+	ours, theirs, err := r.conflictSides()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		if !opts.Includes(path) {
+			continue
+		}
+		if err := r.writeCombinedFilePatch(w, path, ours, theirs, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeCombinedFilePatch writes the combined diff of one conflicted path
+func (r *Repository) writeCombinedFilePatch(w io.Writer, path string, ours, theirs map[string]IndexEntry, opts PatchOptions) error {
+	var parents [][]byte
+	var shas []string
+	for _, side := range []map[string]IndexEntry{ours, theirs} {
+		entry, ok := side[path]
+		if !ok {
+			parents = append(parents, nil)
+			shas = append(shas, r.Format.ZeroSHA()[:7])
+			continue
+		}
+		content, err := r.patchContent(&entry)
+		if err != nil {
+			return err
+		}
+		parents = append(parents, content)
+		shas = append(shas, entry.SHA[:7])
+	}
+	result, err := r.readWorkingContent(r.worktreePath(path))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	name, _ := opts.displayPath(path)
+	if _, err := fmt.Fprintf(w, "diff --cc %s\nindex %s..%s\n", name, strings.Join(shas, ","), r.Format.ZeroSHA()[:7]); err != nil {
+		return err
+	}
+	if isBinary(parents[0]) || isBinary(parents[1]) || isBinary(result) {
+		_, err := fmt.Fprintf(w, "Binary files differ\n")
+		return err
+	}
+
+	lines := diff.Combined([][]string{diff.SplitLines(parents[0]), diff.SplitLines(parents[1])}, diff.SplitLines(result))
+	hunks := diff.CombinedHunks(lines, PatchContext)
+	if len(hunks) == 0 {
+		return nil
+	}
+	src, dst := opts.prefixes()
+	if _, err := fmt.Fprintf(w, "--- %s%s\n+++ %s%s\n", src, name, dst, name); err != nil {
+		return err
+	}
+	for _, hunk := range hunks {
+		if _, err := fmt.Fprintln(w, hunk.Header()); err != nil {
+			return err
+		}
+		for _, line := range hunk.Lines {
+			text := string(line.Marks) + line.Line
+			if !strings.HasSuffix(line.Line, "\n") {
+				text += "\n\\ No newline at end of file\n"
+			}
+			if _, err := io.WriteString(w, text); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
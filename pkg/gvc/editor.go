@@ -0,0 +1,156 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// EnvEditor names the environment variable that picks the editor ahead of
+// core.editor, $VISUAL and $EDITOR
+const EnvEditor = "GVC_EDITOR"
+
+// commitMessageHelp heads the comments below a commit message being edited
+const commitMessageHelp = `Please enter the commit message for your changes. Lines starting
+with '#' will be ignored, and an empty message aborts the commit.
+`
+
+// Editor returns the command that edits messages: $GVC_EDITOR, core.editor,
+// $VISUAL or $EDITOR, whichever is set first, or else vi
+func (r *Repository) Editor() (string, error) {
+	if editor := os.Getenv(EnvEditor); editor != "" {
+		return editor, nil
+	}
+	editor, err := r.getConfigString("core.editor", "")
+	if err != nil || editor != "" {
+		return editor, err
+	}
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if editor := os.Getenv(name); editor != "" {
+			return editor, nil
+		}
+	}
+	return "vi", nil
+}
+
+// EditFile opens path in the editor on the terminal and waits for it to
+// exit. The editor is run by the shell, so it may carry arguments, e.g.
+// "code --wait".
+func (r *Repository) EditFile(path string) error {
+	editor, err := r.Editor()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command("sh", "-c", editor+` "$@"`, editor, path)
+	cmd.Dir = r.Root
+	cmd.Env = r.Environ()
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("there was a problem with the editor '%s': %w", editor, err)
+	}
+	return nil
+}
+
+// CommitTemplate returns the content of the commit message template at path,
+// or of commit.template when path is empty; "" when neither is set
+func (r *Repository) CommitTemplate(path string) (string, error) {
+	if path == "" {
+		var err error
+		if path, err = r.getConfigString("commit.template", ""); err != nil || path == "" {
+			return "", err
+		}
+		// A relative commit.template is found from the top of the working tree
+		if path = expandHome(path); !filepath.IsAbs(path) {
+			path = filepath.Join(r.Root, path)
+		}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read commit template %s: %w", path, err)
+	}
+	return string(content), nil
+}
+
+// EditCommitMessage has the user write a commit message in the editor. The
+// file it opens, COMMIT_EDITMSG, starts with message, or the template (see
+// CommitTemplate) when message is empty, followed by help and status as
+// comment lines. The edited message is cleaned up with CleanupMessage; an
+// empty one, or an unchanged template, aborts the commit.
+func (r *Repository) EditCommitMessage(message, template, status string) (string, error) {
+	if err := r.checkWritable(); err != nil {
+		return "", err
+	}
+	usedTemplate := ""
+	if message == "" {
+		var err error
+		if usedTemplate, err = r.CommitTemplate(template); err != nil {
+			return "", err
+		}
+		message = usedTemplate
+	}
+
+	var b strings.Builder
+	b.WriteString(message)
+	if message != "" && !strings.HasSuffix(message, "\n") {
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	for _, line := range strings.Split(strings.TrimRight(commitMessageHelp+"\n"+status, "\n"), "\n") {
+		switch {
+		case line == "":
+			b.WriteString("#\n")
+		case strings.HasPrefix(line, "\t"):
+			b.WriteString("#" + line + "\n")
+		default:
+			b.WriteString("# " + line + "\n")
+		}
+	}
+
+	msgFile := r.gitPath(CommitMsgFile)
+	if err := os.WriteFile(msgFile, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", CommitMsgFile, err)
+	}
+	if err := r.EditFile(msgFile); err != nil {
+		return "", err
+	}
+	edited, err := os.ReadFile(msgFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", CommitMsgFile, err)
+	}
+
+	message = CleanupMessage(string(edited))
+	if message == "" {
+		return "", errors.New("aborting commit due to empty commit message")
+	}
+	if usedTemplate != "" && message == CleanupMessage(usedTemplate) {
+		return "", errors.New("aborting commit; you did not edit the message")
+	}
+	return message, nil
+}
+
+// CleanupMessage strips the comment lines, those starting with '#', from an
+// edited message along with trailing whitespace, runs of blank lines and
+// leading and trailing blank lines
+func CleanupMessage(text string) string {
+	var lines []string
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			blank = len(lines) > 0
+			continue
+		}
+		if blank {
+			lines = append(lines, "")
+			blank = false
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,741 @@
+package gvc
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// EncryptionKey is the config setting that marks a repository whose object
+// files are encrypted at rest, naming the cipher
+const EncryptionKey = "extensions.encryption"
+
+// EnvPassphrase names the environment variable holding the passphrase of an
+// encrypted repository. Without it the key is derived from the file
+// encryption.keyFile names.
+const EnvPassphrase = "GVC_PASSPHRASE"
+
+const (
+	encryptionCipher = "aes-256-gcm"
+	// encryptionMagic starts every encrypted object file, followed by the
+	// salt its key is derived with
+	encryptionMagic    = "GVCENC\x00\x01"
+	encryptionSaltSize = 16
+	// Files are sealed in chunks, so reads from the middle of a pack only
+	// decrypt the chunks they touch
+	encryptionChunk      = 64 << 10
+	encryptionIterations = 210000
+)
+
+// errDecrypt is returned for an object file that fails authentication:
+// tampered with, truncated or sealed with another key
+var errDecrypt = errors.New("failed to decrypt")
+
+// objectKey returns the key object files are sealed with, or nil when the
+// repository is not encrypted. It is derived once per handle, from
+// GVC_PASSPHRASE or the file encryption.keyFile names, and checked against
+// encryption.check so a wrong passphrase is reported as such.
+func (r *Repository) objectKey() ([]byte, error) {
+	r.keyOnce.Do(func() {
+		r.key, r.keyErr = r.loadObjectKey()
+	})
+	return r.key, r.keyErr
+}
+
+func (r *Repository) loadObjectKey() ([]byte, error) {
+	name, found, err := r.GetConfig(EncryptionKey)
+	if err != nil || !found {
+		return nil, err
+	}
+	if name != encryptionCipher {
+		return nil, fmt.Errorf("unsupported repository: unknown encryption %q", name)
+	}
+	secret, err := r.encryptionSecret()
+	if err != nil {
+		return nil, err
+	}
+	salt, err := r.getConfigString("encryption.salt", "")
+	if err != nil {
+		return nil, err
+	}
+	iterations, err := r.getConfigInt("encryption.iterations", encryptionIterations)
+	if err != nil {
+		return nil, err
+	}
+	saltBytes, err := hex.DecodeString(salt)
+	if err != nil || len(saltBytes) == 0 || iterations < 1 {
+		return nil, errors.New("corrupt encryption settings: encryption.salt or encryption.iterations is invalid")
+	}
+	key := pbkdf2SHA256(secret, saltBytes, int(iterations), 32)
+	check, err := r.getConfigString("encryption.check", "")
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal([]byte(check), []byte(keyCheck(key))) {
+		return nil, errors.New("wrong passphrase or key file for this encrypted repository")
+	}
+	return key, nil
+}
+
+// encryptionSecret returns the passphrase or key file content the key is
+// derived from
+func (r *Repository) encryptionSecret() ([]byte, error) {
+	if passphrase := os.Getenv(EnvPassphrase); passphrase != "" {
+		return []byte(passphrase), nil
+	}
+	keyFile, err := r.getConfigString("encryption.keyFile", "")
+	if err != nil {
+		return nil, err
+	}
+	if keyFile == "" {
+		return nil, fmt.Errorf("repository is encrypted: set %s or encryption.keyFile", EnvPassphrase)
+	}
+	return readKeyFile(expandHome(keyFile))
+}
+
+// readKeyFile reads a key file, dropping a trailing newline
+func readKeyFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	data = bytes.TrimRight(data, "\r\n")
+	if len(data) == 0 {
+		return nil, fmt.Errorf("key file %s is empty", path)
+	}
+	return data, nil
+}
+
+// keyCheck is what encryption.check records to recognize the right key
+func keyCheck(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("gvc encryption check"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// pbkdf2SHA256 derives a key of keyLen bytes from secret as PBKDF2 with
+// HMAC-SHA256 does (RFC 8018)
+func pbkdf2SHA256(secret, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, secret)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, block))
+		u := prf.Sum(nil)
+		t := bytes.Clone(u)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
+
+// fileCipher returns the cipher for the object file with the given salt,
+// which gets a key of its own so nonces can simply count chunks
+func fileCipher(key, salt []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("gvc object file"))
+	mac.Write(salt)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce numbers chunk i; the last chunk is sealed with additional data
+// marking it, so a file cut short at a chunk boundary is detected
+func chunkNonce(aead cipher.AEAD, i uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], i)
+	return nonce
+}
+
+func chunkData(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// sealWriter encrypts what is written to it into w, one chunk at a time
+type sealWriter struct {
+	w     io.Writer
+	aead  cipher.AEAD
+	buf   []byte
+	chunk uint64
+}
+
+// sealObjectFile returns a writer storing an object file through w: sealed
+// in an encrypted repository, unchanged otherwise. Closing it writes the
+// last chunk but leaves w open.
+func (r *Repository) sealObjectFile(w io.Writer) (io.WriteCloser, error) {
+	key, err := r.objectKey()
+	if err != nil {
+		return nil, err
+	}
+	return sealWith(w, key)
+}
+
+// sealWith seals into w with key, or passes writes through when it is nil
+func sealWith(w io.Writer, key []byte) (io.WriteCloser, error) {
+	if key == nil {
+		return nopWriteCloser{w}, nil
+	}
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	aead, err := fileCipher(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(append([]byte(encryptionMagic), salt...)); err != nil {
+		return nil, err
+	}
+	return &sealWriter{w: w, aead: aead, buf: make([]byte, 0, encryptionChunk)}, nil
+}
+
+func (s *sealWriter) Write(p []byte) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data shows it is not the last
+		if len(s.buf) == encryptionChunk {
+			if err := s.flush(false); err != nil {
+				return n, err
+			}
+		}
+		take := min(len(p), encryptionChunk-len(s.buf))
+		s.buf = append(s.buf, p[:take]...)
+		p, n = p[take:], n+take
+	}
+	return n, nil
+}
+
+func (s *sealWriter) flush(final bool) error {
+	sealed := s.aead.Seal(nil, chunkNonce(s.aead, s.chunk), s.buf, chunkData(final))
+	s.chunk++
+	s.buf = s.buf[:0]
+	_, err := s.w.Write(sealed)
+	return err
+}
+
+// Close seals the last chunk
+func (s *sealWriter) Close() error {
+	return s.flush(true)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// objectFile is an object or pack file opened for reading, decrypted when
+// it was stored encrypted
+type objectFile interface {
+	io.ReaderAt
+	io.Closer
+	// Size is the length of the decrypted content
+	Size() int64
+}
+
+// plainFile is an object file stored as it is
+type plainFile struct {
+	*os.File
+	size int64
+}
+
+func (f plainFile) Size() int64 { return f.size }
+
+// isSealed reports whether an object file starts like an encrypted one
+func isSealed(header []byte) bool {
+	return bytes.HasPrefix(header, []byte(encryptionMagic))
+}
+
+// openObjectFile opens an object or pack file. Files stored unencrypted,
+// e.g. before encryption was turned on, are read as they are.
+func (r *Repository) openObjectFile(path string) (objectFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	file, err := r.unsealFile(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return file, nil
+}
+
+// unsealFile wraps an open object file for decrypted reads, taking it over
+func (r *Repository) unsealFile(f *os.File) (objectFile, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, len(encryptionMagic)+encryptionSaltSize)
+	n, err := f.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !isSealed(header[:n]) {
+		return plainFile{f, info.Size()}, nil
+	}
+	return r.openSealed(f, f, info.Size())
+}
+
+// unsealData decrypts the content of an object file read whole, returning
+// unencrypted content as it is
+func (r *Repository) unsealData(data []byte) ([]byte, error) {
+	if !isSealed(data) {
+		return data, nil
+	}
+	file, err := r.openSealed(bytes.NewReader(data), nil, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(io.NewSectionReader(file, 0, file.Size()))
+}
+
+// sealedFile reads an encrypted object file, decrypting the chunks a read
+// touches and keeping the last one for the reads that follow
+type sealedFile struct {
+	src    io.ReaderAt
+	closer io.Closer
+	aead   cipher.AEAD
+	chunks int64
+	size   int64
+
+	mu     sync.Mutex
+	cached int64
+	plain  []byte
+}
+
+// openSealed reads the header of an encrypted file of fileSize bytes from src
+func (r *Repository) openSealed(src io.ReaderAt, closer io.Closer, fileSize int64) (*sealedFile, error) {
+	key, err := r.objectKey()
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, fmt.Errorf("%w: object file is encrypted but the repository is not", errDecrypt)
+	}
+	headerSize := int64(len(encryptionMagic) + encryptionSaltSize)
+	salt := make([]byte, encryptionSaltSize)
+	if _, err := src.ReadAt(salt, int64(len(encryptionMagic))); err != nil {
+		return nil, fmt.Errorf("%w: truncated header", errDecrypt)
+	}
+	aead, err := fileCipher(key, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	sealedChunk := int64(encryptionChunk + aead.Overhead())
+	body := fileSize - headerSize
+	chunks, last := body/sealedChunk, body%sealedChunk
+	if last > 0 {
+		if last < int64(aead.Overhead()) {
+			return nil, fmt.Errorf("%w: truncated chunk", errDecrypt)
+		}
+		chunks++
+	} else {
+		last = sealedChunk
+	}
+	if chunks == 0 {
+		return nil, fmt.Errorf("%w: no content", errDecrypt)
+	}
+	return &sealedFile{
+		src:    io.NewSectionReader(src, headerSize, body),
+		closer: closer,
+		aead:   aead,
+		chunks: chunks,
+		size:   (chunks-1)*encryptionChunk + last - int64(aead.Overhead()),
+		cached: -1,
+	}, nil
+}
+
+// Size is the length of the decrypted content
+func (f *sealedFile) Size() int64 { return f.size }
+
+// ReadAt reads decrypted content
+func (f *sealedFile) ReadAt(p []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for n < len(p) {
+		if off >= f.size {
+			return n, io.EOF
+		}
+		i := off / encryptionChunk
+		if err := f.load(i); err != nil {
+			return n, err
+		}
+		copied := copy(p[n:], f.plain[off-i*encryptionChunk:])
+		n += copied
+		off += int64(copied)
+	}
+	return n, nil
+}
+
+// load decrypts chunk i into the cache
+func (f *sealedFile) load(i int64) error {
+	if f.cached == i {
+		return nil
+	}
+	sealedChunk := int64(encryptionChunk + f.aead.Overhead())
+	sealed := make([]byte, sealedChunk)
+	n, err := f.src.ReadAt(sealed, i*sealedChunk)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	final := i == f.chunks-1
+	plain, err := f.aead.Open(f.plain[:0], chunkNonce(f.aead, uint64(i)), sealed[:n], chunkData(final))
+	if err != nil {
+		f.cached = -1
+		return fmt.Errorf("%w: chunk %d does not authenticate", errDecrypt, i)
+	}
+	f.plain, f.cached = plain, i
+	return nil
+}
+
+// Close closes the underlying file
+func (f *sealedFile) Close() error {
+	if f.closer == nil {
+		return nil
+	}
+	return f.closer.Close()
+}
+
+// writeObjectFile writes data, e.g. a received pack, as an object file at path
+func (r *Repository) writeObjectFile(path string, data []byte) error {
+	var buf bytes.Buffer
+	sealed, err := r.sealObjectFile(&buf)
+	if err != nil {
+		return err
+	}
+	if _, err := sealed.Write(data); err != nil {
+		return err
+	}
+	if err := sealed.Close(); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, buf.Bytes()); err != nil {
+		return err
+	}
+	return r.adjustSharedPerm(path)
+}
+
+// encryptionConfig lists the settings an encrypted repository's key is
+// derived and checked with, the marker last
+var encryptionConfig = []string{"encryption.salt", "encryption.iterations", "encryption.check", "encryption.keyFile", EncryptionKey}
+
+// copyEncryptionConfig makes dst encrypted with the same key as src, so
+// object files can be shared between them as they are
+func copyEncryptionConfig(src, dst *Repository) error {
+	if _, encrypted, err := src.GetConfig(EncryptionKey); err != nil || !encrypted {
+		return err
+	}
+	if err := dst.SetConfig("core.repositoryFormatVersion", "1"); err != nil {
+		return err
+	}
+	for _, key := range encryptionConfig {
+		value, ok, err := src.GetConfig(key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := dst.SetConfig(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sameEncryption reports whether the object files of a and b are sealed
+// alike, neither encrypted or both with the same key
+func sameEncryption(a, b *Repository) (bool, error) {
+	for _, key := range []string{EncryptionKey, "encryption.salt", "encryption.check"} {
+		aValue, _, err := a.GetConfig(key)
+		if err != nil {
+			return false, err
+		}
+		bValue, _, err := b.GetConfig(key)
+		if err != nil {
+			return false, err
+		}
+		if aValue != bValue {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// EncryptionStatus describes how a repository's object files are stored
+type EncryptionStatus struct {
+	Cipher  string // "" when the repository is not encrypted
+	KeyFile string
+	Sealed  int // object and pack files stored encrypted
+	Plain   int // those stored unencrypted
+}
+
+// Encryption reports whether the repository is encrypted and how many of
+// its object files are sealed. Counting sealed files needs the key.
+func (r *Repository) Encryption() (EncryptionStatus, error) {
+	var status EncryptionStatus
+	var err error
+	if status.Cipher, err = r.getConfigString(EncryptionKey, ""); err != nil {
+		return status, err
+	}
+	if status.KeyFile, err = r.getConfigString("encryption.keyFile", ""); err != nil {
+		return status, err
+	}
+	paths, err := r.objectFiles()
+	if err != nil {
+		return status, err
+	}
+	for _, path := range paths {
+		sealed, err := r.objectFileSealed(path)
+		if err != nil {
+			return status, err
+		}
+		if sealed {
+			status.Sealed++
+		} else {
+			status.Plain++
+		}
+	}
+	return status, nil
+}
+
+// EnableEncryption encrypts the repository's object files at rest from now
+// on. The key is derived from the content of keyFile, which is recorded so
+// later commands read the key from it, or without one from GVC_PASSPHRASE.
+// The loose objects and packs already stored are rewritten encrypted. In an
+// encrypted repository it only checks the key and seals any files left
+// unencrypted, e.g. by an interrupted run. It returns how many files were
+// rewritten.
+func (r *Repository) EnableEncryption(keyFile string) (int, error) {
+	if err := r.checkWritable(); err != nil {
+		return 0, err
+	}
+	if !r.usesFileStore() {
+		return 0, errNeedsFileStore
+	}
+	var secret []byte
+	if keyFile != "" {
+		var err error
+		if keyFile, err = filepath.Abs(expandHome(keyFile)); err != nil {
+			return 0, fmt.Errorf("failed to resolve key file: %w", err)
+		}
+		if secret, err = readKeyFile(keyFile); err != nil {
+			return 0, err
+		}
+	} else if secret = []byte(os.Getenv(EnvPassphrase)); len(secret) == 0 {
+		return 0, fmt.Errorf("no key: give a key file or set %s", EnvPassphrase)
+	}
+	cipherName, encrypted, err := r.GetConfig(EncryptionKey)
+	if err != nil {
+		return 0, err
+	}
+	if encrypted && cipherName != encryptionCipher {
+		return 0, fmt.Errorf("unsupported repository: unknown encryption %q", cipherName)
+	}
+
+	var key []byte
+	if encrypted {
+		salt, err := r.getConfigString("encryption.salt", "")
+		if err != nil {
+			return 0, err
+		}
+		iterations, err := r.getConfigInt("encryption.iterations", encryptionIterations)
+		if err != nil {
+			return 0, err
+		}
+		saltBytes, err := hex.DecodeString(salt)
+		if err != nil || len(saltBytes) == 0 || iterations < 1 {
+			return 0, errors.New("corrupt encryption settings: encryption.salt or encryption.iterations is invalid")
+		}
+		key = pbkdf2SHA256(secret, saltBytes, int(iterations), 32)
+		check, err := r.getConfigString("encryption.check", "")
+		if err != nil {
+			return 0, err
+		}
+		if !hmac.Equal([]byte(check), []byte(keyCheck(key))) {
+			return 0, errors.New("wrong passphrase or key file for this encrypted repository")
+		}
+	} else {
+		salt := make([]byte, encryptionSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return 0, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		key = pbkdf2SHA256(secret, salt, encryptionIterations, 32)
+		settings := [][2]string{
+			{"core.repositoryFormatVersion", "1"},
+			{"encryption.salt", hex.EncodeToString(salt)},
+			{"encryption.iterations", strconv.Itoa(encryptionIterations)},
+			{"encryption.check", keyCheck(key)},
+		}
+		for _, setting := range settings {
+			if err := r.SetConfig(setting[0], setting[1]); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if keyFile != "" {
+		if err := r.SetConfig("encryption.keyFile", keyFile); err != nil {
+			return 0, err
+		}
+	}
+	// Set last, so the repository is only marked encrypted once its key can be checked
+	if err := r.SetConfig(EncryptionKey, encryptionCipher); err != nil {
+		return 0, err
+	}
+	r.keyOnce.Do(func() {})
+	r.key, r.keyErr = key, nil
+	return r.rewriteObjectFiles(key)
+}
+
+// DisableEncryption rewrites every object file unencrypted and drops the
+// encryption settings. It returns how many files were rewritten.
+func (r *Repository) DisableEncryption() (int, error) {
+	if err := r.checkWritable(); err != nil {
+		return 0, err
+	}
+	key, err := r.objectKey()
+	if err != nil {
+		return 0, err
+	}
+	if key == nil {
+		return 0, errors.New("repository is not encrypted")
+	}
+	rewritten, err := r.rewriteObjectFiles(nil)
+	if err != nil {
+		return rewritten, err
+	}
+	// The marker goes first, so an interrupted run leaves a repository that
+	// is either still encrypted or not at all
+	for i := len(encryptionConfig) - 1; i >= 0; i-- {
+		if err := r.unsetConfig(encryptionConfig[i]); err != nil {
+			return rewritten, err
+		}
+	}
+	r.key = nil
+	return rewritten, nil
+}
+
+// objectFiles returns the paths of every loose object and pack
+func (r *Repository) objectFiles() ([]string, error) {
+	loose, err := r.listLooseObjects()
+	if err != nil {
+		return nil, err
+	}
+	packs, err := filepath.Glob(r.gitPath(PackDir, "pack-*.pack"))
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, len(loose)+len(packs))
+	for _, sha := range loose {
+		paths = append(paths, r.objectPath(sha))
+	}
+	return append(paths, packs...), nil
+}
+
+// objectFileSealed reports whether the object file at path is encrypted
+func (r *Repository) objectFileSealed(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	header := make([]byte, len(encryptionMagic))
+	n, err := f.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return isSealed(header[:n]), nil
+}
+
+// rewriteObjectFiles stores every object file sealed with key, or
+// unencrypted when it is nil, skipping those already stored that way. Each
+// file is replaced atomically, so an interrupted run leaves every object
+// readable.
+func (r *Repository) rewriteObjectFiles(key []byte) (int, error) {
+	paths, err := r.objectFiles()
+	if err != nil {
+		return 0, err
+	}
+	r.resetPacks()
+	rewritten := 0
+	for _, path := range paths {
+		sealed, err := r.objectFileSealed(path)
+		if err != nil {
+			return rewritten, err
+		}
+		if sealed == (key != nil) {
+			continue
+		}
+		if err := r.rewriteObjectFile(path, key); err != nil {
+			return rewritten, err
+		}
+		rewritten++
+	}
+	return rewritten, nil
+}
+
+// rewriteObjectFile replaces the object file at path with its content
+// sealed with key, or unencrypted when it is nil
+func (r *Repository) rewriteObjectFile(path string, key []byte) error {
+	src, err := r.openObjectFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer src.Close()
+	tmp, err := createTemp(filepath.Dir(path), "tmp_")
+	if err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w, err := sealWith(tmp, key)
+	if err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+	if _, err := io.Copy(w, io.NewSectionReader(src, 0, src.Size())); err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+	if err := r.adjustSharedPerm(tmp.Name()); err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to rewrite %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package gvc
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// worktreeFormat is how files in the working tree differ from the blobs
+// they are stored as, read from the config once per repository handle
+type worktreeFormat struct {
+	// fileMode is core.filemode: whether the executable bit on disk means
+	// anything. It is true by default except on Windows, which has none.
+	fileMode bool
+	// autocrlf is core.autocrlf: "true" stores text files with LF line
+	// endings and checks them out with CRLF, "input" only converts when
+	// storing, and "false", the default, leaves line endings alone
+	autocrlf string
+	// precomposeUnicode is core.precomposeUnicode: whether paths are stored
+	// in Unicode normalization form C. It is true by default on macOS, whose
+	// file systems hand back names decomposed but open either spelling.
+	precomposeUnicode bool
+}
+
+// worktreeFormat returns the working tree settings, reading them once.
+// Files are hashed from several goroutines, so the first read is guarded.
+func (r *Repository) worktreeFormat() (worktreeFormat, error) {
+	r.worktreeFmtOnce.Do(func() {
+		format := worktreeFormat{autocrlf: "false"}
+		if format.fileMode, r.worktreeFmtErr = r.getConfigBool("core.filemode", runtime.GOOS != "windows"); r.worktreeFmtErr != nil {
+			return
+		}
+		if format.precomposeUnicode, r.worktreeFmtErr = r.getConfigBool("core.precomposeUnicode", runtime.GOOS == "darwin"); r.worktreeFmtErr != nil {
+			return
+		}
+		value, err := r.getConfigString("core.autocrlf", "false")
+		if err != nil {
+			r.worktreeFmtErr = err
+			return
+		}
+		if strings.EqualFold(value, "input") {
+			format.autocrlf = "input"
+		} else if enabled, err := parseConfigBool("core.autocrlf", value); err != nil {
+			r.worktreeFmtErr = err
+			return
+		} else if enabled {
+			format.autocrlf = "true"
+		}
+		r.worktreeFmt = format
+	})
+	return r.worktreeFmt, r.worktreeFmtErr
+}
+
+// workingMode returns the tree mode to record for a file on disk. When
+// core.filemode is off, the executable bit is ignored: a file keeps the
+// mode previousMode, from the index, gave it, and a new one is regular.
+func (r *Repository) workingMode(fileInfo os.FileInfo, previousMode string) (string, error) {
+	mode := modeForFile(fileInfo)
+	if mode == SymlinkMode {
+		return mode, nil
+	}
+	format, err := r.worktreeFormat()
+	if err != nil || format.fileMode {
+		return mode, err
+	}
+	if previousMode == "100755" {
+		return previousMode, nil
+	}
+	return "100644", nil
+}
+
+// toStoredEOL converts the content of a working tree file to the form it is
+// stored in: with core.autocrlf set to true or input, a text file's CRLF
+// line endings become LF. It reports whether anything changed.
+func (r *Repository) toStoredEOL(content []byte) ([]byte, bool, error) {
+	format, err := r.worktreeFormat()
+	if err != nil || format.autocrlf == "false" {
+		return content, false, err
+	}
+	if isBinary(content) || !bytes.Contains(content, []byte("\r\n")) {
+		return content, false, nil
+	}
+	return bytes.ReplaceAll(content, []byte("\r\n"), []byte("\n")), true, nil
+}
+
+// toWorkingEOL converts a blob to the form it is checked out in: with
+// core.autocrlf set to true, a text file's LF line endings become CRLF. As
+// in Git, a blob already holding a CR is left alone, so checking it out and
+// adding it again does not change it.
+func (r *Repository) toWorkingEOL(content []byte) ([]byte, error) {
+	format, err := r.worktreeFormat()
+	if err != nil || format.autocrlf != "true" {
+		return content, err
+	}
+	if isBinary(content) || bytes.IndexByte(content, '\r') >= 0 || bytes.IndexByte(content, '\n') < 0 {
+		return content, nil
+	}
+	return bytes.ReplaceAll(content, []byte("\n"), []byte("\r\n")), nil
+}
+
+// readWorkingContent reads a working tree file as it would be stored,
+// with line endings converted by toStoredEOL
+func (r *Repository) readWorkingContent(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	content, _, err = r.toStoredEOL(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return content, nil
+}
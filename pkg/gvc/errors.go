@@ -0,0 +1,55 @@
+package gvc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictError reports an operation that stopped on conflicts that need resolving
+type ConflictError struct {
+	Err error
+}
+
+func (e *ConflictError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+// conflictError wraps err as a ConflictError
+func conflictError(err error) error {
+	return &ConflictError{Err: err}
+}
+
+// CorruptObjectError reports a loose object that cannot be inflated or parsed,
+// along with what is known about recovering it
+type CorruptObjectError struct {
+	SHA      string
+	Path     string   // loose object file
+	Err      error    // what failed while decoding
+	Refs     []string // refs whose history reaches the object
+	PackCopy string   // pack holding an intact copy, if any
+}
+
+func (e *CorruptObjectError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "object %s is corrupt: %v", e.SHA, e.Err)
+	fmt.Fprintf(&b, "\n  loose file: %s", e.Path)
+	if len(e.Refs) > 0 {
+		fmt.Fprintf(&b, "\n  referenced by: %s", strings.Join(e.Refs, ", "))
+	} else {
+		b.WriteString("\n  referenced by: no ref")
+	}
+	if e.PackCopy != "" {
+		fmt.Fprintf(&b, "\n  hint: an intact copy exists in %s; remove the loose file to use it", e.PackCopy)
+	} else {
+		b.WriteString("\n  hint: no other copy exists; re-add the content from the working tree if it is still there")
+	}
+	return b.String()
+}
+
+func (e *CorruptObjectError) Unwrap() error {
+	return e.Err
+}
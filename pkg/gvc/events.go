@@ -0,0 +1,92 @@
+package gvc
+
+import (
+	"fmt"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// Observer receives a repository's events as they happen, so an application
+// embedding the library can react to them without polling or running gvc.
+// Any callback may be nil. Callbacks run on the goroutine making the change,
+// once it is written, and must not modify the repository themselves.
+type Observer struct {
+	// OnCommit is called for every commit recorded on the current branch:
+	// by Commit, and by cherry-pick, revert, merge and rebase
+	OnCommit func(commit *object.Commit)
+	// OnRefUpdate is called whenever a branch, tag, HEAD or other ref moves,
+	// is created or is deleted
+	OnRefUpdate func(update RefUpdate)
+	// OnCheckoutProgress is called after each file a checkout writes or
+	// removes, such as when switching branches or cloning
+	OnCheckoutProgress func(progress CheckoutProgress)
+	// OnTransfer is called once a fetch, push or clone has moved objects,
+	// with what it moved
+	OnTransfer func(stats TransferStats)
+}
+
+// RefUpdate describes a ref moving
+type RefUpdate struct {
+	Name    string // e.g. HEAD or refs/heads/main
+	OldSHA  string // "" when the ref is created
+	NewSHA  string // "" when the ref is deleted
+	Message string // the reflog message, e.g. "commit: Fix typo"
+}
+
+// CheckoutProgress reports how far a checkout has got
+type CheckoutProgress struct {
+	Path  string // the file just written or removed
+	Done  int    // files handled so far, including Path
+	Total int    // files the checkout changes
+}
+
+// Observe registers callbacks for the repository's events. Observers are
+// called in the order they were added.
+func (r *Repository) Observe(observer Observer) {
+	r.observers = append(r.observers, observer)
+}
+
+// notifyCommit tells observers about a commit recorded on the current branch
+func (r *Repository) notifyCommit(commitSHA string) {
+	var commit *object.Commit
+	for _, observer := range r.observers {
+		if observer.OnCommit == nil {
+			continue
+		}
+		if commit == nil {
+			var err error
+			if commit, err = r.ReadCommit(commitSHA); err != nil {
+				fmt.Fprintf(r.Out, "warning: %v\n", err)
+				return
+			}
+		}
+		observer.OnCommit(commit)
+	}
+}
+
+// notifyRefUpdate tells observers about a ref update
+func (r *Repository) notifyRefUpdate(update RefUpdate) {
+	for _, observer := range r.observers {
+		if observer.OnRefUpdate != nil {
+			observer.OnRefUpdate(update)
+		}
+	}
+}
+
+// notifyTransfer tells observers what a transfer moved
+func (r *Repository) notifyTransfer(stats TransferStats) {
+	for _, observer := range r.observers {
+		if observer.OnTransfer != nil {
+			observer.OnTransfer(stats)
+		}
+	}
+}
+
+// notifyCheckout tells observers that a checkout has handled another file
+func (r *Repository) notifyCheckout(path string, done, total int) {
+	for _, observer := range r.observers {
+		if observer.OnCheckoutProgress != nil {
+			observer.OnCheckoutProgress(CheckoutProgress{Path: path, Done: done, Total: total})
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package gvc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// FastExport writes the history selected by revision arguments, every
+// branch and tag when there are none, as a git fast-import stream: the
+// blobs, then each commit after its parents as the changes from its first
+// parent, then the refs and annotated tags. Commits keep their authorship
+// and messages, so importing the stream into Git rebuilds the same commits
+// unless an identity has to be cleaned up for Git to accept it. Signatures
+// are dropped with a warning.
+func (r *Repository) FastExport(w io.Writer, args []string) error {
+	if len(args) == 0 {
+		args = []string{"--branches", "--tags"}
+	}
+	revRange, err := r.ParseRevRange(args)
+	if err != nil {
+		return err
+	}
+	refs, err := r.exportRefs(args)
+	if err != nil {
+		return err
+	}
+	nodes, err := r.WalkRevisions(revRange)
+	if err != nil {
+		return err
+	}
+	nodes = topoOrder(nodes)
+
+	// Name each commit after a ref it is reachable from, so each commit
+	// command builds on a branch the stream creates. Commits selected by
+	// revisions that are not refs go to the current branch.
+	defaultName, err := r.HeadRef()
+	if err != nil {
+		return err
+	}
+	if defaultName == "" {
+		defaultName = HeadsDir + "/main"
+	}
+	names := make(map[string]string, len(nodes))
+	for _, ref := range refs {
+		if _, ok := names[ref.SHA]; !ok {
+			names[ref.SHA] = ref.Name
+		}
+	}
+	for _, node := range nodes {
+		if _, ok := names[node.SHA]; !ok {
+			names[node.SHA] = defaultName
+		}
+		for _, parent := range node.Parents {
+			if _, ok := names[parent]; !ok {
+				names[parent] = names[node.SHA]
+			}
+		}
+	}
+
+	e := &fastExporter{r: r, w: bufio.NewWriter(w), marks: make(map[string]int)}
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if err := e.exportCommit(nodes[i].SHA, names[nodes[i].SHA]); err != nil {
+			return err
+		}
+	}
+	for _, ref := range refs {
+		if err := e.exportRef(ref); err != nil {
+			return err
+		}
+	}
+	if err := e.w.Flush(); err != nil {
+		return fmt.Errorf("failed to write fast-export stream: %w", err)
+	}
+	return nil
+}
+
+// exportRefs returns the refs FastExport ends the stream with: those named
+// by its arguments, without HEAD, which Git's fast-import cannot update
+func (r *Repository) exportRefs(args []string) ([]Ref, error) {
+	named, err := r.bundleRefs(args)
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string)
+	all, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range all {
+		if ref.Tag != "" {
+			tags[ref.Name] = ref.Tag
+		}
+	}
+	var refs []Ref
+	for _, ref := range named {
+		if ref.Name == HeadFile {
+			continue
+		}
+		ref.Tag = tags[ref.Name]
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
+
+// fastExporter writes one fast-export stream, numbering the objects it has
+// written with marks
+type fastExporter struct {
+	r     *Repository
+	w     *bufio.Writer
+	marks map[string]int // object SHA to mark
+}
+
+// mark gives an object the next mark
+func (e *fastExporter) mark(sha string) int {
+	e.marks[sha] = len(e.marks) + 1
+	return e.marks[sha]
+}
+
+// data writes a data command holding content. As in Git, the commands
+// that end with one follow it with a newline.
+func (e *fastExporter) data(content []byte) {
+	fmt.Fprintf(e.w, "data %d\n", len(content))
+	e.w.Write(content)
+}
+
+// exportCommit writes the blobs a commit adds and then the commit itself,
+// as a commit to ref
+func (e *fastExporter) exportCommit(commitSHA, ref string) error {
+	_, content, err := e.r.ReadObject(commitSHA)
+	if err != nil {
+		return fmt.Errorf("failed to read commit %s: %w", commitSHA, err)
+	}
+	header, message, _ := bytes.Cut(content, []byte("\n\n"))
+	var treeSHA, author, committer, encoding string
+	var parents []string
+	for _, line := range strings.Split(string(header), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			treeSHA = value
+		case "parent":
+			parents = append(parents, value)
+		case "author":
+			author = value
+		case "committer":
+			committer = value
+		case "encoding":
+			encoding = value
+		case "gpgsig", "gpgsig-sha256":
+			fmt.Fprintf(e.r.Out, "warning: dropping the signature of commit %s\n", commitSHA)
+		}
+	}
+	if committer == "" {
+		committer = author
+	}
+
+	// Parents that are not part of the stream are left out, as in Git, so
+	// the first exported parent is the one the changes are listed against
+	var from string
+	var merges []int
+	for _, parent := range parents {
+		mark, ok := e.marks[parent]
+		if !ok {
+			continue
+		}
+		if from == "" {
+			from = parent
+		} else {
+			merges = append(merges, mark)
+		}
+	}
+	var baseTree string
+	if from != "" {
+		parent, err := e.r.ReadCommit(from)
+		if err != nil {
+			return err
+		}
+		baseTree = parent.TreeSHA
+	}
+	before, err := e.r.flattenTree(baseTree)
+	if err != nil {
+		return err
+	}
+	after, err := e.r.flattenTree(treeSHA)
+	if err != nil {
+		return err
+	}
+
+	var deleted, changed []string
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			deleted = append(deleted, path)
+		}
+	}
+	for path, entry := range after {
+		if old, ok := before[path]; !ok || old.SHA != entry.SHA || old.Mode != entry.Mode {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(deleted)
+	sort.Strings(changed)
+	for _, path := range changed {
+		entry := after[path]
+		if _, ok := e.marks[entry.SHA]; ok || entry.Mode == GitlinkMode {
+			continue
+		}
+		_, blob, err := e.r.ReadObject(entry.SHA)
+		if err != nil {
+			return fmt.Errorf("failed to read %s in commit %s: %w", path, commitSHA, err)
+		}
+		fmt.Fprintf(e.w, "blob\nmark :%d\n", e.mark(entry.SHA))
+		e.data(blob)
+		e.w.WriteString("\n")
+	}
+
+	if from == "" {
+		fmt.Fprintf(e.w, "reset %s\n", ref)
+	}
+	fmt.Fprintf(e.w, "commit %s\nmark :%d\n", ref, e.mark(commitSHA))
+	fmt.Fprintf(e.w, "author %s\ncommitter %s\n", fastExportIdent(author), fastExportIdent(committer))
+	if encoding != "" {
+		fmt.Fprintf(e.w, "encoding %s\n", encoding)
+	}
+	e.data(message)
+	if !bytes.HasSuffix(message, []byte("\n")) {
+		e.w.WriteString("\n")
+	}
+	if from != "" {
+		fmt.Fprintf(e.w, "from :%d\n", e.marks[from])
+	}
+	for _, mark := range merges {
+		fmt.Fprintf(e.w, "merge :%d\n", mark)
+	}
+	// Deletions go first, so a file replaced by a directory is gone before
+	// the files below it are added
+	for _, path := range deleted {
+		fmt.Fprintf(e.w, "D %s\n", fastExportPath(path))
+	}
+	for _, path := range changed {
+		entry := after[path]
+		dataref := entry.SHA
+		if entry.Mode != GitlinkMode {
+			dataref = ":" + strconv.Itoa(e.marks[entry.SHA])
+		}
+		fmt.Fprintf(e.w, "M %s %s %s\n", entry.Mode, dataref, fastExportPath(path))
+	}
+	e.w.WriteString("\n")
+	return nil
+}
+
+// exportRef points a ref at its exported commit, recreating an annotated
+// tag with its tagger and message
+func (e *fastExporter) exportRef(ref Ref) error {
+	mark, ok := e.marks[ref.SHA]
+	if !ok {
+		return nil // excluded by the revision arguments
+	}
+	if ref.Tag == "" {
+		fmt.Fprintf(e.w, "reset %s\nfrom :%d\n\n", ref.Name, mark)
+		return nil
+	}
+	_, content, err := e.r.ReadObject(ref.Tag)
+	if err != nil {
+		return fmt.Errorf("failed to read tag %s: %w", ref.Name, err)
+	}
+	tag, err := object.ParseTag(ref.Tag, content)
+	if err != nil {
+		return fmt.Errorf("failed to parse tag %s: %w", ref.Name, err)
+	}
+	if tag.Object != ref.SHA {
+		fmt.Fprintf(e.r.Out, "warning: skipping tag %s, which tags another tag\n", ref.Name)
+		return nil
+	}
+	_, message, _ := bytes.Cut(content, []byte("\n\n"))
+	fmt.Fprintf(e.w, "tag %s\nfrom :%d\n", strings.TrimPrefix(ref.Name, "refs/tags/"), mark)
+	if tag.Tagger != "" {
+		fmt.Fprintf(e.w, "tagger %s\n", fastExportIdent(tag.Tagger))
+	}
+	e.data(message)
+	e.w.WriteString("\n")
+	return nil
+}
+
+// fastExportIdent returns an author, committer or tagger line Git's
+// fast-import accepts. Git allows no < or > in a name, so gvc's default
+// identity, whose name holds an email in angle brackets, loses them.
+func fastExportIdent(ident string) string {
+	open := strings.LastIndex(ident, " <")
+	if open < 0 || strings.Count(ident, "<") == 1 {
+		return ident
+	}
+	name := strings.NewReplacer("<", "", ">", "").Replace(ident[:open])
+	return name + ident[open:]
+}
+
+// fastExportPath quotes a path the way fast-import reads it when it would
+// otherwise be misread: when it starts with a quote or holds a newline
+func fastExportPath(path string) string {
+	if !strings.HasPrefix(path, `"`) && !strings.Contains(path, "\n") {
+		return path
+	}
+	var quoted strings.Builder
+	quoted.WriteByte('"')
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; {
+		case c == '"' || c == '\\':
+			quoted.WriteByte('\\')
+			quoted.WriteByte(c)
+		case c == '\n':
+			quoted.WriteString(`\n`)
+		case c < ' ':
+			fmt.Fprintf(&quoted, `\%03o`, c)
+		default:
+			quoted.WriteByte(c)
+		}
+	}
+	quoted.WriteByte('"')
+	return quoted.String()
+}
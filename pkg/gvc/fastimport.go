@@ -0,0 +1,707 @@
+package gvc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// FastImportOptions controls how FastImport updates refs
+type FastImportOptions struct {
+	// Force moves refs to commits that do not contain their current
+	// commit, which FastImport otherwise refuses
+	Force bool
+}
+
+// fastImportIdent matches the raw "Name <email> <seconds> <zone>" form of
+// an author, committer or tagger, the only date format FastImport reads
+var fastImportIdent = regexp.MustCompile(`^[^<>]*<[^<>]*> \d+ [+-]\d{4}$`)
+
+// FastImport reads a git fast-import stream, such as one written by
+// 'git fast-export --all', storing its blobs, commits and annotated tags and
+// then pointing its branches and tags at them. It returns the refs it
+// updated. The working tree and index are left alone, unless the import
+// gives the current branch its first commit: then it is checked out, as in
+// a clone. Notes and the commands that query the importer, ls, cat-blob and
+// get-mark, are not supported.
+func (r *Repository) FastImport(in io.Reader, opts FastImportOptions) ([]Ref, error) {
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	head, err := r.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	imp := &fastImporter{
+		r:        r,
+		in:       bufio.NewReader(in),
+		marks:    make(map[int]string),
+		branches: make(map[string]string),
+	}
+	if err := imp.run(); err != nil {
+		if imp.line > 0 {
+			return nil, fmt.Errorf("fast-import stream line %d: %w", imp.line, err)
+		}
+		return nil, err
+	}
+	refs, err := imp.updateRefs(opts.Force)
+	if err != nil || head != "" {
+		return refs, err
+	}
+	return refs, r.checkoutImported()
+}
+
+// checkoutImported checks out the commit an import gave the current branch,
+// unless something was staged or files are in the way
+func (r *Repository) checkoutImported() error {
+	staged, err := r.stagedEntries()
+	if err != nil || len(staged) > 0 {
+		return err
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	for path := range head {
+		if _, onDisk, err := r.readWorkingEntry(path, false); err != nil {
+			return err
+		} else if onDisk {
+			fmt.Fprintf(r.Out, "warning: not checking out the imported commit: %s is in the way\n", path)
+			return nil
+		}
+	}
+	return r.checkoutEntries(map[string]IndexEntry{}, head)
+}
+
+// fastImporter is the state of one FastImport: what the marks name, and
+// where each branch the stream touched now points
+type fastImporter struct {
+	r        *Repository
+	in       *bufio.Reader
+	line     int    // number of the last line read
+	peeked   string // a line read but not yet handled
+	hasPeek  bool
+	marks    map[int]string
+	branches map[string]string // ref to commit or tag, "" when reset
+	order    []string          // the refs in branches, in the order first touched
+}
+
+// next returns the next line without its newline, and false at the end of the stream
+func (imp *fastImporter) next() (string, bool, error) {
+	if imp.hasPeek {
+		imp.hasPeek = false
+		return imp.peeked, true, nil
+	}
+	line, err := imp.in.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return "", false, nil
+	}
+	if err != nil && err != io.EOF {
+		return "", false, fmt.Errorf("failed to read fast-import stream: %w", err)
+	}
+	imp.line++
+	return strings.TrimSuffix(line, "\n"), true, nil
+}
+
+// unread puts back the line next returned
+func (imp *fastImporter) unread(line string) {
+	imp.peeked, imp.hasPeek = line, true
+}
+
+// optional returns the value of the next line when it starts with prefix,
+// leaving any other line to be read again
+func (imp *fastImporter) optional(prefix string) (string, bool, error) {
+	line, ok, err := imp.next()
+	if err != nil || !ok {
+		return "", false, err
+	}
+	if value, found := strings.CutPrefix(line, prefix); found {
+		return value, true, nil
+	}
+	imp.unread(line)
+	return "", false, nil
+}
+
+// run handles every command in the stream
+func (imp *fastImporter) run() error {
+	for {
+		line, ok, err := imp.next()
+		if err != nil || !ok {
+			return err
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		command, arg, _ := strings.Cut(line, " ")
+		switch command {
+		case "checkpoint":
+		case "blob":
+			err = imp.blob()
+		case "commit":
+			err = imp.commit(arg)
+		case "tag":
+			err = imp.tag(arg)
+		case "reset":
+			err = imp.reset(arg)
+		case "progress":
+			fmt.Fprintln(imp.r.Out, "progress "+arg)
+		case "feature":
+			switch arg {
+			case "done", "date-format=raw":
+			default:
+				err = fmt.Errorf("feature %s is not supported", arg)
+			}
+		case "option":
+			// Options are for the importer that wrote the stream's commands
+		case "done":
+			return nil
+		default:
+			err = fmt.Errorf("unsupported command: %s", line)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// data reads a data command: "data <count>" followed by that many bytes,
+// or "data <<<delimiter>" followed by lines up to the delimiter
+func (imp *fastImporter) data() ([]byte, error) {
+	line, ok, err := imp.next()
+	if err != nil {
+		return nil, err
+	}
+	arg, found := strings.CutPrefix(line, "data ")
+	if !ok || !found {
+		return nil, fmt.Errorf("expected data, got %q", line)
+	}
+
+	var content []byte
+	if delimiter, ok := strings.CutPrefix(arg, "<<"); ok {
+		for {
+			line, ok, err := imp.next()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("data ended before %s", delimiter)
+			}
+			if line == delimiter {
+				break
+			}
+			content = append(content, line+"\n"...)
+		}
+	} else {
+		size, err := strconv.Atoi(arg)
+		if err != nil || size < 0 {
+			return nil, fmt.Errorf("invalid data size: %s", arg)
+		}
+		content = make([]byte, size)
+		if _, err := io.ReadFull(imp.in, content); err != nil {
+			return nil, fmt.Errorf("failed to read %d bytes of data: %w", size, err)
+		}
+		imp.line += bytes.Count(content, []byte("\n"))
+	}
+	// A newline may follow the data
+	if next, err := imp.in.Peek(1); err == nil && next[0] == '\n' {
+		imp.in.ReadByte()
+		imp.line++
+	}
+	return content, nil
+}
+
+// setMark records what the mark given as ":<n>" names
+func (imp *fastImporter) setMark(mark, sha string) error {
+	n, err := strconv.Atoi(strings.TrimPrefix(mark, ":"))
+	if err != nil || !strings.HasPrefix(mark, ":") {
+		return fmt.Errorf("invalid mark: %s", mark)
+	}
+	imp.marks[n] = sha
+	return nil
+}
+
+// lookup resolves a data or commit reference: a mark, a SHA, or a branch
+// written by the stream or already in the repository
+func (imp *fastImporter) lookup(ref string) (string, error) {
+	if mark, ok := strings.CutPrefix(ref, ":"); ok {
+		n, err := strconv.Atoi(mark)
+		if err != nil {
+			return "", fmt.Errorf("invalid mark: %s", ref)
+		}
+		sha, ok := imp.marks[n]
+		if !ok {
+			return "", fmt.Errorf("mark %s not declared", ref)
+		}
+		return sha, nil
+	}
+	if imp.r.Format.ValidateSHA(ref) == nil {
+		return ref, nil
+	}
+	if sha, ok := imp.branches[ref]; ok && sha != "" {
+		return sha, nil
+	}
+	return imp.r.ResolveCommit(ref)
+}
+
+// blob stores a blob and gives it the mark that names it
+func (imp *fastImporter) blob() error {
+	mark, hasMark, err := imp.optional("mark ")
+	if err != nil {
+		return err
+	}
+	if _, _, err := imp.optional("original-oid "); err != nil {
+		return err
+	}
+	content, err := imp.data()
+	if err != nil {
+		return err
+	}
+	sha, err := imp.r.WriteObject(object.BlobObject, content)
+	if err != nil {
+		return err
+	}
+	if hasMark {
+		return imp.setMark(mark, sha)
+	}
+	return nil
+}
+
+// touch records that the stream moved ref
+func (imp *fastImporter) touch(ref, sha string) {
+	if _, ok := imp.branches[ref]; !ok {
+		imp.order = append(imp.order, ref)
+	}
+	imp.branches[ref] = sha
+}
+
+// reset points a branch at a commit, or empties it so that the next commit
+// to it starts a new history
+func (imp *fastImporter) reset(ref string) error {
+	from, hasFrom, err := imp.optional("from ")
+	if err != nil {
+		return err
+	}
+	sha := ""
+	if hasFrom {
+		if sha, err = imp.lookup(from); err != nil {
+			return err
+		}
+	}
+	imp.touch(ref, sha)
+	return nil
+}
+
+// commit builds a commit on a branch from the branch's current commit, or
+// the one named by from, and the file changes that follow
+func (imp *fastImporter) commit(ref string) error {
+	var mark, author, committer, encoding string
+	var hasMark bool
+	for {
+		line, ok, err := imp.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.New("commit ended early")
+		}
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "mark":
+			mark, hasMark = value, true
+			continue
+		case "original-oid":
+			continue
+		case "author":
+			author = value
+			continue
+		case "committer":
+			committer = value
+			continue
+		case "encoding":
+			encoding = value
+			continue
+		}
+		imp.unread(line)
+		break
+	}
+	if committer == "" {
+		return fmt.Errorf("commit to %s has no committer", ref)
+	}
+	if author == "" {
+		author = committer
+	}
+	for _, ident := range []string{author, committer} {
+		if !fastImportIdent.MatchString(ident) {
+			return fmt.Errorf("invalid identity: %s", ident)
+		}
+	}
+	message, err := imp.data()
+	if err != nil {
+		return err
+	}
+
+	// Without from, a commit continues its branch
+	var parents []string
+	if from, ok, err := imp.optional("from "); err != nil {
+		return err
+	} else if ok {
+		sha, err := imp.lookup(from)
+		if err != nil {
+			return err
+		}
+		parents = append(parents, sha)
+	} else if sha, ok := imp.branches[ref]; ok {
+		if sha != "" {
+			parents = append(parents, sha)
+		}
+	} else if sha, err := imp.r.ReadRef(ref); err != nil {
+		return err
+	} else if sha != "" {
+		parents = append(parents, sha)
+	}
+	for {
+		merge, ok, err := imp.optional("merge ")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		sha, err := imp.lookup(merge)
+		if err != nil {
+			return err
+		}
+		parents = append(parents, sha)
+	}
+
+	files := make(map[string]IndexEntry)
+	if len(parents) > 0 {
+		parent, err := imp.r.ReadCommit(parents[0])
+		if err != nil {
+			return err
+		}
+		if files, err = imp.r.flattenTree(parent.TreeSHA); err != nil {
+			return err
+		}
+	}
+	if err := imp.fileChanges(files); err != nil {
+		return err
+	}
+	entries := make([]IndexEntry, 0, len(files))
+	for _, entry := range files {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	treeSHA, err := imp.r.buildTree(entries)
+	if err != nil {
+		return err
+	}
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "tree %s\n", treeSHA)
+	for _, parent := range parents {
+		fmt.Fprintf(&content, "parent %s\n", parent)
+	}
+	fmt.Fprintf(&content, "author %s\ncommitter %s\n", author, committer)
+	if encoding != "" {
+		fmt.Fprintf(&content, "encoding %s\n", encoding)
+	}
+	content.WriteString("\n")
+	content.Write(message)
+	sha, err := imp.r.WriteObject(object.CommitObject, content.Bytes())
+	if err != nil {
+		return err
+	}
+	imp.touch(ref, sha)
+	if hasMark {
+		return imp.setMark(mark, sha)
+	}
+	return nil
+}
+
+// fileChanges applies the M, D, C, R and deleteall commands of a commit to
+// its files, keyed by path
+func (imp *fastImporter) fileChanges(files map[string]IndexEntry) error {
+	for {
+		line, ok, err := imp.next()
+		if err != nil || !ok {
+			return err
+		}
+		command, arg, _ := strings.Cut(line, " ")
+		switch command {
+		case "M":
+			err = imp.modify(files, arg)
+		case "D":
+			var path string
+			if path, _, err = fastImportPath(arg, true); err == nil {
+				removeFastImportPath(files, path)
+			}
+		case "C", "R":
+			var src, dst, rest string
+			if src, rest, err = fastImportPath(arg, false); err != nil {
+				return err
+			}
+			if dst, _, err = fastImportPath(rest, true); err != nil {
+				return err
+			}
+			copied := make(map[string]IndexEntry)
+			for path, entry := range files {
+				if rel, ok := strings.CutPrefix(path, src); ok && (rel == "" || rel[0] == '/') {
+					entry.Path = dst + rel
+					copied[entry.Path] = entry
+				}
+			}
+			if len(copied) == 0 {
+				return fmt.Errorf("path %s not in branch", src)
+			}
+			if command == "R" {
+				removeFastImportPath(files, src)
+			}
+			removeFastImportPath(files, dst)
+			for path, entry := range copied {
+				files[path] = entry
+			}
+		case "deleteall":
+			clear(files)
+		case "N":
+			return errors.New("notes are not supported")
+		case "":
+		default:
+			imp.unread(line)
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// modify applies "M <mode> <dataref> <path>", where dataref is a mark, a
+// SHA, or inline with the content in a data command after the line
+func (imp *fastImporter) modify(files map[string]IndexEntry, arg string) error {
+	fields := strings.SplitN(arg, " ", 3)
+	if len(fields) != 3 {
+		return fmt.Errorf("invalid M command: M %s", arg)
+	}
+	mode, dataref := fields[0], fields[1]
+	path, _, err := fastImportPath(fields[2], true)
+	if err != nil {
+		return err
+	}
+	switch mode {
+	case "644":
+		mode = "100644"
+	case "755":
+		mode = "100755"
+	case "040000":
+		mode = "40000"
+	}
+
+	var sha string
+	if dataref == "inline" {
+		content, err := imp.data()
+		if err != nil {
+			return err
+		}
+		if sha, err = imp.r.WriteObject(object.BlobObject, content); err != nil {
+			return err
+		}
+	} else if sha, err = imp.lookup(dataref); err != nil {
+		return err
+	}
+
+	removeFastImportPath(files, path)
+	// A file in the way of the path's directories goes
+	for dir := path; strings.Contains(dir, "/"); {
+		dir = dir[:strings.LastIndex(dir, "/")]
+		delete(files, dir)
+	}
+	switch mode {
+	case "100644", "100755", "120000", GitlinkMode:
+		files[path] = IndexEntry{Path: path, SHA: sha, Mode: mode}
+	case "40000":
+		tree, err := imp.r.flattenTree(sha)
+		if err != nil {
+			return err
+		}
+		for rel, entry := range tree {
+			entry.Path = path + "/" + rel
+			files[entry.Path] = entry
+		}
+	default:
+		return fmt.Errorf("invalid mode %s for %s", mode, path)
+	}
+	return nil
+}
+
+// removeFastImportPath deletes a file, or every file below a directory
+func removeFastImportPath(files map[string]IndexEntry, path string) {
+	delete(files, path)
+	for existing := range files {
+		if strings.HasPrefix(existing, path+"/") {
+			delete(files, existing)
+		}
+	}
+}
+
+// fastImportPath reads a path from the start of s and returns the rest. A
+// path is C-quoted when it starts with a quote; otherwise it runs to the end
+// of s when last is set, and to the first space when not.
+func fastImportPath(s string, last bool) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		if last {
+			return s, "", nil
+		}
+		path, rest, ok := strings.Cut(s, " ")
+		if !ok {
+			return "", "", fmt.Errorf("missing destination after %s", s)
+		}
+		return path, rest, nil
+	}
+
+	var path []byte
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			rest := strings.TrimPrefix(s[i+1:], " ")
+			return string(path), rest, nil
+		case c != '\\':
+			path = append(path, c)
+			continue
+		}
+		if i++; i == len(s) {
+			break
+		}
+		switch c := s[i]; c {
+		case 'n':
+			path = append(path, '\n')
+		case 't':
+			path = append(path, '\t')
+		case 'a', 'b', 'f', 'r', 'v':
+			path = append(path, "\a\b\f\r\v"[strings.IndexByte("abfrv", c)])
+		case '0', '1', '2', '3':
+			if i+3 > len(s) {
+				return "", "", fmt.Errorf("invalid quoted path: %s", s)
+			}
+			n, err := strconv.ParseUint(s[i:i+3], 8, 8)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid quoted path: %s", s)
+			}
+			path = append(path, byte(n))
+			i += 2
+		default:
+			path = append(path, c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quoted path: %s", s)
+}
+
+// tag builds an annotated tag of a commit
+func (imp *fastImporter) tag(name string) error {
+	mark, hasMark, err := imp.optional("mark ")
+	if err != nil {
+		return err
+	}
+	from, ok, err := imp.optional("from ")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("tag %s has no from", name)
+	}
+	target, err := imp.lookup(from)
+	if err != nil {
+		return err
+	}
+	if _, _, err := imp.optional("original-oid "); err != nil {
+		return err
+	}
+	tagger, hasTagger, err := imp.optional("tagger ")
+	if err != nil {
+		return err
+	}
+	if hasTagger && !fastImportIdent.MatchString(tagger) {
+		return fmt.Errorf("invalid identity: %s", tagger)
+	}
+	message, err := imp.data()
+	if err != nil {
+		return err
+	}
+	targetType, _, err := imp.r.ReadObject(target)
+	if err != nil {
+		return err
+	}
+
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "object %s\ntype %s\ntag %s\n", target, targetType, name)
+	if hasTagger {
+		fmt.Fprintf(&content, "tagger %s\n", tagger)
+	}
+	content.WriteString("\n")
+	content.Write(message)
+	sha, err := imp.r.WriteObject(object.TagObject, content.Bytes())
+	if err != nil {
+		return err
+	}
+	imp.touch("refs/tags/"+name, sha)
+	if hasMark {
+		return imp.setMark(mark, sha)
+	}
+	return nil
+}
+
+// updateRefs points each ref the stream touched at its final object. A ref
+// whose commit the new one does not contain is left alone unless force is
+// set, and reported once the others are written.
+func (imp *fastImporter) updateRefs(force bool) ([]Ref, error) {
+	var updated []Ref
+	var refused []string
+	for _, ref := range imp.order {
+		sha := imp.branches[ref]
+		if sha == "" {
+			continue
+		}
+		old, err := imp.r.ReadRef(ref)
+		if err != nil {
+			return nil, err
+		}
+		if old == sha {
+			continue
+		}
+		if old != "" && !force {
+			newCommit, err := imp.r.peelTag(sha)
+			if err != nil {
+				return nil, err
+			}
+			oldCommit, err := imp.r.peelTag(old)
+			if err != nil {
+				return nil, err
+			}
+			contained, err := imp.r.IsAncestor(oldCommit, newCommit)
+			if err != nil {
+				return nil, err
+			}
+			if !contained {
+				refused = append(refused, ref)
+				continue
+			}
+		}
+		if err := imp.r.writeRef(ref, sha, "fast-import"); err != nil {
+			return nil, err
+		}
+		updated = append(updated, Ref{Name: ref, SHA: sha})
+	}
+	if len(refused) > 0 {
+		return updated, fmt.Errorf("not updating %s: the new commit does not contain the current one (use --force)", strings.Join(refused, ", "))
+	}
+	return updated, nil
+}
@@ -0,0 +1,133 @@
+package gvc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// fetchJobs returns how many remotes FetchAll fetches at once: jobs, or else
+// fetch.parallel, which defaults to 1; 0 means one per CPU
+func (r *Repository) fetchJobs(jobs int) (int, error) {
+	if jobs < 0 {
+		return 0, fmt.Errorf("jobs %d is not a positive number", jobs)
+	}
+	if jobs == 0 {
+		parallel, err := r.getConfigInt("fetch.parallel", 1)
+		if err != nil {
+			return 0, err
+		}
+		if parallel < 0 {
+			return 0, fmt.Errorf("fetch.parallel %d is not a positive number", parallel)
+		}
+		jobs = int(parallel)
+	}
+	if jobs == 0 {
+		jobs = runtime.NumCPU()
+	}
+	return jobs, nil
+}
+
+// fetchHandle opens another handle on r, with r's settings and observers, to
+// fetch a remote alongside others the way another process would. Only
+// repositories whose objects and refs are kept in their own files have one;
+// the remotes of others are fetched one at a time.
+func (r *Repository) fetchHandle() (*Repository, error) {
+	handle, err := openAt(r.Root, r.GitDir, false)
+	if err != nil {
+		return nil, err
+	}
+	handle.ReadOnly = r.ReadOnly
+	handle.Verbose, handle.Context = r.Verbose, r.Context
+	handle.observers = append([]Observer(nil), r.observers...)
+	return handle, nil
+}
+
+// FetchAll fetches every configured remote, as Fetch does, up to opts.Jobs of
+// them at a time. Each remote's messages are held back until it is done and
+// printed under "Fetching <name>" in the order of the remotes, so they never
+// interleave, and a meter on r.Progress counts the remotes done. A remote
+// that fails does not stop the others; the error names every one that did.
+// With more than one job, observers are called from several goroutines.
+func (r *Repository) FetchAll(opts FetchOptions) error {
+	remotes, err := r.Remotes()
+	if err != nil || len(remotes) == 0 {
+		return err
+	}
+	jobs, err := r.fetchJobs(opts.Jobs)
+	if err != nil {
+		return err
+	}
+	jobs = min(jobs, len(remotes))
+	if !r.usesFileStore() || !r.usesFileRefs() || r.memory != nil {
+		jobs = min(jobs, 1)
+	}
+	// Workers fetching with r itself point its output at their buffer
+	out := r.Out
+
+	type result struct {
+		out  bytes.Buffer
+		err  error
+		done chan struct{}
+	}
+	results := make([]*result, len(remotes))
+	for i := range results {
+		results[i] = &result{done: make(chan struct{})}
+	}
+	meter := r.startProgress("Fetching remotes", len(remotes))
+	fetch := func(i int) {
+		res := results[i]
+		defer close(res.done)
+		handle := r
+		if jobs > 1 {
+			if handle, res.err = r.fetchHandle(); res.err != nil {
+				return
+			}
+		}
+		observers := handle.observers
+		var copied int64
+		handle.Out = &res.out
+		handle.Observe(Observer{OnTransfer: func(stats TransferStats) { copied += stats.Copied }})
+		res.err = handle.Fetch(remotes[i].Name, opts)
+		handle.Out, handle.observers = out, observers
+		meter.add(1, copied)
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				fetch(i)
+			}
+		}()
+	}
+	go func() {
+		for i := range remotes {
+			if r.canceled() != nil {
+				results[i].err = r.canceled()
+				close(results[i].done)
+				continue
+			}
+			next <- i
+		}
+		close(next)
+	}()
+
+	var errs []error
+	for i, remote := range remotes {
+		<-results[i].done
+		fmt.Fprintf(out, "Fetching %s\n", remote.Name)
+		out.Write(results[i].out.Bytes())
+		if err := results[i].err; err != nil {
+			errs = append(errs, fmt.Errorf("could not fetch %s: %w", remote.Name, err))
+		}
+	}
+	wg.Wait()
+	meter.done()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,167 @@
+package gvc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// mboxDate is the fixed date on the "From <sha>" line that starts each
+// patch, the same one Git writes so mail tools recognize the format
+const mboxDate = "Mon Sep 17 00:00:00 2001"
+
+// FormatPatchOptions controls FormatPatches
+type FormatPatchOptions struct {
+	// MaxCount formats only the newest this many commits; with a single
+	// revision it counts back from that revision rather than up to HEAD
+	MaxCount int
+	// Numbered writes [PATCH n/m] subjects even for a single patch
+	Numbered bool
+	// NoNumbered writes plain [PATCH] subjects even for a series
+	NoNumbered bool
+	// SubjectPrefix replaces PATCH in the subjects
+	SubjectPrefix string
+}
+
+// FormattedPatch is one commit written as an email
+type FormattedPatch struct {
+	SHA     string
+	Name    string // file name for it, such as 0001-Fix-the-parser.patch
+	Content []byte
+}
+
+// FormatPatches writes each commit of a range as a mailbox-style email, the
+// oldest first: From, Date and Subject headers taken from the commit, its
+// message, a diffstat and the patch. The range is given as log takes it,
+// except that a single revision A stands for A..HEAD, the commits not yet
+// in A. Merge commits are skipped, as a patch cannot record them.
+func (r *Repository) FormatPatches(args []string, opts FormatPatchOptions) ([]FormattedPatch, error) {
+	switch {
+	case len(args) == 0 && opts.MaxCount > 0:
+		args = []string{"HEAD"}
+	case len(args) == 0:
+		return nil, errors.New("no revision range given")
+	case len(args) == 1 && opts.MaxCount == 0 && !strings.Contains(args[0], "..") && !strings.HasPrefix(args[0], "^"):
+		args = []string{args[0] + "..HEAD"}
+	}
+	revRange, err := r.ParseRevRange(args)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := r.WalkRevisions(revRange)
+	if err != nil {
+		return nil, err
+	}
+	var shas []string
+	for _, node := range nodes {
+		if len(node.Parents) > 1 {
+			continue
+		}
+		if opts.MaxCount > 0 && len(shas) == opts.MaxCount {
+			break
+		}
+		shas = append(shas, node.SHA)
+	}
+	slices.Reverse(shas)
+
+	prefix := opts.SubjectPrefix
+	if prefix == "" {
+		prefix = "PATCH"
+	}
+	numbered := (len(shas) > 1 || opts.Numbered) && !opts.NoNumbered
+	patches := make([]FormattedPatch, 0, len(shas))
+	for i, sha := range shas {
+		tag := "[" + prefix + "]"
+		if numbered {
+			tag = fmt.Sprintf("[%s %d/%d]", prefix, i+1, len(shas))
+		}
+		content, subject, err := r.formatPatch(sha, tag)
+		if err != nil {
+			return nil, err
+		}
+		patches = append(patches, FormattedPatch{
+			SHA:     sha,
+			Name:    fmt.Sprintf("%04d-%s.patch", i+1, patchFileName(subject)),
+			Content: content,
+		})
+	}
+	return patches, nil
+}
+
+// formatPatch writes one commit as an email whose subject starts with tag,
+// returning it and the commit's subject
+func (r *Repository) formatPatch(sha, tag string) ([]byte, string, error) {
+	commit, err := r.ReadCommit(sha)
+	if err != nil {
+		return nil, "", err
+	}
+	parentTree := ""
+	if commit.ParentSHA != "" {
+		parent, err := r.ReadCommit(commit.ParentSHA)
+		if err != nil {
+			return nil, "", err
+		}
+		parentTree = parent.TreeSHA
+	}
+	changes, err := r.DiffTrees(parentTree, commit.TreeSHA)
+	if err != nil {
+		return nil, "", err
+	}
+	threshold, err := r.RenameThreshold()
+	if err != nil {
+		return nil, "", err
+	}
+	if changes, err = r.DetectRenames(changes, threshold); err != nil {
+		return nil, "", err
+	}
+
+	// The subject is the message's first paragraph on one line
+	message := strings.TrimSpace(commit.Message)
+	head, body, _ := strings.Cut(message, "\n\n")
+	subject := strings.Join(strings.Fields(head), " ")
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From %s %s\n", sha, mboxDate)
+	fmt.Fprintf(&b, "From: %s\n", commit.Author)
+	fmt.Fprintf(&b, "Date: %s\n", commit.Timestamp.Format("Mon, 2 Jan 2006 15:04:05 -0700"))
+	fmt.Fprintf(&b, "Subject: %s %s\n\n", tag, subject)
+	if body = strings.TrimSpace(body); body != "" {
+		b.WriteString(body + "\n")
+	}
+	b.WriteString("---\n")
+	if err := r.WriteDiffStat(&b, changes); err != nil {
+		return nil, "", err
+	}
+	b.WriteString("\n")
+	if err := r.WritePatch(&b, changes); err != nil {
+		return nil, "", err
+	}
+	b.WriteString("-- \ngvc\n\n")
+	return b.Bytes(), subject, nil
+}
+
+// patchFileName turns a subject into the name part of a patch file: runs of
+// characters other than letters, digits, dots and underscores become a
+// dash, with no dashes or dots at either end, cut to 52 characters as Git does
+func patchFileName(subject string) string {
+	var b strings.Builder
+	dash := false
+	for _, c := range subject {
+		if c < 128 && (c == '.' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			dash = false
+			b.WriteRune(c)
+			continue
+		}
+		dash = true
+	}
+	name := b.String()
+	if len(name) > 52 {
+		name = name[:52]
+	}
+	return strings.Trim(name, "-.")
+}
@@ -0,0 +1,377 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// FsckProblem is one defect found by Fsck. Warnings only fail a strict check.
+type FsckProblem struct {
+	SHA     string
+	Message string
+	Warning bool
+}
+
+// FsckObject is an object Fsck found outside the history of every ref
+type FsckObject struct {
+	SHA  string
+	Type object.Type
+}
+
+// FsckReport is the outcome of checking a repository
+type FsckReport struct {
+	Checked     int
+	Problems    []FsckProblem
+	Dangling    []FsckObject // unreachable and not referenced by another unreachable object
+	Unreachable []FsckObject
+}
+
+// Failed reports whether the check found errors, or any problem at all when strict
+func (report *FsckReport) Failed(strict bool) bool {
+	for _, problem := range report.Problems {
+		if strict || !problem.Warning {
+			return true
+		}
+	}
+	return false
+}
+
+// fsckLink is a reference from one object to another
+type fsckLink struct {
+	sha        string
+	objectType object.Type // "" when any type will do
+}
+
+// identPattern matches the "Name <email> timestamp zone" of author and committer lines
+var identPattern = regexp.MustCompile(`^[^<>\n]*(<[^<>\n]*> )*<[^<>\n]*> [0-9]+ [+-][0-9]{4}$`)
+
+// validTreeModes are the entry modes Git writes. Trees are also read with the
+// legacy mode 100664, which fsck warns about.
+var validTreeModes = map[string]bool{"100644": true, "100755": true, "120000": true, "40000": true, "160000": true}
+
+// Fsck re-hashes every loose and packed object, checks the syntax of trees and
+// commits, verifies that everything reachable from HEAD, the refs, their reflogs
+// and the index exists, and lists the objects nothing reaches.
+func (r *Repository) Fsck() (*FsckReport, error) {
+	report := &FsckReport{}
+	problem := func(sha, format string, args ...any) {
+		report.Problems = append(report.Problems, FsckProblem{SHA: sha, Message: fmt.Sprintf(format, args...)})
+	}
+	warning := func(sha, format string, args ...any) {
+		report.Problems = append(report.Problems, FsckProblem{SHA: sha, Message: fmt.Sprintf(format, args...), Warning: true})
+	}
+
+	shas, err := r.Objects.List()
+	if err != nil {
+		return nil, err
+	}
+	report.Checked = len(shas)
+
+	types := make(map[string]object.Type, len(shas))
+	links := make(map[string][]fsckLink)
+	for _, sha := range shas {
+		if err := r.canceled(); err != nil {
+			return nil, err
+		}
+		objectType, content, err := r.loadRawObject(sha)
+		if err != nil {
+			var corrupt *CorruptObjectError
+			if errors.As(err, &corrupt) {
+				err = corrupt.Err
+			}
+			problem(sha, "%s: corrupt object: %v", sha, err)
+			continue
+		}
+		if actual := r.Format.Hash(objectType, content); actual != sha {
+			problem(sha, "%s: hash mismatch: content hashes to %s", sha, actual)
+			continue
+		}
+		types[sha] = objectType
+
+		switch objectType {
+		case object.BlobObject:
+		case object.TreeObject:
+			links[sha] = r.fsckTree(sha, content, problem, warning)
+		case object.CommitObject:
+			links[sha] = r.fsckCommit(sha, content, problem, warning)
+		case object.TagObject:
+			if tag, err := object.ParseTag(sha, content); err != nil {
+				problem(sha, "error in tag %s: %v", sha, err)
+			} else {
+				links[sha] = []fsckLink{{tag.Object, tag.Type}}
+			}
+		default:
+			problem(sha, "%s: unknown object type %q", sha, objectType)
+		}
+	}
+
+	// Walk everything the repository still refers to, reporting what is missing
+	roots, err := r.fsckRoots(nil)
+	if err != nil {
+		return nil, err
+	}
+	reachable := make(map[string]bool)
+	stack := roots
+	for len(stack) > 0 {
+		link := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[link.sha] {
+			continue
+		}
+		reachable[link.sha] = true
+		objectType, ok := types[link.sha]
+		if !ok {
+			if !r.objectExists(link.sha) {
+				expected := link.objectType
+				if expected == "" {
+					expected = "object"
+				}
+				problem(link.sha, "missing %s %s", expected, link.sha)
+			}
+			continue
+		}
+		if link.objectType != "" && objectType != link.objectType {
+			problem(link.sha, "%s: expected a %s but found a %s", link.sha, link.objectType, objectType)
+		}
+		stack = append(stack, links[link.sha]...)
+	}
+
+	// Dangling objects are the tips of unreachable history
+	referenced := make(map[string]bool)
+	for sha := range types {
+		if !reachable[sha] {
+			for _, link := range links[sha] {
+				referenced[link.sha] = true
+			}
+		}
+	}
+	for _, sha := range shas {
+		objectType, ok := types[sha]
+		if !ok || reachable[sha] {
+			continue
+		}
+		report.Unreachable = append(report.Unreachable, FsckObject{SHA: sha, Type: objectType})
+		if !referenced[sha] {
+			report.Dangling = append(report.Dangling, FsckObject{SHA: sha, Type: objectType})
+		}
+	}
+	return report, nil
+}
+
+// allObjects returns the SHA of every loose and packed object, sorted
+func (r *Repository) allObjects() ([]string, error) {
+	loose, err := r.listLooseObjects()
+	if err != nil {
+		return nil, err
+	}
+	packs, err := r.loadPacks()
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	var shas []string
+	for _, sha := range loose {
+		seen[sha] = true
+		shas = append(shas, sha)
+	}
+	for _, pack := range packs {
+		for _, sha := range pack.shas {
+			if !seen[sha] {
+				seen[sha] = true
+				shas = append(shas, sha)
+			}
+		}
+	}
+	sort.Strings(shas)
+	return shas, nil
+}
+
+// fsckTree checks a tree's entries and returns the objects it points to
+func (r *Repository) fsckTree(sha string, content []byte, problem, warning func(sha, format string, args ...any)) []fsckLink {
+	entries, err := r.Format.ParseTree(content)
+	if err != nil {
+		problem(sha, "error in tree %s: %v", sha, err)
+		return nil
+	}
+
+	var links []fsckLink
+	names := make(map[string]bool)
+	for i, entry := range entries {
+		switch {
+		case !validTreeEntryName(entry.Name):
+			problem(sha, "error in tree %s: invalid entry name %q", sha, entry.Name)
+		case names[entry.Name]:
+			problem(sha, "error in tree %s: duplicate entry %q", sha, entry.Name)
+		case strings.EqualFold(entry.Name, GvcDir):
+			warning(sha, "warning in tree %s: entry %q shadows the repository directory", sha, entry.Name)
+		}
+		names[entry.Name] = true
+		if i > 0 && treeSortName(entries[i-1]) > treeSortName(entry) {
+			warning(sha, "warning in tree %s: entries are not sorted (%q after %q)", sha, entry.Name, entries[i-1].Name)
+		}
+		if !validTreeModes[entry.Mode] {
+			warning(sha, "warning in tree %s: entry %q has unusual mode %s", sha, entry.Name, entry.Mode)
+		}
+
+		// Submodule commits live in another repository
+		if entry.Type != object.CommitObject {
+			links = append(links, fsckLink{entry.SHA, entry.Type})
+		}
+	}
+	return links
+}
+
+// fsckCommit checks a commit's headers and returns the objects it points to
+func (r *Repository) fsckCommit(sha string, content []byte, problem, warning func(sha, format string, args ...any)) []fsckLink {
+	header, _, found := strings.Cut(string(content), "\n\n")
+	if !found {
+		problem(sha, "error in commit %s: no blank line after the header", sha)
+	}
+
+	var links []fsckLink
+	lines := strings.Split(header, "\n")
+	next := func(key string) (string, bool) {
+		if len(lines) == 0 {
+			return "", false
+		}
+		value, ok := strings.CutPrefix(lines[0], key+" ")
+		if ok {
+			lines = lines[1:]
+		}
+		return value, ok
+	}
+
+	tree, ok := next("tree")
+	if !ok {
+		problem(sha, "error in commit %s: missing tree line", sha)
+		return nil
+	}
+	if r.Format.ValidateSHA(tree) != nil {
+		problem(sha, "error in commit %s: invalid tree %q", sha, tree)
+	} else {
+		links = append(links, fsckLink{tree, object.TreeObject})
+	}
+	for {
+		parent, ok := next("parent")
+		if !ok {
+			break
+		}
+		if r.Format.ValidateSHA(parent) != nil {
+			problem(sha, "error in commit %s: invalid parent %q", sha, parent)
+			continue
+		}
+		// A shallow commit's parents were never fetched
+		if shallow, _ := r.isShallowCommit(sha); !shallow {
+			links = append(links, fsckLink{parent, object.CommitObject})
+		}
+	}
+	for _, key := range []string{"author", "committer"} {
+		ident, ok := next(key)
+		if !ok {
+			problem(sha, "error in commit %s: missing %s line", sha, key)
+			return links
+		}
+		if !identPattern.MatchString(ident) {
+			warning(sha, "warning in commit %s: malformed %s %q", sha, key, ident)
+		}
+	}
+	return links
+}
+
+// fsckRoots returns the objects the repository refers to directly: HEAD, every ref,
+// every commit recorded in a reflog and every staged blob. Reflog entries
+// expired says are about to go are left out; expired may be nil.
+func (r *Repository) fsckRoots(expired func(ref string, entry ReflogEntry) bool) ([]fsckLink, error) {
+	var roots []fsckLink
+	head, err := r.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	if head != "" {
+		roots = append(roots, fsckLink{head, object.CommitObject})
+	}
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		if ref.Tag != "" {
+			roots = append(roots, fsckLink{ref.Tag, object.TagObject})
+		} else {
+			roots = append(roots, fsckLink{ref.SHA, object.CommitObject})
+		}
+	}
+
+	err = filepath.WalkDir(r.gitPath(LogsDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, LockSuffix) {
+			return err
+		}
+		rel, err := filepath.Rel(r.gitPath(LogsDir), path)
+		if err != nil {
+			return err
+		}
+		entries, err := r.ReadReflog(filepath.ToSlash(rel))
+		if err != nil {
+			return err
+		}
+		// A tag's log records annotated tags as well as commits
+		expected := object.CommitObject
+		if strings.HasPrefix(filepath.ToSlash(rel), "refs/tags/") {
+			expected = ""
+		}
+		for _, entry := range entries {
+			if expired != nil && expired(filepath.ToSlash(rel), entry) {
+				continue
+			}
+			if r.Format.ValidateSHA(entry.NewSHA) == nil && entry.NewSHA != r.Format.ZeroSHA() {
+				roots = append(roots, fsckLink{entry.NewSHA, expected})
+			}
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read reflogs: %w", err)
+	}
+
+	index, err := r.ReadIndex()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range index.Entries {
+		roots = append(roots, fsckLink{entry.SHA, object.BlobObject})
+	}
+
+	// Other worktrees' HEADs and indexes keep their objects alive too
+	worktrees, err := r.Worktrees()
+	if err != nil {
+		return nil, err
+	}
+	for _, wt := range worktrees {
+		if r.currentWorktree(wt) || wt.Prunable {
+			continue
+		}
+		if wt.Head != "" {
+			roots = append(roots, fsckLink{wt.Head, object.CommitObject})
+		}
+		repo, err := OpenAt(wt.Path, wt.gitDir)
+		if err != nil {
+			return nil, err
+		}
+		index, err := repo.ReadIndex()
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range index.Entries {
+			roots = append(roots, fsckLink{entry.SHA, object.BlobObject})
+		}
+	}
+	return roots, nil
+}
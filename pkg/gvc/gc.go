@@ -0,0 +1,478 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultReflogExpire is how long reflog entries are kept by default, as in Git
+const defaultReflogExpire = "90.days.ago"
+
+// GCOptions controls GC
+type GCOptions struct {
+	// Aggressive repacks every object in the repository into a single pack,
+	// recomputing all deltas with a wider window
+	Aggressive bool
+	// DryRun reports on Out what PlanGC finds to do, without changing
+	// anything
+	DryRun bool
+	// Prune first deletes what nothing needs any more: the reflog entries
+	// older than gc.reflogExpire (default 90.days.ago; "never" keeps them),
+	// except the stash's, then the unreachable loose objects and stale
+	// temporary files older than PruneExpire
+	Prune bool
+	// PruneExpire is the grace period of Prune, as PruneOptions.Expire
+	// takes it, defaulting to gc.pruneExpire; "now" prunes every
+	// unreachable object
+	PruneExpire string
+}
+
+// GCReport is what GC would do, as PlanGC works it out
+type GCReport struct {
+	// Pack is how many objects would be packed into a new pack
+	Pack int
+	// LooseRemoved is how many loose objects would be removed once packed
+	// or because a pack holds them already
+	LooseRemoved int
+	// OldPacks are the packs an aggressive repack would replace
+	OldPacks []string
+	// Pruned is what pruning would delete; without GCOptions.Prune, only the
+	// stale temporary files and locks gc removes anyway
+	Pruned *PruneResult
+	// ExpiredReflog lists the reflog entries that would expire, by ref and
+	// oldest first
+	ExpiredReflog []ExpiredReflogEntry
+	// RemovedReflogs are the refs whose reflogs would be left empty, and so
+	// removed
+	RemovedReflogs []string
+	// Reclaimed is how many bytes the pruned files, the loose copies of
+	// packed objects and the expired entries take. What packing saves is
+	// only known once the pack is written.
+	Reclaimed int64
+}
+
+// ExpiredReflogEntry is a reflog entry GC expires
+type ExpiredReflogEntry struct {
+	Ref string
+	ReflogEntry
+}
+
+// GC packs loose objects. The aggressive mode repacks every object in the
+// repository into a single pack, recomputing all deltas with a wider window.
+func (r *Repository) GC(aggressive bool) error {
+	return r.GCWithOptions(GCOptions{Aggressive: aggressive})
+}
+
+// GCWithOptions packs loose objects as opts asks, after pruning what
+// nothing needs any more with opts.Prune. Either way it first removes what
+// interrupted commands left: temporary files older than gc.tempExpire and
+// the locks of commands that exited.
+func (r *Repository) GCWithOptions(gcOpts GCOptions) error {
+	if gcOpts.DryRun {
+		report, err := r.PlanGC(gcOpts)
+		if err != nil {
+			return err
+		}
+		r.reportGC(report)
+		return nil
+	}
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if !r.usesFileStore() {
+		return fmt.Errorf("packing objects is %w", errNeedsFileStore)
+	}
+	unlock, err := r.lockMaintenance()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	if gcOpts.Prune {
+		// Objects only expired entries reached become unreachable, and
+		// pruning them before packing keeps them out of the pack
+		expired, err := r.reflogExpiry()
+		if err != nil {
+			return err
+		}
+		if err := r.expireReflogs(expired); err != nil {
+			return err
+		}
+		if _, err := r.Prune(PruneOptions{Expire: gcOpts.PruneExpire}); err != nil {
+			return err
+		}
+	} else {
+		stale, err := r.gcStaleFiles()
+		if err != nil {
+			return err
+		}
+		if err := stale.remove(r.CommonDir); err != nil {
+			return err
+		}
+	}
+	opts, err := r.gcPackOptions(gcOpts.Aggressive)
+	if err != nil {
+		return err
+	}
+	work, err := r.gcObjects(gcOpts.Aggressive, nil)
+	if err != nil {
+		return err
+	}
+	objects, loose, redundant, oldPacks := work.objects, work.loose, work.redundant, work.oldPacks
+	if len(objects) == 0 {
+		fmt.Fprintln(r.Out, "Nothing new to pack")
+		return r.removeLooseObjects(redundant)
+	}
+	fmt.Fprintf(r.Out, "Counting objects: %d, done.\n", len(objects))
+
+	if err := r.setNameHashes(objects); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(r.Out, "Delta compression using up to %d threads (window %d, depth %d)\n", opts.Threads, opts.Window, opts.Depth)
+	findDeltas(objects, opts)
+	if err := r.canceled(); err != nil {
+		return err
+	}
+
+	name, err := r.writePack(objects)
+	if err != nil {
+		return err
+	}
+	deltas := 0
+	for _, obj := range objects {
+		if obj.base != nil {
+			deltas++
+		}
+	}
+
+	r.resetPacks()
+	for _, packPath := range oldPacks {
+		if filepath.Base(packPath) == name+".pack" {
+			continue
+		}
+		for _, file := range []string{packPath, strings.TrimSuffix(packPath, ".pack") + ".idx"} {
+			if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove old pack: %w", err)
+			}
+		}
+	}
+	if err := r.removeLooseObjects(append(redundant, loose...)); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(r.Out, "Wrote %s (%d objects, %d deltas)\n", name, len(objects), deltas)
+	return nil
+}
+
+// PlanGC works out what GCWithOptions would do with opts without changing
+// anything: the objects it would pack, the files it would remove and the
+// stale temporary files and locks it would clear, and with opts.Prune the
+// reflog entries and objects it would delete, and the space that frees
+func (r *Repository) PlanGC(gcOpts GCOptions) (*GCReport, error) {
+	if !r.usesFileStore() {
+		return nil, fmt.Errorf("packing objects is %w", errNeedsFileStore)
+	}
+	report := &GCReport{}
+	pruned := make(map[string]bool)
+	if gcOpts.Prune {
+		expired, err := r.reflogExpiry()
+		if err != nil {
+			return nil, err
+		}
+		if report.ExpiredReflog, report.RemovedReflogs, err = r.expiredReflog(expired); err != nil {
+			return nil, err
+		}
+		for _, entry := range report.ExpiredReflog {
+			report.Reclaimed += int64(len(r.formatReflogEntry(entry.ReflogEntry)))
+		}
+		if report.Pruned, err = r.prune(PruneOptions{Expire: gcOpts.PruneExpire, DryRun: true}, expired); err != nil {
+			return nil, err
+		}
+		report.Reclaimed += report.Pruned.Reclaimed
+		for _, obj := range report.Pruned.Objects {
+			pruned[obj.SHA] = true
+		}
+	} else {
+		stale, err := r.gcStaleFiles()
+		if err != nil {
+			return nil, err
+		}
+		report.Pruned = &PruneResult{TempFiles: stale.temp, Locks: stale.locks, Reclaimed: stale.size}
+		report.Reclaimed += stale.size
+	}
+
+	work, err := r.gcObjects(gcOpts.Aggressive, pruned)
+	if err != nil {
+		return nil, err
+	}
+	report.Pack, report.OldPacks = len(work.objects), work.oldPacks
+	// Packing removes every loose object; otherwise only those already packed go
+	report.LooseRemoved = len(work.redundant)
+	if len(work.objects) > 0 {
+		report.LooseRemoved += len(work.loose)
+	}
+	for _, sha := range work.redundant {
+		if info, err := os.Stat(r.objectPath(sha)); err == nil {
+			report.Reclaimed += info.Size()
+		}
+	}
+	return report, nil
+}
+
+// gcStaleFiles finds the temporary files and locks gc removes without
+// pruning
+func (r *Repository) gcStaleFiles() (*staleFiles, error) {
+	cutoff, err := r.tempCutoff(time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	return r.findStaleFiles(cutoff)
+}
+
+// gcWork is what GC packs and removes: the objects for the new pack, the
+// loose objects going into it, the loose copies of objects already packed
+// and the packs an aggressive repack replaces
+type gcWork struct {
+	objects   []*packObject
+	loose     []string
+	redundant []string
+	oldPacks  []string
+}
+
+// gcPackOptions returns the delta search settings of GC, wider when
+// aggressive, honoring pack.windowMemory and pack.threads
+func (r *Repository) gcPackOptions(aggressive bool) (PackOptions, error) {
+	opts := PackOptions{Window: 10, Depth: 50}
+	if aggressive {
+		opts = PackOptions{Window: 250, Depth: 250}
+	}
+	windowMemory, err := r.getConfigInt("pack.windowMemory", 0)
+	if err != nil {
+		return opts, err
+	}
+	threads, err := r.getConfigInt("pack.threads", 0)
+	if err != nil {
+		return opts, err
+	}
+	if threads <= 0 {
+		threads = int64(runtime.NumCPU())
+	}
+	opts.WindowMemory = windowMemory
+	opts.Threads = int(threads)
+	return opts, nil
+}
+
+// gcObjects works out what GC packs and removes, leaving out the loose
+// objects pruning is about to delete
+func (r *Repository) gcObjects(aggressive bool, pruned map[string]bool) (*gcWork, error) {
+	loose, err := r.listLooseObjects()
+	if err != nil {
+		return nil, err
+	}
+	packs, err := r.loadPacks()
+	if err != nil {
+		return nil, err
+	}
+
+	work := &gcWork{}
+	seen := make(map[string]bool)
+	addObject := func(sha string) error {
+		if seen[sha] {
+			return nil
+		}
+		seen[sha] = true
+		if err := r.canceled(); err != nil {
+			return err
+		}
+		objectType, content, err := r.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		work.objects = append(work.objects, &packObject{sha: sha, objType: objectType, data: content})
+		return nil
+	}
+
+	for _, sha := range loose {
+		if pruned[sha] {
+			continue
+		}
+		if !aggressive {
+			packed, err := r.hasPackedObject(sha)
+			if err != nil {
+				return nil, err
+			}
+			if packed {
+				work.redundant = append(work.redundant, sha)
+				continue
+			}
+		}
+		if err := addObject(sha); err != nil {
+			return nil, err
+		}
+		work.loose = append(work.loose, sha)
+	}
+	if aggressive {
+		for _, pack := range packs {
+			for _, sha := range pack.shas {
+				if err := addObject(sha); err != nil {
+					return nil, err
+				}
+			}
+			work.oldPacks = append(work.oldPacks, pack.path)
+		}
+	}
+	return work, nil
+}
+
+// reflogExpiry returns whether gc.reflogExpire has a reflog entry expire:
+// whether it is older than the cutoff, except in the stash, which holds
+// work that is not anywhere else
+func (r *Repository) reflogExpiry() (func(ref string, entry ReflogEntry) bool, error) {
+	if r.namespace != "" {
+		return nil, errors.New("expiring reflogs is not possible in a namespace")
+	}
+	expire, err := r.getConfigString("gc.reflogExpire", defaultReflogExpire)
+	if err != nil {
+		return nil, err
+	}
+	if expire == "never" {
+		return func(string, ReflogEntry) bool { return false }, nil
+	}
+	cutoff, err := ParseDate(expire, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid gc.reflogExpire: %w", err)
+	}
+	return func(ref string, entry ReflogEntry) bool {
+		return ref != StashRef && entry.Timestamp.Before(cutoff)
+	}, nil
+}
+
+// reflogNames lists the refs that have a reflog, HEAD included
+func (r *Repository) reflogNames() ([]string, error) {
+	var refs []string
+	err := filepath.WalkDir(r.gitPath(LogsDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, LockSuffix) {
+			return err
+		}
+		rel, err := filepath.Rel(r.gitPath(LogsDir), path)
+		if err != nil {
+			return err
+		}
+		refs = append(refs, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read reflogs: %w", err)
+	}
+	return refs, nil
+}
+
+// expiredReflog lists the reflog entries expired has go, and the refs
+// whose reflogs would be left empty
+func (r *Repository) expiredReflog(expired func(ref string, entry ReflogEntry) bool) ([]ExpiredReflogEntry, []string, error) {
+	refs, err := r.reflogNames()
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []ExpiredReflogEntry
+	var emptied []string
+	for _, ref := range refs {
+		log, err := r.ReadReflog(ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		kept := len(log)
+		for _, entry := range log {
+			if expired(ref, entry) {
+				entries = append(entries, ExpiredReflogEntry{Ref: ref, ReflogEntry: entry})
+				kept--
+			}
+		}
+		if kept == 0 && len(log) > 0 {
+			emptied = append(emptied, ref)
+		}
+	}
+	return entries, emptied, nil
+}
+
+// expireReflogs drops the reflog entries expired has go, removing the
+// reflogs left empty
+func (r *Repository) expireReflogs(expired func(ref string, entry ReflogEntry) bool) error {
+	refs, err := r.reflogNames()
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		log, err := r.ReadReflog(ref)
+		if err != nil {
+			return err
+		}
+		kept := slices.DeleteFunc(slices.Clone(log), func(entry ReflogEntry) bool { return expired(ref, entry) })
+		if len(kept) == len(log) {
+			continue
+		}
+		if err := r.writeReflog(ref, kept); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reportGC says what a GC dry run found to do
+func (r *Repository) reportGC(report *GCReport) {
+	if len(report.ExpiredReflog) > 0 {
+		fmt.Fprintf(r.Out, "Would expire %d reflog entries\n", len(report.ExpiredReflog))
+		for _, entry := range report.ExpiredReflog {
+			fmt.Fprintf(r.Out, "  %s %s %s %s\n", entry.Ref, entry.NewSHA, entry.Timestamp.Format("2006-01-02 15:04:05 -0700"), entry.Message)
+		}
+	}
+	for _, ref := range report.RemovedReflogs {
+		fmt.Fprintf(r.Out, "Would remove the reflog of %s\n", ref)
+	}
+	if report.Pruned != nil {
+		if len(report.Pruned.Objects) > 0 {
+			fmt.Fprintf(r.Out, "Would prune %d unreachable objects\n", len(report.Pruned.Objects))
+		}
+		for _, obj := range report.Pruned.Objects {
+			fmt.Fprintf(r.Out, "  %s %s %s\n", obj.SHA, obj.Type, FormatSize(obj.Size))
+		}
+		for _, name := range report.Pruned.TempFiles {
+			fmt.Fprintf(r.Out, "Would remove stale temporary file %s\n", name)
+		}
+		for _, name := range report.Pruned.Locks {
+			fmt.Fprintf(r.Out, "Would remove stale lock %s\n", name)
+		}
+	}
+	if report.Pack == 0 {
+		fmt.Fprintln(r.Out, "Nothing new to pack")
+	} else {
+		fmt.Fprintf(r.Out, "Would pack %d objects into a new pack\n", report.Pack)
+	}
+	if report.LooseRemoved > 0 {
+		fmt.Fprintf(r.Out, "Would remove %d loose objects\n", report.LooseRemoved)
+	}
+	for _, packPath := range report.OldPacks {
+		fmt.Fprintf(r.Out, "Would remove %s\n", filepath.Base(packPath))
+	}
+	if report.Reclaimed > 0 {
+		fmt.Fprintf(r.Out, "Would reclaim %s\n", FormatSize(report.Reclaimed))
+	}
+}
+
+// removeLooseObjects deletes loose copies of objects that are now packed
+func (r *Repository) removeLooseObjects(shas []string) error {
+	for _, sha := range shas {
+		if err := os.Remove(r.objectPath(sha)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove loose object %s: %w", sha, err)
+		}
+		os.Remove(filepath.Dir(r.objectPath(sha))) // only succeeds once empty
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package gvc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// GitDirName is the metadata directory of a Git repository. Its objects,
+// refs and index use the formats gvc does, so gvc opens it when a working
+// tree has no .gvc, read-only for now.
+const GitDirName = ".git"
+
+// gitDirFilePrefix starts the .git file of a Git worktree or submodule
+const gitDirFilePrefix = "gitdir: "
+
+// PackedRefsFile is where Git keeps refs packed into one file, one
+// "<sha> <ref>" line each; a "^<sha>" line after an annotated tag gives the
+// commit it peels to
+const PackedRefsFile = "packed-refs"
+
+// GitIgnoreFile is the ignore file read in place of .gvcignore in a Git repository
+const GitIgnoreFile = ".gitignore"
+
+// GitAttributesFile is the attributes file read in place of .gvcattributes
+// in a Git repository
+const GitAttributesFile = ".gitattributes"
+
+// isGitDir reports whether gitDir is a Git repository's metadata: a .git
+// directory, or one of the worktree and submodule directories inside one
+func isGitDir(gitDir string) bool {
+	for dir := filepath.Clean(gitDir); ; dir = filepath.Dir(dir) {
+		if filepath.Base(dir) == GitDirName {
+			return true
+		}
+		if filepath.Dir(dir) == dir {
+			return false
+		}
+	}
+}
+
+// isMetadataDir reports whether a working tree entry named name holds
+// repository metadata and is never part of the tree
+func isMetadataDir(name string) bool {
+	return name == GvcDir || name == GitDirName
+}
+
+// packedRef is a ref read from packed-refs
+type packedRef struct {
+	sha    string
+	peeled string // the commit an annotated tag points to, if recorded
+}
+
+// packedRefs reads the refs in packed-refs the handle sees: in a namespace,
+// those kept there, under their names inside it
+func (r *Repository) packedRefs() (map[string]packedRef, error) {
+	refs, err := r.readPackedRefsFile()
+	if err != nil || r.namespace == "" {
+		return refs, err
+	}
+	prefix := r.namespaceRef("")
+	inside := make(map[string]packedRef)
+	for name, ref := range refs {
+		if name, ok := strings.CutPrefix(name, prefix); ok {
+			inside[name] = ref
+		}
+	}
+	return inside, nil
+}
+
+// readPackedRefsFile reads every ref in packed-refs. A missing file holds no refs.
+func (r *Repository) readPackedRefsFile() (map[string]packedRef, error) {
+	data, err := r.readGitFile(r.gitPath(PackedRefsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read packed refs: %w", err)
+	}
+	refs := make(map[string]packedRef)
+	var last string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "^"):
+			if ref, ok := refs[last]; ok {
+				ref.peeled = line[1:]
+				refs[last] = ref
+			}
+		default:
+			sha, name, ok := strings.Cut(line, " ")
+			if !ok || r.Format.ValidateSHA(sha) != nil {
+				return nil, fmt.Errorf("malformed packed ref: %q", line)
+			}
+			refs[name] = packedRef{sha: sha}
+			last = name
+		}
+	}
+	return refs, nil
+}
+
+// readPackedRef returns the SHA of ref in packed-refs, or "" if it is not there
+func (r *Repository) readPackedRef(ref string) (string, error) {
+	refs, err := r.packedRefs()
+	if err != nil {
+		return "", err
+	}
+	return refs[ref].sha, nil
+}
+
+// peelTag follows annotated tags until it reaches the object they point to,
+// returning sha unchanged when it is not a tag
+func (r *Repository) peelTag(sha string) (string, error) {
+	for {
+		objectType, content, err := r.ReadObject(sha)
+		if err != nil {
+			return "", err
+		}
+		if objectType != object.TagObject {
+			return sha, nil
+		}
+		tag, err := object.ParseTag(sha, content)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse tag %s: %w", sha, err)
+		}
+		sha = tag.Object
+	}
+}
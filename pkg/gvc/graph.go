@@ -0,0 +1,143 @@
+package gvc
+
+import "strings"
+
+// Graph lays out the ASCII rails of log --graph. Commits are fed in
+// topological order; each rail is a column heading for the commit it names.
+type Graph struct {
+	columns []string
+}
+
+// GraphRows are the rails to draw around one commit
+type GraphRows struct {
+	Commit     string   // rails for the commit's first line, with * marking the commit
+	Padding    string   // rails for the rest of the commit's text
+	Connectors []string // rows after the text that lead the rails to the parents
+}
+
+// NextCommit places a commit and returns the rails around it. Parents should
+// only list commits that will be shown, or their rails never end.
+func (g *Graph) NextCommit(sha string, parents []string) GraphRows {
+	idx := -1
+	for i, column := range g.columns {
+		if column == sha {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		g.columns = append(g.columns, sha)
+		idx = len(g.columns) - 1
+	}
+
+	rows := GraphRows{Commit: g.rails(idx, '*')}
+	if len(parents) == 0 {
+		rows.Padding = g.rails(idx, ' ')
+	} else {
+		rows.Padding = g.rails(idx, '|')
+	}
+
+	next := append([]string{}, g.columns[:idx]...)
+	next = append(next, parents...)
+	next = append(next, g.columns[idx+1:]...)
+
+	switch {
+	case len(parents) > 1:
+		// The commit's rail fans out to its extra parents, pushing later rails right
+		row := newGraphRow(len(next))
+		for i := 0; i <= idx; i++ {
+			row.set(2*i, '|')
+		}
+		for i := idx + 1; i < len(next); i++ {
+			row.set(2*i-1, '\\')
+		}
+		rows.Connectors = append(rows.Connectors, row.String())
+	case len(parents) == 0 && idx < len(g.columns)-1:
+		// Later rails move left into the root commit's place
+		rows.Connectors = append(rows.Connectors, shiftLeftRow(len(g.columns), idx))
+	}
+
+	// Rails heading for the same commit join the leftmost one
+	for {
+		target, dup := -1, -1
+		first := make(map[string]int)
+		for i, column := range next {
+			if j, seen := first[column]; seen {
+				target, dup = j, i
+				break
+			}
+			first[column] = i
+		}
+		if dup < 0 {
+			break
+		}
+		rows.Connectors = append(rows.Connectors, joinRows(len(next), target, dup)...)
+		next = append(next[:dup], next[dup+1:]...)
+	}
+
+	g.columns = next
+	return rows
+}
+
+// rails draws a | for every column, with mark in column idx. The row keeps
+// its full width so the text after it lines up.
+func (g *Graph) rails(idx int, mark byte) string {
+	row := newGraphRow(len(g.columns))
+	for i := range g.columns {
+		row.set(2*i, '|')
+	}
+	row.set(2*idx, mark)
+	return string(row[:len(row)-1])
+}
+
+// shiftLeftRow draws the row where column idx ends and every later column moves one left
+func shiftLeftRow(width, idx int) string {
+	row := newGraphRow(width)
+	for i := 0; i < idx; i++ {
+		row.set(2*i, '|')
+	}
+	for i := idx + 1; i < width; i++ {
+		row.set(2*i-1, '/')
+	}
+	return row.String()
+}
+
+// joinRows draws column dup moving left one column per row until it meets
+// column target, crossing the columns in between; later columns move left
+// with the first row
+func joinRows(width, target, dup int) []string {
+	var rows []string
+	for pos := dup; pos > target; pos-- {
+		row := newGraphRow(width)
+		for i := 0; i < dup; i++ {
+			row.set(2*i, '|')
+		}
+		row.set(2*pos-1, '/')
+		for i := dup + 1; i < width; i++ {
+			if pos == dup {
+				row.set(2*i-1, '/')
+			} else {
+				row.set(2*(i-1), '|')
+			}
+		}
+		rows = append(rows, row.String())
+	}
+	return rows
+}
+
+// graphRow is one row of rails, two characters per column
+type graphRow []byte
+
+func newGraphRow(columns int) graphRow {
+	return graphRow(strings.Repeat(" ", 2*columns))
+}
+
+func (row graphRow) set(pos int, c byte) {
+	if pos >= 0 && pos < len(row) {
+		row[pos] = c
+	}
+}
+
+func (row graphRow) String() string {
+	return strings.TrimRight(string(row), " ")
+}
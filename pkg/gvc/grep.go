@@ -0,0 +1,199 @@
+package gvc
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// GrepOptions controls Grep
+type GrepOptions struct {
+	Pattern   *regexp.Regexp
+	Rev       string   // search this commit's tree instead of the working tree
+	Paths     []string // only search files at or below these paths
+	Untracked bool     // also search untracked files that are not ignored
+}
+
+// GrepMatch is a matching line, or a whole binary file that matches
+type GrepMatch struct {
+	Path    string
+	Line    int // 1-based; 0 for a binary file
+	Content string
+	Binary  bool
+}
+
+// grepFile is one file to search: a blob, or a working tree path when sha is ""
+type grepFile struct {
+	path string
+	sha  string
+}
+
+// Grep searches tracked files for lines matching a pattern: those of the
+// working tree, or the blobs of a commit's tree when a revision is given.
+// Files are searched on a pool of core.threads workers and the matches come
+// back sorted by path and line.
+func (r *Repository) Grep(opts GrepOptions) ([]GrepMatch, error) {
+	files, err := r.grepFiles(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	threads, err := r.hashThreads()
+	if err != nil {
+		return nil, err
+	}
+	threads = min(threads, len(files))
+
+	results := make([][]GrepMatch, len(files))
+	errs := make([]error, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range threads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if errs[i] = r.canceled(); errs[i] != nil {
+					continue
+				}
+				results[i], errs[i] = r.grepFile(files[i], opts.Pattern)
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	var matches []GrepMatch
+	for i, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, results[i]...)
+	}
+	return matches, nil
+}
+
+// grepFiles lists the files Grep searches, sorted by path. Submodules are
+// skipped, and so are tracked files deleted from the working tree. A
+// revision's tree is only read below the paths searched.
+func (r *Repository) grepFiles(opts GrepOptions) ([]grepFile, error) {
+	var files []grepFile
+	if opts.Rev != "" {
+		commitSHA, err := r.ResolveCommit(opts.Rev)
+		if err != nil {
+			return nil, err
+		}
+		commit, err := r.ReadCommit(commitSHA)
+		if err != nil {
+			return nil, err
+		}
+		walker := r.WalkTree(commit.TreeSHA, TreeWalkOptions{Paths: opts.Paths})
+		for entry, ok := walker.Next(); ok; entry, ok = walker.Next() {
+			if entry.Mode != GitlinkMode {
+				files = append(files, grepFile{path: entry.Path, sha: entry.SHA})
+			}
+		}
+		if err := walker.Err(); err != nil {
+			return nil, err
+		}
+		sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+		return files, nil
+	}
+
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return nil, err
+	}
+	for path, entry := range staged {
+		if entry.Mode == GitlinkMode || !underAnyPath(path, opts.Paths) {
+			continue
+		}
+		if _, err := os.Lstat(r.worktreePath(path)); err != nil {
+			continue
+		}
+		files = append(files, grepFile{path: path})
+	}
+	if opts.Untracked {
+		untracked, err := r.UntrackedFiles(false)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range untracked {
+			if underAnyPath(path, opts.Paths) {
+				files = append(files, grepFile{path: path})
+			}
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
+
+// grepFile searches one file, reporting a binary file once instead of by line
+func (r *Repository) grepFile(file grepFile, pattern *regexp.Regexp) ([]GrepMatch, error) {
+	var content []byte
+	if file.sha != "" {
+		objectType, data, err := r.ReadObject(file.sha)
+		if err != nil {
+			return nil, err
+		}
+		if objectType != object.BlobObject {
+			return nil, fmt.Errorf("expected blob object for %s, got %s", file.path, objectType)
+		}
+		content = data
+	} else {
+		path := r.worktreePath(file.path)
+		info, err := os.Lstat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat file %s: %w", file.path, err)
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read link %s: %w", file.path, err)
+			}
+			content = []byte(target)
+		} else if content, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", file.path, err)
+		}
+	}
+
+	if len(content) == 0 {
+		return nil, nil
+	}
+	if isBinary(content) {
+		if pattern.Match(content) {
+			return []GrepMatch{{Path: file.path, Binary: true}}, nil
+		}
+		return nil, nil
+	}
+	var matches []GrepMatch
+	for i, line := range bytes.Split(bytes.TrimSuffix(content, []byte("\n")), []byte("\n")) {
+		if pattern.Match(line) {
+			matches = append(matches, GrepMatch{Path: file.path, Line: i + 1, Content: string(line)})
+		}
+	}
+	return matches, nil
+}
+
+// underAnyPath reports whether path is at or below one of paths; no paths
+// means everything
+func underAnyPath(path string, paths []string) bool {
+	if len(paths) == 0 {
+		return true
+	}
+	for _, spec := range paths {
+		if spec == "" || spec == "." || path == spec || strings.HasPrefix(path, spec+"/") {
+			return true
+		}
+	}
+	return false
+}
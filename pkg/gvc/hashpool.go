@@ -0,0 +1,72 @@
+package gvc
+
+import (
+	"os"
+	"runtime"
+	"sync"
+)
+
+// hashThreads returns how many files to hash at once: core.threads, or one
+// per CPU when it is unset or 0
+func (r *Repository) hashThreads() (int, error) {
+	threads, err := r.getConfigInt("core.threads", 0)
+	if err != nil {
+		return 0, err
+	}
+	if threads <= 0 {
+		return runtime.NumCPU(), nil
+	}
+	return int(threads), nil
+}
+
+// hashFiles hashes files into blobs on a bounded pool of workers, storing
+// them when write is set. The SHAs come back in the order of paths, so
+// whatever is built from them does not depend on scheduling.
+func (r *Repository) hashFiles(paths []string, write bool) ([]string, error) {
+	threads, err := r.hashThreads()
+	if err != nil {
+		return nil, err
+	}
+	threads = min(threads, len(paths))
+
+	var meter *progress
+	if write {
+		meter = r.startProgress("Hashing files", len(paths))
+	}
+	shas := make([]string, len(paths))
+	errs := make([]error, len(paths))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range threads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if errs[i] = r.canceled(); errs[i] != nil {
+					continue
+				}
+				shas[i], errs[i] = r.hashWorkingFile(paths[i], write)
+				if meter != nil && errs[i] == nil {
+					var size int64
+					if info, err := os.Lstat(paths[i]); err == nil {
+						size = info.Size()
+					}
+					meter.add(1, size)
+				}
+			}
+		}()
+	}
+	for i := range paths {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	meter.done()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return shas, nil
+}
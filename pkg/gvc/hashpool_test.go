@@ -0,0 +1,52 @@
+package gvc
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// BenchmarkAddLargeTree stages a tree of a few thousand files from an empty
+// index, hashing them one at a time and on the worker pool
+func BenchmarkAddLargeTree(b *testing.B) {
+	repo, err := Init(b.TempDir(), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	content := bytes.Repeat([]byte("some line of source code\n"), 400)
+	for dir := range 20 {
+		for file := range 200 {
+			path := filepath.Join(repo.Root, fmt.Sprintf("dir%02d", dir), fmt.Sprintf("file%03d.txt", file))
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				b.Fatal(err)
+			}
+			if err := os.WriteFile(path, append(content, strconv.Itoa(dir*200+file)...), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	for _, bench := range []struct {
+		name    string
+		threads int
+	}{{"serial", 1}, {"pool", 0}} {
+		b.Run(bench.name, func(b *testing.B) {
+			if err := repo.SetConfig("core.threads", strconv.Itoa(bench.threads)); err != nil {
+				b.Fatal(err)
+			}
+			for range b.N {
+				b.StopTimer()
+				if err := os.Remove(repo.indexPath()); err != nil && !os.IsNotExist(err) {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+				if err := repo.Add("."); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
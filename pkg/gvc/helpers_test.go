@@ -0,0 +1,58 @@
+package gvc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestRepoWithCommit creates a repository in a temporary directory whose
+// one commit, "first", adds file.txt
+func newTestRepoWithCommit(t *testing.T) (*Repository, string) {
+	t.Helper()
+	repo, err := Init(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo, commitTestFile(t, repo)
+}
+
+// commitTestFile commits file.txt as "first" and returns the commit
+func commitTestFile(t *testing.T, repo *Repository) string {
+	t.Helper()
+	writeTestFile(t, repo, "file.txt", "content\n")
+	if err := repo.Add("file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	commitSHA, err := repo.CommitWithOptions("first", CommitOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return commitSHA
+}
+
+// commitTestChange writes path and commits it with message
+func commitTestChange(t *testing.T, repo *Repository, path, content, message string) string {
+	t.Helper()
+	writeTestFile(t, repo, path, content)
+	if err := repo.Add(path); err != nil {
+		t.Fatal(err)
+	}
+	sha, err := repo.CommitWithOptions(message, CommitOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sha
+}
+
+// writeTestFile writes a file in the working tree, creating its directories
+func writeTestFile(t *testing.T, repo *Repository, path, content string) {
+	t.Helper()
+	full := filepath.Join(repo.Root, filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,151 @@
+package gvc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// HooksDir is the default directory inside .gvc holding hook programs
+const HooksDir = "hooks"
+
+// CommitMsgFile is where the commit-msg hook finds the message it checks
+const CommitMsgFile = "COMMIT_EDITMSG"
+
+// HookError reports a hook that exited with a failure and so stopped a command
+type HookError struct {
+	Hook string
+	Err  error
+}
+
+func (e *HookError) Error() string {
+	return fmt.Sprintf("%s hook declined: %v", e.Hook, e.Err)
+}
+
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// hookPath returns the executable for a hook, or "" when none is installed.
+// core.hooksPath moves the hooks directory; a relative path is taken from
+// the top of the working tree.
+func (r *Repository) hookPath(name string) (string, error) {
+	dir, err := r.getConfigString("core.hooksPath", "")
+	if err != nil {
+		return "", err
+	}
+	if dir == "" {
+		dir = r.gitPath(HooksDir)
+	} else if dir = expandHome(dir); !filepath.IsAbs(dir) {
+		dir = filepath.Join(r.Root, dir)
+	}
+	path := filepath.Join(dir, name)
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+		return "", nil
+	}
+	return path, nil
+}
+
+// runHook runs a hook from the top of the working tree with input on stdin.
+// A missing or non-executable hook succeeds; the hook's output goes to stderr.
+func (r *Repository) runHook(name string, input []byte, args ...string) error {
+	path, err := r.hookPath(name)
+	if err != nil || path == "" {
+		return err
+	}
+	cmd := exec.Command(path, args...)
+	cmd.Dir = r.Root
+	cmd.Env = r.Environ()
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout, cmd.Stderr = os.Stderr, os.Stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return &HookError{Hook: name, Err: err}
+		}
+		return fmt.Errorf("failed to run %s hook: %w", name, err)
+	}
+	return nil
+}
+
+// checkNoVerify refuses to skip hooks when hooks.allowNoVerify is false, so
+// a repository can make its hooks mandatory
+func (r *Repository) checkNoVerify() error {
+	allowed, err := r.getConfigBool("hooks.allowNoVerify", true)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return errors.New("--no-verify is not allowed in this repository (hooks.allowNoVerify is false)")
+	}
+	return nil
+}
+
+// runCommitHooks runs pre-commit and then commit-msg, which may rewrite the
+// message, and returns the message to record. noVerify skips both.
+func (r *Repository) runCommitHooks(message string, noVerify bool) (string, error) {
+	if noVerify {
+		return message, r.checkNoVerify()
+	}
+	if err := r.runHook("pre-commit", nil); err != nil {
+		return "", err
+	}
+
+	path, err := r.hookPath("commit-msg")
+	if err != nil || path == "" {
+		return message, err
+	}
+	msgFile := r.gitPath(CommitMsgFile)
+	if err := os.WriteFile(msgFile, []byte(message+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", CommitMsgFile, err)
+	}
+	if err := r.runHook("commit-msg", nil, msgFile); err != nil {
+		return "", err
+	}
+	edited, err := os.ReadFile(msgFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", CommitMsgFile, err)
+	}
+	message = string(bytes.TrimRight(edited, "\n"))
+	if len(bytes.TrimSpace(edited)) == 0 {
+		return "", errors.New("aborting commit due to empty commit message")
+	}
+	return message, nil
+}
+
+// runPostCommitHook runs post-commit once a commit is recorded. The commit
+// stands whatever the hook does, so a failing hook is only reported.
+func (r *Repository) runPostCommitHook() {
+	err := r.runHook("post-commit", nil)
+	var hookErr *HookError
+	if err != nil && !errors.As(err, &hookErr) {
+		fmt.Fprintf(r.Out, "warning: %v\n", err)
+	}
+}
+
+// runPrePushHook runs pre-push with the remote's name and URL, feeding it a
+// "<local ref> <local sha> <remote ref> <remote sha>" line per accepted update
+func (r *Repository) runPrePushHook(name, url string, updates []*refUpdate) error {
+	var input bytes.Buffer
+	for _, update := range updates {
+		if update.rejected != "" {
+			continue
+		}
+		oldSHA := update.oldSHA
+		if oldSHA == "" {
+			oldSHA = r.Format.ZeroSHA()
+		}
+		fmt.Fprintf(&input, "%s %s %s %s\n", update.src, update.newSHA, update.dst, oldSHA)
+	}
+	if input.Len() == 0 {
+		return nil
+	}
+	if name == "" {
+		name = url
+	}
+	return r.runHook("pre-push", input.Bytes(), name, url)
+}
@@ -0,0 +1,248 @@
+package gvc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is the name of the per-directory files listing paths to ignore
+const IgnoreFile = ".gvcignore"
+
+// InfoExcludeFile holds a repository's private ignore patterns, which are not committed
+const InfoExcludeFile = "info/exclude"
+
+// IgnoreRule is one pattern line of an ignore file
+type IgnoreRule struct {
+	Source  string // file the rule came from
+	Line    int
+	Pattern string // the line as written
+
+	base     string // directory the rule applies under, "" for the whole tree
+	glob     string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// Ignore decides which untracked paths are ignored. Rules come from, in
+// increasing priority: core.excludesFile, .gvc/info/exclude, and the
+// .gvcignore files from the top of the working tree down to a path's
+// directory. The last matching rule wins; a "!" rule re-includes a path.
+type Ignore struct {
+	repo  *Repository
+	rules []IgnoreRule // global rules, lowest priority first
+	dirs  map[string][]IgnoreRule
+}
+
+// LoadIgnore reads the global and repository ignore files. The .gvcignore
+// files are read as directories are looked at.
+func (r *Repository) LoadIgnore() (*Ignore, error) {
+	ig := &Ignore{repo: r, dirs: make(map[string][]IgnoreRule)}
+
+	excludesFile, err := r.getConfigString("core.excludesFile", "")
+	if err != nil {
+		return nil, err
+	}
+	if excludesFile == "" {
+		// Like Git's ~/.config/git/ignore
+		if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+			excludesFile = filepath.Join(configHome, "gvc", "ignore")
+		} else if home, err := os.UserHomeDir(); err == nil {
+			excludesFile = filepath.Join(home, ".config", "gvc", "ignore")
+		}
+	}
+	for _, file := range []string{expandHome(excludesFile), r.gitPath(filepath.FromSlash(InfoExcludeFile))} {
+		if file == "" {
+			continue
+		}
+		rules, err := readIgnoreFile(file, "")
+		if err != nil {
+			return nil, err
+		}
+		ig.rules = append(ig.rules, rules...)
+	}
+	return ig, nil
+}
+
+// readIgnoreFile parses an ignore file whose rules apply under base. A
+// missing file has no rules.
+func readIgnoreFile(file, base string) ([]IgnoreRule, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read ignore file %s: %w", file, err)
+	}
+
+	var rules []IgnoreRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		if rule, ok := parseIgnoreRule(scanner.Text()); ok {
+			rule.Source, rule.Line, rule.base = file, line, base
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// parseIgnoreRule reads one line of an ignore file, following Git's syntax:
+// blank lines and "#" comments are skipped, "!" negates, a trailing "/"
+// matches only directories and a "/" anywhere else anchors the pattern to
+// the ignore file's directory
+func parseIgnoreRule(line string) (IgnoreRule, bool) {
+	rule := IgnoreRule{Pattern: line}
+	// Trailing spaces are dropped unless escaped
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate, line = true, line[1:]
+	} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored, line = true, strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	rule.glob = line
+	return rule, true
+}
+
+// Negate reports whether the rule re-includes the paths it matches
+func (rule *IgnoreRule) Negate() bool {
+	return rule.negate
+}
+
+// matches reports whether the rule matches a slash-separated path
+func (rule *IgnoreRule) matches(p string, isDir bool) bool {
+	if rule.dirOnly && !isDir {
+		return false
+	}
+	rel := p
+	if rule.base != "" {
+		var ok bool
+		if rel, ok = strings.CutPrefix(p, rule.base+"/"); !ok {
+			return false
+		}
+	}
+	if !rule.anchored {
+		return globMatch(rule.glob, path.Base(rel))
+	}
+	return globMatch(rule.glob, rel)
+}
+
+// globMatch matches a slash-separated path against a glob where "**" spans
+// any number of directories
+func globMatch(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if matchSegments(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], name[0]); err != nil || !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// dirRules returns the rules of the .gvcignore file in dir, or .gitignore
+// in a Git repository, reading it once
+func (ig *Ignore) dirRules(dir string) ([]IgnoreRule, error) {
+	if rules, ok := ig.dirs[dir]; ok {
+		return rules, nil
+	}
+	name := IgnoreFile
+	if ig.repo.Git {
+		name = GitIgnoreFile
+	}
+	rules, err := readIgnoreFile(filepath.Join(ig.repo.worktreePath(dir), name), dir)
+	if err != nil {
+		return nil, err
+	}
+	// Report rules by their path in the working tree
+	for i := range rules {
+		rules[i].Source = path.Join(dir, name)
+	}
+	ig.dirs[dir] = rules
+	return rules, nil
+}
+
+// matchPath returns the last rule matching p itself, ignoring its parents
+func (ig *Ignore) matchPath(p string, isDir bool) (*IgnoreRule, error) {
+	var match *IgnoreRule
+	check := func(rules []IgnoreRule) {
+		for i := range rules {
+			if rules[i].matches(p, isDir) {
+				match = &rules[i]
+			}
+		}
+	}
+	check(ig.rules)
+
+	dir := ""
+	parts := strings.Split(p, "/")
+	for i := 0; i < len(parts); i++ {
+		rules, err := ig.dirRules(dir)
+		if err != nil {
+			return nil, err
+		}
+		check(rules)
+		dir = path.Join(dir, parts[i])
+	}
+	return match, nil
+}
+
+// Match returns the rule that decides whether a slash-separated path
+// relative to the top of the working tree is ignored, or nil when none
+// matches. A path inside an ignored directory is ignored by the
+// directory's rule, which no rule for the path can undo.
+func (ig *Ignore) Match(p string, isDir bool) (*IgnoreRule, error) {
+	parts := strings.Split(p, "/")
+	for i := 1; i < len(parts); i++ {
+		rule, err := ig.matchPath(strings.Join(parts[:i], "/"), true)
+		if err != nil {
+			return nil, err
+		}
+		if rule != nil && !rule.negate {
+			return rule, nil
+		}
+	}
+	return ig.matchPath(p, isDir)
+}
+
+// Ignored reports whether a slash-separated path relative to the top of the
+// working tree is ignored
+func (ig *Ignore) Ignored(p string, isDir bool) (bool, error) {
+	rule, err := ig.Match(p, isDir)
+	if err != nil {
+		return false, err
+	}
+	return rule != nil && !rule.negate, nil
+}
@@ -0,0 +1,742 @@
+package gvc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IndexEntry represents a file in the staging area
+type IndexEntry struct {
+	Path    string     `json:"path"`
+	SHA     string     `json:"sha"`
+	Mode    string     `json:"mode"`
+	Size    int64      `json:"size"`
+	ModTime time.Time  `json:"mod_time"`
+	Flags   IndexFlags `json:"flags,omitempty"`
+}
+
+// Index represents the staging area
+type Index struct {
+	Entries []IndexEntry `json:"entries"`
+	// TreeCache maps directories, "" for the top, to the SHAs of trees
+	// already written for their entries, so unchanged ones are not rebuilt.
+	// Directories whose entries change are dropped from it on write.
+	TreeCache map[string]string `json:"-"`
+	// ResolveUndo keeps the versions of paths a merge left in conflict, so a
+	// resolution can be undone with RecreateConflicts
+	ResolveUndo []ResolveUndoEntry `json:"-"`
+
+	loaded map[string]string // mode and SHA of each entry as read, to check TreeCache against
+}
+
+// Index file format: Git's "DIRC" layout, so entries are fixed-size records
+// sorted by path, extensions (see indexext.go) may follow them and the file
+// ends with a checksum. Version 2 is written unless an entry has flags only
+// version 3 can hold.
+const (
+	indexSignature = "DIRC"
+	indexVersion   = 2
+	indexVersionV3 = 3
+)
+
+// Flags of an index entry besides its path length
+const (
+	indexFlagExtended = 0x4000
+	indexStageMask    = 0x3000
+	indexStageShift   = 12
+)
+
+// ReadIndex loads the staging area: every path the next commit will record.
+// An index written in the older JSON format, which held only the changes
+// staged since HEAD, is still read by laying it over HEAD's tree; the next
+// write converts it. A corrupt index is rebuilt rather than failing every
+// command that reads it.
+func (r *Repository) ReadIndex() (*Index, error) {
+	data, err := r.readGitFile(r.indexPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Index{Entries: []IndexEntry{}}, nil
+		}
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+
+	if len(data) > 0 && data[0] == '{' {
+		var legacy Index
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			return r.recoverIndex(data, err)
+		}
+		head, err := r.headEntries()
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range legacy.Entries {
+			entry.Path = normalizePath(entry.Path)
+			head[entry.Path] = entry
+		}
+		return &Index{Entries: sortedEntries(head)}, nil
+	}
+	index, err := r.decodeIndex(data)
+	if errors.Is(err, errIndexVersion) || errors.Is(err, errIndexExtension) {
+		return nil, fmt.Errorf("failed to parse index: %w", err)
+	}
+	if err != nil {
+		return r.recoverIndex(data, err)
+	}
+	if index.Entries, err = r.cleanIndexEntries(index.Entries); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// cleanIndexEntries respells the paths of entries as cleanIndexPath stores
+// them. Entries that turn out to be two spellings of one path, such as an
+// index another tool wrote with both the composed and decomposed form of a
+// name, are merged into the later one.
+func (r *Repository) cleanIndexEntries(entries []IndexEntry) ([]IndexEntry, error) {
+	changed := false
+	for i := range entries {
+		path, err := r.cleanIndexPath(entries[i].Path)
+		if err != nil {
+			return nil, err
+		}
+		if path != entries[i].Path {
+			entries[i].Path = path
+			changed = true
+		}
+	}
+	if !changed {
+		return entries, nil
+	}
+	byPath := make(map[string]IndexEntry, len(entries))
+	for _, entry := range entries {
+		byPath[entry.Path] = entry
+	}
+	return sortedEntries(byPath), nil
+}
+
+// WriteIndex replaces the staging area
+func (r *Repository) WriteIndex(index *Index) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	data, err := r.encodeIndex(index)
+	if err != nil {
+		return err
+	}
+
+	if err := r.writeGitFile(r.indexPath(), data); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+
+	return nil
+}
+
+// encodeIndex serializes the index, sorting its entries by path
+func (r *Repository) encodeIndex(index *Index) ([]byte, error) {
+	// Entries kept in order, as AddWithOptions keeps them, are not copied
+	entries := index.Entries
+	if !slices.IsSortedFunc(entries, compareEntryPaths) {
+		entries = append([]IndexEntry{}, entries...)
+		slices.SortFunc(entries, compareEntryPaths)
+	}
+
+	version := uint32(indexVersion)
+	for _, entry := range entries {
+		if entry.Flags&extendedIndexFlags != 0 {
+			version = indexVersionV3
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(indexSignature)
+	binary.Write(&buf, binary.BigEndian, version)
+	binary.Write(&buf, binary.BigEndian, uint32(len(entries)))
+	for _, entry := range entries {
+		if err := checkIndexPath(entry.Path); err != nil {
+			return nil, fmt.Errorf("failed to write index: %w", err)
+		}
+		sha, err := hex.DecodeString(entry.SHA)
+		if err != nil || len(sha) != r.Format.Size {
+			return nil, fmt.Errorf("invalid SHA %q in index entry %s", entry.SHA, entry.Path)
+		}
+		mode, err := strconv.ParseUint(entry.Mode, 8, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mode %q in index entry %s", entry.Mode, entry.Path)
+		}
+
+		start := buf.Len()
+		seconds, nanos := uint32(0), uint32(0)
+		if !entry.ModTime.IsZero() {
+			seconds, nanos = uint32(entry.ModTime.Unix()), uint32(entry.ModTime.Nanosecond())
+		}
+		// ctime, mtime, dev, ino, mode, uid, gid, size; gvc only tracks mtime and size
+		stat := []uint32{seconds, nanos, seconds, nanos, 0, 0, uint32(mode), 0, 0, uint32(entry.Size)}
+		binary.Write(&buf, binary.BigEndian, stat)
+		buf.Write(sha)
+		flags := uint16(min(len(entry.Path), 0xfff))
+		if entry.Flags&IndexAssumeValid != 0 {
+			flags |= indexFlagAssumeValid
+		}
+		if entry.Flags&extendedIndexFlags == 0 {
+			binary.Write(&buf, binary.BigEndian, flags)
+		} else {
+			var extended uint16
+			if entry.Flags&IndexSkipWorktree != 0 {
+				extended |= indexExtSkipWorktree
+			}
+			if entry.Flags&IndexIntentToAdd != 0 {
+				extended |= indexExtIntentToAdd
+			}
+			binary.Write(&buf, binary.BigEndian, []uint16{flags | indexFlagExtended, extended})
+		}
+		buf.WriteString(entry.Path)
+		// NUL-terminate and pad each entry to a multiple of eight bytes
+		buf.Write(make([]byte, 8-(buf.Len()-start)%8))
+	}
+	if err := r.encodeIndexExtensions(&buf, index, entries); err != nil {
+		return nil, err
+	}
+
+	h := r.Format.New()
+	h.Write(buf.Bytes())
+	buf.Write(h.Sum(nil))
+	return buf.Bytes(), nil
+}
+
+// decodeIndex parses an index file, verifying its checksum
+func (r *Repository) decodeIndex(data []byte) (*Index, error) {
+	hashSize := r.Format.Size
+	if len(data) < 12+hashSize || string(data[:4]) != indexSignature {
+		return nil, errors.New("not an index file")
+	}
+	body, checksum := data[:len(data)-hashSize], data[len(data)-hashSize:]
+	h := r.Format.New()
+	h.Write(body)
+	if !bytes.Equal(h.Sum(nil), checksum) {
+		return nil, errIndexChecksum
+	}
+	if version := binary.BigEndian.Uint32(body[4:8]); version != indexVersion && version != indexVersionV3 {
+		return nil, fmt.Errorf("%w %d", errIndexVersion, version)
+	}
+	entries, end, err := r.decodeIndexEntries(body)
+	if err != nil {
+		return nil, err
+	}
+	index := &Index{Entries: entries, loaded: make(map[string]string, len(entries))}
+	for _, entry := range entries {
+		index.loaded[entry.Path] = entry.Mode + " " + entry.SHA
+	}
+	if err := r.decodeIndexExtensions(index, body, end); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+// Errors decodeIndex reports for an index that is intact but not readable,
+// and for one whose content does not match its checksum
+var (
+	errIndexVersion  = errors.New("unsupported index version")
+	errIndexChecksum = errors.New("index checksum mismatch")
+)
+
+// decodeIndexEntries parses the entries following an index header, returning
+// those read before any error and the offset the entries end at. Of a Git
+// merge conflict only "our" stage is kept to stand for the path.
+func (r *Repository) decodeIndexEntries(body []byte) ([]IndexEntry, int, error) {
+	hashSize := r.Format.Size
+	extendedFlags := binary.BigEndian.Uint32(body[4:8]) >= indexVersionV3
+	count := binary.BigEndian.Uint32(body[8:12])
+	entries := make([]IndexEntry, 0, min(count, uint32(len(body)/(40+hashSize+8))))
+	pos := 12
+	for i := uint32(0); i < count; i++ {
+		fixed := 40 + hashSize + 2
+		if pos+fixed > len(body) {
+			return entries, pos, errors.New("truncated index entry")
+		}
+		stat := body[pos : pos+40]
+		sha := body[pos+40 : pos+40+hashSize]
+		flags := binary.BigEndian.Uint16(body[pos+40+hashSize:])
+		var extended uint16
+		if extendedFlags && flags&indexFlagExtended != 0 {
+			fixed += 2
+			if pos+fixed > len(body) {
+				return entries, pos, errors.New("truncated index entry")
+			}
+			extended = binary.BigEndian.Uint16(body[pos+fixed-2:])
+		}
+		nameEnd := bytes.IndexByte(body[pos+fixed:], 0)
+		if nameEnd < 0 {
+			return entries, pos, errors.New("unterminated index entry path")
+		}
+		entry := IndexEntry{
+			Path: string(body[pos+fixed : pos+fixed+nameEnd]),
+			SHA:  hex.EncodeToString(sha),
+			Mode: strconv.FormatUint(uint64(binary.BigEndian.Uint32(stat[24:28])), 8),
+			Size: int64(binary.BigEndian.Uint32(stat[36:40])),
+		}
+		if flags&indexFlagAssumeValid != 0 {
+			entry.Flags |= IndexAssumeValid
+		}
+		if extended&indexExtSkipWorktree != 0 {
+			entry.Flags |= IndexSkipWorktree
+		}
+		if extended&indexExtIntentToAdd != 0 {
+			entry.Flags |= IndexIntentToAdd
+		}
+		if seconds := binary.BigEndian.Uint32(stat[8:12]); seconds != 0 {
+			entry.ModTime = time.Unix(int64(seconds), int64(binary.BigEndian.Uint32(stat[12:16])))
+		}
+		if stage := flags & indexStageMask >> indexStageShift; stage == 0 || stage == 2 {
+			entries = append(entries, entry)
+		}
+
+		length := fixed + nameEnd
+		pos = min(pos+length+8-length%8, len(body))
+	}
+	return entries, pos, nil
+}
+
+// AddOptions selects what Add stages besides the files it is given
+type AddOptions struct {
+	All    bool // also stage new and deleted files; with no paths, the whole working tree
+	Update bool // only restage tracked files, including deletions
+	Force  bool // stage ignored files too
+	// DryRun reports on Out each path that would be staged, as add 'path',
+	// or unstaged, as remove 'path', without storing blobs or writing the index
+	DryRun bool
+}
+
+// Add stages the working tree copies of the given paths, relative to the repository root
+func (r *Repository) Add(paths ...string) error {
+	return r.AddWithOptions(AddOptions{}, paths...)
+}
+
+// AddWithOptions stages the given paths, relative to the repository root.
+// A directory stages every file below it that is not ignored, and a tracked
+// file missing from the working tree is unstaged so the next commit records
+// its removal. With All or Update and no paths, the whole working tree is
+// staged.
+func (r *Repository) AddWithOptions(opts AddOptions, paths ...string) error {
+	if opts.DryRun {
+		return r.addDryRun(opts, paths)
+	}
+	// Hold the index lock from read to write so concurrent adds cannot lose entries
+	l, err := r.lockGitFile(r.indexPath())
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+
+	// The index is read once and written once however many paths are added
+	index, err := r.ReadIndex()
+	if err != nil {
+		return err
+	}
+	staged := indexEntryMap(index)
+
+	files, removed, err := r.addTargets(opts, staged, paths)
+	if err != nil {
+		return err
+	}
+
+	// Reuse the staged SHA of files whose stat data is unchanged and hash
+	// the rest in parallel
+	indexTime := r.indexModTime()
+	entries := make([]IndexEntry, len(files))
+	var toHash []int
+	var fullPaths []string
+	for i, filePath := range files {
+		fileInfo, err := os.Lstat(r.worktreePath(filePath))
+		if err != nil {
+			return fmt.Errorf("failed to stat file %s: %w", filePath, err)
+		}
+		if fileInfo.IsDir() {
+			// A submodule is staged at the commit its HEAD is on
+			var ok bool
+			if entries[i], ok, err = r.submoduleEntry(filePath); err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%s is a directory, not a file or submodule", filePath)
+			}
+			continue
+		}
+		mode, err := r.workingMode(fileInfo, staged[filePath].Mode)
+		if err != nil {
+			return err
+		}
+		entries[i] = IndexEntry{
+			Path:    filePath,
+			Mode:    mode,
+			Size:    fileInfo.Size(),
+			ModTime: fileInfo.ModTime(),
+		}
+		if cached, ok := staged[filePath]; ok && !cached.ModTime.IsZero() && cached.ModTime.Before(indexTime) &&
+			cached.ModTime.Equal(entries[i].ModTime) && cached.Size == entries[i].Size && cached.Mode == entries[i].Mode {
+			entries[i].SHA = cached.SHA
+			continue
+		}
+		toHash = append(toHash, i)
+		fullPaths = append(fullPaths, r.worktreePath(filePath))
+	}
+
+	// Only content about to be stored is checked, so an unchanged large file
+	// is reported once
+	changed := make([]IndexEntry, len(toHash))
+	for i, entryIndex := range toHash {
+		changed[i] = entries[entryIndex]
+	}
+	if err := r.checkLargeFiles(changed); err != nil {
+		return err
+	}
+
+	// Create blob objects, flushed before the index refers to them
+	var shas []string
+	err = r.batchObjects(func() error {
+		shas, err = r.hashFiles(fullPaths, true)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create blobs: %w", err)
+	}
+	for i, sha := range shas {
+		entries[toHash[i]].SHA = sha
+	}
+
+	index.Entries = updateIndexEntries(index.Entries, entries, removed)
+
+	// Write updated index
+	data, err := r.encodeIndex(index)
+	if err != nil {
+		return err
+	}
+	if err := l.commit(data); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// compareEntryPaths orders index entries by path, the order the index keeps
+func compareEntryPaths(a, b IndexEntry) int {
+	return strings.Compare(a.Path, b.Path)
+}
+
+// updateIndexEntries replaces the entries for the paths of changed, adds the
+// new ones and drops the removed paths. Both changed and removed are sorted;
+// existing entries are found by binary search, so restaging files rewrites
+// them in place and new files cost a single merge pass.
+func updateIndexEntries(current, changed []IndexEntry, removed []string) []IndexEntry {
+	for i := range current {
+		current[i].Path = normalizePath(current[i].Path)
+	}
+	if !slices.IsSortedFunc(current, compareEntryPaths) {
+		slices.SortFunc(current, compareEntryPaths)
+	}
+
+	var added []IndexEntry
+	for _, entry := range changed {
+		i, found := slices.BinarySearchFunc(current, entry.Path, func(e IndexEntry, path string) int {
+			return strings.Compare(e.Path, path)
+		})
+		if found {
+			current[i] = entry
+		} else {
+			added = append(added, entry)
+		}
+	}
+	drop := make(map[string]bool, len(removed))
+	for _, path := range removed {
+		drop[path] = true
+	}
+	if len(added) == 0 && len(drop) == 0 {
+		return current
+	}
+
+	merged := make([]IndexEntry, 0, len(current)+len(added))
+	for len(current) > 0 || len(added) > 0 {
+		var entry IndexEntry
+		if len(added) == 0 || len(current) > 0 && current[0].Path < added[0].Path {
+			entry, current = current[0], current[1:]
+		} else {
+			entry, added = added[0], added[1:]
+		}
+		if !drop[entry.Path] {
+			merged = append(merged, entry)
+		}
+	}
+	return merged
+}
+
+// addDryRun reports what AddWithOptions would change in the index: the files
+// whose working tree copy differs from the staged one and the tracked files
+// deleted from the working tree, in path order
+func (r *Repository) addDryRun(opts AddOptions, paths []string) error {
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+	files, removed, err := r.addTargets(opts, staged, paths)
+	if err != nil {
+		return err
+	}
+	indexTime := r.indexModTime()
+	actions := make(map[string]string, len(files)+len(removed))
+	var changed []string
+	for _, path := range files {
+		old, tracked := staged[path]
+		entry, ok, err := r.readWorkingEntryCached(path, old, indexTime)
+		if err != nil {
+			return err
+		}
+		if ok && (!tracked || entry.SHA != old.SHA || entry.Mode != old.Mode) {
+			actions[path] = "add"
+			changed = append(changed, path)
+		}
+	}
+	for _, path := range removed {
+		actions[path] = "remove"
+	}
+	changed = append(changed, removed...)
+	sort.Strings(changed)
+	for _, path := range changed {
+		fmt.Fprintf(r.Out, "%s '%s'\n", actions[path], path)
+	}
+	return nil
+}
+
+// Unstage resets the staged files at or below the given paths, relative to
+// the repository root, to HEAD's, so their changes are no longer staged and
+// files HEAD does not have are no longer tracked. The working tree is left
+// alone.
+func (r *Repository) Unstage(paths ...string) error {
+	l, err := r.lockGitFile(r.indexPath())
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+
+	index, err := r.ReadIndex()
+	if err != nil {
+		return err
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+
+	byPath := make(map[string]IndexEntry, len(index.Entries))
+	matched := false
+	for _, entry := range index.Entries {
+		path := normalizePath(entry.Path)
+		if !underPaths(path, paths) {
+			byPath[path] = entry
+			continue
+		}
+		matched = true
+		if old, ok := head[path]; ok {
+			byPath[path] = old
+		}
+	}
+	// A staged removal is undone by putting HEAD's entry back
+	for path, entry := range head {
+		if _, ok := byPath[path]; !ok && underPaths(path, paths) {
+			matched = true
+			byPath[path] = entry
+		}
+	}
+	if !matched {
+		return fmt.Errorf("pathspec '%s' did not match any files", strings.Join(paths, " "))
+	}
+
+	index.Entries = index.Entries[:0]
+	for _, entry := range byPath {
+		index.Entries = append(index.Entries, entry)
+	}
+	data, err := r.encodeIndex(index)
+	if err != nil {
+		return err
+	}
+	if err := l.commit(data); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// trackedUnder returns the paths of sorted that are spec or lie below it,
+// found by binary search rather than a scan, so adding many paths stays fast
+func trackedUnder(sorted []string, spec string) []string {
+	if spec == "" {
+		return sorted
+	}
+	var paths []string
+	if i := sort.SearchStrings(sorted, spec); i < len(sorted) && sorted[i] == spec {
+		paths = append(paths, spec)
+	}
+	// Paths below spec sort between spec+"/" and spec+"0", '0' following '/'
+	lo := sort.SearchStrings(sorted, spec+"/")
+	hi := sort.SearchStrings(sorted, spec+"0")
+	return append(paths, sorted[lo:hi]...)
+}
+
+// addTargets expands the paths given to add into the files to stage and the
+// tracked paths deleted from the working tree, both sorted
+func (r *Repository) addTargets(opts AddOptions, staged map[string]IndexEntry, paths []string) (files, removed []string, err error) {
+	if len(paths) == 0 && (opts.All || opts.Update) {
+		paths = []string{""}
+	}
+	ig, err := r.LoadIgnore()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tracked := make([]string, 0, len(staged))
+	for path := range staged {
+		tracked = append(tracked, path)
+	}
+	sort.Strings(tracked)
+
+	toStage := make(map[string]bool)
+	toRemove := make(map[string]bool)
+	var ignored []string
+	for _, spec := range paths {
+		if spec, err = r.cleanIndexPath(spec); err != nil {
+			return nil, nil, err
+		}
+		if spec == "." {
+			spec = ""
+		}
+		if spec != "" {
+			if err := checkIndexPath(spec); err != nil {
+				return nil, nil, err
+			}
+			if r.beyondSymlink(spec) {
+				return nil, nil, errBeyondSymlink(spec)
+			}
+		}
+
+		// Tracked files under the path are restaged or, when gone, removed
+		matched := false
+		for _, path := range trackedUnder(tracked, spec) {
+			entry := staged[path]
+			matched = true
+			// A file whose directory became a symlink, or that became a
+			// directory itself, is gone from the tree
+			info, err := os.Lstat(r.worktreePath(path))
+			if err == nil && (r.beyondSymlink(path) || info.IsDir() && entry.Mode != GitlinkMode && !isRepoRoot(r.worktreePath(path))) {
+				err = os.ErrNotExist
+			}
+			if err == nil {
+				toStage[path] = true
+			} else if notOnDisk(err) {
+				// A file a sparse checkout leaves out is not deleted
+				if entry.Flags&IndexSkipWorktree == 0 {
+					toRemove[path] = true
+				}
+			} else {
+				return nil, nil, fmt.Errorf("failed to stat file %s: %w", path, err)
+			}
+		}
+
+		info, err := os.Lstat(r.worktreePath(spec))
+		if err != nil && !notOnDisk(err) {
+			return nil, nil, fmt.Errorf("failed to stat file %s: %w", spec, err)
+		}
+		if err != nil || opts.Update {
+			if !matched {
+				return nil, nil, fmt.Errorf("pathspec '%s' did not match any files", spec)
+			}
+			continue
+		}
+
+		if !info.IsDir() {
+			if _, tracked := staged[spec]; !tracked {
+				isIgnored, err := ig.Ignored(spec, false)
+				if err != nil {
+					return nil, nil, err
+				}
+				if isIgnored && !opts.Force {
+					ignored = append(ignored, spec)
+					continue
+				}
+				toStage[spec] = true
+			}
+			continue
+		}
+
+		// New files below a directory, leaving out ignored ones
+		err = filepath.WalkDir(r.worktreePath(spec), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Nested repositories such as submodules are not part of this one, nor
+			// is the .gvc file of a linked worktree
+			if path != r.Root && (isMetadataDir(d.Name()) || path == r.GitDir || d.IsDir() && isRepoRoot(path)) {
+				if !d.IsDir() {
+					return nil
+				}
+				return filepath.SkipDir
+			}
+			if !d.IsDir() && !d.Type().IsRegular() && d.Type()&fs.ModeSymlink == 0 {
+				return nil
+			}
+			rel, err := filepath.Rel(r.Root, path)
+			if err != nil {
+				return err
+			}
+			if rel, err = r.cleanIndexPath(rel); err != nil {
+				return err
+			}
+			if rel == "." || toStage[rel] {
+				return nil
+			}
+			if !opts.Force {
+				isIgnored, err := ig.Ignored(rel, d.IsDir())
+				if err != nil {
+					return err
+				}
+				if isIgnored && d.IsDir() {
+					return filepath.SkipDir
+				}
+				if isIgnored {
+					return nil
+				}
+			}
+			if !d.IsDir() {
+				if err := checkIndexPath(rel); err != nil {
+					return err
+				}
+				toStage[rel] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan %s: %w", spec, err)
+		}
+	}
+	if len(ignored) > 0 {
+		return nil, nil, fmt.Errorf("the following paths are ignored by one of your %s files:\n%s\nUse -f if you really want to add them",
+			IgnoreFile, strings.Join(ignored, "\n"))
+	}
+
+	for path := range toStage {
+		files = append(files, path)
+	}
+	for path := range toRemove {
+		removed = append(removed, path)
+	}
+	sort.Strings(files)
+	sort.Strings(removed)
+	return files, removed, nil
+}
@@ -0,0 +1,38 @@
+package gvc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddAllStagesDirectoryReplacedByFile(t *testing.T) {
+	repo, _ := newTestRepoWithCommit(t)
+	writeTestFile(t, repo, "d/a", "a\n")
+	writeTestFile(t, repo, "d/b", "b\n")
+	if err := repo.Add("d"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CommitWithOptions("add d", CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(repo.Root, "d")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, repo, "d", "now a file\n")
+
+	if err := repo.AddWithOptions(AddOptions{All: true}); err != nil {
+		t.Fatal(err)
+	}
+	index, err := repo.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, entry := range index.Entries {
+		paths = append(paths, entry.Path)
+	}
+	if len(paths) != 2 || paths[0] != "d" || paths[1] != "file.txt" {
+		t.Errorf("index holds %v, want [d file.txt]", paths)
+	}
+}
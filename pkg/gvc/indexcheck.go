@@ -0,0 +1,181 @@
+package gvc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// CorruptIndexFile is where a damaged index is kept after it is rebuilt
+const CorruptIndexFile = "index.corrupt"
+
+// validIndexModes are the modes an index entry may have
+var validIndexModes = map[string]bool{"100644": true, "100755": true, "120000": true, GitlinkMode: true}
+
+// IndexReport is the outcome of checking the index
+type IndexReport struct {
+	Entries  int      // entries that could be read
+	Problems []string // everything wrong with the file or its entries
+}
+
+// VerifyIndex checks the index without changing it: its header and
+// checksum, that its entries are sorted, unique and well formed, and that
+// the objects they name exist
+func (r *Repository) VerifyIndex() (*IndexReport, error) {
+	report := &IndexReport{}
+	data, err := r.readGitFile(r.indexPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return report, nil
+		}
+		return nil, fmt.Errorf("failed to read index: %w", err)
+	}
+	problem := func(format string, args ...any) {
+		report.Problems = append(report.Problems, fmt.Sprintf(format, args...))
+	}
+
+	if len(data) > 0 && data[0] == '{' {
+		// The old JSON format has no checksum, only its syntax to check
+		var legacy Index
+		if err := json.Unmarshal(data, &legacy); err != nil {
+			problem("failed to parse index: %v", err)
+		}
+		report.Entries = len(legacy.Entries)
+		return report, nil
+	}
+	entries, err := r.salvageIndex(data, problem)
+	report.Entries = len(entries)
+	if err != nil {
+		problem("%v", err)
+	}
+
+	seen := make(map[string]bool, len(entries))
+	for i, entry := range entries {
+		if i > 0 && entry.Path <= entries[i-1].Path && !seen[entry.Path] {
+			problem("index entry %s is out of order", entry.Path)
+		}
+		if seen[entry.Path] {
+			problem("index entry %s appears more than once", entry.Path)
+		}
+		seen[entry.Path] = true
+		if msg := indexEntryProblem(entry); msg != "" {
+			problem("index entry %s: %s", entry.Path, msg)
+		} else if entry.Mode != GitlinkMode && !r.objectExists(entry.SHA) {
+			problem("index entry %s: object %s is missing", entry.Path, entry.SHA)
+		}
+	}
+	return report, nil
+}
+
+// salvageIndex reads as many entries from an index file as it can, ignoring
+// its checksum. Problems with the header and checksum go to problem; the
+// error tells why the entries stopped short, if they did.
+func (r *Repository) salvageIndex(data []byte, problem func(format string, args ...any)) ([]IndexEntry, error) {
+	hashSize := r.Format.Size
+	if len(data) < 12 || string(data[:4]) != indexSignature {
+		return nil, errors.New("index has no valid header")
+	}
+	if version := binary.BigEndian.Uint32(data[4:8]); version != indexVersion && version != indexVersionV3 {
+		problem("%v %d", errIndexVersion, version)
+	}
+	body := data
+	if len(data) >= 12+hashSize {
+		body = data[:len(data)-hashSize]
+		h := r.Format.New()
+		h.Write(body)
+		if !bytes.Equal(h.Sum(nil), data[len(body):]) {
+			problem("%v", errIndexChecksum)
+		}
+	} else {
+		problem("index has no checksum")
+	}
+
+	entries, end, err := r.decodeIndexEntries(body)
+	// Git may follow the entries with extensions: a signature, a size and
+	// that much data each
+	for err == nil && len(body)-end >= 8 {
+		size := int(binary.BigEndian.Uint32(body[end+4 : end+8]))
+		if size > len(body)-end-8 {
+			break
+		}
+		end += 8 + size
+	}
+	if err == nil && end != len(body) {
+		err = fmt.Errorf("index has %d unexpected bytes after its entries", len(body)-end)
+	}
+	return entries, err
+}
+
+// indexEntryProblem describes what makes an entry unusable, or returns ""
+func indexEntryProblem(entry IndexEntry) string {
+	switch {
+	case entry.Path == "" || strings.HasPrefix(entry.Path, "/") || path.Clean(entry.Path) != entry.Path:
+		return "invalid path"
+	case checkWorkingPath(entry.Path) != nil:
+		return "path outside the working tree"
+	case checkIndexPath(entry.Path) != nil:
+		return "path with a backslash"
+	case !validIndexModes[entry.Mode]:
+		return fmt.Sprintf("invalid mode %s", entry.Mode)
+	}
+	return ""
+}
+
+// recoverIndex rebuilds a corrupt index from HEAD's tree, keeping the entries
+// that can still be read and whose objects exist. When every entry was read
+// the salvaged list is complete, so paths missing from it stay deleted. The
+// damaged file is kept as .gvc/index.corrupt. A read-only repository only
+// uses the rebuilt index and leaves the file alone.
+func (r *Repository) recoverIndex(data []byte, cause error) (*Index, error) {
+	staged, err := r.headEntries()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse index: %w", cause)
+	}
+	var salvaged []IndexEntry
+	complete := false
+	if len(data) > 0 && data[0] != '{' {
+		entries, err := r.salvageIndex(data, func(string, ...any) {})
+		salvaged, complete = entries, err == nil
+	}
+
+	if complete {
+		listed := make(map[string]bool, len(salvaged))
+		for _, entry := range salvaged {
+			listed[entry.Path] = true
+		}
+		for path := range staged {
+			if !listed[path] {
+				delete(staged, path)
+			}
+		}
+	}
+	kept := 0
+	for _, entry := range salvaged {
+		if indexEntryProblem(entry) != "" || (entry.Mode != GitlinkMode && !r.objectExists(entry.SHA)) {
+			continue
+		}
+		staged[entry.Path] = entry
+		kept++
+	}
+
+	index := &Index{Entries: sortedEntries(staged)}
+	if r.ReadOnly {
+		// Work from the rebuilt index without replacing the damaged one
+		fmt.Fprintf(r.Out, "warning: index is corrupt (%v); using one rebuilt from HEAD, keeping %d of its entries\n", cause, kept)
+		return index, nil
+	}
+	if err := os.WriteFile(r.gitPath(CorruptIndexFile), data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save corrupt index: %w", err)
+	}
+	if err := r.WriteIndex(index); err != nil {
+		return nil, err
+	}
+	fmt.Fprintf(r.Out, "warning: index was corrupt (%v); rebuilt it from HEAD, keeping %d of its entries\n", cause, kept)
+	fmt.Fprintf(r.Out, "hint: the damaged index was saved as %s/%s\n", GvcDir, CorruptIndexFile)
+	return index, nil
+}
@@ -0,0 +1,377 @@
+package gvc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"sort"
+	"strconv"
+)
+
+// Index extensions follow the entries, as in Git: a four-byte signature, a
+// 32-bit size and that much data each. A signature starting with an
+// uppercase letter marks an optional extension, which a reader that does not
+// know it skips; any other is required, and such a reader must refuse the
+// index rather than misread it. New staging area features extend the index
+// this way without breaking older gvc binaries.
+const (
+	indexExtTreeCache   = "TREE"
+	indexExtResolveUndo = "REUC"
+)
+
+// errIndexExtension is reported for a required extension this version of
+// gvc does not know
+var errIndexExtension = errors.New("unsupported required index extension")
+
+// IndexFlags are per-entry flags kept in the index
+type IndexFlags uint16
+
+const (
+	// IndexAssumeValid marks a file to be taken as unchanged without looking at it
+	IndexAssumeValid IndexFlags = 1 << iota
+	// IndexSkipWorktree marks a file left out of the working tree
+	IndexSkipWorktree
+	// IndexIntentToAdd marks a path recorded to be added later, with no content yet
+	IndexIntentToAdd
+)
+
+// extendedIndexFlags are the flags only index version 3 can hold
+const extendedIndexFlags = IndexSkipWorktree | IndexIntentToAdd
+
+// On-disk bits of the flags: assume-valid among the entry's flags, the
+// others in the extended flags version 3 adds
+const (
+	indexFlagAssumeValid = 0x8000
+	indexExtSkipWorktree = 0x4000
+	indexExtIntentToAdd  = 0x2000
+)
+
+// ResolveUndoEntry keeps the versions of a path that were in conflict before
+// it was resolved, so the conflict can be recreated
+type ResolveUndoEntry struct {
+	Path  string
+	Modes [3]string // base, ours and theirs; "" where that side had no file
+	SHAs  [3]string
+}
+
+// cachedTree is a tree cache record as read: the tree's SHA and how many
+// index entries it covers
+type cachedTree struct {
+	sha     string
+	entries int
+}
+
+// decodeIndexExtensions reads the extensions in body from offset end into index
+func (r *Repository) decodeIndexExtensions(index *Index, body []byte, end int) error {
+	var trees map[string]cachedTree
+	for end < len(body) {
+		if len(body)-end < 8 {
+			return errors.New("truncated index extension")
+		}
+		signature := string(body[end : end+4])
+		size := int(binary.BigEndian.Uint32(body[end+4 : end+8]))
+		if size > len(body)-end-8 {
+			return fmt.Errorf("truncated index extension %q", signature)
+		}
+		data := body[end+8 : end+8+size]
+		end += 8 + size
+
+		var err error
+		switch {
+		case signature == indexExtTreeCache:
+			trees, err = r.decodeTreeCache(data)
+		case signature == indexExtResolveUndo:
+			index.ResolveUndo, err = r.decodeResolveUndo(data)
+		case signature[0] < 'A' || signature[0] > 'Z':
+			return fmt.Errorf("%w %q", errIndexExtension, signature)
+		}
+		// Other optional extensions, such as Git's untracked cache, are
+		// skipped and dropped when the index is next written
+		if err != nil {
+			return fmt.Errorf("invalid index extension %q: %w", signature, err)
+		}
+	}
+
+	// Only trees still covering as many entries as they did are trusted
+	dirs := make(map[string]bool, len(trees))
+	for dir := range trees {
+		dirs[dir] = true
+	}
+	counts := countEntriesBelow(index.Entries, dirs)
+	for dir, tree := range trees {
+		if counts[dir] == tree.entries {
+			if index.TreeCache == nil {
+				index.TreeCache = make(map[string]string)
+			}
+			index.TreeCache[dir] = tree.sha
+		}
+	}
+	return nil
+}
+
+// encodeIndexExtensions appends the extensions index carries to buf
+func (r *Repository) encodeIndexExtensions(buf *bytes.Buffer, index *Index, entries []IndexEntry) error {
+	extension := func(signature string, data []byte) {
+		buf.WriteString(signature)
+		binary.Write(buf, binary.BigEndian, uint32(len(data)))
+		buf.Write(data)
+	}
+	if cache := index.validTreeCache(); len(cache) > 0 {
+		data, err := r.encodeTreeCache(cache, entries)
+		if err != nil {
+			return err
+		}
+		extension(indexExtTreeCache, data)
+	}
+	if len(index.ResolveUndo) > 0 {
+		data, err := r.encodeResolveUndo(index.ResolveUndo)
+		if err != nil {
+			return err
+		}
+		extension(indexExtResolveUndo, data)
+	}
+	return nil
+}
+
+// decodeTreeCache parses a tree cache: for each directory, depth first, its
+// name, the number of entries it covers (-1 when its tree is out of date),
+// the number of its subdirectories listed after it and, unless out of date,
+// its tree's SHA
+func (r *Repository) decodeTreeCache(data []byte) (map[string]cachedTree, error) {
+	trees := make(map[string]cachedTree)
+	pos := 0
+	var read func(parent string, depth int) error
+	read = func(parent string, depth int) error {
+		if depth > maxTreeDepth {
+			return errors.New("tree cache is nested too deeply")
+		}
+		nul := bytes.IndexByte(data[pos:], 0)
+		if nul < 0 {
+			return errors.New("unterminated tree cache path")
+		}
+		dir := path.Join(parent, string(data[pos:pos+nul]))
+		pos += nul + 1
+		newline := bytes.IndexByte(data[pos:], '\n')
+		if newline < 0 {
+			return errors.New("unterminated tree cache counts")
+		}
+		var entries, subtrees int
+		if _, err := fmt.Sscanf(string(data[pos:pos+newline]), "%d %d", &entries, &subtrees); err != nil || subtrees < 0 {
+			return fmt.Errorf("invalid tree cache counts for %q", dir)
+		}
+		pos += newline + 1
+		if entries >= 0 {
+			if len(data)-pos < r.Format.Size {
+				return errors.New("truncated tree cache SHA")
+			}
+			trees[dir] = cachedTree{sha: hex.EncodeToString(data[pos : pos+r.Format.Size]), entries: entries}
+			pos += r.Format.Size
+		}
+		for range subtrees {
+			if err := read(dir, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := read("", 0); err != nil {
+		return nil, err
+	}
+	if pos != len(data) {
+		return nil, errors.New("unexpected data after tree cache")
+	}
+	return trees, nil
+}
+
+// maxTreeDepth bounds how deeply the tree cache may nest
+const maxTreeDepth = 4096
+
+// encodeTreeCache serializes the cached trees of directories in the layout
+// decodeTreeCache reads. Their parents are listed too, as out of date when
+// not cached themselves.
+func (r *Repository) encodeTreeCache(cache map[string]string, entries []IndexEntry) ([]byte, error) {
+	children := make(map[string][]string)
+	listed := map[string]bool{"": true}
+	for dir := range cache {
+		for ; !listed[dir]; dir = parentDir(dir) {
+			listed[dir] = true
+			children[parentDir(dir)] = append(children[parentDir(dir)], dir)
+		}
+	}
+	counts := countEntriesBelow(entries, listed)
+
+	var buf bytes.Buffer
+	var write func(dir string) error
+	write = func(dir string) error {
+		subdirs := children[dir]
+		// Git lists subdirectories by the length of their names, then by name
+		sort.Slice(subdirs, func(i, j int) bool {
+			a, b := path.Base(subdirs[i]), path.Base(subdirs[j])
+			if len(a) != len(b) {
+				return len(a) < len(b)
+			}
+			return a < b
+		})
+		if dir != "" {
+			buf.WriteString(path.Base(dir))
+		}
+		buf.WriteByte(0)
+		sha, ok := cache[dir]
+		if !ok {
+			fmt.Fprintf(&buf, "-1 %d\n", len(subdirs))
+		} else {
+			raw, err := hex.DecodeString(sha)
+			if err != nil || len(raw) != r.Format.Size {
+				return fmt.Errorf("invalid SHA %q in tree cache for %q", sha, dir)
+			}
+			fmt.Fprintf(&buf, "%d %d\n", counts[dir], len(subdirs))
+			buf.Write(raw)
+		}
+		for _, subdir := range subdirs {
+			if err := write(subdir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := write(""); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parentDir returns the directory holding p, "" at the top of the tree
+func parentDir(p string) string {
+	if dir := path.Dir(p); dir != "." {
+		return dir
+	}
+	return ""
+}
+
+// countEntriesBelow counts the entries below each of dirs
+func countEntriesBelow(entries []IndexEntry, dirs map[string]bool) map[string]int {
+	counts := make(map[string]int, len(dirs))
+	if len(dirs) == 0 {
+		return counts
+	}
+	for _, entry := range entries {
+		for dir := parentDir(entry.Path); ; dir = parentDir(dir) {
+			if dirs[dir] {
+				counts[dir]++
+			}
+			if dir == "" {
+				break
+			}
+		}
+	}
+	return counts
+}
+
+// validTreeCache returns the cached trees whose directories hold the same
+// entries as when the index was read. A tree cache on an index that was not
+// read from disk cannot be checked, so none of it is kept.
+func (index *Index) validTreeCache() map[string]string {
+	if len(index.TreeCache) == 0 || index.loaded == nil {
+		return nil
+	}
+	valid := maps.Clone(index.TreeCache)
+	invalidate := func(p string) {
+		for dir := parentDir(p); ; dir = parentDir(dir) {
+			delete(valid, dir)
+			if dir == "" {
+				break
+			}
+		}
+	}
+	current := make(map[string]bool, len(index.Entries))
+	for _, entry := range index.Entries {
+		current[entry.Path] = true
+		if index.loaded[entry.Path] != entry.Mode+" "+entry.SHA {
+			invalidate(entry.Path)
+		}
+	}
+	for p := range index.loaded {
+		if !current[p] {
+			invalidate(p)
+		}
+	}
+	return valid
+}
+
+// decodeResolveUndo parses resolve-undo records: a path, three octal modes
+// each ending in a NUL (0 for a missing side) and the SHA of each side present
+func (r *Repository) decodeResolveUndo(data []byte) ([]ResolveUndoEntry, error) {
+	var entries []ResolveUndoEntry
+	field := func() (string, error) {
+		nul := bytes.IndexByte(data, 0)
+		if nul < 0 {
+			return "", errors.New("unterminated resolve-undo record")
+		}
+		value := string(data[:nul])
+		data = data[nul+1:]
+		return value, nil
+	}
+	for len(data) > 0 {
+		var entry ResolveUndoEntry
+		var err error
+		if entry.Path, err = field(); err != nil {
+			return nil, err
+		}
+		for i := range entry.Modes {
+			mode, err := field()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := strconv.ParseUint(mode, 8, 32); err != nil {
+				return nil, fmt.Errorf("invalid resolve-undo mode %q for %s", mode, entry.Path)
+			}
+			if mode != "0" {
+				entry.Modes[i] = mode
+			}
+		}
+		for i, mode := range entry.Modes {
+			if mode == "" {
+				continue
+			}
+			if len(data) < r.Format.Size {
+				return nil, errors.New("truncated resolve-undo SHA")
+			}
+			entry.SHAs[i] = hex.EncodeToString(data[:r.Format.Size])
+			data = data[r.Format.Size:]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// encodeResolveUndo serializes resolve-undo records in the layout
+// decodeResolveUndo reads
+func (r *Repository) encodeResolveUndo(entries []ResolveUndoEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	for _, entry := range entries {
+		buf.WriteString(entry.Path)
+		buf.WriteByte(0)
+		for _, mode := range entry.Modes {
+			if mode == "" {
+				mode = "0"
+			}
+			buf.WriteString(mode)
+			buf.WriteByte(0)
+		}
+		for i, mode := range entry.Modes {
+			if mode == "" {
+				continue
+			}
+			raw, err := hex.DecodeString(entry.SHAs[i])
+			if err != nil || len(raw) != r.Format.Size {
+				return nil, fmt.Errorf("invalid SHA %q in resolve-undo record for %s", entry.SHAs[i], entry.Path)
+			}
+			buf.Write(raw)
+		}
+	}
+	return buf.Bytes(), nil
+}
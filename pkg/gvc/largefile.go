@@ -0,0 +1,82 @@
+package gvc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// DefaultLargeFileThreshold is the size above which add warns about a file
+// unless add.largeFileThreshold sets another
+const DefaultLargeFileThreshold = 50 << 20
+
+// largeFileHint suggests what to do with a large file instead of committing it
+const largeFileHint = "large files bloat every clone; set lfs.threshold or lfs.track to keep them in the large-file store instead"
+
+// LargeFileThreshold returns add.largeFileThreshold, the size above which add
+// warns about or refuses a file; 0 turns the check off
+func (r *Repository) LargeFileThreshold() (int64, error) {
+	return r.getConfigInt("add.largeFileThreshold", DefaultLargeFileThreshold)
+}
+
+// checkLargeFiles looks at the files add is about to store as blobs. Those
+// above add.largeFileThreshold are reported as a warning, or refused when
+// add.largeFiles is "block". Files going to the large-file store are fine.
+func (r *Repository) checkLargeFiles(entries []IndexEntry) error {
+	threshold, err := r.LargeFileThreshold()
+	if err != nil || threshold <= 0 {
+		return err
+	}
+	action, err := r.getConfigString("add.largeFiles", "warn")
+	if err != nil {
+		return err
+	}
+	if action != "warn" && action != "block" {
+		return fmt.Errorf("bad value for add.largeFiles: %s (use warn or block)", action)
+	}
+
+	var large []string
+	for _, entry := range entries {
+		if entry.Size <= threshold || entry.Mode == SymlinkMode {
+			continue
+		}
+		if tracked, err := r.TracksLargeFile(entry.Path, entry.Size); err != nil {
+			return err
+		} else if !tracked {
+			large = append(large, fmt.Sprintf("%s (%s)", entry.Path, FormatSize(entry.Size)))
+		}
+	}
+	if len(large) == 0 {
+		return nil
+	}
+	if action == "block" {
+		return fmt.Errorf("refusing to stage files larger than add.largeFileThreshold (%s):\n\t%s\nhint: %s\nhint: raise add.largeFileThreshold or set add.largeFiles to warn to stage them anyway",
+			FormatSize(threshold), strings.Join(large, "\n\t"), largeFileHint)
+	}
+	for _, file := range large {
+		fmt.Fprintf(r.Err, "warning: %s is larger than add.largeFileThreshold (%s)\n", file, FormatSize(threshold))
+	}
+	fmt.Fprintf(r.Err, "hint: %s\n", largeFileHint)
+	return nil
+}
+
+// FindLargeBlobs lists the blobs in the history reachable from HEAD and every
+// ref that are at least minSize bytes, largest first, each with the path and
+// commit it first appeared in
+func (r *Repository) FindLargeBlobs(minSize int64) ([]BlobStats, error) {
+	var large []BlobStats
+	err := r.historyBlobs(func(_ *object.Commit, added []BlobStats) {
+		for _, blob := range added {
+			if blob.Size >= minSize {
+				large = append(large, blob)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(large, func(i, j int) bool { return large[i].Size > large[j].Size })
+	return large, nil
+}
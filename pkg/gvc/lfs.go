@@ -0,0 +1,267 @@
+package gvc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LFSDir holds the content of large files kept out of the object store, in
+// Git LFS's layout: lfs/objects/<first two>/<next two>/<sha256>
+const LFSDir = "lfs"
+
+// lfsSpec is the version line that starts a pointer file, as in Git LFS
+const lfsSpec = "version https://git-lfs.github.com/spec/v1"
+
+// maxLFSPointerSize bounds a pointer file; anything larger is content
+const maxLFSPointerSize = 1024
+
+// LFSPointer names a large file's content in the large-file store. Its
+// text is what gets committed in place of the file.
+type LFSPointer struct {
+	OID  string // SHA-256 of the content, in hex
+	Size int64
+}
+
+// Bytes returns the pointer file committed for the content
+func (p LFSPointer) Bytes() []byte {
+	return []byte(fmt.Sprintf("%s\noid sha256:%s\nsize %d\n", lfsSpec, p.OID, p.Size))
+}
+
+// ParseLFSPointer reads a pointer file, reporting whether content is one
+func ParseLFSPointer(content []byte) (LFSPointer, bool) {
+	if len(content) > maxLFSPointerSize {
+		return LFSPointer{}, false
+	}
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) < 3 || lines[0] != lfsSpec {
+		return LFSPointer{}, false
+	}
+	var pointer LFSPointer
+	hasSize := false
+	for _, line := range lines[1:] {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "oid":
+			oid, ok := strings.CutPrefix(value, "sha256:")
+			if _, err := hex.DecodeString(oid); !ok || err != nil || len(oid) != sha256.Size*2 {
+				return LFSPointer{}, false
+			}
+			pointer.OID = oid
+		case "size":
+			size, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || size < 0 {
+				return LFSPointer{}, false
+			}
+			pointer.Size, hasSize = size, true
+		}
+	}
+	return pointer, pointer.OID != "" && hasSize
+}
+
+// largeFileConfig are the settings that decide what goes to the large-file
+// store, which a clone takes from its source so its files hash the same way
+var largeFileConfig = []string{"lfs.threshold", "lfs.track"}
+
+// copyLargeFileConfig copies the large-file store settings of src to dst
+func copyLargeFileConfig(src, dst *Repository) error {
+	for _, key := range largeFileConfig {
+		value, ok, err := src.GetConfig(key)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := dst.SetConfig(key, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// lfsObjectPath returns where the store keeps the content with a given oid
+func (r *Repository) lfsObjectPath(oid string) string {
+	return r.gitPath(LFSDir, "objects", oid[:2], oid[2:4], oid)
+}
+
+// TracksLargeFile reports whether a non-empty working tree file of size
+// bytes at the slash-separated path goes to the large-file store: when it
+// is at least lfs.threshold, or matches one of the space-separated
+// .gvcignore-style patterns in lfs.track. An in-memory repository has no
+// store.
+func (r *Repository) TracksLargeFile(path string, size int64) (bool, error) {
+	if r.memory != nil || size == 0 {
+		return false, nil
+	}
+	threshold, err := r.getConfigInt("lfs.threshold", 0)
+	if err != nil {
+		return false, err
+	}
+	if threshold > 0 && size >= threshold {
+		return true, nil
+	}
+	patterns, err := r.getConfigString("lfs.track", "")
+	if err != nil {
+		return false, err
+	}
+	for _, pattern := range strings.Fields(patterns) {
+		if rule, ok := parseIgnoreRule(pattern); ok && !rule.Negate() && rule.matches(path, false) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// largeFilePointer returns the pointer a working tree file is committed as
+// when it belongs in the large-file store, moving its content there when
+// write is set. ok is false for files kept as ordinary blobs, which
+// includes pointer files themselves, e.g. one whose content was missing at
+// checkout.
+func (r *Repository) largeFilePointer(path string, info os.FileInfo, write bool) (pointer []byte, ok bool, err error) {
+	if !info.Mode().IsRegular() {
+		return nil, false, nil
+	}
+	rel, err := filepath.Rel(r.Root, path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	if tracked, err := r.TracksLargeFile(filepath.ToSlash(rel), info.Size()); err != nil || !tracked {
+		return nil, false, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	defer f.Close()
+	if info.Size() <= maxLFSPointerSize {
+		content, err := io.ReadAll(f)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+		if _, isPointer := ParseLFSPointer(content); isPointer {
+			return nil, false, nil
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, false, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+	}
+
+	hash := sha256.New()
+	var w io.Writer = hash
+	var tmp *os.File
+	if write {
+		if err := r.checkWritable(); err != nil {
+			return nil, false, err
+		}
+		tmpDir := r.gitPath(LFSDir, "tmp")
+		if err := r.mkdirShared(tmpDir); err != nil {
+			return nil, false, fmt.Errorf("failed to create %s: %w", tmpDir, err)
+		}
+		if tmp, err = createTemp(tmpDir, "tmp_"); err != nil {
+			return nil, false, fmt.Errorf("failed to store large file %s: %w", rel, err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		w = io.MultiWriter(hash, tmp)
+	}
+	size, err := io.Copy(w, f)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	oid := hex.EncodeToString(hash.Sum(nil))
+
+	if write {
+		if err := tmp.Close(); err != nil {
+			return nil, false, fmt.Errorf("failed to store large file %s: %w", rel, err)
+		}
+		target := r.lfsObjectPath(oid)
+		if _, err := os.Stat(target); os.IsNotExist(err) {
+			if err := r.mkdirShared(filepath.Dir(target)); err != nil {
+				return nil, false, fmt.Errorf("failed to store large file %s: %w", rel, err)
+			}
+			if err := os.Rename(tmp.Name(), target); err != nil {
+				return nil, false, fmt.Errorf("failed to store large file %s: %w", rel, err)
+			}
+			if err := r.adjustSharedPerm(target); err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	return LFSPointer{OID: oid, Size: size}.Bytes(), true, nil
+}
+
+// openLargeFile opens the content a pointer names. A missing one is an
+// error matching fs.ErrNotExist.
+func (r *Repository) openLargeFile(pointer LFSPointer) (*os.File, error) {
+	f, err := os.Open(r.lfsObjectPath(pointer.OID))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("large file %s is not in the store: %w", pointer.OID, fs.ErrNotExist)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read large file %s: %w", pointer.OID, err)
+	}
+	return f, nil
+}
+
+// writeLargeFile writes the content a pointer names to path
+func (r *Repository) writeLargeFile(path string, pointer LFSPointer, perm os.FileMode) error {
+	src, err := r.openLargeFile(pointer)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	if err := dst.Close(); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	return nil
+}
+
+// transferLargeFiles copies the large-file store content dst lacks from src,
+// by hardlink when allowed and possible
+func transferLargeFiles(src, dst *Repository, hardlink bool) error {
+	if src.memory != nil || dst.memory != nil {
+		return nil
+	}
+	dir := src.gitPath(LFSDir, "objects")
+	copied := 0
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		target := dst.lfsObjectPath(d.Name())
+		if len(d.Name()) != sha256.Size*2 {
+			return nil
+		}
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		}
+		copied++
+		return dst.linkOrCopyFile(path, target, hardlink)
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to copy large files: %w", err)
+	}
+	if copied > 0 {
+		how := "Copied"
+		if hardlink {
+			how = "Linked"
+		}
+		fmt.Fprintf(dst.Out, "%s %d large files\n", how, copied)
+	}
+	return nil
+}
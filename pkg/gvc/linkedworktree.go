@@ -0,0 +1,434 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Linked worktrees keep their metadata in .gvc/worktrees/<name>: HEAD and the
+// index, commondir pointing back at the shared .gvc directory, gvcdir naming
+// the worktree's .gvc file, and locked when the worktree is locked
+const (
+	WorktreesDir   = "worktrees"
+	CommonDirFile  = "commondir"
+	gvcDirLinkFile = "gvcdir"
+	lockedFile     = "locked"
+)
+
+// Worktree is a working directory of the repository
+type Worktree struct {
+	Name       string // "" for the main worktree
+	Path       string
+	Head       string // commit checked out, "" before the first commit
+	Branch     string // full name of the checked out branch, "" when detached
+	Locked     bool
+	LockReason string
+	Prunable   bool // its directory is gone
+	gitDir     string
+}
+
+// WorktreeAddOptions controls AddWorktree
+type WorktreeAddOptions struct {
+	NewBranch string // create this branch at the commit and check it out
+	Detach    bool   // check out the commit without a branch even if it names one
+}
+
+// Worktrees lists the main worktree followed by the linked ones by name
+func (r *Repository) Worktrees() ([]Worktree, error) {
+	main := Worktree{Path: filepath.Dir(r.CommonDir), gitDir: r.CommonDir}
+	if r.GitDir == r.CommonDir {
+		main.Path = r.Root
+	}
+	if err := r.readWorktreeHead(&main); err != nil {
+		return nil, err
+	}
+	worktrees := []Worktree{main}
+
+	dirs, err := os.ReadDir(filepath.Join(r.CommonDir, WorktreesDir))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to list worktrees: %w", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		wt := Worktree{Name: dir.Name(), gitDir: filepath.Join(r.CommonDir, WorktreesDir, dir.Name())}
+		if data, err := os.ReadFile(filepath.Join(wt.gitDir, gvcDirLinkFile)); err == nil {
+			wt.Path = filepath.Dir(strings.TrimSpace(string(data)))
+		}
+		if wt.Path == "" || !isDir(wt.Path) {
+			wt.Prunable = true
+		}
+		if reason, err := os.ReadFile(filepath.Join(wt.gitDir, lockedFile)); err == nil {
+			wt.Locked, wt.LockReason = true, strings.TrimSpace(string(reason))
+		}
+		if err := r.readWorktreeHead(&wt); err != nil {
+			return nil, err
+		}
+		worktrees = append(worktrees, wt)
+	}
+	sort.SliceStable(worktrees[1:], func(i, j int) bool { return worktrees[1+i].Name < worktrees[1+j].Name })
+	return worktrees, nil
+}
+
+// readWorktreeHead fills in what a worktree's HEAD points at
+func (r *Repository) readWorktreeHead(wt *Worktree) error {
+	data, err := os.ReadFile(filepath.Join(wt.gitDir, HeadFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read HEAD of worktree %s: %w", wt.Path, err)
+	}
+	head := strings.TrimSpace(string(data))
+	if ref, ok := strings.CutPrefix(head, "ref: "); ok {
+		wt.Branch = ref
+		wt.Head, err = r.ReadRef(ref)
+		return err
+	}
+	wt.Head = head
+	return nil
+}
+
+// currentWorktree reports whether wt is the worktree r works in
+func (r *Repository) currentWorktree(wt Worktree) bool {
+	return wt.gitDir == r.GitDir
+}
+
+// branchCheckedOut returns the path of a worktree, other than this one when
+// exceptCurrent is set, that has a branch checked out, or ""
+func (r *Repository) branchCheckedOut(branchRef string, exceptCurrent bool) (string, error) {
+	worktrees, err := r.Worktrees()
+	if err != nil {
+		return "", err
+	}
+	for _, wt := range worktrees {
+		if wt.Branch == branchRef && !(exceptCurrent && r.currentWorktree(wt)) && !wt.Prunable {
+			return wt.Path, nil
+		}
+	}
+	return "", nil
+}
+
+// otherWorktreeHeads returns the commits checked out in the other worktrees
+func (r *Repository) otherWorktreeHeads() ([]string, error) {
+	worktrees, err := r.Worktrees()
+	if err != nil {
+		return nil, err
+	}
+	var heads []string
+	for _, wt := range worktrees {
+		if !r.currentWorktree(wt) && wt.Head != "" {
+			heads = append(heads, wt.Head)
+		}
+	}
+	return heads, nil
+}
+
+// findWorktree looks up a linked worktree by name or path
+func (r *Repository) findWorktree(target string) (Worktree, error) {
+	worktrees, err := r.Worktrees()
+	if err != nil {
+		return Worktree{}, err
+	}
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return Worktree{}, fmt.Errorf("failed to resolve %s: %w", target, err)
+	}
+	for _, wt := range worktrees {
+		if wt.Path == abs || (wt.Name != "" && wt.Name == target) {
+			if wt.Name == "" {
+				return Worktree{}, fmt.Errorf("'%s' is the main worktree", target)
+			}
+			return wt, nil
+		}
+	}
+	return Worktree{}, fmt.Errorf("'%s' is not a worktree", target)
+}
+
+// AddWorktree creates a linked worktree at path sharing this repository's
+// objects and refs, with its own HEAD and index. It checks out commit, a
+// branch or any revision, detached unless it names a branch. A branch can
+// only be checked out in one worktree at a time. With no commit a branch
+// named after the new directory is checked out, created from HEAD if needed.
+func (r *Repository) AddWorktree(path, commit string, opts WorktreeAddOptions) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		if entries, _ := os.ReadDir(path); !info.IsDir() || len(entries) > 0 {
+			return fmt.Errorf("'%s' already exists", path)
+		}
+	}
+
+	// Work out what to check out before touching anything
+	branchRef, createBranch := "", false
+	switch {
+	case opts.NewBranch != "":
+		if err := validateBranchName(opts.NewBranch); err != nil {
+			return err
+		}
+		branchRef, createBranch = HeadsDir+"/"+opts.NewBranch, true
+		if existing, err := r.ReadRef(branchRef); err != nil {
+			return err
+		} else if existing != "" {
+			return fmt.Errorf("a branch named '%s' already exists", opts.NewBranch)
+		}
+		if commit == "" {
+			commit = "HEAD"
+		}
+	case commit == "" && !opts.Detach:
+		name := filepath.Base(path)
+		if err := validateBranchName(name); err != nil {
+			return err
+		}
+		branchRef = HeadsDir + "/" + name
+		existing, err := r.ReadRef(branchRef)
+		if err != nil {
+			return err
+		}
+		if existing == "" {
+			createBranch, commit = true, "HEAD"
+		}
+	case commit == "":
+		commit = "HEAD"
+	case !opts.Detach:
+		if sha, err := r.ReadRef(HeadsDir + "/" + commit); err != nil {
+			return err
+		} else if sha != "" {
+			branchRef, commit = HeadsDir+"/"+commit, ""
+		}
+	}
+	var sha string
+	if commit != "" {
+		if sha, err = r.ResolveCommit(commit); err != nil {
+			return err
+		}
+	} else if sha, err = r.ReadRef(branchRef); err != nil {
+		return err
+	}
+	if branchRef != "" && !createBranch {
+		if other, err := r.branchCheckedOut(branchRef, false); err != nil {
+			return err
+		} else if other != "" {
+			return fmt.Errorf("'%s' is already checked out at '%s'", strings.TrimPrefix(branchRef, HeadsDir+"/"), other)
+		}
+	}
+
+	// The metadata directory is named after the worktree, made unique
+	name := strings.Map(func(c rune) rune {
+		if c == '/' || c == '\\' || c == ' ' {
+			return '-'
+		}
+		return c
+	}, filepath.Base(path))
+	adminDir := filepath.Join(r.CommonDir, WorktreesDir, name)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(adminDir); os.IsNotExist(err) {
+			break
+		}
+		adminDir = filepath.Join(r.CommonDir, WorktreesDir, name+strconv.Itoa(i))
+	}
+
+	done := false
+	defer func() {
+		if !done {
+			os.RemoveAll(adminDir)
+			os.RemoveAll(path)
+		}
+	}()
+	if err := os.MkdirAll(adminDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", adminDir, err)
+	}
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	commonDir, err := filepath.Rel(adminDir, r.CommonDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", r.CommonDir, err)
+	}
+	files := map[string]string{
+		filepath.Join(adminDir, CommonDirFile):  commonDir,
+		filepath.Join(adminDir, gvcDirLinkFile): filepath.Join(path, GvcDir),
+		filepath.Join(path, GvcDir):             gvcDirFilePrefix + adminDir,
+	}
+	for file, content := range files {
+		if err := os.WriteFile(file, []byte(content+"\n"), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file, err)
+		}
+	}
+
+	if createBranch {
+		if err := r.writeRef(branchRef, sha, "branch: Created from "+commit); err != nil {
+			return err
+		}
+	}
+	wt, err := OpenAt(path, adminDir)
+	if err != nil {
+		return err
+	}
+	wt.Out = r.Out
+	head := sha
+	if branchRef != "" {
+		head = "ref: " + branchRef
+	}
+	if err := writeFileLocked(wt.gitPath(HeadFile), []byte(head+"\n")); err != nil {
+		return fmt.Errorf("failed to write HEAD: %w", err)
+	}
+	if err := wt.appendReflog("HEAD", "", sha, "worktree: checkout "+sha); err != nil {
+		return err
+	}
+	target, err := wt.headEntries()
+	if err != nil {
+		return err
+	}
+	if branchRef != "" {
+		fmt.Fprintf(r.Out, "Preparing worktree (checking out '%s')\n", strings.TrimPrefix(branchRef, HeadsDir+"/"))
+	} else {
+		fmt.Fprintf(r.Out, "Preparing worktree (detached HEAD %s)\n", sha[:7])
+	}
+	if err := wt.checkoutEntries(map[string]IndexEntry{}, target); err != nil {
+		return err
+	}
+	done = true
+
+	headCommit, err := r.ReadCommit(sha)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.Out, "HEAD is now at %s %s\n", sha[:7], strings.SplitN(headCommit.Message, "\n", 2)[0])
+	return nil
+}
+
+// RemoveWorktree deletes a linked worktree and its metadata. A worktree with
+// modified or untracked files needs force, and a locked one needs it twice.
+func (r *Repository) RemoveWorktree(target string, force int) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	wt, err := r.findWorktree(target)
+	if err != nil {
+		return err
+	}
+	if r.currentWorktree(wt) {
+		return fmt.Errorf("cannot remove the worktree at '%s' from inside it", wt.Path)
+	}
+	if wt.Locked && force < 2 {
+		msg := fmt.Sprintf("cannot remove a locked worktree at '%s'", wt.Path)
+		if wt.LockReason != "" {
+			msg += ", lock reason: " + wt.LockReason
+		}
+		return errors.New(msg + "; use 'remove -f -f' to override or unlock first")
+	}
+	if force == 0 && !wt.Prunable {
+		clean, err := worktreeClean(wt)
+		if err != nil {
+			return err
+		}
+		if !clean {
+			return fmt.Errorf("'%s' contains modified or untracked files; use --force to delete it", wt.Path)
+		}
+	}
+
+	if !wt.Prunable {
+		if err := os.RemoveAll(wt.Path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", wt.Path, err)
+		}
+	}
+	if err := os.RemoveAll(wt.gitDir); err != nil {
+		return fmt.Errorf("failed to remove worktree metadata: %w", err)
+	}
+	return nil
+}
+
+// worktreeClean reports whether a worktree has no changes against its HEAD
+// and no untracked files
+func worktreeClean(wt Worktree) (bool, error) {
+	repo, err := OpenAt(wt.Path, wt.gitDir)
+	if err != nil {
+		return false, err
+	}
+	head, err := repo.headEntries()
+	if err != nil {
+		return false, err
+	}
+	if err := repo.checkCleanState(head); err != nil {
+		return false, nil
+	}
+	untracked, err := repo.UntrackedFiles(false)
+	if err != nil {
+		return false, err
+	}
+	return len(untracked) == 0, nil
+}
+
+// LockWorktree keeps a linked worktree from being removed or pruned, for
+// example while it lives on a drive that is not always mounted
+func (r *Repository) LockWorktree(target, reason string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	wt, err := r.findWorktree(target)
+	if err != nil {
+		return err
+	}
+	if wt.Locked {
+		if wt.LockReason != "" {
+			return fmt.Errorf("'%s' is already locked, reason: %s", target, wt.LockReason)
+		}
+		return fmt.Errorf("'%s' is already locked", target)
+	}
+	if err := writeFileLocked(filepath.Join(wt.gitDir, lockedFile), []byte(reason+"\n")); err != nil {
+		return fmt.Errorf("failed to lock worktree: %w", err)
+	}
+	return nil
+}
+
+// UnlockWorktree lifts LockWorktree
+func (r *Repository) UnlockWorktree(target string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	wt, err := r.findWorktree(target)
+	if err != nil {
+		return err
+	}
+	if !wt.Locked {
+		return fmt.Errorf("'%s' is not locked", target)
+	}
+	if err := os.Remove(filepath.Join(wt.gitDir, lockedFile)); err != nil {
+		return fmt.Errorf("failed to unlock worktree: %w", err)
+	}
+	return nil
+}
+
+// PruneWorktrees removes the metadata of linked worktrees whose directories
+// are gone, unless they are locked
+func (r *Repository) PruneWorktrees() error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	worktrees, err := r.Worktrees()
+	if err != nil {
+		return err
+	}
+	for _, wt := range worktrees {
+		if !wt.Prunable || wt.Locked {
+			continue
+		}
+		fmt.Fprintf(r.Out, "Removing %s/%s: worktree directory no longer exists\n", WorktreesDir, wt.Name)
+		if err := os.RemoveAll(wt.gitDir); err != nil {
+			return fmt.Errorf("failed to remove worktree metadata: %w", err)
+		}
+	}
+	return nil
+}
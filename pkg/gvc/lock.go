@@ -0,0 +1,242 @@
+package gvc
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LockSuffix marks the file that claims another file for writing, e.g. index.lock
+const LockSuffix = ".lock"
+
+// lockTimeout is how long to wait for another gvc process to release a lock
+const lockTimeout = time.Second
+
+// staleLockAge is the age after which a lock whose owner cannot be checked is broken
+const staleLockAge = 10 * time.Minute
+
+// lockFile holds path.lock exclusively. New content is written into the lock
+// and renamed over path on commit, so readers see either the old or the new
+// file, never a partial one. Until then the lock records its owner.
+type lockFile struct {
+	path      string
+	file      *os.File
+	committed bool
+	memory    *memoryFiles // set for a file of an in-memory repository
+	ref       RefLock      // set for a ref kept by a RefStore other than the files
+	shared    sharedPerm   // permissions the file gets on commit, from core.sharedRepository
+}
+
+// lock claims path for writing, waiting briefly for other processes and
+// breaking locks left behind by processes that died
+func lock(path string) (*lockFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	lockPath := path + LockSuffix
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0666)
+		if err == nil {
+			hostname, _ := os.Hostname()
+			fmt.Fprintf(f, "%d %s\n", os.Getpid(), hostname)
+			return &lockFile{path: path, file: f}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create %s: %w", lockPath, err)
+		}
+		if lockIsStale(lockPath) {
+			if err := breakStaleLock(lockPath); err != nil {
+				return nil, fmt.Errorf("failed to remove stale lock %s: %w", lockPath, err)
+			}
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("unable to lock %s: %s exists; another gvc process seems to be running. "+
+				"If it crashed, remove the file and try again", path, lockPath)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// lockIsStale reports whether the owner of a lock is gone. A lock taken on
+// this host is stale once its process has exited, and never while it runs,
+// however long that takes. One whose owner cannot be checked is stale once
+// it has been held too long.
+func lockIsStale(lockPath string) bool {
+	info, err := os.Stat(lockPath)
+	if err != nil {
+		return false
+	}
+	if running, checked := lockOwnerRunning(lockPath); checked {
+		return !running
+	}
+	return time.Since(info.ModTime()) > staleLockAge
+}
+
+// breakStaleLock removes a lock lockIsStale found stale. It is first renamed
+// to a name of this process's own, so of several processes that found it
+// stale only one takes it away, and checked again there: when another
+// process broke it first and took the lock anew, the fresh lock is what was
+// renamed, and it is put back.
+func breakStaleLock(lockPath string) error {
+	claimed := fmt.Sprintf("%s.stale-%d-%d%s", strings.TrimSuffix(lockPath, LockSuffix), os.Getpid(), time.Now().UnixNano(), LockSuffix)
+	if err := os.Rename(lockPath, claimed); err != nil {
+		if os.IsNotExist(err) {
+			return nil // broken by another process
+		}
+		return err
+	}
+	if !lockIsStale(claimed) {
+		// A link fails if yet another process took the lock meanwhile
+		if err := os.Link(claimed, lockPath); err != nil && !os.IsExist(err) {
+			return err
+		}
+	}
+	if err := os.Remove(claimed); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// lockOwnerRunning reports whether the process a lock or writer file names
+// is still running, and whether that can be told at all: only a process on
+// this host can be checked, and a lock being committed names none
+func lockOwnerRunning(path string) (running, checked bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return false, false // the owner is writing its new content
+	}
+	pid, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return false, false
+	}
+	if hostname, _ := os.Hostname(); fields[1] != hostname {
+		return false, false
+	}
+	return processExists(pid), true
+}
+
+// commit replaces the locked file with data and releases the lock
+func (l *lockFile) commit(data []byte) error {
+	if l.ref != nil {
+		l.committed = true
+		return l.ref.Commit(strings.TrimSpace(string(data)))
+	}
+	if l.memory != nil {
+		l.memory.write(l.path, data)
+		l.unlock()
+		return nil
+	}
+	if err := l.file.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write %s: %w", l.path, err)
+	}
+	if _, err := l.file.WriteAt(data, 0); err != nil {
+		return fmt.Errorf("failed to write %s: %w", l.path, err)
+	}
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", l.path, err)
+	}
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", l.path, err)
+	}
+	if err := os.Rename(l.path+LockSuffix, l.path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", l.path, err)
+	}
+	l.committed = true
+	return l.shared.apply(l.path)
+}
+
+// remove deletes the locked file and releases the lock
+func (l *lockFile) remove() error {
+	if l.ref != nil {
+		l.committed = true
+		return l.ref.Delete()
+	}
+	if l.memory != nil {
+		l.memory.remove(l.path)
+		l.unlock()
+		return nil
+	}
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", l.path, err)
+	}
+	l.unlock()
+	return nil
+}
+
+// unlock releases the lock without changing the file; it does nothing after commit
+func (l *lockFile) unlock() {
+	if l.committed {
+		return
+	}
+	if l.ref != nil {
+		l.ref.Unlock()
+		l.committed = true
+		return
+	}
+	if l.memory != nil {
+		l.memory.release(l.path)
+		l.committed = true
+		return
+	}
+	l.file.Close()
+	os.Remove(l.path + LockSuffix)
+	l.committed = true
+}
+
+// writeFileLocked atomically replaces path with data under its lock
+func writeFileLocked(path string, data []byte) error {
+	l, err := lock(path)
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+	return l.commit(data)
+}
+
+// writeFileAtomic writes an immutable file such as an object or pack through a
+// temporary file, so a crash never leaves a truncated copy under its final name
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	tmp, err := createTemp(filepath.Dir(path), "tmp_")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// createTemp creates a new file in dir named pattern and a random suffix, as
+// os.CreateTemp does, but readable and writable as the umask allows rather
+// than by the owner alone, since it is moved into the repository once done
+func createTemp(dir, pattern string) (*os.File, error) {
+	for try := 0; ; try++ {
+		name := filepath.Join(dir, pattern+strconv.FormatUint(uint64(rand.Uint32()), 10))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) && try < 10000 {
+			continue
+		}
+		return f, err
+	}
+}
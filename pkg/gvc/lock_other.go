@@ -0,0 +1,12 @@
+//go:build !unix
+
+package gvc
+
+// checksLockOwners is set where a lock left by a process that exited can be
+// told apart from one still held
+const checksLockOwners = false
+
+// processExists cannot check other processes here, so locks only go stale with age
+func processExists(pid int) bool {
+	return true
+}
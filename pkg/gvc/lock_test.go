@@ -0,0 +1,99 @@
+package gvc
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeLockOwner writes a lock or writer file naming pid on this host, last
+// modified age ago
+func writeLockOwner(t *testing.T, path string, pid int, age time.Duration) {
+	t.Helper()
+	hostname, _ := os.Hostname()
+	if err := os.WriteFile(path, []byte(fmt.Sprintf("%d %s\n", pid, hostname)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-age)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// exitedPID returns the ID of a process that has exited
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestLockKeepsOldLockOfRunningProcess(t *testing.T) {
+	if !checksLockOwners {
+		t.Skip("lock owners cannot be checked on this platform")
+	}
+	path := filepath.Join(t.TempDir(), "index")
+	writeLockOwner(t, path+LockSuffix, os.Getpid(), staleLockAge+time.Minute)
+
+	if lockIsStale(path + LockSuffix) {
+		t.Fatal("a lock held by a running process counts as stale")
+	}
+	if l, err := lock(path); err == nil {
+		l.unlock()
+		t.Fatal("took a lock held by a running process")
+	}
+	if _, err := os.Stat(path + LockSuffix); err != nil {
+		t.Fatalf("the held lock is gone: %v", err)
+	}
+}
+
+func TestLockBreaksLockOfExitedProcess(t *testing.T) {
+	if !checksLockOwners {
+		t.Skip("lock owners cannot be checked on this platform")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "index")
+	writeLockOwner(t, path+LockSuffix, exitedPID(t), 0)
+
+	l, err := lock(path)
+	if err != nil {
+		t.Fatalf("the lock of an exited process was not broken: %v", err)
+	}
+	defer l.unlock()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "index"+LockSuffix {
+		t.Fatalf("expected only the new lock, found %v", entries)
+	}
+}
+
+func TestBreakStaleLockPutsBackFreshLock(t *testing.T) {
+	if !checksLockOwners {
+		t.Skip("lock owners cannot be checked on this platform")
+	}
+	// Another waiter broke the stale lock and took it before this one got to it
+	dir := t.TempDir()
+	lockPath := filepath.Join(dir, "index"+LockSuffix)
+	writeLockOwner(t, lockPath, os.Getpid(), 0)
+
+	if err := breakStaleLock(lockPath); err != nil {
+		t.Fatal(err)
+	}
+	if running, checked := lockOwnerRunning(lockPath); !checked || !running {
+		t.Fatal("the fresh lock was not put back")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only the lock, found %v", entries)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package gvc
+
+import "syscall"
+
+// checksLockOwners is set where a lock left by a process that exited can be
+// told apart from one still held
+const checksLockOwners = true
+
+// processExists reports whether a process with the given ID is running
+func processExists(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
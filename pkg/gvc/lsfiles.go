@@ -0,0 +1,117 @@
+package gvc
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+)
+
+// StagedFiles returns every path the next commit would record, sorted by path
+func (r *Repository) StagedFiles() ([]IndexEntry, error) {
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return nil, err
+	}
+	return sortedEntries(staged), nil
+}
+
+// ModifiedFiles returns the tracked paths whose working tree copy differs
+// from the staged one, including paths deleted from the working tree
+func (r *Repository) ModifiedFiles() ([]string, error) {
+	staged, err := r.StagedFiles()
+	if err != nil {
+		return nil, err
+	}
+	indexTime := r.indexModTime()
+	var modified []string
+	for _, entry := range staged {
+		working, ok, err := r.readWorkingEntryCached(entry.Path, entry, indexTime)
+		if err != nil {
+			return nil, err
+		}
+		if !sameEntry(working, entry, ok, true) {
+			modified = append(modified, entry.Path)
+		}
+	}
+	return modified, nil
+}
+
+// UntrackedFiles returns the working tree files that are neither in HEAD
+// nor staged, sorted by path. Ignored files are left out, or with ignored
+// set are the only ones listed.
+func (r *Repository) UntrackedFiles(ignored bool) ([]string, error) {
+	var untracked []string
+	err := r.walkUntracked(ignored, func(rel string) bool {
+		untracked = append(untracked, rel)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(untracked)
+	return untracked, nil
+}
+
+// walkUntracked calls visit with each untracked file, or each ignored one
+// when ignored is set, in walk order, stopping once visit returns false
+func (r *Repository) walkUntracked(ignored bool, visit func(rel string) bool) error {
+	staged, err := r.StagedFiles()
+	if err != nil {
+		return err
+	}
+	tracked := make(map[string]bool, len(staged))
+	for _, entry := range staged {
+		tracked[entry.Path] = true
+	}
+	ig, err := r.LoadIgnore()
+	if err != nil {
+		return err
+	}
+
+	err = filepath.WalkDir(r.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Nested repositories such as submodules are not part of this one, nor
+		// is the .gvc file of a linked worktree
+		if path != r.Root && (isMetadataDir(d.Name()) || path == r.GitDir || d.IsDir() && isRepoRoot(path)) {
+			if !d.IsDir() {
+				return nil
+			}
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && !d.Type().IsRegular() && d.Type()&fs.ModeSymlink == 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(r.Root, path)
+		if err != nil {
+			return err
+		}
+		if rel, err = r.cleanIndexPath(rel); err != nil {
+			return err
+		}
+		if rel == "." || tracked[rel] {
+			return nil
+		}
+		isIgnored, err := ig.Ignored(rel, d.IsDir())
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Everything below an ignored directory is ignored too
+			if isIgnored && !ignored {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isIgnored == ignored && !visit(rel) {
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan working tree: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaintenanceLockFile is locked, as maintenance.lock in the common
+// directory, while gc or prune packs and deletes objects
+const MaintenanceLockFile = "maintenance"
+
+// WritersDir holds a file for each command writing to the repository, which
+// maintenance waits to see gone before it starts
+const WritersDir = "writers"
+
+// defaultMaintenanceTimeout is how many milliseconds a command waits for
+// maintenance to finish, and maintenance for running commands, by default
+const defaultMaintenanceTimeout = 10000
+
+// ErrMaintenanceRunning is returned when gc or prune holds the repository
+// for longer than maintenance.lockTimeout allows to wait
+var ErrMaintenanceRunning = errors.New("repository maintenance is running")
+
+// BeginWrite registers the caller as a command about to write to the
+// repository, so gc and prune wait for it to finish rather than pack or
+// delete objects under it, until the returned function is called. While
+// maintenance runs, it waits up to maintenance.lockTimeout milliseconds
+// (default 10000) and then fails with ErrMaintenanceRunning. A read-only or
+// in-memory repository is never maintained, so nothing is registered.
+func (r *Repository) BeginWrite() (func(), error) {
+	if r.ReadOnly || r.memory != nil {
+		return func() {}, nil
+	}
+	timeout, err := r.getConfigInt("maintenance.lockTimeout", defaultMaintenanceTimeout)
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(r.CommonDir, MaintenanceLockFile)
+	deadline := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	var l *lockFile
+	for {
+		if l, err = lock(path); err == nil {
+			break
+		}
+		owner, held := lockOwner(path)
+		if !held {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w (%s holds %s); try again once it finishes, or raise maintenance.lockTimeout",
+				ErrMaintenanceRunning, owner, path+LockSuffix)
+		}
+		if err := r.canceled(); err != nil {
+			return nil, err
+		}
+	}
+	// The lock only keeps maintenance from starting while the writer signs in
+	defer l.unlock()
+
+	dir := filepath.Join(r.CommonDir, WritersDir)
+	if err := r.mkdirShared(dir); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	marker := filepath.Join(dir, fmt.Sprintf("%d-%d", os.Getpid(), time.Now().UnixNano()))
+	hostname, _ := os.Hostname()
+	if err := os.WriteFile(marker, []byte(fmt.Sprintf("%d %s\n", os.Getpid(), hostname)), 0644); err != nil {
+		return nil, fmt.Errorf("failed to register with %s: %w", dir, err)
+	}
+	return func() { os.Remove(marker) }, nil
+}
+
+// lockMaintenance takes the maintenance lock, so no command starts writing,
+// and waits up to maintenance.lockTimeout for the commands already writing
+// to finish. Writers that died are forgotten, and this process's own never
+// waited for. Call the returned function to release the lock; taking it
+// again while it is held, as gc does when it prunes, nests.
+func (r *Repository) lockMaintenance() (func(), error) {
+	if r.memory != nil {
+		return func() {}, nil
+	}
+	if r.maintenanceDepth > 0 {
+		r.maintenanceDepth++
+		return r.unlockMaintenance, nil
+	}
+	path := filepath.Join(r.CommonDir, MaintenanceLockFile)
+	l, err := lock(path)
+	if err != nil {
+		if owner, held := lockOwner(path); held {
+			return nil, fmt.Errorf("%w: %s already holds %s", ErrMaintenanceRunning, owner, path+LockSuffix)
+		}
+		return nil, err
+	}
+	timeout, err := r.getConfigInt("maintenance.lockTimeout", defaultMaintenanceTimeout)
+	if err != nil {
+		l.unlock()
+		return nil, err
+	}
+	deadline := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	for {
+		writers, err := r.activeWriters()
+		if err != nil {
+			l.unlock()
+			return nil, err
+		}
+		if len(writers) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			l.unlock()
+			return nil, fmt.Errorf("cannot start maintenance: other gvc commands are writing to the repository (%s); "+
+				"try again once they finish", strings.Join(writers, ", "))
+		}
+		if err := r.canceled(); err != nil {
+			l.unlock()
+			return nil, err
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	r.maintenance, r.maintenanceDepth = l, 1
+	return r.unlockMaintenance, nil
+}
+
+// unlockMaintenance releases one hold of the maintenance lock
+func (r *Repository) unlockMaintenance() {
+	if r.maintenanceDepth--; r.maintenanceDepth == 0 {
+		r.maintenance.unlock()
+		r.maintenance = nil
+	}
+}
+
+// activeWriters describes the commands of other processes registered by
+// BeginWrite, removing the files of those that died. A writer on this host
+// that is still running counts however long it has been writing; only one
+// whose process cannot be checked is given up on with age.
+func (r *Repository) activeWriters() ([]string, error) {
+	dir := filepath.Join(r.CommonDir, WritersDir)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	self := strconv.Itoa(os.Getpid()) + "-"
+	var writers []string
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if strings.HasPrefix(entry.Name(), self) {
+			continue
+		}
+		if lockIsStale(path) {
+			os.Remove(path)
+			continue
+		}
+		if owner, ok := fileOwner(path); ok {
+			writers = append(writers, owner)
+		}
+	}
+	return writers, nil
+}
+
+// lockOwner describes the process holding path's lock, as "pid 123 on
+// host", and reports whether the lock exists
+func lockOwner(path string) (string, bool) {
+	return fileOwner(path + LockSuffix)
+}
+
+// fileOwner describes the process a lock or writer file names, and reports
+// whether the file exists
+func fileOwner(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return "another gvc process", true
+	}
+	return fmt.Sprintf("pid %s on %s", fields[0], fields[1]), true
+}
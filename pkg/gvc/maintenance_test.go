@@ -0,0 +1,63 @@
+package gvc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestActiveWritersKeepsLongRunningWriter(t *testing.T) {
+	if !checksLockOwners {
+		t.Skip("writer processes cannot be checked on this platform")
+	}
+	repo, err := Init(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(repo.CommonDir, WritersDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// Named as another process's registration, so it is not skipped as this one's
+	marker := filepath.Join(dir, "1-0")
+	writeLockOwner(t, marker, os.Getpid(), 11*time.Minute)
+
+	writers, err := repo.activeWriters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(writers) != 1 {
+		t.Fatalf("expected the running writer, got %v", writers)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Fatalf("the running writer's registration was removed: %v", err)
+	}
+}
+
+func TestActiveWritersForgetsExitedWriter(t *testing.T) {
+	if !checksLockOwners {
+		t.Skip("writer processes cannot be checked on this platform")
+	}
+	repo, err := Init(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(repo.CommonDir, WritersDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	marker := filepath.Join(dir, "1-0")
+	writeLockOwner(t, marker, exitedPID(t), 0)
+
+	writers, err := repo.activeWriters()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(writers) != 0 {
+		t.Fatalf("expected no writers, got %v", writers)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Fatal("the exited writer's registration was kept")
+	}
+}
@@ -0,0 +1,236 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// NewMemoryRepository creates a repository whose objects, HEAD, refs,
+// reflogs, index and config live in memory, in a MemoryStore, a
+// MemoryRefStore and the repository itself, so tests of tools built on the
+// library need no .gvc directory. The working tree at root is still on disk
+// for the commands that read or check out files; a repository used through
+// objects, the index and refs alone never touches it. State kept in other
+// files, such as merges, rebases, stashes, bisects, worktrees and the
+// commit-graph, is not available.
+func NewMemoryRepository(root string, format *object.Format) (*Repository, error) {
+	r, err := newRepository(root, filepath.Join(root, GvcDir))
+	if err != nil {
+		return nil, err
+	}
+	if format != nil {
+		r.Format = format
+	}
+	r.Objects = NewMemoryStore()
+	r.Refs = NewMemoryRefStore()
+	r.memory = &memoryFiles{files: make(map[string]memoryFile), locked: make(map[string]bool)}
+	if err := r.initializeMetadata(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// memoryFiles holds the metadata files of an in-memory repository by path
+type memoryFiles struct {
+	mu     sync.Mutex
+	files  map[string]memoryFile
+	locked map[string]bool
+}
+
+// memoryFile is one metadata file held in memory
+type memoryFile struct {
+	data    []byte
+	modTime time.Time
+}
+
+// read returns a copy of a file, failing like os.ReadFile when it is missing
+func (m *memoryFiles) read(path string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[path]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+	}
+	return append([]byte(nil), file.data...), nil
+}
+
+// write replaces a file
+func (m *memoryFiles) write(path string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.files[path] = memoryFile{data: append([]byte(nil), data...), modTime: time.Now()}
+}
+
+// remove deletes a file; a missing one is not an error
+func (m *memoryFiles) remove(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.files, path)
+}
+
+// stat returns when a file was last written, and whether it exists
+func (m *memoryFiles) stat(path string) (time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	file, ok := m.files[path]
+	return file.modTime, ok
+}
+
+// list returns the files below dir, sorted
+func (m *memoryFiles) list(dir string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var paths []string
+	for path := range m.files {
+		if strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// lock claims a file for writing. There is no other process to wait for, so
+// a file that is already claimed fails at once.
+func (m *memoryFiles) lock(path string) (*lockFile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.locked[path] {
+		return nil, fmt.Errorf("unable to lock %s: it is being updated", path)
+	}
+	m.locked[path] = true
+	return &lockFile{path: path, memory: m}, nil
+}
+
+// release gives up the claim on a file
+func (m *memoryFiles) release(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.locked, path)
+}
+
+// readGitFile reads a file below the .gvc directory
+func (r *Repository) readGitFile(path string) ([]byte, error) {
+	path = r.namespacePath(path)
+	if name, ok := r.storedRefName(path); ok {
+		value, err := r.Refs.Read(name)
+		if errors.Is(err, fs.ErrNotExist) {
+			// Callers check for a missing file with os.IsNotExist
+			return nil, &fs.PathError{Op: "open", Path: path, Err: fs.ErrNotExist}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return []byte(value + "\n"), nil
+	}
+	if r.memory != nil {
+		return r.memory.read(path)
+	}
+	return os.ReadFile(path)
+}
+
+// lockGitFile claims a file below the .gvc directory for writing
+func (r *Repository) lockGitFile(path string) (*lockFile, error) {
+	path = r.namespacePath(path)
+	if name, ok := r.storedRefName(path); ok {
+		ref, err := r.Refs.Lock(name)
+		if err != nil {
+			return nil, err
+		}
+		return &lockFile{path: path, ref: ref}, nil
+	}
+	if r.memory != nil {
+		return r.memory.lock(path)
+	}
+	return r.lockShared(path)
+}
+
+// writeGitFile atomically replaces a file below the .gvc directory under its lock
+func (r *Repository) writeGitFile(path string, data []byte) error {
+	l, err := r.lockGitFile(path)
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+	return l.commit(data)
+}
+
+// gitFileModTime returns when a file below the .gvc directory was last
+// written, and whether it exists
+func (r *Repository) gitFileModTime(path string) (time.Time, bool) {
+	path = r.namespacePath(path)
+	if name, ok := r.storedRefName(path); ok {
+		// Stores do not record when a ref changed
+		_, err := r.Refs.Read(name)
+		return time.Time{}, err == nil
+	}
+	if r.memory != nil {
+		return r.memory.stat(path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// walkGitFiles calls visit with every file below dir in the .gvc directory,
+// skipping locks. A missing dir holds no files. In a namespace, the files
+// kept there are visited under the paths they have outside it.
+func (r *Repository) walkGitFiles(dir string, visit func(path string) error) error {
+	if kept := r.namespacePath(dir); kept != dir {
+		outside, inner := dir, visit
+		dir, visit = kept, func(path string) error {
+			rel, err := filepath.Rel(kept, path)
+			if err != nil {
+				return err
+			}
+			return inner(filepath.Join(outside, rel))
+		}
+	}
+	if name, ok := r.storedRefName(dir); ok {
+		names, err := r.Refs.List(name)
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			if err := visit(r.gitPath(filepath.FromSlash(name))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if r.memory != nil {
+		for _, path := range r.memory.list(dir) {
+			if err := visit(path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return walkFiles(dir, visit)
+}
+
+// walkFiles calls visit with every file below dir on disk, skipping locks.
+// A missing dir holds no files.
+func walkFiles(dir string, visit func(path string) error) error {
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(path, LockSuffix) {
+			return err
+		}
+		return visit(path)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package gvc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemoryRepositoryKeepsNothingOnDisk(t *testing.T) {
+	root := t.TempDir()
+	repo, err := NewMemoryRepository(root, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitSHA := commitTestFile(t, repo)
+	if err := repo.CreateBranch("topic", "HEAD"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetConfig("user.name", "A U Thor"); err != nil {
+		t.Fatal(err)
+	}
+
+	if sha, err := repo.ResolveCommit("topic"); err != nil || sha != commitSHA {
+		t.Errorf("topic resolved to %q (%v), want %s", sha, err, commitSHA)
+	}
+	if name, found, err := repo.GetConfig("user.name"); err != nil || !found || name != "A U Thor" {
+		t.Errorf("user.name is %q, %v (%v)", name, found, err)
+	}
+	head, err := repo.HeadRef()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := repo.ReadReflog(head); err != nil || len(entries) != 1 {
+		t.Errorf("reflog of %s has %d entries (%v), want 1", head, len(entries), err)
+	}
+	index, err := repo.ReadIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Entries) != 1 || index.Entries[0].Path != "file.txt" {
+		t.Errorf("index holds %v, want file.txt", index.Entries)
+	}
+	if _, err := os.Stat(filepath.Join(root, GvcDir)); !os.IsNotExist(err) {
+		t.Errorf("%s was created on disk", GvcDir)
+	}
+}
@@ -0,0 +1,266 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/diff"
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// hasConflictMarkers reports whether a blob still contains unresolved conflict markers
+func (r *Repository) hasConflictMarkers(sha string) (bool, error) {
+	_, content, err := r.ReadObject(sha)
+	if err != nil {
+		return false, err
+	}
+	for _, line := range diff.SplitLines(content) {
+		if strings.HasPrefix(line, "<<<<<<< ") || strings.HasPrefix(line, ">>>>>>> ") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// mergeTrees merges two flattened trees that descend from base. Conflicting
+// paths are returned separately; their result entries hold the marked-up content.
+func (r *Repository) mergeTrees(base, ours, theirs map[string]IndexEntry, oursLabel, theirsLabel string) (map[string]IndexEntry, []string, error) {
+	result := make(map[string]IndexEntry)
+	var conflicts []string
+
+	paths := make(map[string]bool)
+	for _, entries := range []map[string]IndexEntry{base, ours, theirs} {
+		for path := range entries {
+			paths[path] = true
+		}
+	}
+
+	for path := range paths {
+		baseEntry, inBase := base[path]
+		oursEntry, inOurs := ours[path]
+		theirsEntry, inTheirs := theirs[path]
+
+		switch {
+		case sameEntry(oursEntry, theirsEntry, inOurs, inTheirs), sameEntry(baseEntry, theirsEntry, inBase, inTheirs):
+			if inOurs {
+				result[path] = oursEntry
+			}
+			continue
+		case sameEntry(baseEntry, oursEntry, inBase, inOurs):
+			if inTheirs {
+				result[path] = theirsEntry
+			}
+			continue
+		}
+
+		// Modified on one side and deleted on the other: keep the modification
+		if !inOurs || !inTheirs {
+			if inOurs {
+				result[path] = oursEntry
+			} else {
+				result[path] = theirsEntry
+			}
+			conflicts = append(conflicts, path)
+			continue
+		}
+
+		var baseLines []string
+		if inBase {
+			_, content, err := r.ReadObject(baseEntry.SHA)
+			if err != nil {
+				return nil, nil, err
+			}
+			baseLines = diff.SplitLines(content)
+		}
+		_, oursContent, err := r.ReadObject(oursEntry.SHA)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, theirsContent, err := r.ReadObject(theirsEntry.SHA)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		merged, conflict := diff.Merge(baseLines, diff.SplitLines(oursContent), diff.SplitLines(theirsContent), oursLabel, theirsLabel)
+		sha, err := r.WriteObject(object.BlobObject, []byte(strings.Join(merged, "")))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Take whichever side changed the mode
+		mode := oursEntry.Mode
+		if inBase && oursEntry.Mode == baseEntry.Mode {
+			mode = theirsEntry.Mode
+		}
+		result[path] = IndexEntry{Path: path, SHA: sha, Mode: mode}
+		if conflict {
+			conflicts = append(conflicts, path)
+		}
+	}
+
+	sort.Strings(conflicts)
+	return result, conflicts, nil
+}
+
+// MergeOptions controls Merge
+type MergeOptions struct {
+	// NoCommit stops before the merge commit, with the merged result staged
+	// and the merge in progress, to be checked or changed and then recorded
+	// with ContinueMerge. Fast-forwards have no commit to stop before.
+	NoCommit bool
+	// Message replaces the default "Merge branch '<name>'" message
+	Message string
+}
+
+// Merge joins the history of rev into the current branch: the branch is
+// fast-forwarded when it is behind rev, and otherwise the changes since
+// their merge base are merged and recorded as a commit with rev as its
+// second parent. On conflicts, or with NoCommit, the merge stops to be
+// finished with ContinueMerge or undone with AbortMerge.
+func (r *Repository) Merge(rev string, opts MergeOptions) error {
+	if op := r.OperationInProgress(); op != "" {
+		return fmt.Errorf("a %s is already in progress; use --continue or --abort", op)
+	}
+	commitSHA, err := r.ResolveCommit(rev)
+	if err != nil {
+		return err
+	}
+	commit, err := r.ReadCommit(commitSHA)
+	if err != nil {
+		return err
+	}
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	if headSHA == "" {
+		return errors.New("cannot merge into an empty branch")
+	}
+	if ok, err := r.IsAncestor(commitSHA, headSHA); err != nil {
+		return err
+	} else if ok {
+		fmt.Fprintln(r.Out, "Already up to date.")
+		return nil
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	if err := r.checkCleanState(head); err != nil {
+		return err
+	}
+	theirs, err := r.flattenTree(commit.TreeSHA)
+	if err != nil {
+		return err
+	}
+
+	if ok, err := r.IsAncestor(headSHA, commitSHA); err != nil {
+		return err
+	} else if ok {
+		fmt.Fprintf(r.Out, "Updating %s..%s\nFast-forward\n", headSHA[:7], commitSHA[:7])
+		if err := r.checkoutEntries(head, theirs); err != nil {
+			return err
+		}
+		return r.UpdateHead(commitSHA, "merge "+rev+": Fast-forward")
+	}
+
+	bases, err := r.MergeBases(headSHA, commitSHA)
+	if err != nil {
+		return err
+	}
+	if len(bases) == 0 {
+		return fmt.Errorf("refusing to merge unrelated histories: %s shares no commit with the current branch", rev)
+	}
+	baseCommit, err := r.ReadCommit(bases[0])
+	if err != nil {
+		return err
+	}
+	base, err := r.flattenTree(baseCommit.TreeSHA)
+	if err != nil {
+		return err
+	}
+
+	message := opts.Message
+	if strings.TrimSpace(message) == "" {
+		message = fmt.Sprintf("Merge commit '%s'", rev)
+		if sha, err := r.ReadRef(HeadsDir + "/" + rev); err != nil {
+			return err
+		} else if sha != "" {
+			message = fmt.Sprintf("Merge branch '%s'", rev)
+		}
+	}
+	conflicts, err := r.applyMerge(base, theirs, rev, head)
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 || opts.NoCommit {
+		if err := r.writeSequencerState(r.gitPath(MergeHeadFile), commitSHA, message, conflicts); err != nil {
+			return err
+		}
+	}
+	if len(conflicts) > 0 {
+		return conflictError(fmt.Errorf("could not merge %s\nconflicts in:\n\t%s\n"+
+			"resolve them, 'gvc add' the files and run 'gvc merge --continue' (or --abort)",
+			rev, strings.Join(conflicts, "\n\t")))
+	}
+	if opts.NoCommit {
+		fmt.Fprintln(r.Out, "Automatic merge went well; stopped before committing as requested")
+		return nil
+	}
+
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+	_, err = r.commitStaged(staged, []string{headSHA, commitSHA}, r.authorIdent(), time.Now(), message, "merge "+rev)
+	return err
+}
+
+// ContinueMerge records an interrupted merge as a merge commit once its
+// conflicts are resolved, with whatever is staged by then
+func (r *Repository) ContinueMerge() error {
+	data, err := os.ReadFile(r.gitPath(MergeHeadFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return errors.New("no merge in progress")
+		}
+		return fmt.Errorf("failed to read %s: %w", MergeHeadFile, err)
+	}
+	commitSHA := strings.TrimSpace(string(data))
+	message, err := os.ReadFile(r.gitPath(MergeMsgFile))
+	if err != nil {
+		return fmt.Errorf("failed to read merge message: %w", err)
+	}
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+	unresolved, err := r.unresolvedConflicts(staged)
+	if err != nil {
+		return err
+	}
+	if len(unresolved) > 0 {
+		return conflictError(fmt.Errorf("unresolved conflicts remain in:\n\t%s\nfix them and 'gvc add' the result",
+			strings.Join(unresolved, "\n\t")))
+	}
+	text := strings.TrimSpace(string(message))
+	if _, err := r.commitStaged(staged, []string{headSHA, commitSHA}, r.authorIdent(), time.Now(), text,
+		"merge: "+strings.SplitN(text, "\n", 2)[0]); err != nil {
+		return err
+	}
+	return r.clearSequencerState(r.gitPath(MergeHeadFile))
+}
+
+// AbortMerge gives up an interrupted merge, returning the working tree and
+// index to HEAD
+func (r *Repository) AbortMerge() error {
+	return r.abortPick(r.gitPath(MergeHeadFile), "merge")
+}
@@ -0,0 +1,72 @@
+package gvc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeNoCommitStopsBeforeTheMergeCommit(t *testing.T) {
+	repo, _ := newTestRepoWithCommit(t)
+	if err := repo.SwitchBranch("topic", true, ""); err != nil {
+		t.Fatal(err)
+	}
+	topicSHA := commitTestChange(t, repo, "topic.txt", "topic\n", "topic")
+	if err := repo.SwitchBranch("main", false, ""); err != nil {
+		t.Fatal(err)
+	}
+	mainSHA := commitTestChange(t, repo, "main.txt", "main\n", "main")
+
+	if err := repo.Merge("topic", MergeOptions{NoCommit: true}); err != nil {
+		t.Fatal(err)
+	}
+	if head, err := repo.HeadCommit(); err != nil || head != mainSHA {
+		t.Fatalf("HEAD moved to %s (%v) before the merge was continued", head, err)
+	}
+	if op := repo.OperationInProgress(); op != "merge" {
+		t.Fatalf("operation in progress is %q, want merge", op)
+	}
+	staged, err := repo.stagedEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := staged["topic.txt"]; !ok {
+		t.Fatal("the merged topic.txt is not staged")
+	}
+	if _, err := repo.Commit("plain commit"); err == nil {
+		t.Fatal("commit went through while the merge was in progress")
+	}
+
+	// The staged result can be changed before it is recorded
+	writeTestFile(t, repo, "main.txt", "main, adjusted\n")
+	if err := repo.Add("main.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ContinueMerge(); err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.HeadCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.ReadCommit(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commit.Parents) != 2 || commit.Parents[0] != mainSHA || commit.Parents[1] != topicSHA {
+		t.Errorf("merge commit has parents %v, want [%s %s]", commit.Parents, mainSHA, topicSHA)
+	}
+	if commit.Message != "Merge branch 'topic'" {
+		t.Errorf("merge commit message is %q", commit.Message)
+	}
+	entries, err := repo.revisionEntries(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, content, err := repo.ReadObject(entries["main.txt"].SHA); err != nil || string(content) != "main, adjusted\n" {
+		t.Errorf("main.txt was recorded as %q (%v), want the adjusted version", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.GitDir, MergeHeadFile)); !os.IsNotExist(err) {
+		t.Errorf("%s was kept after the merge commit: %v", MergeHeadFile, err)
+	}
+}
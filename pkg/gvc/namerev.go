@@ -0,0 +1,89 @@
+package gvc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mergeTraversalWeight makes a path through a second parent cost more than any
+// realistic first-parent chain, so names like main~40 beat main~2^2~3
+const mergeTraversalWeight = 65535
+
+// RevName describes a commit relative to a ref tip
+type RevName struct {
+	tip        string // ref name, possibly with ^n suffixes from merge traversal
+	generation int    // first-parent steps below tip
+	distance   int    // weighted distance from the ref
+	priority   int    // tags beat branches beat other refs
+}
+
+func (n *RevName) String() string {
+	if n.generation == 0 {
+		return n.tip
+	}
+	return fmt.Sprintf("%s~%d", n.tip, n.generation)
+}
+
+// better reports whether n is a preferable name to other
+func (n *RevName) better(other *RevName) bool {
+	if n.priority != other.priority {
+		return n.priority < other.priority
+	}
+	return n.distance < other.distance
+}
+
+// NameRevs names every commit reachable from the refs
+func (r *Repository) NameRevs() (map[string]*RevName, error) {
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+
+	type item struct {
+		sha  string
+		name *RevName
+	}
+	names := make(map[string]*RevName)
+	for _, ref := range refs {
+		name := &RevName{tip: strings.TrimPrefix(ref.Name, "refs/"), priority: 2}
+		switch {
+		case strings.HasPrefix(ref.Name, "refs/tags/"):
+			name.priority = 0
+		case strings.HasPrefix(ref.Name, "refs/heads/"):
+			name.tip = strings.TrimPrefix(ref.Name, "refs/heads/")
+			name.priority = 1
+		}
+
+		stack := []item{{ref.SHA, name}}
+		for len(stack) > 0 {
+			current := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if existing, ok := names[current.sha]; ok && !current.name.better(existing) {
+				continue
+			}
+			node, err := r.LookupCommitNode(current.sha)
+			if err != nil {
+				// Refs may point at non-commits; they simply name nothing
+				if current.sha == ref.SHA {
+					break
+				}
+				return nil, err
+			}
+			names[current.sha] = current.name
+
+			for i := len(node.Parents) - 1; i >= 0; i-- {
+				parent := &RevName{priority: current.name.priority}
+				if i == 0 {
+					parent.tip = current.name.tip
+					parent.generation = current.name.generation + 1
+					parent.distance = current.name.distance + 1
+				} else {
+					parent.tip = fmt.Sprintf("%s^%d", current.name, i+1)
+					parent.distance = current.name.distance + mergeTraversalWeight
+				}
+				stack = append(stack, item{node.Parents[i], parent})
+			}
+		}
+	}
+	return names, nil
+}
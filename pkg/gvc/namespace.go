@@ -0,0 +1,97 @@
+package gvc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvNamespace names the environment variable holding the ref namespace a
+// fetch, push or clone confines the repository on the far side to, so one
+// repository can serve several logical ones, such as one per fork
+const EnvNamespace = "GVC_NAMESPACE"
+
+// NamespacesDir holds the refs of every namespace. Namespace a/b keeps its
+// HEAD and refs below refs/namespaces/a/refs/namespaces/b/.
+const NamespacesDir = "refs/namespaces"
+
+// SetNamespace confines the handle to the refs of namespace ns: HEAD, refs,
+// packed refs and reflogs are read and written below NamespacesDir under
+// their usual names, and the refs outside it are not seen. Objects are
+// shared by every namespace. An empty ns lifts the confinement.
+func (r *Repository) SetNamespace(ns string) error {
+	ns = strings.Trim(ns, "/")
+	if ns != "" {
+		for _, part := range strings.Split(ns, "/") {
+			if _, err := CheckRefFormat(NamespacesDir+"/"+part, RefFormatOptions{}); err != nil {
+				return fmt.Errorf("invalid namespace '%s'", ns)
+			}
+		}
+	}
+	r.namespace = ns
+	return nil
+}
+
+// Namespace returns the namespace the handle is confined to, or "" when it
+// sees every ref
+func (r *Repository) Namespace() string {
+	return r.namespace
+}
+
+// namespaceRef returns the name ref, such as HEAD or refs/heads/main, is kept
+// under in the handle's namespace
+func (r *Repository) namespaceRef(ref string) string {
+	if r.namespace == "" {
+		return ref
+	}
+	var prefix strings.Builder
+	for _, part := range strings.Split(r.namespace, "/") {
+		prefix.WriteString(NamespacesDir + "/" + part + "/")
+	}
+	return prefix.String() + ref
+}
+
+// namespacePath returns where a path below .gvc naming HEAD, refs or a
+// directory of them is kept in the handle's namespace; the metadata file
+// functions read and write that instead. Other paths are returned as is.
+func (r *Repository) namespacePath(path string) string {
+	if r.namespace == "" {
+		return path
+	}
+	for _, base := range []string{r.GitDir, r.CommonDir} {
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			continue
+		}
+		name := filepath.ToSlash(rel)
+		if name != HeadFile && name != RefsDir && !strings.HasPrefix(name, RefsDir+"/") {
+			continue
+		}
+		if r.gitPath(rel) == path {
+			return filepath.Join(r.CommonDir, filepath.FromSlash(r.namespaceRef(name)))
+		}
+	}
+	return path
+}
+
+// openNamespace confines a handle on the far side of a transfer to the
+// namespace EnvNamespace names, if any
+func (r *Repository) openNamespace() error {
+	return r.SetNamespace(os.Getenv(EnvNamespace))
+}
+
+// initNamespaceHead points the HEAD of a namespace that has none at branch,
+// so the first branch pushed to a namespace is what clones of it check out
+func (r *Repository) initNamespaceHead(branch string) error {
+	if r.namespace == "" || !strings.HasPrefix(branch, HeadsDir+"/") {
+		return nil
+	}
+	if _, ok := r.gitFileModTime(r.gitPath(HeadFile)); ok {
+		return nil
+	}
+	if err := r.writeGitFile(r.gitPath(HeadFile), []byte("ref: "+branch+"\n")); err != nil {
+		return fmt.Errorf("failed to write HEAD of namespace %s: %w", r.namespace, err)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package gvc
+
+import (
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+// Hangul syllables decompose and compose by formula rather than by table
+const (
+	hangulSBase  = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulSCount = hangulLCount * hangulNCount
+)
+
+// combiningRange gives the canonical combining class of the characters lo to hi
+type combiningRange struct {
+	lo, hi rune
+	class  uint8
+}
+
+var (
+	nfcCompositionsOnce sync.Once
+	nfcCompositions     map[[2]rune]rune
+)
+
+// toNFC returns s in Unicode normalization form C, the composed spelling
+// Linux and Windows file names usually have, so a name macOS hands back
+// decomposed ("e" and a combining accent rather than "é") is the same path
+// in the index. Invalid UTF-8 is left as it is.
+func toNFC(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii || !utf8.ValidString(s) {
+		return s
+	}
+
+	var decomposed []rune
+	for _, c := range s {
+		decomposed = appendDecomposed(decomposed, c)
+	}
+	// Put each run of combining marks in canonical order, keeping marks of the
+	// same class as they were
+	for i := 1; i < len(decomposed); i++ {
+		for j := i; j > 0; j-- {
+			class, prev := combiningClass(decomposed[j]), combiningClass(decomposed[j-1])
+			if class == 0 || prev <= class {
+				break
+			}
+			decomposed[j], decomposed[j-1] = decomposed[j-1], decomposed[j]
+		}
+	}
+
+	composed := decomposed[:0]
+	starter := -1
+	for _, c := range decomposed {
+		class := combiningClass(c)
+		if starter >= 0 {
+			// c joins the last starter unless a character between them is a
+			// starter or has a class at least c's
+			last := len(composed) - 1
+			blocked := last != starter && (combiningClass(composed[last]) == 0 || combiningClass(composed[last]) >= class)
+			if !blocked {
+				if joined, ok := composePair(composed[starter], c); ok {
+					composed[starter] = joined
+					continue
+				}
+			}
+		}
+		if class == 0 {
+			starter = len(composed)
+		}
+		composed = append(composed, c)
+	}
+	return string(composed)
+}
+
+// appendDecomposed appends the full canonical decomposition of c
+func appendDecomposed(out []rune, c rune) []rune {
+	if c >= hangulSBase && c < hangulSBase+hangulSCount {
+		index := c - hangulSBase
+		out = append(out, hangulLBase+index/hangulNCount, hangulVBase+index%hangulNCount/hangulTCount)
+		if t := index % hangulTCount; t != 0 {
+			out = append(out, hangulTBase+t)
+		}
+		return out
+	}
+	pair, ok := nfcDecompositions[c]
+	if !ok {
+		return append(out, c)
+	}
+	out = appendDecomposed(out, pair[0])
+	if pair[1] != 0 {
+		out = appendDecomposed(out, pair[1])
+	}
+	return out
+}
+
+// composePair returns the character first and second compose to, if any
+func composePair(first, second rune) (rune, bool) {
+	if first >= hangulLBase && first < hangulLBase+hangulLCount &&
+		second >= hangulVBase && second < hangulVBase+hangulVCount {
+		return hangulSBase + ((first-hangulLBase)*hangulVCount+second-hangulVBase)*hangulTCount, true
+	}
+	if first >= hangulSBase && first < hangulSBase+hangulSCount && (first-hangulSBase)%hangulTCount == 0 &&
+		second > hangulTBase && second < hangulTBase+hangulTCount {
+		return first + second - hangulTBase, true
+	}
+	nfcCompositionsOnce.Do(func() {
+		nfcCompositions = make(map[[2]rune]rune, len(nfcDecompositions))
+		for c, pair := range nfcDecompositions {
+			if pair[1] != 0 && !nfcExclusions[c] {
+				nfcCompositions[pair] = c
+			}
+		}
+	})
+	c, ok := nfcCompositions[[2]rune{first, second}]
+	return c, ok
+}
+
+// combiningClass returns the canonical combining class of c, 0 for starters
+func combiningClass(c rune) uint8 {
+	i := sort.Search(len(nfcCombiningClasses), func(i int) bool { return nfcCombiningClasses[i].hi >= c })
+	if i < len(nfcCombiningClasses) && nfcCombiningClasses[i].lo <= c {
+		return nfcCombiningClasses[i].class
+	}
+	return 0
+}
@@ -0,0 +1,179 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// NotesRef is the ref holding the notes attached to commits. It points to a
+// commit whose tree has a blob named after each annotated commit, so notes
+// keep their own history and are added or removed without rewriting the
+// commits they describe.
+const NotesRef = "refs/notes/commits"
+
+// Notes are the notes attached to commits, as a notes ref held them when
+// they were loaded
+type Notes struct {
+	Ref   string // the notes ref, e.g. refs/notes/commits
+	r     *Repository
+	blobs map[string]string // annotated commit to the blob of its note
+}
+
+// ExpandNotesRef turns the name of a notes ref into the full ref the way Git
+// does: "review" and "notes/review" both become refs/notes/review, and a
+// name starting with refs/ is kept
+func ExpandNotesRef(name string) string {
+	switch {
+	case strings.HasPrefix(name, "refs/"):
+		return name
+	case strings.HasPrefix(name, "notes/"):
+		return "refs/" + name
+	}
+	return "refs/notes/" + name
+}
+
+// LoadNotes reads the notes of NotesRef
+func (r *Repository) LoadNotes() (*Notes, error) {
+	return r.LoadNotesRef(NotesRef)
+}
+
+// LoadNotesRef reads the notes tree of a notes ref, given as a name
+// ExpandNotesRef takes; a ref that does not exist holds no notes. Besides
+// the flat layout gvc writes it understands the fanout Git uses for large
+// trees, where the first bytes of a commit's SHA name subdirectories, e.g.
+// "ab/cdef...".
+func (r *Repository) LoadNotesRef(ref string) (*Notes, error) {
+	ref = ExpandNotesRef(ref)
+	if _, err := CheckRefFormat(ref, RefFormatOptions{}); err != nil {
+		return nil, err
+	}
+	notes := &Notes{Ref: ref, r: r, blobs: make(map[string]string)}
+	tip, err := r.ReadRef(ref)
+	if err != nil || tip == "" {
+		return notes, err
+	}
+	commit, err := r.ReadCommit(tip)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := r.flattenTree(commit.TreeSHA)
+	if err != nil {
+		return nil, err
+	}
+	for path, entry := range entries {
+		sha := strings.ReplaceAll(path, "/", "")
+		if r.Format.ValidateSHA(sha) == nil {
+			notes.blobs[sha] = entry.SHA
+		}
+	}
+	return notes, nil
+}
+
+// Get returns the note attached to a commit
+func (n *Notes) Get(commitSHA string) (string, bool, error) {
+	blob, ok := n.blobs[commitSHA]
+	if !ok {
+		return "", false, nil
+	}
+	_, content, err := n.r.ReadObject(blob)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read the note of %s: %w", commitSHA, err)
+	}
+	return string(content), true, nil
+}
+
+// Note returns the note a notes ref attaches to a commit, given as any
+// revision
+func (r *Repository) Note(ref, rev string) (string, bool, error) {
+	commitSHA, err := r.ResolveCommit(rev)
+	if err != nil {
+		return "", false, err
+	}
+	notes, err := r.LoadNotesRef(ref)
+	if err != nil {
+		return "", false, err
+	}
+	return notes.Get(commitSHA)
+}
+
+// errNoteExists is returned when adding a note to a commit that has one
+var errNoteExists = errors.New("the commit already has a note; use -f to overwrite it")
+
+// AddNote attaches message to a commit, given as any revision, in a notes
+// ref, replacing its note only when force is set. A trailing newline is
+// added if missing.
+func (r *Repository) AddNote(ref, rev, message string, force bool) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	commitSHA, err := r.ResolveCommit(rev)
+	if err != nil {
+		return err
+	}
+	notes, err := r.LoadNotesRef(ref)
+	if err != nil {
+		return err
+	}
+	if _, ok := notes.blobs[commitSHA]; ok && !force {
+		return fmt.Errorf("cannot add a note to %s: %w", commitSHA[:7], errNoteExists)
+	}
+	if !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	blob, err := r.WriteObject(object.BlobObject, []byte(message))
+	if err != nil {
+		return err
+	}
+	notes.blobs[commitSHA] = blob
+	return r.writeNotes(notes, "Notes added by 'gvc notes add'")
+}
+
+// RemoveNote removes the note a notes ref attaches to a commit, given as
+// any revision
+func (r *Repository) RemoveNote(ref, rev string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	commitSHA, err := r.ResolveCommit(rev)
+	if err != nil {
+		return err
+	}
+	notes, err := r.LoadNotesRef(ref)
+	if err != nil {
+		return err
+	}
+	if _, ok := notes.blobs[commitSHA]; !ok {
+		return fmt.Errorf("commit %s has no note", commitSHA[:7])
+	}
+	delete(notes.blobs, commitSHA)
+	return r.writeNotes(notes, "Notes removed by 'gvc notes remove'")
+}
+
+// writeNotes records notes as a new commit on their ref, in the flat layout
+func (r *Repository) writeNotes(notes *Notes, message string) error {
+	tip, err := r.ReadRef(notes.Ref)
+	if err != nil {
+		return err
+	}
+	entries := make(map[string]IndexEntry, len(notes.blobs))
+	for commitSHA, blob := range notes.blobs {
+		entries[commitSHA] = IndexEntry{Path: commitSHA, SHA: blob, Mode: "100644"}
+	}
+	treeSHA, err := r.buildTree(sortedEntries(entries))
+	if err != nil {
+		return err
+	}
+	var parents []string
+	if tip != "" {
+		parents = []string{tip}
+	}
+	commitSHA, err := r.writeCommit(treeSHA, parents, r.authorIdent(), time.Now(), message)
+	if err != nil {
+		return err
+	}
+	return r.UpdateRefs([]RefEdit{{Ref: notes.Ref, New: commitSHA, Old: tip, CheckOld: true}}, "notes: "+message)
+}
@@ -0,0 +1,85 @@
+package gvc
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// defaultObjectCacheLimit is how many bytes of objects a repository handle
+// keeps decoded when core.objectCacheLimit is not set
+const defaultObjectCacheLimit = 32 << 20
+
+// objectCache keeps the most recently read commits, trees and tags of a
+// repository handle decoded, so walking history does not inflate and hash
+// the same objects over and over. Blobs are not kept: they are large and
+// rarely read twice. Objects never change once named, so entries are only
+// ever evicted, least recently used first, once the cache holds more than
+// limit bytes. It is safe for concurrent use.
+type objectCache struct {
+	mu      sync.Mutex
+	limit   int64
+	size    int64
+	entries map[string]*list.Element
+	order   *list.List // most recently used at the front
+}
+
+// cachedObject is one object held by an objectCache
+type cachedObject struct {
+	sha        string
+	objectType object.Type
+	content    []byte
+}
+
+// objectCache returns the repository's object cache, created on first use
+// from core.objectCacheLimit, or nil when the limit is 0
+func (r *Repository) objectCache() (*objectCache, error) {
+	r.objectCacheOnce.Do(func() {
+		limit, err := r.getConfigInt("core.objectCacheLimit", defaultObjectCacheLimit)
+		if err != nil || limit <= 0 {
+			r.objectCacheErr = err
+			return
+		}
+		r.objCache = &objectCache{limit: limit, entries: make(map[string]*list.Element), order: list.New()}
+	})
+	return r.objCache, r.objectCacheErr
+}
+
+// get returns a cached object, marking it as recently used
+func (c *objectCache) get(sha string) (object.Type, []byte, bool) {
+	if c == nil {
+		return "", nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.entries[sha]
+	if !ok {
+		return "", nil, false
+	}
+	c.order.MoveToFront(elem)
+	cached := elem.Value.(*cachedObject)
+	return cached.objectType, cached.content, true
+}
+
+// add caches an object that has been read and, if required, verified.
+// Callers share the content, so they must not modify it.
+func (c *objectCache) add(sha string, objectType object.Type, content []byte) {
+	if c == nil || objectType == object.BlobObject || int64(len(content)) > c.limit {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[sha]; ok {
+		return
+	}
+	c.entries[sha] = c.order.PushFront(&cachedObject{sha: sha, objectType: objectType, content: content})
+	c.size += int64(len(content))
+	for c.size > c.limit {
+		oldest := c.order.Back()
+		cached := oldest.Value.(*cachedObject)
+		c.order.Remove(oldest)
+		delete(c.entries, cached.sha)
+		c.size -= int64(len(cached.content))
+	}
+}
@@ -0,0 +1,189 @@
+package gvc
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// ObjectFilter leaves objects out of ListObjects, as Git's --filter does
+type ObjectFilter struct {
+	NoBlobs   bool        // blob:none
+	BlobLimit int64       // blob:limit=<n>; blobs of at least this size are left out when positive
+	TreeDepth int         // tree:<depth>; trees and blobs this deep or deeper are left out when not negative
+	Type      object.Type // object:type=<type>; only objects of this type are kept
+}
+
+// ParseObjectFilter reads a filter spec: blob:none, blob:limit=<n> with an
+// optional k, m or g suffix, tree:<depth>, where the root tree is at depth
+// 0, or object:type=(commit|tree|blob|tag)
+func ParseObjectFilter(spec string) (*ObjectFilter, error) {
+	filter := &ObjectFilter{TreeDepth: -1}
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch {
+	case spec == "blob:none":
+		filter.NoBlobs = true
+	case kind == "blob" && strings.HasPrefix(arg, "limit="):
+		limit, err := ParseSize(strings.TrimPrefix(arg, "limit="))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid filter '%s': bad size", spec)
+		}
+		if limit == 0 {
+			filter.NoBlobs = true
+		}
+		filter.BlobLimit = limit
+	case kind == "tree":
+		depth, err := strconv.Atoi(arg)
+		if err != nil || depth < 0 {
+			return nil, fmt.Errorf("invalid filter '%s': bad depth", spec)
+		}
+		filter.TreeDepth = depth
+	case kind == "object" && strings.HasPrefix(arg, "type="):
+		switch objectType := object.Type(strings.TrimPrefix(arg, "type=")); objectType {
+		case object.CommitObject, object.TreeObject, object.BlobObject, object.TagObject:
+			filter.Type = objectType
+		default:
+			return nil, fmt.Errorf("invalid filter '%s': unknown object type", spec)
+		}
+	default:
+		return nil, fmt.Errorf("invalid filter '%s'", spec)
+	}
+	return filter, nil
+}
+
+// keeps reports whether the filter lets an object through. depth is how far
+// below the root tree a tree or blob is; size is only looked at for blobs.
+func (f *ObjectFilter) keeps(objectType object.Type, depth int, size func() (int64, error)) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+	if f.Type != "" && objectType != f.Type {
+		return false, nil
+	}
+	if objectType == object.CommitObject {
+		return true, nil
+	}
+	if f.TreeDepth >= 0 && depth >= f.TreeDepth {
+		return false, nil
+	}
+	if objectType != object.BlobObject {
+		return true, nil
+	}
+	if f.NoBlobs {
+		return false, nil
+	}
+	if f.BlobLimit > 0 {
+		n, err := size()
+		if err != nil {
+			return false, err
+		}
+		return n < f.BlobLimit, nil
+	}
+	return true, nil
+}
+
+// ListedObject is an object ListObjects found, with the path a tree or blob
+// was first met at; "" for commits and root trees
+type ListedObject struct {
+	SHA     string
+	Type    object.Type
+	Path    string
+	Omitted bool // left out by the filter
+}
+
+// ListObjects lists commits and then the trees and blobs they reach, in the
+// order of commits and each tree walked depth first, leaving out objects
+// reachable from exclude, as rev-list --objects does. Objects the filter
+// leaves out are listed with Omitted set; the trees below an omitted tree
+// are not walked.
+func (r *Repository) ListObjects(commits []*CommitNode, exclude []string, filter *ObjectFilter) ([]ListedObject, error) {
+	seen := make(map[string]bool)
+	var walkTree func(sha, name string, depth int, out *[]ListedObject) error
+	walkTree = func(sha, name string, depth int, out *[]ListedObject) error {
+		if seen[sha] {
+			return nil
+		}
+		seen[sha] = true
+		if err := r.canceled(); err != nil {
+			return err
+		}
+		if out != nil {
+			keep, err := filter.keeps(object.TreeObject, depth, nil)
+			if err != nil {
+				return err
+			}
+			*out = append(*out, ListedObject{SHA: sha, Type: object.TreeObject, Path: name, Omitted: !keep})
+			// A tree past the depth limit is not walked; one object:type
+			// leaves out still is, for the blobs below it
+			if filter != nil && filter.TreeDepth >= 0 && depth >= filter.TreeDepth {
+				return nil
+			}
+		}
+		_, content, err := r.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		entries, err := r.Format.ParseTree(content)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			entryPath := path.Join(name, entry.Name)
+			switch entry.Type {
+			case object.TreeObject:
+				if err := walkTree(entry.SHA, entryPath, depth+1, out); err != nil {
+					return err
+				}
+			case object.BlobObject:
+				if seen[entry.SHA] {
+					continue
+				}
+				seen[entry.SHA] = true
+				if out == nil {
+					continue
+				}
+				keep, err := filter.keeps(object.BlobObject, depth+1, func() (int64, error) { return r.objectSize(entry.SHA) })
+				if err != nil {
+					return err
+				}
+				*out = append(*out, ListedObject{SHA: entry.SHA, Type: object.BlobObject, Path: entryPath, Omitted: !keep})
+			}
+		}
+		return nil
+	}
+
+	// Everything the excluded commits reach is marked seen first
+	excluded, err := r.reachableCommits(exclude)
+	if err != nil {
+		return nil, err
+	}
+	for sha := range excluded {
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return nil, err
+		}
+		if err := walkTree(commit.TreeSHA, "", 0, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	var listed, trees []ListedObject
+	for _, node := range commits {
+		keep, err := filter.keeps(object.CommitObject, 0, nil)
+		if err != nil {
+			return nil, err
+		}
+		listed = append(listed, ListedObject{SHA: node.SHA, Type: object.CommitObject, Omitted: !keep})
+		commit, err := r.ReadCommit(node.SHA)
+		if err != nil {
+			return nil, err
+		}
+		if err := walkTree(commit.TreeSHA, "", 0, &trees); err != nil {
+			return nil, err
+		}
+	}
+	return append(listed, trees...), nil
+}
@@ -0,0 +1,307 @@
+package gvc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// objectPath returns the file path for a given object SHA
+func (r *Repository) objectPath(sha string) string {
+	return r.gitPath(ObjectsDir, sha[:2], sha[2:])
+}
+
+// ReadObject reads and decompresses a Git object. Unless core.verifyObjects is
+// false the content is re-hashed, so a corrupt object is reported instead of returned.
+func (r *Repository) ReadObject(sha string) (object.Type, []byte, error) {
+	cache, err := r.objectCache()
+	if err != nil {
+		return "", nil, err
+	}
+	if objectType, content, ok := cache.get(sha); ok {
+		return objectType, content, nil
+	}
+	verify, err := r.verifyObjects()
+	if err != nil {
+		return "", nil, err
+	}
+	objectType, content, err := r.readObject(sha, verify)
+	if err != nil {
+		return "", nil, err
+	}
+	cache.add(sha, objectType, content)
+	return objectType, content, nil
+}
+
+// verifyObjects reports whether reads re-hash objects, reading core.verifyObjects once
+func (r *Repository) verifyObjects() (bool, error) {
+	if !r.verifyLoaded {
+		verify, err := r.getConfigBool("core.verifyObjects", true)
+		if err != nil {
+			return false, err
+		}
+		r.verify, r.verifyLoaded = verify, true
+	}
+	return r.verify, nil
+}
+
+// readObject reads an object from loose storage or a pack, checking that its
+// content hashes to sha when verify is set
+func (r *Repository) readObject(sha string, verify bool) (object.Type, []byte, error) {
+	objectType, content, err := r.loadObject(sha)
+	if err != nil {
+		return "", nil, err
+	}
+	if verify {
+		if actual := r.Format.Hash(objectType, content); !strings.EqualFold(actual, sha) {
+			return "", nil, fmt.Errorf("object %s is corrupt: its content hashes to %s", sha, actual)
+		}
+	}
+	return objectType, content, nil
+}
+
+// loadObject reads and decompresses an object without verifying it. A loose
+// object that cannot be decoded is reported as a CorruptObjectError with recovery hints.
+func (r *Repository) loadObject(sha string) (object.Type, []byte, error) {
+	objectType, content, err := r.loadRawObject(sha)
+	var corrupt *CorruptObjectError
+	if errors.As(err, &corrupt) {
+		r.addRecoveryHints(corrupt)
+	}
+	return objectType, content, err
+}
+
+// loadRawObject reads and decompresses an object without verifying it or
+// looking for recovery hints
+func (r *Repository) loadRawObject(sha string) (object.Type, []byte, error) {
+	if err := r.Format.ValidateSHA(sha); err != nil {
+		return "", nil, err
+	}
+	if r.unsaved != nil {
+		if objectType, content, err := r.unsaved.Get(sha); err == nil {
+			return objectType, content, nil
+		}
+	}
+	if !r.usesFileStore() {
+		objectType, content, err := r.Objects.Get(sha)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to read object %s: %w", sha, err)
+		}
+		return objectType, content, nil
+	}
+
+	objPath := r.objectPath(sha)
+	data, err := os.ReadFile(objPath)
+	if err != nil {
+		// Objects moved into a pack by gc no longer exist as loose files
+		if os.IsNotExist(err) {
+			objectType, content, found, packErr := r.readPackedObject(sha)
+			if packErr != nil {
+				return "", nil, packErr
+			}
+			if found {
+				return objectType, content, nil
+			}
+		}
+		return "", nil, fmt.Errorf("failed to read object %s: %w", sha, err)
+	}
+
+	if data, err = r.unsealData(data); err != nil && !errors.Is(err, errDecrypt) {
+		return "", nil, fmt.Errorf("failed to read object %s: %w", sha, err)
+	}
+	var objectType object.Type
+	var content []byte
+	if err == nil {
+		objectType, content, err = decodeLooseObject(data)
+	}
+	if err != nil {
+		return "", nil, &CorruptObjectError{SHA: sha, Path: objPath, Err: err}
+	}
+	return objectType, content, nil
+}
+
+// ObjectSize returns the size of an object's content without reading all of it
+func (r *Repository) ObjectSize(sha string) (int64, error) {
+	return r.objectSize(sha)
+}
+
+// objectSize returns the size of an object's content, inflating no more of
+// it than the header that records the size
+func (r *Repository) objectSize(sha string) (int64, error) {
+	if err := r.Format.ValidateSHA(sha); err != nil {
+		return 0, err
+	}
+	if !r.usesFileStore() {
+		_, content, err := r.loadRawObject(sha)
+		return int64(len(content)), err
+	}
+	f, err := r.openObjectFile(r.objectPath(sha))
+	if os.IsNotExist(err) {
+		packs, err := r.loadPacks()
+		if err != nil {
+			return 0, err
+		}
+		for _, pack := range packs {
+			if offset, ok := pack.find(sha); ok {
+				return pack.sizeAt(offset)
+			}
+		}
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read object %s: %w", sha, err)
+	}
+	defer f.Close()
+	_, size, err := readLooseHeader(f, sha)
+	return size, err
+}
+
+// readLooseHeader inflates no more of a loose object file than its header,
+// returning the type and size it records
+func readLooseHeader(f objectFile, sha string) (object.Type, int64, error) {
+	zr, err := zlib.NewReader(io.NewSectionReader(f, 0, f.Size()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to decompress object %s: %w", sha, err)
+	}
+	defer zr.Close()
+	var objectType string
+	var size int64
+	if _, err := fmt.Fscanf(zr, "%s %d\x00", &objectType, &size); err != nil {
+		return "", 0, fmt.Errorf("failed to parse object header of %s: %w", sha, err)
+	}
+	return object.Type(objectType), size, nil
+}
+
+// decodeLooseObject inflates a loose object file and splits off its header
+func decodeLooseObject(data []byte) (object.Type, []byte, error) {
+	// Decompress the object
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decompress object: %w", err)
+	}
+	defer zr.Close()
+
+	// Read the object header (e.g., "blob 14")
+	var objectType string
+	var contentLength int
+	if _, err := fmt.Fscanf(zr, "%s %d\x00", &objectType, &contentLength); err != nil {
+		return "", nil, fmt.Errorf("failed to parse object header: %w", err)
+	}
+
+	// Read and verify the content
+	content, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read decompressed content: %w", err)
+	}
+
+	if len(content) != contentLength {
+		return "", nil, fmt.Errorf("content length mismatch: expected %d, got %d", contentLength, len(content))
+	}
+
+	return object.Type(objectType), content, nil
+}
+
+// WriteObject compresses and stores an object, returning its SHA
+func (r *Repository) WriteObject(objectType object.Type, content []byte) (string, error) {
+	if r.batch != nil {
+		return r.batch.Write(objectType, content)
+	}
+	return r.WriteObjectFrom(objectType, int64(len(content)), bytes.NewReader(content))
+}
+
+// WriteObjectFrom stores an object whose size bytes of content are read from src.
+// The filesystem store hashes and compresses as it goes so large files never
+// sit in memory; other stores are handed the whole content.
+func (r *Repository) WriteObjectFrom(objectType object.Type, size int64, src io.Reader) (string, error) {
+	if r.batch != nil {
+		return r.batch.WriteFrom(objectType, size, src)
+	}
+	if err := r.checkWritable(); err != nil {
+		return "", err
+	}
+	if r.usesFileStore() {
+		return r.writeLooseObject(objectType, size, src)
+	}
+	content := make([]byte, size)
+	if _, err := io.ReadFull(src, content); err != nil {
+		return "", fmt.Errorf("failed to read object content: %w", err)
+	}
+	sha := r.Format.Hash(objectType, content)
+	if err := r.Objects.Put(sha, objectType, content); err != nil {
+		return "", fmt.Errorf("failed to write object %s: %w", sha, err)
+	}
+	return sha, nil
+}
+
+// writeLooseObject compresses an object into a file under .gvc/objects. It
+// is stored even if it exists, so a corrupt copy can be replaced.
+func (r *Repository) writeLooseObject(objectType object.Type, size int64, src io.Reader) (string, error) {
+	if err := r.checkWritable(); err != nil {
+		return "", err
+	}
+	w, err := r.NewObjectWriter()
+	if err != nil {
+		return "", err
+	}
+	w.rewrite = true
+	sha, err := w.writeLoose(objectType, size, src)
+	if err != nil {
+		return "", err
+	}
+	return sha, w.Close()
+}
+
+// HashFile returns the blob SHA of the file at path, storing the blob when write
+// is set. The file is streamed rather than read into memory.
+func (r *Repository) HashFile(path string, write bool) (string, error) {
+	if write && r.batch != nil {
+		return r.batch.WriteFile(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file %s: %w", path, err)
+	}
+	if write {
+		return r.WriteObjectFrom(object.BlobObject, info.Size(), f)
+	}
+	return r.Format.HashReader(object.BlobObject, info.Size(), f)
+}
+
+// listLooseObjects returns the SHAs of every loose object
+func (r *Repository) listLooseObjects() ([]string, error) {
+	dirs, err := os.ReadDir(r.gitPath(ObjectsDir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read objects directory: %w", err)
+	}
+
+	var shas []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 {
+			continue
+		}
+		files, err := os.ReadDir(r.gitPath(ObjectsDir, dir.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object directory: %w", err)
+		}
+		for _, file := range files {
+			sha := dir.Name() + file.Name()
+			if r.Format.ValidateSHA(sha) == nil {
+				shas = append(shas, sha)
+			}
+		}
+	}
+	sort.Strings(shas)
+	return shas, nil
+}
@@ -0,0 +1,332 @@
+package gvc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// fsyncPolicy says when object files are flushed to disk, set with
+// core.fsyncObjectFiles and core.fsyncMethod
+type fsyncPolicy int
+
+const (
+	fsyncNone  fsyncPolicy = iota // leave flushing to the operating system
+	fsyncEach                     // flush each object and its directory as it is written
+	fsyncBatch                    // flush everything a batch wrote once, when it is closed
+)
+
+// objectFsyncPolicy reads the fsync policy for object files. Nothing is
+// flushed unless core.fsyncObjectFiles is true; core.fsyncMethod then picks
+// fsync (each object) or batch.
+func (r *Repository) objectFsyncPolicy() (fsyncPolicy, error) {
+	if !r.fsyncLoaded {
+		enabled, err := r.getConfigBool("core.fsyncObjectFiles", false)
+		if err != nil {
+			return fsyncNone, err
+		}
+		method, err := r.getConfigString("core.fsyncMethod", "fsync")
+		if err != nil {
+			return fsyncNone, err
+		}
+		policy := fsyncNone
+		switch {
+		case method != "fsync" && method != "batch":
+			return fsyncNone, fmt.Errorf("bad value for core.fsyncMethod: %s (use fsync or batch)", method)
+		case !enabled:
+		case method == "batch":
+			policy = fsyncBatch
+		default:
+			policy = fsyncEach
+		}
+		r.fsync, r.fsyncLoaded = policy, true
+	}
+	return r.fsync, nil
+}
+
+// ObjectWriter stores many objects at once, as write-tree and add do. An
+// object that already exists, or was written earlier by the same writer, is
+// skipped before it is compressed; each fan-out directory is created once;
+// and under core.fsyncMethod=batch the files and directories written are
+// flushed together by Close rather than one by one. It is safe for
+// concurrent use.
+type ObjectWriter struct {
+	r       *Repository
+	policy  fsyncPolicy
+	mu      sync.Mutex
+	known   map[string]bool // objects that exist or have been written
+	dirs    map[string]bool // fan-out directories known to exist
+	pending []string        // object files Close flushes
+	rewrite bool            // store objects even when they exist, replacing corrupt copies
+}
+
+// NewObjectWriter returns a writer for a batch of objects. Close must be
+// called once the batch is written.
+func (r *Repository) NewObjectWriter() (*ObjectWriter, error) {
+	policy, err := r.objectFsyncPolicy()
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectWriter{r: r, policy: policy, known: make(map[string]bool), dirs: make(map[string]bool)}, nil
+}
+
+// batchObjects runs fn with the objects it writes going through one
+// ObjectWriter, closed when fn returns. Nested calls share the outer writer.
+func (r *Repository) batchObjects(fn func() error) error {
+	if r.batch != nil {
+		return fn()
+	}
+	w, err := r.NewObjectWriter()
+	if err != nil {
+		return err
+	}
+	r.batch = w
+	err = fn()
+	r.batch = nil
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// exists reports whether the object is already stored, remembering the answer
+func (w *ObjectWriter) exists(sha string) bool {
+	w.mu.Lock()
+	known := w.known[sha]
+	w.mu.Unlock()
+	if known {
+		return true
+	}
+	if !w.r.objectExists(sha) {
+		return false
+	}
+	w.remember(sha)
+	return true
+}
+
+// remember records that the object is stored
+func (w *ObjectWriter) remember(sha string) {
+	w.mu.Lock()
+	w.known[sha] = true
+	w.mu.Unlock()
+}
+
+// Write stores an object unless it already exists, returning its SHA
+func (w *ObjectWriter) Write(objectType object.Type, content []byte) (string, error) {
+	if err := w.r.checkWritable(); err != nil {
+		return "", err
+	}
+	sha := w.r.Format.Hash(objectType, content)
+	if w.exists(sha) {
+		return sha, nil
+	}
+	if !w.r.usesFileStore() {
+		if err := w.r.Objects.Put(sha, objectType, content); err != nil {
+			return "", fmt.Errorf("failed to write object %s: %w", sha, err)
+		}
+		w.remember(sha)
+		return sha, nil
+	}
+	written, err := w.writeLoose(objectType, int64(len(content)), bytes.NewReader(content))
+	if err != nil {
+		return "", err
+	}
+	if written != sha {
+		return "", fmt.Errorf("object %s hashes to %s", sha, written)
+	}
+	return sha, nil
+}
+
+// WriteFile stores the file at path as a blob unless it already exists,
+// returning its SHA. The file is hashed first, and read a second time to be
+// compressed only when the blob is new.
+func (w *ObjectWriter) WriteFile(path string) (string, error) {
+	if err := w.r.checkWritable(); err != nil {
+		return "", err
+	}
+	sha, err := w.r.HashFile(path, false)
+	if err != nil {
+		return "", err
+	}
+	if w.exists(sha) {
+		return sha, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file %s: %w", path, err)
+	}
+	written, err := w.WriteFrom(object.BlobObject, info.Size(), f)
+	if err != nil {
+		return "", err
+	}
+	if written != sha {
+		return "", fmt.Errorf("file %s changed while it was being stored", path)
+	}
+	return sha, nil
+}
+
+// WriteFrom stores an object whose size bytes of content are read from src.
+// The SHA is only known once the content has been read, so an object that
+// already exists is compressed but then dropped.
+func (w *ObjectWriter) WriteFrom(objectType object.Type, size int64, src io.Reader) (string, error) {
+	if err := w.r.checkWritable(); err != nil {
+		return "", err
+	}
+	if !w.r.usesFileStore() {
+		content := make([]byte, size)
+		if _, err := io.ReadFull(src, content); err != nil {
+			return "", fmt.Errorf("failed to read object content: %w", err)
+		}
+		return w.Write(objectType, content)
+	}
+	return w.writeLoose(objectType, size, src)
+}
+
+// writeLoose compresses an object into a temporary file under .gvc/objects,
+// hashing it on the way, and moves it into place unless it already exists
+func (w *ObjectWriter) writeLoose(objectType object.Type, size int64, src io.Reader) (string, error) {
+	tmp, err := createTemp(w.r.gitPath(ObjectsDir), "tmp_obj_")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temporary object: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// Hash the uncompressed stream while compressing it into the temporary
+	// file, sealed in an encrypted repository
+	sealed, err := w.r.sealObjectFile(tmp)
+	if err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	hasher := w.r.Format.New()
+	zw := zlib.NewWriter(sealed)
+	mw := io.MultiWriter(hasher, zw)
+	if _, err := io.WriteString(mw, object.Header(objectType, size)); err != nil {
+		return "", fmt.Errorf("failed to compress object: %w", err)
+	}
+	if _, err := io.CopyN(mw, src, size); err != nil {
+		return "", fmt.Errorf("failed to compress object: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return "", fmt.Errorf("failed to close compressor: %w", err)
+	}
+	if err := sealed.Close(); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	sha := hex.EncodeToString(hasher.Sum(nil))
+	if !w.rewrite && w.exists(sha) {
+		return sha, nil
+	}
+	if w.policy == fsyncEach {
+		if err := tmp.Sync(); err != nil {
+			return "", fmt.Errorf("failed to flush object %s: %w", sha, err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+
+	// Move the finished object into place
+	objPath := w.r.objectPath(sha)
+	dir := filepath.Dir(objPath)
+	if err := w.makeDir(dir); err != nil {
+		return "", err
+	}
+	if err := w.r.adjustSharedPerm(tmp.Name()); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), objPath); err != nil {
+		return "", fmt.Errorf("failed to write object: %w", err)
+	}
+	switch w.policy {
+	case fsyncEach:
+		if err := syncDir(dir); err != nil {
+			return "", err
+		}
+	case fsyncBatch:
+		w.mu.Lock()
+		w.pending = append(w.pending, objPath)
+		w.mu.Unlock()
+	}
+	w.remember(sha)
+	return sha, nil
+}
+
+// makeDir creates a fan-out directory the first time it is needed
+func (w *ObjectWriter) makeDir(dir string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.dirs[dir] {
+		return nil
+	}
+	if err := w.r.mkdirShared(dir); err != nil {
+		return fmt.Errorf("failed to create object directory: %w", err)
+	}
+	w.dirs[dir] = true
+	return nil
+}
+
+// Close ends the batch. Under core.fsyncMethod=batch it flushes every object
+// file written and then the directories holding them, so the batch is on
+// disk once Close returns.
+func (w *ObjectWriter) Close() error {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	dirs := make(map[string]bool)
+	for _, path := range pending {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to flush object: %w", err)
+		}
+		err = f.Sync()
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("failed to flush object: %w", err)
+		}
+		dirs[filepath.Dir(path)] = true
+	}
+	dirs[w.r.gitPath(ObjectsDir)] = true
+	for dir := range dirs {
+		if err := syncDir(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncDir flushes a directory so the files renamed into it survive a crash.
+// Windows cannot flush directories and does not need to.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("failed to flush directory %s: %w", dir, err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to flush directory %s: %w", dir, err)
+	}
+	return nil
+}
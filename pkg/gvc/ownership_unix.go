@@ -0,0 +1,33 @@
+//go:build unix
+
+package gvc
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// checksOwnership is set where repositories of other users are refused
+const checksOwnership = true
+
+// ownedByCurrentUser reports whether the current user owns path. Under sudo
+// the user who ran sudo counts as the current one, so a repository of theirs
+// can still be opened, as Git allows.
+func ownedByCurrentUser(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return true, nil
+	}
+	uid := os.Geteuid()
+	if uid == 0 {
+		if sudoUID, err := strconv.Atoi(os.Getenv("SUDO_UID")); err == nil {
+			uid = sudoUID
+		}
+	}
+	return int(stat.Uid) == uid, nil
+}
@@ -0,0 +1,774 @@
+package gvc
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+const PackDir = "objects/pack"
+
+// Object type codes used inside packfiles
+const (
+	packCommit   = 1
+	packTree     = 2
+	packBlob     = 3
+	packTag      = 4
+	packOfsDelta = 6
+	packRefDelta = 7
+)
+
+var packTypes = map[object.Type]byte{object.CommitObject: packCommit, object.TreeObject: packTree, object.BlobObject: packBlob, object.TagObject: packTag}
+
+// packFile is a pack together with its loaded index
+type packFile struct {
+	path    string
+	shas    []string // sorted
+	offsets []uint64
+	file    objectFile
+	repo    *Repository // resolves delta bases stored outside the pack
+}
+
+// loadPacks reads every pack index under objects/pack once per repository handle
+func (r *Repository) loadPacks() ([]*packFile, error) {
+	if r.packsLoaded {
+		return r.loadedPacks, nil
+	}
+
+	idxFiles, err := filepath.Glob(r.gitPath(PackDir, "pack-*.idx"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(idxFiles)
+	for _, idxFile := range idxFiles {
+		pack, err := readPackIndex(idxFile, r.Format)
+		if err != nil {
+			return nil, err
+		}
+		pack.repo = r
+		r.loadedPacks = append(r.loadedPacks, pack)
+	}
+	r.packsLoaded = true
+	return r.loadedPacks, nil
+}
+
+// resetPacks forgets the loaded packs after they were rewritten
+func (r *Repository) resetPacks() {
+	for _, pack := range r.loadedPacks {
+		if pack.file != nil {
+			pack.file.Close()
+		}
+	}
+	r.loadedPacks = nil
+	r.packsLoaded = false
+}
+
+// readPackIndex parses a version 2 pack index whose object IDs use format
+func readPackIndex(idxFile string, format *object.Format) (*packFile, error) {
+	data, err := os.ReadFile(idxFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pack index: %w", err)
+	}
+	if len(data) < 8+256*4 || !bytes.Equal(data[:4], []byte("\xfftOc")) || binary.BigEndian.Uint32(data[4:8]) != 2 {
+		return nil, fmt.Errorf("unsupported pack index format: %s", idxFile)
+	}
+
+	count := int(binary.BigEndian.Uint32(data[8+255*4:]))
+	shaStart := 8 + 256*4
+	crcStart := shaStart + count*format.Size
+	offsetStart := crcStart + count*4
+	largeStart := offsetStart + count*4
+	if len(data) < largeStart+2*format.Size {
+		return nil, fmt.Errorf("truncated pack index: %s", idxFile)
+	}
+
+	pack := &packFile{
+		path:    strings.TrimSuffix(idxFile, ".idx") + ".pack",
+		shas:    make([]string, count),
+		offsets: make([]uint64, count),
+	}
+	for i := 0; i < count; i++ {
+		pack.shas[i] = hex.EncodeToString(data[shaStart+i*format.Size : shaStart+(i+1)*format.Size])
+		offset := binary.BigEndian.Uint32(data[offsetStart+i*4:])
+		if offset&0x80000000 != 0 {
+			large := largeStart + int(offset&0x7fffffff)*8
+			if large+8 > len(data) {
+				return nil, fmt.Errorf("corrupt pack index: %s", idxFile)
+			}
+			pack.offsets[i] = binary.BigEndian.Uint64(data[large:])
+		} else {
+			pack.offsets[i] = uint64(offset)
+		}
+	}
+	return pack, nil
+}
+
+// find returns the offset of an object within the pack
+func (p *packFile) find(sha string) (uint64, bool) {
+	i := sort.SearchStrings(p.shas, sha)
+	if i < len(p.shas) && p.shas[i] == sha {
+		return p.offsets[i], true
+	}
+	return 0, false
+}
+
+// readPackedObject looks an object up in every pack
+func (r *Repository) readPackedObject(sha string) (object.Type, []byte, bool, error) {
+	packs, err := r.loadPacks()
+	if err != nil {
+		return "", nil, false, err
+	}
+	for _, pack := range packs {
+		if offset, ok := pack.find(sha); ok {
+			objectType, content, err := pack.readAt(offset)
+			if err != nil {
+				return "", nil, false, fmt.Errorf("failed to read %s from %s: %w", sha, filepath.Base(pack.path), err)
+			}
+			return objectType, content, true, nil
+		}
+	}
+	return "", nil, false, nil
+}
+
+// hasPackedObject reports whether any pack contains sha
+func (r *Repository) hasPackedObject(sha string) (bool, error) {
+	packs, err := r.loadPacks()
+	if err != nil {
+		return false, err
+	}
+	for _, pack := range packs {
+		if _, ok := pack.find(sha); ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// readAt inflates the object stored at offset, resolving deltas against their bases
+func (p *packFile) readAt(offset uint64) (object.Type, []byte, error) {
+	header, err := p.headerAt(offset)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Type and inflated size: 3 type bits, then 4+7n size bits
+	typeCode := (header[0] >> 4) & 0x07
+	pos := 0
+	for header[pos]&0x80 != 0 {
+		pos++
+		if pos >= len(header) {
+			return "", nil, errors.New("corrupt object header")
+		}
+	}
+	pos++
+
+	var baseType object.Type
+	var base []byte
+	switch typeCode {
+	case packOfsDelta:
+		distance := uint64(header[pos] & 0x7f)
+		for header[pos]&0x80 != 0 {
+			pos++
+			if pos >= len(header) {
+				return "", nil, errors.New("corrupt delta offset")
+			}
+			distance = ((distance + 1) << 7) | uint64(header[pos]&0x7f)
+		}
+		pos++
+		if distance > offset {
+			return "", nil, errors.New("delta base offset out of range")
+		}
+		if baseType, base, err = p.readAt(offset - distance); err != nil {
+			return "", nil, err
+		}
+	case packRefDelta:
+		size := p.repo.Format.Size
+		if pos+size > len(header) {
+			return "", nil, errors.New("corrupt delta base")
+		}
+		if baseType, base, err = p.repo.ReadObject(hex.EncodeToString(header[pos : pos+size])); err != nil {
+			return "", nil, err
+		}
+		pos += size
+	}
+
+	zr, err := zlib.NewReader(io.NewSectionReader(p.file, int64(offset)+int64(pos), 1<<62))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decompress object: %w", err)
+	}
+	defer zr.Close()
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decompress object: %w", err)
+	}
+
+	switch typeCode {
+	case packCommit:
+		return object.CommitObject, data, nil
+	case packTree:
+		return object.TreeObject, data, nil
+	case packBlob:
+		return object.BlobObject, data, nil
+	case packTag:
+		return object.TagObject, data, nil
+	case packOfsDelta, packRefDelta:
+		content, err := applyDelta(base, data)
+		return baseType, content, err
+	default:
+		return "", nil, fmt.Errorf("unsupported pack object type %d", typeCode)
+	}
+}
+
+// headerAt reads enough bytes at offset to hold an entry's header, opening
+// the pack on first use
+func (p *packFile) headerAt(offset uint64) ([]byte, error) {
+	if p.file == nil {
+		f, err := p.repo.openObjectFile(p.path)
+		if err != nil {
+			return nil, err
+		}
+		p.file = f
+	}
+
+	header := make([]byte, 12+p.repo.Format.Size)
+	n, err := p.file.ReadAt(header, int64(offset))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if n == 0 {
+		return nil, errors.New("offset past end of pack")
+	}
+	return header[:n], nil
+}
+
+// typeAt returns the type of the object stored at offset, following a
+// delta to its base without inflating either
+func (p *packFile) typeAt(offset uint64) (object.Type, error) {
+	header, err := p.headerAt(offset)
+	if err != nil {
+		return "", err
+	}
+	typeCode := (header[0] >> 4) & 0x07
+	pos := 0
+	for header[pos]&0x80 != 0 {
+		pos++
+		if pos >= len(header) {
+			return "", errors.New("corrupt object header")
+		}
+	}
+	pos++
+
+	switch typeCode {
+	case packOfsDelta:
+		distance := uint64(header[pos] & 0x7f)
+		for header[pos]&0x80 != 0 {
+			pos++
+			if pos >= len(header) {
+				return "", errors.New("corrupt delta offset")
+			}
+			distance = ((distance + 1) << 7) | uint64(header[pos]&0x7f)
+		}
+		if distance > offset {
+			return "", errors.New("delta base offset out of range")
+		}
+		return p.typeAt(offset - distance)
+	case packRefDelta:
+		size := p.repo.Format.Size
+		if pos+size > len(header) {
+			return "", errors.New("corrupt delta base")
+		}
+		baseType, _, err := p.repo.ReadObject(hex.EncodeToString(header[pos : pos+size]))
+		return baseType, err
+	}
+	for objectType, code := range packTypes {
+		if code == typeCode {
+			return objectType, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported pack object type %d", typeCode)
+}
+
+// sizeAt returns the inflated size of the object stored at offset without
+// reading the object. A delta records the size of the object it produces at
+// the start of its data, so only those few bytes are inflated.
+func (p *packFile) sizeAt(offset uint64) (int64, error) {
+	header, err := p.headerAt(offset)
+	if err != nil {
+		return 0, err
+	}
+
+	typeCode := (header[0] >> 4) & 0x07
+	size := int64(header[0] & 0x0f)
+	pos, shift := 0, 4
+	for header[pos]&0x80 != 0 {
+		pos++
+		if pos >= len(header) {
+			return 0, errors.New("corrupt object header")
+		}
+		size |= int64(header[pos]&0x7f) << shift
+		shift += 7
+	}
+	pos++
+
+	switch typeCode {
+	case packOfsDelta:
+		for pos < len(header) && header[pos]&0x80 != 0 {
+			pos++
+		}
+		pos++
+	case packRefDelta:
+		pos += p.repo.Format.Size
+	default:
+		return size, nil
+	}
+	if pos > len(header) {
+		return 0, errors.New("corrupt delta header")
+	}
+
+	zr, err := zlib.NewReader(io.NewSectionReader(p.file, int64(offset)+int64(pos), 1<<62))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decompress object: %w", err)
+	}
+	defer zr.Close()
+	start := make([]byte, 20)
+	n, err := io.ReadFull(zr, start)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return 0, fmt.Errorf("failed to decompress object: %w", err)
+	}
+	_, used, err := readDeltaSize(start[:n])
+	if err != nil {
+		return 0, err
+	}
+	targetSize, _, err := readDeltaSize(start[used:n])
+	if err != nil {
+		return 0, err
+	}
+	return int64(targetSize), nil
+}
+
+// packObject is an object queued for writing into a pack
+type packObject struct {
+	sha      string
+	objType  object.Type
+	data     []byte
+	nameHash uint32
+	base     *packObject
+	delta    []byte
+	depth    int
+	offset   uint64
+	crc      uint32
+}
+
+// nameHash groups objects stored under similar file names, weighting the end of the name
+func nameHash(name string) uint32 {
+	var h uint32
+	for i := 0; i < len(name); i++ {
+		if name[i] == ' ' {
+			continue
+		}
+		h = (h >> 2) + (uint32(name[i]) << 24)
+	}
+	return h
+}
+
+// setNameHashes gives every object the name it has in the trees being packed,
+// so blobs of the same file delta against each other
+func (r *Repository) setNameHashes(objects []*packObject) error {
+	byName := make(map[string]string)
+	for _, obj := range objects {
+		if obj.objType != object.TreeObject {
+			continue
+		}
+		entries, err := r.Format.ParseTree(obj.data)
+		if err != nil {
+			return fmt.Errorf("failed to parse tree %s: %w", obj.sha, err)
+		}
+		for _, entry := range entries {
+			byName[entry.SHA] = entry.Name
+		}
+	}
+	for _, obj := range objects {
+		obj.nameHash = nameHash(byName[obj.sha])
+	}
+	return nil
+}
+
+// PackOptions controls delta search when writing a pack
+type PackOptions struct {
+	Window       int
+	Depth        int
+	WindowMemory int64
+	Threads      int
+}
+
+// findDeltas picks a delta base for each object from a sliding window of similar objects.
+// The list is split into contiguous chunks searched in parallel, so results are deterministic.
+func findDeltas(objects []*packObject, opts PackOptions) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		a, b := objects[i], objects[j]
+		if a.objType != b.objType {
+			return a.objType < b.objType
+		}
+		if a.nameHash != b.nameHash {
+			return a.nameHash < b.nameHash
+		}
+		return len(a.data) > len(b.data)
+	})
+
+	threads := opts.Threads
+	if threads < 1 {
+		threads = 1
+	}
+	chunk := (len(objects) + threads - 1) / threads
+	if chunk == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for start := 0; start < len(objects); start += chunk {
+		end := start + chunk
+		if end > len(objects) {
+			end = len(objects)
+		}
+		wg.Add(1)
+		go func(list []*packObject) {
+			defer wg.Done()
+			searchDeltaWindow(list, opts)
+		}(objects[start:end])
+	}
+	wg.Wait()
+}
+
+func searchDeltaWindow(objects []*packObject, opts PackOptions) {
+	for i, target := range objects {
+		if len(target.data) < 64 {
+			continue
+		}
+		maxSize := len(target.data)/2 - 20
+		var windowBytes int64
+		for j := i - 1; j >= 0 && j >= i-opts.Window; j-- {
+			candidate := objects[j]
+			windowBytes += int64(len(candidate.data))
+			if opts.WindowMemory > 0 && windowBytes > opts.WindowMemory {
+				break
+			}
+			if candidate.objType != target.objType || candidate.depth >= opts.Depth {
+				continue
+			}
+			delta := createDelta(candidate.data, target.data)
+			if len(delta) < maxSize {
+				target.base = candidate
+				target.delta = delta
+				target.depth = candidate.depth + 1
+				maxSize = len(delta)
+			}
+		}
+	}
+}
+
+// writePack stores objects as a pack plus index and returns the pack's name. Objects
+// must be ordered so every delta base precedes the objects that use it.
+func (r *Repository) writePack(objects []*packObject) (string, error) {
+	if err := r.checkWritable(); err != nil {
+		return "", err
+	}
+	if err := r.mkdirShared(r.gitPath(PackDir)); err != nil {
+		return "", fmt.Errorf("failed to create pack directory: %w", err)
+	}
+	tmp, err := createTemp(r.gitPath(PackDir), "tmp_pack_")
+	if err != nil {
+		return "", fmt.Errorf("failed to create pack: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	sealed, err := r.sealObjectFile(tmp)
+	if err != nil {
+		return "", fmt.Errorf("failed to write pack: %w", err)
+	}
+	packSum, err := encodePack(sealed, objects, r.Format, r.startProgress("Writing objects", len(objects)))
+	if err != nil {
+		return "", err
+	}
+	if err := sealed.Close(); err != nil {
+		return "", fmt.Errorf("failed to write pack: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write pack: %w", err)
+	}
+
+	name := "pack-" + hex.EncodeToString(packSum)
+	if err := writePackIndex(r.gitPath(PackDir, name+".idx"), objects, packSum, r.Format); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), r.gitPath(PackDir, name+".pack")); err != nil {
+		return "", fmt.Errorf("failed to install pack: %w", err)
+	}
+	if err := r.adjustSharedPerm(r.gitPath(PackDir, name+".pack")); err != nil {
+		return "", err
+	}
+	return name, r.adjustSharedPerm(r.gitPath(PackDir, name+".idx"))
+}
+
+// encodePack streams objects as a version 2 packfile and returns its checksum,
+// recording each object's offset and CRC for the index and advancing meter
+// as it goes
+func encodePack(w io.Writer, objects []*packObject, format *object.Format, meter *progress) ([]byte, error) {
+	hasher := format.New()
+	out := io.MultiWriter(w, hasher)
+
+	header := make([]byte, 12)
+	copy(header, "PACK")
+	binary.BigEndian.PutUint32(header[4:], 2)
+	binary.BigEndian.PutUint32(header[8:], uint32(len(objects)))
+	if _, err := out.Write(header); err != nil {
+		return nil, fmt.Errorf("failed to write pack: %w", err)
+	}
+	offset := uint64(len(header))
+
+	for _, obj := range objects {
+		typeCode, payload := packTypes[obj.objType], obj.data
+		if obj.base != nil {
+			typeCode, payload = packOfsDelta, obj.delta
+		}
+
+		// Type and size header
+		size := len(payload)
+		entry := []byte{typeCode<<4 | byte(size&0x0f)}
+		size >>= 4
+		for size > 0 {
+			entry[len(entry)-1] |= 0x80
+			entry = append(entry, byte(size&0x7f))
+			size >>= 7
+		}
+		if obj.base != nil {
+			distance := offset - obj.base.offset
+			encoded := []byte{byte(distance & 0x7f)}
+			for distance >>= 7; distance > 0; distance >>= 7 {
+				distance--
+				encoded = append([]byte{byte(0x80 | distance&0x7f)}, encoded...)
+			}
+			entry = append(entry, encoded...)
+		}
+
+		var compressed bytes.Buffer
+		zw := zlib.NewWriter(&compressed)
+		if _, err := zw.Write(payload); err != nil {
+			return nil, fmt.Errorf("failed to compress object: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to close compressor: %w", err)
+		}
+		entry = append(entry, compressed.Bytes()...)
+
+		obj.offset = offset
+		obj.crc = crc32.ChecksumIEEE(entry)
+		if _, err := out.Write(entry); err != nil {
+			return nil, fmt.Errorf("failed to write pack: %w", err)
+		}
+		offset += uint64(len(entry))
+		meter.add(1, int64(len(entry)))
+	}
+	meter.done()
+
+	packSum := hasher.Sum(nil)
+	if _, err := w.Write(packSum); err != nil {
+		return nil, fmt.Errorf("failed to write pack: %w", err)
+	}
+	return packSum, nil
+}
+
+// writePackIndex writes a version 2 index for the objects of a pack
+func writePackIndex(idxFile string, objects []*packObject, packSum []byte, format *object.Format) error {
+	sorted := make([]*packObject, len(objects))
+	copy(sorted, objects)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].sha < sorted[j].sha
+	})
+
+	var idx bytes.Buffer
+	idx.WriteString("\xfftOc")
+	binary.Write(&idx, binary.BigEndian, uint32(2))
+
+	var fanout [256]uint32
+	for _, obj := range sorted {
+		first, _ := strconv.ParseUint(obj.sha[:2], 16, 8)
+		for b := int(first); b < 256; b++ {
+			fanout[b]++
+		}
+	}
+	binary.Write(&idx, binary.BigEndian, fanout)
+
+	for _, obj := range sorted {
+		shaBytes, _ := hex.DecodeString(obj.sha)
+		idx.Write(shaBytes)
+	}
+	for _, obj := range sorted {
+		binary.Write(&idx, binary.BigEndian, obj.crc)
+	}
+	var large []uint64
+	for _, obj := range sorted {
+		if obj.offset >= 0x80000000 {
+			binary.Write(&idx, binary.BigEndian, uint32(0x80000000|len(large)))
+			large = append(large, obj.offset)
+		} else {
+			binary.Write(&idx, binary.BigEndian, uint32(obj.offset))
+		}
+	}
+	for _, offset := range large {
+		binary.Write(&idx, binary.BigEndian, offset)
+	}
+	idx.Write(packSum)
+	idxSum := format.New()
+	idxSum.Write(idx.Bytes())
+	idx.Write(idxSum.Sum(nil))
+
+	if err := writeFileAtomic(idxFile, idx.Bytes()); err != nil {
+		return fmt.Errorf("failed to write pack index: %w", err)
+	}
+	return nil
+}
+
+// indexPack parses a packfile received from elsewhere, resolving deltas so
+// every object's SHA, offset and CRC are known
+func (r *Repository) indexPack(data []byte) ([]*packObject, error) {
+	if len(data) < 12+r.Format.Size || string(data[:4]) != "PACK" {
+		return nil, errors.New("not a packfile")
+	}
+	body := data[:len(data)-r.Format.Size]
+	sum := r.Format.New()
+	sum.Write(body)
+	if !bytes.Equal(sum.Sum(nil), data[len(body):]) {
+		return nil, errors.New("pack checksum mismatch")
+	}
+	if version := binary.BigEndian.Uint32(data[4:]); version != 2 && version != 3 {
+		return nil, fmt.Errorf("unsupported pack version %d", version)
+	}
+
+	count := binary.BigEndian.Uint32(data[8:])
+	objects := make([]*packObject, 0, count)
+	byOffset := make(map[uint64]*packObject)
+	bySHA := make(map[string]*packObject)
+	reader := bytes.NewReader(body[12:])
+	offset := uint64(12)
+	for i := uint32(0); i < count; i++ {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return nil, errors.New("truncated pack")
+		}
+		typeCode := (c >> 4) & 0x07
+		for c&0x80 != 0 {
+			if c, err = reader.ReadByte(); err != nil {
+				return nil, errors.New("truncated pack")
+			}
+		}
+
+		var baseType object.Type
+		var base []byte
+		switch typeCode {
+		case packOfsDelta:
+			c, err := reader.ReadByte()
+			if err != nil {
+				return nil, errors.New("truncated pack")
+			}
+			distance := uint64(c & 0x7f)
+			for c&0x80 != 0 {
+				if c, err = reader.ReadByte(); err != nil {
+					return nil, errors.New("truncated pack")
+				}
+				distance = ((distance + 1) << 7) | uint64(c&0x7f)
+			}
+			baseObj, ok := byOffset[offset-distance]
+			if distance > offset || !ok {
+				return nil, fmt.Errorf("delta base at offset %d not found", offset-distance)
+			}
+			baseType, base = baseObj.objType, baseObj.data
+		case packRefDelta:
+			raw := make([]byte, r.Format.Size)
+			if _, err := io.ReadFull(reader, raw); err != nil {
+				return nil, errors.New("truncated pack")
+			}
+			baseSHA := hex.EncodeToString(raw)
+			if baseObj, ok := bySHA[baseSHA]; ok {
+				baseType, base = baseObj.objType, baseObj.data
+			} else if baseType, base, err = r.ReadObject(baseSHA); err != nil {
+				return nil, fmt.Errorf("delta base %s not found: %w", baseSHA, err)
+			}
+		}
+
+		zr, err := zlib.NewReader(reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress object: %w", err)
+		}
+		payload, err := io.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress object: %w", err)
+		}
+		zr.Close()
+		end := uint64(len(body) - reader.Len())
+
+		obj := &packObject{offset: offset, crc: crc32.ChecksumIEEE(body[offset:end])}
+		switch typeCode {
+		case packCommit:
+			obj.objType, obj.data = object.CommitObject, payload
+		case packTree:
+			obj.objType, obj.data = object.TreeObject, payload
+		case packBlob:
+			obj.objType, obj.data = object.BlobObject, payload
+		case packTag:
+			obj.objType, obj.data = object.TagObject, payload
+		case packOfsDelta, packRefDelta:
+			if obj.data, err = applyDelta(base, payload); err != nil {
+				return nil, err
+			}
+			obj.objType = baseType
+		default:
+			return nil, fmt.Errorf("unsupported pack object type %d", typeCode)
+		}
+		obj.sha = r.Format.Hash(obj.objType, obj.data)
+
+		objects = append(objects, obj)
+		byOffset[offset] = obj
+		bySHA[obj.sha] = obj
+		offset = end
+	}
+	return objects, nil
+}
+
+// installPack verifies a packfile, writes its index and adds it to the object store
+func (r *Repository) installPack(data []byte) (string, []*packObject, error) {
+	objects, err := r.indexPack(data)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := r.mkdirShared(r.gitPath(PackDir)); err != nil {
+		return "", nil, fmt.Errorf("failed to create pack directory: %w", err)
+	}
+
+	packSum := data[len(data)-r.Format.Size:]
+	name := "pack-" + hex.EncodeToString(packSum)
+	if err := r.writeObjectFile(r.gitPath(PackDir, name+".pack"), data); err != nil {
+		return "", nil, fmt.Errorf("failed to install pack: %w", err)
+	}
+	if err := writePackIndex(r.gitPath(PackDir, name+".idx"), objects, packSum, r.Format); err != nil {
+		return "", nil, err
+	}
+	if err := r.adjustSharedPerm(r.gitPath(PackDir, name+".idx")); err != nil {
+		return "", nil, err
+	}
+	r.resetPacks()
+	return name, objects, nil
+}
@@ -0,0 +1,504 @@
+package gvc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/diff"
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// PatchContext is the number of unchanged lines shown around each change
+const PatchContext = 3
+
+// FileChange is a path whose entry differs between two snapshots. Old is nil
+// when the path was added and New is nil when it was deleted. A rename found
+// by DetectRenames records the path it came from in OldPath.
+type FileChange struct {
+	Path       string
+	Old        *IndexEntry
+	New        *IndexEntry
+	OldPath    string // set for a rename
+	Similarity int    // percentage of a rename's content kept
+
+	// Set by IgnoreSubmodules for a submodule whose working tree has
+	// changes of its own; its patch shows the commit as <sha>-dirty
+	ModifiedContent  bool
+	UntrackedContent bool
+}
+
+// DiffTrees lists the paths that differ between two trees, sorted by path.
+// An empty tree SHA stands for the empty tree. Subtrees with the same SHA
+// on both sides are not read at all.
+func (r *Repository) DiffTrees(oldTree, newTree string) ([]FileChange, error) {
+	var changes []FileChange
+	if err := r.diffTreeLevel(oldTree, newTree, "", &changes); err != nil {
+		return nil, err
+	}
+	sortChanges(changes)
+	return changes, nil
+}
+
+// diffTreeLevel adds the changes between the trees at dir to changes,
+// going into the subtrees that differ
+func (r *Repository) diffTreeLevel(oldTree, newTree, dir string, changes *[]FileChange) error {
+	if oldTree == newTree {
+		return nil
+	}
+	var oldEntries, newEntries []object.TreeEntry
+	var err error
+	if oldTree != "" {
+		if oldEntries, err = r.readTreeEntries(oldTree); err != nil {
+			return err
+		}
+	}
+	if newTree != "" {
+		if newEntries, err = r.readTreeEntries(newTree); err != nil {
+			return err
+		}
+	}
+	newByName := make(map[string]object.TreeEntry, len(newEntries))
+	for _, entry := range newEntries {
+		newByName[entry.Name] = entry
+	}
+	oldByName := make(map[string]object.TreeEntry, len(oldEntries))
+	for _, oldEntry := range oldEntries {
+		oldByName[oldEntry.Name] = oldEntry
+		path := joinTreePath(dir, oldEntry.Name)
+		newEntry, ok := newByName[oldEntry.Name]
+		oldIsTree, newIsTree := oldEntry.Type == object.TreeObject, newEntry.Type == object.TreeObject
+		switch {
+		case ok && oldIsTree && newIsTree:
+			err = r.diffTreeLevel(oldEntry.SHA, newEntry.SHA, path, changes)
+		case ok && !oldIsTree && !newIsTree:
+			if oldEntry.SHA != newEntry.SHA || oldEntry.Mode != newEntry.Mode {
+				*changes = append(*changes, FileChange{
+					Path: path,
+					Old:  &IndexEntry{Path: path, SHA: oldEntry.SHA, Mode: oldEntry.Mode},
+					New:  &IndexEntry{Path: path, SHA: newEntry.SHA, Mode: newEntry.Mode},
+				})
+			}
+		default:
+			err = r.diffTreeSide(oldEntry, path, true, changes)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, newEntry := range newEntries {
+		oldEntry, ok := oldByName[newEntry.Name]
+		if ok && (oldEntry.Type == object.TreeObject) == (newEntry.Type == object.TreeObject) {
+			continue
+		}
+		if err := r.diffTreeSide(newEntry, joinTreePath(dir, newEntry.Name), false, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// diffTreeSide adds the entry at path, or every file below it for a tree,
+// to changes as deleted, or as added when deleted is false
+func (r *Repository) diffTreeSide(entry object.TreeEntry, path string, deleted bool, changes *[]FileChange) error {
+	add := func(file IndexEntry) {
+		if deleted {
+			*changes = append(*changes, FileChange{Path: file.Path, Old: &file})
+		} else {
+			*changes = append(*changes, FileChange{Path: file.Path, New: &file})
+		}
+	}
+	if entry.Type != object.TreeObject {
+		add(IndexEntry{Path: path, SHA: entry.SHA, Mode: entry.Mode})
+		return nil
+	}
+	walker := r.WalkTree(entry.SHA, TreeWalkOptions{})
+	for file, ok := walker.Next(); ok; file, ok = walker.Next() {
+		add(IndexEntry{Path: path + "/" + file.Path, SHA: file.SHA, Mode: file.Mode})
+	}
+	return walker.Err()
+}
+
+// diffEntries compares two flattened snapshots
+func diffEntries(oldEntries, newEntries map[string]IndexEntry) []FileChange {
+	var changes []FileChange
+	for path, oldEntry := range oldEntries {
+		newEntry, ok := newEntries[path]
+		if sameEntry(oldEntry, newEntry, true, ok) {
+			continue
+		}
+		change := FileChange{Path: path, Old: &oldEntry}
+		if ok {
+			change.New = &newEntry
+		}
+		changes = append(changes, change)
+	}
+	for path, newEntry := range newEntries {
+		if _, ok := oldEntries[path]; !ok {
+			changes = append(changes, FileChange{Path: path, New: &newEntry})
+		}
+	}
+	sortChanges(changes)
+	return changes
+}
+
+// sortChanges orders changes by path
+func sortChanges(changes []FileChange) {
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+}
+
+// PatchOptions controls how WritePatchWithOptions shows changes
+type PatchOptions struct {
+	// SubmoduleLog summarizes a changed submodule by the commits between
+	// its old and new commit instead of a "Subproject commit" patch
+	SubmoduleLog bool
+	// SrcPrefix and DstPrefix, when either is set, replace the "a/" and "b/"
+	// before the old and new paths; NoPrefix leaves both out
+	SrcPrefix, DstPrefix string
+	NoPrefix             bool
+	// Relative leaves out the changes outside this directory and shows the
+	// paths of the others relative to it
+	Relative string
+}
+
+// DiffPatchOptions returns the options diff.srcPrefix, diff.dstPrefix,
+// diff.noPrefix and diff.relative set; the last makes paths relative to the
+// current directory
+func (r *Repository) DiffPatchOptions() (PatchOptions, error) {
+	var opts PatchOptions
+	var err error
+	if opts.SrcPrefix, err = r.getConfigString("diff.srcPrefix", "a/"); err != nil {
+		return opts, err
+	}
+	if opts.DstPrefix, err = r.getConfigString("diff.dstPrefix", "b/"); err != nil {
+		return opts, err
+	}
+	if opts.NoPrefix, err = r.getConfigBool("diff.noPrefix", false); err != nil {
+		return opts, err
+	}
+	relative, err := r.getConfigBool("diff.relative", false)
+	if err != nil || !relative {
+		return opts, err
+	}
+	opts.Relative, err = r.RelPath(".")
+	return opts, err
+}
+
+// Includes reports whether a patch written with opts shows path, a path in
+// the working tree
+func (opts PatchOptions) Includes(path string) bool {
+	_, ok := opts.displayPath(path)
+	return ok
+}
+
+// displayPath returns how path is shown: relative to opts.Relative, and
+// whether it is inside it at all. A path outside it is shown whole.
+func (opts PatchOptions) displayPath(path string) (string, bool) {
+	dir := strings.Trim(opts.Relative, "/")
+	if dir == "" || dir == "." {
+		return path, true
+	}
+	if rel, ok := strings.CutPrefix(path, dir+"/"); ok {
+		return rel, true
+	}
+	return path, false
+}
+
+// prefixes returns what comes before the old and new paths in a patch
+func (opts PatchOptions) prefixes() (string, string) {
+	switch {
+	case opts.NoPrefix:
+		return "", ""
+	case opts.SrcPrefix == "" && opts.DstPrefix == "":
+		return "a/", "b/"
+	}
+	return opts.SrcPrefix, opts.DstPrefix
+}
+
+// WritePatch writes changes as a unified diff in Git's format. A path that
+// changed type, such as a file replaced by a symlink, is shown as a deletion
+// followed by a creation.
+func (r *Repository) WritePatch(w io.Writer, changes []FileChange) error {
+	return r.WritePatchWithOptions(w, changes, PatchOptions{})
+}
+
+// WritePatchWithOptions is WritePatch showing changes as opts asks
+func (r *Repository) WritePatchWithOptions(w io.Writer, changes []FileChange, opts PatchOptions) error {
+	write := func(change FileChange) error {
+		if opts.SubmoduleLog && change.isSubmodule() {
+			name, _ := opts.displayPath(change.Path)
+			return r.writeSubmoduleLog(w, change, name)
+		}
+		return r.writeFilePatch(w, change, opts)
+	}
+	for _, change := range changes {
+		if !opts.Includes(change.Path) {
+			continue
+		}
+		if change.Old != nil && change.New != nil && modeType(change.Old.Mode) != modeType(change.New.Mode) {
+			if err := write(FileChange{Path: change.Path, Old: change.Old}); err != nil {
+				return err
+			}
+			change.Old = nil
+		}
+		if err := write(change); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// modeType returns the kind of object a tree mode records: regular and
+// executable files share a kind, while symlinks and submodules have their own
+func modeType(mode string) string {
+	switch mode {
+	case SymlinkMode, GitlinkMode:
+		return mode
+	}
+	return "100644"
+}
+
+// writeFilePatch writes the header and hunks for one changed path
+func (r *Repository) writeFilePatch(w io.Writer, change FileChange, opts PatchOptions) error {
+	newPath, _ := opts.displayPath(change.Path)
+	oldPath := newPath
+	if change.OldPath != "" {
+		oldPath, _ = opts.displayPath(change.OldPath)
+	}
+	src, dst := opts.prefixes()
+	oldName, newName := src+oldPath, dst+newPath
+	var header strings.Builder
+	fmt.Fprintf(&header, "diff --git %s %s\n", oldName, newName)
+
+	oldSHA, newSHA := r.Format.ZeroSHA(), r.Format.ZeroSHA()
+	switch {
+	case change.Old == nil:
+		newSHA = change.New.SHA
+		fmt.Fprintf(&header, "new file mode %s\n", change.New.Mode)
+		oldName = "/dev/null"
+	case change.New == nil:
+		oldSHA = change.Old.SHA
+		fmt.Fprintf(&header, "deleted file mode %s\n", change.Old.Mode)
+		newName = "/dev/null"
+	default:
+		oldSHA, newSHA = change.Old.SHA, change.New.SHA
+		if change.Old.Mode != change.New.Mode {
+			fmt.Fprintf(&header, "old mode %s\nnew mode %s\n", change.Old.Mode, change.New.Mode)
+		}
+		if change.OldPath != "" {
+			fmt.Fprintf(&header, "similarity index %d%%\nrename from %s\nrename to %s\n", change.Similarity, oldPath, newPath)
+		}
+	}
+	if oldSHA != newSHA {
+		fmt.Fprintf(&header, "index %s..%s", oldSHA[:7], newSHA[:7])
+		if change.Old != nil && change.New != nil && change.Old.Mode == change.New.Mode {
+			fmt.Fprintf(&header, " %s", change.New.Mode)
+		}
+		header.WriteString("\n")
+	}
+	if _, err := io.WriteString(w, header.String()); err != nil {
+		return err
+	}
+	if oldSHA == newSHA && !change.dirty() {
+		return nil
+	}
+
+	oldContent, newContent, err := r.changeContents(change)
+	if err != nil {
+		return err
+	}
+	if isBinary(oldContent) || isBinary(newContent) {
+		_, err := fmt.Fprintf(w, "Binary files %s and %s differ\n", oldName, newName)
+		return err
+	}
+
+	hunks := diff.Hunks(diff.Lines(diff.SplitLines(oldContent), diff.SplitLines(newContent)), PatchContext)
+	if len(hunks) == 0 {
+		return nil
+	}
+	if _, err := fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName); err != nil {
+		return err
+	}
+	for _, hunk := range hunks {
+		if _, err := fmt.Fprintln(w, hunk.Header()); err != nil {
+			return err
+		}
+		for _, op := range hunk.Ops {
+			line := string(op.Kind) + op.Line
+			if !strings.HasSuffix(op.Line, "\n") {
+				line += "\n\\ No newline at end of file\n"
+			}
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// changeContents returns the content a patch shows on each side of a change
+func (r *Repository) changeContents(change FileChange) ([]byte, []byte, error) {
+	oldContent, err := r.patchContent(change.Old)
+	if err != nil {
+		return nil, nil, err
+	}
+	newContent, err := r.patchContent(change.New)
+	if err != nil {
+		return nil, nil, err
+	}
+	if change.dirty() && change.New != nil && change.New.Mode == GitlinkMode {
+		newContent = []byte("Subproject commit " + change.New.SHA + "-dirty\n")
+	}
+	return oldContent, newContent, nil
+}
+
+// patchContent returns the content a patch shows for an entry; submodules show the commit they point to
+func (r *Repository) patchContent(entry *IndexEntry) ([]byte, error) {
+	if entry == nil {
+		return nil, nil
+	}
+	if entry.Mode == GitlinkMode {
+		return []byte("Subproject commit " + entry.SHA + "\n"), nil
+	}
+	_, content, err := r.ReadObject(entry.SHA)
+	if err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// isBinary reports whether content looks binary the way Git decides: a NUL
+// byte in its first 8000 bytes
+func isBinary(content []byte) bool {
+	return bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0
+}
+
+// renameDisplay names a rename the way diffstats do, braces around the part
+// that changed: "src/{old.go => new.go}"
+func renameDisplay(oldPath, newPath string) string {
+	// The shared prefix ends at a slash and the shared suffix starts at one;
+	// the suffix may reuse the prefix's slash, as in "a/{ => b}/c"
+	prefix := 0
+	for i := 0; i < len(oldPath) && i < len(newPath) && oldPath[i] == newPath[i]; i++ {
+		if oldPath[i] == '/' {
+			prefix = i + 1
+		}
+	}
+	stop := prefix
+	if prefix > 0 {
+		stop--
+	}
+	suffix := 0
+	for i, j := len(oldPath)-1, len(newPath)-1; i >= stop && j >= stop && oldPath[i] == newPath[j]; i, j = i-1, j-1 {
+		if oldPath[i] == '/' {
+			suffix = len(oldPath) - i
+		}
+	}
+	if prefix == 0 && suffix == 0 {
+		return oldPath + " => " + newPath
+	}
+	oldMid := max(len(oldPath)-prefix-suffix, 0)
+	newMid := max(len(newPath)-prefix-suffix, 0)
+	return oldPath[:prefix] + "{" + oldPath[prefix:prefix+oldMid] + " => " +
+		newPath[prefix:prefix+newMid] + "}" + oldPath[len(oldPath)-suffix:]
+}
+
+// diffStatWidth is the most +/- characters a diffstat line draws
+const diffStatWidth = 50
+
+// WriteDiffStat writes a diffstat of changes: a line per path with its count
+// of changed lines and a +/- graph, then a summary line
+func (r *Repository) WriteDiffStat(w io.Writer, changes []FileChange) error {
+	return r.WriteDiffStatWithOptions(w, changes, PatchOptions{})
+}
+
+// WriteDiffStatWithOptions writes a diffstat of the changes opts.Relative
+// includes, with their paths shown as it says
+func (r *Repository) WriteDiffStatWithOptions(w io.Writer, changes []FileChange, opts PatchOptions) error {
+	type stat struct {
+		path               string
+		added, deleted     int
+		binary             bool
+		oldBytes, newBytes int
+	}
+	stats := make([]stat, 0, len(changes))
+	nameWidth, maxChanged := 0, 0
+	totalAdded, totalDeleted := 0, 0
+	for _, change := range changes {
+		path, ok := opts.displayPath(change.Path)
+		if !ok {
+			continue
+		}
+		oldContent, newContent, err := r.changeContents(change)
+		if err != nil {
+			return err
+		}
+		s := stat{path: path, oldBytes: len(oldContent), newBytes: len(newContent)}
+		if change.OldPath != "" {
+			oldPath, _ := opts.displayPath(change.OldPath)
+			s.path = renameDisplay(oldPath, path)
+		}
+		if isBinary(oldContent) || isBinary(newContent) {
+			s.binary = true
+		} else {
+			for _, op := range diff.Lines(diff.SplitLines(oldContent), diff.SplitLines(newContent)) {
+				switch op.Kind {
+				case '+':
+					s.added++
+				case '-':
+					s.deleted++
+				}
+			}
+		}
+		totalAdded += s.added
+		totalDeleted += s.deleted
+		nameWidth = max(nameWidth, len(s.path))
+		maxChanged = max(maxChanged, s.added+s.deleted)
+		stats = append(stats, s)
+	}
+
+	countWidth := len(fmt.Sprint(maxChanged))
+	for _, s := range stats {
+		if s.binary {
+			if _, err := fmt.Fprintf(w, " %-*s | Bin %d -> %d bytes\n", nameWidth, s.path, s.oldBytes, s.newBytes); err != nil {
+				return err
+			}
+			continue
+		}
+		added, deleted := s.added, s.deleted
+		if maxChanged > diffStatWidth {
+			// Scale the graph down, keeping at least one mark for any change
+			added = (added*diffStatWidth + maxChanged - 1) / maxChanged
+			deleted = (deleted*diffStatWidth + maxChanged - 1) / maxChanged
+		}
+		graph := strings.Repeat("+", added) + strings.Repeat("-", deleted)
+		line := strings.TrimRight(fmt.Sprintf(" %-*s | %*d %s", nameWidth, s.path, countWidth, s.added+s.deleted, graph), " ")
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+
+	summary := fmt.Sprintf(" %d file", len(stats))
+	if len(stats) != 1 {
+		summary += "s"
+	}
+	summary += " changed"
+	if totalAdded > 0 || totalDeleted == 0 {
+		summary += fmt.Sprintf(", %d insertion", totalAdded)
+		if totalAdded != 1 {
+			summary += "s"
+		}
+		summary += "(+)"
+	}
+	if totalDeleted > 0 || totalAdded == 0 {
+		summary += fmt.Sprintf(", %d deletion", totalDeleted)
+		if totalDeleted != 1 {
+			summary += "s"
+		}
+		summary += "(-)"
+	}
+	_, err := fmt.Fprintln(w, summary)
+	return err
+}
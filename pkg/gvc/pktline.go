@@ -0,0 +1,143 @@
+package gvc
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// pktMax is the largest pkt-line, length prefix included
+const pktMax = 65520
+
+// Side-band channels: pack data, progress messages and a fatal error
+const (
+	bandData     = 1
+	bandProgress = 2
+	bandError    = 3
+)
+
+// writePkt writes data as one pkt-line: its length, prefix included, in four
+// hex digits, then data
+func writePkt(w io.Writer, data []byte) error {
+	if len(data)+4 > pktMax {
+		return fmt.Errorf("pkt-line of %d bytes is too long", len(data))
+	}
+	if _, err := fmt.Fprintf(w, "%04x%s", len(data)+4, data); err != nil {
+		return fmt.Errorf("failed to write pkt-line: %w", err)
+	}
+	return nil
+}
+
+// writePktf writes a formatted pkt-line
+func writePktf(w io.Writer, format string, args ...any) error {
+	return writePkt(w, []byte(fmt.Sprintf(format, args...)))
+}
+
+// writeFlush writes the flush-pkt that ends a section
+func writeFlush(w io.Writer) error {
+	if _, err := io.WriteString(w, "0000"); err != nil {
+		return fmt.Errorf("failed to write pkt-line: %w", err)
+	}
+	return nil
+}
+
+// readPkt reads one pkt-line, reporting a flush-pkt as flush with no data
+func readPkt(r io.Reader) (data []byte, flush bool, err error) {
+	var prefix [4]byte
+	if _, err := io.ReadFull(r, prefix[:]); err != nil {
+		if err == io.EOF {
+			return nil, false, io.ErrUnexpectedEOF
+		}
+		return nil, false, fmt.Errorf("failed to read pkt-line: %w", err)
+	}
+	size, err := strconv.ParseUint(string(prefix[:]), 16, 16)
+	if err != nil {
+		return nil, false, fmt.Errorf("bad pkt-line length %q", prefix[:])
+	}
+	switch {
+	case size == 0:
+		return nil, true, nil
+	case size < 4 || size > pktMax:
+		return nil, false, fmt.Errorf("bad pkt-line length %d", size)
+	}
+	data = make([]byte, size-4)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, false, fmt.Errorf("failed to read pkt-line: %w", err)
+	}
+	return data, false, nil
+}
+
+// readPktLine reads a pkt-line holding text, without its trailing newline.
+// A line starting with "ERR " is the other side giving up, returned as an
+// error.
+func readPktLine(r io.Reader) (line string, flush bool, err error) {
+	data, flush, err := readPkt(r)
+	if err != nil || flush {
+		return "", flush, err
+	}
+	line = strings.TrimSuffix(string(data), "\n")
+	if msg, ok := strings.CutPrefix(line, "ERR "); ok {
+		return "", false, fmt.Errorf("remote error: %s", msg)
+	}
+	return line, false, nil
+}
+
+// sidebandWriter sends what is written to it on one side-band channel, in
+// pkt-lines of at most size bytes
+type sidebandWriter struct {
+	w    io.Writer
+	band byte
+	size int
+}
+
+func (s *sidebandWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := min(len(p), s.size-5)
+		if err := writePkt(s.w, append([]byte{s.band}, p[:n]...)); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// sidebandReader reads the data channel of a side-band stream. Progress
+// messages go to progress, which may be nil to drop them, and an error
+// message ends the stream with that error.
+type sidebandReader struct {
+	r        io.Reader
+	progress io.Writer
+	pending  []byte
+	err      error
+}
+
+func (s *sidebandReader) Read(p []byte) (int, error) {
+	for len(s.pending) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		data, flush, err := readPkt(s.r)
+		switch {
+		case err != nil:
+			s.err = err
+		case flush || len(data) == 0:
+			s.err = io.EOF
+		case data[0] == bandData:
+			s.pending = data[1:]
+		case data[0] == bandProgress:
+			if s.progress != nil {
+				s.progress.Write(data[1:])
+			}
+		case data[0] == bandError:
+			s.err = fmt.Errorf("remote error: %s", strings.TrimSpace(string(data[1:])))
+		default:
+			s.err = fmt.Errorf("bad side-band channel %d", data[0])
+		}
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	return n, nil
+}
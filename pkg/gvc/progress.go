@@ -0,0 +1,97 @@
+package gvc
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// progressDelay is how long an operation runs before its meter appears, so
+// quick ones print nothing, and progressInterval how often it is redrawn
+const (
+	progressDelay    = 500 * time.Millisecond
+	progressInterval = 100 * time.Millisecond
+)
+
+// progress is a meter for a long operation, drawn on one line that is
+// rewritten as it advances, e.g.
+//
+//	Writing objects:  45% (450/1000), 1.20 MiB | 3.10 MiB/s
+//
+// A nil *progress draws nothing, so callers need not check whether progress
+// was asked for. It may be advanced from several goroutines.
+type progress struct {
+	mu      sync.Mutex
+	w       io.Writer
+	title   string
+	total   int   // 0 when not known up front
+	count   int   // items done
+	bytes   int64 // bytes handled, shown with throughput when non-zero
+	start   time.Time
+	delay   time.Duration // how long to wait before drawing the meter
+	drawn   time.Time     // when the line was last drawn; zero before the first time
+	lastLen int           // length of the last line drawn, to blank out leftovers
+}
+
+// startProgress begins a meter on r.Progress titled e.g. "Writing objects",
+// counting up to total items
+func (r *Repository) startProgress(title string, total int) *progress {
+	if r.Progress == nil {
+		return nil
+	}
+	p := &progress{w: r.Progress, title: title, total: total, start: time.Now(), delay: progressDelay}
+	if r.Verbose {
+		p.delay = 0
+	}
+	return p
+}
+
+// add records items and bytes done, redrawing the meter if it is due
+func (p *progress) add(items int, bytes int64) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.count += items
+	p.bytes += bytes
+	now := time.Now()
+	if now.Sub(p.start) < p.delay || now.Sub(p.drawn) < progressInterval {
+		return
+	}
+	p.draw(now, "")
+	p.drawn = now
+}
+
+// done draws the meter a last time and ends its line. Unless r.Verbose is
+// set, nothing is printed for an operation that finished before its meter
+// appeared.
+func (p *progress) done() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.drawn.IsZero() && p.delay > 0 {
+		return
+	}
+	p.draw(time.Now(), ", done.\n")
+}
+
+// draw rewrites the meter's line, followed by end
+func (p *progress) draw(now time.Time, end string) {
+	line := fmt.Sprintf("%s: %d", p.title, p.count)
+	if p.total > 0 {
+		line = fmt.Sprintf("%s: %3d%% (%d/%d)", p.title, p.count*100/p.total, p.count, p.total)
+	}
+	if p.bytes > 0 {
+		line += ", " + FormatSize(p.bytes)
+		if elapsed := now.Sub(p.start).Seconds(); elapsed > 0 {
+			line += " | " + FormatSize(int64(float64(p.bytes)/elapsed)) + "/s"
+		}
+	}
+	padding := max(p.lastLen-len(line), 0)
+	p.lastLen = len(line)
+	fmt.Fprintf(p.w, "\r%s%*s%s", line, padding, "", end)
+}
@@ -0,0 +1,153 @@
+package gvc
+
+import "strings"
+
+// PromptOptions controls PromptStatus
+type PromptOptions struct {
+	// Untracked also looks for untracked files, which means walking the
+	// working tree; the walk stops at the first one found
+	Untracked bool
+}
+
+// PromptStatus is a summary of the repository's state small enough to show
+// in a shell prompt
+type PromptStatus struct {
+	Branch string // the current branch, empty when HEAD is detached
+	Commit string // the commit HEAD points to, empty before the first commit
+	// Upstream is the remote-tracking ref the branch's upstream is fetched
+	// into, empty when it has none or it has not been fetched
+	Upstream      string
+	Ahead, Behind int    // commits only on the branch, and only on Upstream
+	Staged        bool   // the index differs from HEAD
+	Unstaged      bool   // a tracked file differs from the index
+	Untracked     bool   // there is an untracked file; only with PromptOptions.Untracked
+	Operation     string // the operation in progress, as OperationInProgress reports it
+}
+
+// PromptStatus returns the state a shell prompt shows, as cheaply as it can
+// be found: staged changes come from the index's cached top tree when it is
+// valid, working tree files are only hashed when their stat data no longer
+// matches the index, and every check stops at the first change it finds.
+// Submodules are not looked into.
+func (r *Repository) PromptStatus(opts PromptOptions) (*PromptStatus, error) {
+	status := &PromptStatus{Operation: r.OperationInProgress()}
+	headRef, err := r.HeadRef()
+	if err != nil {
+		return nil, err
+	}
+	status.Branch = strings.TrimPrefix(headRef, HeadsDir+"/")
+	if status.Commit, err = r.HeadCommit(); err != nil {
+		return nil, err
+	}
+
+	if status.Branch != "" && status.Commit != "" {
+		if err := r.promptUpstream(status); err != nil {
+			return nil, err
+		}
+	}
+
+	index, err := r.ReadIndex()
+	if err != nil {
+		return nil, err
+	}
+	if status.Staged, err = r.promptStaged(index, status.Commit); err != nil {
+		return nil, err
+	}
+
+	indexTime := r.indexModTime()
+	for _, entry := range index.Entries {
+		if entry.Mode == GitlinkMode {
+			continue
+		}
+		entry.Path = normalizePath(entry.Path)
+		working, ok, err := r.readWorkingEntryCached(entry.Path, entry, indexTime)
+		if err != nil {
+			return nil, err
+		}
+		if !sameEntry(working, entry, ok, true) {
+			status.Unstaged = true
+			break
+		}
+	}
+
+	if opts.Untracked {
+		err := r.walkUntracked(false, func(string) bool {
+			status.Untracked = true
+			return false
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// promptUpstream fills in the upstream of the current branch and how far
+// the branch has diverged from it
+func (r *Repository) promptUpstream(status *PromptStatus) error {
+	remote, merge, err := r.Upstream(status.Branch)
+	if err != nil || remote == "" {
+		return err
+	}
+	// A branch may track another local branch, with remote "."
+	tracking := merge
+	if remote != "." {
+		specs, err := r.fetchRefspecs(remote)
+		if err != nil {
+			return err
+		}
+		tracking = ""
+		for _, spec := range specs {
+			if dst, ok := spec.match(merge); ok {
+				tracking = dst
+				break
+			}
+		}
+	}
+	if tracking == "" {
+		return nil
+	}
+	upstream, err := r.ReadRef(tracking)
+	if err != nil || upstream == "" {
+		return err
+	}
+	status.Upstream = tracking
+	if upstream == status.Commit {
+		return nil
+	}
+	ahead, err := r.walkRevisions(&RevRange{Include: []string{status.Commit}, Exclude: []string{upstream}}, nil)
+	if err != nil {
+		return err
+	}
+	behind, err := r.walkRevisions(&RevRange{Include: []string{upstream}, Exclude: []string{status.Commit}}, nil)
+	if err != nil {
+		return err
+	}
+	status.Ahead, status.Behind = len(ahead), len(behind)
+	return nil
+}
+
+// promptStaged reports whether the index differs from the commit head
+func (r *Repository) promptStaged(index *Index, head string) (bool, error) {
+	if head == "" {
+		return len(index.Entries) > 0, nil
+	}
+	commit, err := r.ReadCommit(head)
+	if err != nil {
+		return false, err
+	}
+	// An index written since the last commit usually still has its top tree
+	if tree, ok := index.validTreeCache()[""]; ok {
+		return tree != commit.TreeSHA, nil
+	}
+	headEntries, err := r.headEntries()
+	if err != nil {
+		return false, err
+	}
+	staged := make(map[string]IndexEntry, len(index.Entries))
+	for _, entry := range index.Entries {
+		entry.Path = normalizePath(entry.Path)
+		staged[entry.Path] = entry
+	}
+	return len(diffEntries(headEntries, staged)) > 0, nil
+}
@@ -0,0 +1,497 @@
+package gvc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// Version is the version of gvc, set when building a release with
+// -ldflags "-X github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/gvc.Version=v1.2.3"
+var Version = "dev"
+
+// GitPort is the port of git:// URLs that name none, where gvc serve and
+// git daemon listen by default
+const GitPort = 9418
+
+// infiniteDepth is the depth a client asks for to get all the history a
+// shallow repository is missing, as Git does
+const infiniteDepth = 0x7fffffff
+
+// agent names gvc to the other side of a connection
+func agent() string {
+	return "gvc/" + Version
+}
+
+// capabilities lists what one side of a connection supports, as the first
+// ref of an advertisement and the first want end with: names, some with a
+// value after "=", e.g. "side-band-64k agent=git/2.43.0"
+type capabilities []string
+
+// get returns the value of a capability, "" for one without a value
+func (c capabilities) get(name string) (string, bool) {
+	for _, capability := range c {
+		if capability == name {
+			return "", true
+		}
+		if value, ok := strings.CutPrefix(capability, name+"="); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// has reports whether a capability is listed
+func (c capabilities) has(name string) bool {
+	_, ok := c.get(name)
+	return ok
+}
+
+// advertisement is what an upload-pack server offers: its refs, sorted by
+// name, the commit its HEAD is on, the branch HEAD is on ("" when detached,
+// unborn or not told) and its capabilities
+type advertisement struct {
+	refs       []Ref
+	head       string
+	headBranch string
+	caps       capabilities
+}
+
+// format returns the object format the server's refs are named in
+func (a *advertisement) format() (*object.Format, error) {
+	name, ok := a.caps.get("object-format")
+	if !ok {
+		return object.SHA1, nil
+	}
+	return object.ParseFormat(name)
+}
+
+// readAdvertisement reads the refs and capabilities a server starts with
+func readAdvertisement(rd io.Reader) (*advertisement, error) {
+	adv := &advertisement{}
+	for first := true; ; first = false {
+		line, flush, err := readPktLine(rd)
+		if err != nil {
+			return nil, err
+		}
+		if flush {
+			break
+		}
+		if first {
+			var caps string
+			line, caps, _ = strings.Cut(line, "\x00")
+			adv.caps = strings.Fields(caps)
+		}
+		sha, name, ok := strings.Cut(line, " ")
+		if !ok || (len(sha) != object.SHA1.HexSize() && len(sha) != object.SHA256.HexSize()) {
+			return nil, fmt.Errorf("bad ref advertisement line %q", line)
+		}
+		switch {
+		case name == "capabilities^{}":
+			// An empty repository only advertises its capabilities
+		case strings.HasSuffix(name, "^{}"):
+			// Peeled tags are not needed to fetch them
+		case name == "HEAD":
+			adv.head = sha
+		default:
+			adv.refs = append(adv.refs, Ref{Name: name, SHA: sha})
+		}
+	}
+	if symref, ok := adv.caps.get("symref"); ok {
+		if target, ok := strings.CutPrefix(symref, "HEAD:"); ok && adv.head != "" {
+			adv.headBranch = target
+		}
+	}
+	// A server that does not say which branch HEAD is on has it on one of
+	// the branches at the same commit, most likely main or master
+	if adv.headBranch == "" && adv.head != "" {
+		for _, ref := range adv.refs {
+			if ref.SHA != adv.head || !strings.HasPrefix(ref.Name, HeadsDir+"/") {
+				continue
+			}
+			if adv.headBranch == "" || ref.Name == HeadsDir+"/main" || ref.Name == HeadsDir+"/master" {
+				adv.headBranch = ref.Name
+			}
+		}
+	}
+	return adv, nil
+}
+
+// isGitURL reports whether url names a repository served over git://, by
+// gvc serve or git daemon
+func isGitURL(url string) bool {
+	return strings.HasPrefix(url, "git://")
+}
+
+// gitURLPath returns the repository path of a git:// URL, without a
+// trailing "/" or ".git", for naming a clone of it
+func gitURLPath(url string) string {
+	_, p, _ := strings.Cut(strings.TrimPrefix(url, "git://"), "/")
+	return strings.TrimSuffix(path.Clean("/"+p), ".git")
+}
+
+// gitConn is a connection to the upload-pack service of a git:// URL,
+// past the advertisement it starts with
+type gitConn struct {
+	net.Conn
+	adv  *advertisement
+	stop func() bool // stops closing the connection when the context is done
+}
+
+// Close closes the connection
+func (c *gitConn) Close() error {
+	c.stop()
+	return c.Conn.Close()
+}
+
+// dialUploadPack connects to the upload-pack service at a git:// URL and
+// reads its advertisement. The connection is closed once ctx is done, which
+// stops any read or write in progress.
+func dialUploadPack(ctx context.Context, url string) (*gitConn, error) {
+	host, repoPath, ok := strings.Cut(strings.TrimPrefix(url, "git://"), "/")
+	if !ok || host == "" {
+		return nil, fmt.Errorf("invalid git:// URL: %s", url)
+	}
+	address := host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		address = net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(GitPort))
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", host, err)
+	}
+	c := &gitConn{Conn: conn, stop: context.AfterFunc(ctx, func() { conn.Close() })}
+	// The request names the service, the repository and the host asked for
+	if err := writePktf(c, "git-upload-pack /%s\x00host=%s\x00", repoPath, host); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if c.adv, err = readAdvertisement(c); err != nil {
+		c.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("failed to read refs from %s: %w", url, err)
+	}
+	return c, nil
+}
+
+// lsRemote returns what the upload-pack service at a git:// URL advertises,
+// hanging up without fetching
+func lsRemote(ctx context.Context, url string) (*advertisement, error) {
+	conn, err := dialUploadPack(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if err := writeFlush(conn); err != nil {
+		return nil, err
+	}
+	return conn.adv, nil
+}
+
+// requestCapabilities picks what to ask of a server offering server: the
+// side-band-64k channels, or else side-band, ofs-delta and the agent when
+// offered, so a simpler or older server is still fetched from without them.
+// Only a shallow fetch needs the server to support shallow, and the server
+// must name its objects the way r does.
+func (r *Repository) requestCapabilities(server capabilities, shallow bool) (capabilities, error) {
+	var caps capabilities
+	switch {
+	case server.has("side-band-64k"):
+		caps = append(caps, "side-band-64k")
+	case server.has("side-band"):
+		caps = append(caps, "side-band")
+	}
+	if server.has("ofs-delta") {
+		caps = append(caps, "ofs-delta")
+	}
+	if shallow {
+		if !server.has("shallow") {
+			return nil, errors.New("the server does not support shallow clients")
+		}
+		caps = append(caps, "shallow")
+	}
+	// The server's progress messages are shown where r draws its meters
+	if r.Progress == nil && server.has("no-progress") {
+		caps = append(caps, "no-progress")
+	}
+	format, ok := server.get("object-format")
+	if !ok {
+		format = object.SHA1.Name
+	}
+	if format != r.Format.Name {
+		return nil, fmt.Errorf("the server uses %s objects but this repository uses %s", format, r.Format.Name)
+	}
+	if ok {
+		caps = append(caps, "object-format="+format)
+	}
+	if server.has("agent") {
+		caps = append(caps, "agent="+agent())
+	}
+	return caps, nil
+}
+
+// fetchGit fetches from the upload-pack service at a git:// URL the objects
+// of its refs that r lacks, as a pack, and returns the refs it offers, the
+// commit its HEAD is on and the branch HEAD is on. The refs r has are sent
+// as haves, so only what is new comes across. With a depth, or unshallow,
+// the server works out the new shallow commits, which are recorded; a
+// shallow r tells the server where its history stops. A dry run only reads
+// the advertisement.
+func (r *Repository) fetchGit(url string, opts FetchOptions, shallow bool) (refs []Ref, head, headBranch string, err error) {
+	if !opts.DryRun {
+		if err := r.checkWritable(); err != nil {
+			return nil, "", "", err
+		}
+	}
+	conn, err := dialUploadPack(r.Context, url)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer conn.Close()
+	adv := conn.adv
+	refs, head, headBranch = adv.refs, adv.head, adv.headBranch
+	if err := r.fetchPack(conn, adv, opts, shallow); err != nil {
+		if r.Context != nil && r.Context.Err() != nil {
+			return nil, "", "", r.Context.Err()
+		}
+		return nil, "", "", fmt.Errorf("failed to fetch from %s: %w", url, err)
+	}
+	return refs, head, headBranch, nil
+}
+
+// fetchPack runs the negotiation of fetchGit on a connection past the
+// advertisement
+func (r *Repository) fetchPack(conn io.ReadWriter, adv *advertisement, opts FetchOptions, shallow bool) error {
+	deepen := opts.Depth > 0 || opts.Unshallow
+	// Deepening needs the tips to count the depth from, even ones r has
+	var wants []string
+	for _, ref := range append(adv.refs, Ref{SHA: adv.head}) {
+		if ref.SHA != "" && !slices.Contains(wants, ref.SHA) && (deepen || !r.objectExists(ref.SHA)) {
+			wants = append(wants, ref.SHA)
+		}
+	}
+	if opts.DryRun || len(wants) == 0 {
+		return writeFlush(conn)
+	}
+	caps, err := r.requestCapabilities(adv.caps, deepen || shallow)
+	if err != nil {
+		return err
+	}
+	for i, want := range wants {
+		if i == 0 {
+			err = writePktf(conn, "want %s %s\n", want, strings.Join(caps, " "))
+		} else {
+			err = writePktf(conn, "want %s\n", want)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	current, err := r.shallowCommits()
+	if err != nil {
+		return err
+	}
+	for _, sha := range slices.Sorted(maps.Keys(current)) {
+		if err := writePktf(conn, "shallow %s\n", sha); err != nil {
+			return err
+		}
+	}
+	switch {
+	case opts.Unshallow:
+		err = writePktf(conn, "deepen %d\n", infiniteDepth)
+	case opts.Depth > 0:
+		err = writePktf(conn, "deepen %d\n", opts.Depth)
+	}
+	if err != nil {
+		return err
+	}
+	if err := writeFlush(conn); err != nil {
+		return err
+	}
+
+	// The server says where the history it sends stops
+	newShallow := make(map[string]bool, len(current))
+	for sha := range current {
+		newShallow[sha] = true
+	}
+	if deepen || len(current) > 0 {
+		for {
+			line, flush, err := readPktLine(conn)
+			if err != nil {
+				return err
+			}
+			if flush {
+				break
+			}
+			if sha, ok := strings.CutPrefix(line, "shallow "); ok {
+				newShallow[sha] = true
+			} else if sha, ok := strings.CutPrefix(line, "unshallow "); ok {
+				delete(newShallow, sha)
+			} else {
+				return fmt.Errorf("unexpected line %q in the shallow list", line)
+			}
+		}
+	}
+
+	haves, err := r.fetchHaves()
+	if err != nil {
+		return err
+	}
+	for _, sha := range haves {
+		if err := writePktf(conn, "have %s\n", sha); err != nil {
+			return err
+		}
+	}
+	if err := writePktf(conn, "done\n"); err != nil {
+		return err
+	}
+	// Without multi_ack the server answers once: ACK for the first commit
+	// both sides have, or NAK when there is none
+	line, _, err := readPktLine(conn)
+	if err != nil {
+		return err
+	}
+	if line != "NAK" && !strings.HasPrefix(line, "ACK ") {
+		return fmt.Errorf("unexpected acknowledgement %q", line)
+	}
+
+	var packReader io.Reader = conn
+	if caps.has("side-band") || caps.has("side-band-64k") {
+		band := &sidebandReader{r: conn}
+		if r.Progress != nil {
+			band.progress = &remoteWriter{w: r.Progress}
+		}
+		packReader = band
+	}
+	data, err := io.ReadAll(&meteredReader{r: packReader, meter: r.transfer})
+	if err != nil {
+		return err
+	}
+	if err := r.receivePack(data, newShallow); err != nil {
+		return err
+	}
+	return r.writeShallow(newShallow)
+}
+
+// remoteWriter passes on the progress messages of a server as they arrive,
+// each line prefixed with "remote: " the way Git shows them. Meters that
+// rewrite their line with carriage returns keep doing so.
+type remoteWriter struct {
+	w       io.Writer
+	midLine bool // the last message stopped inside a line
+}
+
+func (w *remoteWriter) Write(p []byte) (int, error) {
+	var b bytes.Buffer
+	for rest := p; len(rest) > 0; {
+		if !w.midLine {
+			b.WriteString("remote: ")
+		}
+		end := bytes.IndexAny(rest, "\r\n")
+		if end < 0 {
+			b.Write(rest)
+			w.midLine = true
+			break
+		}
+		b.Write(rest[:end+1])
+		rest = rest[end+1:]
+		w.midLine = false
+	}
+	if _, err := w.w.Write(b.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// fetchHaves lists the commits r's refs and HEAD are on, which a server
+// need not send again
+func (r *Repository) fetchHaves() ([]string, error) {
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	var haves []string
+	for _, ref := range append(refs, Ref{SHA: head}) {
+		if ref.SHA == "" || slices.Contains(haves, ref.SHA) {
+			continue
+		}
+		if sha, err := r.peelTag(ref.SHA); err == nil && r.objectExists(sha) {
+			objectType, _, err := r.ReadObject(sha)
+			if err == nil && objectType == object.CommitObject && !slices.Contains(haves, sha) {
+				haves = append(haves, sha)
+			}
+		}
+	}
+	return haves, nil
+}
+
+// receivePack installs a pack a server sent, for a history that stops at
+// the shallow commits. With fetch.fsckObjects its objects are checked, and
+// a pack that fails is removed again.
+func (r *Repository) receivePack(data []byte, shallow map[string]bool) error {
+	if len(data) >= 12 && string(data[8:12]) == "\x00\x00\x00\x00" {
+		return nil
+	}
+	name, objects, err := r.installPack(data)
+	if err != nil {
+		return err
+	}
+	var size int64
+	received := make(map[string]bool, len(objects))
+	for _, obj := range objects {
+		received[obj.sha] = true
+		size += int64(len(obj.data))
+	}
+	r.transfer.add(len(objects), size, 0, 0)
+	verify, err := r.fsckOnTransfer("fetch.fsckObjects")
+	if err != nil {
+		return err
+	}
+	if !verify {
+		return nil
+	}
+	if err := fsckTransfer(r, r, received, shallow); err != nil {
+		os.Remove(r.gitPath(PackDir, name+".pack"))
+		os.Remove(r.gitPath(PackDir, name+".idx"))
+		r.resetPacks()
+		return err
+	}
+	return nil
+}
+
+// meteredReader counts the bytes read through it on a transfer meter,
+// pacing them to its rate limit
+type meteredReader struct {
+	r     io.Reader
+	meter *transferMeter
+}
+
+func (m *meteredReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.meter.add(0, 0, int64(n), 0)
+	if err == nil {
+		err = m.meter.pace()
+	}
+	return n, err
+}
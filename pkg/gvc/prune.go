@@ -0,0 +1,216 @@
+package gvc
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// defaultPruneExpire is how long unreachable objects are kept by default, as in Git
+const defaultPruneExpire = "2.weeks.ago"
+
+// PruneOptions controls Prune
+type PruneOptions struct {
+	// Expire is the grace period: only unreachable objects older than this
+	// date, as ParseDate reads it, are deleted; "never" keeps them all. It
+	// defaults to gc.pruneExpire, or 2.weeks.ago.
+	Expire string
+	// DryRun only works out what would be deleted
+	DryRun bool
+}
+
+// PruneResult lists what Prune deleted, or would delete
+type PruneResult struct {
+	Objects   []PrunedObject // unreachable loose objects, sorted by SHA
+	TempFiles []string       // stale temporary files left by interrupted writes, relative to the repository directory
+	Locks     []string       // locks and writer files of commands that exited, likewise
+	Reclaimed int64          // bytes the objects and temporary files took on disk
+}
+
+// PrunedObject is an unreachable loose object Prune deletes
+type PrunedObject struct {
+	SHA  string
+	Type object.Type
+	Size int64 // bytes its file takes
+}
+
+// Prune deletes the loose objects nothing refers to: not HEAD, the refs,
+// their reflogs, the index, other worktrees or an operation in progress.
+// Only objects older than the grace period go, so one a concurrent command
+// has just written but not yet referred to is kept, along with recent
+// history a reset or amend left behind. Temporary files interrupted
+// commands left are removed too once past gc.tempExpire (default a day) or
+// the grace period, whichever is shorter, along with the locks of commands
+// that exited; a grace period of never still removes those. Packed objects
+// are left alone.
+func (r *Repository) Prune(opts PruneOptions) (*PruneResult, error) {
+	return r.prune(opts, nil)
+}
+
+// prune is Prune for a repository whose reflog entries expired says are
+// about to go, so the objects only they reach are pruned too; expired may
+// be nil
+func (r *Repository) prune(opts PruneOptions, expired func(ref string, entry ReflogEntry) bool) (*PruneResult, error) {
+	if !opts.DryRun {
+		if err := r.checkWritable(); err != nil {
+			return nil, err
+		}
+	}
+	if !r.usesFileStore() {
+		return nil, fmt.Errorf("pruning objects is %w", errNeedsFileStore)
+	}
+	if !opts.DryRun {
+		unlock, err := r.lockMaintenance()
+		if err != nil {
+			return nil, err
+		}
+		defer unlock()
+	}
+	expire := opts.Expire
+	var err error
+	if expire == "" {
+		if expire, err = r.getConfigString("gc.pruneExpire", defaultPruneExpire); err != nil {
+			return nil, err
+		}
+	}
+	result := &PruneResult{}
+	var cutoff time.Time
+	if expire != "never" {
+		if cutoff, err = ParseDate(expire, time.Now()); err != nil {
+			return nil, fmt.Errorf("invalid prune expiry: %w", err)
+		}
+	}
+	tempCutoff, err := r.tempCutoff(cutoff)
+	if err != nil {
+		return nil, err
+	}
+	stale, err := r.findStaleFiles(tempCutoff)
+	if err != nil {
+		return nil, err
+	}
+	result.TempFiles, result.Locks, result.Reclaimed = stale.temp, stale.locks, stale.size
+	if expire == "never" {
+		if !opts.DryRun {
+			if err := stale.remove(r.CommonDir); err != nil {
+				return nil, err
+			}
+		}
+		return result, nil
+	}
+
+	reachable, err := r.referencedObjects(expired)
+	if err != nil {
+		return nil, err
+	}
+	loose, err := r.listLooseObjects()
+	if err != nil {
+		return nil, err
+	}
+	var pruned []string
+	for _, sha := range loose {
+		if reachable[sha] {
+			continue
+		}
+		info, err := os.Stat(r.objectPath(sha))
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+		// A corrupt object is pruned all the same
+		objectType, _, err := r.loadObject(sha)
+		if err != nil {
+			objectType = "unknown"
+		}
+		result.Objects = append(result.Objects, PrunedObject{SHA: sha, Type: objectType, Size: info.Size()})
+		result.Reclaimed += info.Size()
+		pruned = append(pruned, sha)
+	}
+
+	if opts.DryRun {
+		return result, nil
+	}
+	if err := r.removeLooseObjects(pruned); err != nil {
+		return nil, err
+	}
+	if err := stale.remove(r.CommonDir); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// referencedObjects returns every object reached from the roots fsck walks,
+// less the reflog entries expired says are about to go, and from the
+// commits an operation in progress still needs. Missing objects are
+// skipped; reporting them is fsck's job.
+func (r *Repository) referencedObjects(expired func(ref string, entry ReflogEntry) bool) (map[string]bool, error) {
+	roots, err := r.fsckRoots(expired)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range []string{
+		r.gitPath(CherryPickHeadFile),
+		r.gitPath(RevertHeadFile),
+		r.gitPath(SubtreeMergeHeadFile),
+		r.gitPath(MergeHeadFile),
+		r.rebaseFile("orig-head"),
+		r.rebaseFile("onto"),
+		r.rebaseFile("stopped-sha"),
+	} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		if sha := strings.TrimSpace(string(data)); r.Format.ValidateSHA(sha) == nil {
+			roots = append(roots, fsckLink{sha, object.CommitObject})
+		}
+	}
+
+	reachable := make(map[string]bool)
+	stack := roots
+	for len(stack) > 0 {
+		link := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[link.sha] || !r.objectExists(link.sha) {
+			continue
+		}
+		reachable[link.sha] = true
+		if link.objectType == object.BlobObject {
+			continue
+		}
+
+		objectType, content, err := r.ReadObject(link.sha)
+		if err != nil {
+			return nil, err
+		}
+		switch objectType {
+		case object.CommitObject:
+			commit, err := object.ParseCommit(link.sha, content)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, fsckLink{commit.TreeSHA, object.TreeObject})
+			for _, parent := range commit.Parents {
+				stack = append(stack, fsckLink{parent, object.CommitObject})
+			}
+		case object.TreeObject:
+			entries, err := r.Format.ParseTree(content)
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range entries {
+				if entry.Mode != GitlinkMode {
+					stack = append(stack, fsckLink{entry.SHA, entry.Type})
+				}
+			}
+		case object.TagObject:
+			tag, err := object.ParseTag(link.sha, content)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, fsckLink{tag.Object, tag.Type})
+		}
+	}
+	return reachable, nil
+}
@@ -0,0 +1,36 @@
+package gvc
+
+import (
+	"errors"
+	"os"
+)
+
+// EnvReadOnly names the environment variable that opens repositories
+// read-only, or with a false value lifts core.readOnly
+const EnvReadOnly = "GVC_READ_ONLY"
+
+// ErrReadOnly is returned by anything that would modify a read-only repository
+var ErrReadOnly = errors.New("repository is read-only")
+
+// loadReadOnly turns on read-only mode from GVC_READ_ONLY, or from
+// core.readOnly when the variable is not set
+func (r *Repository) loadReadOnly() error {
+	if value, ok := os.LookupEnv(EnvReadOnly); ok {
+		readOnly, err := parseConfigBool(EnvReadOnly, value)
+		r.ReadOnly = readOnly
+		return err
+	}
+	readOnly, err := r.getConfigBool("core.readOnly", false)
+	r.ReadOnly = readOnly
+	return err
+}
+
+// checkWritable fails with ErrReadOnly in read-only mode. Everything that
+// writes objects, refs, the index, config or the working tree checks it
+// first, so a read-only repository is never partly modified.
+func (r *Repository) checkWritable() error {
+	if r.ReadOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
@@ -0,0 +1,564 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+const RebaseDir = "rebase-merge"
+
+// RebaseTodoFile is the file the todo list of an interactive rebase is edited in
+const RebaseTodoFile = "git-rebase-todo"
+
+// rebaseState is the progress of a rebase, kept in RebaseDir between runs
+type rebaseState struct {
+	HeadName string   // branch being rebased, e.g. refs/heads/topic
+	OrigHead string   // branch tip before the rebase started
+	Onto     string   // commit the branch is being replayed onto
+	Todo     []string // "<action> <commit>" lines still to be replayed, oldest first
+}
+
+// RebaseOptions controls how Rebase replays a branch
+type RebaseOptions struct {
+	// Autosquash folds "fixup! " and "squash! " commits into the commits they
+	// name; rebase.autoSquash turns it on by default
+	Autosquash bool
+	// NoAutosquash overrides rebase.autoSquash
+	NoAutosquash bool
+	// Interactive opens the todo list, after any autosquash reordering, in
+	// the editor, where steps can be reordered, changed or dropped
+	Interactive bool
+}
+
+// rebaseFile returns the path of a file in the rebase state directory
+func (r *Repository) rebaseFile(name string) string {
+	return r.gitPath(RebaseDir, name)
+}
+
+// readRebaseState loads the state of an interrupted rebase
+func (r *Repository) readRebaseState() (*rebaseState, error) {
+	if _, err := os.Stat(r.gitPath(RebaseDir)); err != nil {
+		return nil, errors.New("no rebase in progress")
+	}
+	read := func(name string) (string, error) {
+		data, err := os.ReadFile(r.rebaseFile(name))
+		if err != nil {
+			return "", fmt.Errorf("failed to read rebase state: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+
+	state := &rebaseState{}
+	var err error
+	if state.HeadName, err = read("head-name"); err != nil {
+		return nil, err
+	}
+	if state.OrigHead, err = read("orig-head"); err != nil {
+		return nil, err
+	}
+	if state.Onto, err = read("onto"); err != nil {
+		return nil, err
+	}
+	todo, err := read("todo")
+	if err != nil {
+		return nil, err
+	}
+	if todo != "" {
+		state.Todo = strings.Split(todo, "\n")
+	}
+	return state, nil
+}
+
+// writeRebaseState saves the rebase progress
+func (r *Repository) writeRebaseState(state *rebaseState) error {
+	if err := os.MkdirAll(r.gitPath(RebaseDir), 0755); err != nil {
+		return fmt.Errorf("failed to create rebase state: %w", err)
+	}
+	files := map[string]string{
+		"head-name": state.HeadName,
+		"orig-head": state.OrigHead,
+		"onto":      state.Onto,
+		"todo":      strings.Join(state.Todo, "\n"),
+	}
+	for name, value := range files {
+		if err := writeFileLocked(r.rebaseFile(name), []byte(value+"\n")); err != nil {
+			return fmt.Errorf("failed to write rebase state: %w", err)
+		}
+	}
+	return nil
+}
+
+// reachableCommits returns every commit reachable from the given tips
+func (r *Repository) reachableCommits(tips []string) (map[string]bool, error) {
+	reachable := make(map[string]bool)
+	stack := append([]string(nil), tips...)
+	for len(stack) > 0 {
+		sha := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if reachable[sha] {
+			continue
+		}
+		reachable[sha] = true
+		if err := r.canceled(); err != nil {
+			return nil, err
+		}
+		node, err := r.LookupCommitNode(sha)
+		if err != nil {
+			return nil, err
+		}
+		stack = append(stack, node.Parents...)
+	}
+	return reachable, nil
+}
+
+// commitsToReplay lists the non-merge commits reachable from head but not from
+// upstream, parents before children
+func (r *Repository) commitsToReplay(upstream, head string) ([]string, error) {
+	excluded, err := r.reachableCommits([]string{upstream})
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []string
+	visited := make(map[string]bool)
+	var visit func(sha string) error
+	visit = func(sha string) error {
+		if excluded[sha] || visited[sha] {
+			return nil
+		}
+		visited[sha] = true
+		node, err := r.LookupCommitNode(sha)
+		if err != nil {
+			return err
+		}
+		for _, parent := range node.Parents {
+			if err := visit(parent); err != nil {
+				return err
+			}
+		}
+		if len(node.Parents) <= 1 {
+			commits = append(commits, sha)
+		}
+		return nil
+	}
+	if err := visit(head); err != nil {
+		return nil, err
+	}
+	return commits, nil
+}
+
+// detachHead points HEAD directly at a commit, moving the working tree and index there
+func (r *Repository) detachHead(commitSHA, message string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	from, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	oldSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	commit, err := r.ReadCommit(commitSHA)
+	if err != nil {
+		return err
+	}
+	to, err := r.flattenTree(commit.TreeSHA)
+	if err != nil {
+		return err
+	}
+	if err := r.checkoutEntries(from, to); err != nil {
+		return err
+	}
+	if err := r.writeGitFile(r.gitPath(HeadFile), []byte(commitSHA+"\n")); err != nil {
+		return fmt.Errorf("failed to write HEAD: %w", err)
+	}
+	return r.appendReflog("HEAD", oldSHA, commitSHA, message)
+}
+
+// attachHead points HEAD back at a branch without touching the working tree
+func (r *Repository) attachHead(branchRef, message string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	oldSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	if err := r.writeGitFile(r.gitPath(HeadFile), []byte("ref: "+branchRef+"\n")); err != nil {
+		return fmt.Errorf("failed to write HEAD: %w", err)
+	}
+	newSHA, err := r.ReadRef(branchRef)
+	if err != nil {
+		return err
+	}
+	return r.appendReflog("HEAD", oldSHA, newSHA, message)
+}
+
+// Rebase replays the current branch onto upstream
+func (r *Repository) Rebase(upstreamRev string) error {
+	return r.RebaseWithOptions(upstreamRev, RebaseOptions{})
+}
+
+// RebaseWithOptions replays the current branch onto upstream
+func (r *Repository) RebaseWithOptions(upstreamRev string, opts RebaseOptions) error {
+	if op := r.OperationInProgress(); op != "" {
+		return fmt.Errorf("a %s is already in progress; use --continue or --abort", op)
+	}
+
+	branchRef, err := r.HeadRef()
+	if err != nil {
+		return err
+	}
+	if branchRef == "" {
+		return errors.New("cannot rebase a detached HEAD; switch to a branch first")
+	}
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	if headSHA == "" {
+		return errors.New("cannot rebase an empty branch")
+	}
+	upstreamSHA, err := r.ResolveCommit(upstreamRev)
+	if err != nil {
+		return err
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	if err := r.checkCleanState(head); err != nil {
+		return err
+	}
+
+	branch := strings.TrimPrefix(branchRef, "refs/heads/")
+	commits, err := r.commitsToReplay(upstreamSHA, headSHA)
+	if err != nil {
+		return err
+	}
+	autosquash := opts.Autosquash
+	if !autosquash && !opts.NoAutosquash {
+		if autosquash, err = r.getConfigBool("rebase.autoSquash", false); err != nil {
+			return err
+		}
+	}
+	var todo []string
+	if autosquash {
+		if todo, err = r.autosquashTodo(commits); err != nil {
+			return err
+		}
+	} else {
+		for _, sha := range commits {
+			todo = append(todo, RebasePick+" "+sha)
+		}
+	}
+
+	// Without commits to fold in, a branch that contains upstream stays as it is
+	upToDate, err := r.IsAncestor(upstreamSHA, headSHA)
+	if err != nil {
+		return err
+	}
+	for _, line := range todo {
+		if action, _, _ := parseTodoLine(line); action != RebasePick {
+			upToDate = false
+		}
+	}
+	if upToDate && !opts.Interactive {
+		fmt.Fprintf(r.Out, "Current branch %s is up to date.\n", branch)
+		return nil
+	}
+	if opts.Interactive {
+		if todo, err = r.editRebaseTodo(todo, upstreamSHA, headSHA); err != nil {
+			return err
+		}
+	}
+
+	// Picks that already sit on the new base are kept as they are
+	onto := upstreamSHA
+	for len(todo) > 0 {
+		action, sha, err := parseTodoLine(todo[0])
+		if err != nil {
+			return err
+		}
+		parents, err := r.commitParents(sha)
+		if err != nil {
+			return err
+		}
+		if action != RebasePick || len(parents) != 1 || parents[0] != onto {
+			break
+		}
+		// A pick followed by a fixup is rewritten, so it has to be replayed
+		if len(todo) > 1 {
+			if next, _, err := parseTodoLine(todo[1]); err != nil || next != RebasePick {
+				break
+			}
+		}
+		onto, todo = sha, todo[1:]
+	}
+	// Dropping the last commits still moves the branch
+	if len(todo) == 0 && onto == headSHA {
+		fmt.Fprintf(r.Out, "Current branch %s is up to date.\n", branch)
+		return nil
+	}
+
+	state := &rebaseState{HeadName: branchRef, OrigHead: headSHA, Onto: onto, Todo: todo}
+	if err := r.writeRebaseState(state); err != nil {
+		return err
+	}
+	if err := r.detachHead(onto, "rebase (start): checkout "+upstreamRev); err != nil {
+		return err
+	}
+	return r.runRebase(state)
+}
+
+// runRebase replays the remaining commits, stopping at the first conflict
+func (r *Repository) runRebase(state *rebaseState) error {
+	for len(state.Todo) > 0 {
+		action, commitSHA, err := parseTodoLine(state.Todo[0])
+		if err != nil {
+			return err
+		}
+		state.Todo = state.Todo[1:]
+		if err := r.writeRebaseState(state); err != nil {
+			return err
+		}
+
+		commit, err := r.ReadCommit(commitSHA)
+		if err != nil {
+			return err
+		}
+		parents, err := r.commitParents(commitSHA)
+		if err != nil {
+			return err
+		}
+		parentTree := ""
+		if len(parents) == 1 {
+			parentCommit, err := r.ReadCommit(parents[0])
+			if err != nil {
+				return err
+			}
+			parentTree = parentCommit.TreeSHA
+		}
+
+		headSHA, err := r.HeadCommit()
+		if err != nil {
+			return err
+		}
+		head, err := r.headEntries()
+		if err != nil {
+			return err
+		}
+		message, err := r.rebaseStepMessage(action, headSHA, commit)
+		if err != nil {
+			return err
+		}
+		subject := strings.SplitN(commit.Message, "\n", 2)[0]
+		label := fmt.Sprintf("%s (%s)", commitSHA[:7], subject)
+		conflicts, err := r.applyPick(parentTree, commit.TreeSHA, label, head)
+		if err != nil {
+			return err
+		}
+
+		if len(conflicts) > 0 {
+			if err := r.writeSequencerState(r.rebaseFile("stopped-sha"), commitSHA, message, conflicts); err != nil {
+				return err
+			}
+			if err := writeFileLocked(r.rebaseFile("stopped-action"), []byte(action+"\n")); err != nil {
+				return fmt.Errorf("failed to write rebase state: %w", err)
+			}
+			return conflictError(fmt.Errorf("could not apply %s... %s\nconflicts in:\n\t%s\n"+
+				"resolve them, 'gvc add' the files and run 'gvc rebase --continue' (or --abort)",
+				commitSHA[:7], subject, strings.Join(conflicts, "\n\t")))
+		}
+
+		if err := r.commitRebaseStep(action, head, headSHA, commit, message); err != nil {
+			return err
+		}
+	}
+	return r.finishRebase(state)
+}
+
+// rebaseStepMessage returns the message a todo step records: a pick keeps its
+// own, a fixup keeps the message of the commit it folds into and a squash
+// appends its own to that
+func (r *Repository) rebaseStepMessage(action, headSHA string, commit *object.Commit) (string, error) {
+	if action == RebasePick {
+		return commit.Message, nil
+	}
+	head, err := r.ReadCommit(headSHA)
+	if err != nil {
+		return "", err
+	}
+	if action == RebaseFixup {
+		return head.Message, nil
+	}
+	return strings.TrimRight(head.Message, "\n") + "\n\n" + commit.Message, nil
+}
+
+// commitRebaseStep records a replayed change: a pick as a new commit, a fixup
+// or squash by rewriting HEAD, the commit it folds into
+func (r *Repository) commitRebaseStep(action string, head map[string]IndexEntry, headSHA string, commit *object.Commit, message string) error {
+	if action == RebasePick {
+		return r.commitRebasePick(head, headSHA, commit, message)
+	}
+	target, err := r.ReadCommit(headSHA)
+	if err != nil {
+		return err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+	subject := strings.SplitN(commit.Message, "\n", 2)[0]
+	_, err = r.commitStaged(staged, target.Parents, target.Author, target.Timestamp, message,
+		fmt.Sprintf("rebase (%s): %s", action, subject))
+	return err
+}
+
+// commitRebasePick commits a replayed change, dropping it when upstream already has it
+func (r *Repository) commitRebasePick(head map[string]IndexEntry, headSHA string, commit *object.Commit, message string) error {
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+	subject := strings.SplitN(message, "\n", 2)[0]
+
+	changed := len(staged) != len(head)
+	for path, entry := range staged {
+		headEntry, ok := head[path]
+		if !sameEntry(entry, headEntry, true, ok) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		fmt.Fprintf(r.Out, "dropping %s %s -- patch contents already upstream\n", commit.SHA[:7], subject)
+		return nil
+	}
+
+	_, err = r.commitStaged(staged, []string{headSHA}, commit.Author, commit.Timestamp, message, "rebase (pick): "+subject)
+	return err
+}
+
+// finishRebase moves the branch to the rebased commits and checks it out again
+func (r *Repository) finishRebase(state *rebaseState) error {
+	newHead, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("rebase (finish): %s onto %s", state.HeadName, state.Onto)
+	if err := r.writeRef(state.HeadName, newHead, message); err != nil {
+		return err
+	}
+	if err := r.attachHead(state.HeadName, "rebase (finish): returning to "+state.HeadName); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(r.gitPath(RebaseDir)); err != nil {
+		return fmt.Errorf("failed to remove rebase state: %w", err)
+	}
+	fmt.Fprintf(r.Out, "Successfully rebased and updated %s.\n", state.HeadName)
+	return nil
+}
+
+// ContinueRebase commits the resolved conflict and replays the remaining commits
+func (r *Repository) ContinueRebase() error {
+	state, err := r.readRebaseState()
+	if err != nil {
+		return err
+	}
+	if commit, err := r.SplittingCommit(); err != nil {
+		return err
+	} else if commit != nil {
+		if err := r.commitSplitRest(commit); err != nil {
+			return err
+		}
+	}
+
+	stoppedFile := r.rebaseFile("stopped-sha")
+	if data, err := os.ReadFile(stoppedFile); err == nil {
+		commit, err := r.ReadCommit(strings.TrimSpace(string(data)))
+		if err != nil {
+			return err
+		}
+		message, err := os.ReadFile(r.gitPath(MergeMsgFile))
+		if err != nil {
+			return fmt.Errorf("failed to read merge message: %w", err)
+		}
+
+		headSHA, err := r.HeadCommit()
+		if err != nil {
+			return err
+		}
+		head, err := r.headEntries()
+		if err != nil {
+			return err
+		}
+		staged, err := r.stagedEntries()
+		if err != nil {
+			return err
+		}
+		unresolved, err := r.unresolvedConflicts(staged)
+		if err != nil {
+			return err
+		}
+		if len(unresolved) > 0 {
+			return conflictError(fmt.Errorf("unresolved conflicts remain in:\n\t%s\nfix them and 'gvc add' the result",
+				strings.Join(unresolved, "\n\t")))
+		}
+
+		action := RebasePick
+		if data, err := os.ReadFile(r.rebaseFile("stopped-action")); err == nil {
+			action = strings.TrimSpace(string(data))
+		} else if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read rebase state: %w", err)
+		}
+		if err := r.commitRebaseStep(action, head, headSHA, commit, strings.TrimSpace(string(message))); err != nil {
+			return err
+		}
+		if err := r.clearSequencerState(stoppedFile); err != nil {
+			return err
+		}
+		if err := os.Remove(r.rebaseFile("stopped-action")); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove rebase state: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read rebase state: %w", err)
+	}
+
+	return r.runRebase(state)
+}
+
+// AbortRebase returns the branch, working tree and index to where they were before the rebase
+func (r *Repository) AbortRebase() error {
+	state, err := r.readRebaseState()
+	if err != nil {
+		return err
+	}
+
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	if err := r.resetHard(head); err != nil {
+		return err
+	}
+	if err := r.detachHead(state.OrigHead, "rebase (abort): checkout "+state.OrigHead); err != nil {
+		return err
+	}
+	if err := r.attachHead(state.HeadName, "rebase (abort): returning to "+state.HeadName); err != nil {
+		return err
+	}
+	if err := r.clearSequencerState(r.rebaseFile("stopped-sha")); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(r.gitPath(RebaseDir)); err != nil {
+		return fmt.Errorf("failed to remove rebase state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package gvc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInteractiveRebaseEditsTheAutosquashedTodo(t *testing.T) {
+	repo, firstSHA := newTestRepoWithCommit(t)
+	if err := repo.SwitchBranch("topic", true, ""); err != nil {
+		t.Fatal(err)
+	}
+	aSHA := commitTestChange(t, repo, "a.txt", "a\n", "A")
+	bSHA := commitTestChange(t, repo, "b.txt", "b\n", "B")
+	fixupMessage, err := repo.FixupMessage(RebaseFixup, aSHA, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixupSHA := commitTestChange(t, repo, "a.txt", "a, fixed\n", fixupMessage)
+
+	// The editor keeps a copy of the todo it was given and drops B
+	dir := t.TempDir()
+	seen := filepath.Join(dir, "seen")
+	script := filepath.Join(dir, "editor")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\ncp \"$1\" '"+seen+"'\n"+
+		"sed -e 's/^pick \\([0-9a-f]*\\) B$/drop \\1 B/' \"$1\" > \"$1.new\" && mv \"$1.new\" \"$1\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvEditor, script)
+
+	if err := repo.RebaseWithOptions("main", RebaseOptions{Interactive: true, Autosquash: true}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(seen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var steps []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			steps = append(steps, line)
+		}
+	}
+	want := []string{"pick " + aSHA[:7] + " A", "fixup " + fixupSHA[:7] + " fixup! A", "pick " + bSHA[:7] + " B"}
+	if strings.Join(steps, "\n") != strings.Join(want, "\n") {
+		t.Errorf("the todo opened in the editor was\n%s\nwant\n%s", strings.Join(steps, "\n"), strings.Join(want, "\n"))
+	}
+
+	head, err := repo.HeadCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.ReadCommit(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Message != "A" || len(commit.Parents) != 1 || commit.Parents[0] != firstSHA {
+		t.Errorf("topic is %q on %v, want A with the fixup on %s", commit.Message, commit.Parents, firstSHA)
+	}
+	entries, err := repo.revisionEntries(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := entries["b.txt"]; ok {
+		t.Error("the dropped commit's b.txt is still there")
+	}
+	if _, content, err := repo.ReadObject(entries["a.txt"].SHA); err != nil || string(content) != "a, fixed\n" {
+		t.Errorf("a.txt is %q (%v), want the fixup folded in", content, err)
+	}
+	if ref, err := repo.HeadRef(); err != nil || ref != HeadsDir+"/topic" {
+		t.Errorf("HEAD is at %q (%v) after the rebase, want topic", ref, err)
+	}
+}
@@ -0,0 +1,91 @@
+package gvc
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RefFormatOptions relaxes or extends CheckRefFormat
+type RefFormatOptions struct {
+	AllowOneLevel bool // accept names without a "/", such as HEAD
+	Normalize     bool // drop a leading "/" and collapse repeated slashes first
+}
+
+// CheckRefFormat returns name if it is a valid ref name by Git's rules, or
+// its normalized form with Normalize set. A name is invalid when:
+//   - a component begins with "." or ends with ".lock"
+//   - it contains "..", "@{", a control character, space, or any of ~^:?*[\
+//   - it begins or ends with "/", contains "//", or ends with "."
+//   - it is "@" or has a single component (unless AllowOneLevel)
+func CheckRefFormat(name string, opts RefFormatOptions) (string, error) {
+	if opts.Normalize {
+		name = strings.TrimLeft(name, "/")
+		for strings.Contains(name, "//") {
+			name = strings.ReplaceAll(name, "//", "/")
+		}
+	}
+	invalid := fmt.Errorf("invalid ref name: %s", name)
+
+	if name == "" || name == "@" || strings.HasSuffix(name, "/") || strings.HasSuffix(name, ".") ||
+		strings.Contains(name, "..") || strings.Contains(name, "@{") {
+		return "", invalid
+	}
+	for _, r := range name {
+		if r < ' ' || r == 0x7f || strings.ContainsRune(` ~^:?*[\`, r) {
+			return "", invalid
+		}
+	}
+	components := strings.Split(name, "/")
+	if len(components) < 2 && !opts.AllowOneLevel {
+		return "", invalid
+	}
+	for _, component := range components {
+		if component == "" || strings.HasPrefix(component, ".") || strings.HasSuffix(component, LockSuffix) {
+			return "", invalid
+		}
+	}
+	return name, nil
+}
+
+// ExpandBranchName resolves the @{-n} shorthand for the nth branch checked
+// out before the current one and checks that the result is a valid branch
+// name, returning it without the refs/heads/ prefix
+func (r *Repository) ExpandBranchName(name string) (string, error) {
+	if rest, ok := strings.CutPrefix(name, "@{-"); ok && strings.HasSuffix(rest, "}") {
+		n, err := strconv.Atoi(strings.TrimSuffix(rest, "}"))
+		if err != nil || n < 1 {
+			return "", fmt.Errorf("invalid branch name: %s", name)
+		}
+		previous, err := r.PreviousBranch(n)
+		if err != nil {
+			return "", err
+		}
+		name = previous
+	}
+	if err := validateBranchName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// PreviousBranch returns the branch or commit checked out n switches ago,
+// read from the HEAD reflog
+func (r *Repository) PreviousBranch(n int) (string, error) {
+	entries, err := r.ReadReflog("HEAD")
+	if err != nil {
+		return "", err
+	}
+	remaining := n
+	for i := len(entries) - 1; i >= 0; i-- {
+		rest, ok := strings.CutPrefix(entries[i].Message, "checkout: moving from ")
+		if !ok {
+			continue
+		}
+		if remaining--; remaining == 0 {
+			from, _, _ := strings.Cut(rest, " to ")
+			return from, nil
+		}
+	}
+	return "", fmt.Errorf("no previous branch for @{-%d}", n)
+}
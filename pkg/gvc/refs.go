@@ -0,0 +1,507 @@
+package gvc
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// HeadRef returns the current branch reference
+func (r *Repository) HeadRef() (string, error) {
+	headData, err := r.readGitFile(r.gitPath(HeadFile))
+	if os.IsNotExist(err) && r.namespace != "" {
+		// Nothing has been pushed to the namespace yet
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+
+	headContent := strings.TrimSpace(string(headData))
+	if strings.HasPrefix(headContent, "ref: ") {
+		return strings.TrimPrefix(headContent, "ref: "), nil
+	}
+	// HEAD points directly to a commit (detached HEAD)
+	return "", nil
+}
+
+// HeadCommit returns the SHA of the current commit
+func (r *Repository) HeadCommit() (string, error) {
+	branchRef, err := r.HeadRef()
+	if err != nil {
+		return "", err
+	}
+
+	if branchRef == "" {
+		// Detached HEAD
+		headData, err := r.readGitFile(r.gitPath(HeadFile))
+		if os.IsNotExist(err) && r.namespace != "" {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(headData)), nil
+	}
+
+	// Read branch reference
+	branchFile := r.gitPath(branchRef)
+	data, err := r.readGitFile(branchFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return r.readPackedRef(branchRef) // "" when there are no commits yet
+		}
+		return "", fmt.Errorf("failed to read branch ref: %w", err)
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// UpdateHead updates the current branch to point to a commit and records the move in the reflog
+func (r *Repository) UpdateHead(commitSHA, message string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	branchRef, err := r.HeadRef()
+	if err != nil {
+		return err
+	}
+
+	oldSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+
+	if branchRef == "" {
+		// Detached HEAD: move HEAD itself
+		if err := r.writeGitFile(r.gitPath(HeadFile), []byte(commitSHA+"\n")); err != nil {
+			return fmt.Errorf("failed to write HEAD: %w", err)
+		}
+		return r.appendReflog("HEAD", oldSHA, commitSHA, message)
+	}
+
+	l, err := r.lockGitFile(r.gitPath(branchRef))
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+
+	// Re-read under the lock so the reflog records the value actually replaced
+	if oldSHA, err = r.HeadCommit(); err != nil {
+		return err
+	}
+	if err := l.commit([]byte(commitSHA + "\n")); err != nil {
+		return fmt.Errorf("failed to write branch ref: %w", err)
+	}
+
+	// HEAD moved along with the branch it points to, so both logs record it
+	if err := r.appendReflog(branchRef, oldSHA, commitSHA, message); err != nil {
+		return err
+	}
+	return r.appendReflog("HEAD", oldSHA, commitSHA, message)
+}
+
+// ReflogEntry records one update of a ref
+type ReflogEntry struct {
+	OldSHA    string
+	NewSHA    string
+	Ident     string
+	Timestamp time.Time
+	Message   string
+}
+
+// reflogPath returns the log file for a ref such as HEAD or refs/heads/main
+func (r *Repository) reflogPath(ref string) string {
+	return r.gitPath(LogsDir, filepath.FromSlash(r.namespaceRef(ref)))
+}
+
+// formatReflogEntry renders an entry in the on-disk format:
+// <old-sha> <new-sha> <ident> <timestamp> <tz>\t<message>
+func (r *Repository) formatReflogEntry(entry ReflogEntry) string {
+	oldSHA := entry.OldSHA
+	if oldSHA == "" {
+		oldSHA = r.Format.ZeroSHA()
+	}
+	message := strings.ReplaceAll(entry.Message, "\n", " ")
+	return fmt.Sprintf("%s %s %s %d +0000\t%s\n", oldSHA, entry.NewSHA, entry.Ident, entry.Timestamp.Unix(), message)
+}
+
+// pruneEmptyDirs removes dir and the directories above it, up to but not
+// including stop, for as long as they are empty
+func pruneEmptyDirs(dir, stop string) {
+	for ; dir != stop && strings.HasPrefix(dir, stop+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			return
+		}
+	}
+}
+
+// pruneRefDirs removes the directories a deleted loose ref leaves empty, so
+// a ref can be created under their name; refs/heads and the like are kept
+func (r *Repository) pruneRefDirs(ref string) {
+	if r.memory != nil {
+		return
+	}
+	if parts := strings.SplitN(ref, "/", 3); len(parts) == 3 {
+		pruneEmptyDirs(filepath.Dir(r.gitPath(filepath.FromSlash(ref))), r.gitPath(parts[0], parts[1]))
+	}
+}
+
+// prepareReflog makes room for the log of ref before the ref itself is
+// written, so a ref is never moved without its log: the directories are
+// created, and the log of a deleted ref in the way of one, or an empty
+// directory in place of the log file, is removed.
+func (r *Repository) prepareReflog(ref string) error {
+	if r.memory != nil {
+		return nil
+	}
+	logFile := r.reflogPath(ref)
+	logsDir := r.gitPath(LogsDir)
+	rel, err := filepath.Rel(logsDir, logFile)
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	for i := 1; i < len(parts); i++ {
+		path := filepath.Join(logsDir, filepath.Join(parts[:i]...))
+		info, err := os.Lstat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		owner := filepath.ToSlash(filepath.Join(parts[:i]...))
+		if sha, err := r.ReadRef(strings.TrimPrefix(owner, r.namespaceRef(""))); err != nil {
+			return err
+		} else if sha != "" {
+			return fmt.Errorf("cannot create log for %s: %s exists", ref, owner)
+		}
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove stale reflog %s: %w", path, err)
+		}
+		break
+	}
+	if info, err := os.Lstat(logFile); err == nil && info.IsDir() {
+		// Removing fails unless it is empty, when it only held logs of deleted refs
+		if err := os.Remove(logFile); err != nil {
+			return fmt.Errorf("cannot create log for %s: %s is a directory", ref, logFile)
+		}
+	}
+	if err := r.mkdirShared(filepath.Dir(logFile)); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	return nil
+}
+
+// appendReflog adds an entry to the log of ref
+func (r *Repository) appendReflog(ref, oldSHA, newSHA, message string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	r.notifyRefUpdate(RefUpdate{Name: ref, OldSHA: oldSHA, NewSHA: newSHA, Message: message})
+	logFile := r.reflogPath(ref)
+	entry := ReflogEntry{OldSHA: oldSHA, NewSHA: newSHA, Ident: r.authorIdent(), Timestamp: time.Now(), Message: message}
+	if r.memory != nil {
+		data, _ := r.memory.read(logFile)
+		r.memory.write(logFile, append(data, r.formatReflogEntry(entry)...))
+		return nil
+	}
+	if err := r.mkdirShared(filepath.Dir(logFile)); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	_, statErr := os.Stat(logFile)
+	f, err := os.OpenFile(logFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open reflog for %s: %w", ref, err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(r.formatReflogEntry(entry)); err != nil {
+		return fmt.Errorf("failed to write reflog for %s: %w", ref, err)
+	}
+	if os.IsNotExist(statErr) {
+		return r.adjustSharedPerm(logFile)
+	}
+	return nil
+}
+
+// ReadReflog returns the entries of a ref's log, oldest first
+func (r *Repository) ReadReflog(ref string) ([]ReflogEntry, error) {
+	data, err := r.readGitFile(r.reflogPath(ref))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read reflog for %s: %w", ref, err)
+	}
+
+	var entries []ReflogEntry
+	for _, line := range strings.Split(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		header, message, _ := strings.Cut(line, "\t")
+		fields := strings.Fields(header)
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("malformed reflog entry for %s: %q", ref, line)
+		}
+		entries = append(entries, ReflogEntry{
+			OldSHA:    fields[0],
+			NewSHA:    fields[1],
+			Ident:     strings.Join(fields[2:len(fields)-2], " "),
+			Timestamp: time.Unix(object.ParseInt64(fields[len(fields)-2]), 0),
+			Message:   message,
+		})
+	}
+	return entries, nil
+}
+
+// writeReflog replaces the log of ref with entries (oldest first), removing it when empty
+func (r *Repository) writeReflog(ref string, entries []ReflogEntry) error {
+	logFile := r.reflogPath(ref)
+	if len(entries) == 0 {
+		if r.memory != nil {
+			r.memory.remove(logFile)
+			return nil
+		}
+		if err := os.Remove(logFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove reflog for %s: %w", ref, err)
+		}
+		// Directories left empty would keep a ref of their name from having a log
+		pruneEmptyDirs(filepath.Dir(logFile), r.gitPath(LogsDir))
+		return nil
+	}
+
+	var log bytes.Buffer
+	for _, entry := range entries {
+		log.WriteString(r.formatReflogEntry(entry))
+	}
+
+	if err := r.writeGitFile(logFile, log.Bytes()); err != nil {
+		return fmt.Errorf("failed to write reflog for %s: %w", ref, err)
+	}
+	return nil
+}
+
+// currentBranchName returns the short name of the checked out branch
+func (r *Repository) currentBranchName() (string, error) {
+	branchRef, err := r.HeadRef()
+	if err != nil {
+		return "", err
+	}
+	if branchRef == "" {
+		return "(no branch)", nil
+	}
+	return strings.TrimPrefix(branchRef, "refs/heads/"), nil
+}
+
+// CommitSummary returns the line reporting a commit just recorded on HEAD,
+// "[<branch> <sha>] <subject>", with "detached HEAD" for the branch when
+// there is none
+func (r *Repository) CommitSummary(commitSHA, message string) (string, error) {
+	branchRef, err := r.HeadRef()
+	if err != nil {
+		return "", err
+	}
+	branch, ok := strings.CutPrefix(branchRef, "refs/heads/")
+	if !ok {
+		branch = "detached HEAD"
+	}
+	return fmt.Sprintf("[%s %s] %s", branch, commitSHA[:7], strings.SplitN(message, "\n", 2)[0]), nil
+}
+
+// ExpandRefName turns a short ref name such as "main" or "stash" into its full name
+func (r *Repository) ExpandRefName(name string) (string, error) {
+	packed, err := r.packedRefs()
+	if err != nil {
+		return "", err
+	}
+	for _, candidate := range refCandidates(name) {
+		if _, ok := packed[candidate]; ok {
+			return candidate, nil
+		}
+		if candidate == "HEAD" {
+			return candidate, nil
+		}
+		if _, ok := r.gitFileModTime(r.gitPath(filepath.FromSlash(candidate))); ok {
+			return candidate, nil
+		}
+		if _, ok := r.gitFileModTime(r.reflogPath(candidate)); ok {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("unknown ref: %s", name)
+}
+
+// Ref is a named pointer to a commit
+type Ref struct {
+	Name string // full name, e.g. refs/heads/main
+	SHA  string
+	Tag  string // the annotated tag the ref names, whose commit is SHA
+}
+
+// listRefs returns every ref under .gvc/refs and in packed-refs, sorted by
+// name. A linked worktree sees the shared refs and its own bisect refs.
+func (r *Repository) listRefs() ([]Ref, error) {
+	var refs []Ref
+	walk := func(base, dir string) error {
+		err := r.walkGitFiles(filepath.Join(base, filepath.FromSlash(dir)), func(path string) error {
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			// Skip refs that belong to another worktree
+			if r.gitPath(rel) != path {
+				return nil
+			}
+			data, err := r.readGitFile(path)
+			if err != nil {
+				return err
+			}
+			if sha := strings.TrimSpace(string(data)); r.Format.ValidateSHA(sha) == nil {
+				refs = append(refs, Ref{Name: filepath.ToSlash(rel), SHA: sha})
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list refs: %w", err)
+		}
+		return nil
+	}
+	if err := walk(r.CommonDir, RefsDir); err != nil {
+		return nil, err
+	}
+	if r.CommonDir != r.GitDir {
+		if err := walk(r.GitDir, BisectRefsDir); err != nil {
+			return nil, err
+		}
+	}
+
+	// Packed refs count unless a loose file overrides them
+	packed, err := r.packedRefs()
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		delete(packed, ref.Name)
+	}
+	for name, ref := range packed {
+		refs = append(refs, Ref{Name: name, SHA: ref.sha})
+	}
+	// Annotated tags stand for the commits they point to
+	for i, ref := range refs {
+		if !strings.HasPrefix(ref.Name, "refs/tags/") {
+			continue
+		}
+		if peeled := packed[ref.Name].peeled; peeled != "" {
+			refs[i].SHA, refs[i].Tag = peeled, ref.SHA
+			continue
+		}
+		if objectType, _, err := r.ReadObject(ref.SHA); err != nil || objectType != object.TagObject {
+			continue
+		}
+		peeled, err := r.peelTag(ref.SHA)
+		if err != nil {
+			return nil, err
+		}
+		refs[i].SHA, refs[i].Tag = peeled, ref.SHA
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Name < refs[j].Name })
+	return refs, nil
+}
+
+// allRefTips returns the commits HEAD, every ref and the HEADs of other
+// worktrees point to
+func (r *Repository) allRefTips() ([]string, error) {
+	var tips []string
+	head, err := r.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	if head != "" {
+		tips = append(tips, head)
+	}
+
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range refs {
+		tips = append(tips, ref.SHA)
+	}
+	others, err := r.otherWorktreeHeads()
+	if err != nil {
+		return nil, err
+	}
+	return append(tips, others...), nil
+}
+
+// ShowRefOptions selects the refs ShowRefs lists
+type ShowRefOptions struct {
+	Head   bool // include HEAD
+	Heads  bool // only branches, together with Tags if set
+	Tags   bool // only tags, together with Heads if set
+	Verify bool // patterns are full ref names that must all exist
+}
+
+// ShowRefs lists refs sorted by name, HEAD first when asked for. A pattern
+// matches a ref whose name is the pattern or ends with "/" and the pattern,
+// so "main" matches refs/heads/main and refs/remotes/origin/main.
+func (r *Repository) ShowRefs(patterns []string, opts ShowRefOptions) ([]Ref, error) {
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	var shown []Ref
+	if opts.Head || opts.Verify && slices.Contains(patterns, "HEAD") {
+		head, err := r.HeadCommit()
+		if err != nil {
+			return nil, err
+		}
+		if head != "" {
+			shown = append(shown, Ref{Name: "HEAD", SHA: head})
+		}
+	}
+
+	if opts.Verify {
+		byName := make(map[string]Ref, len(refs))
+		for _, ref := range refs {
+			byName[ref.Name] = ref
+		}
+		for _, pattern := range patterns {
+			if pattern == "HEAD" && len(shown) > 0 {
+				continue
+			}
+			ref, ok := byName[pattern]
+			if !ok {
+				return nil, fmt.Errorf("'%s' - not a valid ref", pattern)
+			}
+			shown = append(shown, ref)
+		}
+		return shown, nil
+	}
+
+	for _, ref := range refs {
+		isHead, isTag := strings.HasPrefix(ref.Name, HeadsDir+"/"), strings.HasPrefix(ref.Name, "refs/tags/")
+		if (opts.Heads || opts.Tags) && !(opts.Heads && isHead || opts.Tags && isTag) {
+			continue
+		}
+		matched := len(patterns) == 0
+		for _, pattern := range patterns {
+			if ref.Name == pattern || strings.HasSuffix(ref.Name, "/"+pattern) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			shown = append(shown, ref)
+		}
+	}
+	return shown, nil
+}
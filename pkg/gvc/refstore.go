@@ -0,0 +1,203 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// errNeedsFileRefs is returned by maintenance that works on the files of the
+// default ref store, such as packing refs
+var errNeedsFileRefs = errors.New("only possible with the filesystem ref store")
+
+// RefStore keeps a repository's HEAD and the refs below refs/. A value is
+// either a SHA or, for a symbolic ref, "ref: " and the ref it points to. The
+// repository checks names and values, follows symbolic refs, reads
+// packed-refs and writes reflogs; a store only has to keep values.
+// Implementations must be safe for concurrent use.
+type RefStore interface {
+	// Read returns a ref's value, or an error matching fs.ErrNotExist
+	Read(name string) (string, error)
+	// Lock claims a ref for updating. Reads keep returning the old value
+	// until the claim is committed.
+	Lock(name string) (RefLock, error)
+	// List returns the name of every ref below dir, such as "refs/heads", sorted
+	List(dir string) ([]string, error)
+}
+
+// RefLock is a claim on one ref of a RefStore. Commit and Delete release it
+// whether or not they succeed.
+type RefLock interface {
+	// Commit sets the ref to value
+	Commit(value string) error
+	// Delete removes the ref; a missing ref is not an error
+	Delete() error
+	// Unlock releases the claim without changing the ref. It does nothing
+	// after Commit or Delete.
+	Unlock()
+}
+
+// FileRefStore is the default RefStore: a file per ref below .gvc, where
+// linked worktrees keep their own HEAD and share the rest
+type FileRefStore struct {
+	repo *Repository
+}
+
+// Read reads a ref's file
+func (s *FileRefStore) Read(name string) (string, error) {
+	data, err := os.ReadFile(s.repo.gitPath(filepath.FromSlash(name)))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Lock creates the ref's lock file
+func (s *FileRefStore) Lock(name string) (RefLock, error) {
+	l, err := s.repo.lockShared(s.repo.gitPath(filepath.FromSlash(name)))
+	if err != nil {
+		return nil, err
+	}
+	return fileRefLock{l}, nil
+}
+
+// List walks the ref files below dir
+func (s *FileRefStore) List(dir string) ([]string, error) {
+	var names []string
+	err := walkFiles(s.repo.gitPath(filepath.FromSlash(dir)), func(path string) error {
+		rel, err := filepath.Rel(s.repo.gitPath(filepath.FromSlash(dir)), path)
+		if err != nil {
+			return err
+		}
+		names = append(names, dir+"/"+filepath.ToSlash(rel))
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+// fileRefLock is the lock file of a ref kept by a FileRefStore
+type fileRefLock struct {
+	l *lockFile
+}
+
+func (l fileRefLock) Commit(value string) error { return l.l.commit([]byte(value + "\n")) }
+func (l fileRefLock) Delete() error             { return l.l.remove() }
+func (l fileRefLock) Unlock()                   { l.l.unlock() }
+
+// usesFileRefs reports whether refs live in the repository's own .gvc
+// directory, where packed-refs and loose ref files can be worked on directly
+func (r *Repository) usesFileRefs() bool {
+	store, ok := r.Refs.(*FileRefStore)
+	return ok && store.repo == r
+}
+
+// MemoryRefStore is a RefStore that keeps refs in memory, for tests and
+// short-lived repositories
+type MemoryRefStore struct {
+	mu     sync.Mutex
+	refs   map[string]string
+	locked map[string]bool
+}
+
+// NewMemoryRefStore returns an empty MemoryRefStore
+func NewMemoryRefStore() *MemoryRefStore {
+	return &MemoryRefStore{refs: make(map[string]string), locked: make(map[string]bool)}
+}
+
+// Read returns a ref's value
+func (s *MemoryRefStore) Read(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.refs[name]
+	if !ok {
+		return "", &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return value, nil
+}
+
+// Lock claims a ref. There is no other process to wait for, so a ref that is
+// already claimed fails at once.
+func (s *MemoryRefStore) Lock(name string) (RefLock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locked[name] {
+		return nil, fmt.Errorf("unable to lock %s: it is being updated", name)
+	}
+	s.locked[name] = true
+	return &memoryRefLock{store: s, name: name}, nil
+}
+
+// List returns the refs below dir, sorted
+func (s *MemoryRefStore) List(dir string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.refs {
+		if strings.HasPrefix(name, dir+"/") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// memoryRefLock is a claim on a ref of a MemoryRefStore
+type memoryRefLock struct {
+	store *MemoryRefStore
+	name  string
+	done  bool
+}
+
+func (l *memoryRefLock) Commit(value string) error {
+	l.release(func() { l.store.refs[l.name] = value })
+	return nil
+}
+
+func (l *memoryRefLock) Delete() error {
+	l.release(func() { delete(l.store.refs, l.name) })
+	return nil
+}
+
+func (l *memoryRefLock) Unlock() { l.release(func() {}) }
+
+// release applies change and gives up the claim, unless that already happened
+func (l *memoryRefLock) release(change func()) {
+	l.store.mu.Lock()
+	defer l.store.mu.Unlock()
+	if l.done {
+		return
+	}
+	change()
+	delete(l.store.locked, l.name)
+	l.done = true
+}
+
+// storedRefName returns the ref a path below .gvc holds, HEAD, refs or a
+// directory of them, when refs are kept by a store other than the
+// repository's own files; the metadata file functions hand those paths to
+// the store.
+func (r *Repository) storedRefName(path string) (string, bool) {
+	if r.usesFileRefs() {
+		return "", false
+	}
+	for _, base := range []string{r.GitDir, r.CommonDir} {
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			continue
+		}
+		name := filepath.ToSlash(rel)
+		if name != HeadFile && name != RefsDir && !strings.HasPrefix(name, RefsDir+"/") {
+			continue
+		}
+		if r.gitPath(rel) == path {
+			return name, true
+		}
+	}
+	return "", false
+}
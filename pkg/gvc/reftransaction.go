@@ -0,0 +1,401 @@
+package gvc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// RefEdit is one change in a ref transaction
+type RefEdit struct {
+	Ref string
+	// New is the SHA to point Ref at, or "" to delete it
+	New string
+	// Old, when CheckOld is set, is the value Ref must have for the
+	// transaction to go ahead, "" meaning that it must not exist
+	Old      string
+	CheckOld bool
+	// Verify only checks Old, leaving Ref as it is
+	Verify bool
+	// NoDeref updates HEAD itself, detaching it, instead of the branch it
+	// points to
+	NoDeref bool
+}
+
+// lockedRef is a ref held by a transaction
+type lockedRef struct {
+	edit    RefEdit
+	lock    *lockFile
+	current string
+	viaHead bool // the edit named HEAD and moves the branch it points to
+}
+
+// UpdateRefs applies edits as one transaction. Every ref is locked and
+// checked against its expected old value before any of them changes, so
+// either all edits are made or, when a check fails or another process holds
+// a lock, none. Each update and deletion is recorded in the reflog with
+// message. Deleting a ref also drops it from packed-refs.
+func (r *Repository) UpdateRefs(edits []RefEdit, message string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	headRef, err := r.HeadRef()
+	if err != nil {
+		return err
+	}
+
+	locked := make([]*lockedRef, 0, len(edits))
+	defer func() {
+		for _, ref := range locked {
+			ref.lock.unlock()
+		}
+	}()
+	seen := make(map[string]bool)
+	for _, edit := range edits {
+		viaHead := false
+		if edit.Ref == "HEAD" && headRef != "" && !edit.NoDeref {
+			edit.Ref, viaHead = headRef, true
+		}
+		if err := checkUpdatableRef(edit.Ref); err != nil {
+			return err
+		}
+		if edit.Ref == "HEAD" && edit.New == "" && !edit.Verify {
+			return errors.New("refusing to delete HEAD")
+		}
+		if seen[edit.Ref] {
+			return fmt.Errorf("multiple updates for ref '%s' not allowed", edit.Ref)
+		}
+		seen[edit.Ref] = true
+		if err := r.checkRefTarget(edit); err != nil {
+			return err
+		}
+		locked = append(locked, &lockedRef{edit: edit, viaHead: viaHead})
+	}
+
+	// Lock in name order so concurrent transactions cannot deadlock
+	sort.Slice(locked, func(i, j int) bool { return locked[i].edit.Ref < locked[j].edit.Ref })
+	for _, ref := range locked {
+		path := r.gitPath(HeadFile)
+		if ref.edit.Ref != "HEAD" {
+			path = r.gitPath(filepath.FromSlash(ref.edit.Ref))
+		}
+		if ref.lock, err = r.lockGitFile(path); err != nil {
+			return fmt.Errorf("cannot lock ref '%s': %w", ref.edit.Ref, err)
+		}
+		if ref.current, err = r.ReadRef(ref.edit.Ref); err != nil {
+			return err
+		}
+		if err := ref.checkOld(); err != nil {
+			return err
+		}
+	}
+
+	var deleted []string
+	for _, ref := range locked {
+		if !ref.edit.Verify && ref.edit.New == "" && ref.current != "" {
+			deleted = append(deleted, ref.edit.Ref)
+		}
+	}
+	if err := r.removePackedRefs(deleted); err != nil {
+		return err
+	}
+	// Logs are made room for before any ref moves, so none moves without one
+	for _, ref := range locked {
+		if ref.edit.Verify || ref.edit.New == "" {
+			continue
+		}
+		if err := r.prepareReflog(ref.edit.Ref); err != nil {
+			return err
+		}
+		if ref.viaHead {
+			if err := r.prepareReflog("HEAD"); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, ref := range locked {
+		edit := ref.edit
+		switch {
+		case edit.Verify:
+			ref.lock.unlock()
+			continue
+		case edit.New == "":
+			if err := ref.lock.remove(); err != nil {
+				return err
+			}
+			r.pruneRefDirs(edit.Ref)
+			if err := r.writeReflog(edit.Ref, nil); err != nil {
+				return err
+			}
+			if ref.current != "" {
+				r.notifyRefUpdate(RefUpdate{Name: edit.Ref, OldSHA: ref.current, Message: message})
+			}
+			continue
+		}
+		if err := ref.lock.commit([]byte(edit.New + "\n")); err != nil {
+			return fmt.Errorf("failed to write ref %s: %w", edit.Ref, err)
+		}
+		if err := r.appendReflog(edit.Ref, ref.current, edit.New, message); err != nil {
+			return err
+		}
+		if ref.viaHead {
+			if err := r.appendReflog("HEAD", ref.current, edit.New, message); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkOld compares a locked ref with the value its edit expects
+func (ref *lockedRef) checkOld() error {
+	edit := ref.edit
+	switch {
+	case !edit.CheckOld || ref.current == edit.Old:
+		return nil
+	case edit.Old == "":
+		return fmt.Errorf("cannot lock ref '%s': reference already exists", edit.Ref)
+	case ref.current == "":
+		return fmt.Errorf("cannot lock ref '%s': unable to resolve reference", edit.Ref)
+	default:
+		return fmt.Errorf("cannot lock ref '%s': is at %s but expected %s", edit.Ref, ref.current, edit.Old)
+	}
+}
+
+// checkUpdatableRef accepts HEAD and well-formed names below refs/
+func checkUpdatableRef(ref string) error {
+	if ref == "HEAD" {
+		return nil
+	}
+	if !strings.HasPrefix(ref, "refs/") {
+		return fmt.Errorf("refusing to update ref outside of refs/: %s", ref)
+	}
+	if _, err := CheckRefFormat(ref, RefFormatOptions{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// checkRefTarget makes sure an edit points its ref at an existing object,
+// and a branch or HEAD at a commit
+func (r *Repository) checkRefTarget(edit RefEdit) error {
+	if edit.Verify || edit.New == "" {
+		return nil
+	}
+	if err := r.Format.ValidateSHA(edit.New); err != nil {
+		return err
+	}
+	objectType, _, err := r.ReadObject(edit.New)
+	if err != nil {
+		return fmt.Errorf("trying to write ref '%s' with nonexistent object %s", edit.Ref, edit.New)
+	}
+	if objectType != object.CommitObject && (edit.Ref == "HEAD" || strings.HasPrefix(edit.Ref, HeadsDir+"/")) {
+		return fmt.Errorf("trying to write non-commit object %s to branch '%s'", edit.New, edit.Ref)
+	}
+	return nil
+}
+
+// removePackedRefs rewrites packed-refs without the given refs
+func (r *Repository) removePackedRefs(refs []string) error {
+	packed, err := r.packedRefs()
+	if err != nil {
+		return err
+	}
+	var remove []string
+	for _, ref := range refs {
+		if _, ok := packed[ref]; ok {
+			remove = append(remove, ref)
+		}
+	}
+	if len(remove) == 0 {
+		return nil
+	}
+
+	l, err := r.lockGitFile(r.gitPath(PackedRefsFile))
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+	if packed, err = r.readPackedRefsFile(); err != nil {
+		return err
+	}
+	for _, ref := range remove {
+		delete(packed, r.namespaceRef(ref))
+	}
+	if err := l.commit(formatPackedRefs(packed)); err != nil {
+		return fmt.Errorf("failed to write packed refs: %w", err)
+	}
+	return nil
+}
+
+// packedRefsHeader is the first line of packed-refs as gvc writes it: sorted,
+// with every annotated tag followed by the commit it peels to
+const packedRefsHeader = "# pack-refs with: peeled fully-peeled sorted \n"
+
+// formatPackedRefs serializes refs in the packed-refs format
+func formatPackedRefs(refs map[string]packedRef) []byte {
+	names := make([]string, 0, len(refs))
+	for name := range refs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b bytes.Buffer
+	b.WriteString(packedRefsHeader)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s %s\n", refs[name].sha, name)
+		if peeled := refs[name].peeled; peeled != "" {
+			fmt.Fprintf(&b, "^%s\n", peeled)
+		}
+	}
+	return b.Bytes()
+}
+
+// PackRefs moves loose refs into packed-refs, which a repository with many
+// refs reads faster than one file per ref. Tags and refs already packed are
+// packed by default, every ref below refs/ with all; branches otherwise stay
+// loose, as they move often. Unless prune is false, each packed loose file is
+// removed once packed-refs is written, provided it still holds the packed
+// value. It returns how many refs were packed.
+func (r *Repository) PackRefs(all, prune bool) (int, error) {
+	if err := r.checkWritable(); err != nil {
+		return 0, err
+	}
+	if !r.usesFileRefs() {
+		return 0, fmt.Errorf("packing refs is %w", errNeedsFileRefs)
+	}
+	if r.namespace != "" {
+		return 0, errors.New("packing refs is not possible in a namespace")
+	}
+	l, err := r.lockGitFile(r.gitPath(PackedRefsFile))
+	if err != nil {
+		return 0, err
+	}
+	defer l.unlock()
+	packed, err := r.packedRefs()
+	if err != nil {
+		return 0, err
+	}
+	if packed == nil {
+		packed = make(map[string]packedRef)
+	}
+
+	var loose []string
+	err = r.walkGitFiles(filepath.Join(r.CommonDir, RefsDir), func(path string) error {
+		rel, err := filepath.Rel(r.CommonDir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		_, wasPacked := packed[name]
+		if r.gitPath(rel) != path || !all && !wasPacked && !strings.HasPrefix(name, "refs/tags/") {
+			return nil
+		}
+		data, err := r.readGitFile(path)
+		if err != nil {
+			return err
+		}
+		sha := strings.TrimSpace(string(data))
+		if r.Format.ValidateSHA(sha) != nil {
+			return nil
+		}
+		ref := packedRef{sha: sha}
+		if peeled, err := r.peelTag(sha); err == nil && peeled != sha {
+			ref.peeled = peeled
+		}
+		packed[name] = ref
+		loose = append(loose, name)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list refs: %w", err)
+	}
+	if err := l.commit(formatPackedRefs(packed)); err != nil {
+		return 0, fmt.Errorf("failed to write packed refs: %w", err)
+	}
+
+	if prune {
+		for _, name := range loose {
+			if err := r.pruneLooseRef(name, packed[name].sha); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(loose), nil
+}
+
+// pruneLooseRef removes the loose file of a ref that was just packed, unless
+// it was updated in the meantime
+func (r *Repository) pruneLooseRef(ref, packedSHA string) error {
+	l, err := r.lockGitFile(r.gitPath(filepath.FromSlash(ref)))
+	if err != nil {
+		return err
+	}
+	data, err := r.readGitFile(r.gitPath(filepath.FromSlash(ref)))
+	if err != nil || strings.TrimSpace(string(data)) != packedSHA {
+		l.unlock()
+		return nil
+	}
+	return l.remove()
+}
+
+// SymbolicRef returns the ref a symbolic ref, HEAD or a remote's HEAD such as
+// refs/remotes/origin/HEAD, points to, or an error when it is not symbolic
+func (r *Repository) SymbolicRef(name string) (string, error) {
+	if name != "HEAD" {
+		if _, err := CheckRefFormat(name, RefFormatOptions{}); err != nil || !strings.HasPrefix(name, "refs/") {
+			return "", fmt.Errorf("ref %s is not a symbolic ref", name)
+		}
+		data, err := r.readGitFile(r.gitPath(filepath.FromSlash(name)))
+		target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: ")
+		if err != nil || !ok {
+			return "", fmt.Errorf("ref %s is not a symbolic ref", name)
+		}
+		return target, nil
+	}
+	target, err := r.HeadRef()
+	if err != nil {
+		return "", err
+	}
+	if target == "" {
+		return "", errors.New("ref HEAD is not a symbolic ref")
+	}
+	return target, nil
+}
+
+// SetSymbolicRef points HEAD at target, a ref below refs/ that need not exist
+// yet, without touching the index or working tree
+func (r *Repository) SetSymbolicRef(name, target, message string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if name != "HEAD" {
+		return fmt.Errorf("only HEAD can be a symbolic ref, not %s", name)
+	}
+	if !strings.HasPrefix(target, "refs/") {
+		return fmt.Errorf("refusing to point HEAD outside of refs/: %s", target)
+	}
+	if _, err := CheckRefFormat(target, RefFormatOptions{}); err != nil {
+		return err
+	}
+	oldSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	newSHA, err := r.ReadRef(target)
+	if err != nil {
+		return err
+	}
+	if err := r.writeGitFile(r.gitPath(HeadFile), []byte("ref: "+target+"\n")); err != nil {
+		return fmt.Errorf("failed to write HEAD: %w", err)
+	}
+	if message == "" || oldSHA == newSHA {
+		return nil
+	}
+	return r.appendReflog("HEAD", oldSHA, newSHA, message)
+}
@@ -0,0 +1,257 @@
+package gvc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Remote is a repository configured under remote.<name>.*
+type Remote struct {
+	Name    string
+	URL     string // fetch URL after url.<base>.insteadOf rewriting
+	PushURL string // push URL after url.<base>.pushInsteadOf rewriting
+}
+
+// rewriteURL applies the url.<base>.<key> rule with the longest matching prefix,
+// the way Git picks between overlapping insteadOf settings
+func rewriteURL(entries [][2]string, url, key string) (string, bool) {
+	suffix := "." + key
+	best, bestBase := "", ""
+	for _, entry := range entries {
+		name, prefix := entry[0], entry[1]
+		if !strings.HasPrefix(name, "url.") || !strings.HasSuffix(name, suffix) {
+			continue
+		}
+		if prefix == "" || !strings.HasPrefix(url, prefix) || len(prefix) <= len(best) {
+			continue
+		}
+		best, bestBase = prefix, strings.TrimSuffix(strings.TrimPrefix(name, "url."), suffix)
+	}
+	if best == "" {
+		return url, false
+	}
+	return bestBase + strings.TrimPrefix(url, best), true
+}
+
+// Remotes returns every configured remote, sorted by name
+func (r *Repository) Remotes() ([]*Remote, error) {
+	entries, err := r.ReadConfigEntries()
+	if err != nil {
+		return nil, err
+	}
+	urls := make(map[string]string)
+	pushURLs := make(map[string]string)
+	for _, entry := range entries {
+		rest, ok := strings.CutPrefix(entry[0], "remote.")
+		if !ok {
+			continue
+		}
+		if name, ok := strings.CutSuffix(rest, ".url"); ok {
+			urls[name] = entry[1]
+		} else if name, ok := strings.CutSuffix(rest, ".pushurl"); ok {
+			pushURLs[name] = entry[1]
+		}
+	}
+
+	var remotes []*Remote
+	for name, url := range urls {
+		remote := &Remote{Name: name}
+		remote.URL, _ = rewriteURL(entries, url, "insteadof")
+		if pushURL, ok := pushURLs[name]; ok {
+			// An explicit push URL is only subject to insteadOf
+			remote.PushURL, _ = rewriteURL(entries, pushURL, "insteadof")
+		} else if rewritten, ok := rewriteURL(entries, url, "pushinsteadof"); ok {
+			remote.PushURL = rewritten
+		} else {
+			remote.PushURL = remote.URL
+		}
+		remotes = append(remotes, remote)
+	}
+	sort.Slice(remotes, func(i, j int) bool { return remotes[i].Name < remotes[j].Name })
+	return remotes, nil
+}
+
+// LookupRemote returns the remote with the given name
+func (r *Repository) LookupRemote(name string) (*Remote, error) {
+	remotes, err := r.Remotes()
+	if err != nil {
+		return nil, err
+	}
+	for _, remote := range remotes {
+		if remote.Name == name {
+			return remote, nil
+		}
+	}
+	return nil, fmt.Errorf("no such remote: %s", name)
+}
+
+// remoteHeadRef is the symbolic ref recording a remote's default branch
+func remoteHeadRef(name string) string {
+	return RemotesDir + "/" + name + "/HEAD"
+}
+
+// RemoteHead returns the remote-tracking branch refs/remotes/<name>/HEAD
+// points at, the remote's default branch, e.g. refs/remotes/origin/main, or
+// "" when it is not known
+func (r *Repository) RemoteHead(name string) (string, error) {
+	data, err := r.readGitFile(r.gitPath(filepath.FromSlash(remoteHeadRef(name))))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", remoteHeadRef(name), err)
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: ")
+	if !ok {
+		return "", fmt.Errorf("%s is not a symbolic ref", remoteHeadRef(name))
+	}
+	return target, nil
+}
+
+// trackingRef returns the remote-tracking ref remote.<name>.fetch maps a
+// branch of the remote to, e.g. refs/heads/main to refs/remotes/origin/main,
+// or "" when no refspec maps it
+func (r *Repository) trackingRef(name, branch string) (string, error) {
+	specs, err := r.fetchRefspecs(name)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(branch, "refs/") {
+		branch = HeadsDir + "/" + branch
+	}
+	for _, spec := range specs {
+		if dst, ok := spec.match(branch); ok {
+			return dst, nil
+		}
+	}
+	return "", nil
+}
+
+// SetRemoteHead points refs/remotes/<name>/HEAD at the remote-tracking branch
+// of branch, e.g. main, which must have been fetched. An empty branch deletes
+// it.
+func (r *Repository) SetRemoteHead(name, branch string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if _, err := r.LookupRemote(name); err != nil {
+		return err
+	}
+	path := r.gitPath(filepath.FromSlash(remoteHeadRef(name)))
+	l, err := r.lockGitFile(path)
+	if err != nil {
+		return err
+	}
+	if branch == "" {
+		return l.remove()
+	}
+	target, err := r.trackingRef(name, branch)
+	if err == nil && target == "" {
+		err = fmt.Errorf("remote.%s.fetch does not map %s to a remote-tracking branch", name, branch)
+	}
+	if err != nil {
+		l.unlock()
+		return err
+	}
+	if sha, err := r.ReadRef(target); err != nil || sha == "" {
+		l.unlock()
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("not a valid ref: %s", target)
+	}
+	if err := l.commit([]byte("ref: " + target + "\n")); err != nil {
+		return fmt.Errorf("failed to write %s: %w", remoteHeadRef(name), err)
+	}
+	return nil
+}
+
+// SetRemoteHeadAuto asks the remote which branch its HEAD is on and points
+// refs/remotes/<name>/HEAD at it, returning the branch, e.g. refs/heads/main
+func (r *Repository) SetRemoteHeadAuto(name string) (string, error) {
+	remote, err := r.LookupRemote(name)
+	if err != nil {
+		return "", err
+	}
+	branch, err := remoteHeadBranch(remote.URL)
+	if err != nil {
+		return "", err
+	}
+	if branch == "" {
+		return "", fmt.Errorf("cannot determine the default branch of %s", name)
+	}
+	return branch, r.SetRemoteHead(name, branch)
+}
+
+// remoteHeadBranch returns the branch the HEAD of the repository or bundle at
+// url is on, or "" when it is detached or unborn
+func remoteHeadBranch(url string) (string, error) {
+	if path, err := localRemotePath(url); err == nil && isBundleFile(path) {
+		bundle, err := ReadBundleHeader(path)
+		if err != nil {
+			return "", err
+		}
+		return bundle.headBranch(), nil
+	}
+	if isGitURL(url) {
+		adv, err := lsRemote(nil, url)
+		if err != nil {
+			return "", err
+		}
+		return adv.headBranch, nil
+	}
+	src, err := openLocalRemote(url)
+	if err != nil {
+		return "", err
+	}
+	branch, err := src.HeadRef()
+	if err != nil {
+		return "", err
+	}
+	if sha, err := src.ReadRef(branch); err != nil || sha == "" {
+		return "", err
+	}
+	return branch, nil
+}
+
+// followRemoteHead updates refs/remotes/<name>/HEAD after a fetch to the
+// branch the remote's HEAD is on, branch, as remote.<name>.followRemoteHEAD
+// says: "create" it when it is missing (the default), "always" move it,
+// "warn" when it differs, or "never" touch it
+func (r *Repository) followRemoteHead(name, branch string) error {
+	policy, _, err := r.GetConfig("remote." + name + ".followRemoteHEAD")
+	if err != nil {
+		return err
+	}
+	if policy == "" {
+		policy = "create"
+	}
+	if policy != "create" && policy != "always" && policy != "warn" && policy != "never" {
+		return fmt.Errorf("bad value for remote.%s.followRemoteHEAD: %s", name, policy)
+	}
+	if policy == "never" || branch == "" {
+		return nil
+	}
+	target, err := r.trackingRef(name, branch)
+	if err != nil || target == "" {
+		return err
+	}
+	if sha, err := r.ReadRef(target); err != nil || sha == "" {
+		return err
+	}
+	current, err := r.RemoteHead(name)
+	if err != nil || current == target {
+		return err
+	}
+	switch {
+	case current == "" || policy == "always":
+		return r.SetRemoteHead(name, branch)
+	case policy == "warn":
+		fmt.Fprintf(r.Out, "warning: '%s' HEAD is now '%s', not '%s'; run 'gvc remote set-head %s -a' to follow it\n",
+			name, strings.TrimPrefix(branch, HeadsDir+"/"), strings.TrimPrefix(current, RemotesDir+"/"+name+"/"), name)
+	}
+	return nil
+}
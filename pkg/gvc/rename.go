@@ -0,0 +1,225 @@
+package gvc
+
+import (
+	"hash/fnv"
+	"path"
+	"sort"
+)
+
+// DefaultRenameThreshold is the similarity, in percent, a deleted and an
+// added file need to be reported as a rename
+const DefaultRenameThreshold = 50
+
+// DefaultRenameLimit caps the sources and destinations compared by content:
+// beyond limit² pairs only exact renames are found
+const DefaultRenameLimit = 1000
+
+// maxSimilarity is the scale similarity scores are computed on, as in Git
+const maxSimilarity = 60000
+
+// similarityChunk is the longest run of bytes hashed as one chunk when a
+// line does not end sooner
+const similarityChunk = 64
+
+// RenameThreshold returns the similarity percentage diff pairs renames at by
+// default: diff.renameThreshold, 50 unless set, or 0 when diff.renames is
+// false and renames are not looked for
+func (r *Repository) RenameThreshold() (int, error) {
+	enabled, err := r.getConfigBool("diff.renames", true)
+	if err != nil || !enabled {
+		return 0, err
+	}
+	threshold, err := r.getConfigInt("diff.renameThreshold", DefaultRenameThreshold)
+	if err != nil {
+		return 0, err
+	}
+	return min(max(int(threshold), 1), 100), nil
+}
+
+// renameCandidate is a deleted or added file considered for a rename pair
+type renameCandidate struct {
+	index int // position in the change list
+	entry *IndexEntry
+	path  string
+}
+
+// renamePair is a scored source and destination
+type renamePair struct {
+	src, dst int // indexes into the source and destination candidates
+	score    int
+}
+
+// DetectRenames pairs deleted files with added files whose content is at
+// least threshold percent similar, turning each pair into one change from
+// the old path to the new. Identical content is paired first, preferring a
+// source with the same file name; the rest are scored by how many bytes of
+// line-sized chunks the two files share, best pairs first. A threshold of 0
+// leaves the changes as they are.
+func (r *Repository) DetectRenames(changes []FileChange, threshold int) ([]FileChange, error) {
+	if threshold <= 0 {
+		return changes, nil
+	}
+	var srcs, dsts []renameCandidate
+	for i, change := range changes {
+		switch {
+		case change.New == nil && change.Old.Mode != GitlinkMode:
+			srcs = append(srcs, renameCandidate{index: i, entry: change.Old, path: change.Path})
+		case change.Old == nil && change.New.Mode != GitlinkMode:
+			dsts = append(dsts, renameCandidate{index: i, entry: change.New, path: change.Path})
+		}
+	}
+	if len(srcs) == 0 || len(dsts) == 0 {
+		return changes, nil
+	}
+
+	srcUsed := make([]bool, len(srcs))
+	dstUsed := make([]bool, len(dsts))
+	var pairs []renamePair
+
+	// Exact renames
+	for d, dst := range dsts {
+		best := -1
+		for s, src := range srcs {
+			if srcUsed[s] || src.entry.SHA != dst.entry.SHA || modeType(src.entry.Mode) != modeType(dst.entry.Mode) {
+				continue
+			}
+			if best < 0 || (path.Base(src.path) == path.Base(dst.path) && path.Base(srcs[best].path) != path.Base(dst.path)) {
+				best = s
+			}
+		}
+		if best >= 0 {
+			srcUsed[best], dstUsed[d] = true, true
+			pairs = append(pairs, renamePair{src: best, dst: d, score: maxSimilarity})
+		}
+	}
+
+	// Inexact renames, unless there are too many files to compare
+	limit, err := r.getConfigInt("diff.renameLimit", DefaultRenameLimit)
+	if err != nil {
+		return nil, err
+	}
+	remainingSrcs, remainingDsts := 0, 0
+	for s := range srcs {
+		if !srcUsed[s] {
+			remainingSrcs++
+		}
+	}
+	for d := range dsts {
+		if !dstUsed[d] {
+			remainingDsts++
+		}
+	}
+	if threshold < 100 && remainingSrcs > 0 && remainingDsts > 0 && (limit <= 0 || int64(remainingSrcs)*int64(remainingDsts) <= limit*limit) {
+		minScore := threshold * maxSimilarity / 100
+		type fileChunks struct {
+			counts map[uint64]int
+			size   int
+		}
+		chunks := make(map[string]fileChunks)
+		chunksOf := func(sha string) (fileChunks, error) {
+			if file, ok := chunks[sha]; ok {
+				return file, nil
+			}
+			_, content, err := r.ReadObject(sha)
+			if err != nil {
+				return fileChunks{}, err
+			}
+			file := fileChunks{counts: similarityChunks(content), size: len(content)}
+			chunks[sha] = file
+			return file, nil
+		}
+
+		var scored []renamePair
+		for d, dst := range dsts {
+			if dstUsed[d] {
+				continue
+			}
+			dstFile, err := chunksOf(dst.entry.SHA)
+			if err != nil {
+				return nil, err
+			}
+			for s, src := range srcs {
+				if srcUsed[s] || modeType(src.entry.Mode) != modeType(dst.entry.Mode) {
+					continue
+				}
+				srcFile, err := chunksOf(src.entry.SHA)
+				if err != nil {
+					return nil, err
+				}
+				if score := similarity(srcFile.counts, srcFile.size, dstFile.counts, dstFile.size, minScore); score >= minScore {
+					scored = append(scored, renamePair{src: s, dst: d, score: score})
+				}
+			}
+		}
+		sort.SliceStable(scored, func(i, j int) bool { return scored[i].score > scored[j].score })
+		for _, pair := range scored {
+			if srcUsed[pair.src] || dstUsed[pair.dst] {
+				continue
+			}
+			srcUsed[pair.src], dstUsed[pair.dst] = true, true
+			pairs = append(pairs, pair)
+		}
+	}
+	if len(pairs) == 0 {
+		return changes, nil
+	}
+
+	// Each rename takes the place of its addition; the deletions it explains go
+	drop := make(map[int]bool)
+	renamed := make(map[int]FileChange)
+	for _, pair := range pairs {
+		src, dst := srcs[pair.src], dsts[pair.dst]
+		drop[src.index] = true
+		renamed[dst.index] = FileChange{Path: dst.path, OldPath: src.path, Old: src.entry, New: dst.entry,
+			Similarity: pair.score * 100 / maxSimilarity}
+	}
+	var result []FileChange
+	for i, change := range changes {
+		if drop[i] {
+			continue
+		}
+		if rename, ok := renamed[i]; ok {
+			change = rename
+		}
+		result = append(result, change)
+	}
+	return result, nil
+}
+
+// similarityChunks counts the bytes of content in each distinct chunk: a
+// line, or a 64-byte run of a longer line
+func similarityChunks(content []byte) map[uint64]int {
+	counts := make(map[uint64]int)
+	for len(content) > 0 {
+		n := 0
+		for n < len(content) && n < similarityChunk {
+			n++
+			if content[n-1] == '\n' {
+				break
+			}
+		}
+		h := fnv.New64a()
+		h.Write(content[:n])
+		counts[h.Sum64()] += n
+		content = content[n:]
+	}
+	return counts
+}
+
+// similarity scores how much of two files' content is shared, from 0 to
+// maxSimilarity, skipping the comparison when their sizes alone put them
+// below minScore
+func similarity(src map[uint64]int, srcSize int, dst map[uint64]int, dstSize int, minScore int) int {
+	maxSize := max(srcSize, dstSize)
+	if maxSize == 0 {
+		return 0
+	}
+	if int64(maxSize)*int64(maxSimilarity-minScore) < int64(maxSize-min(srcSize, dstSize))*maxSimilarity {
+		return 0
+	}
+	copied := 0
+	for h, n := range src {
+		copied += min(n, dst[h])
+	}
+	return int(int64(copied) * maxSimilarity / int64(maxSize))
+}
@@ -0,0 +1,243 @@
+package gvc
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// RescueRefsDir holds refs that repair moved aside because their history is damaged
+const RescueRefsDir = "refs/rescue"
+
+// RepairReport describes what Repair restored and what it could not
+type RepairReport struct {
+	Restored map[string]string // object SHA -> where its content came from
+	Broken   []string          // objects still missing or corrupt
+	Rescued  []Ref             // refs moved under refs/rescue, by their new name
+}
+
+// Repair finds missing and corrupt objects reachable from HEAD and the refs and
+// rebuilds them where it can: from an intact packed copy, from working tree
+// files, and from the trees described by the index and the working tree. Refs
+// whose history is still damaged afterwards move under refs/rescue.
+func (r *Repository) Repair() (*RepairReport, error) {
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	report := &RepairReport{Restored: make(map[string]string)}
+	broken, err := r.brokenObjects()
+	if err != nil {
+		return nil, err
+	}
+	if len(broken) == 0 {
+		fmt.Fprintln(r.Out, "No missing or corrupt objects found")
+		return report, nil
+	}
+	fmt.Fprintf(r.Out, "Found %d missing or corrupt objects\n", len(broken))
+
+	// Each source is tried in turn for whatever is still broken
+	sources := []struct {
+		name    string
+		restore func(broken map[string]bool) error
+	}{
+		{"pack", r.restoreFromPacks},
+		{"working tree", r.restoreBlobsFromWorktree},
+		{"index", r.restoreTreesFromIndex},
+		{"working tree", r.restoreTreesFromWorktree},
+	}
+	for _, source := range sources {
+		if len(broken) == 0 {
+			break
+		}
+		if err := source.restore(broken); err != nil {
+			return nil, err
+		}
+		for sha := range broken {
+			if _, ok := r.intactObject(sha); ok {
+				report.Restored[sha] = source.name
+				delete(broken, sha)
+				fmt.Fprintf(r.Out, "Restored %s from the %s\n", sha, source.name)
+			}
+		}
+	}
+
+	for sha := range broken {
+		report.Broken = append(report.Broken, sha)
+	}
+	sort.Strings(report.Broken)
+	if len(broken) == 0 {
+		return report, nil
+	}
+
+	rescued, err := r.rescueDamagedRefs()
+	if err != nil {
+		return nil, err
+	}
+	report.Rescued = rescued
+	return report, nil
+}
+
+// intactObject reads sha and reports whether it exists and hashes correctly
+func (r *Repository) intactObject(sha string) (object.Type, bool) {
+	objectType, content, err := r.loadRawObject(sha)
+	if err != nil || r.Format.Hash(objectType, content) != sha {
+		return "", false
+	}
+	return objectType, true
+}
+
+// damageWalker reports whether an object's history includes a broken object,
+// remembering the answer for every object it visits
+type damageWalker struct {
+	repo    *Repository
+	broken  map[string]bool
+	damaged map[string]bool
+}
+
+func newDamageWalker(r *Repository) *damageWalker {
+	return &damageWalker{repo: r, broken: make(map[string]bool), damaged: make(map[string]bool)}
+}
+
+// walk returns true when sha or anything it reaches is missing or corrupt
+func (w *damageWalker) walk(sha string) bool {
+	if damaged, seen := w.damaged[sha]; seen {
+		return damaged
+	}
+	w.damaged[sha] = false
+	if _, ok := w.repo.intactObject(sha); !ok {
+		w.broken[sha] = true
+		w.damaged[sha] = true
+		return true
+	}
+	damaged := false
+	for _, next := range w.repo.objectLinks(sha) {
+		// Keep walking after the first hit so every broken object is found
+		if w.walk(next) {
+			damaged = true
+		}
+	}
+	w.damaged[sha] = damaged
+	return damaged
+}
+
+// repairTips returns HEAD and every ref
+func (r *Repository) repairTips() ([]Ref, error) {
+	var tips []Ref
+	head, err := r.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	if head != "" {
+		tips = append(tips, Ref{Name: HeadFile, SHA: head})
+	}
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	return append(tips, refs...), nil
+}
+
+// brokenObjects returns every missing or corrupt object reachable from the repair tips
+func (r *Repository) brokenObjects() (map[string]bool, error) {
+	tips, err := r.repairTips()
+	if err != nil {
+		return nil, err
+	}
+	walker := newDamageWalker(r)
+	for _, tip := range tips {
+		walker.walk(tip.SHA)
+	}
+	return walker.broken, nil
+}
+
+// restoreFromPacks removes corrupt loose objects that a pack holds an intact copy of
+func (r *Repository) restoreFromPacks(broken map[string]bool) error {
+	for sha := range broken {
+		if r.intactPackCopy(sha) == "" {
+			continue
+		}
+		if err := os.Remove(r.objectPath(sha)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove corrupt object %s: %w", sha, err)
+		}
+	}
+	return nil
+}
+
+// restoreBlobsFromWorktree stores every working tree file whose content hashes to a broken blob
+func (r *Repository) restoreBlobsFromWorktree(broken map[string]bool) error {
+	return filepath.WalkDir(r.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != r.Root && (isMetadataDir(d.Name()) || path == r.GitDir) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		sha, err := r.HashFile(path, false)
+		if err != nil {
+			return err
+		}
+		if broken[sha] {
+			if _, err := r.HashFile(path, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// restoreTreesFromIndex rewrites the trees the index describes, which recreates
+// any broken tree with the same entries
+func (r *Repository) restoreTreesFromIndex(broken map[string]bool) error {
+	index, err := r.ReadIndex()
+	if err != nil {
+		return err
+	}
+	if len(index.Entries) == 0 {
+		return nil
+	}
+	_, err = r.buildTree(index.Entries)
+	return err
+}
+
+// restoreTreesFromWorktree rewrites the trees of the working tree
+func (r *Repository) restoreTreesFromWorktree(broken map[string]bool) error {
+	_, err := r.WriteTree()
+	return err
+}
+
+// rescueDamagedRefs moves every ref whose history reaches a broken object under
+// refs/rescue, so the remaining refs can be used normally
+func (r *Repository) rescueDamagedRefs() ([]Ref, error) {
+	tips, err := r.repairTips()
+	if err != nil {
+		return nil, err
+	}
+	walker := newDamageWalker(r)
+	var rescued []Ref
+	for _, tip := range tips {
+		if tip.Name == HeadFile || strings.HasPrefix(tip.Name, RescueRefsDir+"/") || !walker.walk(tip.SHA) {
+			continue
+		}
+		name := RescueRefsDir + "/" + strings.TrimPrefix(tip.Name, RefsDir+"/")
+		if err := r.writeRef(name, tip.SHA, "repair: damaged history of "+tip.Name); err != nil {
+			return nil, err
+		}
+		if err := r.deleteRef(tip.Name); err != nil {
+			return nil, err
+		}
+		rescued = append(rescued, Ref{Name: name, SHA: tip.SHA})
+		fmt.Fprintf(r.Out, "Moved %s to %s: its history reaches a broken object\n", tip.Name, name)
+	}
+	return rescued, nil
+}
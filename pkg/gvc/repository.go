@@ -0,0 +1,494 @@
+// Package gvc implements a gvc repository: the object store, index, refs
+// and the history operations built on them. The gvc command is a thin
+// layer over this package.
+package gvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// GvcDir is the name of the directory holding a repository's metadata
+const GvcDir = ".gvc"
+
+// Locations inside the .gvc directory
+const (
+	ObjectsDir = "objects"
+	RefsDir    = "refs"
+	HeadFile   = "HEAD"
+	IndexFile  = "index"
+	LogsDir    = "logs"
+)
+
+// Repository is a working tree together with the .gvc directory recording its history
+type Repository struct {
+	// Root is the top of the working tree
+	Root string
+	// GitDir is the .gvc directory
+	GitDir string
+	// CommonDir holds what every worktree of the repository shares: objects,
+	// refs, config and hooks. It is GitDir except in a linked worktree, whose
+	// GitDir only keeps that worktree's HEAD, index and operation state.
+	CommonDir string
+	// Out receives the messages commands print; nothing is printed unless it is set
+	Out io.Writer
+	// Err receives warnings, kept apart from Out so they stay out of output
+	// other programs read; nothing is printed unless it is set
+	Err io.Writer
+	// Progress receives meters for long operations such as hashing the
+	// working tree, writing packs and checking out; none are drawn when nil.
+	// Meters rewrite their line with carriage returns, so it should be a
+	// terminal.
+	Progress io.Writer
+	// Verbose draws progress meters from the start, even for operations that
+	// finish quickly, instead of only once one has run for a while
+	Verbose bool
+	// Format is the hash algorithm objects are named with
+	Format *object.Format
+	// ReadOnly makes every attempt to modify the repository fail with ErrReadOnly
+	ReadOnly bool
+	// Git is set when the metadata is a Git repository's, which gvc reads
+	// but does not modify yet, so it is always opened read-only
+	Git bool
+	// Objects is where objects are kept: a FileStore on .gvc/objects unless
+	// it is replaced, e.g. with a MemoryStore, before the repository is used
+	Objects ObjectStore
+	// Refs is where HEAD and the refs below refs/ are kept: a FileRefStore
+	// on .gvc unless it is replaced, e.g. with a MemoryRefStore, before the
+	// repository is used. packed-refs and reflogs stay in .gvc.
+	Refs RefStore
+	// Operation names the command the repository is being used for, such as
+	// "commit" or "rebase"; hooks, editors and other programs gvc runs see it
+	// as GVC_OPERATION
+	Operation string
+	// Context, when set, stops long operations once it is done: walking
+	// history, hashing files, fetching, pushing, cloning, packing, archives,
+	// fsck and grep check it between objects, files and commits and return its error.
+	// A checkout checks it only before it starts changing files. A handle is
+	// cheap to open, so a server opens one per request with its context.
+	Context context.Context
+
+	loadedPacks       []*packFile
+	packsLoaded       bool
+	commitGraph       map[string]*CommitNode
+	commitGraphLoaded bool
+	commitNodeCache   map[string]*CommitNode
+	verify            bool
+	verifyLoaded      bool
+	fsync             fsyncPolicy
+	fsyncLoaded       bool
+	batch             *ObjectWriter   // set while objects are written in a batch
+	transfer          *transferMeter  // set while a fetch or push moves objects in
+	shallow           map[string]bool // commits whose parents were not fetched
+	namespace         string          // set by SetNamespace
+	shallowLoaded     bool
+	worktreeFmtOnce   sync.Once
+	worktreeFmt       worktreeFormat
+	worktreeFmtErr    error
+	objectCacheOnce   sync.Once
+	objCache          *objectCache // recently read commits, trees and tags
+	objectCacheErr    error
+	keyOnce           sync.Once
+	key               []byte // seals object files in an encrypted repository
+	keyErr            error
+	unsaved           *MemoryStore // working tree blobs a read-only repository could not store
+	memory            *memoryFiles // metadata files of a repository made by NewMemoryRepository
+	observers         []Observer
+	indexFile         string     // set by GVC_INDEX_FILE; "" for the index in GitDir
+	shared            sharedPerm // core.sharedRepository
+	maintenance       *lockFile  // the maintenance lock, while LockMaintenance holds it
+	maintenanceDepth  int
+}
+
+// canceled returns the error Context ended with, or nil while it is not done
+func (r *Repository) canceled() error {
+	if r.Context == nil {
+		return nil
+	}
+	return r.Context.Err()
+}
+
+// EnvGvcDir names the environment variable that points gvc at a .gvc directory
+// directly. Discovery is skipped and the starting directory is the working tree root.
+const EnvGvcDir = "GVC_DIR"
+
+// Environment variables Discover reads alongside GVC_DIR, and which Environ
+// sets for the programs gvc runs
+const (
+	// EnvGvcWorkTree names the top of the working tree, overriding the one
+	// found by discovery or the starting directory
+	EnvGvcWorkTree = "GVC_WORK_TREE"
+	// EnvGvcIndexFile names the index file, instead of the one in GVC_DIR
+	EnvGvcIndexFile = "GVC_INDEX_FILE"
+	// EnvGvcCommonDir names the directory holding the objects, refs, config
+	// and hooks every worktree shares; it is only set for programs gvc runs
+	EnvGvcCommonDir = "GVC_COMMON_DIR"
+	// EnvGvcOperation names the command a program is run for; it is only set
+	// for programs gvc runs
+	EnvGvcOperation = "GVC_OPERATION"
+)
+
+// newRepository returns a handle on the repository with the given working tree and .gvc directory
+func newRepository(root, gitDir string) (*Repository, error) {
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", root, err)
+	}
+	gitDir, err = filepath.Abs(gitDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", gitDir, err)
+	}
+	r := &Repository{
+		Root:            root,
+		GitDir:          gitDir,
+		CommonDir:       gitDir,
+		Out:             io.Discard,
+		Err:             io.Discard,
+		Format:          object.SHA1,
+		commitNodeCache: make(map[string]*CommitNode),
+	}
+	r.Objects = &FileStore{repo: r}
+	r.Refs = &FileRefStore{repo: r}
+	return r, nil
+}
+
+// Init creates a new repository at path whose objects are named with format,
+// or SHA-1 when format is nil
+func Init(path string, format *object.Format) (*Repository, error) {
+	return InitAt(path, filepath.Join(path, GvcDir), format)
+}
+
+// InitAt creates a new repository whose metadata lives in gitDir instead of root/.gvc
+func InitAt(root, gitDir string, format *object.Format) (*Repository, error) {
+	r, err := newRepository(root, gitDir)
+	if err != nil {
+		return nil, err
+	}
+	if format != nil {
+		r.Format = format
+	}
+	if err := r.initialize(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Open returns the repository whose working tree is path
+func Open(path string) (*Repository, error) {
+	gitDir, ok := gvcDirOf(path)
+	if !ok {
+		gitDir = filepath.Join(path, GvcDir)
+	}
+	return OpenAt(path, gitDir)
+}
+
+// OpenAt returns the repository with working tree root and metadata in gitDir.
+// A repository another user owns is refused unless safe.directory lists it.
+func OpenAt(root, gitDir string) (*Repository, error) {
+	return openAt(root, gitDir, true)
+}
+
+// openAt is OpenAt, checking who owns the repository only when checkOwner is set
+func openAt(root, gitDir string, checkOwner bool) (*Repository, error) {
+	r, err := newRepository(root, gitDir)
+	if err != nil {
+		return nil, err
+	}
+	if !isDir(r.GitDir) {
+		return nil, fmt.Errorf("not a gvc repository: %s", r.GitDir)
+	}
+	if data, err := os.ReadFile(r.gitPath(CommonDirFile)); err == nil {
+		commonDir := strings.TrimSpace(string(data))
+		if !filepath.IsAbs(commonDir) {
+			commonDir = filepath.Join(r.GitDir, commonDir)
+		}
+		r.CommonDir = filepath.Clean(commonDir)
+	}
+	if checkOwner {
+		if err := r.checkOwnership(); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.loadFormat(); err != nil {
+		return nil, err
+	}
+	if err := r.loadReadOnly(); err != nil {
+		return nil, err
+	}
+	if err := r.loadShared(); err != nil {
+		return nil, err
+	}
+	if isGitDir(r.GitDir) {
+		r.Git, r.ReadOnly = true, true
+	}
+	if !r.ReadOnly {
+		r.removeExitedLocks()
+	}
+	return r, nil
+}
+
+// Discover finds the repository containing start by walking up its parent
+// directories to the first one holding a .gvc directory. When GVC_DIR is set
+// it names the .gvc directory and start is taken as the working tree root;
+// as in Git, naming it explicitly skips the ownership check.
+//
+// GVC_WORK_TREE and GVC_INDEX_FILE, when set, name the working tree and the
+// index in place of the ones found, as they do for the hooks, editors and
+// commands gvc runs.
+func Discover(start string) (*Repository, error) {
+	r, err := discover(start)
+	if err != nil {
+		return nil, err
+	}
+	if workTree := os.Getenv(EnvGvcWorkTree); workTree != "" {
+		if r.Root, err = filepath.Abs(workTree); err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", workTree, err)
+		}
+	}
+	if indexFile := os.Getenv(EnvGvcIndexFile); indexFile != "" {
+		if r.indexFile, err = filepath.Abs(indexFile); err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", indexFile, err)
+		}
+	}
+	return r, nil
+}
+
+// discover finds the repository Discover opens, before the environment
+// overrides its working tree and index
+func discover(start string) (*Repository, error) {
+	if gitDir := os.Getenv(EnvGvcDir); gitDir != "" {
+		return openAt(start, gitDir, false)
+	}
+
+	dir, err := filepath.Abs(start)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", start, err)
+	}
+	for {
+		if gitDir, ok := gvcDirOf(dir); ok {
+			return OpenAt(dir, gitDir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, fmt.Errorf("not a gvc repository (or any of the parent directories): %s", start)
+		}
+		dir = parent
+	}
+}
+
+// ObjectFormatKey is the config setting naming a repository's object format
+const ObjectFormatKey = "extensions.objectFormat"
+
+// loadFormat reads the object format the repository was initialized with
+func (r *Repository) loadFormat() error {
+	name, found, err := r.GetConfig(ObjectFormatKey)
+	if err != nil || !found {
+		return err
+	}
+	format, err := object.ParseFormat(name)
+	if err != nil {
+		return fmt.Errorf("unsupported repository: %w", err)
+	}
+	r.Format = format
+	return nil
+}
+
+// gvcDirFilePrefix starts the .gvc file of a linked worktree, which names the
+// directory holding its metadata
+const gvcDirFilePrefix = "gvcdir: "
+
+// gvcDirOf returns the metadata directory of the working tree at root: its
+// .gvc directory, or the one a linked worktree's .gvc file points to. Without
+// a .gvc, a Git repository's .git directory or file is used.
+func gvcDirOf(root string) (string, bool) {
+	if gitDir, ok := metadataDirOf(root, GvcDir, gvcDirFilePrefix); ok {
+		return gitDir, true
+	}
+	return metadataDirOf(root, GitDirName, gitDirFilePrefix)
+}
+
+// metadataDirOf returns root/name when it is a directory, or the directory
+// a root/name file names after prefix
+func metadataDirOf(root, name, prefix string) (string, bool) {
+	path := filepath.Join(root, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", false
+	}
+	if info.IsDir() {
+		return path, true
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	gitDir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), prefix)
+	if !ok || gitDir == "" {
+		return "", false
+	}
+	if !filepath.IsAbs(gitDir) {
+		gitDir = filepath.Join(root, gitDir)
+	}
+	return gitDir, true
+}
+
+// isRepoRoot reports whether dir is the top of a working tree: a nested
+// repository or a linked worktree
+func isRepoRoot(dir string) bool {
+	_, ok := gvcDirOf(dir)
+	return ok
+}
+
+// isDir reports whether path exists and is a directory
+func isDir(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// RelPath turns a path given relative to the current directory into the
+// slash-separated path inside the working tree that trees and the index use
+func (r *Repository) RelPath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	rel, err := filepath.Rel(r.Root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside repository at %s", path, r.Root)
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// gitPath joins elements onto the .gvc directory: the worktree's own for
+// HEAD, the index and operation state, the common one for everything shared
+func (r *Repository) gitPath(elem ...string) string {
+	path := filepath.Join(elem...)
+	if r.CommonDir != r.GitDir && sharedGitPath(filepath.ToSlash(path)) {
+		return filepath.Join(r.CommonDir, path)
+	}
+	return filepath.Join(r.GitDir, path)
+}
+
+// indexPath returns the index file: the one in GitDir, or the file
+// GVC_INDEX_FILE named when the repository was discovered
+func (r *Repository) indexPath() string {
+	if r.indexFile != "" {
+		return r.indexFile
+	}
+	return r.gitPath(IndexFile)
+}
+
+// Environ returns the environment for a program gvc runs for the
+// repository, such as a hook, an editor or a bisect run command: the
+// process's own, with GVC_DIR, GVC_COMMON_DIR, GVC_WORK_TREE and
+// GVC_INDEX_FILE naming this repository's directories and index, which in
+// a linked worktree are that worktree's, and GVC_OPERATION the command it is
+// run for. Without an Operation, an interrupted cherry-pick, revert,
+// rebase, merge or subtree merge names it; with neither, GVC_OPERATION is unset.
+func (r *Repository) Environ() []string {
+	vars := []string{EnvGvcDir, EnvGvcCommonDir, EnvGvcWorkTree, EnvGvcIndexFile, EnvGvcOperation}
+	var env []string
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if !slices.Contains(vars, name) {
+			env = append(env, kv)
+		}
+	}
+	env = append(env,
+		EnvGvcDir+"="+r.GitDir,
+		EnvGvcCommonDir+"="+r.CommonDir,
+		EnvGvcWorkTree+"="+r.Root,
+		EnvGvcIndexFile+"="+r.indexPath())
+	operation := r.Operation
+	if operation == "" {
+		operation = r.OperationInProgress()
+	}
+	if operation != "" {
+		env = append(env, EnvGvcOperation+"="+operation)
+	}
+	return env
+}
+
+// sharedGitPaths are the parts of a .gvc directory every worktree shares,
+// and privateGitPaths the exceptions inside them
+var (
+	sharedGitPaths  = []string{ObjectsDir, RefsDir, LogsDir, ConfigFile, HooksDir, "info", WorktreesDir, PackedRefsFile, LFSDir, ShallowFile}
+	privateGitPaths = []string{LogsDir + "/" + HeadFile, BisectRefsDir, LogsDir + "/" + BisectRefsDir}
+)
+
+// sharedGitPath reports whether a slash-separated path inside .gvc belongs to
+// every worktree rather than one
+func sharedGitPath(path string) bool {
+	under := func(dir string) bool { return path == dir || strings.HasPrefix(path, dir+"/") }
+	for _, dir := range privateGitPaths {
+		if under(dir) {
+			return false
+		}
+	}
+	for _, dir := range sharedGitPaths {
+		if under(dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// worktreePath returns where a slash-separated tree path lives on disk
+func (r *Repository) worktreePath(path string) string {
+	return filepath.Join(r.Root, filepath.FromSlash(path))
+}
+
+// initialize sets up a new .gvc directory structure if it doesn't already exist.
+func (r *Repository) initialize() error {
+	if _, err := os.Stat(r.GitDir); err == nil {
+		return errors.New("gvc repository already initialized")
+	}
+
+	// Create required subdirectories
+	dirs := []string{r.GitDir, r.gitPath(ObjectsDir), r.gitPath(RefsDir), r.gitPath(RefsDir, "heads")}
+	for _, dir := range dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+
+	return r.initializeMetadata()
+}
+
+// initializeMetadata writes the files of a new repository: HEAD on main, the
+// object format when it is not the default, and an empty index
+func (r *Repository) initializeMetadata() error {
+	// Write the HEAD reference to point to main branch
+	headContent := []byte("ref: refs/heads/main\n")
+	if err := r.writeGitFile(r.gitPath(HeadFile), headContent); err != nil {
+		return fmt.Errorf("failed to write HEAD file: %w", err)
+	}
+
+	// Only repositories that leave the SHA-1 default record their object format,
+	// the same way Git marks them with repository format version 1
+	if r.Format != object.SHA1 {
+		if err := r.SetConfig("core.repositoryFormatVersion", "1"); err != nil {
+			return err
+		}
+		if err := r.SetConfig(ObjectFormatKey, r.Format.Name); err != nil {
+			return err
+		}
+	}
+
+	// Initialize empty index
+	emptyIndex := Index{Entries: []IndexEntry{}}
+	if err := r.WriteIndex(&emptyIndex); err != nil {
+		return fmt.Errorf("failed to initialize index: %w", err)
+	}
+
+	return nil
+}
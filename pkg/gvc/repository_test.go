@@ -0,0 +1,30 @@
+package gvc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiscoverFindsRepositoryAbove(t *testing.T) {
+	repo, commitSHA := newTestRepoWithCommit(t)
+
+	sub := filepath.Join(repo.Root, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	found, err := Discover(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.Root != repo.Root || found.GitDir != repo.GitDir {
+		t.Errorf("found %s (%s), want %s (%s)", found.Root, found.GitDir, repo.Root, repo.GitDir)
+	}
+	commits, err := found.Log()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commits) != 1 || commits[0].SHA != commitSHA || commits[0].Message != "first" {
+		t.Errorf("log of the found repository is %v, want the one commit %s", commits, commitSHA)
+	}
+}
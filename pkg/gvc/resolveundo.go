@@ -0,0 +1,210 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/diff"
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// recordResolveUndo keeps the base, ours and theirs versions of paths a
+// merge left in conflict as resolve-undo records, replacing older records
+// for the same paths. They outlast the conflict's resolution, so it can be
+// undone with RecreateConflicts.
+func (r *Repository) recordResolveUndo(paths []string, base, ours, theirs map[string]IndexEntry) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	l, err := r.lockGitFile(r.indexPath())
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+	index, err := r.ReadIndex()
+	if err != nil {
+		return err
+	}
+
+	conflicted := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		conflicted[path] = true
+	}
+	var records []ResolveUndoEntry
+	for _, record := range index.ResolveUndo {
+		if !conflicted[record.Path] {
+			records = append(records, record)
+		}
+	}
+	for _, path := range paths {
+		record := ResolveUndoEntry{Path: path}
+		for i, side := range []map[string]IndexEntry{base, ours, theirs} {
+			if entry, ok := side[path]; ok {
+				record.Modes[i], record.SHAs[i] = entry.Mode, entry.SHA
+			}
+		}
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Path < records[j].Path })
+	index.ResolveUndo = records
+
+	data, err := r.encodeIndex(index)
+	if err != nil {
+		return err
+	}
+	if err := l.commit(data); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// RecreateConflicts brings back the conflicts of paths at or below specs
+// from their resolve-undo records, as Git's checkout --conflict=merge does:
+// the working tree file gets ours and theirs merged again, with conflict
+// markers, and is staged that way. During a cherry-pick, revert or rebase
+// the paths count as unresolved once more. A spec matching no record is an
+// error. It returns the paths whose conflicts were recreated.
+func (r *Repository) RecreateConflicts(specs []string) ([]string, error) {
+	if err := r.checkWritable(); err != nil {
+		return nil, err
+	}
+	if len(specs) == 0 {
+		return nil, errors.New("no paths given")
+	}
+	index, err := r.ReadIndex()
+	if err != nil {
+		return nil, err
+	}
+	var records []ResolveUndoEntry
+	for _, spec := range specs {
+		found := false
+		for _, record := range index.ResolveUndo {
+			if underPaths(record.Path, []string{spec}) {
+				records = append(records, record)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("path '%s' has no conflict to recreate", spec)
+		}
+	}
+
+	conflicts := make(map[string]IndexEntry)
+	for _, record := range records {
+		if _, ok := conflicts[record.Path]; ok {
+			continue
+		}
+		entry, err := r.conflictEntry(record)
+		if err != nil {
+			return nil, err
+		}
+		if err := r.writeWorkingFile(entry); err != nil {
+			return nil, err
+		}
+		conflicts[record.Path] = entry
+	}
+
+	l, err := r.lockGitFile(r.indexPath())
+	if err != nil {
+		return nil, err
+	}
+	defer l.unlock()
+	if index, err = r.ReadIndex(); err != nil {
+		return nil, err
+	}
+	var entries []IndexEntry
+	for _, entry := range index.Entries {
+		if _, ok := conflicts[normalizePath(entry.Path)]; !ok {
+			entries = append(entries, entry)
+		}
+	}
+	var paths []string
+	for path, entry := range conflicts {
+		entries = append(entries, entry)
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	index.Entries = entries
+	data, err := r.encodeIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.commit(data); err != nil {
+		return nil, fmt.Errorf("failed to write index: %w", err)
+	}
+
+	if r.OperationInProgress() != "" {
+		if err := r.addConflictPaths(paths); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// conflictEntry builds the entry a conflicted path had from its
+// resolve-undo record: ours and theirs merged with conflict markers, or
+// the side that kept a file the other deleted
+func (r *Repository) conflictEntry(record ResolveUndoEntry) (IndexEntry, error) {
+	base, ours, theirs := record.Modes[0] != "", record.Modes[1] != "", record.Modes[2] != ""
+	switch {
+	case !ours && !theirs:
+		return IndexEntry{}, fmt.Errorf("path '%s' has no conflict to recreate", record.Path)
+	case !theirs:
+		return IndexEntry{Path: record.Path, SHA: record.SHAs[1], Mode: record.Modes[1]}, nil
+	case !ours:
+		return IndexEntry{Path: record.Path, SHA: record.SHAs[2], Mode: record.Modes[2]}, nil
+	}
+
+	sides := make([][]string, 3)
+	for i, sha := range record.SHAs {
+		if record.Modes[i] == "" {
+			continue
+		}
+		_, content, err := r.ReadObject(sha)
+		if err != nil {
+			return IndexEntry{}, err
+		}
+		sides[i] = diff.SplitLines(content)
+	}
+	merged, _ := diff.Merge(sides[0], sides[1], sides[2], "ours", "theirs")
+	sha, err := r.WriteObject(object.BlobObject, []byte(strings.Join(merged, "")))
+	if err != nil {
+		return IndexEntry{}, err
+	}
+	// Take whichever side changed the mode, as the merge did
+	mode := record.Modes[1]
+	if base && record.Modes[1] == record.Modes[0] {
+		mode = record.Modes[2]
+	}
+	return IndexEntry{Path: record.Path, SHA: sha, Mode: mode}, nil
+}
+
+// addConflictPaths adds paths to those the operation in progress records as
+// conflicted
+func (r *Repository) addConflictPaths(paths []string) error {
+	data, err := os.ReadFile(r.gitPath(ConflictsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read conflicts: %w", err)
+	}
+	recorded := make(map[string]bool)
+	var conflicts []string
+	for _, path := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if path != "" && !recorded[path] {
+			recorded[path] = true
+			conflicts = append(conflicts, path)
+		}
+	}
+	for _, path := range paths {
+		if !recorded[path] {
+			conflicts = append(conflicts, path)
+		}
+	}
+	sort.Strings(conflicts)
+	if err := writeFileLocked(r.gitPath(ConflictsFile), []byte(strings.Join(conflicts, "\n")+"\n")); err != nil {
+		return fmt.Errorf("failed to record conflicts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package gvc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// PartSuffix marks a pack whose copy was interrupted; the next fetch
+// continues it instead of starting over
+const PartSuffix = ".part"
+
+// resumeChunk is how much of a pack is copied between checks for cancellation
+const resumeChunk = 4 << 20
+
+// packCopies holds a mutex per pack path, so fetches of remotes sharing
+// history running at once do not both copy the same pack
+var packCopies sync.Map
+
+// copyPack copies a pack into place through path+PartSuffix, which is kept
+// when the copy is interrupted, so fetching again continues where it stopped
+// instead of starting multi-gigabyte packs from zero. A pack never changes
+// once named, so the bytes already copied are still good; the finished file
+// is checked against the checksum the pack is named after before it is put
+// in place, and a partial copy that fails the check is copied again from
+// the start. It reports false when the pack was put in place meanwhile, by
+// a fetch running alongside.
+func (r *Repository) copyPack(src, dst string) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return false, fmt.Errorf("failed to create directory for %s: %w", dst, err)
+	}
+	mu, _ := packCopies.LoadOrStore(dst, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	defer mu.(*sync.Mutex).Unlock()
+	if _, err := os.Stat(dst); err == nil {
+		return false, nil
+	}
+	part := dst + PartSuffix
+	for attempt := 0; ; attempt++ {
+		if err := r.resumeCopy(src, part); err != nil {
+			return false, err
+		}
+		err := r.verifyPackChecksum(part, strings.TrimSuffix(filepath.Base(dst), ".pack"))
+		if err == nil {
+			break
+		}
+		if err := os.Remove(part); err != nil {
+			return false, fmt.Errorf("failed to remove %s: %w", part, err)
+		}
+		if attempt > 0 {
+			return false, err
+		}
+		fmt.Fprintf(r.Out, "warning: %s: %v; copying it again\n", filepath.Base(part), err)
+	}
+	if err := os.Rename(part, dst); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return true, nil
+}
+
+// resumeCopy appends to part whatever of src it does not hold yet
+func (r *Repository) resumeCopy(src, part string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	out, err := os.OpenFile(part, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", part, err)
+	}
+	defer out.Close()
+	offset, err := out.Seek(0, io.SeekEnd)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", part, err)
+	}
+	if offset > info.Size() {
+		if err := out.Truncate(0); err != nil {
+			return fmt.Errorf("failed to write %s: %w", part, err)
+		}
+		if offset, err = out.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to write %s: %w", part, err)
+		}
+	}
+	if offset > 0 && offset < info.Size() {
+		fmt.Fprintf(r.Out, "Resuming %s at %d of %d bytes\n", filepath.Base(src), offset, info.Size())
+	}
+
+	reader := io.NewSectionReader(in, offset, info.Size()-offset)
+	for {
+		if err := r.canceled(); err != nil {
+			return err
+		}
+		_, err := io.CopyN(r.transfer.writer(out), reader, resumeChunk)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", src, err)
+		}
+	}
+	if err := out.Sync(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", part, err)
+	}
+	return out.Close()
+}
+
+// verifyPackChecksum checks that a pack file ends with the checksum of
+// everything before it, and that it is the checksum name records
+func (r *Repository) verifyPackChecksum(path, name string) error {
+	f, err := r.openObjectFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	defer f.Close()
+	size := f.Size()
+	if size < 12+int64(r.Format.Size) {
+		return errors.New("pack is truncated")
+	}
+	sum := r.Format.New()
+	if _, err := io.Copy(sum, io.NewSectionReader(f, 0, size-int64(r.Format.Size))); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	trailer := make([]byte, r.Format.Size)
+	if _, err := f.ReadAt(trailer, size-int64(r.Format.Size)); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if !bytes.Equal(sum.Sum(nil), trailer) || "pack-"+hex.EncodeToString(trailer) != name {
+		return errors.New("pack checksum mismatch")
+	}
+	return nil
+}
+
+// CloneStateFile records a clone that was interrupted while objects were
+// being copied, so running the same clone again resumes it
+const CloneStateFile = "CLONE_STATE"
+
+// cloneState is what CloneStateFile records: where the clone comes from and
+// the options that decide which objects it copies
+type cloneState struct {
+	url   string
+	depth int
+}
+
+// writeCloneState records the clone in progress
+func (r *Repository) writeCloneState(state cloneState) error {
+	data := fmt.Sprintf("url %s\ndepth %d\n", state.url, state.depth)
+	return writeFileLocked(r.gitPath(CloneStateFile), []byte(data))
+}
+
+// readCloneState reads the clone state of the repository in dir, reporting
+// false when dir holds no interrupted clone
+func readCloneState(dir string) (cloneState, bool, error) {
+	data, err := os.ReadFile(filepath.Join(dir, GvcDir, CloneStateFile))
+	if os.IsNotExist(err) {
+		return cloneState{}, false, nil
+	}
+	if err != nil {
+		return cloneState{}, false, fmt.Errorf("failed to read clone state: %w", err)
+	}
+	var state cloneState
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "url":
+			state.url = value
+		case "depth":
+			if state.depth, err = strconv.Atoi(value); err != nil {
+				return cloneState{}, false, fmt.Errorf("malformed clone state: %q", line)
+			}
+		}
+	}
+	return state, true, nil
+}
@@ -0,0 +1,90 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Revert records a new commit undoing the changes introduced by a commit
+func (r *Repository) Revert(rev string) error {
+	if op := r.OperationInProgress(); op != "" {
+		return fmt.Errorf("a %s is already in progress; use --continue or --abort", op)
+	}
+
+	commitSHA, err := r.ResolveCommit(rev)
+	if err != nil {
+		return err
+	}
+	commit, err := r.ReadCommit(commitSHA)
+	if err != nil {
+		return err
+	}
+	parents, err := r.commitParents(commitSHA)
+	if err != nil {
+		return err
+	}
+	if len(parents) > 1 {
+		return fmt.Errorf("commit %s is a merge; reverting merges is not supported", commitSHA[:7])
+	}
+
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	if headSHA == "" {
+		return errors.New("cannot revert on an empty branch")
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	if err := r.checkCleanState(head); err != nil {
+		return err
+	}
+
+	parentTree := ""
+	if len(parents) == 1 {
+		parentCommit, err := r.ReadCommit(parents[0])
+		if err != nil {
+			return err
+		}
+		parentTree = parentCommit.TreeSHA
+	}
+
+	// The inverse patch is the change from the commit back to its parent
+	subject := strings.SplitN(commit.Message, "\n", 2)[0]
+	label := fmt.Sprintf("parent of %s (%s)", commitSHA[:7], subject)
+	conflicts, err := r.applyPick(commit.TreeSHA, parentTree, label, head)
+	if err != nil {
+		return err
+	}
+
+	message := fmt.Sprintf("Revert \"%s\"\n\nThis reverts commit %s.", subject, commitSHA)
+	if len(conflicts) > 0 {
+		if err := r.writeSequencerState(r.gitPath(RevertHeadFile), commitSHA, message, conflicts); err != nil {
+			return err
+		}
+		return conflictError(fmt.Errorf("could not revert %s... %s\nconflicts in:\n\t%s\n"+
+			"resolve them, 'gvc add' the files and run 'gvc revert --continue' (or --abort)",
+			commitSHA[:7], subject, strings.Join(conflicts, "\n\t")))
+	}
+
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+	_, err = r.commitStaged(staged, []string{headSHA}, r.authorIdent(), time.Now(), message, "revert: "+subject)
+	return err
+}
+
+// ContinueRevert commits an interrupted revert once its conflicts are resolved
+func (r *Repository) ContinueRevert() error {
+	return r.continuePick(r.gitPath(RevertHeadFile), "revert", false)
+}
+
+// AbortRevert gives up an interrupted revert
+func (r *Repository) AbortRevert() error {
+	return r.abortPick(r.gitPath(RevertHeadFile), "revert")
+}
@@ -0,0 +1,348 @@
+package gvc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// refCandidates lists the full ref names a short name may refer to, in lookup order
+func refCandidates(name string) []string {
+	if name == "HEAD" || strings.HasPrefix(name, "refs/") {
+		return []string{name}
+	}
+	return []string{
+		"refs/" + name,
+		"refs/tags/" + name,
+		"refs/heads/" + name,
+		"refs/remotes/" + name,
+		"refs/remotes/" + name + "/HEAD",
+	}
+}
+
+// maxSymbolicRefDepth bounds how many symbolic refs ReadRef follows, so refs
+// pointing at each other cannot loop
+const maxSymbolicRefDepth = 5
+
+// ReadRef returns the commit a ref points to, or "" when it does not exist.
+// Symbolic refs such as refs/remotes/origin/HEAD are followed.
+func (r *Repository) ReadRef(ref string) (string, error) {
+	for range maxSymbolicRefDepth {
+		if ref == "HEAD" {
+			return r.HeadCommit()
+		}
+		path := r.gitPath(filepath.FromSlash(ref))
+		data, err := r.readGitFile(path)
+		if err != nil {
+			// A directory, such as refs/remotes/origin, is not a ref
+			if os.IsNotExist(err) || isDir(r.namespacePath(path)) {
+				return r.readPackedRef(ref)
+			}
+			return "", fmt.Errorf("failed to read ref %s: %w", ref, err)
+		}
+		value := strings.TrimSpace(string(data))
+		target, ok := strings.CutPrefix(value, "ref: ")
+		if !ok {
+			return value, nil
+		}
+		ref = target
+	}
+	return "", fmt.Errorf("too many levels of symbolic refs at %s", ref)
+}
+
+// resolveObjectPrefix expands an abbreviated object SHA
+func (r *Repository) resolveObjectPrefix(prefix string) (string, error) {
+	if len(prefix) < 4 || len(prefix) > r.Format.HexSize() {
+		return "", nil
+	}
+	if _, err := hex.DecodeString(prefix[:len(prefix)&^1]); err != nil {
+		return "", nil
+	}
+	prefix = strings.ToLower(prefix)
+	if !r.usesFileStore() {
+		shas, err := r.Objects.List()
+		if err != nil {
+			return "", fmt.Errorf("failed to list objects: %w", err)
+		}
+		var match string
+		for i := sort.SearchStrings(shas, prefix); i < len(shas) && strings.HasPrefix(shas[i], prefix); i++ {
+			if match != "" {
+				return "", fmt.Errorf("short SHA %s is ambiguous", prefix)
+			}
+			match = shas[i]
+		}
+		return match, nil
+	}
+
+	dirEntries, err := os.ReadDir(r.gitPath(ObjectsDir, prefix[:2]))
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read object directory: %w", err)
+	}
+
+	var match string
+	for _, entry := range dirEntries {
+		if strings.HasPrefix(entry.Name(), prefix[2:]) {
+			if match != "" {
+				return "", fmt.Errorf("short SHA %s is ambiguous", prefix)
+			}
+			match = prefix[:2] + entry.Name()
+		}
+	}
+
+	packs, err := r.loadPacks()
+	if err != nil {
+		return "", err
+	}
+	for _, pack := range packs {
+		for i := sort.SearchStrings(pack.shas, prefix); i < len(pack.shas) && strings.HasPrefix(pack.shas[i], prefix); i++ {
+			if match != "" && match != pack.shas[i] {
+				return "", fmt.Errorf("short SHA %s is ambiguous", prefix)
+			}
+			match = pack.shas[i]
+		}
+	}
+	return match, nil
+}
+
+// resolveRevision turns a revision such as HEAD~2, main^, stash@{1},
+// v1.0^{tree} or an abbreviated SHA into a full object SHA
+func (r *Repository) resolveRevision(rev string) (string, error) {
+	base := rev
+	suffix := ""
+	if i := strings.IndexAny(rev, "~^"); i >= 0 {
+		base, suffix = rev[:i], rev[i:]
+	}
+	if base == "" || base == "@" {
+		base = "HEAD"
+	}
+
+	sha, err := r.resolveRevisionBase(base)
+	if err != nil {
+		return "", err
+	}
+	for suffix != "" {
+		op := suffix[0]
+		suffix = suffix[1:]
+		if op == '^' && strings.HasPrefix(suffix, "{") {
+			end := strings.IndexByte(suffix, '}')
+			if end < 0 {
+				return "", fmt.Errorf("invalid revision: %s", rev)
+			}
+			if sha, err = r.peelTo(sha, suffix[1:end], rev); err != nil {
+				return "", err
+			}
+			suffix = suffix[end+1:]
+			continue
+		}
+		// Parents are those of the commit an annotated tag points to
+		if sha, err = r.peelTag(sha); err != nil {
+			return "", err
+		}
+		digits := 0
+		for digits < len(suffix) && suffix[digits] >= '0' && suffix[digits] <= '9' {
+			digits++
+		}
+		n := 1
+		if digits > 0 {
+			n, _ = strconv.Atoi(suffix[:digits])
+			suffix = suffix[digits:]
+		}
+
+		if op == '^' {
+			// <rev>^n selects the nth parent, <rev>^0 the commit itself
+			if n == 0 {
+				continue
+			}
+			parents, err := r.commitParents(sha)
+			if err != nil {
+				return "", err
+			}
+			if n > len(parents) {
+				return "", fmt.Errorf("revision %s has no parent %d", rev, n)
+			}
+			sha = parents[n-1]
+			continue
+		}
+
+		// <rev>~n follows first parents n times
+		for ; n > 0; n-- {
+			commit, err := r.ReadCommit(sha)
+			if err != nil {
+				return "", err
+			}
+			if commit.ParentSHA == "" {
+				return "", fmt.Errorf("revision %s goes past the root commit", rev)
+			}
+			sha = commit.ParentSHA
+		}
+	}
+	return sha, nil
+}
+
+// peelTo peels sha to an object of kind, as <rev>^{<kind>} does: tags are
+// followed, and a commit stands for its tree. An empty kind only follows
+// tags, tag keeps sha if it is a tag and object if it exists.
+func (r *Repository) peelTo(sha, kind, rev string) (string, error) {
+	switch kind {
+	case "", "commit", "tree", "blob":
+	case "tag", "object":
+		objectType, _, err := r.ReadObject(sha)
+		if err != nil {
+			return "", err
+		}
+		if kind == "tag" && objectType != object.TagObject {
+			return "", fmt.Errorf("%s is a %s, not a tag", rev, objectType)
+		}
+		return sha, nil
+	default:
+		return "", fmt.Errorf("invalid revision: %s: unknown object type %q", rev, kind)
+	}
+	sha, err := r.peelTag(sha)
+	if err != nil || kind == "" {
+		return sha, err
+	}
+	objectType, _, err := r.ReadObject(sha)
+	if err != nil {
+		return "", err
+	}
+	if kind == "tree" && objectType == object.CommitObject {
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return "", err
+		}
+		sha, objectType = commit.TreeSHA, object.TreeObject
+	}
+	if objectType != object.Type(kind) {
+		return "", fmt.Errorf("%s is a %s, not a %s", rev, objectType, kind)
+	}
+	return sha, nil
+}
+
+func (r *Repository) resolveRevisionBase(name string) (string, error) {
+	// <ref>@{n} looks up the nth previous value in the ref's reflog
+	if i := strings.Index(name, "@{"); i >= 0 && strings.HasSuffix(name, "}") {
+		refName := name[:i]
+		if refName == "" {
+			refName = "HEAD"
+		}
+		n, err := strconv.Atoi(name[i+2 : len(name)-1])
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid reflog selector: %s", name)
+		}
+		ref, err := r.ExpandRefName(refName)
+		if err != nil {
+			return "", err
+		}
+		entries, err := r.ReadReflog(ref)
+		if err != nil {
+			return "", err
+		}
+		if n >= len(entries) {
+			return "", fmt.Errorf("reflog for %s has only %d entries", refName, len(entries))
+		}
+		return entries[len(entries)-1-n].NewSHA, nil
+	}
+
+	if r.Format.ValidateSHA(name) == nil {
+		return strings.ToLower(name), nil
+	}
+
+	for _, ref := range refCandidates(name) {
+		sha, err := r.ReadRef(ref)
+		if err != nil {
+			return "", err
+		}
+		if sha != "" {
+			return sha, nil
+		}
+		if ref == "HEAD" {
+			return "", errors.New("HEAD does not point to a commit yet")
+		}
+	}
+
+	sha, err := r.resolveObjectPrefix(name)
+	if err != nil {
+		return "", err
+	}
+	if sha == "" {
+		return "", fmt.Errorf("unknown revision: %s", name)
+	}
+	return sha, nil
+}
+
+// ResolveCommit resolves a revision and checks that it names a commit
+func (r *Repository) ResolveCommit(rev string) (string, error) {
+	sha, err := r.resolveRevision(rev)
+	if err != nil {
+		return "", err
+	}
+	if sha, err = r.peelTag(sha); err != nil {
+		return "", err
+	}
+	objectType, _, err := r.ReadObject(sha)
+	if err != nil {
+		return "", err
+	}
+	if objectType != object.CommitObject {
+		return "", fmt.Errorf("%s is a %s, not a commit", rev, objectType)
+	}
+	return sha, nil
+}
+
+// ResolveObject resolves a revision to any object. <rev>:<path> names the
+// tree or blob at path in the commit's tree, and :<path> the blob staged
+// for path in the index.
+func (r *Repository) ResolveObject(rev string) (string, error) {
+	commitRev, path, hasPath := strings.Cut(rev, ":")
+	if !hasPath {
+		return r.resolveRevision(rev)
+	}
+	if commitRev == "" {
+		return r.resolveStagedPath(path)
+	}
+	commitSHA, err := r.ResolveCommit(commitRev)
+	if err != nil {
+		return "", err
+	}
+	commit, err := r.ReadCommit(commitSHA)
+	if err != nil {
+		return "", err
+	}
+	if strings.Trim(path, "/") == "" {
+		return commit.TreeSHA, nil
+	}
+	entry, ok, err := r.treeEntryAt(commit.TreeSHA, path)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("path %s does not exist in %s", path, commitRev)
+	}
+	return entry.SHA, nil
+}
+
+// resolveStagedPath returns the blob the index holds for path
+func (r *Repository) resolveStagedPath(path string) (string, error) {
+	path = strings.Trim(path, "/")
+	index, err := r.ReadIndex()
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range index.Entries {
+		if entry.Path != path {
+			continue
+		}
+		if entry.Flags&IndexIntentToAdd != 0 {
+			return "", fmt.Errorf("path %s is only intended to be added; it has no staged content", path)
+		}
+		return entry.SHA, nil
+	}
+	return "", fmt.Errorf("path %s is not in the index", path)
+}
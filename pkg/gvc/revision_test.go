@@ -0,0 +1,69 @@
+package gvc
+
+import (
+	"testing"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+func TestResolveObjectStagedPath(t *testing.T) {
+	repo, _ := newTestRepoWithCommit(t)
+	committed := repo.Format.Hash(object.BlobObject, []byte("content\n"))
+	writeTestFile(t, repo, "file.txt", "staged\n")
+	if err := repo.Add("file.txt"); err != nil {
+		t.Fatal(err)
+	}
+	staged := repo.Format.Hash(object.BlobObject, []byte("staged\n"))
+
+	for rev, want := range map[string]string{"HEAD:file.txt": committed, ":file.txt": staged} {
+		got, err := repo.ResolveObject(rev)
+		if err != nil {
+			t.Fatalf("%s: %v", rev, err)
+		}
+		if got != want {
+			t.Errorf("%s resolved to %s, want %s", rev, got, want)
+		}
+	}
+	if _, err := repo.ResolveObject(":missing.txt"); err == nil {
+		t.Error(":missing.txt resolved, want an error")
+	}
+}
+
+func TestResolveObjectPeel(t *testing.T) {
+	repo, commitSHA := newTestRepoWithCommit(t)
+	commit, err := repo.ReadCommit(commitSHA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag, err := repo.WriteObject(object.TagObject, []byte("object "+commitSHA+"\ntype commit\ntag v1\n"+
+		"tagger A U Thor <author@example.com> 0 +0000\n\nfirst release\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for rev, want := range map[string]string{
+		"HEAD^{commit}":     commitSHA,
+		"HEAD^{tree}":       commit.TreeSHA,
+		"HEAD^{}":           commitSHA,
+		tag + "^{tag}":      tag,
+		tag + "^{}":         commitSHA,
+		tag + "^{tree}":     commit.TreeSHA,
+		tag + "^{object}":   tag,
+		tag + "^{commit}~0": commitSHA,
+	} {
+		got, err := repo.ResolveObject(rev)
+		if err != nil {
+			t.Errorf("%s: %v", rev, err)
+		} else if got != want {
+			t.Errorf("%s resolved to %s, want %s", rev, got, want)
+		}
+	}
+	for _, rev := range []string{"HEAD^{blob}", "HEAD^{tag}", "HEAD^{bogus}", "HEAD^{tree"} {
+		if _, err := repo.ResolveObject(rev); err == nil {
+			t.Errorf("%s resolved, want an error", rev)
+		}
+	}
+	if _, err := repo.ResolveCommit("HEAD^{tree}"); err == nil {
+		t.Error("HEAD^{tree} resolved as a commit")
+	}
+}
@@ -0,0 +1,731 @@
+package gvc
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// RevRange is the set of commits selected by revision arguments: everything
+// reachable from Include but not from Exclude
+type RevRange struct {
+	Include []string
+	Exclude []string
+	Left    map[string]bool // for A...B, the commits reachable from A
+}
+
+// refSelectors are the revision options that stand for every ref in a
+// namespace, or for those matching a glob given as --branches=<pattern>
+var refSelectors = map[string]string{
+	"--branches": HeadsDir,
+	"--tags":     "refs/tags",
+	"--remotes":  RemotesDir,
+}
+
+// selectRefs returns the refs named by --all, which is HEAD and every ref,
+// or by one of refSelectors, each with the commit it points to. ok is false
+// when arg is not such an option. As in Git, a pattern without ?, * or [
+// selects the refs below it.
+func (r *Repository) selectRefs(arg string) (selected []Ref, ok bool, err error) {
+	if arg == "--all" {
+		head, err := r.HeadCommit()
+		if err != nil {
+			return nil, true, err
+		}
+		if head != "" {
+			selected = append(selected, Ref{Name: "HEAD", SHA: head})
+		}
+	}
+	option, pattern, hasPattern := strings.Cut(arg, "=")
+	namespace, ok := refSelectors[option]
+	switch {
+	case arg == "--all":
+		pattern = "refs/*"
+	case !ok:
+		return nil, false, nil
+	case !hasPattern:
+		pattern = namespace + "/*"
+	default:
+		pattern = namespace + "/" + strings.TrimPrefix(pattern, "/")
+	}
+
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, true, err
+	}
+	for _, ref := range refs {
+		matched := strings.HasPrefix(ref.Name, strings.TrimSuffix(pattern, "/")+"/")
+		if strings.ContainsAny(pattern, "?*[") {
+			// Git lets wildcards match "/" here, which path.Match does not, so
+			// both sides swap it for a byte that cannot appear in a ref name
+			unslash := func(s string) string { return strings.ReplaceAll(s, "/", "\x00") }
+			if matched, err = path.Match(unslash(pattern), unslash(ref.Name)); err != nil {
+				return nil, true, fmt.Errorf("invalid pattern in %s: %w", arg, err)
+			}
+		}
+		if !matched {
+			continue
+		}
+		sha, err := r.ResolveCommit(ref.Name)
+		if err != nil {
+			return nil, true, err
+		}
+		selected = append(selected, Ref{Name: ref.Name, SHA: sha})
+	}
+	return selected, true, nil
+}
+
+// ParseRevRange resolves revision arguments such as B, ^A, A..B and A...B,
+// and the ref selectors --all, --branches, --tags and --remotes
+func (r *Repository) ParseRevRange(args []string) (*RevRange, error) {
+	revRange := &RevRange{}
+	resolve := func(rev string) (string, error) {
+		if rev == "" {
+			rev = "HEAD"
+		}
+		return r.ResolveCommit(rev)
+	}
+
+	for _, arg := range args {
+		if refs, ok, err := r.selectRefs(arg); err != nil {
+			return nil, err
+		} else if ok {
+			for _, ref := range refs {
+				revRange.Include = append(revRange.Include, ref.SHA)
+			}
+			continue
+		}
+		switch {
+		case strings.Contains(arg, "..."):
+			if revRange.Left != nil {
+				return nil, errors.New("only one symmetric difference range is supported")
+			}
+			parts := strings.SplitN(arg, "...", 2)
+			left, err := resolve(parts[0])
+			if err != nil {
+				return nil, err
+			}
+			right, err := resolve(parts[1])
+			if err != nil {
+				return nil, err
+			}
+			bases, err := r.MergeBases(left, right)
+			if err != nil {
+				return nil, err
+			}
+			if revRange.Left, err = r.reachableCommits([]string{left}); err != nil {
+				return nil, err
+			}
+			revRange.Include = append(revRange.Include, left, right)
+			revRange.Exclude = append(revRange.Exclude, bases...)
+		case strings.Contains(arg, ".."):
+			parts := strings.SplitN(arg, "..", 2)
+			from, err := resolve(parts[0])
+			if err != nil {
+				return nil, err
+			}
+			to, err := resolve(parts[1])
+			if err != nil {
+				return nil, err
+			}
+			revRange.Exclude = append(revRange.Exclude, from)
+			revRange.Include = append(revRange.Include, to)
+		case strings.HasPrefix(arg, "^"):
+			sha, err := resolve(arg[1:])
+			if err != nil {
+				return nil, err
+			}
+			revRange.Exclude = append(revRange.Exclude, sha)
+		default:
+			sha, err := resolve(arg)
+			if err != nil {
+				return nil, err
+			}
+			revRange.Include = append(revRange.Include, sha)
+		}
+	}
+	return revRange, nil
+}
+
+// dateQueue orders commits newest first, keeping insertion order for equal dates
+type dateQueue []*dateQueueItem
+
+type dateQueueItem struct {
+	node *CommitNode
+	time int64 // the date the queue orders by
+	seq  int
+}
+
+func (q dateQueue) Len() int { return len(q) }
+func (q dateQueue) Less(i, j int) bool {
+	if q[i].time != q[j].time {
+		return q[i].time > q[j].time
+	}
+	return q[i].seq < q[j].seq
+}
+func (q dateQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *dateQueue) Push(x any)   { *q = append(*q, x.(*dateQueueItem)) }
+func (q *dateQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// WalkRevisions lists the commits of a range, newest first
+func (r *Repository) WalkRevisions(revRange *RevRange) ([]*CommitNode, error) {
+	return r.walkRevisions(revRange, nil)
+}
+
+// walkRevisions is WalkRevisions going on from each commit only to the
+// parents follow returns, or to all of them when follow is nil
+func (r *Repository) walkRevisions(revRange *RevRange, follow func(*CommitNode) ([]string, error)) ([]*CommitNode, error) {
+	excluded, err := r.reachableCommits(revRange.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []*CommitNode
+	queue := &dateQueue{}
+	seen := make(map[string]bool)
+	seq := 0
+	push := func(sha string) error {
+		if seen[sha] || excluded[sha] {
+			return nil
+		}
+		seen[sha] = true
+		node, err := r.LookupCommitNode(sha)
+		if err != nil {
+			return err
+		}
+		heap.Push(queue, &dateQueueItem{node: node, time: node.Time, seq: seq})
+		seq++
+		return nil
+	}
+
+	for _, sha := range revRange.Include {
+		if err := push(sha); err != nil {
+			return nil, err
+		}
+	}
+	for queue.Len() > 0 {
+		if err := r.canceled(); err != nil {
+			return nil, err
+		}
+		node := heap.Pop(queue).(*dateQueueItem).node
+		commits = append(commits, node)
+		parents := node.Parents
+		if follow != nil {
+			if parents, err = follow(node); err != nil {
+				return nil, err
+			}
+		}
+		for _, parent := range parents {
+			if err := push(parent); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return commits, nil
+}
+
+// reachableObjects lists every commit, tree and blob reachable from include but
+// not from exclude, commits first
+func (r *Repository) reachableObjects(include, exclude []string) ([]string, error) {
+	have := make(map[string]bool)
+	var markTree func(treeSHA string, out *[]string) error
+	markTree = func(treeSHA string, out *[]string) error {
+		if have[treeSHA] {
+			return nil
+		}
+		have[treeSHA] = true
+		if out != nil {
+			*out = append(*out, treeSHA)
+		}
+		if err := r.canceled(); err != nil {
+			return err
+		}
+		_, content, err := r.ReadObject(treeSHA)
+		if err != nil {
+			return err
+		}
+		entries, err := r.Format.ParseTree(content)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.Type == object.TreeObject {
+				if err := markTree(entry.SHA, out); err != nil {
+					return err
+				}
+			} else if entry.Type == object.BlobObject && !have[entry.SHA] {
+				have[entry.SHA] = true
+				if out != nil {
+					*out = append(*out, entry.SHA)
+				}
+			}
+		}
+		return nil
+	}
+
+	excludedCommits, err := r.reachableCommits(exclude)
+	if err != nil {
+		return nil, err
+	}
+	for sha := range excludedCommits {
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return nil, err
+		}
+		if err := markTree(commit.TreeSHA, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	commits, err := r.WalkRevisions(&RevRange{Include: include, Exclude: exclude})
+	if err != nil {
+		return nil, err
+	}
+	var objects, trees []string
+	for _, node := range commits {
+		objects = append(objects, node.SHA)
+		commit, err := r.ReadCommit(node.SHA)
+		if err != nil {
+			return nil, err
+		}
+		if err := markTree(commit.TreeSHA, &trees); err != nil {
+			return nil, err
+		}
+	}
+	return append(objects, trees...), nil
+}
+
+// LogOptions filters and limits the commits Log returns
+type LogOptions struct {
+	MaxCount int            // stop after this many commits; 0 means no limit
+	Author   *regexp.Regexp // keep commits whose author matches
+	Since    time.Time      // keep commits made at or after this time
+	Until    time.Time      // keep commits made at or before this time
+	Paths    []string       // keep commits that changed a file at or below one of these paths
+	Order    RevOrder       // how to order the commits
+
+	// FullHistory walks every parent of a path-limited merge. By default a
+	// merge that left the paths as one parent had them is followed down that
+	// parent only, so side branches whose changes did not survive are skipped.
+	FullHistory bool
+	// RewriteParents replaces the parents of path-limited commits that are
+	// left out with their nearest ancestors that are shown, as --graph needs
+	RewriteParents bool
+	// Follow continues the history of a single file past renames: once the
+	// walk reaches the commit that renamed the file, older commits are
+	// limited to the name it had before
+	Follow bool
+	// Reverse lists the commits oldest first. MaxCount still keeps the
+	// newest ones, as in Git.
+	Reverse bool
+}
+
+// Log returns the commits in the given revisions or ranges such as A..B and A...B,
+// newest first. Without revisions it lists the history of HEAD.
+func (r *Repository) Log(revs ...string) ([]*object.Commit, error) {
+	return r.LogWithOptions(LogOptions{}, revs...)
+}
+
+// LogWithOptions is Log keeping only the commits that pass the filters in opts
+func (r *Repository) LogWithOptions(opts LogOptions, revs ...string) ([]*object.Commit, error) {
+	if len(revs) == 0 {
+		currentCommit, err := r.HeadCommit()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get current commit: %w", err)
+		}
+		if currentCommit == "" {
+			return nil, nil
+		}
+		revs = []string{currentCommit}
+	}
+
+	revRange, err := r.ParseRevRange(revs)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Follow && len(opts.Paths) != 1 {
+		return nil, errors.New("--follow requires exactly one path")
+	}
+	var simplified *pathSimplification
+	var follow func(*CommitNode) ([]string, error)
+	if len(opts.Paths) > 0 {
+		simplified = &pathSimplification{shown: make(map[string]bool), follow: make(map[string][]string)}
+		follow = func(node *CommitNode) ([]string, error) {
+			parents, err := r.simplifyCommit(node, opts, simplified)
+			if err != nil || !opts.Follow || !simplified.shown[node.SHA] {
+				return parents, err
+			}
+			renamedFrom, err := r.renameSource(node, opts.Paths[0])
+			if err != nil {
+				return nil, err
+			}
+			if renamedFrom != "" {
+				opts.Paths = []string{renamedFrom}
+			}
+			return parents, nil
+		}
+	}
+	nodes, err := r.walkRevisions(revRange, follow)
+	if err != nil {
+		return nil, err
+	}
+	if nodes, err = r.OrderRevisions(nodes, opts.Order); err != nil {
+		return nil, err
+	}
+
+	commits := make([]*object.Commit, 0, len(nodes))
+	for _, node := range nodes {
+		if opts.MaxCount > 0 && len(commits) == opts.MaxCount {
+			break
+		}
+		commit, err := r.ReadCommit(node.SHA)
+		if err != nil {
+			return nil, err
+		}
+		if opts.Author != nil && !opts.Author.MatchString(commit.Author) {
+			continue
+		}
+		if (!opts.Since.IsZero() && commit.Timestamp.Before(opts.Since)) ||
+			(!opts.Until.IsZero() && commit.Timestamp.After(opts.Until)) {
+			continue
+		}
+		if simplified != nil {
+			if !simplified.shown[commit.SHA] {
+				continue
+			}
+			if opts.RewriteParents {
+				commit.Parents = simplified.rewriteParents(commit.SHA)
+				commit.ParentSHA = ""
+				if len(commit.Parents) > 0 {
+					commit.ParentSHA = commit.Parents[0]
+				}
+			}
+		}
+		commits = append(commits, commit)
+	}
+	if opts.Reverse {
+		slices.Reverse(commits)
+	}
+	return commits, nil
+}
+
+// BoundaryCommits returns the commits just outside a log: the parents of
+// commits that are not listed themselves, because a range excluded them or
+// a limit or filter left them out. A root commit has no boundary, nor does a
+// shallow commit, whose parents are missing. They are returned newest first.
+func (r *Repository) BoundaryCommits(commits []*object.Commit) ([]*object.Commit, error) {
+	listed := make(map[string]bool, len(commits))
+	for _, commit := range commits {
+		listed[commit.SHA] = true
+	}
+	var boundary []*object.Commit
+	for _, commit := range commits {
+		if shallow, err := r.isShallowCommit(commit.SHA); err != nil {
+			return nil, err
+		} else if shallow {
+			continue
+		}
+		for _, parent := range commit.Parents {
+			if listed[parent] {
+				continue
+			}
+			listed[parent] = true
+			parentCommit, err := r.ReadCommit(parent)
+			if err != nil {
+				return nil, err
+			}
+			boundary = append(boundary, parentCommit)
+		}
+	}
+	sort.SliceStable(boundary, func(i, j int) bool {
+		return boundary[i].Committed.After(boundary[j].Committed)
+	})
+	return boundary, nil
+}
+
+// pathSimplification records how a path-limited walk treated each commit
+type pathSimplification struct {
+	shown  map[string]bool     // commits that changed the paths
+	follow map[string][]string // the parents the walk went on to from each commit
+}
+
+// simplifyCommit decides whether a path-limited walk shows a commit and which
+// of its parents it goes on to, the way Git simplifies history. A parent is
+// TREESAME when it has the same entries at the paths as the commit. A commit
+// with no TREESAME parent changed the paths and is shown; by default, one
+// with a TREESAME parent is hidden and the walk continues down that parent
+// alone. With FullHistory every parent is walked and a merge is shown when it
+// differs from any parent, or always when parents are rewritten.
+func (r *Repository) simplifyCommit(node *CommitNode, opts LogOptions, s *pathSimplification) ([]string, error) {
+	commit, err := r.ReadCommit(node.SHA)
+	if err != nil {
+		return nil, err
+	}
+	if len(node.Parents) == 0 {
+		same, err := r.samePaths(commit.TreeSHA, "", opts.Paths)
+		if err != nil {
+			return nil, err
+		}
+		s.shown[node.SHA], s.follow[node.SHA] = !same, nil
+		return nil, nil
+	}
+
+	var treesame []string
+	for _, parentSHA := range node.Parents {
+		parent, err := r.ReadCommit(parentSHA)
+		if err != nil {
+			return nil, err
+		}
+		same, err := r.samePaths(commit.TreeSHA, parent.TreeSHA, opts.Paths)
+		if err != nil {
+			return nil, err
+		}
+		if same {
+			treesame = append(treesame, parentSHA)
+		}
+	}
+
+	follow := node.Parents
+	switch {
+	case opts.FullHistory:
+		s.shown[node.SHA] = len(treesame) < len(node.Parents) || (opts.RewriteParents && len(node.Parents) > 1)
+	case len(treesame) > 0:
+		follow = treesame[:1]
+	default:
+		s.shown[node.SHA] = true
+	}
+	s.follow[node.SHA] = follow
+	return follow, nil
+}
+
+// renameSource returns the path a commit renamed path from, found by rename
+// detection against its first parent, or "" when the commit did not create
+// path by a rename
+func (r *Repository) renameSource(node *CommitNode, path string) (string, error) {
+	if len(node.Parents) == 0 {
+		return "", nil
+	}
+	commit, err := r.ReadCommit(node.SHA)
+	if err != nil {
+		return "", err
+	}
+	parent, err := r.ReadCommit(node.Parents[0])
+	if err != nil {
+		return "", err
+	}
+	if _, found, err := r.treeEntryAt(parent.TreeSHA, path); err != nil || found {
+		return "", err
+	}
+	changes, err := r.DiffTrees(parent.TreeSHA, commit.TreeSHA)
+	if err != nil {
+		return "", err
+	}
+	threshold, err := r.RenameThreshold()
+	if err != nil {
+		return "", err
+	}
+	if threshold == 0 {
+		threshold = DefaultRenameThreshold
+	}
+	if changes, err = r.DetectRenames(changes, threshold); err != nil {
+		return "", err
+	}
+	for _, change := range changes {
+		if change.Path == path && change.OldPath != "" {
+			return change.OldPath, nil
+		}
+	}
+	return "", nil
+}
+
+// rewriteParents returns the parents of a shown commit with every hidden one
+// replaced by the nearest shown commits the walk reached through it. Parents
+// outside the walked range are kept as they are.
+func (s *pathSimplification) rewriteParents(sha string) []string {
+	var parents []string
+	seen := make(map[string]bool)
+	var rewrite func(parent string)
+	rewrite = func(parent string) {
+		follow, walked := s.follow[parent]
+		if !walked || s.shown[parent] {
+			if !seen[parent] {
+				seen[parent] = true
+				parents = append(parents, parent)
+			}
+			return
+		}
+		for _, grandparent := range follow {
+			rewrite(grandparent)
+		}
+	}
+	for _, parent := range s.follow[sha] {
+		rewrite(parent)
+	}
+	return parents
+}
+
+// RevOrder is an order log and rev-list can list commits in
+type RevOrder int
+
+const (
+	// OrderDefault lists commits newest commit date first, as the walk reaches
+	// them; a parent with a later date than its child may come before it
+	OrderDefault RevOrder = iota
+	// OrderTopo shows no parent before all of its children, keeping lines of
+	// history together (--topo-order)
+	OrderTopo
+	// OrderDate shows no parent before all of its children, and otherwise
+	// goes by commit date (--date-order)
+	OrderDate
+	// OrderAuthorDate is OrderDate by author date (--author-date-order)
+	OrderAuthorDate
+)
+
+// OrderRevisions reorders commits listed by WalkRevisions. Ties between
+// commits with the same date keep the walk's order, so every order is
+// deterministic.
+func (r *Repository) OrderRevisions(nodes []*CommitNode, order RevOrder) ([]*CommitNode, error) {
+	switch order {
+	case OrderTopo:
+		return topoOrder(nodes), nil
+	case OrderDate:
+		return dateOrder(nodes, func(node *CommitNode) (int64, error) { return node.Time, nil })
+	case OrderAuthorDate:
+		return dateOrder(nodes, func(node *CommitNode) (int64, error) {
+			commit, err := r.ReadCommit(node.SHA)
+			if err != nil {
+				return 0, err
+			}
+			return commit.Timestamp.Unix(), nil
+		})
+	}
+	return nodes, nil
+}
+
+// dateOrder reorders commits listed newest first so that every commit comes
+// after all of its children, taking the newest of the commits whose children
+// have all been listed each time
+func dateOrder(nodes []*CommitNode, date func(*CommitNode) (int64, error)) ([]*CommitNode, error) {
+	bySHA := make(map[string]*dateQueueItem, len(nodes))
+	for i, node := range nodes {
+		time, err := date(node)
+		if err != nil {
+			return nil, err
+		}
+		bySHA[node.SHA] = &dateQueueItem{node: node, time: time, seq: i}
+	}
+	children := make(map[string]int, len(nodes))
+	for _, node := range nodes {
+		for _, parent := range node.Parents {
+			if _, ok := bySHA[parent]; ok {
+				children[parent]++
+			}
+		}
+	}
+	queue := &dateQueue{}
+	for _, node := range nodes {
+		if children[node.SHA] == 0 {
+			heap.Push(queue, bySHA[node.SHA])
+		}
+	}
+
+	ordered := make([]*CommitNode, 0, len(nodes))
+	for queue.Len() > 0 {
+		node := heap.Pop(queue).(*dateQueueItem).node
+		ordered = append(ordered, node)
+		for _, parent := range node.Parents {
+			item, ok := bySHA[parent]
+			if !ok {
+				continue
+			}
+			if children[parent]--; children[parent] == 0 {
+				heap.Push(queue, item)
+			}
+		}
+	}
+	return ordered, nil
+}
+
+// topoOrder reorders commits listed newest first so that every commit comes
+// after all of its children. Like Git's --topo-order it works depth-first
+// from the last parent of each commit, so each line of history stays together.
+func topoOrder(nodes []*CommitNode) []*CommitNode {
+	bySHA := make(map[string]*CommitNode, len(nodes))
+	for _, node := range nodes {
+		bySHA[node.SHA] = node
+	}
+	children := make(map[string]int, len(nodes))
+	for _, node := range nodes {
+		for _, parent := range node.Parents {
+			if _, ok := bySHA[parent]; ok {
+				children[parent]++
+			}
+		}
+	}
+	// The newest tip ends up on top of the stack
+	var stack []*CommitNode
+	for i := len(nodes) - 1; i >= 0; i-- {
+		if children[nodes[i].SHA] == 0 {
+			stack = append(stack, nodes[i])
+		}
+	}
+
+	ordered := make([]*CommitNode, 0, len(nodes))
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		ordered = append(ordered, node)
+		// As in Git, the last parent is pushed last and so visited next
+		for _, sha := range node.Parents {
+			parent, ok := bySHA[sha]
+			if !ok {
+				continue
+			}
+			if children[parent.SHA]--; children[parent.SHA] == 0 {
+				stack = append(stack, parent)
+			}
+		}
+	}
+	return ordered
+}
+
+// samePaths reports whether two trees hold the same entries at every path;
+// an empty tree SHA stands for the empty tree
+func (r *Repository) samePaths(treeA, treeB string, paths []string) (bool, error) {
+	for _, path := range paths {
+		if path == "." || path == "" {
+			if treeA != treeB {
+				return false, nil
+			}
+			continue
+		}
+		var entries [2]object.TreeEntry
+		var found [2]bool
+		for i, tree := range []string{treeA, treeB} {
+			if tree == "" {
+				continue
+			}
+			var err error
+			if entries[i], found[i], err = r.treeEntryAt(tree, path); err != nil {
+				return false, err
+			}
+		}
+		if found[0] != found[1] || entries[0].SHA != entries[1].SHA || entries[0].Mode != entries[1].Mode {
+			return false, nil
+		}
+	}
+	return true, nil
+}
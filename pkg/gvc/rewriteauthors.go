@@ -0,0 +1,346 @@
+package gvc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// identMapping is one line of an IdentMap: the proper name and email to
+// record for identities with the commit email and, when set, commit name
+type identMapping struct {
+	properName, properEmail string
+	commitName, commitEmail string
+}
+
+// IdentMap maps the identities recorded in commits and tags to the ones
+// they should have been, read from a file in Git's mailmap format:
+//
+//	Proper Name <commit@email>
+//	<proper@email> <commit@email>
+//	Proper Name <proper@email> <commit@email>
+//	Proper Name <proper@email> Commit Name <commit@email>
+//
+// Emails match case-insensitively, as do names. A line naming the commit
+// name wins over one that only names its email.
+type IdentMap struct {
+	mappings []identMapping
+}
+
+// errNoMapping is returned for a mapping file with no identities in it
+var errNoMapping = errors.New("the mapping file maps no identities")
+
+// ParseIdentMap reads a mapping file. Blank lines and lines starting with #
+// are skipped.
+func ParseIdentMap(data []byte) (*IdentMap, error) {
+	m := &IdentMap{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, email, rest, ok := cutIdent(line)
+		if !ok {
+			return nil, fmt.Errorf("line %d: no <email> in %q", i+1, line)
+		}
+		mapping := identMapping{properName: name, properEmail: email}
+		if rest = strings.TrimSpace(rest); rest == "" {
+			// A single identity gives the proper name for its email
+			mapping.commitEmail, mapping.properEmail = email, ""
+		} else {
+			// The commit name may hold angle brackets, as gvc's old
+			// default identity does, so its email is the last one
+			open := strings.LastIndex(rest, "<")
+			end := strings.LastIndex(rest, ">")
+			if open < 0 || end < open || strings.TrimSpace(rest[end+1:]) != "" {
+				return nil, fmt.Errorf("line %d: malformed commit identity %q", i+1, rest)
+			}
+			mapping.commitName = strings.TrimSpace(rest[:open])
+			mapping.commitEmail = rest[open+1 : end]
+		}
+		if mapping.commitEmail == "" {
+			return nil, fmt.Errorf("line %d: empty commit email", i+1)
+		}
+		m.mappings = append(m.mappings, mapping)
+	}
+	if len(m.mappings) == 0 {
+		return nil, errNoMapping
+	}
+	return m, nil
+}
+
+// cutIdent splits off the first "Name <email>" of a mapping line
+func cutIdent(line string) (name, email, rest string, ok bool) {
+	open := strings.Index(line, "<")
+	if open < 0 {
+		return "", "", "", false
+	}
+	end := strings.Index(line[open:], ">")
+	if end < 0 {
+		return "", "", "", false
+	}
+	end += open
+	return strings.TrimSpace(line[:open]), line[open+1 : end], line[end+1:], true
+}
+
+// Map returns the identity "Name <email>" should be recorded as, which is
+// ident itself when no line matches it. The email is the last one in angle
+// brackets, so the name of gvc's old default identity keeps its brackets.
+func (m *IdentMap) Map(ident string) string {
+	open := strings.LastIndex(ident, "<")
+	end := strings.LastIndex(ident, ">")
+	if open < 0 || end < open {
+		return ident
+	}
+	name, email := strings.TrimSpace(ident[:open]), ident[open+1:end]
+
+	var match *identMapping
+	for i, mapping := range m.mappings {
+		if !strings.EqualFold(mapping.commitEmail, email) {
+			continue
+		}
+		if mapping.commitName == "" && match == nil {
+			match = &m.mappings[i]
+		} else if mapping.commitName != "" && strings.EqualFold(mapping.commitName, name) {
+			match = &m.mappings[i]
+			break
+		}
+	}
+	if match == nil {
+		return ident
+	}
+	if match.properName != "" {
+		name = match.properName
+	}
+	if match.properEmail != "" {
+		email = match.properEmail
+	}
+	if name == "" {
+		return "<" + email + ">"
+	}
+	return name + " <" + email + ">"
+}
+
+// RewriteAuthorsOptions controls RewriteAuthors
+type RewriteAuthorsOptions struct {
+	// DryRun works out the new commits and the refs that would move without
+	// writing any object or ref
+	DryRun bool
+}
+
+// RewriteAuthors rewrites the history of every branch and tag, and of a
+// detached HEAD, so the authors, committers and taggers the mapping names are
+// recorded as their proper identities. Commits are rewritten parents first,
+// so a commit whose identities are right still gets a new SHA when one of
+// its ancestors does; trees, messages and dates stay as they were.
+// Signatures no longer match and are dropped, with a warning. All refs move
+// in one transaction, with the old commits left in their reflogs. Remote
+// tracking refs are left alone. It returns the SHA of each rewritten commit
+// and annotated tag, keyed by the old one.
+func (r *Repository) RewriteAuthors(mapping *IdentMap, opts RewriteAuthorsOptions) (map[string]string, error) {
+	if !opts.DryRun {
+		if err := r.checkWritable(); err != nil {
+			return nil, err
+		}
+	}
+	if op := r.OperationInProgress(); op != "" {
+		return nil, fmt.Errorf("a %s is in progress; use --continue or --abort first", op)
+	}
+
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	type target struct{ ref, sha string }
+	var targets []target
+	for _, ref := range refs {
+		if !strings.HasPrefix(ref.Name, "refs/heads/") && !strings.HasPrefix(ref.Name, "refs/tags/") {
+			continue
+		}
+		sha := ref.SHA
+		if ref.Tag != "" {
+			sha = ref.Tag
+		}
+		targets = append(targets, target{ref.Name, sha})
+	}
+	headRef, err := r.HeadRef()
+	if err != nil {
+		return nil, err
+	}
+	if headRef == "" {
+		head, err := r.HeadCommit()
+		if err != nil {
+			return nil, err
+		}
+		if head != "" {
+			targets = append(targets, target{"HEAD", head})
+		}
+	}
+
+	rw := &authorRewriter{r: r, mapping: mapping, dryRun: opts.DryRun, rewritten: make(map[string]string)}
+	var edits []RefEdit
+	for _, t := range targets {
+		newSHA, err := rw.rewrite(t.sha)
+		if err != nil {
+			return nil, err
+		}
+		if newSHA == t.sha {
+			continue
+		}
+		edit := RefEdit{Ref: t.ref, New: newSHA, Old: t.sha, CheckOld: true, NoDeref: t.ref == "HEAD"}
+		// The current branch moves through HEAD, so HEAD's reflog shows it
+		if t.ref == headRef {
+			edit.Ref = "HEAD"
+		}
+		edits = append(edits, edit)
+	}
+
+	changed := make(map[string]string)
+	for oldSHA, newSHA := range rw.rewritten {
+		if oldSHA != newSHA {
+			changed[oldSHA] = newSHA
+		}
+	}
+	if opts.DryRun || len(edits) == 0 {
+		return changed, nil
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Ref < edits[j].Ref })
+	if err := r.UpdateRefs(edits, "rewrite-authors"); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+// authorRewriter rewrites commits and tags, remembering the result for each
+type authorRewriter struct {
+	r         *Repository
+	mapping   *IdentMap
+	dryRun    bool
+	rewritten map[string]string // old SHA to new, the same when unchanged
+}
+
+// rewrite returns the new SHA of a commit or tag, rewriting the history
+// below it first. Commits are visited with an explicit stack, as a long
+// history would be too deep to recurse through.
+func (rw *authorRewriter) rewrite(sha string) (string, error) {
+	type frame struct {
+		sha     string
+		visited bool
+	}
+	stack := []frame{{sha: sha}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, done := rw.rewritten[top.sha]; done {
+			continue
+		}
+		objectType, content, err := rw.r.ReadObject(top.sha)
+		if err != nil {
+			return "", err
+		}
+		deps, err := rw.dependencies(top.sha, objectType, content)
+		if err != nil {
+			return "", err
+		}
+		if !top.visited {
+			stack = append(stack, frame{sha: top.sha, visited: true})
+			for _, dep := range deps {
+				if _, done := rw.rewritten[dep]; !done {
+					stack = append(stack, frame{sha: dep})
+				}
+			}
+			continue
+		}
+		newSHA, err := rw.rewriteObject(top.sha, objectType, content)
+		if err != nil {
+			return "", err
+		}
+		rw.rewritten[top.sha] = newSHA
+	}
+	return rw.rewritten[sha], nil
+}
+
+// dependencies returns the objects that must be rewritten before one: a
+// commit's parents, which a shallow commit lacks, or the object a tag names
+func (rw *authorRewriter) dependencies(sha string, objectType object.Type, content []byte) ([]string, error) {
+	switch objectType {
+	case object.CommitObject:
+		return rw.r.commitParents(sha)
+	case object.TagObject:
+		tag, err := object.ParseTag(sha, content)
+		if err != nil {
+			return nil, err
+		}
+		if tag.Type == object.CommitObject || tag.Type == object.TagObject {
+			return []string{tag.Object}, nil
+		}
+	}
+	return nil, nil
+}
+
+// rewriteObject writes a commit or tag with its identities mapped and the
+// objects it names replaced by their rewritten versions, returning its new
+// SHA, or sha itself when nothing changed. Other objects are left alone.
+func (rw *authorRewriter) rewriteObject(sha string, objectType object.Type, content []byte) (string, error) {
+	if objectType != object.CommitObject && objectType != object.TagObject {
+		return sha, nil
+	}
+	header, message, _ := bytes.Cut(content, []byte("\n\n"))
+	var b bytes.Buffer
+	inSignature := false
+	for _, line := range strings.Split(string(header), "\n") {
+		// Continuation lines of a multi-line header start with a space
+		if inSignature && strings.HasPrefix(line, " ") {
+			continue
+		}
+		inSignature = false
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "parent", "object":
+			if newSHA, ok := rw.rewritten[value]; ok {
+				value = newSHA
+			}
+		case "author", "committer", "tagger":
+			value = rw.mapIdentLine(value)
+		case "gpgsig", "gpgsig-sha256":
+			inSignature = true
+			fmt.Fprintf(rw.r.Out, "warning: dropping the signature of commit %s\n", sha)
+			continue
+		}
+		b.WriteString(key + " " + value + "\n")
+	}
+	// A tag's signature trails its message
+	if objectType == object.TagObject {
+		for _, marker := range []string{"-----BEGIN PGP SIGNATURE-----", "-----BEGIN SSH SIGNATURE-----"} {
+			if i := bytes.Index(message, []byte(marker)); i >= 0 {
+				message = message[:i]
+				fmt.Fprintf(rw.r.Out, "warning: dropping the signature of tag %s\n", sha)
+				break
+			}
+		}
+	}
+	b.WriteString("\n")
+	b.Write(message)
+
+	if bytes.Equal(b.Bytes(), content) {
+		return sha, nil
+	}
+	if rw.dryRun {
+		return rw.r.Format.Hash(objectType, b.Bytes()), nil
+	}
+	return rw.r.WriteObject(objectType, b.Bytes())
+}
+
+// mapIdentLine maps the identity of an author, committer or tagger header
+// value, keeping the date that follows it
+func (rw *authorRewriter) mapIdentLine(value string) string {
+	end := strings.LastIndex(value, ">")
+	if end < 0 {
+		return value
+	}
+	return rw.mapping.Map(value[:end+1]) + value[end+1:]
+}
@@ -0,0 +1,169 @@
+package gvc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RemoveOptions controls Remove
+type RemoveOptions struct {
+	Cached    bool // only stop tracking the files, keeping them in the working tree
+	Recursive bool // let a directory remove every tracked file below it
+	Force     bool // remove files even when changes to them would be lost
+	DryRun    bool // only work out what would be removed
+}
+
+// Remove stops tracking the files at the given paths, relative to the
+// repository root, and deletes them from the working tree unless Cached is
+// set, so the next commit records their removal. It returns the removed
+// paths, sorted. Unless Force is set, a file whose staged content differs
+// from HEAD, or whose working tree copy differs from the staged one, is
+// refused so no change is lost; with Cached only a file that differs from
+// both is. With DryRun nothing is changed.
+func (r *Repository) Remove(opts RemoveOptions, paths ...string) ([]string, error) {
+	// Hold the index lock from read to write, unless only looking
+	var l *lockFile
+	if !opts.DryRun {
+		if err := r.checkWritable(); err != nil {
+			return nil, err
+		}
+		var err error
+		if l, err = r.lockGitFile(r.indexPath()); err != nil {
+			return nil, err
+		}
+		defer l.unlock()
+	}
+
+	index, err := r.ReadIndex()
+	if err != nil {
+		return nil, err
+	}
+	staged := make(map[string]IndexEntry, len(index.Entries))
+	for _, entry := range index.Entries {
+		entry.Path = normalizePath(entry.Path)
+		staged[entry.Path] = entry
+	}
+	removed, err := removeTargets(staged, paths, opts.Recursive)
+	if err != nil {
+		return nil, err
+	}
+	if !opts.Force {
+		if err := r.checkRemovable(staged, removed, opts.Cached); err != nil {
+			return nil, err
+		}
+	}
+	if opts.DryRun {
+		return removed, nil
+	}
+
+	for _, path := range removed {
+		delete(staged, path)
+	}
+	index.Entries = index.Entries[:0]
+	for _, entry := range staged {
+		index.Entries = append(index.Entries, entry)
+	}
+	data, err := r.encodeIndex(index)
+	if err != nil {
+		return nil, err
+	}
+	if err := l.commit(data); err != nil {
+		return nil, fmt.Errorf("failed to write index: %w", err)
+	}
+	if !opts.Cached {
+		for _, path := range removed {
+			if err := r.removeWorkingFile(path); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return removed, nil
+}
+
+// removeTargets expands the paths given to Remove into the staged files they
+// name, sorted. A directory, or "" for the whole tree, needs recursive.
+func removeTargets(staged map[string]IndexEntry, paths []string, recursive bool) ([]string, error) {
+	targets := make(map[string]bool)
+	for _, spec := range paths {
+		spec = normalizePath(spec)
+		if _, ok := staged[spec]; ok {
+			targets[spec] = true
+			continue
+		}
+		matched := false
+		for path := range staged {
+			if underPaths(path, []string{spec}) {
+				matched = true
+				targets[path] = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("pathspec '%s' did not match any files", spec)
+		}
+		if !recursive {
+			if spec == "" {
+				spec = "."
+			}
+			return nil, fmt.Errorf("not removing '%s' recursively without -r", spec)
+		}
+	}
+	removed := make([]string, 0, len(targets))
+	for path := range targets {
+		removed = append(removed, path)
+	}
+	sort.Strings(removed)
+	return removed, nil
+}
+
+// checkRemovable refuses to remove files whose changes would be lost: staged
+// content that HEAD lacks, or working tree changes that were never staged
+func (r *Repository) checkRemovable(staged map[string]IndexEntry, paths []string, cached bool) error {
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	indexTime := r.indexModTime()
+	var both, stagedOnly, localOnly []string
+	for _, path := range paths {
+		entry := staged[path]
+		headEntry, inHead := head[path]
+		stagedChanged := !inHead || headEntry.SHA != entry.SHA || headEntry.Mode != entry.Mode
+		working, onDisk, err := r.readWorkingEntryCached(path, entry, indexTime)
+		if err != nil {
+			return err
+		}
+		workingChanged := onDisk && (working.SHA != entry.SHA || working.Mode != entry.Mode)
+		switch {
+		case stagedChanged && workingChanged:
+			both = append(both, path)
+		case cached:
+		case stagedChanged:
+			stagedOnly = append(stagedOnly, path)
+		case workingChanged:
+			localOnly = append(localOnly, path)
+		}
+	}
+
+	var problems []string
+	for _, problem := range []struct {
+		paths []string
+		what  string
+	}{
+		{both, "staged content different from both the file and the HEAD"},
+		{stagedOnly, "changes staged in the index"},
+		{localOnly, "local modifications"},
+	} {
+		if len(problem.paths) > 0 {
+			problems = append(problems, fmt.Sprintf("these files have %s:\n\t%s", problem.what, strings.Join(problem.paths, "\n\t")))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	hint := "use -f to force removal"
+	if !cached {
+		hint = "use --cached to keep the files, or -f to force removal"
+	}
+	return fmt.Errorf("%s\n(%s)", strings.Join(problems, "\n"), hint)
+}
@@ -0,0 +1,154 @@
+package gvc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GlobalConfigPath returns the per-user config file, gvc/config below
+// $XDG_CONFIG_HOME or ~/.config. Only settings a repository must not be able
+// to make for itself, such as safe.directory, are read from it.
+func GlobalConfigPath() string {
+	if configHome := os.Getenv("XDG_CONFIG_HOME"); configHome != "" {
+		return filepath.Join(configHome, "gvc", ConfigFile)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".config", "gvc", ConfigFile)
+	}
+	return ""
+}
+
+// GlobalConfigEntries returns every setting in the global config, in file order
+func GlobalConfigEntries() ([][2]string, error) {
+	path := GlobalConfigPath()
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return parseConfigEntries(string(data)), nil
+}
+
+// AddGlobalConfig adds a value to a setting in the global config, keeping
+// the values it already has, as multi-valued settings like safe.directory need
+func AddGlobalConfig(key, value string) error {
+	section, subsection, name, err := splitConfigKey(key)
+	if err != nil {
+		return err
+	}
+	path := GlobalConfigPath()
+	if path == "" {
+		return fmt.Errorf("failed to add %s: no home directory", key)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	l, err := lock(path)
+	if err != nil {
+		return err
+	}
+	defer l.unlock()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+
+	// The value goes at the end of the last matching section
+	newLine := fmt.Sprintf("\t%s = %s", name, value)
+	sectionEnd, inSection := -1, false
+	for i, raw := range lines {
+		line := strings.TrimSpace(raw)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			s, sub := parseConfigSection(line)
+			inSection = s == section && sub == subsection
+		}
+		if inSection {
+			sectionEnd = i
+		}
+	}
+	if sectionEnd >= 0 {
+		lines = append(lines[:sectionEnd+1], append([]string{newLine}, lines[sectionEnd+1:]...)...)
+	} else {
+		header := "[" + section + "]"
+		if subsection != "" {
+			header = fmt.Sprintf("[%s %q]", section, subsection)
+		}
+		lines = append(lines, header, newLine)
+	}
+	return writeConfigLines(l, lines)
+}
+
+// UnsafeRepositoryError is returned when opening a repository another user
+// owns that safe.directory does not list. Its hooks, config and filters
+// would run with the permissions of whoever opened it, so it is refused.
+type UnsafeRepositoryError struct {
+	Path string // the directory owned by someone else
+}
+
+func (e *UnsafeRepositoryError) Error() string {
+	return fmt.Sprintf("detected dubious ownership in repository at '%s'\n"+
+		"To trust it, run:\n\n\tgvc config --global --add safe.directory %s", e.Path, e.Path)
+}
+
+// checkOwnership refuses a repository whose working tree or metadata is
+// owned by another user, unless safe.directory in the global config lists it.
+// safe.directory may be given several times: each names a working tree or
+// .gvc directory, "<dir>/*" trusts everything below dir, "*" trusts every
+// repository and an empty value forgets the values before it.
+func (r *Repository) checkOwnership() error {
+	unsafe := ""
+	for _, dir := range []string{r.Root, r.GitDir, r.CommonDir} {
+		owned, err := ownedByCurrentUser(dir)
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to check the owner of %s: %w", dir, err)
+		}
+		if err == nil && !owned {
+			unsafe = dir
+			break
+		}
+	}
+	if unsafe == "" {
+		return nil
+	}
+
+	entries, err := GlobalConfigEntries()
+	if err != nil {
+		return err
+	}
+	var safe []string
+	for _, entry := range entries {
+		if entry[0] != "safe.directory" {
+			continue
+		}
+		if entry[1] == "" {
+			safe = nil
+			continue
+		}
+		safe = append(safe, entry[1])
+	}
+	for _, value := range safe {
+		if value == "*" {
+			return nil
+		}
+		prefix, below := strings.CutSuffix(value, "/*")
+		prefix = filepath.Clean(expandHome(prefix))
+		for _, dir := range []string{r.Root, r.GitDir} {
+			if dir == prefix || below && strings.HasPrefix(dir, prefix+string(filepath.Separator)) {
+				return nil
+			}
+		}
+	}
+	return &UnsafeRepositoryError{Path: unsafe}
+}
@@ -0,0 +1,248 @@
+package gvc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/diff"
+)
+
+// SecretAllowMarker on a line keeps the secret scanner from reporting it,
+// for test fixtures and other values that only look like credentials
+const SecretAllowMarker = "gvc:allow-secret"
+
+// SecretRule is one pattern the secret scanner looks for
+type SecretRule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// builtinSecretRules are scanned for unless config replaces or disables them
+var builtinSecretRules = []struct{ name, pattern string }{
+	{"private-key", `-----BEGIN ((RSA|DSA|EC|OPENSSH|ENCRYPTED|PGP) )?PRIVATE KEY( BLOCK)?-----`},
+	{"aws-access-key-id", `\b(AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{"github-token", `\bgh[pousr]_[A-Za-z0-9]{36,}\b`},
+	{"gitlab-token", `\bglpat-[A-Za-z0-9_-]{20,}`},
+	{"slack-token", `\bxox[abposr]-[A-Za-z0-9-]{10,}`},
+	{"stripe-secret-key", `\b[sr]k_live_[A-Za-z0-9]{20,}`},
+	{"google-api-key", `\bAIza[0-9A-Za-z_-]{35}`},
+	{"generic-api-key", `(?i)\b(api[_-]?key|secret[_-]?key|access[_-]?token|auth[_-]?token|password)\b\s*[:=]\s*["']?[A-Za-z0-9_/+=.-]{16,}`},
+}
+
+// SecretFinding is a line that matched a secret rule
+type SecretFinding struct {
+	Commit string // commit that added the line, "" for staged changes
+	Path   string
+	Line   int
+	Rule   string
+	Match  string // the matched text, redacted
+}
+
+func (f SecretFinding) String() string {
+	return fmt.Sprintf("%s:%d: %s %s", f.Path, f.Line, f.Rule, f.Match)
+}
+
+// SecretRules returns the rules the scanner uses: the built-in ones, then
+// those set as secret.<name>.pattern, which replace a built-in rule of the
+// same name. An empty pattern disables a rule.
+func (r *Repository) SecretRules() ([]SecretRule, error) {
+	patterns := make(map[string]string)
+	var names []string
+	for _, rule := range builtinSecretRules {
+		patterns[rule.name] = rule.pattern
+		names = append(names, rule.name)
+	}
+	entries, err := r.ReadConfigEntries()
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		rest, ok := strings.CutPrefix(entry[0], "secret.")
+		if !ok {
+			continue
+		}
+		name, ok := strings.CutSuffix(rest, ".pattern")
+		if !ok {
+			continue
+		}
+		if _, known := patterns[name]; !known {
+			names = append(names, name)
+		}
+		patterns[name] = entry[1]
+	}
+
+	var rules []SecretRule
+	for _, name := range names {
+		if patterns[name] == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(patterns[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret.%s.pattern: %w", name, err)
+		}
+		rules = append(rules, SecretRule{Name: name, Pattern: pattern})
+	}
+	return rules, nil
+}
+
+// ScanStaged looks for secrets in the lines the index adds to HEAD
+func (r *Repository) ScanStaged() ([]SecretFinding, error) {
+	rules, err := r.SecretRules()
+	if err != nil {
+		return nil, err
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return nil, err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return nil, err
+	}
+	return r.scanChanges(rules, head, staged, "")
+}
+
+// checkSecrets refuses a commit whose staged changes hold secrets, unless
+// allowed or secrets.scan is off
+func (r *Repository) checkSecrets(allow bool) error {
+	if allow {
+		return nil
+	}
+	scan, err := r.getConfigBool("secrets.scan", true)
+	if err != nil || !scan {
+		return err
+	}
+	findings, err := r.ScanStaged()
+	if err != nil {
+		return fmt.Errorf("failed to scan for secrets: %w", err)
+	}
+	if len(findings) > 0 {
+		return &SecretsError{Findings: findings}
+	}
+	return nil
+}
+
+// ScanHistory looks for secrets in every commit of a revision range,
+// reporting each line in the commit that added it compared with its first
+// parent. Without revisions it scans the history of HEAD.
+func (r *Repository) ScanHistory(revs []string) ([]SecretFinding, error) {
+	rules, err := r.SecretRules()
+	if err != nil {
+		return nil, err
+	}
+	if len(revs) == 0 {
+		revs = []string{"HEAD"}
+	}
+	revRange, err := r.ParseRevRange(revs)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := r.WalkRevisions(revRange)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []SecretFinding
+	for _, node := range nodes {
+		commit, err := r.ReadCommit(node.SHA)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := r.flattenTree(commit.TreeSHA)
+		if err != nil {
+			return nil, err
+		}
+		parent := make(map[string]IndexEntry)
+		if len(node.Parents) > 0 {
+			parentCommit, err := r.ReadCommit(node.Parents[0])
+			if err != nil {
+				return nil, err
+			}
+			if parent, err = r.flattenTree(parentCommit.TreeSHA); err != nil {
+				return nil, err
+			}
+		}
+		found, err := r.scanChanges(rules, parent, entries, node.SHA)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, found...)
+	}
+	return findings, nil
+}
+
+// scanChanges runs the rules over the lines each file in to adds to the
+// same file in from, skipping binary files and submodules
+func (r *Repository) scanChanges(rules []SecretRule, from, to map[string]IndexEntry, commit string) ([]SecretFinding, error) {
+	var findings []SecretFinding
+	for _, entry := range sortedEntries(to) {
+		old, inFrom := from[entry.Path]
+		if inFrom && old.SHA == entry.SHA || entry.Mode == GitlinkMode {
+			continue
+		}
+		_, content, err := r.ReadObject(entry.SHA)
+		if err != nil {
+			return nil, err
+		}
+		if isBinary(content) {
+			continue
+		}
+		var oldLines []string
+		if inFrom && old.Mode != GitlinkMode {
+			_, oldContent, err := r.ReadObject(old.SHA)
+			if err != nil {
+				return nil, err
+			}
+			if !isBinary(oldContent) {
+				oldLines = diff.SplitLines(oldContent)
+			}
+		}
+
+		line := 0
+		for _, op := range diff.Lines(oldLines, diff.SplitLines(content)) {
+			if op.Kind == '-' {
+				continue
+			}
+			line++
+			if op.Kind != '+' || strings.Contains(op.Line, SecretAllowMarker) {
+				continue
+			}
+			for _, rule := range rules {
+				if match := rule.Pattern.FindString(op.Line); match != "" {
+					findings = append(findings, SecretFinding{
+						Commit: commit,
+						Path:   entry.Path,
+						Line:   line,
+						Rule:   rule.Name,
+						Match:  redactSecret(match),
+					})
+				}
+			}
+		}
+	}
+	return findings, nil
+}
+
+// redactSecret keeps the first few characters of a match, enough to find it
+// again, and masks the rest
+func redactSecret(match string) string {
+	match = strings.TrimSpace(match)
+	keep := min(len(match)/4, 6)
+	return match[:keep] + strings.Repeat("*", min(len(match)-keep, 12))
+}
+
+// SecretsError reports secrets found in changes about to be committed
+type SecretsError struct {
+	Findings []SecretFinding
+}
+
+func (e *SecretsError) Error() string {
+	lines := make([]string, len(e.Findings))
+	for i, finding := range e.Findings {
+		lines[i] = finding.String()
+	}
+	return fmt.Sprintf("possible secrets in staged changes:\n\t%s\n"+
+		"remove them, mark the line with %q, or commit with --allow-secrets",
+		strings.Join(lines, "\n\t"), SecretAllowMarker)
+}
@@ -0,0 +1,295 @@
+package gvc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	CherryPickHeadFile = "CHERRY_PICK_HEAD"
+	RevertHeadFile     = "REVERT_HEAD"
+	MergeHeadFile      = "MERGE_HEAD"
+	MergeMsgFile       = "MERGE_MSG"
+	ConflictsFile      = "MERGE_CONFLICTS"
+)
+
+// checkCleanState refuses to start an operation over uncommitted changes to tracked files
+func (r *Repository) checkCleanState(head map[string]IndexEntry) error {
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+
+	var dirty []string
+	for path, stagedEntry := range staged {
+		headEntry, inHead := head[path]
+		if !sameEntry(stagedEntry, headEntry, true, inHead) {
+			dirty = append(dirty, path)
+			continue
+		}
+		workingEntry, onDisk, err := r.readTrackedEntry(stagedEntry, false)
+		if err != nil {
+			return err
+		}
+		if !sameEntry(workingEntry, stagedEntry, onDisk, true) {
+			dirty = append(dirty, path)
+		}
+	}
+	if len(dirty) > 0 {
+		sort.Strings(dirty)
+		return fmt.Errorf("your local changes would be overwritten:\n\t%s\nplease commit or stash them first",
+			strings.Join(dirty, "\n\t"))
+	}
+	return nil
+}
+
+// checkUnstagedChanges refuses to start an operation over changes to tracked
+// files that are not staged
+func (r *Repository) checkUnstagedChanges(staged map[string]IndexEntry) error {
+	var dirty []string
+	for path, stagedEntry := range staged {
+		workingEntry, onDisk, err := r.readTrackedEntry(stagedEntry, false)
+		if err != nil {
+			return err
+		}
+		if !sameEntry(workingEntry, stagedEntry, onDisk, true) {
+			dirty = append(dirty, path)
+		}
+	}
+	if len(dirty) > 0 {
+		sort.Strings(dirty)
+		return fmt.Errorf("your local changes would be overwritten:\n\t%s\nplease stage, commit or stash them first",
+			strings.Join(dirty, "\n\t"))
+	}
+	return nil
+}
+
+// checkoutEntries moves the working tree and index from one snapshot to
+// another, leaving out files a sparse checkout does not include
+func (r *Repository) checkoutEntries(from, to map[string]IndexEntry) error {
+	var removed, written []string
+	for path := range from {
+		if _, ok := to[path]; !ok {
+			removed = append(removed, path)
+		}
+	}
+	for path, toEntry := range to {
+		fromEntry, inFrom := from[path]
+		if !sameEntry(fromEntry, toEntry, inFrom, true) {
+			written = append(written, path)
+		}
+	}
+	sort.Strings(removed)
+	sort.Strings(written)
+	inside, err := r.sparseCheckout()
+	if err != nil {
+		return err
+	}
+	// Once files start changing the checkout runs to the end, so a canceled
+	// one never leaves the working tree halfway between two commits
+	if err := r.canceled(); err != nil {
+		return err
+	}
+
+	total := len(removed) + len(written)
+	meter := r.startProgress("Updating files", total)
+	for i, path := range removed {
+		if err := r.removeWorkingFile(path); err != nil {
+			return err
+		}
+		r.notifyCheckout(path, i+1, total)
+		meter.add(1, 0)
+	}
+	for i, path := range written {
+		if r.leftOut(inside, path) {
+			continue
+		}
+		if err := r.writeWorkingFile(to[path]); err != nil {
+			return err
+		}
+		r.notifyCheckout(path, len(removed)+i+1, total)
+		meter.add(1, 0)
+	}
+	meter.done()
+	return r.writeStagedEntries(to)
+}
+
+// resetHard discards all changes to tracked files, leaving the working tree and index at HEAD
+func (r *Repository) resetHard(head map[string]IndexEntry) error {
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+	for path := range staged {
+		if _, ok := head[path]; !ok {
+			if err := r.removeWorkingFile(path); err != nil {
+				return err
+			}
+		}
+	}
+	inside, err := r.sparseCheckout()
+	if err != nil {
+		return err
+	}
+	for path, headEntry := range head {
+		if r.leftOut(inside, path) {
+			continue
+		}
+		workingEntry, onDisk, err := r.readWorkingEntry(path, false)
+		if err != nil {
+			return err
+		}
+		if sameEntry(workingEntry, headEntry, onDisk, true) {
+			continue
+		}
+		if err := r.writeWorkingFile(headEntry); err != nil {
+			return err
+		}
+	}
+	return r.writeStagedEntries(head)
+}
+
+// applyPick merges the change from parent to commit onto HEAD, updating the
+// working tree and index. Conflicting paths are returned with their markers
+// staged, and the versions that conflicted are kept as resolve-undo records.
+func (r *Repository) applyPick(parentTree, commitTree, commitLabel string, head map[string]IndexEntry) ([]string, error) {
+	base, err := r.flattenTree(parentTree)
+	if err != nil {
+		return nil, err
+	}
+	theirs, err := r.flattenTree(commitTree)
+	if err != nil {
+		return nil, err
+	}
+	return r.applyMerge(base, theirs, commitLabel, head)
+}
+
+// applyMerge merges the change from base to theirs onto HEAD the way
+// applyPick does, for snapshots that are not the trees of commits
+func (r *Repository) applyMerge(base, theirs map[string]IndexEntry, theirsLabel string, head map[string]IndexEntry) ([]string, error) {
+	result, conflicts, err := r.mergeTrees(base, head, theirs, "HEAD", theirsLabel)
+	if err != nil {
+		return nil, err
+	}
+	// Conflicts are resolved in the working tree, even outside a sparse checkout
+	inside, err := r.sparseCheckout()
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range conflicts {
+		if entry, ok := result[path]; ok && r.leftOut(inside, path) {
+			if err := r.writeWorkingFile(entry); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := r.checkoutEntries(head, result); err != nil {
+		return nil, err
+	}
+	if err := r.recordResolveUndo(conflicts, base, head, theirs); err != nil {
+		return nil, err
+	}
+	return conflicts, nil
+}
+
+// writeSequencerState records an interrupted pick so it can be continued or aborted
+func (r *Repository) writeSequencerState(headFile, commitSHA, message string, conflicts []string) error {
+	if err := writeFileLocked(headFile, []byte(commitSHA+"\n")); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(headFile), err)
+	}
+	if err := writeFileLocked(r.gitPath(MergeMsgFile), []byte(message+"\n")); err != nil {
+		return fmt.Errorf("failed to write merge message: %w", err)
+	}
+	if err := writeFileLocked(r.gitPath(ConflictsFile), []byte(strings.Join(conflicts, "\n")+"\n")); err != nil {
+		return fmt.Errorf("failed to record conflicts: %w", err)
+	}
+	return nil
+}
+
+// clearSequencerState removes the files written by writeSequencerState
+func (r *Repository) clearSequencerState(headFile string) error {
+	for _, file := range []string{headFile, r.gitPath(MergeMsgFile), r.gitPath(ConflictsFile)} {
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", filepath.Base(file), err)
+		}
+	}
+	return nil
+}
+
+// unresolvedConflicts returns the recorded conflict paths whose staged content still has markers
+func (r *Repository) unresolvedConflicts(staged map[string]IndexEntry) ([]string, error) {
+	data, err := os.ReadFile(r.gitPath(ConflictsFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read conflicts: %w", err)
+	}
+
+	var unresolved []string
+	for _, path := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		entry, ok := staged[path]
+		if path == "" || !ok {
+			continue
+		}
+		marked, err := r.hasConflictMarkers(entry.SHA)
+		if err != nil {
+			return nil, err
+		}
+		if marked {
+			unresolved = append(unresolved, path)
+		}
+	}
+	return unresolved, nil
+}
+
+// OperationInProgress returns the name of an interrupted sequencer operation, if any
+func (r *Repository) OperationInProgress() string {
+	if _, err := os.Stat(r.gitPath(CherryPickHeadFile)); err == nil {
+		return "cherry-pick"
+	}
+	if _, err := os.Stat(r.gitPath(RevertHeadFile)); err == nil {
+		return "revert"
+	}
+	if _, err := os.Stat(r.gitPath(RebaseDir)); err == nil {
+		return "rebase"
+	}
+	if _, err := os.Stat(r.gitPath(MergeHeadFile)); err == nil {
+		return "merge"
+	}
+	if _, err := os.Stat(r.gitPath(SubtreeMergeHeadFile)); err == nil {
+		return "subtree merge"
+	}
+	return ""
+}
+
+// commitStaged records the staged snapshot as a commit on the current branch
+func (r *Repository) commitStaged(staged map[string]IndexEntry, parents []string, author string, authorTime time.Time, message, reflogMessage string) (string, error) {
+	treeSHA, err := r.buildTree(sortedEntries(staged))
+	if err != nil {
+		return "", err
+	}
+	commitSHA, err := r.writeCommit(treeSHA, parents, author, authorTime, message)
+	if err != nil {
+		return "", err
+	}
+	if err := r.UpdateHead(commitSHA, reflogMessage); err != nil {
+		return "", fmt.Errorf("failed to update branch: %w", err)
+	}
+	if err := r.writeStagedEntries(staged); err != nil {
+		return "", err
+	}
+
+	summary, err := r.CommitSummary(commitSHA, message)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintln(r.Out, summary)
+	r.runPostCommitHook()
+	r.notifyCommit(commitSHA)
+	return commitSHA, nil
+}
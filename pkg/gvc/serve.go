@@ -0,0 +1,598 @@
+package gvc
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// ServeOptions configures Serve
+type ServeOptions struct {
+	// BasePath is the directory the paths of git:// URLs are found in; a
+	// path cannot reach outside it
+	BasePath string
+	// Log receives a line for each request and each failure; nothing is
+	// logged when it is nil
+	Log io.Writer
+}
+
+// Serve answers git:// requests on listener until ctx is done, the way git
+// daemon does: gvc and Git clients can clone and fetch the repositories
+// below opts.BasePath, each connection served by UploadPack on a handle of
+// its own. Pushing is refused.
+func Serve(ctx context.Context, listener net.Listener, opts ServeOptions) error {
+	base, err := filepath.Abs(opts.BasePath)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s: %w", opts.BasePath, err)
+	}
+	var mu sync.Mutex
+	logf := func(format string, args ...any) {
+		if opts.Log != nil {
+			mu.Lock()
+			fmt.Fprintf(opts.Log, format+"\n", args...)
+			mu.Unlock()
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { listener.Close() })
+	defer stop()
+	var conns sync.WaitGroup
+	defer conns.Wait()
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to accept a connection: %w", err)
+		}
+		conns.Add(1)
+		go func() {
+			defer conns.Done()
+			serveConn(ctx, conn, base, logf)
+		}()
+	}
+}
+
+// serveConn answers the one request a git:// connection makes
+func serveConn(ctx context.Context, conn net.Conn, base string, logf func(string, ...any)) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// "git-upload-pack /project.git\0host=example.com\0", maybe followed by
+	// extra parameters, which are ignored
+	line, _, err := readPktLine(conn)
+	if err != nil {
+		logf("%s: %v", conn.RemoteAddr(), err)
+		return
+	}
+	request, _, _ := strings.Cut(line, "\x00")
+	service, urlPath, _ := strings.Cut(request, " ")
+	if service != "git-upload-pack" {
+		logf("%s: refused %s %s", conn.RemoteAddr(), service, urlPath)
+		writePktf(conn, "ERR service not enabled: '%s'\n", service)
+		return
+	}
+	repo, err := openServedRepository(base, urlPath)
+	if err != nil {
+		logf("%s: %s: %v", conn.RemoteAddr(), urlPath, err)
+		writePktf(conn, "ERR repository not found: %s\n", urlPath)
+		return
+	}
+	repo.Context = ctx
+	logf("%s: upload-pack %s", conn.RemoteAddr(), urlPath)
+	if err := repo.UploadPack(conn, conn); err != nil && ctx.Err() == nil {
+		logf("%s: %s: %v", conn.RemoteAddr(), urlPath, err)
+	}
+}
+
+// openServedRepository opens the repository a git:// path names below
+// base; "/project", "/project.git" and "/project/.gvc" all name project
+func openServedRepository(base, urlPath string) (*Repository, error) {
+	dir := filepath.Join(base, filepath.FromSlash(path.Clean("/"+urlPath)))
+	if trimmed, ok := strings.CutSuffix(dir, ".git"); ok && !isDir(dir) {
+		dir = trimmed
+	}
+	return openLocalRemote(dir)
+}
+
+// uploadPackCapabilities lists what UploadPack supports, with the branch
+// HEAD is on when it is on one
+func (r *Repository) uploadPackCapabilities(headBranch string) capabilities {
+	caps := capabilities{"ofs-delta", "side-band", "side-band-64k", "shallow", "no-progress"}
+	if headBranch != "" {
+		caps = append(caps, "symref=HEAD:"+headBranch)
+	}
+	return append(caps, "object-format="+r.Format.Name, "agent="+agent())
+}
+
+// UploadPack serves one clone or fetch over in and out the way Git's
+// upload-pack does in protocol version 0: it advertises the refs with its
+// capabilities, reads the objects the client wants and, for a shallow
+// client, its shallow commits and the depth it asks for, then the commits it
+// has, and sends a pack of what it lacks. The pack goes on the side-band
+// channel the client picked, with deltas only when it takes ofs-delta, and
+// meters and a closing "Total" line go on the progress channel unless the
+// client asked for no-progress; r.Progress is only borrowed for them. A
+// failure once the client waits for the pack is sent on the error channel.
+// A client that only wanted the advertisement hangs up after it.
+func (r *Repository) UploadPack(in io.Reader, out io.Writer) error {
+	refs, err := r.listRefs()
+	if err != nil {
+		return err
+	}
+	head, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	headBranch := ""
+	if head != "" {
+		if headBranch, err = r.HeadRef(); err != nil {
+			return err
+		}
+	}
+
+	// The first ref carries the capabilities; an empty repository
+	// advertises them on their own
+	caps := r.uploadPackCapabilities(headBranch)
+	advertised := make(map[string]bool)
+	advertise := func(sha, name string) error {
+		first := len(advertised) == 0
+		advertised[sha] = true
+		if first {
+			return writePktf(out, "%s %s\x00%s\n", sha, name, strings.Join(caps, " "))
+		}
+		return writePktf(out, "%s %s\n", sha, name)
+	}
+	if head != "" {
+		if err := advertise(head, "HEAD"); err != nil {
+			return err
+		}
+	}
+	for _, ref := range refs {
+		if err := advertise(ref.SHA, ref.Name); err != nil {
+			return err
+		}
+		if peeled, err := r.peelTag(ref.SHA); err == nil && peeled != ref.SHA {
+			if err := advertise(peeled, ref.Name+"^{}"); err != nil {
+				return err
+			}
+		}
+	}
+	if len(advertised) == 0 {
+		if err := writePktf(out, "%s capabilities^{}\x00%s\n", r.Format.ZeroSHA(), strings.Join(caps, " ")); err != nil {
+			return err
+		}
+	}
+	if err := writeFlush(out); err != nil {
+		return err
+	}
+
+	// The wants, the client's shallow commits and the depth it asks for
+	var wants []string
+	var clientCaps capabilities
+	clientShallow := make(map[string]bool)
+	depth := 0
+	refuse := func(format string, args ...any) error {
+		msg := fmt.Sprintf(format, args...)
+		writePktf(out, "ERR upload-pack: %s\n", msg)
+		return errors.New(msg)
+	}
+	for {
+		line, flush, err := readPktLine(in)
+		if errors.Is(err, io.ErrUnexpectedEOF) && len(wants) == 0 {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if flush {
+			break
+		}
+		if rest, ok := strings.CutPrefix(line, "want "); ok {
+			sha, capList, _ := strings.Cut(rest, " ")
+			if len(wants) == 0 {
+				clientCaps = strings.Fields(capList)
+			}
+			if !advertised[sha] {
+				return refuse("not our ref %s", sha)
+			}
+			wants = append(wants, sha)
+		} else if sha, ok := strings.CutPrefix(line, "shallow "); ok {
+			if r.Format.ValidateSHA(sha) != nil {
+				return refuse("invalid shallow line: %s", line)
+			}
+			clientShallow[sha] = true
+		} else if n, ok := strings.CutPrefix(line, "deepen "); ok {
+			if depth, err = strconv.Atoi(n); err != nil || depth <= 0 {
+				return refuse("invalid deepen: %s", n)
+			}
+		} else {
+			return refuse("unexpected line %q", line)
+		}
+	}
+	if len(wants) == 0 {
+		return nil
+	}
+	if format, ok := clientCaps.get("object-format"); ok && format != r.Format.Name {
+		return refuse("this repository uses %s objects, not %s", r.Format.Name, format)
+	}
+
+	// A shallow client is told where the history it gets stops
+	var within, boundary, deepened map[string]bool
+	if depth > 0 {
+		if within, boundary, deepened, err = r.uploadShallow(wants, depth, clientShallow); err != nil {
+			return err
+		}
+	}
+	if depth > 0 || len(clientShallow) > 0 {
+		for _, sha := range slices.Sorted(maps.Keys(boundary)) {
+			if !clientShallow[sha] {
+				if err := writePktf(out, "shallow %s\n", sha); err != nil {
+					return err
+				}
+			}
+		}
+		for _, sha := range slices.Sorted(maps.Keys(deepened)) {
+			if err := writePktf(out, "unshallow %s\n", sha); err != nil {
+				return err
+			}
+		}
+		if err := writeFlush(out); err != nil {
+			return err
+		}
+	}
+
+	// Without multi_ack the first commit both sides have is acknowledged
+	// at once, and a NAK answers each flush and the done while there is none
+	var common []string
+	for {
+		line, flush, err := readPktLine(in)
+		if err != nil {
+			return err
+		}
+		if flush || line == "done" {
+			if len(common) == 0 {
+				if err := writePktf(out, "NAK\n"); err != nil {
+					return err
+				}
+			}
+			if line == "done" {
+				break
+			}
+			continue
+		}
+		sha, ok := strings.CutPrefix(line, "have ")
+		if !ok {
+			return refuse("unexpected line %q", line)
+		}
+		if objectType, _, err := r.ReadObject(sha); err == nil && objectType == object.CommitObject && !slices.Contains(common, sha) {
+			common = append(common, sha)
+			if len(common) == 1 {
+				if err := writePktf(out, "ACK %s\n", sha); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	// From here on a client taking a side-band gets progress messages on
+	// channel 2, unless it asked for no-progress, and a failure on channel 3
+	bandSize := 0
+	switch {
+	case clientCaps.has("side-band-64k"):
+		bandSize = pktMax
+	case clientCaps.has("side-band"):
+		bandSize = 1000
+	}
+	var w, progressOut io.Writer = out, nil
+	if bandSize > 0 {
+		w = &sidebandWriter{w: out, band: bandData, size: bandSize}
+		if !clientCaps.has("no-progress") {
+			progressOut = &sidebandWriter{w: out, band: bandProgress, size: bandSize}
+		}
+	}
+	defer func(saved io.Writer) { r.Progress = saved }(r.Progress)
+	r.Progress = progressOut
+	fail := func(err error) error {
+		if bandSize > 0 {
+			writePkt(out, append([]byte{bandError}, "upload-pack: "+err.Error()+"\n"...))
+		}
+		return err
+	}
+
+	shas, err := r.uploadObjects(wants, common, clientShallow, within)
+	if err != nil {
+		return fail(err)
+	}
+	buffered := bufio.NewWriterSize(w, max(bandSize-5, 4096))
+	deltas, err := r.writeUploadPack(buffered, shas, clientCaps.has("ofs-delta"))
+	if err == nil {
+		err = buffered.Flush()
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if progressOut != nil {
+		fmt.Fprintf(progressOut, "Total %d (delta %d)\n", len(shas), deltas)
+	}
+	if bandSize > 0 {
+		return writeFlush(out)
+	}
+	return nil
+}
+
+// uploadShallow walks the history of wants breadth first down to depth
+// commits, returning the commits within it, the ones at the bottom whose
+// parents are left out, and the client's shallow commits walked past, whose
+// parents the client now gets
+func (r *Repository) uploadShallow(wants []string, depth int, clientShallow map[string]bool) (within, boundary, deepened map[string]bool, err error) {
+	type queued struct {
+		sha   string
+		depth int
+	}
+	within = make(map[string]bool)
+	boundary = make(map[string]bool)
+	deepened = make(map[string]bool)
+	var queue []queued
+	for _, want := range wants {
+		sha, err := r.peelTag(want)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if objectType, _, err := r.ReadObject(sha); err != nil {
+			return nil, nil, nil, err
+		} else if objectType == object.CommitObject {
+			queue = append(queue, queued{sha, 1})
+		}
+	}
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+		if within[item.sha] {
+			continue
+		}
+		within[item.sha] = true
+		if err := r.canceled(); err != nil {
+			return nil, nil, nil, err
+		}
+		parents, err := r.commitParents(item.sha)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if len(parents) == 0 {
+			// The server's own shallow commits stay shallow for the client
+			if shallow, err := r.isShallowCommit(item.sha); err != nil {
+				return nil, nil, nil, err
+			} else if shallow {
+				boundary[item.sha] = true
+			}
+			continue
+		}
+		if item.depth >= depth {
+			boundary[item.sha] = true
+			continue
+		}
+		if clientShallow[item.sha] {
+			deepened[item.sha] = true
+		}
+		for _, parent := range parents {
+			queue = append(queue, queued{parent, item.depth + 1})
+		}
+	}
+	return within, boundary, deepened, nil
+}
+
+// uploadObjects lists the objects a client that has the commits common,
+// and everything below them down to its shallow commits, lacks to get
+// wants: the tags wanted, the commits, and their trees and blobs except the
+// ones of the commits it has that the new ones build on. For a shallow
+// fetch the commits are those within the depth; otherwise the walk stops at
+// the client's shallow commits.
+func (r *Repository) uploadObjects(wants, common []string, clientShallow, within map[string]bool) ([]string, error) {
+	has := make(map[string]bool)
+	stack := slices.Clone(common)
+	for len(stack) > 0 {
+		sha := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if has[sha] {
+			continue
+		}
+		has[sha] = true
+		if clientShallow[sha] {
+			continue
+		}
+		parents, err := r.commitParents(sha)
+		if err != nil {
+			return nil, err
+		}
+		stack = append(stack, parents...)
+	}
+
+	// markTree adds a tree and everything below it that is not in had yet
+	// to into, and to out when it is set
+	had := make(map[string]bool)
+	var markTree func(treeSHA string, into map[string]bool, out *[]string) error
+	markTree = func(treeSHA string, into map[string]bool, out *[]string) error {
+		if into[treeSHA] || had[treeSHA] {
+			return nil
+		}
+		into[treeSHA] = true
+		if out != nil {
+			*out = append(*out, treeSHA)
+		}
+		_, content, err := r.ReadObject(treeSHA)
+		if err != nil {
+			return err
+		}
+		entries, err := r.Format.ParseTree(content)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			switch {
+			case entry.Mode == GitlinkMode:
+			case entry.Type == object.TreeObject:
+				if err := markTree(entry.SHA, into, out); err != nil {
+					return err
+				}
+			case !into[entry.SHA] && !had[entry.SHA]:
+				into[entry.SHA] = true
+				if out != nil {
+					*out = append(*out, entry.SHA)
+				}
+			}
+		}
+		return nil
+	}
+
+	// Tags are sent with what they point to; a tagged tree or blob needs
+	// nothing else
+	var shas, wanted []string
+	sending := make(map[string]bool)
+	for _, want := range wants {
+		sha := want
+		for {
+			objectType, content, err := r.ReadObject(sha)
+			if err != nil {
+				return nil, err
+			}
+			switch objectType {
+			case object.CommitObject:
+				wanted = append(wanted, sha)
+			case object.TreeObject:
+				err = markTree(sha, sending, &shas)
+			case object.BlobObject:
+				if !sending[sha] {
+					sending[sha] = true
+					shas = append(shas, sha)
+				}
+			case object.TagObject:
+				if !sending[sha] {
+					sending[sha] = true
+					shas = append(shas, sha)
+				}
+				var tag *object.Tag
+				if tag, err = object.ParseTag(sha, content); err == nil {
+					sha = tag.Object
+					continue
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	var commits []string
+	if within != nil {
+		for _, sha := range slices.Sorted(maps.Keys(within)) {
+			if !has[sha] {
+				commits = append(commits, sha)
+			}
+		}
+	} else {
+		seen := make(map[string]bool)
+		stack := slices.Clone(wanted)
+		for len(stack) > 0 {
+			sha := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if seen[sha] || has[sha] {
+				continue
+			}
+			seen[sha] = true
+			if err := r.canceled(); err != nil {
+				return nil, err
+			}
+			commits = append(commits, sha)
+			if clientShallow[sha] {
+				continue
+			}
+			parents, err := r.commitParents(sha)
+			if err != nil {
+				return nil, err
+			}
+			stack = append(stack, parents...)
+		}
+	}
+
+	// The trees of the commits the client has next to the new ones are
+	// marked as had, so unchanged files are not sent again
+	for _, sha := range commits {
+		parents, err := r.commitParents(sha)
+		if err != nil {
+			return nil, err
+		}
+		for _, parent := range parents {
+			if !has[parent] {
+				continue
+			}
+			commit, err := r.ReadCommit(parent)
+			if err != nil {
+				return nil, err
+			}
+			if err := markTree(commit.TreeSHA, had, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+	shas = append(shas, commits...)
+	for _, sha := range commits {
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return nil, err
+		}
+		if err := markTree(commit.TreeSHA, sending, &shas); err != nil {
+			return nil, err
+		}
+	}
+	return shas, nil
+}
+
+// writeUploadPack writes the objects shas as a pack, with deltas between
+// them when ofsDelta allows offset deltas, and returns how many are deltas
+func (r *Repository) writeUploadPack(w io.Writer, shas []string, ofsDelta bool) (deltas int, err error) {
+	objects := make([]*packObject, 0, len(shas))
+	meter := r.startProgress("Counting objects", len(shas))
+	for _, sha := range shas {
+		if err := r.canceled(); err != nil {
+			return 0, err
+		}
+		objectType, content, err := r.ReadObject(sha)
+		if err != nil {
+			return 0, err
+		}
+		objects = append(objects, &packObject{sha: sha, objType: objectType, data: content})
+		meter.add(1, 0)
+	}
+	meter.done()
+	if ofsDelta {
+		if err := r.setNameHashes(objects); err != nil {
+			return 0, err
+		}
+		findDeltas(objects, PackOptions{Window: 10, Depth: 50, Threads: runtime.NumCPU()})
+		for _, obj := range objects {
+			if obj.base != nil {
+				deltas++
+			}
+		}
+	}
+	if _, err := encodePack(w, objects, r.Format, nil); err != nil {
+		return 0, err
+	}
+	return deltas, nil
+}
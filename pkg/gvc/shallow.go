@@ -0,0 +1,281 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// ShallowFile lists the commits of a shallow repository whose parents were
+// not fetched, one SHA per line, as in Git
+const ShallowFile = "shallow"
+
+// shallowCommits returns the commits ShallowFile lists, read once per handle
+func (r *Repository) shallowCommits() (map[string]bool, error) {
+	if r.shallowLoaded {
+		return r.shallow, nil
+	}
+	data, err := r.readGitFile(r.gitPath(ShallowFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", ShallowFile, err)
+	}
+	shallow := make(map[string]bool)
+	for _, line := range strings.Fields(string(data)) {
+		if err := r.Format.ValidateSHA(line); err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q", ShallowFile, line)
+		}
+		shallow[line] = true
+	}
+	r.shallow, r.shallowLoaded = shallow, true
+	return shallow, nil
+}
+
+// IsShallow reports whether the repository was fetched with a limited
+// depth, so some of its commits are missing their parents
+func (r *Repository) IsShallow() (bool, error) {
+	shallow, err := r.shallowCommits()
+	return len(shallow) > 0, err
+}
+
+// isShallowCommit reports whether a commit's parents are cut off. History
+// walks treat such a commit as a root.
+func (r *Repository) isShallowCommit(sha string) (bool, error) {
+	shallow, err := r.shallowCommits()
+	return shallow[sha], err
+}
+
+// writeShallow replaces the list of shallow commits, removing ShallowFile
+// once the repository is complete
+func (r *Repository) writeShallow(shallow map[string]bool) error {
+	path := r.gitPath(ShallowFile)
+	if len(shallow) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", ShallowFile, err)
+		}
+	} else {
+		shas := make([]string, 0, len(shallow))
+		for sha := range shallow {
+			shas = append(shas, sha)
+		}
+		sort.Strings(shas)
+		if err := r.writeGitFile(path, []byte(strings.Join(shas, "\n")+"\n")); err != nil {
+			return fmt.Errorf("failed to write %s: %w", ShallowFile, err)
+		}
+	}
+	r.shallow, r.shallowLoaded = shallow, true
+	// The commit-graph is only trusted in a complete repository
+	r.commitGraph, r.commitGraphLoaded = nil, false
+	r.commitNodeCache = make(map[string]*CommitNode)
+	return nil
+}
+
+// transferShallow copies into dst the objects of the history reachable from
+// tips that dst lacks, down to depth commits from each tip (0 for no limit).
+// The commits where the copied history stops short of its roots are recorded
+// in dst's ShallowFile; src's own shallow commits stay shallow. Without a
+// depth or unshallow the walk stops at commits dst already has, keeping its
+// shallow commits as they are; otherwise it goes through them, so dst's
+// history is deepened, or shortened, to depth, or completed by unshallow.
+// With verify nothing is copied unless every object passes fsckTransfer.
+func transferShallow(src, dst *Repository, tips []string, depth int, unshallow, verify bool) error {
+	if src.Format != dst.Format {
+		return fmt.Errorf("cannot transfer objects: %s uses %s but %s uses %s",
+			src.Root, src.Format.Name, dst.Root, dst.Format.Name)
+	}
+	if err := dst.checkWritable(); err != nil {
+		return err
+	}
+	srcShallow, err := src.shallowCommits()
+	if err != nil {
+		return err
+	}
+	dstShallow, err := dst.shallowCommits()
+	if err != nil {
+		return err
+	}
+
+	// Walk the commits breadth first, so each is reached at its least depth
+	type queued struct {
+		sha   string
+		depth int
+	}
+	sending := make(map[string]bool)
+	boundary := make(map[string]bool) // commits left without their parents
+	deepened := make(map[string]bool) // dst's shallow commits walked past
+	seen := make(map[string]bool)
+	var queue []queued
+	var commits []string
+	for _, tip := range tips {
+		sha, err := transferTag(src, dst, tip, sending)
+		if err != nil {
+			return err
+		}
+		if sha != "" {
+			queue = append(queue, queued{sha, 1})
+		}
+	}
+	for len(queue) > 0 {
+		item := queue[0]
+		queue = queue[1:]
+		if seen[item.sha] {
+			continue
+		}
+		seen[item.sha] = true
+
+		// Only a deepening fetch looks at the history dst already has
+		have := dst.objectExists(item.sha)
+		if have && depth == 0 && !unshallow {
+			continue
+		}
+		commit, err := src.ReadCommit(item.sha)
+		if err != nil {
+			return err
+		}
+		if !have {
+			sending[item.sha] = true
+			commits = append(commits, item.sha)
+		}
+		if len(commit.Parents) == 0 {
+			if srcShallow[item.sha] {
+				boundary[item.sha] = true
+			}
+			continue
+		}
+		if depth > 0 && item.depth >= depth {
+			boundary[item.sha] = true
+			continue
+		}
+		if dstShallow[item.sha] {
+			deepened[item.sha] = true
+		}
+		for _, parent := range commit.Parents {
+			queue = append(queue, queued{parent, item.depth + 1})
+		}
+	}
+	for _, sha := range commits {
+		commit, err := src.ReadCommit(sha)
+		if err != nil {
+			return err
+		}
+		if err := collectTree(src, dst, commit.TreeSHA, sending); err != nil {
+			return err
+		}
+	}
+
+	if verify {
+		if err := fsckTransfer(src, dst, sending, boundary); err != nil {
+			return err
+		}
+	}
+	shas := make([]string, 0, len(sending))
+	for sha := range sending {
+		shas = append(shas, sha)
+	}
+	sort.Strings(shas)
+	err = dst.batchObjects(func() error {
+		for _, sha := range shas {
+			objectType, content, err := src.ReadObject(sha)
+			if err != nil {
+				return err
+			}
+			if _, err := dst.WriteObject(objectType, content); err != nil {
+				return err
+			}
+			dst.transfer.add(1, int64(len(content)), int64(len(content)), 0)
+			if err := dst.transfer.pace(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(shas) > 0 {
+		fmt.Fprintf(dst.Out, "Copied %d objects\n", len(shas))
+	}
+
+	shallow := make(map[string]bool, len(dstShallow)+len(boundary))
+	for sha := range dstShallow {
+		if !deepened[sha] {
+			shallow[sha] = true
+		}
+	}
+	for sha := range boundary {
+		shallow[sha] = true
+	}
+	if err := dst.writeShallow(shallow); err != nil {
+		return err
+	}
+	return transferLargeFiles(src, dst, false)
+}
+
+// transferTag adds the tags a ref points through to sending, returning the
+// commit they end at, or "" when they end at a tree or blob, whose objects
+// are added too
+func transferTag(src, dst *Repository, sha string, sending map[string]bool) (string, error) {
+	for {
+		objectType, content, err := src.ReadObject(sha)
+		if err != nil {
+			return "", err
+		}
+		switch objectType {
+		case object.CommitObject:
+			return sha, nil
+		case object.TreeObject:
+			return "", collectTree(src, dst, sha, sending)
+		case object.BlobObject:
+			if !dst.objectExists(sha) {
+				sending[sha] = true
+			}
+			return "", nil
+		case object.TagObject:
+			if !dst.objectExists(sha) {
+				sending[sha] = true
+			}
+			tag, err := object.ParseTag(sha, content)
+			if err != nil {
+				return "", err
+			}
+			sha = tag.Object
+		default:
+			return "", fmt.Errorf("unknown object type %q for %s", objectType, sha)
+		}
+	}
+}
+
+// collectTree adds a tree and everything below it that dst lacks to
+// sending. A tree dst has is complete, so it is not looked into.
+func collectTree(src, dst *Repository, treeSHA string, sending map[string]bool) error {
+	if sending[treeSHA] || dst.objectExists(treeSHA) {
+		return nil
+	}
+	sending[treeSHA] = true
+	_, content, err := src.ReadObject(treeSHA)
+	if err != nil {
+		return err
+	}
+	entries, err := src.Format.ParseTree(content)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		switch {
+		case entry.Mode == GitlinkMode:
+		case entry.Type == object.TreeObject:
+			if err := collectTree(src, dst, entry.SHA, sending); err != nil {
+				return err
+			}
+		case !sending[entry.SHA] && !dst.objectExists(entry.SHA):
+			sending[entry.SHA] = true
+		}
+	}
+	return nil
+}
+
+// errNotShallow is returned for --unshallow in a complete repository
+var errNotShallow = errors.New("--unshallow on a complete repository does not make sense")
@@ -0,0 +1,177 @@
+package gvc
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// sharedPerm is core.sharedRepository: the permissions files in the
+// repository directory get so several users can push to it. The zero value
+// leaves them to the umask.
+type sharedPerm struct {
+	bits  os.FileMode // permission bits added to a file's, or given it when exact
+	exact bool        // set from an octal mode, which replaces the file's bits
+}
+
+// parseSharedRepository reads a core.sharedRepository value as Git does:
+// false, umask or 0 keep the umask's permissions, true, group or 1 make
+// files group-writable, all, world or everybody (2) also readable by
+// everyone, and an octal mode such as 0640 is used as it is. The owner must
+// keep read and write permission.
+func parseSharedRepository(value string) (sharedPerm, error) {
+	switch strings.ToLower(value) {
+	case "", "false", "umask", "0", "no", "off":
+		return sharedPerm{}, nil
+	case "true", "group", "1", "yes", "on":
+		return sharedPerm{bits: 0660}, nil
+	case "all", "world", "everybody", "2":
+		return sharedPerm{bits: 0664}, nil
+	}
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return sharedPerm{}, fmt.Errorf("invalid core.sharedRepository value '%s'", value)
+	}
+	if mode&0600 != 0600 {
+		return sharedPerm{}, fmt.Errorf("invalid core.sharedRepository value '%s': the owner of files must always have read and write permissions", value)
+	}
+	return sharedPerm{bits: os.FileMode(mode), exact: true}, nil
+}
+
+// loadShared reads core.sharedRepository
+func (r *Repository) loadShared() error {
+	value, err := r.getConfigString("core.sharedRepository", "")
+	if err != nil {
+		return err
+	}
+	r.shared, err = parseSharedRepository(value)
+	return err
+}
+
+// mode returns the mode a file or directory with mode gets. Directories and
+// executable files can be entered or run by whoever may read them, and
+// shared directories are setgid so what is created inside keeps their group.
+func (p sharedPerm) mode(mode os.FileMode) os.FileMode {
+	perm := mode.Perm()
+	if p.exact {
+		perm = p.bits
+	} else {
+		perm |= p.bits
+	}
+	if mode.IsDir() || mode&0100 != 0 {
+		perm |= (perm & 0444) >> 2
+	}
+	mode = mode&^os.ModePerm | perm
+	if mode.IsDir() {
+		mode |= os.ModeSetgid
+	}
+	return mode
+}
+
+// apply gives a file or directory the permissions p asks for. It does
+// nothing for the zero value, and on Windows, which has no such permissions.
+func (p sharedPerm) apply(path string) error {
+	if p == (sharedPerm{}) || runtime.GOOS == "windows" {
+		return nil
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %w", path, err)
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return nil
+	}
+	if mode := p.mode(info.Mode()); mode != info.Mode() {
+		if err := os.Chmod(path, mode&(os.ModePerm|os.ModeSetgid)); err != nil {
+			return fmt.Errorf("failed to set permissions of %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// adjustSharedPerm gives a file or directory just created in the repository
+// directory the permissions core.sharedRepository asks for
+func (r *Repository) adjustSharedPerm(path string) error {
+	return r.shared.apply(path)
+}
+
+// mkdirShared creates dir and any missing parents, as os.MkdirAll does,
+// giving the ones it creates the permissions core.sharedRepository asks for
+func (r *Repository) mkdirShared(dir string) error {
+	if r.shared == (sharedPerm{}) {
+		return os.MkdirAll(dir, 0755)
+	}
+	missing := dir
+	for {
+		parent := filepath.Dir(missing)
+		if parent == missing || isDir(parent) {
+			break
+		}
+		missing = parent
+	}
+	created := !isDir(dir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if !created {
+		return nil
+	}
+	for path := dir; ; path = filepath.Dir(path) {
+		if err := r.adjustSharedPerm(path); err != nil {
+			return err
+		}
+		if path == missing {
+			return nil
+		}
+	}
+}
+
+// lockShared locks a file in the repository directory whose directories,
+// when created, and content on commit get the permissions
+// core.sharedRepository asks for
+func (r *Repository) lockShared(path string) (*lockFile, error) {
+	if r.shared == (sharedPerm{}) {
+		return lock(path)
+	}
+	if err := r.mkdirShared(filepath.Dir(path)); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	l, err := lock(path)
+	if err != nil {
+		return nil, err
+	}
+	l.shared = r.shared
+	return l, nil
+}
+
+// SetSharedRepository sets core.sharedRepository to value and gives every
+// file and directory already in the repository directory the permissions it
+// asks for, as init --shared does. A value of umask only changes the
+// setting; the permissions files already have are left alone.
+func (r *Repository) SetSharedRepository(value string) error {
+	shared, err := parseSharedRepository(value)
+	if err != nil {
+		return err
+	}
+	r.shared = shared
+	if err := r.SetConfig("core.sharedRepository", value); err != nil {
+		return err
+	}
+	err = filepath.WalkDir(r.CommonDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		return r.adjustSharedPerm(path)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to share %s: %w", r.CommonDir, err)
+	}
+	if r.GitDir != r.CommonDir {
+		return r.adjustSharedPerm(r.GitDir)
+	}
+	return nil
+}
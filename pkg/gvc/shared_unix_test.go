@@ -0,0 +1,64 @@
+//go:build unix
+
+package gvc
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// withUmask runs the rest of the test with the process umask set to mask
+func withUmask(t *testing.T, mask int) {
+	old := syscall.Umask(mask)
+	t.Cleanup(func() { syscall.Umask(old) })
+}
+
+func assertPerm(t *testing.T, path string, want os.FileMode) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != want {
+		t.Errorf("%s has mode %o, want %o", filepath.Base(path), got, want)
+	}
+}
+
+func TestNewFilesFollowUmask(t *testing.T) {
+	withUmask(t, 077)
+	repo, err := Init(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sha, err := repo.WriteObject(object.BlobObject, []byte("content\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPerm(t, repo.objectPath(sha), 0600)
+
+	path := filepath.Join(repo.GitDir, "atomic")
+	if err := writeFileAtomic(path, []byte("data\n")); err != nil {
+		t.Fatal(err)
+	}
+	assertPerm(t, path, 0600)
+}
+
+func TestSharedRepositoryOverridesUmask(t *testing.T) {
+	withUmask(t, 077)
+	repo, err := Init(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetSharedRepository("group"); err != nil {
+		t.Fatal(err)
+	}
+	sha, err := repo.WriteObject(object.BlobObject, []byte("content\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPerm(t, repo.objectPath(sha), 0660)
+}
@@ -0,0 +1,338 @@
+package gvc
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SignatureStatus is the outcome of checking a commit signature, using the
+// letters of Git's %G? placeholder
+type SignatureStatus byte
+
+const (
+	SignatureGood      SignatureStatus = 'G' // valid signature from a trusted key
+	SignatureUntrusted SignatureStatus = 'U' // valid signature from a key of unknown trust
+	SignatureBad       SignatureStatus = 'B' // the signature does not match the commit
+	SignatureUnchecked SignatureStatus = 'E' // the signature could not be checked, e.g. the key is missing
+	SignatureNone      SignatureStatus = 'N' // the commit is not signed
+)
+
+// Valid reports whether the signature matches the commit, trusted or not
+func (s SignatureStatus) Valid() bool {
+	return s == SignatureGood || s == SignatureUntrusted
+}
+
+// SignatureCheck describes the signature of a commit
+type SignatureCheck struct {
+	Status SignatureStatus
+	Format string // "openpgp" or "ssh"
+	Signer string // user ID or principal that made the signature, if known
+	Key    string // key ID or fingerprint
+	Output string // human-readable report of the signing program
+}
+
+// Verified reports whether the commit can be relied on: a good signature, or
+// an OpenPGP one from a key of unknown trust. An SSH key that no allowed
+// signer matches could be anyone's, so its signature does not count.
+func (c *SignatureCheck) Verified() bool {
+	return c.Status == SignatureGood || c.Status == SignatureUntrusted && c.Format != "ssh"
+}
+
+const (
+	pgpSignatureHeader = "-----BEGIN PGP SIGNATURE-----"
+	sshSignatureHeader = "-----BEGIN SSH SIGNATURE-----"
+)
+
+// signatureHeader is the commit header holding the signature for this object format
+func (r *Repository) signatureHeader() string {
+	if r.Format.Name == "sha1" {
+		return "gpgsig"
+	}
+	return "gpgsig-" + r.Format.Name
+}
+
+// signingFormat returns gpg.format, "openpgp" by default
+func (r *Repository) signingFormat() (string, error) {
+	format, found, err := r.GetConfig("gpg.format")
+	if err != nil || !found {
+		return "openpgp", err
+	}
+	switch format = strings.ToLower(format); format {
+	case "openpgp", "ssh":
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported gpg.format %q: use openpgp or ssh", format)
+}
+
+// runSigner runs a signing program with input on stdin and returns its stdout and stderr
+func runSigner(program string, input []byte, args ...string) ([]byte, []byte, error) {
+	cmd := exec.Command(program, args...)
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// signPayload signs commit content with the key configured in user.signingKey,
+// using gpg for OpenPGP and ssh-keygen for SSH signatures
+func (r *Repository) signPayload(payload []byte) (string, error) {
+	format, err := r.signingFormat()
+	if err != nil {
+		return "", err
+	}
+	key, err := r.getConfigString("user.signingKey", "")
+	if err != nil {
+		return "", err
+	}
+
+	if format == "ssh" {
+		if key == "" {
+			return "", fmt.Errorf("ssh signing needs user.signingKey set to a private key file")
+		}
+		key = expandHome(key)
+		program, err := r.getConfigString("gpg.ssh.program", "ssh-keygen")
+		if err != nil {
+			return "", err
+		}
+		signature, stderr, err := runSigner(program, payload, "-Y", "sign", "-n", "git", "-f", key)
+		if err != nil {
+			return "", fmt.Errorf("failed to sign commit with %s: %w\n%s", program, err, strings.TrimSpace(string(stderr)))
+		}
+		return string(signature), nil
+	}
+
+	program, err := r.getConfigString("gpg.program", "gpg")
+	if err != nil {
+		return "", err
+	}
+	args := []string{"--status-fd=2", "-bsa"}
+	if key != "" {
+		args = append(args, "-u", key)
+	}
+	signature, stderr, err := runSigner(program, payload, args...)
+	if err == nil && !bytes.Contains(stderr, []byte("[GNUPG:] SIG_CREATED ")) {
+		err = fmt.Errorf("no signature was created")
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to sign commit with %s: %w\n%s", program, err, strings.TrimSpace(string(stderr)))
+	}
+	return string(signature), nil
+}
+
+// expandHome replaces a leading ~/ with the home directory
+func expandHome(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// signCommit inserts a signature header after the committer line of commit content
+func (r *Repository) signCommit(content []byte) ([]byte, error) {
+	signature, err := r.signPayload(content)
+	if err != nil {
+		return nil, err
+	}
+	header, message, _ := bytes.Cut(content, []byte("\n\n"))
+
+	var signed bytes.Buffer
+	signed.Write(header)
+	signed.WriteString("\n" + r.signatureHeader())
+	// Continuation lines start with a space, so blank lines become a lone space
+	for i, line := range strings.Split(strings.TrimRight(signature, "\n"), "\n") {
+		if i > 0 {
+			signed.WriteString("\n")
+		}
+		signed.WriteString(" " + line)
+	}
+	signed.WriteString("\n\n")
+	signed.Write(message)
+	return signed.Bytes(), nil
+}
+
+// splitCommitSignature separates the signature header from commit content,
+// returning the signature and the content it signs
+func (r *Repository) splitCommitSignature(content []byte) (string, []byte) {
+	header, message, found := bytes.Cut(content, []byte("\n\n"))
+	if !found {
+		return "", content
+	}
+
+	var payload bytes.Buffer
+	var signature []string
+	inSignature := false
+	for _, line := range strings.Split(string(header), "\n") {
+		if inSignature {
+			if continuation, ok := strings.CutPrefix(line, " "); ok {
+				signature = append(signature, continuation)
+				continue
+			}
+			inSignature = false
+		}
+		if value, ok := strings.CutPrefix(line, r.signatureHeader()+" "); ok && signature == nil {
+			signature = append(signature, value)
+			inSignature = true
+			continue
+		}
+		payload.WriteString(line + "\n")
+	}
+	if signature == nil {
+		return "", content
+	}
+	payload.WriteString("\n")
+	payload.Write(message)
+	return strings.Join(signature, "\n") + "\n", payload.Bytes()
+}
+
+// VerifyCommit checks the signature of a commit with gpg or ssh-keygen. SSH
+// signers are identified through gpg.ssh.allowedSignersFile; without it an SSH
+// signature cannot be checked, and one by a key no allowed signer matches is
+// reported as untrusted.
+func (r *Repository) VerifyCommit(commitSHA string) (*SignatureCheck, error) {
+	_, content, err := r.ReadObject(commitSHA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %s: %w", commitSHA, err)
+	}
+	signature, payload := r.splitCommitSignature(content)
+	switch {
+	case signature == "":
+		return &SignatureCheck{Status: SignatureNone}, nil
+	case strings.HasPrefix(signature, sshSignatureHeader):
+		return r.verifySSH(signature, payload)
+	case strings.HasPrefix(signature, pgpSignatureHeader):
+		return r.verifyOpenPGP(signature, payload)
+	}
+	return &SignatureCheck{Status: SignatureUnchecked, Output: "unknown signature type"}, nil
+}
+
+// writeSignatureFile stores a signature in a temporary file for the verifying program
+func writeSignatureFile(signature string) (string, error) {
+	tmp, err := os.CreateTemp("", "gvc_sig_")
+	if err != nil {
+		return "", fmt.Errorf("failed to write signature: %w", err)
+	}
+	defer tmp.Close()
+	if _, err := tmp.WriteString(signature); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write signature: %w", err)
+	}
+	return tmp.Name(), nil
+}
+
+// verifyOpenPGP checks a signature with gpg, reading the verdict from its status lines
+func (r *Repository) verifyOpenPGP(signature string, payload []byte) (*SignatureCheck, error) {
+	program, err := r.getConfigString("gpg.program", "gpg")
+	if err != nil {
+		return nil, err
+	}
+	sigFile, err := writeSignatureFile(signature)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(sigFile)
+
+	status, output, err := runSigner(program, payload, "--status-fd=1", "--verify", sigFile, "-")
+	if _, isExit := err.(*exec.ExitError); err != nil && !isExit {
+		return nil, fmt.Errorf("failed to run %s: %w", program, err)
+	}
+
+	check := &SignatureCheck{Status: SignatureUnchecked, Format: "openpgp", Output: string(output)}
+	for _, line := range strings.Split(string(status), "\n") {
+		fields := strings.SplitN(strings.TrimPrefix(line, "[GNUPG:] "), " ", 3)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "GOODSIG", "EXPSIG", "EXPKEYSIG", "REVKEYSIG":
+			check.Status = SignatureGood
+			if fields[0] != "GOODSIG" {
+				check.Status = SignatureUntrusted
+			}
+			check.Key = fields[1]
+			if len(fields) == 3 {
+				check.Signer = fields[2]
+			}
+		case "BADSIG":
+			check.Status, check.Key = SignatureBad, fields[1]
+			if len(fields) == 3 {
+				check.Signer = fields[2]
+			}
+		case "ERRSIG":
+			check.Status, check.Key = SignatureUnchecked, fields[1]
+		case "TRUST_UNDEFINED", "TRUST_NEVER":
+			if check.Status == SignatureGood {
+				check.Status = SignatureUntrusted
+			}
+		}
+	}
+	return check, nil
+}
+
+// sshKeyPattern finds the key fingerprint in ssh-keygen's verification output
+var sshKeyPattern = regexp.MustCompile(`key (SHA256:\S+)`)
+
+// verifySSH checks a signature with ssh-keygen against the allowed signers file
+func (r *Repository) verifySSH(signature string, payload []byte) (*SignatureCheck, error) {
+	program, err := r.getConfigString("gpg.ssh.program", "ssh-keygen")
+	if err != nil {
+		return nil, err
+	}
+	allowed, err := r.getConfigString("gpg.ssh.allowedSignersFile", "")
+	if err != nil {
+		return nil, err
+	}
+	if allowed == "" {
+		return &SignatureCheck{Status: SignatureUnchecked, Format: "ssh",
+			Output: "error: gpg.ssh.allowedSignersFile needs to be configured and exist for ssh signature verification\n"}, nil
+	}
+	allowed = expandHome(allowed)
+	sigFile, err := writeSignatureFile(signature)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(sigFile)
+
+	check := &SignatureCheck{Format: "ssh"}
+	principal := ""
+	out, _, err := runSigner(program, nil, "-Y", "find-principals", "-f", allowed, "-s", sigFile)
+	if err == nil {
+		principal = strings.TrimSpace(strings.SplitN(string(out), "\n", 2)[0])
+	}
+
+	var stdout, stderr []byte
+	if principal != "" {
+		stdout, stderr, err = runSigner(program, payload, "-Y", "verify", "-n", "git", "-f", allowed, "-I", principal, "-s", sigFile)
+		check.Signer = principal
+		check.Status = SignatureGood
+	} else {
+		// No allowed signer: only check that the signature matches the commit,
+		// to tell a bad signature from an untrusted one
+		stdout, stderr, err = runSigner(program, payload, "-Y", "check-novalidate", "-n", "git", "-s", sigFile)
+		check.Status = SignatureUntrusted
+	}
+	if _, isExit := err.(*exec.ExitError); err != nil && !isExit {
+		return nil, fmt.Errorf("failed to run %s: %w", program, err)
+	}
+	check.Output = strings.TrimSpace(string(stdout) + string(stderr))
+	if match := sshKeyPattern.FindStringSubmatch(check.Output); match != nil {
+		check.Key = match[1]
+	}
+	switch {
+	case err != nil:
+		check.Status = SignatureBad
+	case principal == "":
+		// ssh-keygen calls the signature good, which it is not to anyone
+		// relying on the allowed signers
+		check.Output = fmt.Sprintf("Signature with key %s matches, but no principal in %s is allowed to sign with it", check.Key, allowed)
+	}
+	check.Output += "\n"
+	return check, nil
+}
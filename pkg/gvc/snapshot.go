@@ -0,0 +1,227 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SnapshotRefPrefix namespaces the automatic snapshots of each branch, which
+// never move a branch, HEAD or the index
+const SnapshotRefPrefix = "refs/snapshots/"
+
+// detachedSnapshots names the snapshot chain recorded while HEAD is detached
+const detachedSnapshots = "detached"
+
+// Snapshot is one automatic commit of the working tree
+type Snapshot struct {
+	Name    string // e.g. snapshots/main~2, which resolves to SHA
+	SHA     string
+	Time    time.Time
+	Message string
+}
+
+// snapshotRef returns the ref holding the snapshots of the current branch
+func (r *Repository) snapshotRef() (string, error) {
+	branchRef, err := r.HeadRef()
+	if err != nil {
+		return "", err
+	}
+	if branchRef == "" {
+		return SnapshotRefPrefix + detachedSnapshots, nil
+	}
+	return SnapshotRefPrefix + strings.TrimPrefix(branchRef, "refs/heads/"), nil
+}
+
+// SaveSnapshot commits the working tree, tracked files and untracked ones
+// that are not ignored, to the current branch's snapshot ref, on top of its
+// previous snapshot. Nothing is recorded when the working tree matches that
+// snapshot, in which case the returned SHA is "".
+func (r *Repository) SaveSnapshot() (string, error) {
+	if err := r.checkWritable(); err != nil {
+		return "", err
+	}
+	ref, err := r.snapshotRef()
+	if err != nil {
+		return "", err
+	}
+	tree, err := r.workingTreeSHA()
+	if err != nil {
+		return "", err
+	}
+
+	previous, err := r.ReadRef(ref)
+	if err != nil {
+		return "", err
+	}
+	var parents []string
+	if previous != "" {
+		commit, err := r.ReadCommit(previous)
+		if err != nil {
+			return "", err
+		}
+		if commit.TreeSHA == tree {
+			return "", nil
+		}
+		parents = []string{previous}
+	}
+
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return "", err
+	}
+	branch := strings.TrimPrefix(ref, SnapshotRefPrefix)
+	message := fmt.Sprintf("snapshot on %s", branch)
+	if headSHA != "" {
+		head, err := r.ReadCommit(headSHA)
+		if err != nil {
+			return "", err
+		}
+		message += fmt.Sprintf(": %s %s", headSHA[:7], strings.SplitN(head.Message, "\n", 2)[0])
+	}
+	sha, err := r.commitTreeWithParents(tree, parents, message)
+	if err != nil {
+		return "", err
+	}
+	if err := r.writeRef(ref, sha, message); err != nil {
+		return "", err
+	}
+	return sha, nil
+}
+
+// workingTreeSHA stores the working tree as a tree object. Tracked files
+// whose stat data still matches the index reuse their staged blobs, so only
+// changed and untracked files are hashed.
+func (r *Repository) workingTreeSHA() (string, error) {
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return "", err
+	}
+	indexTime := r.indexModTime()
+	working := make(map[string]IndexEntry, len(staged))
+	for path, stagedEntry := range staged {
+		entry, ok, err := r.readWorkingEntryCached(path, stagedEntry, indexTime)
+		if err != nil {
+			return "", err
+		}
+		if entry.SHA != stagedEntry.SHA {
+			// A changed file was only hashed, not stored
+			entry, ok, err = r.readWorkingEntry(path, true)
+			if err != nil {
+				return "", err
+			}
+		}
+		if ok {
+			entry.Path = path
+			working[path] = entry
+		}
+	}
+
+	untracked, err := r.UntrackedFiles(false)
+	if err != nil {
+		return "", err
+	}
+	for _, path := range untracked {
+		entry, ok, err := r.readWorkingEntry(path, true)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			working[path] = entry
+		}
+	}
+	return r.buildTree(sortedEntries(working))
+}
+
+// Snapshots returns the snapshots of branch, newest first, or those of the
+// current branch when it is "". A detached HEAD's are under "detached".
+func (r *Repository) Snapshots(branch string) ([]Snapshot, error) {
+	ref := SnapshotRefPrefix + branch
+	if branch == "" {
+		var err error
+		if ref, err = r.snapshotRef(); err != nil {
+			return nil, err
+		}
+	}
+	sha, err := r.ReadRef(ref)
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimPrefix(ref, "refs/")
+
+	var snapshots []Snapshot
+	for sha != "" {
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, Snapshot{
+			Name:    fmt.Sprintf("%s~%d", name, len(snapshots)),
+			SHA:     sha,
+			Time:    commit.Committed,
+			Message: commit.Message,
+		})
+		sha = commit.ParentSHA
+	}
+	return snapshots, nil
+}
+
+// SnapshotBranches lists the branches that have snapshots, sorted by name
+func (r *Repository) SnapshotBranches() ([]string, error) {
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	var branches []string
+	for _, ref := range refs {
+		if branch, ok := strings.CutPrefix(ref.Name, SnapshotRefPrefix); ok {
+			branches = append(branches, branch)
+		}
+	}
+	return branches, nil
+}
+
+// RestoreSnapshot writes the files a snapshot recorded at or below paths,
+// all of them when there are none, back into the working tree. The working
+// tree is snapshotted first, so a restore can itself be undone; files the
+// snapshot does not have are left alone, as are the index and branches. It
+// returns how many files were written and the snapshot taken beforehand,
+// "" when the working tree matched the latest one.
+func (r *Repository) RestoreSnapshot(rev string, paths ...string) (int, string, error) {
+	sha, err := r.ResolveCommit(rev)
+	if err != nil {
+		return 0, "", err
+	}
+	commit, err := r.ReadCommit(sha)
+	if err != nil {
+		return 0, "", err
+	}
+	entries, err := r.flattenTree(commit.TreeSHA)
+	if err != nil {
+		return 0, "", err
+	}
+	var restore []IndexEntry
+	for _, entry := range sortedEntries(entries) {
+		if underPaths(entry.Path, paths) {
+			restore = append(restore, entry)
+		}
+	}
+	if len(restore) == 0 {
+		if len(paths) == 0 {
+			return 0, "", errors.New("snapshot has no files")
+		}
+		return 0, "", fmt.Errorf("pathspec '%s' did not match any files in the snapshot", strings.Join(paths, " "))
+	}
+
+	saved, err := r.SaveSnapshot()
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to snapshot the working tree before restoring: %w", err)
+	}
+	for _, entry := range restore {
+		if err := r.writeWorkingFile(entry); err != nil {
+			return 0, saved, err
+		}
+	}
+	return len(restore), saved, nil
+}
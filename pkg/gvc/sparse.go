@@ -0,0 +1,202 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// SparseCheckoutFile lists the paths a sparse checkout materializes, one per
+// line as "/<path>", a pattern Git reads the same way
+const SparseCheckoutFile = "info/sparse-checkout"
+
+// SparseCheckoutPaths returns the paths listed in SparseCheckoutFile
+func (r *Repository) SparseCheckoutPaths() ([]string, error) {
+	data, err := r.readGitFile(r.gitPath(SparseCheckoutFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", SparseCheckoutFile, err)
+	}
+	var paths []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		paths = append(paths, strings.Trim(line, "/"))
+	}
+	return paths, nil
+}
+
+// sparseCheckout returns whether a path belongs in the working tree of a
+// sparse checkout, or nil when core.sparseCheckout is off and every path does
+func (r *Repository) sparseCheckout() (func(path string) bool, error) {
+	enabled, err := r.getConfigBool("core.sparseCheckout", false)
+	if err != nil || !enabled {
+		return nil, err
+	}
+	paths, err := r.SparseCheckoutPaths()
+	if err != nil {
+		return nil, err
+	}
+	return func(path string) bool {
+		for _, spec := range paths {
+			if path == spec || strings.HasPrefix(path, spec+"/") {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// SetSparseCheckout limits the working tree to the files at or below paths
+// and turns on core.sparseCheckout. Files outside them are removed from the
+// working tree but stay in the index, marked skip-worktree, so commits keep
+// them and status does not report them as deleted. Files that move inside
+// are checked out. A file outside with local changes is left in place.
+func (r *Repository) SetSparseCheckout(paths []string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return errors.New("no paths given")
+	}
+	lines := make([]string, 0, len(paths))
+	for _, spec := range paths {
+		spec = strings.Trim(normalizePath(spec), "/")
+		if spec == "" || spec == "." {
+			return errors.New("to check out the whole tree, disable sparse checkout instead")
+		}
+		if path.Clean(spec) != spec || spec == ".." || strings.HasPrefix(spec, "../") {
+			return fmt.Errorf("invalid sparse checkout path '%s'", spec)
+		}
+		lines = append(lines, "/"+spec)
+	}
+	if err := r.writeGitFile(r.gitPath(SparseCheckoutFile), []byte(strings.Join(lines, "\n")+"\n")); err != nil {
+		return fmt.Errorf("failed to write %s: %w", SparseCheckoutFile, err)
+	}
+	if err := r.SetConfig("core.sparseCheckout", "true"); err != nil {
+		return err
+	}
+	return r.ReapplySparseCheckout()
+}
+
+// DisableSparseCheckout turns off core.sparseCheckout and checks out every
+// file again. SparseCheckoutFile is kept for a later SetSparseCheckout.
+func (r *Repository) DisableSparseCheckout() error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if err := r.SetConfig("core.sparseCheckout", "false"); err != nil {
+		return err
+	}
+	return r.ReapplySparseCheckout()
+}
+
+// ReapplySparseCheckout brings the working tree in line with the sparse
+// checkout paths: index entries outside them are removed from the working
+// tree and marked skip-worktree, and marked entries inside them, or every
+// entry when sparse checkout is off, are checked out again
+func (r *Repository) ReapplySparseCheckout() error {
+	if op := r.OperationInProgress(); op != "" {
+		return fmt.Errorf("a %s is in progress; use --continue or --abort first", op)
+	}
+	inside, err := r.sparseCheckout()
+	if err != nil {
+		return err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(staged))
+	for path := range staged {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	indexTime := r.indexModTime()
+	var kept []string
+	for _, path := range paths {
+		entry := staged[path]
+		skipped := entry.Flags&IndexSkipWorktree != 0
+		if entry.Mode == GitlinkMode {
+			continue
+		}
+		if inside == nil || inside(path) {
+			if !skipped {
+				continue
+			}
+			// A file created over a skipped entry is the user's to keep
+			if _, err := os.Lstat(r.worktreePath(path)); os.IsNotExist(err) {
+				if err := r.writeWorkingFile(entry); err != nil {
+					return err
+				}
+			}
+			entry.Flags &^= IndexSkipWorktree
+			staged[path] = entry
+			continue
+		}
+		if skipped {
+			continue
+		}
+		working, onDisk, err := r.readWorkingEntryCached(path, entry, indexTime)
+		if err != nil {
+			return err
+		}
+		if onDisk && !sameEntry(working, entry, true, true) {
+			kept = append(kept, path)
+			continue
+		}
+		if err := r.removeWorkingFile(path); err != nil {
+			return err
+		}
+		entry.Flags |= IndexSkipWorktree
+		staged[path] = entry
+	}
+	if err := r.writeStagedEntries(staged); err != nil {
+		return err
+	}
+	if len(kept) > 0 {
+		fmt.Fprintf(r.Out, "warning: leaving files with local changes outside the sparse checkout:\n\t%s\n",
+			strings.Join(kept, "\n\t"))
+	}
+	return nil
+}
+
+// markSparseEntries sets the skip-worktree flag of entries outside the
+// sparse checkout that are not in the working tree, as after a checkout
+// that left them out, and clears it for entries inside
+func (r *Repository) markSparseEntries(entries []IndexEntry) error {
+	inside, err := r.sparseCheckout()
+	if err != nil || inside == nil {
+		return err
+	}
+	for i, entry := range entries {
+		if inside(entry.Path) || entry.Mode == GitlinkMode {
+			entries[i].Flags &^= IndexSkipWorktree
+			continue
+		}
+		if _, err := os.Lstat(r.worktreePath(entry.Path)); os.IsNotExist(err) {
+			entries[i].Flags |= IndexSkipWorktree
+		} else {
+			entries[i].Flags &^= IndexSkipWorktree
+		}
+	}
+	return nil
+}
+
+// leftOut reports whether a checkout leaves path out of the working tree:
+// inside, from sparseCheckout, excludes it and no file is there already
+func (r *Repository) leftOut(inside func(path string) bool, path string) bool {
+	if inside == nil || inside(path) {
+		return false
+	}
+	_, err := os.Lstat(r.worktreePath(path))
+	return os.IsNotExist(err)
+}
@@ -0,0 +1,299 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// splitFile names the commit being split, in the rebase state directory
+const splitFile = "split-sha"
+
+// WholeChange is a change split can only take whole: a deleted, binary or
+// symlinked file, a mode change, or a file the split commit added
+type WholeChange struct {
+	Path string
+	Kind string // "added", "deleted" or "modified"
+}
+
+// StartSplit begins splitting a commit on the current branch into several.
+// The split runs as a rebase: HEAD moves to the commit's parent with the
+// index there, the working tree holds the commit's changes, and the commits
+// after it wait in the todo list until FinishSplit replays them. AbortRebase
+// undoes the whole split.
+func (r *Repository) StartSplit(rev string) (*object.Commit, error) {
+	if op := r.OperationInProgress(); op != "" {
+		return nil, fmt.Errorf("a %s is already in progress; use --continue or --abort", op)
+	}
+	branchRef, err := r.HeadRef()
+	if err != nil {
+		return nil, err
+	}
+	if branchRef == "" {
+		return nil, errors.New("cannot split on a detached HEAD; switch to a branch first")
+	}
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return nil, err
+	}
+	if headSHA == "" {
+		return nil, errors.New("cannot split on an empty branch")
+	}
+	commitSHA, err := r.ResolveCommit(rev)
+	if err != nil {
+		return nil, err
+	}
+	commit, err := r.ReadCommit(commitSHA)
+	if err != nil {
+		return nil, err
+	}
+	if len(commit.Parents) != 1 {
+		return nil, fmt.Errorf("cannot split %s: only a commit with one parent can be split", commitSHA[:7])
+	}
+
+	// The commits after it are replayed, so they must lead from HEAD
+	// straight down to it
+	var todo []string
+	for sha := headSHA; sha != commitSHA; {
+		parents, err := r.commitParents(sha)
+		if err != nil {
+			return nil, err
+		}
+		if len(parents) != 1 {
+			return nil, fmt.Errorf("cannot split %s: it is not in the linear history of the current branch", commitSHA[:7])
+		}
+		todo = append(todo, RebasePick+" "+sha)
+		sha = parents[0]
+	}
+	slices.Reverse(todo)
+
+	head, err := r.headEntries()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.checkCleanState(head); err != nil {
+		return nil, err
+	}
+	parent, err := r.ReadCommit(commit.Parents[0])
+	if err != nil {
+		return nil, err
+	}
+	parentEntries, err := r.flattenTree(parent.TreeSHA)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &rebaseState{HeadName: branchRef, OrigHead: headSHA, Onto: parent.SHA, Todo: todo}
+	if err := r.writeRebaseState(state); err != nil {
+		return nil, err
+	}
+	if err := writeFileLocked(r.rebaseFile(splitFile), []byte(commitSHA+"\n")); err != nil {
+		return nil, fmt.Errorf("failed to write rebase state: %w", err)
+	}
+	if err := r.detachHead(commitSHA, "split (start): checkout "+commitSHA); err != nil {
+		return nil, err
+	}
+	// Like a mixed reset to the parent: the commit's changes stay in the
+	// working tree, none of them staged
+	if err := r.writeGitFile(r.gitPath(HeadFile), []byte(parent.SHA+"\n")); err != nil {
+		return nil, fmt.Errorf("failed to write HEAD: %w", err)
+	}
+	if err := r.appendReflog("HEAD", commitSHA, parent.SHA, "split: moving to "+parent.SHA); err != nil {
+		return nil, err
+	}
+	if err := r.writeStagedEntries(parentEntries); err != nil {
+		return nil, err
+	}
+	return commit, nil
+}
+
+// SplittingCommit returns the commit a split started by StartSplit is
+// taking apart, or nil when no split is in progress
+func (r *Repository) SplittingCommit() (*object.Commit, error) {
+	data, err := os.ReadFile(r.rebaseFile(splitFile))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rebase state: %w", err)
+	}
+	return r.ReadCommit(strings.TrimSpace(string(data)))
+}
+
+// splitAdded returns the files the commit being split added that are not
+// staged yet and still in the working tree, sorted
+func (r *Repository) splitAdded(commit *object.Commit) ([]string, error) {
+	entries, err := r.flattenTree(commit.TreeSHA)
+	if err != nil {
+		return nil, err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return nil, err
+	}
+	var added []string
+	for path := range entries {
+		if _, ok := staged[path]; ok {
+			continue
+		}
+		if _, err := os.Lstat(r.worktreePath(path)); err == nil {
+			added = append(added, path)
+		}
+	}
+	sort.Strings(added)
+	return added, nil
+}
+
+// SplitChanges returns what is left to split: the unstaged hunks of tracked
+// files, as UnstagedPatches finds them, and the changes that can only be
+// taken whole, which Add stages
+func (r *Repository) SplitChanges() ([]FilePatch, []WholeChange, error) {
+	patches, err := r.UnstagedPatches()
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := r.DiffWorkingTree("")
+	if err != nil {
+		return nil, nil, err
+	}
+	byHunk := make(map[string]bool, len(patches))
+	for _, patch := range patches {
+		byHunk[patch.Path] = true
+	}
+
+	var whole []WholeChange
+	for _, change := range changes {
+		switch {
+		case byHunk[change.Path]:
+		case change.New == nil:
+			whole = append(whole, WholeChange{Path: change.Path, Kind: "deleted"})
+		default:
+			whole = append(whole, WholeChange{Path: change.Path, Kind: "modified"})
+		}
+	}
+	commit, err := r.SplittingCommit()
+	if err != nil {
+		return nil, nil, err
+	}
+	if commit != nil {
+		added, err := r.splitAdded(commit)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, path := range added {
+			whole = append(whole, WholeChange{Path: path, Kind: "added"})
+		}
+		sort.Slice(whole, func(i, j int) bool { return whole[i].Path < whole[j].Path })
+	}
+	return patches, whole, nil
+}
+
+// HasStagedChanges reports whether the index differs from HEAD
+func (r *Repository) HasStagedChanges() (bool, error) {
+	head, err := r.headEntries()
+	if err != nil {
+		return false, err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return false, err
+	}
+	if len(staged) != len(head) {
+		return true, nil
+	}
+	for path, entry := range staged {
+		headEntry, ok := head[path]
+		if !sameEntry(entry, headEntry, true, ok) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CommitSplit commits the staged changes as one part of a split. Parts of a
+// split commit keep its author and date; otherwise this is a plain commit.
+func (r *Repository) CommitSplit(message string) (string, error) {
+	commit, err := r.SplittingCommit()
+	if err != nil {
+		return "", err
+	}
+	if commit == nil {
+		commitSHA, err := r.CommitWithOptions(message, CommitOptions{})
+		if err != nil {
+			return "", err
+		}
+		// The commit-msg hook may have rewritten the message
+		written, err := r.ReadCommit(commitSHA)
+		if err != nil {
+			return "", err
+		}
+		summary, err := r.CommitSummary(commitSHA, written.Message)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintln(r.Out, summary)
+		return commitSHA, nil
+	}
+
+	if strings.TrimSpace(message) == "" {
+		message = commit.Message
+	}
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return "", err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return "", err
+	}
+	return r.commitStaged(staged, []string{headSHA}, commit.Author, commit.Timestamp, message,
+		"split: "+strings.SplitN(message, "\n", 2)[0])
+}
+
+// commitSplitRest commits whatever is left of the commit being split under
+// its own message, then forgets the split, leaving the rebase to go on
+func (r *Repository) commitSplitRest(commit *object.Commit) error {
+	added, err := r.splitAdded(commit)
+	if err != nil {
+		return err
+	}
+	if err := r.AddWithOptions(AddOptions{Update: true}); err != nil {
+		return err
+	}
+	if len(added) > 0 {
+		if err := r.Add(added...); err != nil {
+			return err
+		}
+	}
+	staged, err := r.HasStagedChanges()
+	if err != nil {
+		return err
+	}
+	if staged {
+		if _, err := r.CommitSplit(commit.Message); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(r.rebaseFile(splitFile)); err != nil {
+		return fmt.Errorf("failed to remove rebase state: %w", err)
+	}
+	return nil
+}
+
+// FinishSplit commits what is left of the commit being split under its own
+// message and replays the commits that came after it
+func (r *Repository) FinishSplit() error {
+	commit, err := r.SplittingCommit()
+	if err != nil {
+		return err
+	}
+	if commit == nil {
+		return errors.New("no split in progress")
+	}
+	return r.ContinueRebase()
+}
@@ -0,0 +1,373 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	StashRef     = "refs/stash"
+	StashRefFile = "refs/stash"
+)
+
+// ReadStashEntries returns the stash stack, newest first
+func (r *Repository) ReadStashEntries() ([]ReflogEntry, error) {
+	log, err := r.ReadReflog(StashRef)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ReflogEntry, len(log))
+	for i, entry := range log {
+		entries[len(log)-1-i] = entry
+	}
+	return entries, nil
+}
+
+// WriteStashEntries rewrites the stash log and ref from a newest-first stack
+func (r *Repository) WriteStashEntries(entries []ReflogEntry) error {
+	if len(entries) == 0 {
+		if err := r.deleteRef(StashRef); err != nil {
+			return fmt.Errorf("failed to remove stash ref: %w", err)
+		}
+		return nil
+	}
+
+	log := make([]ReflogEntry, 0, len(entries))
+	oldSHA := r.Format.ZeroSHA()
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		entry.OldSHA = oldSHA
+		log = append(log, entry)
+		oldSHA = entry.NewSHA
+	}
+
+	if err := r.writeReflog(StashRef, log); err != nil {
+		return err
+	}
+	if err := writeFileLocked(r.gitPath(StashRefFile), []byte(entries[0].NewSHA+"\n")); err != nil {
+		return fmt.Errorf("failed to write stash ref: %w", err)
+	}
+	return nil
+}
+
+// ParseStashIndex resolves a stash@{n} argument to a position in the stack
+func ParseStashIndex(arg string, entries []ReflogEntry) (int, error) {
+	n := 0
+	if arg != "" {
+		inner := strings.TrimSuffix(strings.TrimPrefix(arg, "stash@{"), "}")
+		parsed, err := strconv.Atoi(inner)
+		if err != nil || parsed < 0 {
+			return 0, fmt.Errorf("invalid stash reference: %s", arg)
+		}
+		n = parsed
+	}
+	if n >= len(entries) {
+		if len(entries) == 0 {
+			return 0, errors.New("no stash entries found")
+		}
+		return 0, fmt.Errorf("stash@{%d} does not exist", n)
+	}
+	return n, nil
+}
+
+// StashChanges lists the paths a stash commit changed relative to the
+// commit it was made on, as its working tree recorded them
+func (r *Repository) StashChanges(stashSHA string) ([]FileChange, error) {
+	stashCommit, err := r.ReadCommit(stashSHA)
+	if err != nil {
+		return nil, err
+	}
+	if len(stashCommit.Parents) < 2 {
+		return nil, fmt.Errorf("%s is not a stash commit", stashSHA[:7])
+	}
+	baseCommit, err := r.ReadCommit(stashCommit.Parents[0])
+	if err != nil {
+		return nil, err
+	}
+	return r.DiffTrees(baseCommit.TreeSHA, stashCommit.TreeSHA)
+}
+
+// StashMode selects which changes a stash push records and resets
+type StashMode int
+
+const (
+	// StashAll records the index and working tree and resets both to HEAD
+	StashAll StashMode = iota
+	// StashKeepIndex records everything but leaves staged changes in place
+	StashKeepIndex
+	// StashStaged records and resets only the staged changes
+	StashStaged
+)
+
+// StashPush records the index and working tree as stash commits and resets them to HEAD
+func (r *Repository) StashPush(message string, mode StashMode) error {
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	if headSHA == "" {
+		return errors.New("you do not have the initial commit yet")
+	}
+
+	headCommit, err := r.ReadCommit(headSHA)
+	if err != nil {
+		return err
+	}
+	head, err := r.flattenTree(headCommit.TreeSHA)
+	if err != nil {
+		return err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+
+	// Snapshot every tracked path as it currently exists on disk
+	working := make(map[string]IndexEntry)
+	onDisk := make(map[string]IndexEntry)
+	stagedChanges := false
+	workingChanges := false
+	var partiallyStaged []string
+	for path, stagedEntry := range staged {
+		headEntry, inHead := head[path]
+		isStaged := !sameEntry(stagedEntry, headEntry, true, inHead)
+		if isStaged {
+			stagedChanges = true
+		}
+
+		entry, ok, err := r.readTrackedEntry(stagedEntry, mode != StashStaged)
+		if err != nil {
+			return err
+		}
+		if ok {
+			entry.Path = path
+			onDisk[path] = entry
+		}
+		if !sameEntry(entry, stagedEntry, ok, true) {
+			workingChanges = true
+			if isStaged {
+				partiallyStaged = append(partiallyStaged, path)
+			}
+		}
+	}
+
+	if mode == StashStaged {
+		// Only the index is recorded, so the stash's working tree is the index
+		for path, entry := range staged {
+			working[path] = entry
+		}
+		if len(partiallyStaged) > 0 {
+			sort.Strings(partiallyStaged)
+			return fmt.Errorf("cannot stash staged changes only; these files also have unstaged changes:\n\t%s",
+				strings.Join(partiallyStaged, "\n\t"))
+		}
+	} else {
+		working = onDisk
+	}
+
+	if !stagedChanges && (mode == StashStaged || !workingChanges) {
+		fmt.Fprintln(r.Out, "No local changes to save")
+		return nil
+	}
+
+	branch, err := r.currentBranchName()
+	if err != nil {
+		return err
+	}
+	subject := strings.SplitN(headCommit.Message, "\n", 2)[0]
+	description := fmt.Sprintf("%s: %s %s", branch, headSHA[:7], subject)
+	if message == "" {
+		message = "WIP on " + description
+	} else {
+		message = fmt.Sprintf("On %s: %s", branch, message)
+	}
+
+	indexTree, err := r.buildTree(sortedEntries(staged))
+	if err != nil {
+		return err
+	}
+	indexCommit, err := r.CommitTree(indexTree, headSHA, "index on "+description)
+	if err != nil {
+		return err
+	}
+	workingTree, err := r.buildTree(sortedEntries(working))
+	if err != nil {
+		return err
+	}
+	stashCommit, err := r.commitTreeWithParents(workingTree, []string{headSHA, indexCommit}, message)
+	if err != nil {
+		return err
+	}
+
+	entries, err := r.ReadStashEntries()
+	if err != nil {
+		return err
+	}
+	entry := ReflogEntry{NewSHA: stashCommit, Ident: r.authorIdent(), Timestamp: time.Now(), Message: message}
+	entries = append([]ReflogEntry{entry}, entries...)
+	if err := r.WriteStashEntries(entries); err != nil {
+		return err
+	}
+
+	// Put the recorded paths back to the state the mode leaves behind
+	target := head
+	if mode == StashKeepIndex {
+		target = staged
+	}
+	for path := range staged {
+		if mode == StashStaged && sameEntry(staged[path], head[path], true, hasEntry(head, path)) {
+			continue
+		}
+		current, exists := onDisk[path]
+		targetEntry, inTarget := target[path]
+		if sameEntry(current, targetEntry, exists, inTarget) {
+			continue
+		}
+		if !inTarget {
+			if err := r.removeWorkingFile(path); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := r.writeWorkingFile(targetEntry); err != nil {
+			return err
+		}
+	}
+	if mode != StashKeepIndex {
+		if err := r.writeStagedEntries(head); err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(r.Out, "Saved working directory and index state %s\n", message)
+	return nil
+}
+
+// hasEntry reports whether path is present in entries
+func hasEntry(entries map[string]IndexEntry, path string) bool {
+	_, ok := entries[path]
+	return ok
+}
+
+// StashApply restores a stash commit onto the working tree and index. It refuses
+// to touch any path that has changed since the stash was recorded.
+func (r *Repository) StashApply(stashSHA string) error {
+	stashCommit, err := r.ReadCommit(stashSHA)
+	if err != nil {
+		return err
+	}
+	parents, err := r.commitParents(stashSHA)
+	if err != nil {
+		return err
+	}
+	if len(parents) < 2 {
+		return fmt.Errorf("%s is not a stash commit", stashSHA[:7])
+	}
+
+	baseCommit, err := r.ReadCommit(parents[0])
+	if err != nil {
+		return err
+	}
+	indexCommit, err := r.ReadCommit(parents[1])
+	if err != nil {
+		return err
+	}
+	base, err := r.flattenTree(baseCommit.TreeSHA)
+	if err != nil {
+		return err
+	}
+	stashIndex, err := r.flattenTree(indexCommit.TreeSHA)
+	if err != nil {
+		return err
+	}
+	stashWorking, err := r.flattenTree(stashCommit.TreeSHA)
+	if err != nil {
+		return err
+	}
+
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+
+	// Collect the paths the stash changed relative to its base
+	var paths []string
+	seen := make(map[string]bool)
+	for _, entries := range []map[string]IndexEntry{base, stashIndex, stashWorking} {
+		for path := range entries {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			baseEntry, inBase := base[path]
+			indexEntry, inIndex := stashIndex[path]
+			workingEntry, inWorking := stashWorking[path]
+			if !sameEntry(indexEntry, baseEntry, inIndex, inBase) || !sameEntry(workingEntry, baseEntry, inWorking, inBase) {
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	// A path is safe to overwrite when it still matches the stash base or
+	// already holds the stashed content (e.g. after push --keep-index)
+	var conflicts []string
+	for _, path := range paths {
+		baseEntry, inBase := base[path]
+		indexEntry, inIndex := stashIndex[path]
+		workingEntry, inWorking := stashWorking[path]
+		stagedEntry, inStaged := staged[path]
+		if !sameEntry(stagedEntry, baseEntry, inStaged, inBase) && !sameEntry(stagedEntry, indexEntry, inStaged, inIndex) {
+			conflicts = append(conflicts, path)
+			continue
+		}
+		diskEntry, onDisk, err := r.readWorkingEntry(path, false)
+		if inStaged {
+			diskEntry, onDisk, err = r.readTrackedEntry(stagedEntry, false)
+		}
+		if err != nil {
+			return err
+		}
+		if !sameEntry(diskEntry, stagedEntry, onDisk, inStaged) && !sameEntry(diskEntry, workingEntry, onDisk, inWorking) {
+			conflicts = append(conflicts, path)
+		}
+	}
+	if len(conflicts) > 0 {
+		return conflictError(fmt.Errorf("your local changes to the following files would be overwritten:\n\t%s\nplease commit or stash them first",
+			strings.Join(conflicts, "\n\t")))
+	}
+
+	for _, path := range paths {
+		if entry, ok := stashWorking[path]; ok {
+			if err := r.writeWorkingFile(entry); err != nil {
+				return err
+			}
+		} else if err := r.removeWorkingFile(path); err != nil {
+			return err
+		}
+
+		if entry, ok := stashIndex[path]; ok {
+			staged[path] = entry
+		} else {
+			delete(staged, path)
+		}
+	}
+
+	return r.writeStagedEntries(staged)
+}
+
+// sortedEntries returns the values of an entry map ordered by path
+func sortedEntries(entries map[string]IndexEntry) []IndexEntry {
+	list := make([]IndexEntry, 0, len(entries))
+	for _, entry := range entries {
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Path < list[j].Path
+	})
+	return list
+}
@@ -0,0 +1,271 @@
+package gvc
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// AuthorStats counts the commits of one author
+type AuthorStats struct {
+	Name    string
+	Commits int
+}
+
+// MonthStats is one month of history: the commits authored in it and the
+// blobs those commits added to the object store
+type MonthStats struct {
+	Month     string // YYYY-MM
+	Commits   int
+	NewBlobs  int
+	BlobBytes int64
+}
+
+// FileTypeStats totals the files at HEAD with one extension
+type FileTypeStats struct {
+	Extension string // lowercased with its dot, or "" for none
+	Files     int
+	Bytes     int64
+}
+
+// BlobStats is a blob and the first path and commit it appeared in
+type BlobStats struct {
+	SHA    string
+	Path   string
+	Size   int64
+	Commit string
+}
+
+// StoreStats describes the object store on disk
+type StoreStats struct {
+	LooseObjects  int
+	LooseBytes    int64
+	Packs         int
+	PackedObjects int
+	PackBytes     int64
+}
+
+// RepoStats summarizes the history reachable from HEAD and every ref
+type RepoStats struct {
+	Commits      int
+	Authors      []AuthorStats   // most commits first
+	Months       []MonthStats    // oldest first
+	FileTypes    []FileTypeStats // most bytes first
+	LargestBlobs []BlobStats     // largest first
+	Store        StoreStats
+}
+
+// Stats summarizes the repository: commits per author and month, the file
+// types at HEAD, the largest blobs in history and the size of the object
+// store. Commits are found through the commit-graph, and blob sizes are read
+// from object headers rather than by inflating every blob.
+func (r *Repository) Stats(topBlobs int) (*RepoStats, error) {
+	stats := &RepoStats{}
+	authors := make(map[string]int)
+	months := make(map[string]*MonthStats)
+	var blobs []BlobStats
+	err := r.historyBlobs(func(commit *object.Commit, added []BlobStats) {
+		stats.Commits++
+		name, _, _ := strings.Cut(commit.Author, " <")
+		authors[name]++
+		key := commit.Timestamp.Format("2006-01")
+		month := months[key]
+		if month == nil {
+			month = &MonthStats{Month: key}
+			months[key] = month
+		}
+		month.Commits++
+		for _, blob := range added {
+			month.NewBlobs++
+			month.BlobBytes += blob.Size
+		}
+		blobs = append(blobs, added...)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, month := range months {
+		stats.Months = append(stats.Months, *month)
+	}
+	sort.Slice(stats.Months, func(i, j int) bool { return stats.Months[i].Month < stats.Months[j].Month })
+	for name, count := range authors {
+		stats.Authors = append(stats.Authors, AuthorStats{Name: name, Commits: count})
+	}
+	sort.Slice(stats.Authors, func(i, j int) bool {
+		if stats.Authors[i].Commits != stats.Authors[j].Commits {
+			return stats.Authors[i].Commits > stats.Authors[j].Commits
+		}
+		return stats.Authors[i].Name < stats.Authors[j].Name
+	})
+	sort.SliceStable(blobs, func(i, j int) bool { return blobs[i].Size > blobs[j].Size })
+	stats.LargestBlobs = blobs[:min(topBlobs, len(blobs))]
+
+	if stats.FileTypes, err = r.headFileTypes(); err != nil {
+		return nil, err
+	}
+	if stats.Store, err = r.storeStats(); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// historyBlobs walks the commits reachable from HEAD and every ref oldest
+// first, by author date, calling visit with each commit and the blobs it was
+// the first to contain, so each blob is credited to where it appeared
+func (r *Repository) historyBlobs(visit func(commit *object.Commit, added []BlobStats)) error {
+	tips, err := r.allRefTips()
+	if err != nil {
+		return err
+	}
+	reachable, err := r.reachableCommits(tips)
+	if err != nil {
+		return err
+	}
+
+	var commits []*object.Commit
+	for sha := range reachable {
+		commit, err := r.ReadCommit(sha)
+		if err != nil {
+			return err
+		}
+		commits = append(commits, commit)
+	}
+	sort.Slice(commits, func(i, j int) bool {
+		if a, b := commits[i].Timestamp, commits[j].Timestamp; !a.Equal(b) {
+			return a.Before(b)
+		}
+		return commits[i].SHA < commits[j].SHA
+	})
+
+	seen := make(map[string]bool)
+	for _, commit := range commits {
+		added, err := r.newBlobs(commit.SHA, commit.TreeSHA, "", seen)
+		if err != nil {
+			return err
+		}
+		visit(commit, added)
+	}
+	return nil
+}
+
+// newBlobs lists the blobs of a commit's tree not yet in seen, marking them
+// and the trees walked as seen. Trees seen before hold no new blobs and are
+// skipped.
+func (r *Repository) newBlobs(commitSHA, treeSHA, prefix string, seen map[string]bool) ([]BlobStats, error) {
+	if seen[treeSHA] {
+		return nil, nil
+	}
+	seen[treeSHA] = true
+	_, content, err := r.ReadObject(treeSHA)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := r.Format.ParseTree(content)
+	if err != nil {
+		return nil, err
+	}
+
+	var blobs []BlobStats
+	for _, entry := range entries {
+		switch {
+		case entry.Type == object.TreeObject:
+			added, err := r.newBlobs(commitSHA, entry.SHA, prefix+entry.Name+"/", seen)
+			if err != nil {
+				return nil, err
+			}
+			blobs = append(blobs, added...)
+		case entry.Mode == GitlinkMode || seen[entry.SHA]:
+		default:
+			seen[entry.SHA] = true
+			size, err := r.objectSize(entry.SHA)
+			if err != nil {
+				return nil, err
+			}
+			blobs = append(blobs, BlobStats{SHA: entry.SHA, Path: prefix + entry.Name, Size: size, Commit: commitSHA})
+		}
+	}
+	return blobs, nil
+}
+
+// headFileTypes totals the files at HEAD by extension
+func (r *Repository) headFileTypes() ([]FileTypeStats, error) {
+	entries, err := r.headEntries()
+	if err != nil {
+		return nil, err
+	}
+	byExtension := make(map[string]*FileTypeStats)
+	for filePath, entry := range entries {
+		if entry.Mode == GitlinkMode {
+			continue
+		}
+		size, err := r.objectSize(entry.SHA)
+		if err != nil {
+			return nil, err
+		}
+		ext := strings.ToLower(path.Ext(filePath))
+		if ext == strings.ToLower(path.Base(filePath)) {
+			ext = ""
+		}
+		fileType := byExtension[ext]
+		if fileType == nil {
+			fileType = &FileTypeStats{Extension: ext}
+			byExtension[ext] = fileType
+		}
+		fileType.Files++
+		fileType.Bytes += size
+	}
+
+	var types []FileTypeStats
+	for _, fileType := range byExtension {
+		types = append(types, *fileType)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].Bytes != types[j].Bytes {
+			return types[i].Bytes > types[j].Bytes
+		}
+		return types[i].Extension < types[j].Extension
+	})
+	return types, nil
+}
+
+// storeStats measures loose objects and packs on disk, counting packed
+// objects from the pack indexes. Another object store has none to measure.
+func (r *Repository) storeStats() (StoreStats, error) {
+	var store StoreStats
+	if !r.usesFileStore() {
+		return store, nil
+	}
+	loose, err := r.listLooseObjects()
+	if err != nil {
+		return store, err
+	}
+	for _, sha := range loose {
+		info, err := os.Stat(r.objectPath(sha))
+		if err != nil {
+			return store, err
+		}
+		store.LooseObjects++
+		store.LooseBytes += info.Size()
+	}
+
+	packs, err := r.loadPacks()
+	if err != nil {
+		return store, err
+	}
+	for _, pack := range packs {
+		store.Packs++
+		store.PackedObjects += len(pack.shas)
+		for _, file := range []string{pack.path, strings.TrimSuffix(pack.path, ".pack") + ".idx"} {
+			info, err := os.Stat(file)
+			if err != nil {
+				return store, err
+			}
+			store.PackBytes += info.Size()
+		}
+	}
+	return store, nil
+}
@@ -0,0 +1,134 @@
+package gvc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"sync"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// ErrObjectNotFound is returned by an ObjectStore asked for an object it
+// does not hold. It matches fs.ErrNotExist, like a missing loose object.
+var ErrObjectNotFound = fmt.Errorf("object not found: %w", fs.ErrNotExist)
+
+// errNeedsFileStore is returned by maintenance that works on the files of the
+// default object store, such as packing and repair
+var errNeedsFileStore = errors.New("only possible with the filesystem object store")
+
+// ObjectStore keeps a repository's objects, named by the SHA of their type
+// and content. The repository hashes, verifies and parses objects; a store
+// only has to keep them. Implementations must be safe for concurrent use.
+type ObjectStore interface {
+	// Get returns an object's type and content, or ErrObjectNotFound
+	Get(sha string) (object.Type, []byte, error)
+	// Put stores an object under sha. Storing an object the store already
+	// holds is not an error.
+	Put(sha string, objectType object.Type, content []byte) error
+	// Has reports whether the store holds an object
+	Has(sha string) (bool, error)
+	// List returns the SHA of every object held, sorted
+	List() ([]string, error)
+}
+
+// FileStore is the default ObjectStore: loose zlib-compressed files under
+// .gvc/objects, plus the packs gc writes
+type FileStore struct {
+	repo *Repository
+}
+
+// Get reads a loose or packed object
+func (s *FileStore) Get(sha string) (object.Type, []byte, error) {
+	return s.repo.loadRawObject(sha)
+}
+
+// Put writes an object as a loose file
+func (s *FileStore) Put(sha string, objectType object.Type, content []byte) error {
+	written, err := s.repo.writeLooseObject(objectType, int64(len(content)), bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	if written != sha {
+		return fmt.Errorf("object %s hashes to %s", sha, written)
+	}
+	return nil
+}
+
+// Has reports whether an object exists loose or in a pack
+func (s *FileStore) Has(sha string) (bool, error) {
+	return s.repo.objectExists(sha), nil
+}
+
+// List returns every loose and packed object
+func (s *FileStore) List() ([]string, error) {
+	return s.repo.allObjects()
+}
+
+// usesFileStore reports whether objects live in the repository's own
+// .gvc/objects, where gc, repair and hardlinking clones can reach them
+func (r *Repository) usesFileStore() bool {
+	store, ok := r.Objects.(*FileStore)
+	return ok && store.repo == r
+}
+
+// MemoryStore is an ObjectStore that keeps objects in memory, for tests and
+// short-lived repositories
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string]memoryObject
+}
+
+// memoryObject is an object held by a MemoryStore
+type memoryObject struct {
+	objectType object.Type
+	content    []byte
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{objects: make(map[string]memoryObject)}
+}
+
+// Get returns a copy of an object's content
+func (s *MemoryStore) Get(sha string) (object.Type, []byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	obj, ok := s.objects[sha]
+	if !ok {
+		return "", nil, ErrObjectNotFound
+	}
+	return obj.objectType, bytes.Clone(obj.content), nil
+}
+
+// Put keeps a copy of an object
+func (s *MemoryStore) Put(sha string, objectType object.Type, content []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.objects[sha]; !ok {
+		s.objects[sha] = memoryObject{objectType: objectType, content: bytes.Clone(content)}
+	}
+	return nil
+}
+
+// Has reports whether an object is held
+func (s *MemoryStore) Has(sha string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.objects[sha]
+	return ok, nil
+}
+
+// List returns the SHA of every object held, sorted
+func (s *MemoryStore) List() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	shas := make([]string, 0, len(s.objects))
+	for sha := range s.objects {
+		shas = append(shas, sha)
+	}
+	sort.Strings(shas)
+	return shas, nil
+}
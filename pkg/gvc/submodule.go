@@ -0,0 +1,300 @@
+package gvc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ModulesFile lists a repository's submodules, in config format, at the top of its working tree
+const ModulesFile = ".gvcmodules"
+
+// GitlinkMode is the tree mode of a submodule: an entry naming a commit in another repository
+const GitlinkMode = "160000"
+
+// Submodule is a repository checked out inside its superproject's working tree
+type Submodule struct {
+	Name string
+	Path string
+	URL  string // as written in .gvcmodules, possibly relative to the superproject's remote
+}
+
+// SubmoduleUpdateOptions controls UpdateSubmodules
+type SubmoduleUpdateOptions struct {
+	// Init registers submodules that have no submodule.<name>.url yet
+	Init bool
+	// Recursive updates the submodules of each submodule too
+	Recursive bool
+	// NoHardlinks copies object files instead of hardlinking them
+	NoHardlinks bool
+}
+
+// Submodules returns the submodules listed in .gvcmodules, sorted by path
+func (r *Repository) Submodules() ([]Submodule, error) {
+	data, err := os.ReadFile(r.worktreePath(ModulesFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", ModulesFile, err)
+	}
+
+	byName := make(map[string]*Submodule)
+	for _, entry := range parseConfigEntries(string(data)) {
+		rest, ok := strings.CutPrefix(entry[0], "submodule.")
+		if !ok {
+			continue
+		}
+		dot := strings.LastIndex(rest, ".")
+		if dot <= 0 {
+			continue
+		}
+		name := rest[:dot]
+		sub := byName[name]
+		if sub == nil {
+			sub = &Submodule{Name: name}
+			byName[name] = sub
+		}
+		switch rest[dot+1:] {
+		case "path":
+			sub.Path = normalizePath(entry[1])
+		case "url":
+			sub.URL = entry[1]
+		}
+	}
+
+	var submodules []Submodule
+	for _, sub := range byName {
+		if sub.Path == "" || sub.URL == "" {
+			return nil, fmt.Errorf("submodule %s in %s needs both a path and a url", sub.Name, ModulesFile)
+		}
+		submodules = append(submodules, *sub)
+	}
+	sort.Slice(submodules, func(i, j int) bool { return submodules[i].Path < submodules[j].Path })
+	return submodules, nil
+}
+
+// superprojectURL returns the URL relative submodule URLs are resolved
+// against: the remote of the current branch's upstream, then origin, and the
+// superproject itself when it has neither
+func (r *Repository) superprojectURL() (string, error) {
+	remote := "origin"
+	branch, err := r.currentBranchName()
+	if err != nil {
+		return "", err
+	}
+	if branch != "" {
+		if upstream, _, err := r.Upstream(branch); err != nil {
+			return "", err
+		} else if upstream != "" {
+			remote = upstream
+		}
+	}
+	url, ok, err := r.GetConfig("remote." + remote + ".url")
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return r.Root, nil
+	}
+	return url, nil
+}
+
+// resolveRelativeURL resolves a "./" or "../" submodule URL against base the
+// way Git does: base names a directory, and each "../" drops one component
+// of it, including the host part of an scp-like "host:path" URL
+func resolveRelativeURL(base, rel string) string {
+	base = strings.TrimRight(base, "/")
+	for {
+		if rest, ok := strings.CutPrefix(rel, "./"); ok {
+			rel = rest
+		} else if rest, ok := strings.CutPrefix(rel, "../"); ok {
+			rel = rest
+			if i := strings.LastIndexAny(base, "/:"); i >= 0 && !strings.HasSuffix(base[:i+1], "://") {
+				if base[i] == ':' {
+					i++ // keep the colon of "host:"
+				}
+				base = base[:i]
+			} else {
+				base = "."
+			}
+		} else {
+			break
+		}
+	}
+	if strings.HasSuffix(base, ":") {
+		return base + rel
+	}
+	return base + "/" + rel
+}
+
+// resolveSubmoduleURL resolves a relative submodule URL against the superproject's remote
+func (r *Repository) resolveSubmoduleURL(url string) (string, error) {
+	if !strings.HasPrefix(url, "./") && !strings.HasPrefix(url, "../") {
+		return url, nil
+	}
+	base, err := r.superprojectURL()
+	if err != nil {
+		return "", err
+	}
+	return resolveRelativeURL(base, url), nil
+}
+
+// SubmoduleURL returns the URL a submodule is cloned from. A URL set with
+// submodule.<name>.url overrides .gvcmodules; a relative URL is resolved
+// against the superproject's remote, so forks find their own copies of the
+// submodules; and url.<base>.insteadOf rewrites apply last.
+func (r *Repository) SubmoduleURL(sub Submodule) (string, error) {
+	url := sub.URL
+	if configured, ok, err := r.GetConfig("submodule." + sub.Name + ".url"); err != nil {
+		return "", err
+	} else if ok {
+		url = configured
+	}
+	url, err := r.resolveSubmoduleURL(url)
+	if err != nil {
+		return "", err
+	}
+	entries, err := r.ReadConfigEntries()
+	if err != nil {
+		return "", err
+	}
+	url, _ = rewriteURL(entries, url, "insteadof")
+	return url, nil
+}
+
+// InitSubmodules records each submodule's resolved URL as submodule.<name>.url,
+// keeping URLs that are already set, so later updates clone from there
+func (r *Repository) InitSubmodules() error {
+	submodules, err := r.Submodules()
+	if err != nil {
+		return err
+	}
+	for _, sub := range submodules {
+		if _, ok, err := r.GetConfig("submodule." + sub.Name + ".url"); err != nil {
+			return err
+		} else if ok {
+			continue
+		}
+		url, err := r.resolveSubmoduleURL(sub.URL)
+		if err != nil {
+			return err
+		}
+		if err := r.SetConfig("submodule."+sub.Name+".url", url); err != nil {
+			return err
+		}
+		fmt.Fprintf(r.Out, "Submodule '%s' (%s) registered for path '%s'\n", sub.Name, url, sub.Path)
+	}
+	return nil
+}
+
+// UpdateSubmodules clones every initialized submodule that is not checked
+// out yet and moves each one's HEAD to the commit the index records for it
+func (r *Repository) UpdateSubmodules(opts SubmoduleUpdateOptions) error {
+	if opts.Init {
+		if err := r.InitSubmodules(); err != nil {
+			return err
+		}
+	}
+	submodules, err := r.Submodules()
+	if err != nil {
+		return err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range submodules {
+		if _, ok, err := r.GetConfig("submodule." + sub.Name + ".url"); err != nil {
+			return err
+		} else if !ok {
+			continue // not initialized
+		}
+		gitlink, ok := staged[sub.Path]
+		if !ok || gitlink.Mode != GitlinkMode {
+			return fmt.Errorf("submodule %s: no commit recorded at %s", sub.Name, sub.Path)
+		}
+
+		subRepo, err := r.openSubmodule(sub.Path)
+		if err != nil {
+			return err
+		}
+		if subRepo == nil {
+			url, err := r.SubmoduleURL(sub)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(r.Out, "Cloning into '%s'...\n", r.worktreePath(sub.Path))
+			if subRepo, err = Clone(url, r.worktreePath(sub.Path), CloneOptions{NoHardlinks: opts.NoHardlinks, Progress: r.Progress, Verbose: r.Verbose}); err != nil {
+				return fmt.Errorf("failed to clone submodule %s: %w", sub.Name, err)
+			}
+		}
+
+		headSHA, err := subRepo.HeadCommit()
+		if err != nil {
+			return err
+		}
+		if headSHA != gitlink.SHA {
+			if err := subRepo.detachHead(gitlink.SHA, "checkout: moving to "+gitlink.SHA); err != nil {
+				return fmt.Errorf("failed to check out %s in submodule %s: %w", gitlink.SHA[:7], sub.Name, err)
+			}
+			fmt.Fprintf(r.Out, "Submodule path '%s': checked out '%s'\n", sub.Path, gitlink.SHA)
+		}
+		if opts.Recursive {
+			subRepo.Out, subRepo.Progress, subRepo.Verbose = r.Out, r.Progress, r.Verbose
+			if err := subRepo.UpdateSubmodules(opts); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// SubmoduleHead returns the commit a submodule's HEAD is on, or "" when it
+// has not been cloned yet
+func (r *Repository) SubmoduleHead(sub Submodule) (string, error) {
+	subRepo, err := r.openSubmodule(sub.Path)
+	if err != nil || subRepo == nil {
+		return "", err
+	}
+	return subRepo.HeadCommit()
+}
+
+// openSubmodule opens the repository checked out at a submodule path, or
+// returns nil when it has not been cloned yet
+func (r *Repository) openSubmodule(path string) (*Repository, error) {
+	if !isDir(filepath.Join(r.worktreePath(path), GvcDir)) {
+		return nil, nil
+	}
+	return Open(r.worktreePath(path))
+}
+
+// submoduleEntry reads a submodule's working tree state: a gitlink to the
+// commit its HEAD is on. A submodule that has not been cloned yet is
+// reported at the commit the index records, so it does not show as changed.
+// Any other directory holds no entry of its name: a file tracked there is
+// gone from the working tree.
+func (r *Repository) submoduleEntry(path string) (IndexEntry, bool, error) {
+	subRepo, err := r.openSubmodule(path)
+	if err != nil {
+		return IndexEntry{}, false, err
+	}
+	if subRepo == nil {
+		staged, err := r.stagedEntries()
+		if err != nil {
+			return IndexEntry{}, false, err
+		}
+		if entry, ok := staged[path]; ok && entry.Mode == GitlinkMode {
+			return entry, true, nil
+		}
+		return IndexEntry{}, false, nil
+	}
+	headSHA, err := subRepo.HeadCommit()
+	if err != nil {
+		return IndexEntry{}, false, err
+	}
+	return IndexEntry{Path: path, SHA: headSHA, Mode: GitlinkMode}, true, nil
+}
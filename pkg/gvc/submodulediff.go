@@ -0,0 +1,289 @@
+package gvc
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// SubmoduleIgnore is how much of a submodule's state diff and status leave out
+type SubmoduleIgnore int
+
+const (
+	IgnoreSubmodulesNone      SubmoduleIgnore = iota // show new commits and local changes
+	IgnoreSubmodulesUntracked                        // leave out untracked files inside submodules
+	IgnoreSubmodulesDirty                            // leave out local changes, showing only new commits
+	IgnoreSubmodulesAll                              // leave out submodules altogether
+)
+
+// submoduleIgnoreNames are the values --ignore-submodules and
+// diff.ignoreSubmodules take, by level
+var submoduleIgnoreNames = []string{"none", "untracked", "dirty", "all"}
+
+// ParseSubmoduleIgnore parses none, untracked, dirty or all
+func ParseSubmoduleIgnore(value string) (SubmoduleIgnore, error) {
+	for level, name := range submoduleIgnoreNames {
+		if value == name {
+			return SubmoduleIgnore(level), nil
+		}
+	}
+	return 0, fmt.Errorf("bad --ignore-submodules argument: %s", value)
+}
+
+// DiffIgnoreSubmodules returns the level diff.ignoreSubmodules sets, none by default
+func (r *Repository) DiffIgnoreSubmodules() (SubmoduleIgnore, error) {
+	value, err := r.getConfigString("diff.ignoreSubmodules", "none")
+	if err != nil {
+		return 0, err
+	}
+	ignore, err := ParseSubmoduleIgnore(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid diff.ignoreSubmodules: %s", value)
+	}
+	return ignore, nil
+}
+
+// SubmoduleState is how a checked out submodule differs from its staged gitlink
+type SubmoduleState struct {
+	Path             string
+	NewCommits       bool // its HEAD is on another commit
+	ModifiedContent  bool // tracked files in it have changes
+	UntrackedContent bool // it has untracked files
+}
+
+// Describe lists what changed the way status does, e.g. "new commits, modified content"
+func (s SubmoduleState) Describe() string {
+	var parts []string
+	if s.NewCommits {
+		parts = append(parts, "new commits")
+	}
+	if s.ModifiedContent {
+		parts = append(parts, "modified content")
+	}
+	if s.UntrackedContent {
+		parts = append(parts, "untracked content")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// SubmoduleStates reports the staged submodules whose checkout differs from
+// the index, sorted by path, leaving out what ignore hides. Submodules not
+// cloned yet are not reported.
+func (r *Repository) SubmoduleStates(ignore SubmoduleIgnore) ([]SubmoduleState, error) {
+	if ignore == IgnoreSubmodulesAll {
+		return nil, nil
+	}
+	staged, err := r.StagedFiles()
+	if err != nil {
+		return nil, err
+	}
+	var states []SubmoduleState
+	for _, entry := range staged {
+		if entry.Mode != GitlinkMode {
+			continue
+		}
+		working, _, err := r.submoduleEntry(entry.Path)
+		if err != nil {
+			return nil, err
+		}
+		state := SubmoduleState{Path: entry.Path, NewCommits: working.SHA != entry.SHA}
+		if state.ModifiedContent, state.UntrackedContent, err = r.submoduleDirt(entry.Path, ignore); err != nil {
+			return nil, err
+		}
+		if state.Describe() != "" {
+			states = append(states, state)
+		}
+	}
+	return states, nil
+}
+
+// submoduleDirt checks a cloned submodule for changes to its tracked files
+// and for untracked files, as far as ignore asks for them
+func (r *Repository) submoduleDirt(path string, ignore SubmoduleIgnore) (modified, untracked bool, err error) {
+	if ignore >= IgnoreSubmodulesDirty {
+		return false, false, nil
+	}
+	sub, err := r.openSubmodule(path)
+	if err != nil || sub == nil {
+		return false, false, err
+	}
+	head, err := sub.headEntries()
+	if err != nil {
+		return false, false, err
+	}
+	modified = sub.checkCleanState(head) != nil
+	if ignore < IgnoreSubmodulesUntracked {
+		files, err := sub.UntrackedFiles(false)
+		if err != nil {
+			return false, false, err
+		}
+		untracked = len(files) > 0
+	}
+	return modified, untracked, nil
+}
+
+// IgnoreSubmodules applies ignore to a list of changes. With all, changes to
+// submodules are dropped. For a diff against the working tree, workingTree
+// flags submodules that have local changes, adding those whose commit did
+// not change.
+func (r *Repository) IgnoreSubmodules(changes []FileChange, ignore SubmoduleIgnore, workingTree bool) ([]FileChange, error) {
+	if ignore == IgnoreSubmodulesAll {
+		var kept []FileChange
+		for _, change := range changes {
+			if !change.isSubmodule() {
+				kept = append(kept, change)
+			}
+		}
+		return kept, nil
+	}
+	if !workingTree || ignore == IgnoreSubmodulesDirty {
+		return changes, nil
+	}
+
+	changed := make(map[string]int, len(changes))
+	for i, change := range changes {
+		changed[change.Path] = i
+	}
+	staged, err := r.StagedFiles()
+	if err != nil {
+		return nil, err
+	}
+	var result []FileChange
+	for _, entry := range staged {
+		if entry.Mode != GitlinkMode {
+			continue
+		}
+		modified, untracked, err := r.submoduleDirt(entry.Path, ignore)
+		if err != nil {
+			return nil, err
+		}
+		if !modified && !untracked {
+			continue
+		}
+		if i, ok := changed[entry.Path]; ok {
+			if changes[i].New != nil && changes[i].New.Mode == GitlinkMode {
+				changes[i].ModifiedContent, changes[i].UntrackedContent = modified, untracked
+			}
+			continue
+		}
+		working, _, err := r.submoduleEntry(entry.Path)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, FileChange{Path: entry.Path, Old: &working, New: &working,
+			ModifiedContent: modified, UntrackedContent: untracked})
+	}
+	if len(result) == 0 {
+		return changes, nil
+	}
+	result = append(result, changes...)
+	sortChanges(result)
+	return result, nil
+}
+
+// isSubmodule reports whether either side of a change is a submodule
+func (c FileChange) isSubmodule() bool {
+	return c.Old != nil && c.Old.Mode == GitlinkMode || c.New != nil && c.New.Mode == GitlinkMode
+}
+
+// dirty reports whether a change is to a submodule with local changes
+func (c FileChange) dirty() bool {
+	return c.ModifiedContent || c.UntrackedContent
+}
+
+// writeSubmoduleLog summarizes a change to a submodule the way
+// diff --submodule=log does: a header naming both commits, then the subject
+// of each first-parent commit between them, ">" for those added and "<" for
+// those dropped. name is the path the summary shows.
+func (r *Repository) writeSubmoduleLog(w io.Writer, change FileChange, name string) error {
+	if change.UntrackedContent {
+		if _, err := fmt.Fprintf(w, "Submodule %s contains untracked content\n", name); err != nil {
+			return err
+		}
+	}
+	if change.ModifiedContent {
+		if _, err := fmt.Fprintf(w, "Submodule %s contains modified content\n", name); err != nil {
+			return err
+		}
+	}
+	oldSHA, newSHA := r.Format.ZeroSHA(), r.Format.ZeroSHA()
+	if change.Old != nil {
+		oldSHA = change.Old.SHA
+	}
+	if change.New != nil {
+		newSHA = change.New.SHA
+	}
+	if oldSHA == newSHA {
+		return nil
+	}
+
+	message := ""
+	switch {
+	case change.Old == nil:
+		message = "(new submodule)"
+	case change.New == nil:
+		message = "(submodule deleted)"
+	}
+	sub, err := r.openSubmodule(change.Path)
+	if err != nil {
+		return err
+	}
+	if message == "" && sub == nil {
+		message = "(not checked out)"
+	}
+	if message == "" {
+		for _, sha := range []string{oldSHA, newSHA} {
+			if _, _, err := sub.ReadObject(sha); err != nil {
+				message = "(commits not present)"
+			}
+		}
+	}
+	if message != "" {
+		_, err := fmt.Fprintf(w, "Submodule %s %s...%s %s\n", name, oldSHA[:7], newSHA[:7], message)
+		return err
+	}
+
+	forward, err := sub.IsAncestor(oldSHA, newSHA)
+	if err != nil {
+		return err
+	}
+	backward, err := sub.IsAncestor(newSHA, oldSHA)
+	if err != nil {
+		return err
+	}
+	separator, suffix := "...", ""
+	if forward || backward {
+		separator = ".."
+	}
+	if backward {
+		suffix = " (rewind)"
+	}
+	if _, err := fmt.Fprintf(w, "Submodule %s %s%s%s%s:\n", name, oldSHA[:7], separator, newSHA[:7], suffix); err != nil {
+		return err
+	}
+
+	revRange, err := sub.ParseRevRange([]string{oldSHA + "..." + newSHA})
+	if err != nil {
+		return err
+	}
+	nodes, err := sub.walkRevisions(revRange, func(node *CommitNode) ([]string, error) {
+		return node.Parents[:min(len(node.Parents), 1)], nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, node := range nodes {
+		commit, err := sub.ReadCommit(node.SHA)
+		if err != nil {
+			return err
+		}
+		side := ">"
+		if revRange.Left[node.SHA] {
+			side = "<"
+		}
+		if _, err := fmt.Fprintf(w, "  %s %s\n", side, strings.SplitN(commit.Message, "\n", 2)[0]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
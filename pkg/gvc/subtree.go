@@ -0,0 +1,580 @@
+package gvc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+const (
+	// SubtreeMergeHeadFile names the commit an interrupted subtree merge is
+	// bringing in
+	SubtreeMergeHeadFile = "SUBTREE_MERGE_HEAD"
+	// subtreeMergePrefixFile names the directory it is merging into
+	subtreeMergePrefixFile = "SUBTREE_MERGE_PREFIX"
+)
+
+// subtreeDirTrailer marks the commits add, merge and split --rejoin write:
+// their parents after the first are subtree history, kept as it is by split.
+// Git's subtree command writes the same trailer.
+const subtreeDirTrailer = "git-subtree-dir: "
+
+// SubtreeSplitOptions controls SubtreeSplit
+type SubtreeSplitOptions struct {
+	// Branch is created at the split history, or fast-forwarded to it
+	Branch string
+	// Rejoin records a merge of the split history into the current branch,
+	// so later merges from the subtree's own repository find a common base
+	Rejoin bool
+}
+
+// subtreePrefix cleans up the directory a subtree command works on
+func subtreePrefix(prefix string) (string, error) {
+	prefix = strings.TrimSuffix(normalizePath(prefix), "/")
+	if prefix == "" || prefix == "." {
+		return "", errors.New("a subtree needs a --prefix naming a directory")
+	}
+	for _, part := range strings.Split(prefix, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", fmt.Errorf("invalid subtree prefix %q", prefix)
+		}
+	}
+	return prefix, nil
+}
+
+// subtreeTree returns the tree at prefix in a commit's tree, or "" when the
+// commit has no such directory
+func (r *Repository) subtreeTree(treeSHA, prefix string) (string, error) {
+	entry, ok, err := r.treeEntryAt(treeSHA, prefix)
+	if err != nil || !ok || entry.Type != object.TreeObject {
+		return "", err
+	}
+	return entry.SHA, nil
+}
+
+// subtreeMessage writes the message of an add, merge or rejoin commit, with
+// the trailers Git's subtree command uses
+func subtreeMessage(subject, prefix, mainline, split string) string {
+	return fmt.Sprintf("%s\n\n%s%s\ngit-subtree-mainline: %s\ngit-subtree-split: %s", subject, subtreeDirTrailer, prefix, mainline, split)
+}
+
+// hasSubtreeTrailer reports whether a commit message names prefix in a
+// git-subtree-dir trailer
+func hasSubtreeTrailer(message, prefix string) bool {
+	for _, line := range strings.Split(message, "\n") {
+		if dir, ok := strings.CutPrefix(strings.TrimSpace(line), subtreeDirTrailer); ok && strings.Trim(dir, "/") == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// SubtreeSplit rewrites the history of rev (HEAD when empty) into a history
+// of the directory prefix alone, whose commits have that directory's tree as
+// their root tree. Commits that leave the directory unchanged, or do not
+// have it, are dropped. Authors, committers, dates and messages are kept, so
+// splitting the same history again gives the same commits, and a later
+// split only adds commits on top of an earlier one. History that subtree add
+// or merge brought in is kept as it is. It returns the tip of the split
+// history.
+func (r *Repository) SubtreeSplit(prefix, rev string, opts SubtreeSplitOptions) (string, error) {
+	prefix, err := subtreePrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	if rev == "" {
+		rev = "HEAD"
+	}
+	tip, err := r.ResolveCommit(rev)
+	if err != nil {
+		return "", err
+	}
+	if opts.Rejoin {
+		if op := r.OperationInProgress(); op != "" {
+			return "", fmt.Errorf("a %s is in progress; use --continue or --abort first", op)
+		}
+	}
+
+	order, err := r.commitsParentsFirst(tip)
+	if err != nil {
+		return "", err
+	}
+	split := make(map[string]string, len(order))
+	meter := r.startProgress("Splitting commits", len(order))
+	for _, sha := range order {
+		if err := r.canceled(); err != nil {
+			return "", err
+		}
+		if split[sha], err = r.splitCommit(sha, prefix, split); err != nil {
+			return "", err
+		}
+		meter.add(1, 0)
+	}
+	meter.done()
+	result := split[tip]
+	if result == "" {
+		return "", fmt.Errorf("no commit in %s has a directory %s", rev, prefix)
+	}
+
+	if opts.Branch != "" {
+		if err := validateBranchName(opts.Branch); err != nil {
+			return "", err
+		}
+		ref := "refs/heads/" + opts.Branch
+		old, err := r.ReadRef(ref)
+		if err != nil {
+			return "", err
+		}
+		if old != "" {
+			if ok, err := r.IsAncestor(old, result); err != nil {
+				return "", err
+			} else if !ok {
+				return "", fmt.Errorf("branch '%s' is not an ancestor of the split history; cannot update it", opts.Branch)
+			}
+		}
+		if err := r.writeRef(ref, result, "subtree split: "+prefix); err != nil {
+			return "", err
+		}
+	}
+	if opts.Rejoin {
+		if err := r.subtreeRejoin(prefix, result); err != nil {
+			return "", err
+		}
+	}
+	return result, nil
+}
+
+// commitsParentsFirst lists the commits reachable from tip, each after its
+// parents. The walk uses an explicit stack, as a long history would be too
+// deep to recurse through.
+func (r *Repository) commitsParentsFirst(tip string) ([]string, error) {
+	type frame struct {
+		sha     string
+		visited bool
+	}
+	var order []string
+	done := make(map[string]bool)
+	stack := []frame{{sha: tip}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if done[top.sha] {
+			continue
+		}
+		if top.visited {
+			done[top.sha] = true
+			order = append(order, top.sha)
+			continue
+		}
+		parents, err := r.commitParents(top.sha)
+		if err != nil {
+			return nil, err
+		}
+		stack = append(stack, frame{sha: top.sha, visited: true})
+		for i := len(parents) - 1; i >= 0; i-- {
+			if !done[parents[i]] {
+				stack = append(stack, frame{sha: parents[i]})
+			}
+		}
+	}
+	return order, nil
+}
+
+// splitCommit returns the commit that stands for sha in the split history
+// of prefix, writing it when needed, given what its parents became; ""
+// means it has none
+func (r *Repository) splitCommit(sha, prefix string, split map[string]string) (string, error) {
+	commit, err := r.ReadCommit(sha)
+	if err != nil {
+		return "", err
+	}
+	tree, err := r.subtreeTree(commit.TreeSHA, prefix)
+	if err != nil {
+		return "", err
+	}
+
+	var parents []string
+	keepSides := hasSubtreeTrailer(commit.Message, prefix)
+	for i, parent := range commit.Parents {
+		mapped := split[parent]
+		if mapped == "" && i > 0 && keepSides {
+			mapped = parent
+		}
+		if mapped != "" && !slices.Contains(parents, mapped) {
+			parents = append(parents, mapped)
+		}
+	}
+	if tree == "" {
+		// Without the directory, the commit stands for its first parent's split
+		if len(parents) == 0 {
+			return "", nil
+		}
+		return parents[0], nil
+	}
+
+	// Parents reachable from another parent add nothing to the history
+	var reduced []string
+	for i, parent := range parents {
+		redundant := false
+		for j, other := range parents {
+			if i == j {
+				continue
+			}
+			if ok, err := r.IsAncestor(parent, other); err != nil {
+				return "", err
+			} else if ok {
+				redundant = true
+				break
+			}
+		}
+		if !redundant {
+			reduced = append(reduced, parent)
+		}
+	}
+	if len(reduced) == 1 {
+		parent, err := r.ReadCommit(reduced[0])
+		if err != nil {
+			return "", err
+		}
+		if parent.TreeSHA == tree {
+			return reduced[0], nil
+		}
+	}
+
+	_, content, err := r.ReadObject(sha)
+	if err != nil {
+		return "", err
+	}
+	return r.WriteObject(object.CommitObject, rewriteCommitHeader(content, tree, reduced))
+}
+
+// rewriteCommitHeader returns a commit object with its tree and parents
+// replaced, keeping its identities, dates and message. Signatures and
+// merged tags no longer match and are dropped.
+func rewriteCommitHeader(content []byte, tree string, parents []string) []byte {
+	header, message, _ := bytes.Cut(content, []byte("\n\n"))
+	var b bytes.Buffer
+	inMultiline := false
+	for _, line := range strings.Split(string(header), "\n") {
+		// Continuation lines of a multi-line header start with a space
+		if inMultiline && strings.HasPrefix(line, " ") {
+			continue
+		}
+		inMultiline = false
+		key, _, _ := strings.Cut(line, " ")
+		switch key {
+		case "tree":
+			b.WriteString("tree " + tree + "\n")
+			for _, parent := range parents {
+				b.WriteString("parent " + parent + "\n")
+			}
+		case "parent":
+		case "gpgsig", "gpgsig-sha256", "mergetag":
+			inMultiline = true
+		default:
+			b.WriteString(line + "\n")
+		}
+	}
+	b.WriteString("\n")
+	b.Write(message)
+	return b.Bytes()
+}
+
+// subtreeRejoin merges split history back into the current branch without
+// changing its tree
+func (r *Repository) subtreeRejoin(prefix, split string) error {
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	if headSHA == "" {
+		return errors.New("cannot rejoin on an empty branch")
+	}
+	if ok, err := r.IsAncestor(split, headSHA); err != nil || ok {
+		return err
+	}
+	head, err := r.ReadCommit(headSHA)
+	if err != nil {
+		return err
+	}
+	message := subtreeMessage(fmt.Sprintf("Split '%s/' into commit '%s'", prefix, split), prefix, headSHA, split)
+	commitSHA, err := r.writeCommit(head.TreeSHA, []string{headSHA, split}, r.authorIdent(), time.Now(), message)
+	if err != nil {
+		return err
+	}
+	if err := r.UpdateHead(commitSHA, "subtree split: rejoin "+prefix); err != nil {
+		return fmt.Errorf("failed to update branch: %w", err)
+	}
+	summary, err := r.CommitSummary(commitSHA, message)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(r.Out, summary)
+	r.notifyCommit(commitSHA)
+	return nil
+}
+
+// subtreeEntries returns entries with everything at or below prefix
+// replaced by the files of a tree, which may be "" for none
+func (r *Repository) subtreeEntries(entries map[string]IndexEntry, prefix, treeSHA string) (map[string]IndexEntry, error) {
+	result := make(map[string]IndexEntry, len(entries))
+	for path, entry := range entries {
+		if path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			result[path] = entry
+		}
+	}
+	walker := r.WalkTree(treeSHA, TreeWalkOptions{})
+	for entry, ok := walker.Next(); ok; entry, ok = walker.Next() {
+		path := joinTreePath(prefix, entry.Path)
+		result[path] = IndexEntry{Path: path, SHA: entry.SHA, Mode: entry.Mode}
+	}
+	if err := walker.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SubtreeAdd brings the history of rev, typically another project's branch,
+// into the current branch as the directory prefix: a merge commit with rev
+// as its second parent adds rev's files under prefix, which must not exist
+// yet. An empty message gets a default one.
+func (r *Repository) SubtreeAdd(prefix, rev, message string) (string, error) {
+	prefix, err := subtreePrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	if op := r.OperationInProgress(); op != "" {
+		return "", fmt.Errorf("a %s is in progress; use --continue or --abort first", op)
+	}
+	commitSHA, err := r.ResolveCommit(rev)
+	if err != nil {
+		return "", err
+	}
+	commit, err := r.ReadCommit(commitSHA)
+	if err != nil {
+		return "", err
+	}
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return "", err
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return "", err
+	}
+	if err := r.checkCleanState(head); err != nil {
+		return "", err
+	}
+	for path := range head {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return "", fmt.Errorf("prefix '%s' already exists", prefix)
+		}
+	}
+	if _, err := os.Lstat(r.worktreePath(prefix)); err == nil {
+		return "", fmt.Errorf("prefix '%s' already exists in the working tree", prefix)
+	}
+
+	result, err := r.subtreeEntries(head, prefix, commit.TreeSHA)
+	if err != nil {
+		return "", err
+	}
+	if err := r.checkoutEntries(head, result); err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(message) == "" {
+		message = subtreeMessage(fmt.Sprintf("Add '%s/' from commit '%s'", prefix, commitSHA), prefix, headSHA, commitSHA)
+	}
+	var parents []string
+	if headSHA != "" {
+		parents = append(parents, headSHA)
+	}
+	parents = append(parents, commitSHA)
+	return r.commitStaged(result, parents, r.authorIdent(), time.Now(), message, "subtree add: "+prefix)
+}
+
+// SubtreeMerge merges the changes rev made since the subtree was last added
+// or merged into the directory prefix, as a merge commit with rev as its
+// second parent. The merge base comes from rev's history, so rev must share
+// history with what SubtreeAdd, SubtreeMerge or a split with Rejoin brought
+// in. On conflicts it stops like a cherry-pick, to be finished with
+// ContinueSubtreeMerge or undone with AbortSubtreeMerge. An empty message
+// gets a default one.
+func (r *Repository) SubtreeMerge(prefix, rev, message string) (string, error) {
+	prefix, err := subtreePrefix(prefix)
+	if err != nil {
+		return "", err
+	}
+	if op := r.OperationInProgress(); op != "" {
+		return "", fmt.Errorf("a %s is in progress; use --continue or --abort first", op)
+	}
+	commitSHA, err := r.ResolveCommit(rev)
+	if err != nil {
+		return "", err
+	}
+	commit, err := r.ReadCommit(commitSHA)
+	if err != nil {
+		return "", err
+	}
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return "", err
+	}
+	if headSHA == "" {
+		return "", fmt.Errorf("prefix '%s' does not exist; use subtree add first", prefix)
+	}
+	headCommit, err := r.ReadCommit(headSHA)
+	if err != nil {
+		return "", err
+	}
+	if tree, err := r.subtreeTree(headCommit.TreeSHA, prefix); err != nil {
+		return "", err
+	} else if tree == "" {
+		return "", fmt.Errorf("prefix '%s' does not exist; use subtree add first", prefix)
+	}
+	if ok, err := r.IsAncestor(commitSHA, headSHA); err != nil {
+		return "", err
+	} else if ok {
+		fmt.Fprintln(r.Out, "Already up to date.")
+		return headSHA, nil
+	}
+	bases, err := r.MergeBases(headSHA, commitSHA)
+	if err != nil {
+		return "", err
+	}
+	if len(bases) == 0 {
+		return "", fmt.Errorf("%s shares no history with the current branch; use subtree add, or split --rejoin, first", rev)
+	}
+	baseCommit, err := r.ReadCommit(bases[0])
+	if err != nil {
+		return "", err
+	}
+
+	head, err := r.headEntries()
+	if err != nil {
+		return "", err
+	}
+	if err := r.checkCleanState(head); err != nil {
+		return "", err
+	}
+	base, err := r.subtreeEntries(head, prefix, baseCommit.TreeSHA)
+	if err != nil {
+		return "", err
+	}
+	theirs, err := r.subtreeEntries(head, prefix, commit.TreeSHA)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(message) == "" {
+		message = subtreeMessage(fmt.Sprintf("Merge '%s/' from commit '%s'", prefix, commitSHA), prefix, headSHA, commitSHA)
+	}
+	label := fmt.Sprintf("%s (%s)", commitSHA[:7], strings.SplitN(commit.Message, "\n", 2)[0])
+	conflicts, err := r.applyMerge(base, theirs, label, head)
+	if err != nil {
+		return "", err
+	}
+	if len(conflicts) > 0 {
+		if err := r.writeSequencerState(r.gitPath(SubtreeMergeHeadFile), commitSHA, message, conflicts); err != nil {
+			return "", err
+		}
+		if err := writeFileLocked(r.gitPath(subtreeMergePrefixFile), []byte(prefix+"\n")); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", subtreeMergePrefixFile, err)
+		}
+		return "", conflictError(fmt.Errorf("could not merge %s into '%s/'\nconflicts in:\n\t%s\n"+
+			"resolve them, 'gvc add' the files and run 'gvc subtree merge --continue' (or --abort)",
+			commitSHA[:7], prefix, strings.Join(conflicts, "\n\t")))
+	}
+
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return "", err
+	}
+	return r.commitStaged(staged, []string{headSHA, commitSHA}, r.authorIdent(), time.Now(), message, "subtree merge: "+prefix)
+}
+
+// subtreeMergeState returns the commit and directory of an interrupted
+// subtree merge, or "" when there is none
+func (r *Repository) subtreeMergeState() (commitSHA, prefix string, err error) {
+	data, err := os.ReadFile(r.gitPath(SubtreeMergeHeadFile))
+	if os.IsNotExist(err) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s: %w", SubtreeMergeHeadFile, err)
+	}
+	prefixData, err := os.ReadFile(r.gitPath(subtreeMergePrefixFile))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s: %w", subtreeMergePrefixFile, err)
+	}
+	return strings.TrimSpace(string(data)), strings.TrimSpace(string(prefixData)), nil
+}
+
+// clearSubtreeMergeState forgets an interrupted subtree merge
+func (r *Repository) clearSubtreeMergeState() error {
+	if err := r.clearSequencerState(r.gitPath(SubtreeMergeHeadFile)); err != nil {
+		return err
+	}
+	if err := os.Remove(r.gitPath(subtreeMergePrefixFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", subtreeMergePrefixFile, err)
+	}
+	return nil
+}
+
+// ContinueSubtreeMerge commits an interrupted subtree merge once its
+// conflicts are resolved
+func (r *Repository) ContinueSubtreeMerge() (string, error) {
+	commitSHA, prefix, err := r.subtreeMergeState()
+	if err != nil {
+		return "", err
+	}
+	if commitSHA == "" {
+		return "", errors.New("no subtree merge in progress")
+	}
+	message, err := os.ReadFile(r.gitPath(MergeMsgFile))
+	if err != nil {
+		return "", fmt.Errorf("failed to read merge message: %w", err)
+	}
+	headSHA, err := r.HeadCommit()
+	if err != nil {
+		return "", err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return "", err
+	}
+	unresolved, err := r.unresolvedConflicts(staged)
+	if err != nil {
+		return "", err
+	}
+	if len(unresolved) > 0 {
+		return "", conflictError(fmt.Errorf("unresolved conflicts remain in:\n\t%s\nfix them and 'gvc add' the result",
+			strings.Join(unresolved, "\n\t")))
+	}
+	sha, err := r.commitStaged(staged, []string{headSHA, commitSHA}, r.authorIdent(), time.Now(),
+		strings.TrimSpace(string(message)), "subtree merge: "+prefix)
+	if err != nil {
+		return "", err
+	}
+	return sha, r.clearSubtreeMergeState()
+}
+
+// AbortSubtreeMerge gives up an interrupted subtree merge, returning the
+// working tree and index to HEAD
+func (r *Repository) AbortSubtreeMerge() error {
+	if _, err := os.Stat(r.gitPath(SubtreeMergeHeadFile)); err != nil {
+		return errors.New("no subtree merge in progress")
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	if err := r.resetHard(head); err != nil {
+		return err
+	}
+	return r.clearSubtreeMergeState()
+}
@@ -0,0 +1,166 @@
+package gvc
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultTempExpire is how old a temporary file must be before gc removes it
+// by default, unless a prune's grace period is shorter
+const defaultTempExpire = "1.day.ago"
+
+// staleFiles are what interrupted commands left in the repository directory,
+// by their paths relative to the common directory
+type staleFiles struct {
+	temp   []string  // temporary files and partial packs
+	locks  []string  // locks and writer files of processes that are gone
+	size   int64     // bytes the temporary files take
+	cutoff time.Time // files older than this are stale
+}
+
+// findStaleFiles looks through the repository directory for the leftovers
+// of interrupted commands: temporary object, pack and large file files and
+// partial packs last written before cutoff, and locks and BeginWrite
+// registrations whose process on this host has exited. A lock whose owner
+// cannot be checked, because it ran on another host, is stale once older
+// than cutoff and than a waiting command would let it be; one whose owner is
+// still running never is. A zero cutoff leaves every file that is stale
+// only by its age.
+func (r *Repository) findStaleFiles(cutoff time.Time) (*staleFiles, error) {
+	stale := &staleFiles{cutoff: cutoff}
+	err := filepath.WalkDir(r.CommonDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil // removed by a command finishing meanwhile
+			}
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(r.CommonDir, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if isTempFile(filepath.ToSlash(rel)) {
+			if info.ModTime().Before(cutoff) {
+				stale.temp = append(stale.temp, rel)
+				stale.size += info.Size()
+			}
+		} else if isLockFile(filepath.ToSlash(rel)) && stale.lockIsLeftover(path, info) {
+			stale.locks = append(stale.locks, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look for stale files in %s: %w", r.CommonDir, err)
+	}
+	return stale, nil
+}
+
+// isTempFile reports whether rel, relative to the common directory, is a
+// file gvc writes before renaming it into place, or a pack whose copy was
+// interrupted. Temporary files are only looked for where gvc makes them, so
+// a branch or anything else named tmp_ is never taken for one.
+func isTempFile(rel string) bool {
+	name := filepath.Base(rel)
+	switch {
+	case strings.HasPrefix(name, "tmp_"):
+		return strings.HasPrefix(rel, ObjectsDir+"/") || strings.HasPrefix(rel, LFSDir+"/")
+	case strings.HasSuffix(name, PartSuffix):
+		return filepath.ToSlash(filepath.Dir(rel)) == PackDir
+	}
+	return false
+}
+
+// isLockFile reports whether rel, relative to the common directory, is a
+// lock or a BeginWrite registration
+func isLockFile(rel string) bool {
+	return strings.HasSuffix(rel, LockSuffix) || filepath.ToSlash(filepath.Dir(rel)) == WritersDir
+}
+
+// lockIsLeftover reports whether the lock or writer file at path belongs to
+// a process that is gone. One whose owner cannot be checked is also given
+// the time a waiting command would before breaking it.
+func (s *staleFiles) lockIsLeftover(path string, info fs.FileInfo) bool {
+	if running, checked := lockOwnerRunning(path); checked {
+		return !running
+	}
+	return info.ModTime().Before(s.cutoff) && time.Since(info.ModTime()) > staleLockAge
+}
+
+// remove deletes the stale files. A lock is checked again first, since a
+// new command may have taken it since it was found.
+func (s *staleFiles) remove(dir string) error {
+	for _, rel := range s.temp {
+		if err := os.Remove(filepath.Join(dir, rel)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", rel, err)
+		}
+	}
+	for _, rel := range s.locks {
+		path := filepath.Join(dir, rel)
+		if info, err := os.Stat(path); err != nil || !s.lockIsLeftover(path, info) {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", rel, err)
+		}
+	}
+	return nil
+}
+
+// tempCutoff returns the time before which temporary files are stale:
+// gc.tempExpire ago (default a day), or pruneCutoff when that is later, so
+// prune --expire=now takes every temporary file too. "never" keeps them.
+func (r *Repository) tempCutoff(pruneCutoff time.Time) (time.Time, error) {
+	expire, err := r.getConfigString("gc.tempExpire", defaultTempExpire)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var cutoff time.Time
+	if expire != "never" {
+		if cutoff, err = ParseDate(expire, time.Now()); err != nil {
+			return time.Time{}, fmt.Errorf("invalid gc.tempExpire: %w", err)
+		}
+	}
+	if pruneCutoff.After(cutoff) {
+		cutoff = pruneCutoff
+	}
+	return cutoff, nil
+}
+
+// removeExitedLocks removes the locks at the top of the repository
+// directory, such as index.lock or maintenance.lock, left by a process on
+// this host that has exited, so the next command does not trip over a
+// crashed one's. It only reads a directory or two, cheap enough for every
+// open; gc looks through the rest. Failures are ignored: a lock that stays
+// is broken when a command next needs it.
+func (r *Repository) removeExitedLocks() {
+	dirs := []string{r.GitDir}
+	if r.CommonDir != r.GitDir {
+		dirs = append(dirs, r.CommonDir)
+	}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || !strings.HasSuffix(entry.Name(), LockSuffix) {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if running, checked := lockOwnerRunning(path); checked && !running {
+				os.Remove(path)
+			}
+		}
+	}
+}
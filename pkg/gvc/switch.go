@@ -0,0 +1,308 @@
+package gvc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// SwitchBranch checks out a branch, carrying local changes to paths the switch does not touch.
+// A branch that does not exist falls back to a remote: the name of a remote
+// switches to its default branch, and a branch only one remote has is
+// created from it, tracking it.
+func (r *Repository) SwitchBranch(name string, create bool, startRev string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if op := r.OperationInProgress(); op != "" {
+		return fmt.Errorf("a %s is in progress; use --continue or --abort first", op)
+	}
+
+	ref := "refs/heads/" + name
+	current, err := r.HeadRef()
+	if err != nil {
+		return err
+	}
+	if !create && current == ref {
+		fmt.Fprintf(r.Out, "Already on '%s'\n", name)
+		return nil
+	}
+
+	var targetSHA string
+	if create {
+		if err := validateBranchName(name); err != nil {
+			return err
+		}
+		existing, err := r.ReadRef(ref)
+		if err != nil {
+			return err
+		}
+		if existing != "" {
+			return fmt.Errorf("a branch named '%s' already exists", name)
+		}
+		if targetSHA, err = r.ResolveCommit(startRev); err != nil {
+			return err
+		}
+	} else {
+		if targetSHA, err = r.ReadRef(ref); err != nil {
+			return err
+		}
+		if targetSHA == "" {
+			remote, tracking, err := r.remoteBranchFallback(name)
+			if err != nil {
+				return err
+			}
+			if tracking == "" {
+				return fmt.Errorf("invalid reference: %s", name)
+			}
+			return r.switchTracking(remote, tracking)
+		}
+		if other, err := r.branchCheckedOut(ref, true); err != nil {
+			return err
+		} else if other != "" {
+			return fmt.Errorf("'%s' is already checked out at '%s'", name, other)
+		}
+	}
+
+	headTree, err := r.headTree()
+	if err != nil {
+		return err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+	targetCommit, err := r.ReadCommit(targetSHA)
+	if err != nil {
+		return err
+	}
+
+	// Only paths that differ between the two commits are rewritten, and they must be clean
+	changes, err := r.DiffTrees(headTree, targetCommit.TreeSHA)
+	if err != nil {
+		return err
+	}
+	var dirty []string
+	for _, change := range changes {
+		var headEntry IndexEntry
+		if change.Old != nil {
+			headEntry = *change.Old
+		}
+		stagedEntry, inStaged := staged[change.Path]
+		workingEntry, onDisk, err := r.readWorkingEntry(change.Path, false)
+		if inStaged {
+			workingEntry, onDisk, err = r.readTrackedEntry(stagedEntry, false)
+		}
+		if err != nil {
+			return err
+		}
+		if !sameEntry(stagedEntry, headEntry, inStaged, change.Old != nil) || !sameEntry(workingEntry, stagedEntry, onDisk, inStaged) {
+			dirty = append(dirty, change.Path)
+		}
+	}
+	if len(dirty) > 0 {
+		sort.Strings(dirty)
+		return fmt.Errorf("your local changes would be overwritten by switch:\n\t%s\nplease commit or stash them first",
+			strings.Join(dirty, "\n\t"))
+	}
+
+	inside, err := r.sparseCheckout()
+	if err != nil {
+		return err
+	}
+	// Files are removed before any are written, so a file and a directory
+	// can trade places: x goes before x/f is written, and x/f before x
+	meter := r.startProgress("Updating files", len(changes))
+	done := 0
+	for _, removing := range []bool{true, false} {
+		for _, change := range changes {
+			if (change.New == nil) != removing {
+				continue
+			}
+			if removing {
+				err = r.removeWorkingFile(change.Path)
+				delete(staged, change.Path)
+			} else {
+				if !r.leftOut(inside, change.Path) {
+					err = r.writeWorkingFile(*change.New)
+				}
+				staged[change.Path] = *change.New
+			}
+			if err != nil {
+				return err
+			}
+			done++
+			r.notifyCheckout(change.Path, done, len(changes))
+			meter.add(1, 0)
+		}
+	}
+	meter.done()
+	if err := r.writeStagedEntries(staged); err != nil {
+		return err
+	}
+
+	if create {
+		if err := r.writeRef(ref, targetSHA, "branch: Created from "+startRev); err != nil {
+			return err
+		}
+	}
+	from, err := r.currentBranchName()
+	if err != nil {
+		return err
+	}
+	oldSHA, err := r.HeadCommit()
+	if err != nil {
+		return err
+	}
+	if err := r.writeGitFile(r.gitPath(HeadFile), []byte("ref: "+ref+"\n")); err != nil {
+		return fmt.Errorf("failed to write HEAD: %w", err)
+	}
+	if err := r.appendReflog("HEAD", oldSHA, targetSHA, fmt.Sprintf("checkout: moving from %s to %s", from, name)); err != nil {
+		return err
+	}
+
+	if create {
+		fmt.Fprintf(r.Out, "Switched to a new branch '%s'\n", name)
+	} else {
+		fmt.Fprintf(r.Out, "Switched to branch '%s'\n", name)
+	}
+	return nil
+}
+
+// SwitchOrphan points HEAD at a new, unborn branch whose first commit will
+// have no parents. Unless keep is set the tracked files are removed and the
+// index emptied, which refuses to lose local changes; with keep the current
+// files stay in the working tree and index to seed the new history.
+func (r *Repository) SwitchOrphan(name string, keep bool) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if op := r.OperationInProgress(); op != "" {
+		return fmt.Errorf("a %s is in progress; use --continue or --abort first", op)
+	}
+	if err := validateBranchName(name); err != nil {
+		return err
+	}
+	ref := "refs/heads/" + name
+	existing, err := r.ReadRef(ref)
+	if err != nil {
+		return err
+	}
+	if existing != "" {
+		return fmt.Errorf("a branch named '%s' already exists", name)
+	}
+
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	staged, err := r.stagedEntries()
+	if err != nil {
+		return err
+	}
+
+	// With keep the index already holds every file for the first commit
+	if !keep {
+		var dirty []string
+		for path, stagedEntry := range staged {
+			headEntry, inHead := head[path]
+			workingEntry, onDisk, err := r.readTrackedEntry(stagedEntry, false)
+			if err != nil {
+				return err
+			}
+			if !sameEntry(stagedEntry, headEntry, true, inHead) || !sameEntry(workingEntry, stagedEntry, onDisk, true) {
+				dirty = append(dirty, path)
+			}
+		}
+		for path := range head {
+			if _, ok := staged[path]; !ok {
+				dirty = append(dirty, path)
+			}
+		}
+		if len(dirty) > 0 {
+			sort.Strings(dirty)
+			return fmt.Errorf("your local changes would be lost by switch --orphan:\n\t%s\nplease commit or stash them first, or use --keep",
+				strings.Join(dirty, "\n\t"))
+		}
+		for path := range staged {
+			if err := r.removeWorkingFile(path); err != nil {
+				return err
+			}
+		}
+		if err := r.WriteIndex(&Index{Entries: []IndexEntry{}}); err != nil {
+			return err
+		}
+	}
+
+	if err := r.writeGitFile(r.gitPath(HeadFile), []byte("ref: "+ref+"\n")); err != nil {
+		return fmt.Errorf("failed to write HEAD: %w", err)
+	}
+	fmt.Fprintf(r.Out, "Switched to a new branch '%s'\n", name)
+	return nil
+}
+
+// remoteBranchFallback finds the remote-tracking branch switching to a branch
+// that does not exist falls back to: the default branch of the remote called
+// name, or name on the one remote that has it. It returns "" when there is
+// none.
+func (r *Repository) remoteBranchFallback(name string) (remote, tracking string, err error) {
+	remotes, err := r.Remotes()
+	if err != nil {
+		return "", "", err
+	}
+	for _, candidate := range remotes {
+		if candidate.Name != name {
+			continue
+		}
+		if tracking, err = r.RemoteHead(name); err != nil || tracking == "" {
+			return "", "", err
+		}
+		return name, tracking, nil
+	}
+
+	var matches []string
+	for _, candidate := range remotes {
+		ref, err := r.trackingRef(candidate.Name, name)
+		if err != nil {
+			return "", "", err
+		}
+		if ref == "" {
+			continue
+		}
+		if sha, err := r.ReadRef(ref); err != nil {
+			return "", "", err
+		} else if sha != "" {
+			remote, tracking = candidate.Name, ref
+			matches = append(matches, strings.TrimPrefix(ref, RemotesDir+"/"))
+		}
+	}
+	if len(matches) > 1 {
+		return "", "", fmt.Errorf("'%s' matched more than one remote-tracking branch: %s; use 'gvc switch -c %s <remote>/%s'",
+			name, strings.Join(matches, ", "), name, name)
+	}
+	return remote, tracking, nil
+}
+
+// switchTracking switches to the local branch for the remote-tracking branch
+// tracking of remote, creating it to track that branch unless it exists
+func (r *Repository) switchTracking(remote, tracking string) error {
+	branch, ok := strings.CutPrefix(tracking, RemotesDir+"/"+remote+"/")
+	if !ok {
+		return fmt.Errorf("cannot tell which branch of %s %s tracks", remote, tracking)
+	}
+	existing, err := r.ReadRef(HeadsDir + "/" + branch)
+	if err != nil {
+		return err
+	}
+	if existing != "" {
+		return r.SwitchBranch(branch, false, "")
+	}
+	if err := r.SwitchBranch(branch, true, tracking); err != nil {
+		return err
+	}
+	if err := r.SetUpstream(branch, remote, HeadsDir+"/"+branch); err != nil {
+		return err
+	}
+	fmt.Fprintf(r.Out, "branch '%s' set up to track '%s/%s'.\n", branch, remote, branch)
+	return nil
+}
@@ -0,0 +1,45 @@
+package gvc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSwitchBetweenFileAndDirectory(t *testing.T) {
+	repo, _ := newTestRepoWithCommit(t)
+	// main has x as a file, topic has x/f under a directory
+	writeTestFile(t, repo, "x", "file\n")
+	if err := repo.Add("x"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CommitWithOptions("x is a file", CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SwitchBranch("topic", true, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(repo.Root, "x")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, repo, "x/f", "nested\n")
+	if err := repo.AddWithOptions(AddOptions{All: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CommitWithOptions("x is a directory", CommitOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.SwitchBranch("main", false, ""); err != nil {
+		t.Fatalf("switching from the directory to the file: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(repo.Root, "x")); err != nil || string(data) != "file\n" {
+		t.Errorf("x is %q (%v), want the file", data, err)
+	}
+	if err := repo.SwitchBranch("topic", false, ""); err != nil {
+		t.Fatalf("switching from the file to the directory: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(repo.Root, "x", "f")); err != nil || string(data) != "nested\n" {
+		t.Errorf("x/f is %q (%v), want the nested file", data, err)
+	}
+}
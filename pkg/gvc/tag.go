@@ -0,0 +1,234 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// TagsDir holds the tags
+const TagsDir = "refs/tags"
+
+// TagListOptions selects and orders the tags ListTags returns. The
+// reachability filters take any revision naming a commit.
+type TagListOptions struct {
+	Patterns   []string // globs a tag's name must match one of; every tag when empty
+	Contains   string   // only tags whose history includes this commit
+	NoContains string   // only tags whose history does not include this commit
+	Merged     string   // only tags whose commit is in this commit's history
+	NoMerged   string   // only tags whose commit is not in this commit's history
+	Sort       string   // "refname" (the default) or "version:refname"; a leading "-" reverses it
+}
+
+// ListTags returns the tags opts selects. An annotated tag's Ref names the
+// commit it points to in SHA and the tag object in Tag. Tags of trees or
+// blobs have no history, so any reachability filter leaves them out.
+// --contains filters share a ContainsCache across the tags; the others use
+// IsAncestor. Both are sped up by the commit-graph once it is written.
+func (r *Repository) ListTags(opts TagListOptions) ([]Ref, error) {
+	sortKey, reverse := strings.CutPrefix(opts.Sort, "-")
+	if sortKey != "" && sortKey != "refname" && sortKey != "version:refname" && sortKey != "v:refname" {
+		return nil, fmt.Errorf("unsupported sort key '%s'", opts.Sort)
+	}
+	type filter struct {
+		sha      string
+		contains bool // the tag's commit must reach sha, or else be reached by it
+		want     bool
+		cache    *ContainsCache // set for contains filters
+	}
+	var filters []filter
+	for _, f := range []struct {
+		rev            string
+		contains, want bool
+	}{
+		{opts.Contains, true, true},
+		{opts.NoContains, true, false},
+		{opts.Merged, false, true},
+		{opts.NoMerged, false, false},
+	} {
+		if f.rev == "" {
+			continue
+		}
+		sha, err := r.ResolveCommit(f.rev)
+		if err != nil {
+			return nil, err
+		}
+		var cache *ContainsCache
+		if f.contains {
+			if cache, err = r.NewContainsCache(sha); err != nil {
+				return nil, err
+			}
+		}
+		filters = append(filters, filter{sha, f.contains, f.want, cache})
+	}
+
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	var tags []Ref
+	for _, ref := range refs {
+		name, ok := strings.CutPrefix(ref.Name, TagsDir+"/")
+		if !ok {
+			continue
+		}
+		if len(opts.Patterns) > 0 {
+			matched := false
+			for _, pattern := range opts.Patterns {
+				if ok, err := path.Match(pattern, name); err != nil {
+					return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+				} else if ok {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+		}
+		if len(filters) > 0 {
+			objectType, _, err := r.ReadObject(ref.SHA)
+			if err != nil {
+				return nil, err
+			}
+			if objectType != object.CommitObject {
+				continue
+			}
+		}
+		keep := true
+		for _, f := range filters {
+			var reaches bool
+			if f.contains {
+				reaches, err = f.cache.ReachedFrom(ref.SHA)
+			} else {
+				reaches, err = r.IsAncestor(ref.SHA, f.sha)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if reaches != f.want {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			tags = append(tags, ref)
+		}
+	}
+
+	// listRefs sorts by name already
+	if sortKey == "version:refname" || sortKey == "v:refname" {
+		slices.SortStableFunc(tags, func(a, b Ref) int { return compareVersions(a.Name, b.Name) })
+	}
+	if reverse {
+		slices.Reverse(tags)
+	}
+	return tags, nil
+}
+
+// compareVersions orders names such as v1.9 and v1.10 the way people read
+// them, comparing runs of digits as numbers and everything else as text
+func compareVersions(a, b string) int {
+	for a != "" && b != "" {
+		partA, restA := versionPart(a)
+		partB, restB := versionPart(b)
+		numA, errA := strconv.ParseUint(partA, 10, 64)
+		numB, errB := strconv.ParseUint(partB, 10, 64)
+		switch {
+		case errA == nil && errB == nil && numA != numB:
+			if numA < numB {
+				return -1
+			}
+			return 1
+		case (errA != nil || errB != nil) && partA != partB:
+			return strings.Compare(partA, partB)
+		}
+		a, b = restA, restB
+	}
+	return strings.Compare(a, b)
+}
+
+// versionPart splits off the leading run of digits or of other characters
+func versionPart(s string) (string, string) {
+	digits := s[0] >= '0' && s[0] <= '9'
+	i := 1
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9') == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// CreateTag points the lightweight tag name at the commit rev names,
+// refusing to move an existing tag unless force is set
+func (r *Repository) CreateTag(name, rev string, force bool) error {
+	ref, sha, err := r.tagTarget(name, rev, force)
+	if err != nil {
+		return err
+	}
+	return r.writeRef(ref, sha, "tag: tagging "+sha)
+}
+
+// CreateAnnotatedTag points name at a new tag object for the commit rev
+// names, recording the tagger, date and message as Git's tag -a does, so
+// describe uses it without --tags
+func (r *Repository) CreateAnnotatedTag(name, rev, message string, force bool) error {
+	if strings.TrimSpace(message) == "" {
+		return errors.New("an annotated tag needs a message")
+	}
+	ref, sha, err := r.tagTarget(name, rev, force)
+	if err != nil {
+		return err
+	}
+	content := fmt.Sprintf("object %s\ntype %s\ntag %s\ntagger %s %d +0000\n\n%s\n",
+		sha, object.CommitObject, name, r.authorIdent(), time.Now().Unix(), strings.TrimSpace(message))
+	tagSHA, err := r.WriteObject(object.TagObject, []byte(content))
+	if err != nil {
+		return err
+	}
+	return r.writeRef(ref, tagSHA, "tag: tagging "+sha)
+}
+
+// tagTarget checks that name can be created as a tag, or replaced with
+// force, and returns its ref and the commit rev names
+func (r *Repository) tagTarget(name, rev string, force bool) (ref, sha string, err error) {
+	if strings.HasPrefix(name, "-") {
+		return "", "", fmt.Errorf("invalid tag name: %s", name)
+	}
+	ref = TagsDir + "/" + name
+	if _, err := CheckRefFormat(ref, RefFormatOptions{}); err != nil {
+		return "", "", fmt.Errorf("invalid tag name: %s", name)
+	}
+	existing, err := r.ReadRef(ref)
+	if err != nil {
+		return "", "", err
+	}
+	if existing != "" && !force {
+		return "", "", fmt.Errorf("tag '%s' already exists", name)
+	}
+	if sha, err = r.ResolveCommit(rev); err != nil {
+		return "", "", err
+	}
+	return ref, sha, nil
+}
+
+// DeleteTag removes a tag and returns the SHA it pointed to
+func (r *Repository) DeleteTag(name string) (string, error) {
+	ref := TagsDir + "/" + name
+	sha, err := r.ReadRef(ref)
+	if err != nil {
+		return "", err
+	}
+	if sha == "" {
+		return "", fmt.Errorf("tag '%s' not found", name)
+	}
+	if err := r.deleteRef(ref); err != nil {
+		return "", fmt.Errorf("failed to delete tag: %w", err)
+	}
+	return sha, nil
+}
@@ -0,0 +1,25 @@
+package gvc
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDeletedTagMakesRoomForNestedTag(t *testing.T) {
+	repo, commitSHA := newTestRepoWithCommit(t)
+	if err := repo.CreateTag("v1", "HEAD", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.DeleteTag("v1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(repo.reflogPath(TagsDir + "/v1")); !os.IsNotExist(err) {
+		t.Fatalf("the reflog of the deleted tag was kept: %v", err)
+	}
+	if err := repo.CreateTag("v1/rc1", "HEAD", false); err != nil {
+		t.Fatal(err)
+	}
+	if sha, err := repo.ReadRef(TagsDir + "/v1/rc1"); err != nil || sha != commitSHA {
+		t.Errorf("v1/rc1 points at %q (%v), want %s", sha, err, commitSHA)
+	}
+}
@@ -0,0 +1,183 @@
+package gvc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// TransferStats sums up what a fetch, push or clone moved between repositories
+type TransferStats struct {
+	Objects int   // objects the receiving side did not have
+	Size    int64 // their content, uncompressed
+	Copied  int64 // bytes of packs, objects and large files copied
+	Linked  int64 // bytes shared by hardlink instead of being copied
+	Elapsed time.Duration
+}
+
+// Ratio is how many times smaller the objects were in transit than their
+// content, or 0 when no bytes were moved
+func (s TransferStats) Ratio() float64 {
+	if s.Copied+s.Linked == 0 {
+		return 0
+	}
+	return float64(s.Size) / float64(s.Copied+s.Linked)
+}
+
+// String sums the transfer up on one line, e.g.
+//
+//	1200 objects, 3.2 MiB, compression 2.41x, in 1.52s at 2.1 MiB/s
+func (s TransferStats) String() string {
+	line := fmt.Sprintf("%d objects, %s", s.Objects, FormatSize(s.Copied+s.Linked))
+	if s.Linked > 0 {
+		line += fmt.Sprintf(" (%s hardlinked)", FormatSize(s.Linked))
+	}
+	if ratio := s.Ratio(); ratio > 0 {
+		line += fmt.Sprintf(", compression %.2fx", ratio)
+	}
+	line += ", in " + s.Elapsed.Round(time.Millisecond).String()
+	if seconds := s.Elapsed.Seconds(); s.Copied > 0 && seconds > 0 {
+		line += " at " + FormatSize(int64(float64(s.Copied)/seconds)) + "/s"
+	}
+	return line
+}
+
+// transferMeter counts what a transfer moves into the repository it is set
+// on and, with a rate limit, paces the bytes it copies. A nil *transferMeter
+// counts and paces nothing, so callers need not check whether one is set.
+type transferMeter struct {
+	mu    sync.Mutex
+	stats TransferStats
+	rate  int64 // bytes per second; 0 means no limit
+	start time.Time
+	ctx   context.Context
+}
+
+// meterTransfer runs move with a meter limited to rate bytes per second set
+// on dst, the repository receiving objects, then reports what it moved on
+// r's output as e.g. "Received 12 objects, ..." and to r's observers
+func (r *Repository) meterTransfer(dst *Repository, verb string, rate int64, move func() error) error {
+	if rate < 0 {
+		return fmt.Errorf("rate limit %d is not a positive number", rate)
+	}
+	meter := &transferMeter{rate: rate, start: time.Now(), ctx: r.Context}
+	dst.transfer = meter
+	defer func() { dst.transfer = nil }()
+	if err := move(); err != nil {
+		return err
+	}
+	meter.mu.Lock()
+	stats := meter.stats
+	meter.mu.Unlock()
+	stats.Elapsed = time.Since(meter.start)
+	if stats.Objects > 0 || stats.Copied+stats.Linked > 0 {
+		fmt.Fprintf(r.Out, "%s %s\n", verb, stats)
+		r.notifyTransfer(stats)
+	}
+	return nil
+}
+
+// add records objects arriving with size bytes of content, and bytes
+// copied or linked to move them
+func (m *transferMeter) add(objects int, size, copied, linked int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Objects += objects
+	m.stats.Size += size
+	m.stats.Copied += copied
+	m.stats.Linked += linked
+}
+
+// addObjects records the objects shas arriving from src, reading the size of
+// each one's content
+func (m *transferMeter) addObjects(src *Repository, shas []string) {
+	if m == nil {
+		return
+	}
+	var size int64
+	for _, sha := range shas {
+		if n, err := src.objectSize(sha); err == nil {
+			size += n
+		}
+	}
+	m.add(len(shas), size, 0, 0)
+}
+
+// addLinked records the file at path as shared by hardlink
+func (m *transferMeter) addLinked(path string) {
+	if m == nil {
+		return
+	}
+	if info, err := os.Stat(path); err == nil {
+		m.add(0, 0, 0, info.Size())
+	}
+}
+
+// pace waits until copying what has been copied so far keeps to the rate
+// limit, or the context is done
+func (m *transferMeter) pace() error {
+	if m == nil || m.rate == 0 {
+		return nil
+	}
+	m.mu.Lock()
+	due := m.start.Add(time.Duration(float64(m.stats.Copied) / float64(m.rate) * float64(time.Second)))
+	m.mu.Unlock()
+	wait := time.Until(due)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	var done <-chan struct{}
+	if m.ctx != nil {
+		done = m.ctx.Done()
+	}
+	select {
+	case <-timer.C:
+		return nil
+	case <-done:
+		return m.ctx.Err()
+	}
+}
+
+// writer returns w with everything written to it counted as copied and
+// paced, in pieces small enough to keep the rate steady
+func (m *transferMeter) writer(w io.Writer) io.Writer {
+	if m == nil {
+		return w
+	}
+	return &meteredWriter{m: m, w: w}
+}
+
+// meteredWriter is what transferMeter.writer returns
+type meteredWriter struct {
+	m *transferMeter
+	w io.Writer
+}
+
+func (w *meteredWriter) Write(p []byte) (int, error) {
+	// A tenth of a second's worth at a time, within reason
+	piece := len(p)
+	if w.m.rate > 0 {
+		piece = int(min(max(w.m.rate/10, 1<<10), 1<<20))
+	}
+	written := 0
+	for written < len(p) {
+		n, err := w.w.Write(p[written:min(written+piece, len(p))])
+		written += n
+		w.m.add(0, 0, int64(n), 0)
+		if err != nil {
+			return written, err
+		}
+		if err := w.m.pace(); err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
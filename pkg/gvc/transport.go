@@ -0,0 +1,1134 @@
+package gvc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+const (
+	// RemotesDir holds the remote-tracking refs that fetch updates
+	RemotesDir = "refs/remotes"
+	// FetchHeadFile records what a fetch from a URL brought in
+	FetchHeadFile = "FETCH_HEAD"
+)
+
+// FetchOptions controls Fetch
+type FetchOptions struct {
+	// NoHardlinks copies object files instead of hardlinking them
+	NoHardlinks bool
+	// DryRun reports the refs that would be updated without copying objects,
+	// changing any ref or writing FETCH_HEAD
+	DryRun bool
+	// Depth limits the history fetched to this many commits from each ref,
+	// making the repository shallow; in a shallow repository it deepens or
+	// shortens the history of what is fetched. 0 means no limit.
+	Depth int
+	// Unshallow fetches the history a shallow repository is missing
+	Unshallow bool
+	// LimitRate caps the bytes copied per second; 0 means no limit
+	LimitRate int64
+	// Jobs is how many remotes FetchAll fetches at once; 0 means
+	// fetch.parallel
+	Jobs int
+}
+
+// PushOptions controls Push
+type PushOptions struct {
+	// NoHardlinks copies object files instead of hardlinking them
+	NoHardlinks bool
+	// Force allows updates that are not fast-forwards
+	Force bool
+	// DryRun reports what would be updated, including rejections, without
+	// sending objects or changing any ref
+	DryRun bool
+	// SetUpstream makes each pushed branch track the branch it was pushed to
+	SetUpstream bool
+	// NoVerify skips the pre-push hook unless hooks.allowNoVerify forbids it
+	NoVerify bool
+	// LimitRate caps the bytes copied per second; 0 means no limit
+	LimitRate int64
+}
+
+// CloneOptions controls Clone
+type CloneOptions struct {
+	// NoHardlinks copies object files instead of hardlinking them
+	NoHardlinks bool
+	// RecurseSubmodules clones and checks out every submodule, recursively
+	RecurseSubmodules bool
+	// Depth makes a shallow clone of only this many commits of each branch
+	Depth int
+	// LimitRate caps the bytes copied per second; 0 means no limit
+	LimitRate int64
+	// Out receives progress messages; nothing is printed when it is nil
+	Out io.Writer
+	// Progress and Verbose set up the new repository's progress meters, as
+	// Repository.Progress and Repository.Verbose do
+	Progress io.Writer
+	Verbose  bool
+	// Observer, when set, watches the new repository from the start, so it
+	// sees the refs the clone creates and the progress of its checkout
+	Observer *Observer
+	// Context, when set, stops the clone once it is done, as
+	// Repository.Context does; whatever was cloned so far is removed
+	Context context.Context
+}
+
+// localRemotePath returns the directory a file:// URL or plain path names,
+// rejecting URLs that need a network transport
+func localRemotePath(url string) (string, error) {
+	if path, ok := strings.CutPrefix(url, "file://"); ok {
+		return path, nil
+	}
+	// scheme://host/path and scp-like host:path name network remotes
+	if strings.Contains(url, "://") {
+		return "", fmt.Errorf("unsupported transport for %s: only local paths, file:// and git:// URLs are supported", url)
+	}
+	if colon := strings.Index(url, ":"); colon > 1 && !strings.Contains(url[:colon], "/") {
+		return "", fmt.Errorf("unsupported transport for %s: only local paths, file:// and git:// URLs are supported", url)
+	}
+	return url, nil
+}
+
+// openLocalRemote opens the repository at a local path or file:// URL, given
+// either as its working tree or as its .gvc directory, confined to the
+// namespace EnvNamespace names
+func openLocalRemote(url string) (*Repository, error) {
+	path, err := localRemotePath(url)
+	if err != nil {
+		return nil, err
+	}
+	var remote *Repository
+	switch {
+	case isDir(filepath.Join(path, GvcDir)):
+		remote, err = Open(path)
+	case filepath.Base(filepath.Clean(path)) == GvcDir && isDir(path):
+		remote, err = Open(filepath.Dir(filepath.Clean(path)))
+	default:
+		return nil, fmt.Errorf("%s does not appear to be a gvc repository", url)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := remote.openNamespace(); err != nil {
+		return nil, err
+	}
+	return remote, nil
+}
+
+// transferObjects makes every object of src available in dst. Packs and loose
+// objects dst lacks are hardlinked, falling back to a copy across filesystems
+// or when hardlink is false; objects are immutable, so sharing inodes is safe.
+// With verify, nothing is transferred unless every object passes fsckTransfer.
+// The large-file store content dst lacks comes along.
+func transferObjects(src, dst *Repository, hardlink, verify bool) error {
+	if src.Format != dst.Format {
+		return fmt.Errorf("cannot transfer objects: %s uses %s but %s uses %s",
+			src.Root, src.Format.Name, dst.Root, dst.Format.Name)
+	}
+	same, err := sameEncryption(src, dst)
+	if err != nil {
+		return err
+	}
+	// Object files are only shared as they are between filesystem stores
+	// sealed alike; otherwise each object is read and stored again
+	if !src.usesFileStore() || !dst.usesFileStore() || !same {
+		if err := copyStoredObjects(src, dst, verify); err != nil {
+			return err
+		}
+		return transferLargeFiles(src, dst, false)
+	}
+
+	packs, err := src.loadPacks()
+	if err != nil {
+		return err
+	}
+	var newPacks []string
+	packShas := make(map[string][]string)
+	sending := make(map[string]bool)
+	for _, pack := range packs {
+		name := filepath.Base(strings.TrimSuffix(pack.path, ".pack"))
+		if _, err := os.Stat(dst.gitPath(PackDir, name+".idx")); err == nil {
+			continue
+		}
+		newPacks = append(newPacks, name)
+		packShas[name] = pack.shas
+		for _, sha := range pack.shas {
+			sending[sha] = true
+		}
+	}
+	loose, err := src.listLooseObjects()
+	if err != nil {
+		return err
+	}
+	var newLoose []string
+	for _, sha := range loose {
+		if !sending[sha] && !dst.objectExists(sha) {
+			newLoose = append(newLoose, sha)
+			sending[sha] = true
+		}
+	}
+	dst.transfer.addObjects(src, newLoose)
+
+	if verify {
+		if err := fsckTransfer(src, dst, sending, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range newPacks {
+		// The index goes last so readers never find an index without its
+		// pack. A pack that cannot be linked is copied so that an
+		// interrupted copy resumes.
+		srcPack, dstPack := src.gitPath(PackDir, name+".pack"), dst.gitPath(PackDir, name+".pack")
+		if err := dst.mkdirShared(filepath.Dir(dstPack)); err != nil {
+			return fmt.Errorf("failed to create pack directory: %w", err)
+		}
+		placed, copied := false, false
+		if _, err := os.Stat(dstPack); err != nil {
+			if hardlink && os.Link(srcPack, dstPack) == nil {
+				dst.transfer.addLinked(dstPack)
+				placed = true
+			} else if copied, err = dst.copyPack(srcPack, dstPack); err != nil {
+				return err
+			}
+			placed = placed || copied
+		}
+		if placed {
+			dst.transfer.addObjects(src, packShas[name])
+		}
+		// A linked pack is the source's file, whose permissions stay its own
+		if copied {
+			if err := dst.adjustSharedPerm(dstPack); err != nil {
+				return err
+			}
+		}
+		if err := dst.linkOrCopyFile(src.gitPath(PackDir, name+".idx"), dst.gitPath(PackDir, name+".idx"), hardlink); err != nil {
+			return err
+		}
+	}
+	dst.resetPacks()
+	for _, sha := range newLoose {
+		if err := src.canceled(); err != nil {
+			return err
+		}
+		if err := dst.linkOrCopyFile(src.objectPath(sha), dst.objectPath(sha), hardlink); err != nil {
+			return err
+		}
+	}
+
+	if len(newPacks) > 0 || len(newLoose) > 0 {
+		how := "Copied"
+		if hardlink {
+			how = "Linked"
+		}
+		fmt.Fprintf(dst.Out, "%s %d packs and %d loose objects\n", how, len(newPacks), len(newLoose))
+	}
+	return transferLargeFiles(src, dst, hardlink)
+}
+
+// copyStoredObjects copies the objects dst lacks one at a time, for object
+// stores whose files cannot be linked
+func copyStoredObjects(src, dst *Repository, verify bool) error {
+	shas, err := src.Objects.List()
+	if err != nil {
+		return fmt.Errorf("failed to list objects: %w", err)
+	}
+	sending := make(map[string]bool)
+	var missing []string
+	for _, sha := range shas {
+		if !dst.objectExists(sha) {
+			sending[sha] = true
+			missing = append(missing, sha)
+		}
+	}
+	if verify {
+		if err := fsckTransfer(src, dst, sending, nil); err != nil {
+			return err
+		}
+	}
+	if err := dst.checkWritable(); err != nil {
+		return err
+	}
+	for _, sha := range missing {
+		if err := src.canceled(); err != nil {
+			return err
+		}
+		objectType, content, err := src.ReadObject(sha)
+		if err != nil {
+			return err
+		}
+		if err := dst.Objects.Put(sha, objectType, content); err != nil {
+			return fmt.Errorf("failed to write object %s: %w", sha, err)
+		}
+		dst.transfer.add(1, int64(len(content)), int64(len(content)), 0)
+		if err := dst.transfer.pace(); err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		fmt.Fprintf(dst.Out, "Copied %d objects\n", len(missing))
+	}
+	return nil
+}
+
+// fsckTransfer checks the objects src is about to send to dst the way fsck
+// does, strictly: each must hash to its name, parse without errors or
+// warnings and point only at objects that dst has or is receiving. The
+// parents of shallow commits, which a shallow fetch leaves behind, need not
+// be sent.
+func fsckTransfer(src, dst *Repository, sending, shallow map[string]bool) error {
+	var problems []string
+	problem := func(sha, format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	shas := make([]string, 0, len(sending))
+	for sha := range sending {
+		shas = append(shas, sha)
+	}
+	sort.Strings(shas)
+	for _, sha := range shas {
+		if err := src.canceled(); err != nil {
+			return err
+		}
+		objectType, content, err := src.loadRawObject(sha)
+		if err != nil {
+			problem(sha, "%s: corrupt object: %v", sha, err)
+			continue
+		}
+		if actual := src.Format.Hash(objectType, content); actual != sha {
+			problem(sha, "%s: hash mismatch: content hashes to %s", sha, actual)
+			continue
+		}
+		var links []fsckLink
+		switch objectType {
+		case object.BlobObject:
+		case object.TreeObject:
+			links = src.fsckTree(sha, content, problem, problem)
+		case object.CommitObject:
+			links = src.fsckCommit(sha, content, problem, problem)
+		default:
+			problem(sha, "%s: unknown object type %q", sha, objectType)
+		}
+		for _, link := range links {
+			if shallow[sha] && objectType == object.CommitObject && link.objectType == object.CommitObject {
+				continue
+			}
+			if !sending[link.sha] && !dst.objectExists(link.sha) {
+				problem(sha, "%s: points to missing %s %s", sha, link.objectType, link.sha)
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("refusing to transfer objects from %s: %d problems found\n\t%s",
+			src.Root, len(problems), strings.Join(problems, "\n\t"))
+	}
+	return nil
+}
+
+// fsckOnTransfer reports whether objects arriving through key (fetch.fsckObjects
+// or receive.fsckObjects) must be checked; transfer.fsckObjects sets the default
+func (r *Repository) fsckOnTransfer(key string) (bool, error) {
+	fallback, err := r.getConfigBool("transfer.fsckObjects", false)
+	if err != nil {
+		return false, err
+	}
+	return r.getConfigBool(key, fallback)
+}
+
+// linkOrCopyFile places src at dst, a path in r, by hardlink when allowed
+// and possible
+func (r *Repository) linkOrCopyFile(src, dst string, hardlink bool) error {
+	if err := r.mkdirShared(filepath.Dir(dst)); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", dst, err)
+	}
+	if hardlink {
+		err := os.Link(src, dst)
+		if err == nil {
+			r.transfer.addLinked(dst)
+			return nil
+		}
+		if os.IsExist(err) {
+			return nil
+		}
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	defer in.Close()
+	tmp, err := createTemp(filepath.Dir(dst), "tmp_")
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(r.transfer.writer(tmp), in); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	if err := r.adjustSharedPerm(tmp.Name()); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return nil
+}
+
+// refspec maps source refs onto destination refs, e.g. +refs/heads/*:refs/remotes/origin/*
+type refspec struct {
+	src, dst string
+	force    bool
+}
+
+// parseRefspec parses "[+]<src>[:<dst>]"; a missing destination maps a ref onto itself
+func parseRefspec(spec string) (refspec, error) {
+	var rs refspec
+	spec, rs.force = strings.CutPrefix(spec, "+")
+	src, dst, found := strings.Cut(spec, ":")
+	if !found {
+		dst = src
+	}
+	if src == "" || dst == "" || strings.Count(src, "*") != strings.Count(dst, "*") || strings.Count(src, "*") > 1 {
+		return refspec{}, fmt.Errorf("invalid refspec: %s", spec)
+	}
+	rs.src, rs.dst = src, dst
+	return rs, nil
+}
+
+// match returns the destination for ref when it matches the source pattern
+func (rs refspec) match(ref string) (string, bool) {
+	prefix, suffix, glob := strings.Cut(rs.src, "*")
+	if !glob {
+		return rs.dst, ref == rs.src
+	}
+	if !strings.HasPrefix(ref, prefix) || !strings.HasSuffix(ref, suffix) || len(ref) < len(prefix)+len(suffix) {
+		return "", false
+	}
+	middle := ref[len(prefix) : len(ref)-len(suffix)]
+	return strings.Replace(rs.dst, "*", middle, 1), true
+}
+
+// refUpdate is one ref moved by a fetch or push
+type refUpdate struct {
+	src, dst       string
+	oldSHA, newSHA string
+	forced         bool   // not a fast-forward, allowed by a forcing refspec
+	rejected       string // why the update was refused, if it was
+}
+
+// planRefUpdate works out how moving dst to newSHA in refs would go, checking
+// for a fast-forward in history, which must hold newSHA. An old commit that
+// history lacks cannot be an ancestor of the new one.
+func planRefUpdate(refs, history *Repository, src, dst, newSHA string, force bool) (*refUpdate, error) {
+	oldSHA, err := refs.ReadRef(dst)
+	if err != nil {
+		return nil, err
+	}
+	update := &refUpdate{src: src, dst: dst, oldSHA: oldSHA, newSHA: newSHA}
+	if oldSHA == "" || oldSHA == newSHA {
+		return update, nil
+	}
+	fastForward := false
+	if history.objectExists(oldSHA) && history.objectExists(newSHA) {
+		if fastForward, err = history.IsAncestor(oldSHA, newSHA); err != nil {
+			return nil, err
+		}
+	}
+	if !fastForward {
+		if force {
+			update.forced = true
+		} else {
+			update.rejected = "non-fast-forward"
+		}
+	}
+	return update, nil
+}
+
+// shortRefName drops the refs/heads/, refs/tags/ or refs/remotes/ prefix of a ref
+func shortRefName(ref string) string {
+	for _, prefix := range []string{HeadsDir + "/", "refs/tags/", RemotesDir + "/"} {
+		if short, ok := strings.CutPrefix(ref, prefix); ok {
+			return short
+		}
+	}
+	return ref
+}
+
+// String renders the update the way fetch and push report it
+func (u *refUpdate) String() string {
+	var flag, summary, note string
+	switch {
+	case u.rejected != "":
+		flag, summary, note = "!", "[rejected]", " ("+u.rejected+")"
+	case u.oldSHA == u.newSHA:
+		flag, summary = "=", "[up to date]"
+	case u.oldSHA == "" && strings.HasPrefix(u.dst, "refs/tags/"):
+		flag, summary = "*", "[new tag]"
+	case u.oldSHA == "":
+		flag, summary = "*", "[new branch]"
+	case u.forced:
+		flag, summary, note = "+", u.oldSHA[:7]+"..."+u.newSHA[:7], " (forced update)"
+	default:
+		flag, summary = " ", u.oldSHA[:7]+".."+u.newSHA[:7]
+	}
+	return fmt.Sprintf(" %s %-17s %s -> %s%s", flag, summary, shortRefName(u.src), shortRefName(u.dst), note)
+}
+
+// resolveRemote returns the URL for a configured remote name, or takes the
+// argument itself as a URL. The name is empty when a URL was given.
+func (r *Repository) resolveRemote(arg string, push bool) (name, url string, err error) {
+	remotes, err := r.Remotes()
+	if err != nil {
+		return "", "", err
+	}
+	for _, remote := range remotes {
+		if remote.Name == arg {
+			if push {
+				return remote.Name, remote.PushURL, nil
+			}
+			return remote.Name, remote.URL, nil
+		}
+	}
+	if arg == "origin" {
+		return "", "", fmt.Errorf("no such remote: %s", arg)
+	}
+	return "", arg, nil
+}
+
+// fetchRefspecs returns the remote.<name>.fetch refspecs, defaulting to every branch
+func (r *Repository) fetchRefspecs(name string) ([]refspec, error) {
+	entries, err := r.ReadConfigEntries()
+	if err != nil {
+		return nil, err
+	}
+	var specs []refspec
+	for _, entry := range entries {
+		if entry[0] != "remote."+name+".fetch" {
+			continue
+		}
+		spec, err := parseRefspec(entry[1])
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	if len(specs) == 0 {
+		specs = append(specs, refspec{src: HeadsDir + "/*", dst: RemotesDir + "/" + name + "/*", force: true})
+	}
+	return specs, nil
+}
+
+// Fetch copies the objects of a remote, given by name or as a local path,
+// file:// or git:// URL, and updates the remote-tracking refs its fetch refspecs map,
+// and refs/remotes/<name>/HEAD as remote.<name>.followRemoteHEAD says. The
+// remote may also be a bundle file. Fetching from a URL only records the
+// remote HEAD in FETCH_HEAD.
+func (r *Repository) Fetch(remote string, opts FetchOptions) error {
+	name, url, err := r.resolveRemote(remote, false)
+	if err != nil {
+		return err
+	}
+	refs, head, headBranch, history, err := r.fetchObjects(url, opts)
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		if head == "" {
+			return fmt.Errorf("%s has no commits", url)
+		}
+		if opts.DryRun {
+			fmt.Fprintf(r.Out, "From %s\n * branch            HEAD       -> FETCH_HEAD\n", url)
+			return nil
+		}
+		if err := writeFileLocked(r.gitPath(FetchHeadFile), []byte(head+"\n")); err != nil {
+			return fmt.Errorf("failed to write FETCH_HEAD: %w", err)
+		}
+		fmt.Fprintf(r.Out, "From %s\n * branch            HEAD       -> FETCH_HEAD\n", url)
+		return nil
+	}
+
+	specs, err := r.fetchRefspecs(name)
+	if err != nil {
+		return err
+	}
+	var rejected bool
+	header := false
+	for _, ref := range refs {
+		for _, spec := range specs {
+			dst, ok := spec.match(ref.Name)
+			if !ok {
+				continue
+			}
+			update, err := planRefUpdate(r, history, ref.Name, dst, ref.SHA, spec.force)
+			if err != nil {
+				return err
+			}
+			if update.oldSHA == update.newSHA {
+				continue
+			}
+			if !header {
+				fmt.Fprintf(r.Out, "From %s\n", url)
+				header = true
+			}
+			fmt.Fprintln(r.Out, update)
+			if update.rejected != "" {
+				rejected = true
+				continue
+			}
+			if opts.DryRun {
+				continue
+			}
+			if err := r.writeRef(dst, ref.SHA, "fetch: "+update.summaryVerb()); err != nil {
+				return err
+			}
+		}
+	}
+	if rejected {
+		return fmt.Errorf("some refs from %s were not updated", url)
+	}
+	if opts.DryRun {
+		return nil
+	}
+	return r.followRemoteHead(name, headBranch)
+}
+
+// fetchObjects copies the objects of the repository or bundle file at url
+// into r and returns the refs it offers, the commit its HEAD is on, the
+// branch its HEAD is on ("" when detached or unknown) and the repository
+// whose history shows whether a ref update is a fast-forward.
+// With DryRun nothing is copied, and a bundle is only checked.
+func (r *Repository) fetchObjects(url string, opts FetchOptions) (refs []Ref, head, headBranch string, history *Repository, err error) {
+	if opts.Depth < 0 {
+		return nil, "", "", nil, fmt.Errorf("depth %d is not a positive number", opts.Depth)
+	}
+	shallow, err := r.IsShallow()
+	if err != nil {
+		return nil, "", "", nil, err
+	}
+	if opts.Unshallow && !shallow {
+		return nil, "", "", nil, errNotShallow
+	}
+	if path, err := localRemotePath(url); err == nil && isBundleFile(path) {
+		if opts.Depth > 0 || opts.Unshallow {
+			return nil, "", "", nil, errors.New("shallow fetches from bundles are not supported")
+		}
+		var bundleRefs []Ref
+		if opts.DryRun {
+			var bundle *Bundle
+			if bundle, err = r.VerifyBundle(path); err == nil {
+				bundleRefs = bundle.Refs
+			}
+		} else {
+			err = r.meterTransfer(r, "Received", opts.LimitRate, func() (err error) {
+				bundleRefs, err = r.Unbundle(path)
+				return err
+			})
+		}
+		if err != nil {
+			return nil, "", "", nil, err
+		}
+		for _, ref := range bundleRefs {
+			if ref.Name == "HEAD" {
+				head = ref.SHA
+			} else {
+				refs = append(refs, ref)
+			}
+		}
+		// A bundle of a single branch needs no HEAD to be fetched from
+		if head == "" && len(refs) == 1 {
+			head = refs[0].SHA
+		}
+		headBranch = (&Bundle{Refs: bundleRefs}).headBranch()
+		return refs, head, headBranch, r, nil
+	}
+	if isGitURL(url) {
+		err = r.meterTransfer(r, "Received", opts.LimitRate, func() (err error) {
+			refs, head, headBranch, err = r.fetchGit(url, opts, shallow)
+			return err
+		})
+		if err != nil {
+			return nil, "", "", nil, err
+		}
+		return refs, head, headBranch, r, nil
+	}
+
+	src, err := openLocalRemote(url)
+	if err != nil {
+		return nil, "", "", nil, err
+	}
+	src.Context = r.Context
+	if head, err = src.HeadCommit(); err != nil {
+		return nil, "", "", nil, err
+	}
+	if head != "" {
+		if headBranch, err = src.HeadRef(); err != nil {
+			return nil, "", "", nil, err
+		}
+	}
+	if refs, err = src.listRefs(); err != nil {
+		return nil, "", "", nil, err
+	}
+	history = r
+	if opts.DryRun {
+		// The new commits are only in the remote
+		history = src
+		return refs, head, headBranch, history, nil
+	}
+	verify, err := r.fsckOnTransfer("fetch.fsckObjects")
+	if err != nil {
+		return nil, "", "", nil, err
+	}
+	srcShallow, err := src.IsShallow()
+	if err != nil {
+		return nil, "", "", nil, err
+	}
+	// Only the history that is wanted is copied when either side is or
+	// becomes shallow; otherwise every object goes across
+	err = r.meterTransfer(r, "Received", opts.LimitRate, func() error {
+		if opts.Depth == 0 && !shallow && !srcShallow {
+			return transferObjects(src, r, !opts.NoHardlinks, verify)
+		}
+		tips := make([]string, 0, len(refs)+1)
+		for _, ref := range refs {
+			tips = append(tips, ref.SHA)
+		}
+		if head != "" {
+			tips = append(tips, head)
+		}
+		return transferShallow(src, r, tips, opts.Depth, opts.Unshallow, verify)
+	})
+	if err != nil {
+		return nil, "", "", nil, err
+	}
+	return refs, head, headBranch, history, nil
+}
+
+// summaryVerb describes the update in a reflog message
+func (u *refUpdate) summaryVerb() string {
+	switch {
+	case u.oldSHA == "":
+		return "storing head"
+	case u.forced:
+		return "forced-update"
+	}
+	return "fast-forward"
+}
+
+// pushRefspecs turns push arguments into refspecs. Without any, the remote's
+// push refspecs or push.default decide what is pushed.
+func (r *Repository) pushRefspecs(name string, dst *Repository, args []string, force bool) ([]refspec, error) {
+	if len(args) == 0 {
+		var err error
+		if args, err = r.defaultPushRefspecs(name, dst); err != nil {
+			return nil, err
+		}
+	}
+
+	var specs []refspec
+	for _, arg := range args {
+		spec, err := parseRefspec(arg)
+		if err != nil {
+			return nil, err
+		}
+		spec.force = spec.force || force
+		if strings.Contains(spec.src, "*") {
+			expanded, err := r.expandPushRefspec(spec)
+			if err != nil {
+				return nil, err
+			}
+			specs = append(specs, expanded...)
+			continue
+		}
+
+		// Short names resolve locally for the source and name a branch on the remote
+		if spec.src == "HEAD" && !strings.Contains(arg, ":") {
+			branch, err := r.HeadRef()
+			if err != nil {
+				return nil, err
+			}
+			if branch == "" {
+				return nil, fmt.Errorf("HEAD is detached; use HEAD:<branch> to push it")
+			}
+			spec.dst = branch
+		}
+		if spec.src != "HEAD" {
+			if spec.src, err = r.ExpandRefName(spec.src); err != nil {
+				return nil, err
+			}
+		}
+		if !strings.HasPrefix(spec.dst, "refs/") {
+			if !strings.Contains(arg, ":") {
+				spec.dst = spec.src
+			} else {
+				spec.dst = HeadsDir + "/" + spec.dst
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// expandPushRefspec turns a wildcard refspec into one refspec per matching local ref
+func (r *Repository) expandPushRefspec(spec refspec) ([]refspec, error) {
+	refs, err := r.listRefs()
+	if err != nil {
+		return nil, err
+	}
+	var specs []refspec
+	for _, ref := range refs {
+		if dst, ok := spec.match(ref.Name); ok {
+			specs = append(specs, refspec{src: ref.Name, dst: dst, force: spec.force})
+		}
+	}
+	return specs, nil
+}
+
+// Push sends the refs named by refspecs (the current branch by default) to a
+// remote, given by name or as a local path or file:// URL. Updates that are
+// not fast-forwards are refused unless forced, as is moving the branch checked
+// out in the remote's working tree. A dry run stops after reporting them. An
+// empty remote means DefaultPushRemote, and no refspecs means whatever
+// remote.<name>.push or push.default selects.
+func (r *Repository) Push(remote string, refspecs []string, opts PushOptions) error {
+	if remote == "" {
+		var err error
+		if remote, err = r.DefaultPushRemote(); err != nil {
+			return err
+		}
+	}
+	name, url, err := r.resolveRemote(remote, true)
+	if err != nil {
+		return err
+	}
+	if isGitURL(url) {
+		return fmt.Errorf("cannot push to %s: git:// serves clones and fetches only", url)
+	}
+	dst, err := openLocalRemote(url)
+	if err != nil {
+		return err
+	}
+	dst.Context = r.Context
+	specs, err := r.pushRefspecs(name, dst, refspecs, opts.Force)
+	if err != nil {
+		return err
+	}
+
+	remoteHead, err := dst.HeadRef()
+	if err != nil {
+		return err
+	}
+
+	// Decide every update up front, the way the receiving side would
+	var updates []*refUpdate
+	rejected := false
+	for _, spec := range specs {
+		sha, err := r.ReadRef(spec.src)
+		if err != nil {
+			return err
+		}
+		if sha == "" {
+			return fmt.Errorf("src refspec %s does not match any commit", spec.src)
+		}
+		update, err := planRefUpdate(dst, r, spec.src, spec.dst, sha, spec.force)
+		if err != nil {
+			return err
+		}
+		if update.oldSHA == update.newSHA {
+			continue
+		}
+		// The HEAD of a namespace is not checked out anywhere
+		if update.rejected == "" && update.dst == remoteHead && dst.namespace == "" {
+			update.rejected = "branch is currently checked out"
+		}
+		rejected = rejected || update.rejected != ""
+		updates = append(updates, update)
+	}
+
+	if opts.NoVerify {
+		if err := r.checkNoVerify(); err != nil {
+			return err
+		}
+	} else if err := r.runPrePushHook(name, url, updates); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(r.Out, "To %s\n", url)
+	if len(updates) == 0 {
+		fmt.Fprintln(r.Out, "Everything up-to-date")
+		return nil
+	}
+	for _, update := range updates {
+		fmt.Fprintln(r.Out, update)
+	}
+	if !opts.DryRun {
+		if err := r.applyPush(name, dst, updates, opts); err != nil {
+			return err
+		}
+	}
+	if rejected {
+		return fmt.Errorf("failed to push some refs to %s", url)
+	}
+	return nil
+}
+
+// applyPush sends the objects for the accepted updates and moves the remote
+// refs, keeping the matching remote-tracking refs of a named remote in step
+func (r *Repository) applyPush(name string, dst *Repository, updates []*refUpdate, opts PushOptions) error {
+	// The sender checks what it uploads; the receiver checks what it accepts
+	verify, err := r.getConfigBool("transfer.fsckObjects", false)
+	if err != nil {
+		return err
+	}
+	if !verify {
+		if verify, err = dst.fsckOnTransfer("receive.fsckObjects"); err != nil {
+			return err
+		}
+	}
+
+	sent := false
+	for _, update := range updates {
+		if update.rejected != "" {
+			continue
+		}
+		if !sent {
+			err := r.meterTransfer(dst, "Sent", opts.LimitRate, func() error {
+				return transferObjects(r, dst, !opts.NoHardlinks, verify)
+			})
+			if err != nil {
+				return err
+			}
+			sent = true
+		}
+		if err := dst.writeRef(update.dst, update.newSHA, "push"); err != nil {
+			return err
+		}
+		if err := dst.initNamespaceHead(update.dst); err != nil {
+			return err
+		}
+		if name != "" && strings.HasPrefix(update.dst, HeadsDir+"/") {
+			tracking := RemotesDir + "/" + name + "/" + strings.TrimPrefix(update.dst, HeadsDir+"/")
+			if err := r.writeRef(tracking, update.newSHA, "update by push"); err != nil {
+				return err
+			}
+		}
+		if opts.SetUpstream && name != "" && strings.HasPrefix(update.src, HeadsDir+"/") && strings.HasPrefix(update.dst, HeadsDir+"/") {
+			branch := strings.TrimPrefix(update.src, HeadsDir+"/")
+			if err := r.SetUpstream(branch, name, update.dst); err != nil {
+				return err
+			}
+			fmt.Fprintf(r.Out, "branch '%s' set up to track '%s/%s'.\n", branch, name, strings.TrimPrefix(update.dst, HeadsDir+"/"))
+		}
+	}
+	return nil
+}
+
+// Clone creates a repository in dir holding all objects and branches of the
+// repository or bundle file at url, or served at a git:// URL, registers it as the remote "origin" and
+// checks out its current branch. An empty dir is named after the source.
+func Clone(url, dir string, opts CloneOptions) (*Repository, error) {
+	var root, branch string
+	var format *object.Format
+	var src *Repository
+	if opts.Depth < 0 {
+		return nil, fmt.Errorf("depth %d is not a positive number", opts.Depth)
+	}
+	if path, err := localRemotePath(url); err == nil && isBundleFile(path) {
+		if opts.Depth > 0 {
+			return nil, errors.New("shallow clones from bundles are not supported")
+		}
+		bundle, err := ReadBundleHeader(path)
+		if err != nil {
+			return nil, err
+		}
+		if root, err = filepath.Abs(path); err != nil {
+			return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+		branch, format = bundle.headBranch(), bundle.Format
+		if !strings.HasPrefix(url, "file://") {
+			url = root
+		}
+		root = strings.TrimSuffix(root, ".bundle")
+	} else if isGitURL(url) {
+		adv, err := lsRemote(opts.Context, url)
+		if err != nil {
+			return nil, err
+		}
+		if format, err = adv.format(); err != nil {
+			return nil, err
+		}
+		if branch, root = adv.headBranch, gitURLPath(url); root == "/" {
+			return nil, fmt.Errorf("%s names no repository", url)
+		}
+	} else {
+		var err error
+		if src, err = openLocalRemote(url); err != nil {
+			return nil, err
+		}
+		src.Context = opts.Context
+		if branch, err = src.HeadRef(); err != nil {
+			return nil, err
+		}
+		root, format = src.Root, src.Format
+		// A plain path is recorded absolutely so the clone works from any directory
+		if !strings.HasPrefix(url, "file://") {
+			url = src.Root
+		}
+	}
+	if dir == "" {
+		dir = filepath.Base(root)
+	}
+	if state, ok, err := readCloneState(dir); err != nil {
+		return nil, err
+	} else if ok {
+		return resumeClone(dir, branch, url, format, state, opts)
+	}
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("destination path '%s' already exists and is not an empty directory", dir)
+	}
+
+	_, statErr := os.Stat(dir)
+	r, err := Init(dir, format)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Out != nil {
+		r.Out = opts.Out
+	}
+	r.Progress, r.Verbose, r.Context = opts.Progress, opts.Verbose, opts.Context
+	if opts.Observer != nil {
+		r.Observe(*opts.Observer)
+	}
+	if src != nil {
+		if err = copyLargeFileConfig(src, r); err == nil {
+			err = copyEncryptionConfig(src, r)
+		}
+	}
+	if err == nil {
+		err = r.writeCloneState(cloneState{url: url, depth: opts.Depth})
+	}
+	if err == nil {
+		if err = r.fetchClone(url, opts); err != nil {
+			// The objects copied so far are kept for the clone to resume from
+			return nil, interruptedClone(dir, err)
+		}
+		err = r.finishClone(branch, url, opts)
+	}
+	if err != nil {
+		// Leave nothing half-cloned behind
+		if os.IsNotExist(statErr) {
+			os.RemoveAll(dir)
+		} else {
+			os.RemoveAll(r.GitDir)
+		}
+		return nil, err
+	}
+	return r, nil
+}
+
+// resumeClone continues the clone of url into dir that was interrupted while
+// fetching. Packs copied in full are kept, a partly copied pack continues
+// where it stopped, and the refs are negotiated again in case the source
+// moved on.
+func resumeClone(dir, branch, url string, format *object.Format, state cloneState, opts CloneOptions) (*Repository, error) {
+	if state.url != url || state.depth != opts.Depth {
+		return nil, fmt.Errorf("'%s' holds an interrupted clone of %s with depth %d; remove it to clone anything else", dir, state.url, state.depth)
+	}
+	r, err := Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	if r.Format != format {
+		return nil, fmt.Errorf("'%s' holds an interrupted clone with %s objects, but %s uses %s", dir, r.Format.Name, url, format.Name)
+	}
+	if opts.Out != nil {
+		r.Out = opts.Out
+	}
+	r.Progress, r.Verbose, r.Context = opts.Progress, opts.Verbose, opts.Context
+	if opts.Observer != nil {
+		r.Observe(*opts.Observer)
+	}
+	fmt.Fprintf(r.Out, "Resuming the interrupted clone of %s\n", url)
+	if err := r.fetchClone(url, opts); err != nil {
+		return nil, interruptedClone(dir, err)
+	}
+	if err := r.finishClone(branch, url, opts); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// interruptedClone wraps the error that stopped a clone while fetching, which
+// running the same clone again recovers from
+func interruptedClone(dir string, err error) error {
+	return fmt.Errorf("clone of '%s' interrupted, run the same clone again to resume it: %w", dir, err)
+}
+
+// fetchClone registers url as origin and fetches everything from it into a
+// fresh repository
+func (r *Repository) fetchClone(url string, opts CloneOptions) error {
+	if err := r.SetConfig("remote.origin.url", url); err != nil {
+		return err
+	}
+	if err := r.SetConfig("remote.origin.fetch", "+"+HeadsDir+"/*:"+RemotesDir+"/origin/*"); err != nil {
+		return err
+	}
+	return r.Fetch("origin", FetchOptions{NoHardlinks: opts.NoHardlinks, Depth: opts.Depth, LimitRate: opts.LimitRate})
+}
+
+// finishClone checks out branch, the branch the source's HEAD is on, once
+// everything is fetched, and drops the record of the clone in progress
+func (r *Repository) finishClone(branch, url string, opts CloneOptions) error {
+	if err := os.Remove(r.gitPath(CloneStateFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove clone state: %w", err)
+	}
+	headSHA := ""
+	var err error
+	if branch != "" {
+		if headSHA, err = r.ReadRef(RemotesDir + "/origin/" + strings.TrimPrefix(branch, HeadsDir+"/")); err != nil {
+			return err
+		}
+	}
+	if headSHA == "" {
+		fmt.Fprintln(r.Out, "warning: You appear to have cloned an empty repository.")
+		return nil
+	}
+
+	if err := r.writeRef(branch, headSHA, "clone: from "+url); err != nil {
+		return err
+	}
+	if err := r.SetUpstream(strings.TrimPrefix(branch, HeadsDir+"/"), "origin", branch); err != nil {
+		return err
+	}
+	if err := writeFileLocked(r.gitPath(HeadFile), []byte("ref: "+branch+"\n")); err != nil {
+		return fmt.Errorf("failed to write HEAD: %w", err)
+	}
+	if err := r.appendReflog("HEAD", "", headSHA, "clone: from "+url); err != nil {
+		return err
+	}
+	head, err := r.headEntries()
+	if err != nil {
+		return err
+	}
+	if err := r.checkoutEntries(map[string]IndexEntry{}, head); err != nil {
+		return err
+	}
+	if opts.RecurseSubmodules {
+		return r.UpdateSubmodules(SubmoduleUpdateOptions{Init: true, Recursive: true, NoHardlinks: opts.NoHardlinks})
+	}
+	return nil
+}
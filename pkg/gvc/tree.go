@@ -0,0 +1,391 @@
+package gvc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// createTreeFromIndex creates a tree object from the current index. The
+// index is a full snapshot, so this is the tree of the next commit; before
+// the first commit it must not be empty. Trees in the index's tree cache are
+// reused, and the ones built are added to it.
+func (r *Repository) createTreeFromIndex() (string, error) {
+	index, err := r.ReadIndex()
+	if err != nil {
+		return "", err
+	}
+
+	if len(index.Entries) == 0 {
+		headSHA, err := r.HeadCommit()
+		if err != nil {
+			return "", err
+		}
+		if headSHA == "" {
+			return "", errors.New("nothing to commit (staging area is empty)")
+		}
+	}
+
+	cache := maps.Clone(index.TreeCache)
+	if cache == nil {
+		cache = make(map[string]string)
+	}
+	// Trees rebuilt with the same entries as before are not stored again
+	var treeSHA string
+	err = r.batchObjects(func() error {
+		treeSHA, err = r.buildTreeLevel(index.Entries, "", cache)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	if !maps.Equal(cache, index.TreeCache) {
+		r.saveTreeCache(index, cache)
+	}
+	return treeSHA, nil
+}
+
+// saveTreeCache records the trees built for index in its tree cache, unless
+// the index changed meanwhile. The cache only saves work, so when the index
+// cannot be written it is left as it is.
+func (r *Repository) saveTreeCache(index *Index, cache map[string]string) {
+	if r.ReadOnly {
+		return
+	}
+	l, err := r.lockGitFile(r.indexPath())
+	if err != nil {
+		return
+	}
+	defer l.unlock()
+	current, err := r.ReadIndex()
+	if err != nil || len(current.Entries) != len(index.Entries) {
+		return
+	}
+	for i, entry := range current.Entries {
+		if entry.Path != index.Entries[i].Path || entry.SHA != index.Entries[i].SHA || entry.Mode != index.Entries[i].Mode {
+			return
+		}
+	}
+	current.TreeCache = cache
+	if data, err := r.encodeIndex(current); err == nil {
+		l.commit(data)
+	}
+}
+
+// normalizePath converts a user supplied path into the slash-separated form used inside trees
+func normalizePath(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// buildTree writes nested tree objects for a flat list of entries and returns the root tree SHA
+func (r *Repository) buildTree(entries []IndexEntry) (string, error) {
+	return r.buildTreeLevel(entries, "", nil)
+}
+
+// buildTreeLevel writes the tree for the entries below prefix, recursing into
+// subdirectories. A non-nil cache maps directories to trees already written
+// for them, which are reused if they exist, and gains the trees written.
+func (r *Repository) buildTreeLevel(entries []IndexEntry, prefix string, cache map[string]string) (string, error) {
+	dir := strings.TrimSuffix(prefix, "/")
+	if sha, ok := cache[dir]; ok && r.objectExists(sha) {
+		return sha, nil
+	}
+	var treeEntries []object.TreeEntry
+	subdirs := make(map[string][]IndexEntry)
+	var subdirNames []string
+
+	for _, entry := range entries {
+		rel := strings.TrimPrefix(normalizePath(entry.Path), prefix)
+		if i := strings.Index(rel, "/"); i >= 0 {
+			name := rel[:i]
+			if _, ok := subdirs[name]; !ok {
+				subdirNames = append(subdirNames, name)
+			}
+			subdirs[name] = append(subdirs[name], entry)
+			continue
+		}
+
+		treeEntries = append(treeEntries, object.TreeEntry{
+			Mode: entry.Mode,
+			Name: rel,
+			SHA:  entry.SHA,
+			Type: object.BlobObject,
+		})
+	}
+
+	for _, name := range subdirNames {
+		subtreeSHA, err := r.buildTreeLevel(subdirs[name], prefix+name+"/", cache)
+		if err != nil {
+			return "", err
+		}
+		treeEntries = append(treeEntries, object.TreeEntry{
+			Mode: "40000",
+			Name: name,
+			SHA:  subtreeSHA,
+			Type: object.TreeObject,
+		})
+	}
+
+	sha, err := r.writeTreeEntries(treeEntries)
+	if err != nil {
+		return "", err
+	}
+	if cache != nil {
+		cache[dir] = sha
+	}
+	return sha, nil
+}
+
+// treeSortName is the key Git sorts tree entries by: the name, with a "/"
+// after a subtree's, so "a.txt" comes before the directory "a"
+func treeSortName(entry object.TreeEntry) string {
+	if entry.Type == object.TreeObject {
+		return entry.Name + "/"
+	}
+	return entry.Name
+}
+
+// validTreeEntryName reports whether name can be an entry of a tree: an
+// empty, "." or ".." name, or one with a slash, would make a path that
+// leaves the tree or names another entry
+func validTreeEntryName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.Contains(name, "/")
+}
+
+// writeTreeEntries serializes a single tree level and stores it
+func (r *Repository) writeTreeEntries(treeEntries []object.TreeEntry) (string, error) {
+	// Sort entries by name (Git requirement)
+	sort.Slice(treeEntries, func(i, j int) bool {
+		return treeSortName(treeEntries[i]) < treeSortName(treeEntries[j])
+	})
+
+	// Build tree content
+	var treeContent bytes.Buffer
+	for _, entry := range treeEntries {
+		// Format: <mode> <name>\0<20-byte SHA>
+		treeContent.WriteString(fmt.Sprintf("%s %s", entry.Mode, entry.Name))
+		treeContent.WriteByte(0)
+		shaBytes, _ := hex.DecodeString(entry.SHA)
+		treeContent.Write(shaBytes)
+	}
+
+	return r.WriteObject(object.TreeObject, treeContent.Bytes())
+}
+
+// flattenTree walks a tree recursively and returns its blobs keyed by slash-separated path
+func (r *Repository) flattenTree(treeSHA string) (map[string]IndexEntry, error) {
+	entries := make(map[string]IndexEntry)
+	walker := r.WalkTree(treeSHA, TreeWalkOptions{})
+	for entry, ok := walker.Next(); ok; entry, ok = walker.Next() {
+		entries[entry.Path] = IndexEntry{Path: entry.Path, SHA: entry.SHA, Mode: entry.Mode}
+	}
+	if err := walker.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// treeEntryAt looks up a single path in a tree without flattening it
+func (r *Repository) treeEntryAt(treeSHA, path string) (object.TreeEntry, bool, error) {
+	parts := strings.Split(normalizePath(path), "/")
+	for i, name := range parts {
+		objectType, content, err := r.ReadObject(treeSHA)
+		if err != nil {
+			return object.TreeEntry{}, false, err
+		}
+		if objectType != object.TreeObject {
+			return object.TreeEntry{}, false, nil
+		}
+
+		// Entries are decoded only up to the one wanted
+		found := false
+		iter := r.Format.IterTree(content)
+		for entry, ok := iter.Next(); ok; entry, ok = iter.Next() {
+			if entry.Name != name {
+				continue
+			}
+			if i == len(parts)-1 {
+				return entry, true, nil
+			}
+			treeSHA, found = entry.SHA, true
+			break
+		}
+		if err := iter.Err(); err != nil {
+			return object.TreeEntry{}, false, err
+		}
+		if !found {
+			return object.TreeEntry{}, false, nil
+		}
+	}
+	return object.TreeEntry{}, false, nil
+}
+
+// WriteTree creates tree objects for the whole working tree. Files are
+// hashed in parallel (see core.threads); the trees are then assembled in
+// directory order, so the result is the same however the work was scheduled.
+// Ignored files are left out unless they are tracked.
+func (r *Repository) WriteTree() (string, error) {
+	staged, err := r.StagedFiles()
+	if err != nil {
+		return "", err
+	}
+	tracked := make(map[string]bool)
+	modes := make(map[string]string, len(staged))
+	for _, entry := range staged {
+		modes[entry.Path] = entry.Mode
+		// Directories holding tracked files are scanned even when ignored
+		for p := entry.Path; p != "."; p = path.Dir(p) {
+			tracked[p] = true
+		}
+	}
+	ig, err := r.LoadIgnore()
+	if err != nil {
+		return "", err
+	}
+
+	root, err := r.scanDir(r.Root, "", ig, tracked, modes)
+	if err != nil {
+		return "", err
+	}
+
+	// The blobs and trees go through one ObjectWriter, so the many that are
+	// unchanged since the last snapshot are not compressed again
+	var paths []string
+	root.collectFiles(&paths)
+	var treeSHA string
+	err = r.batchObjects(func() error {
+		shas, err := r.hashFiles(paths, true)
+		if err != nil {
+			return err
+		}
+		next := 0
+		treeSHA, err = r.writeDirTree(root, shas, &next)
+		return err
+	})
+	return treeSHA, err
+}
+
+// dirNode is a working tree directory as scanned by WriteTree
+type dirNode struct {
+	path     string
+	names    []string
+	modes    []string   // tree mode of each entry
+	commits  []string   // commit each submodule is on; empty for other entries
+	children []*dirNode // nil for files and submodules
+}
+
+// scanDir lists a directory and its subdirectories, skipping the .gvc
+// directory and ignored paths that are not tracked. Symlinks are recorded
+// rather than followed, and nested repositories become submodules at the
+// commit their HEAD is on. rel is the directory's slash-separated path in
+// the working tree. modes holds the staged mode of each tracked file, which
+// it keeps when core.filemode is off.
+func (r *Repository) scanDir(basePath, rel string, ig *Ignore, tracked map[string]bool, modes map[string]string) (*dirNode, error) {
+	dirEntries, err := os.ReadDir(basePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", basePath, err)
+	}
+
+	node := &dirNode{path: basePath}
+	for _, entry := range dirEntries {
+		name := entry.Name()
+
+		// Skip the .gvc and .git directories
+		fullPath := filepath.Join(basePath, name)
+		if isMetadataDir(name) || fullPath == r.GitDir {
+			continue
+		}
+		// Recorded as the index spells it, opened as the directory does
+		if name, err = r.cleanIndexPath(name); err != nil {
+			return nil, err
+		}
+		entryRel := path.Join(rel, name)
+		if !tracked[entryRel] {
+			ignored, err := ig.Ignored(entryRel, entry.IsDir())
+			if err != nil {
+				return nil, err
+			}
+			if ignored {
+				continue
+			}
+		}
+
+		var child *dirNode
+		var mode, commit string
+		switch {
+		case entry.IsDir() && isRepoRoot(fullPath):
+			gitlink, _, err := r.submoduleEntry(entryRel)
+			if err != nil {
+				return nil, err
+			}
+			mode, commit = GitlinkMode, gitlink.SHA
+		case entry.IsDir():
+			if child, err = r.scanDir(fullPath, entryRel, ig, tracked, modes); err != nil {
+				return nil, err
+			}
+			mode = "40000"
+		default:
+			info, err := entry.Info()
+			if err != nil {
+				return nil, fmt.Errorf("failed to stat file %s: %w", entryRel, err)
+			}
+			if !info.Mode().IsRegular() && info.Mode()&os.ModeSymlink == 0 {
+				continue // sockets, devices and the like are not tracked
+			}
+			if mode, err = r.workingMode(info, modes[entryRel]); err != nil {
+				return nil, err
+			}
+		}
+		node.names = append(node.names, name)
+		node.modes = append(node.modes, mode)
+		node.commits = append(node.commits, commit)
+		node.children = append(node.children, child)
+	}
+	return node, nil
+}
+
+// collectFiles appends the paths of every file under the node, depth first
+func (node *dirNode) collectFiles(paths *[]string) {
+	for i, child := range node.children {
+		switch {
+		case child != nil:
+			child.collectFiles(paths)
+		case node.modes[i] != GitlinkMode:
+			*paths = append(*paths, filepath.Join(node.path, node.names[i]))
+		}
+	}
+}
+
+// writeDirTree recursively creates tree objects for a scanned directory,
+// taking blob SHAs from shas in the order collectFiles listed the files
+func (r *Repository) writeDirTree(node *dirNode, shas []string, next *int) (string, error) {
+	var treeEntries []object.TreeEntry
+	for i, child := range node.children {
+		entry := object.TreeEntry{Name: node.names[i], Mode: node.modes[i]}
+		switch {
+		case child != nil:
+			sha, err := r.writeDirTree(child, shas, next)
+			if err != nil {
+				return "", err
+			}
+			entry.Type, entry.SHA = object.TreeObject, sha
+		case entry.Mode == GitlinkMode:
+			entry.Type, entry.SHA = object.CommitObject, node.commits[i]
+		default:
+			entry.Type, entry.SHA = object.BlobObject, shas[*next]
+			*next++
+		}
+		treeEntries = append(treeEntries, entry)
+	}
+
+	return r.writeTreeEntries(treeEntries)
+}
@@ -0,0 +1,234 @@
+package gvc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// TreeWalkOptions controls WalkTree
+type TreeWalkOptions struct {
+	// Paths limits the walk to the entries at or below these slash-separated
+	// paths, relative to the walked tree, and the trees leading to them.
+	// Subtrees outside them are never read. Empty walks everything.
+	Paths []string
+	// Trees also returns each subtree, just before its contents; otherwise
+	// only files, symlinks and submodules are returned
+	Trees bool
+}
+
+// TreeWalkEntry is an entry a TreeWalker returns
+type TreeWalkEntry struct {
+	Path string // slash-separated, relative to the walked tree
+	object.TreeEntry
+}
+
+// TreeWalker streams the entries of a tree and its subtrees depth first, in
+// tree order, holding only the trees on the way down to the current entry
+// rather than the whole listing. An entry whose name could not be a path
+// part, such as "..", ends the walk with an error. While the entries of a tree are consumed,
+// the subtrees coming up are read ahead on core.threads workers.
+//
+//	walker := repo.WalkTree(treeSHA, gvc.TreeWalkOptions{})
+//	for entry, ok := walker.Next(); ok; entry, ok = walker.Next() {
+//		...
+//	}
+//	if err := walker.Err(); err != nil {
+//		...
+//	}
+type TreeWalker struct {
+	r       *Repository
+	paths   []string
+	trees   bool
+	sem     chan struct{} // one slot per worker; nil reads subtrees as they are reached
+	stack   []*treeWalkDir
+	pending *treeWalkDir // the subtree just reached, entered on the next call
+	err     error
+}
+
+// treeWalkDir is a tree on the walker's way down
+type treeWalkDir struct {
+	path    string
+	sha     string
+	load    *treeLoad
+	entries []object.TreeEntry
+	next    int               // the entry Next looks at next
+	ahead   int               // the entry readAhead looks at next
+	subdirs map[int]*treeLoad // subtrees read ahead, by entry index
+}
+
+// treeLoad is a tree being read: done is closed once entries or err is set
+type treeLoad struct {
+	done    chan struct{}
+	entries []object.TreeEntry
+	err     error
+}
+
+// WalkTree returns a walker over the tree treeSHA; an empty SHA walks the
+// empty tree
+func (r *Repository) WalkTree(treeSHA string, opts TreeWalkOptions) *TreeWalker {
+	w := &TreeWalker{r: r, trees: opts.Trees}
+	for _, spec := range opts.Paths {
+		spec = strings.Trim(spec, "/")
+		if spec == "" || spec == "." {
+			w.paths = nil
+			break
+		}
+		w.paths = append(w.paths, spec)
+	}
+	threads, err := r.hashThreads()
+	if err != nil {
+		w.err = err
+		return w
+	}
+	if threads > 1 {
+		w.sem = make(chan struct{}, threads)
+	}
+	if treeSHA != "" {
+		w.pending = &treeWalkDir{sha: treeSHA, load: w.startLoad(treeSHA)}
+	}
+	return w
+}
+
+// Next returns the next entry, or false once the walk is over or failed
+func (w *TreeWalker) Next() (TreeWalkEntry, bool) {
+	for w.err == nil {
+		if w.pending != nil {
+			if w.err = w.enter(w.pending); w.err != nil {
+				break
+			}
+			w.pending = nil
+		}
+		if len(w.stack) == 0 {
+			break
+		}
+		dir := w.stack[len(w.stack)-1]
+		if dir.next == len(dir.entries) {
+			w.stack = w.stack[:len(w.stack)-1]
+			continue
+		}
+		i := dir.next
+		dir.next++
+		if name := dir.entries[i].Name; !validTreeEntryName(name) {
+			w.err = fmt.Errorf("invalid entry name %q in tree %s", name, dir.sha)
+			break
+		}
+		entry := TreeWalkEntry{Path: joinTreePath(dir.path, dir.entries[i].Name), TreeEntry: dir.entries[i]}
+		if entry.Type != object.TreeObject {
+			if w.wanted(entry.Path, false) {
+				return entry, true
+			}
+			continue
+		}
+		if !w.wanted(entry.Path, true) {
+			continue
+		}
+		load, ok := dir.subdirs[i]
+		if ok {
+			delete(dir.subdirs, i)
+		} else {
+			load = w.startLoad(entry.SHA)
+			dir.ahead = max(dir.ahead, i+1)
+		}
+		w.readAhead(dir)
+		w.pending = &treeWalkDir{path: entry.Path, sha: entry.SHA, load: load}
+		if w.trees {
+			return entry, true
+		}
+	}
+	return TreeWalkEntry{}, false
+}
+
+// SkipDir leaves out the contents of the tree Next just returned
+func (w *TreeWalker) SkipDir() {
+	w.pending = nil
+}
+
+// Err returns the error that ended the walk, if any
+func (w *TreeWalker) Err() error {
+	return w.err
+}
+
+// enter waits for dir's tree to be read and starts reading its subtrees
+func (w *TreeWalker) enter(dir *treeWalkDir) error {
+	if err := w.r.canceled(); err != nil {
+		return err
+	}
+	if dir.load == nil {
+		return nil
+	}
+	<-dir.load.done
+	if dir.load.err != nil {
+		return dir.load.err
+	}
+	dir.entries, dir.load = dir.load.entries, nil
+	dir.subdirs = make(map[int]*treeLoad)
+	w.readAhead(dir)
+	w.stack = append(w.stack, dir)
+	return nil
+}
+
+// readAhead starts reading the next subtrees of dir the walk goes into, as
+// many as there are workers, so they are ready when it gets there
+func (w *TreeWalker) readAhead(dir *treeWalkDir) {
+	for ; dir.ahead < len(dir.entries) && len(dir.subdirs) < cap(w.sem); dir.ahead++ {
+		entry := dir.entries[dir.ahead]
+		if entry.Type == object.TreeObject && w.wanted(joinTreePath(dir.path, entry.Name), true) {
+			dir.subdirs[dir.ahead] = w.startLoad(entry.SHA)
+		}
+	}
+}
+
+// startLoad starts reading a tree on a worker, or reads it at once when
+// there is only one
+func (w *TreeWalker) startLoad(sha string) *treeLoad {
+	load := &treeLoad{done: make(chan struct{})}
+	if w.sem == nil {
+		load.entries, load.err = w.r.readTreeEntries(sha)
+		close(load.done)
+		return load
+	}
+	go func() {
+		w.sem <- struct{}{}
+		load.entries, load.err = w.r.readTreeEntries(sha)
+		<-w.sem
+		close(load.done)
+	}()
+	return load
+}
+
+// wanted reports whether the walk returns path, or for a tree goes into it
+func (w *TreeWalker) wanted(path string, isTree bool) bool {
+	if underAnyPath(path, w.paths) {
+		return true
+	}
+	if isTree {
+		for _, spec := range w.paths {
+			if strings.HasPrefix(spec, path+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// readTreeEntries reads and parses the tree sha
+func (r *Repository) readTreeEntries(sha string) ([]object.TreeEntry, error) {
+	objectType, content, err := r.ReadObject(sha)
+	if err != nil {
+		return nil, err
+	}
+	if objectType != object.TreeObject {
+		return nil, fmt.Errorf("expected tree object, got %s", objectType)
+	}
+	return r.Format.ParseTree(content)
+}
+
+// joinTreePath returns the path of name in the tree at dir
+func joinTreePath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
@@ -0,0 +1,138 @@
+package gvc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Upstream returns the remote and remote branch ref a local branch tracks,
+// from branch.<name>.remote and branch.<name>.merge. Both are empty when the
+// branch has no upstream.
+func (r *Repository) Upstream(branch string) (remote, merge string, err error) {
+	if remote, err = r.getConfigString("branch."+branch+".remote", ""); err != nil {
+		return "", "", err
+	}
+	if merge, err = r.getConfigString("branch."+branch+".merge", ""); err != nil {
+		return "", "", err
+	}
+	if remote == "" || merge == "" {
+		return "", "", nil
+	}
+	return remote, merge, nil
+}
+
+// SetUpstream makes a local branch track merge, a branch ref on remote
+func (r *Repository) SetUpstream(branch, remote, merge string) error {
+	if err := r.SetConfig("branch."+branch+".remote", remote); err != nil {
+		return err
+	}
+	return r.SetConfig("branch."+branch+".merge", merge)
+}
+
+// DefaultPushRemote returns the remote a bare "push" goes to: the current
+// branch's branch.<name>.pushRemote, then remote.pushDefault, then the
+// branch's upstream remote, and finally origin
+func (r *Repository) DefaultPushRemote() (string, error) {
+	branch, err := r.HeadRef()
+	if err != nil {
+		return "", err
+	}
+	keys := []string{"remote.pushDefault"}
+	if short, ok := strings.CutPrefix(branch, HeadsDir+"/"); ok {
+		keys = []string{"branch." + short + ".pushRemote", "remote.pushDefault", "branch." + short + ".remote"}
+	}
+	for _, key := range keys {
+		remote, err := r.getConfigString(key, "")
+		if err != nil || remote != "" {
+			return remote, err
+		}
+	}
+	return "origin", nil
+}
+
+// defaultPushRefspecs decides what a push without refspecs sends: the
+// remote.<name>.push refspecs when configured, otherwise what push.default
+// selects. Modes follow Git: nothing, current, upstream, simple (the
+// default) and matching.
+func (r *Repository) defaultPushRefspecs(name string, dst *Repository) ([]string, error) {
+	if name != "" {
+		entries, err := r.ReadConfigEntries()
+		if err != nil {
+			return nil, err
+		}
+		var configured []string
+		for _, entry := range entries {
+			if entry[0] == "remote."+name+".push" {
+				configured = append(configured, entry[1])
+			}
+		}
+		if len(configured) > 0 {
+			return configured, nil
+		}
+	}
+
+	mode, err := r.getConfigString("push.default", "simple")
+	if err != nil {
+		return nil, err
+	}
+	switch mode {
+	case "nothing":
+		return nil, fmt.Errorf("push.default is 'nothing': name the refs to push")
+	case "matching":
+		// Every local branch that has a namesake on the remote
+		refs, err := r.listRefs()
+		if err != nil {
+			return nil, err
+		}
+		var specs []string
+		for _, ref := range refs {
+			if !strings.HasPrefix(ref.Name, HeadsDir+"/") {
+				continue
+			}
+			if sha, err := dst.ReadRef(ref.Name); err != nil {
+				return nil, err
+			} else if sha != "" {
+				specs = append(specs, ref.Name+":"+ref.Name)
+			}
+		}
+		return specs, nil
+	case "current", "upstream", "simple":
+	default:
+		return nil, fmt.Errorf("unsupported push.default %q: use nothing, current, upstream, simple or matching", mode)
+	}
+
+	branch, err := r.HeadRef()
+	if err != nil {
+		return nil, err
+	}
+	if branch == "" {
+		return nil, fmt.Errorf("you are not currently on a branch; name the branch to push")
+	}
+	if mode == "current" {
+		return []string{branch + ":" + branch}, nil
+	}
+
+	short := strings.TrimPrefix(branch, HeadsDir+"/")
+	upstreamRemote, merge, err := r.Upstream(short)
+	if err != nil {
+		return nil, err
+	}
+	fetchRemote := upstreamRemote
+	if fetchRemote == "" {
+		fetchRemote = "origin"
+	}
+	// simple pushes to a remote the branch does not fetch from like current
+	if mode == "simple" && name != fetchRemote {
+		return []string{branch + ":" + branch}, nil
+	}
+	if merge == "" || upstreamRemote != name {
+		return nil, fmt.Errorf("the current branch %s has no upstream branch on this remote; "+
+			"to push it and track it, use 'gvc push -u %s %s'", short, fetchRemote, short)
+	}
+	if mode == "simple" && merge != branch {
+		return nil, fmt.Errorf("the upstream branch of your current branch does not match its name; "+
+			"use 'gvc push %s HEAD:%s' to push to the upstream branch or 'gvc push %s HEAD' for one of the same name",
+			name, strings.TrimPrefix(merge, HeadsDir+"/"), name)
+	}
+	return []string{branch + ":" + merge}, nil
+}
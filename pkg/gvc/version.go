@@ -0,0 +1,85 @@
+package gvc
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// BuildInfo describes a build of gvc and the repository formats, transports
+// and features it supports, so tools can check it can work on a repository
+// before they start
+type BuildInfo struct {
+	Version      string // Version, or the module version the Go toolchain recorded
+	GoVersion    string // Go release the binary was built with
+	Platform     string // operating system and architecture, as "linux/amd64"
+	Revision     string // commit the binary was built from, when known
+	RevisionTime string // its commit time, RFC 3339
+	Modified     bool   // built from a working tree with uncommitted changes
+
+	RepositoryFormatVersions []int    // core.repositoryFormatVersion values
+	Extensions               []string // extensions.* settings understood
+	ObjectFormats            []string // hash algorithms, the default first
+	IndexVersions            []int
+	PackVersions             []int
+	BundleVersions           []int
+	Transports               []Transport
+	Features                 []string
+}
+
+// Transport is a kind of repository address and what it can be used for
+type Transport struct {
+	Scheme string // "file" also stands for plain local paths
+	Fetch  bool   // clone and fetch
+	Push   bool
+}
+
+// ReadBuildInfo describes the running build of gvc
+func ReadBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:                  Version,
+		GoVersion:                runtime.Version(),
+		Platform:                 runtime.GOOS + "/" + runtime.GOARCH,
+		RepositoryFormatVersions: []int{0, 1},
+		Extensions:               []string{ObjectFormatKey, EncryptionKey},
+		ObjectFormats:            []string{object.SHA1.Name, object.SHA256.Name},
+		IndexVersions:            []int{indexVersion, indexVersionV3},
+		PackVersions:             []int{2, 3},
+		BundleVersions:           []int{2, 3},
+		Transports: []Transport{
+			{Scheme: "file", Fetch: true, Push: true},
+			{Scheme: "git", Fetch: true},
+		},
+		Features: []string{"commit-graph", "encryption", "hooks", "large-files", "namespaces",
+			"shallow", "signing", "sparse-checkout", "submodules", "worktrees"},
+	}
+	if checksLockOwners {
+		info.Features = append(info.Features, "lock-owner-check")
+	}
+	if checksOwnership {
+		info.Features = append(info.Features, "ownership-check")
+	}
+	if runtime.GOOS != "windows" {
+		info.Features = append(info.Features, "shared-repository")
+	}
+
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if info.Version == "dev" && build.Main.Version != "" && build.Main.Version != "(devel)" {
+		info.Version = build.Main.Version
+	}
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info.Revision = setting.Value
+		case "vcs.time":
+			info.RevisionTime = setting.Value
+		case "vcs.modified":
+			info.Modified = setting.Value == "true"
+		}
+	}
+	return info
+}
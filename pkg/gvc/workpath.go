@@ -0,0 +1,91 @@
+package gvc
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// checkWorkingPath rejects a tree, index or patch path that does not name a
+// file inside the working tree: an absolute path, an empty, "." or ".." part,
+// or a part naming the repository directory, .gvc or .git in any case, which
+// checking out a malicious tree would otherwise write into. On Windows a
+// backslash or drive colon in a name would write outside the path it names,
+// and trailing dots and spaces are dropped from names, so those are
+// rejected too.
+func checkWorkingPath(path string) error {
+	if path == "" || strings.HasPrefix(path, "/") {
+		return fmt.Errorf("invalid path '%s'", path)
+	}
+	if runtime.GOOS == "windows" && strings.ContainsAny(path, `\:`) {
+		return fmt.Errorf("invalid path '%s' on Windows", path)
+	}
+	for _, part := range strings.Split(path, "/") {
+		if runtime.GOOS == "windows" {
+			part = strings.TrimRight(part, ". ")
+		}
+		switch {
+		case part == "" || part == "." || part == "..":
+			return fmt.Errorf("invalid path '%s'", path)
+		case strings.EqualFold(part, GvcDir) || strings.EqualFold(part, ".git"):
+			return fmt.Errorf("invalid path '%s': it names the repository directory", path)
+		}
+	}
+	return nil
+}
+
+// checkIndexPath rejects a path the index cannot hold: one checkWorkingPath
+// rejects, or one with a backslash, which names a directory on Windows and
+// part of a file name elsewhere, so the same entry would be two different
+// files
+func checkIndexPath(path string) error {
+	if err := checkWorkingPath(path); err != nil {
+		return err
+	}
+	if strings.Contains(path, `\`) {
+		return fmt.Errorf("invalid path '%s': it contains a backslash", path)
+	}
+	return nil
+}
+
+// cleanIndexPath returns the spelling of a working tree path the index
+// stores: relative to the root, slash-separated and without "." parts, so
+// "./foo" and "foo" are the same entry. With core.precomposeUnicode on it
+// is also in Unicode normalization form C.
+func (r *Repository) cleanIndexPath(path string) (string, error) {
+	path = normalizePath(path)
+	format, err := r.worktreeFormat()
+	if err != nil {
+		return "", err
+	}
+	if format.precomposeUnicode {
+		path = toNFC(path)
+	}
+	return path, nil
+}
+
+// beyondSymlink reports whether a directory on the way to a working tree
+// path is a symbolic link, so the path really names a file elsewhere,
+// possibly outside the working tree. A missing directory is not a link.
+func (r *Repository) beyondSymlink(path string) bool {
+	dir := r.Root
+	parts := strings.Split(path, "/")
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		info, err := os.Lstat(dir)
+		if err != nil {
+			return false
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// errBeyondSymlink is returned for a path beyondSymlink finds a link on the way to
+func errBeyondSymlink(path string) error {
+	return fmt.Errorf("'%s' is beyond a symbolic link", path)
+}
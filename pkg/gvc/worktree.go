@@ -0,0 +1,349 @@
+package gvc
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/Ritikchauhan1704/Gvc-Go-version-control.git/pkg/object"
+)
+
+// SymlinkMode is the tree mode of a symbolic link, whose blob holds the link's target
+const SymlinkMode = "120000"
+
+// modeForFile returns the tree mode recorded for a file on disk, as reported by os.Lstat
+func modeForFile(fileInfo os.FileInfo) string {
+	if fileInfo.Mode()&os.ModeSymlink != 0 {
+		return SymlinkMode
+	}
+	if fileInfo.Mode()&0111 != 0 {
+		return "100755" // Executable file
+	}
+	return "100644" // Regular file
+}
+
+// sameEntry reports whether two entries record the same content and mode
+func sameEntry(a, b IndexEntry, aOK, bOK bool) bool {
+	if aOK != bOK {
+		return false
+	}
+	return !aOK || (a.SHA == b.SHA && a.Mode == b.Mode)
+}
+
+// headEntries returns the flattened tree of the current commit
+func (r *Repository) headEntries() (map[string]IndexEntry, error) {
+	treeSHA, err := r.headTree()
+	if err != nil {
+		return nil, err
+	}
+	return r.flattenTree(treeSHA)
+}
+
+// headTree returns the tree of the current commit, or "" on an unborn branch
+func (r *Repository) headTree() (string, error) {
+	headSHA, err := r.HeadCommit()
+	if err != nil || headSHA == "" {
+		return "", err
+	}
+	commit, err := r.ReadCommit(headSHA)
+	if err != nil {
+		return "", err
+	}
+	return commit.TreeSHA, nil
+}
+
+// stagedEntries returns every path the next commit would record: the index
+func (r *Repository) stagedEntries() (map[string]IndexEntry, error) {
+	index, err := r.ReadIndex()
+	if err != nil {
+		return nil, err
+	}
+	return indexEntryMap(index), nil
+}
+
+// indexEntryMap keys the entries of an index by their normalized path
+func indexEntryMap(index *Index) map[string]IndexEntry {
+	staged := make(map[string]IndexEntry, len(index.Entries))
+	for _, entry := range index.Entries {
+		entry.Path = normalizePath(entry.Path)
+		staged[entry.Path] = entry
+	}
+	return staged
+}
+
+// writeStagedEntries stores staged as the index. Entries whose content is
+// unchanged keep the stat data recorded for them, so their working tree
+// files are not re-hashed, and directories whose entries are all unchanged
+// keep their cached trees. In a sparse checkout, entries left out of the
+// working tree are marked skip-worktree.
+func (r *Repository) writeStagedEntries(staged map[string]IndexEntry) error {
+	previous, err := r.ReadIndex()
+	if err != nil {
+		return err
+	}
+	current := make(map[string]IndexEntry, len(previous.Entries))
+	for _, entry := range previous.Entries {
+		current[normalizePath(entry.Path)] = entry
+	}
+	index := Index{Entries: sortedEntries(staged), TreeCache: previous.TreeCache, loaded: previous.loaded}
+	for i, entry := range index.Entries {
+		if old, ok := current[entry.Path]; ok && entry.ModTime.IsZero() && sameEntry(entry, old, true, true) {
+			index.Entries[i].Size, index.Entries[i].ModTime = old.Size, old.ModTime
+		}
+	}
+	if err := r.markSparseEntries(index.Entries); err != nil {
+		return err
+	}
+	// A resolve-undo record is kept while its path is left as it was, as
+	// when a resolved conflict is committed
+	for _, record := range previous.ResolveUndo {
+		old, inPrevious := current[record.Path]
+		entry, inStaged := staged[record.Path]
+		if inPrevious && inStaged && sameEntry(entry, old, true, true) {
+			index.ResolveUndo = append(index.ResolveUndo, record)
+		}
+	}
+	return r.WriteIndex(&index)
+}
+
+// hashWorkingFile hashes a working tree file into a blob, storing it when
+// write is set. A symlink is not followed: its blob is the link's target. A
+// file the large-file store tracks is hashed as its pointer, and other files
+// with their line endings converted as core.autocrlf says.
+func (r *Repository) hashWorkingFile(path string, write bool) (string, error) {
+	fileInfo, err := os.Lstat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat file %s: %w", path, err)
+	}
+	if fileInfo.Mode()&os.ModeSymlink == 0 {
+		pointer, ok, err := r.largeFilePointer(path, fileInfo, write)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			format, err := r.worktreeFormat()
+			if err != nil {
+				return "", err
+			}
+			if format.autocrlf == "false" {
+				return r.HashFile(path, write)
+			}
+			content, err := r.readWorkingContent(path)
+			if err != nil {
+				return "", fmt.Errorf("failed to read file %s: %w", path, err)
+			}
+			if write {
+				return r.WriteObject(object.BlobObject, content)
+			}
+			return r.Format.Hash(object.BlobObject, content), nil
+		}
+		if write {
+			return r.WriteObject(object.BlobObject, pointer)
+		}
+		return r.Format.Hash(object.BlobObject, pointer), nil
+	}
+	target, err := os.Readlink(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read symlink %s: %w", path, err)
+	}
+	if write {
+		return r.WriteObject(object.BlobObject, []byte(filepath.ToSlash(target)))
+	}
+	return r.Format.Hash(object.BlobObject, []byte(filepath.ToSlash(target))), nil
+}
+
+// notOnDisk reports whether err from looking up a working tree path means
+// nothing is there: the path is missing, or one of its parents is now a file
+func notOnDisk(err error) bool {
+	return os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR)
+}
+
+// readWorkingEntry hashes the working tree copy of path, storing it as a blob when write is set
+func (r *Repository) readWorkingEntry(path string, write bool) (IndexEntry, bool, error) {
+	return r.readWorkingEntryMode(path, write, "")
+}
+
+// readWorkingEntryMode is readWorkingEntry for a file the index records
+// with previousMode, which it keeps when core.filemode is off
+func (r *Repository) readWorkingEntryMode(path string, write bool, previousMode string) (IndexEntry, bool, error) {
+	fileInfo, err := os.Lstat(r.worktreePath(path))
+	if err != nil {
+		if notOnDisk(err) {
+			return IndexEntry{}, false, nil
+		}
+		return IndexEntry{}, false, fmt.Errorf("failed to stat file %s: %w", path, err)
+	}
+	if fileInfo.IsDir() {
+		return r.submoduleEntry(path)
+	}
+
+	sha, err := r.hashWorkingFile(r.worktreePath(path), write)
+	if err != nil {
+		return IndexEntry{}, false, err
+	}
+	mode, err := r.workingMode(fileInfo, previousMode)
+	if err != nil {
+		return IndexEntry{}, false, err
+	}
+
+	return IndexEntry{
+		Path:    path,
+		SHA:     sha,
+		Mode:    mode,
+		Size:    fileInfo.Size(),
+		ModTime: fileInfo.ModTime(),
+	}, true, nil
+}
+
+// readTrackedEntry is readWorkingEntry for a path with a staged entry. A
+// file a sparse checkout leaves out of the working tree counts as unchanged.
+func (r *Repository) readTrackedEntry(staged IndexEntry, write bool) (IndexEntry, bool, error) {
+	if staged.Flags&IndexSkipWorktree != 0 {
+		return staged, true, nil
+	}
+	return r.readWorkingEntryMode(staged.Path, write, staged.Mode)
+}
+
+// indexModTime returns when the index was last written, or the zero time
+// when there is none
+func (r *Repository) indexModTime() time.Time {
+	modTime, _ := r.gitFileModTime(r.indexPath())
+	return modTime
+}
+
+// readWorkingEntryCached is readWorkingEntry for a file whose staged entry
+// is known: when the file's size, mode and modification time still match the
+// entry, its SHA is reused instead of re-hashing the file. A file modified
+// no earlier than indexTime, when the index was written, could have changed
+// again within the same timestamp, so it is always hashed. A file a sparse
+// checkout leaves out counts as unchanged.
+func (r *Repository) readWorkingEntryCached(path string, cached IndexEntry, indexTime time.Time) (IndexEntry, bool, error) {
+	if cached.Flags&IndexSkipWorktree != 0 {
+		cached.Path = path
+		return cached, true, nil
+	}
+	fileInfo, err := os.Lstat(r.worktreePath(path))
+	if err != nil {
+		if notOnDisk(err) {
+			return IndexEntry{}, false, nil
+		}
+		return IndexEntry{}, false, fmt.Errorf("failed to stat file %s: %w", path, err)
+	}
+	mode, err := r.workingMode(fileInfo, cached.Mode)
+	if err != nil {
+		return IndexEntry{}, false, err
+	}
+	if !cached.ModTime.IsZero() && cached.ModTime.Before(indexTime) &&
+		cached.ModTime.Equal(fileInfo.ModTime()) && cached.Size == fileInfo.Size() &&
+		cached.Mode == mode {
+		cached.Path = path
+		return cached, true, nil
+	}
+	return r.readWorkingEntryMode(path, false, cached.Mode)
+}
+
+// writeWorkingFile materializes a blob entry in the working tree. A
+// submodule gets an empty directory to be cloned into, and a symlink is
+// created pointing at the target its blob records. A large-file pointer is
+// replaced by the content it names, when the store has it, and other files
+// get the line endings core.autocrlf asks for. Entries with modes Git does
+// not define are written as regular files.
+func (r *Repository) writeWorkingFile(entry IndexEntry) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	// A tree may come from anyone; none of its files may land outside the
+	// working tree, in .gvc, or through a symlink checked out before them
+	if err := checkWorkingPath(entry.Path); err != nil {
+		return err
+	}
+	if r.beyondSymlink(entry.Path) {
+		return errBeyondSymlink(entry.Path)
+	}
+	if entry.Mode == GitlinkMode {
+		if err := os.MkdirAll(r.worktreePath(entry.Path), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", entry.Path, err)
+		}
+		return nil
+	}
+	objectType, content, err := r.ReadObject(entry.SHA)
+	if err != nil {
+		return err
+	}
+	if objectType != object.BlobObject {
+		return fmt.Errorf("expected blob object for %s, got %s", entry.Path, objectType)
+	}
+
+	path := r.worktreePath(entry.Path)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", entry.Path, err)
+	}
+
+	// Replace rather than write through a symlink already at the path
+	if info, err := os.Lstat(path); err == nil && (info.Mode()&os.ModeSymlink != 0 || entry.Mode == SymlinkMode) {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", entry.Path, err)
+		}
+	}
+	if entry.Mode == SymlinkMode {
+		if err := os.Symlink(filepath.FromSlash(string(content)), path); err != nil {
+			return fmt.Errorf("failed to create symlink %s: %w", entry.Path, err)
+		}
+		return nil
+	}
+
+	perm := os.FileMode(0644)
+	if entry.Mode == "100755" {
+		perm = 0755
+	}
+	if pointer, ok := ParseLFSPointer(content); ok {
+		err := r.writeLargeFile(path, pointer, perm)
+		if err == nil {
+			return os.Chmod(path, perm)
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		fmt.Fprintf(r.Out, "warning: %s: %v; checked out its pointer\n", entry.Path, err)
+	}
+	if content, err = r.toWorkingEOL(content); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, perm); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", entry.Path, err)
+	}
+	return os.Chmod(path, perm)
+}
+
+// removeWorkingFile deletes a file from the working tree along with any
+// directories it leaves empty. A submodule that has been cloned is left in
+// place, as its repository may hold work found nowhere else. Nothing is
+// removed through a symlinked directory, as the file there is not the one
+// the path names.
+func (r *Repository) removeWorkingFile(path string) error {
+	if err := r.checkWritable(); err != nil {
+		return err
+	}
+	if err := checkWorkingPath(path); err != nil {
+		return err
+	}
+	if r.beyondSymlink(path) {
+		return nil
+	}
+	if err := os.Remove(r.worktreePath(path)); err != nil && !os.IsNotExist(err) {
+		if isDir(r.worktreePath(path)) {
+			return nil
+		}
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	for dir := filepath.Dir(r.worktreePath(path)); dir != r.Root && dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
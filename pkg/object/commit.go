@@ -0,0 +1,84 @@
+package object
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit represents parsed commit information
+type Commit struct {
+	SHA       string
+	TreeSHA   string
+	ParentSHA string   // first parent, empty for a root commit
+	Parents   []string // every parent, in order
+	Author    string
+	Message   string
+	Timestamp time.Time // when the commit was authored
+	Committed time.Time // when it was committed; differs after an amend, rebase or cherry-pick
+}
+
+// ParseCommit parses a commit object and returns a Commit
+func ParseCommit(commitSHA string, content []byte) (*Commit, error) {
+	lines := strings.Split(string(content), "\n")
+
+	commit := &Commit{SHA: commitSHA}
+	messageStart := -1
+
+	for i, line := range lines {
+		if line == "" {
+			messageStart = i + 1
+			break
+		}
+
+		parts := strings.SplitN(line, " ", 2)
+		if len(parts) < 2 {
+			continue
+		}
+
+		switch parts[0] {
+		case "tree":
+			commit.TreeSHA = parts[1]
+		case "parent":
+			// ParentSHA tracks the first parent; the rest are merge parents
+			if commit.ParentSHA == "" {
+				commit.ParentSHA = parts[1]
+			}
+			commit.Parents = append(commit.Parents, parts[1])
+		case "author":
+			// Parse author and timestamp
+			authorParts := strings.Split(parts[1], " ")
+			if len(authorParts) >= 2 {
+				timestamp := authorParts[len(authorParts)-2]
+				ts := time.Unix(ParseInt64(timestamp), 0)
+				commit.Timestamp = ts
+
+				commit.Author = strings.Join(authorParts[:len(authorParts)-2], " ")
+			}
+		case "committer":
+			if committerParts := strings.Split(parts[1], " "); len(committerParts) >= 2 {
+				commit.Committed = time.Unix(ParseInt64(committerParts[len(committerParts)-2]), 0)
+			}
+		}
+	}
+	if commit.Committed.IsZero() {
+		commit.Committed = commit.Timestamp
+	}
+
+	if messageStart > 0 && messageStart < len(lines) {
+		commit.Message = strings.Join(lines[messageStart:], "\n")
+		commit.Message = strings.TrimSpace(commit.Message)
+	}
+
+	return commit, nil
+}
+
+// ParseInt64 parses a Unix timestamp, warning and returning 0 when it is malformed
+func ParseInt64(s string) int64 {
+	if ts, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ts
+	}
+	fmt.Printf("warning: could not parse timestamp %q\n", s)
+	return 0
+}
@@ -0,0 +1,69 @@
+package object
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Format expands the placeholders of log --format: %H and %h (commit),
+// %T and %t (tree), %P and %p (first parent), %an, %ae and %aN (author),
+// %ad, %ai and %at (date), %s (subject), %b (body), %B (message), %m (side
+// marker), %n (newline) and %%. marker is what %m stands for, such as "<"
+// or ">" for log --left-right; "" elsewhere.
+func (commit *Commit) Format(format, marker string) string {
+	short := func(sha string) string {
+		if len(sha) > 7 {
+			return sha[:7]
+		}
+		return sha
+	}
+	name, email := commit.Author, ""
+	if open := strings.LastIndex(commit.Author, " <"); open >= 0 && strings.HasSuffix(commit.Author, ">") {
+		name, email = commit.Author[:open], commit.Author[open+2:len(commit.Author)-1]
+	}
+	subject, body, _ := strings.Cut(commit.Message, "\n")
+	body = strings.TrimSpace(body)
+
+	placeholders := map[string]string{
+		"H":  commit.SHA,
+		"h":  short(commit.SHA),
+		"T":  commit.TreeSHA,
+		"t":  short(commit.TreeSHA),
+		"P":  commit.ParentSHA,
+		"p":  short(commit.ParentSHA),
+		"an": name,
+		"aN": name,
+		"ae": email,
+		"ad": commit.Timestamp.Format("Mon Jan 2 15:04:05 2006 -0700"),
+		"ai": commit.Timestamp.Format("2006-01-02 15:04:05 -0700"),
+		"at": strconv.FormatInt(commit.Timestamp.Unix(), 10),
+		"s":  subject,
+		"b":  body,
+		"B":  commit.Message,
+		"m":  strings.TrimSpace(marker),
+		"n":  "\n",
+		"%":  "%",
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			out.WriteByte(format[i])
+			continue
+		}
+		if i+2 < len(format) {
+			if value, ok := placeholders[format[i+1:i+3]]; ok {
+				out.WriteString(value)
+				i += 2
+				continue
+			}
+		}
+		if value, ok := placeholders[format[i+1:i+2]]; ok {
+			out.WriteString(value)
+			i++
+			continue
+		}
+		out.WriteByte(format[i])
+	}
+	return out.String()
+}
@@ -0,0 +1,103 @@
+// Package object defines the blob, tree and commit objects gvc stores and
+// their Git-compatible encoding.
+package object
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// Type represents the type of Git object
+type Type string
+
+const (
+	BlobObject   Type = "blob"
+	TreeObject   Type = "tree"
+	CommitObject Type = "commit"
+	// TagObject is an annotated tag; gvc reads them from Git repositories
+	TagObject Type = "tag"
+)
+
+// Format is the hash algorithm a repository names its objects with
+type Format struct {
+	// Name is the value of extensions.objectFormat, e.g. "sha256"
+	Name string
+	// Size is the length of a raw object ID in bytes
+	Size int
+	// Version identifies the algorithm in commit-graph headers
+	Version byte
+
+	newHash func() hash.Hash
+}
+
+// The supported object formats. SHA1 is the default, matching Git.
+var (
+	SHA1   = &Format{Name: "sha1", Size: sha1.Size, Version: 1, newHash: sha1.New}
+	SHA256 = &Format{Name: "sha256", Size: sha256.Size, Version: 2, newHash: sha256.New}
+)
+
+// ParseFormat returns the object format with the given name
+func ParseFormat(name string) (*Format, error) {
+	switch strings.ToLower(name) {
+	case SHA1.Name:
+		return SHA1, nil
+	case SHA256.Name:
+		return SHA256, nil
+	}
+	return nil, fmt.Errorf("unknown object format: %s", name)
+}
+
+// HexSize returns the length of an object ID written in hex
+func (f *Format) HexSize() int {
+	return f.Size * 2
+}
+
+// New returns a hash for computing object IDs and file checksums
+func (f *Format) New() hash.Hash {
+	return f.newHash()
+}
+
+// ZeroSHA stands in for a missing object in ref logs
+func (f *Format) ZeroSHA() string {
+	return strings.Repeat("0", f.HexSize())
+}
+
+// ValidateSHA checks if the provided SHA is valid
+func (f *Format) ValidateSHA(sha string) error {
+	if len(sha) != f.HexSize() {
+		return fmt.Errorf("invalid SHA length: expected %d, got %d", f.HexSize(), len(sha))
+	}
+	if _, err := hex.DecodeString(sha); err != nil {
+		return fmt.Errorf("invalid SHA format: %w", err)
+	}
+	return nil
+}
+
+// Header returns the "<type> <size>\x00" prefix hashed and stored before an object's content
+func Header(objectType Type, size int64) string {
+	return fmt.Sprintf("%s %d\x00", objectType, size)
+}
+
+// Hash returns the SHA an object with the given content is stored under
+func (f *Format) Hash(objectType Type, content []byte) string {
+	h := f.New()
+	io.WriteString(h, Header(objectType, int64(len(content))))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// HashReader returns the SHA of an object whose size bytes of content are read
+// from r, without holding the content in memory
+func (f *Format) HashReader(objectType Type, size int64, r io.Reader) (string, error) {
+	h := f.New()
+	io.WriteString(h, Header(objectType, size))
+	if _, err := io.CopyN(h, r, size); err != nil {
+		return "", fmt.Errorf("failed to read %d bytes of content: %w", size, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,40 @@
+package object
+
+import (
+	"errors"
+	"strings"
+)
+
+// Tag is an annotated tag: a named pointer to another object, with a tagger
+// and message
+type Tag struct {
+	SHA     string
+	Object  string // the object tagged
+	Type    Type   // its type
+	Name    string
+	Tagger  string
+	Message string
+}
+
+// ParseTag parses a tag object and returns a Tag
+func ParseTag(tagSHA string, content []byte) (*Tag, error) {
+	header, message, _ := strings.Cut(string(content), "\n\n")
+	tag := &Tag{SHA: tagSHA, Message: strings.TrimSpace(message)}
+	for _, line := range strings.Split(header, "\n") {
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "object":
+			tag.Object = value
+		case "type":
+			tag.Type = Type(value)
+		case "tag":
+			tag.Name = value
+		case "tagger":
+			tag.Tagger = value
+		}
+	}
+	if tag.Object == "" || tag.Type == "" {
+		return nil, errors.New("tag has no object or type")
+	}
+	return tag, nil
+}
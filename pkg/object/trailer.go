@@ -0,0 +1,58 @@
+package object
+
+import (
+	"strings"
+)
+
+// Trailer is a "Key: value" line at the end of a commit message, such as
+// Signed-off-by or BREAKING CHANGE
+type Trailer struct {
+	Key   string
+	Value string
+}
+
+// ParseTrailers returns the trailers of a commit message: its last
+// paragraph, when that is not the subject and every line in it is a trailer
+// or an indented continuation of the one before
+func ParseTrailers(message string) []Trailer {
+	lines := strings.Split(strings.TrimSpace(message), "\n")
+	// Lines of nothing but spaces separate paragraphs too
+	start := len(lines)
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+	if start == 0 {
+		return nil
+	}
+	var trailers []Trailer
+	for _, line := range lines[start:] {
+		if line != "" && (line[0] == ' ' || line[0] == '\t') && len(trailers) > 0 {
+			trailers[len(trailers)-1].Value += " " + strings.TrimSpace(line)
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || !isTrailerKey(key) {
+			return nil
+		}
+		trailers = append(trailers, Trailer{Key: key, Value: strings.TrimSpace(value)})
+	}
+	return trailers
+}
+
+// isTrailerKey reports whether key names a trailer: letters, digits and
+// dashes, or BREAKING CHANGE, the one key with a space conventional commits
+// allow
+func isTrailerKey(key string) bool {
+	if key == "BREAKING CHANGE" {
+		return true
+	}
+	if key == "" {
+		return false
+	}
+	for _, c := range key {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
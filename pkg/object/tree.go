@@ -0,0 +1,123 @@
+package object
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// TreeEntry represents an entry in a tree object
+type TreeEntry struct {
+	Mode string
+	Name string
+	SHA  string
+	Type Type
+}
+
+// entryTypes maps every mode a tree entry may have to the type of object it
+// names: files, executables and symlinks are blobs, directories trees and
+// submodules commits. 100664, which very early versions of Git wrote for
+// group-writable files, is read as a regular file.
+var entryTypes = map[string]Type{
+	"100644": BlobObject,
+	"100755": BlobObject,
+	"120000": BlobObject,
+	"100664": BlobObject,
+	"40000":  TreeObject,
+	"160000": CommitObject,
+}
+
+// ModeType returns the type of object a tree entry with mode names
+func ModeType(mode string) (Type, error) {
+	objType, ok := entryTypes[mode]
+	if !ok {
+		return "", fmt.Errorf("invalid mode %q", mode)
+	}
+	return objType, nil
+}
+
+// ParseTree parses tree object content into structured entries, whose object
+// IDs are raw hashes of the format's size
+func (f *Format) ParseTree(content []byte) ([]TreeEntry, error) {
+	var entries []TreeEntry
+	iter := f.IterTree(content)
+	for {
+		entry, ok := iter.Next()
+		if !ok {
+			break
+		}
+		entries = append(entries, entry)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// TreeIterator decodes the entries of a tree one at a time, so a lookup
+// can stop at the entry it wants without decoding the rest of the tree
+type TreeIterator struct {
+	format  *Format
+	content []byte
+	index   int
+	err     error
+}
+
+// IterTree returns an iterator over the entries of tree object content
+func (f *Format) IterTree(content []byte) *TreeIterator {
+	return &TreeIterator{format: f, content: content}
+}
+
+// Next returns the next entry, or false once the tree is exhausted or
+// malformed; Err tells the two apart
+func (it *TreeIterator) Next() (TreeEntry, bool) {
+	content, index := it.content, it.index
+	if it.err != nil || index >= len(content) {
+		return TreeEntry{}, false
+	}
+
+	// Read mode
+	modeStart := index
+	for index < len(content) && content[index] != ' ' {
+		index++
+	}
+	if index >= len(content) {
+		it.err = errors.New("malformed tree: missing space after mode")
+		return TreeEntry{}, false
+	}
+	mode := string(content[modeStart:index])
+	index++ // skip space
+
+	// Read name
+	nameStart := index
+	for index < len(content) && content[index] != 0 {
+		index++
+	}
+	if index >= len(content) {
+		it.err = errors.New("malformed tree: missing null byte after name")
+		return TreeEntry{}, false
+	}
+	name := string(content[nameStart:index])
+	index++ // skip null byte
+
+	// Read SHA (20 bytes for SHA-1, 32 for SHA-256)
+	size := it.format.Size
+	if index+size > len(content) {
+		it.err = errors.New("malformed tree: incomplete SHA")
+		return TreeEntry{}, false
+	}
+	sha := hex.EncodeToString(content[index : index+size])
+
+	objType, err := ModeType(mode)
+	if err != nil {
+		it.err = fmt.Errorf("malformed tree: entry %q has %w", name, err)
+		return TreeEntry{}, false
+	}
+	it.index = index + size
+	return TreeEntry{Mode: mode, Name: name, SHA: sha, Type: objType}, true
+}
+
+// Err returns the error that stopped the iterator, if the tree is malformed
+func (it *TreeIterator) Err() error {
+	return it.err
+}